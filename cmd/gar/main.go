@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -13,7 +15,6 @@ import (
 	codingtool "gar/internal/coding-agent/tool"
 	"gar/internal/config"
 	"gar/internal/llm"
-	sessionstore "gar/internal/session"
 	"gar/internal/tui"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -37,6 +38,11 @@ func execute() error {
 
 func newRootCmd() *cobra.Command {
 	var configPath string
+	var approve bool
+	var resumeID string
+	var continueLatest bool
+	var once bool
+	var promptFile string
 
 	cmd := &cobra.Command{
 		Use:   "gar",
@@ -47,48 +53,100 @@ func newRootCmd() *cobra.Command {
 				return fmt.Errorf("load config: %w", err)
 			}
 
-			provider, model, err := buildProviderFromConfig(cfg)
-			if err != nil {
-				return fmt.Errorf("build provider: %w", err)
+			if once || promptFile != "" {
+				// Batch mode has nobody to answer approval prompts.
+				rt, err := buildAgentRuntime(cfg, approvalNone)
+				if err != nil {
+					return err
+				}
+				return runOnce(cmd.Context(), rt, promptFile, cmd.InOrStdin(), cmd.OutOrStdout())
 			}
 
-			registry, err := buildToolRegistry()
+			approval := approvalConfirmed
+			if approve {
+				approval = approvalAll
+			}
+			rt, err := buildAgentRuntime(cfg, approval)
 			if err != nil {
-				return fmt.Errorf("build tool registry: %w", err)
+				return err
 			}
 
-			ag, err := agent.New(agent.Config{
-				Provider:     provider,
-				ToolRegistry: registry,
-				MaxTurns:     cfg.Agent.MaxTurns,
+			summarizer, err := agent.New(agent.Config{
+				Provider: rt.provider,
+				MaxTurns: 1,
 			})
 			if err != nil {
-				return fmt.Errorf("create agent: %w", err)
+				return fmt.Errorf("create summarizer: %w", err)
 			}
 
 			cwd, err := os.Getwd()
 			if err != nil {
 				return fmt.Errorf("resolve cwd: %w", err)
 			}
-			store, err := sessionstore.NewStore(sessionstore.DefaultDir(cwd))
+			store, err := openSessionStore(cfg, cwd)
+			if err != nil {
+				return err
+			}
+			contextGuard, err := cfg.ContextGuardSettings()
+			if err != nil {
+				return fmt.Errorf("resolve context guard: %w", err)
+			}
+			keyBindings, err := cfg.KeyBindings()
+			if err != nil {
+				return err
+			}
+			themeFile, err := cfg.ThemeFile()
+			if err != nil {
+				return err
+			}
+			theme, err := tui.ResolveThemeFile(cfg.TUI.Theme, themeFile)
 			if err != nil {
-				return fmt.Errorf("create session store: %w", err)
+				return fmt.Errorf("load theme: %w", err)
+			}
+			if !tui.ColorEnabled(cfg.TUI.Color) {
+				tui.DisableColor()
+				theme = tui.ResolveTheme(tui.MonochromeThemeName)
+			}
+			sessionID, err := startupSession(cmd.Context(), store, resumeID, continueLatest, time.Now())
+			if err != nil {
+				return err
 			}
 
 			app := tui.NewApp(tui.AppConfig{
-				Version:       "v0.1.0",
-				ModelName:     model,
-				CWD:           cwd,
-				SessionID:     time.Now().UTC().Format("20060102-150405"),
-				ThemeName:     cfg.TUI.Theme,
-				ShowInspector: cfg.TUI.ShowInspector,
-				Runner:        ag,
-				MaxTokens:     defaultRunMaxTokens,
-				Tools:         buildToolSpecs(),
-				SessionStore:  store,
+				Version:            "v0.1.0",
+				ModelName:          rt.model,
+				ProviderName:       providerName(cfg),
+				CWD:                cwd,
+				SessionID:          sessionID,
+				ThemeName:          cfg.TUI.Theme,
+				ShowInspector:      cfg.TUI.ShowInspector,
+				Markdown:           cfg.TUI.Markdown,
+				ShowThinking:       cfg.TUI.ShowThinking,
+				ShowInputCount:     cfg.TUI.ShowInputCount,
+				ChatLimit:          cfg.TUI.ChatLimit,
+				Welcome:            cfg.TUI.Welcome,
+				HideHints:          cfg.TUI.HideHints,
+				ProjectPromptFiles: rt.projectPromptFiles,
+				KeyBindings:        keyBindings,
+				Runner:             rt.agent,
+				Summarizer:         summarizer,
+				MaxTokens:          defaultRunMaxTokens,
+				Temperature:        rt.temperature,
+				ThinkingBudget:     rt.thinkingBudget,
+				CacheSystem:        rt.cacheSystem,
+				PersistThinking:    cfg.Session.PersistThinking,
+				Tools:              buildToolSpecs(rt.tools),
+				SessionStore:       store,
+
+				ContextWindows:          contextGuard.Windows,
+				ContextWarnRatio:        contextGuard.WarnRatio,
+				CompactOnContextWarning: contextGuard.CompactOnWarning,
+				DedupeQueue:             cfg.Agent.DedupeQueue,
+				AutoNameSessions:        cfg.Session.AutoName,
+				Theme:                   &theme,
 			})
 
-			program := tea.NewProgram(app, tea.WithAltScreen())
+			program := tea.NewProgram(app, tea.WithAltScreen(), tea.WithMouseCellMotion())
 			if _, err := program.Run(); err != nil {
 				return fmt.Errorf("run tui: %w", err)
 			}
@@ -96,10 +154,208 @@ func newRootCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&configPath, "config", "", "Path to config file")
+	cmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to config file")
+	cmd.Flags().BoolVar(&approve, "approve", false, "Require confirmation before running tools not in agent.auto_approve")
+	cmd.Flags().StringVar(&resumeID, "resume", "", "Open the session with this ID instead of starting a new one")
+	cmd.Flags().BoolVar(&continueLatest, "continue", false, "Open the most recently updated session")
+	cmd.Flags().BoolVar(&once, "once", false, "Run the prompt read from stdin without the TUI and print the answer")
+	cmd.Flags().StringVar(&promptFile, "prompt-file", "", "Run the prompt in this file (- for stdin) without the TUI and print the answer")
+	cmd.MarkFlagsMutuallyExclusive("resume", "continue")
+	cmd.MarkFlagsMutuallyExclusive("once", "resume", "continue", "approve")
+	cmd.MarkFlagsMutuallyExclusive("prompt-file", "resume", "continue", "approve")
+	cmd.AddCommand(newRunCmd(&configPath))
+	cmd.AddCommand(newModelsCmd(&configPath))
 	return cmd
 }
 
+// agentRuntime bundles the pieces shared by the TUI and headless entry points.
+type agentRuntime struct {
+	provider      llm.Provider
+	model         string
+	workspaceRoot string
+	temperature   *float64
+	// thinkingBudget enables extended thinking when positive.
+	thinkingBudget int
+	// cacheSystem marks the system prompt and tools as cacheable.
+	cacheSystem bool
+	tools       []agenttool.Tool
+	agent       *agent.Agent
+	// projectPromptFiles are the candidate project instruction files.
+	projectPromptFiles []string
+}
+
+// approvalMode selects which tool calls pause for the user's approval.
+type approvalMode int
+
+const (
+	// approvalNone never pauses; headless runs have nobody to answer.
+	approvalNone approvalMode = iota
+	// approvalConfirmed pauses only for tools configured to always confirm,
+	// such as bash with agent.bash.confirm.
+	approvalConfirmed
+	// approvalAll also pauses for tools not in agent.auto_approve (--approve).
+	approvalAll
+)
+
+// bashConfirmUnavailable is the tool result for bash in runs that cannot
+// confirm it.
+const bashConfirmUnavailable = "bash requires interactive confirmation (agent.bash.confirm) and this run cannot ask for it"
+
+func buildAgentRuntime(cfg config.Config, approval approvalMode) (agentRuntime, error) {
+	provider, model, err := buildProviderFromConfig(cfg)
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("build provider: %w", err)
+	}
+	if cfg.Provider.ValidateModel {
+		warnUnknownModel(context.Background(), provider, model, os.Stderr)
+	}
+	provider, err = wrapDebugLog(cfg, provider)
+	if err != nil {
+		return agentRuntime{}, err
+	}
+
+	toolRetry, err := cfg.ToolRetrySettings()
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("resolve tool retry settings: %w", err)
+	}
+	toolTimeout, err := cfg.ToolTimeoutSetting()
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("resolve tool timeout: %w", err)
+	}
+	streamIdleTimeout, err := cfg.StreamIdleTimeoutSetting()
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("resolve stream idle timeout: %w", err)
+	}
+	temperature, err := cfg.TemperatureSetting()
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("resolve temperature: %w", err)
+	}
+	workspaceRoot, err := cfg.WorkspaceRoot()
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("resolve workspace root: %w", err)
+	}
+	externalTools, err := cfg.ExternalToolSettings()
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("resolve external tools: %w", err)
+	}
+	tools, err := buildTools(workspaceRoot, agenttool.BashOptions{
+		Deny:  cfg.Agent.Bash.Deny,
+		Allow: cfg.Agent.Bash.Allow,
+	}, agenttool.EditOptions{
+		ExactMatch: cfg.Agent.Edit.ExactMatch,
+	}, externalTools)
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("build tools: %w", err)
+	}
+	fetch, err := cfg.FetchSettings()
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("resolve fetch settings: %w", err)
+	}
+	if fetch.Enabled {
+		tools = append(tools, agenttool.NewFetchToolWithOptions(agenttool.FetchOptions{
+			Timeout:  fetch.Timeout,
+			MaxBytes: fetch.MaxBytes,
+		}))
+	}
+	registry, err := buildToolRegistry(tools, llm.RetryPolicy{
+		MaxRetries: toolRetry.MaxRetries,
+		BaseDelay:  toolRetry.BaseDelay,
+		MaxDelay:   toolRetry.MaxDelay,
+	})
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("build tool registry: %w", err)
+	}
+	toolConcurrency, err := cfg.ToolConcurrencySettings()
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("resolve tool concurrency: %w", err)
+	}
+	registry.SetConcurrencyLimits(toolConcurrency.Max, toolConcurrency.ByTool)
+
+	toolResults, err := cfg.ToolResultSettings()
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("resolve tool result settings: %w", err)
+	}
+	truncationByTool := make(map[string]agent.Truncation, len(toolResults.ByTool))
+	for name, mode := range toolResults.ByTool {
+		truncationByTool[name] = agent.Truncation(mode)
+	}
+
+	// Commands that need confirming are refused when nobody can confirm them.
+	var confirmTools []string
+	var denyTools map[string]string
+	if cfg.Agent.Bash.Confirm {
+		if approval == approvalNone {
+			denyTools = map[string]string{"bash": bashConfirmUnavailable}
+		} else {
+			confirmTools = append(confirmTools, "bash")
+		}
+	}
+
+	ag, err := agent.New(agent.Config{
+		Provider:                   provider,
+		ToolRegistry:               registry,
+		MaxTurns:                   cfg.Agent.MaxTurns,
+		RequireApproval:            approval == approvalAll,
+		AutoApprove:                cfg.Agent.AutoApprove,
+		ConfirmTools:               confirmTools,
+		DenyTools:                  denyTools,
+		ParallelTools:              cfg.Agent.ParallelTools,
+		ToolTimeout:                toolTimeout,
+		StreamIdleTimeout:          streamIdleTimeout,
+		MaxToolResultBytes:         toolResults.MaxBytes,
+		ToolResultTruncation:       agent.Truncation(toolResults.Truncation),
+		ToolResultTruncationByTool: truncationByTool,
+	})
+	if err != nil {
+		return agentRuntime{}, fmt.Errorf("create agent: %w", err)
+	}
+
+	return agentRuntime{
+		provider:           provider,
+		model:              model,
+		workspaceRoot:      workspaceRoot,
+		temperature:        temperature,
+		thinkingBudget:     cfg.Agent.ThinkingBudget,
+		cacheSystem:        cfg.Provider.PromptCache,
+		tools:              tools,
+		agent:              ag,
+		projectPromptFiles: cfg.ProjectPromptPaths(workspaceRoot),
+	}, nil
+}
+
+// providerName returns the configured provider, defaulting to anthropic.
+func providerName(cfg config.Config) string {
+	name := strings.ToLower(strings.TrimSpace(cfg.Provider.Default))
+	if name == "" {
+		return "anthropic"
+	}
+	return name
+}
+
+// wrapDebugLog wraps provider with request/event logging when debug.log_file
+// is set; otherwise provider is returned unchanged.
+func wrapDebugLog(cfg config.Config, provider llm.Provider) (llm.Provider, error) {
+	path, err := cfg.DebugLogFile()
+	if err != nil {
+		return nil, fmt.Errorf("resolve debug log file: %w", err)
+	}
+	if path == "" {
+		return provider, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create debug log dir: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open debug log file: %w", err)
+	}
+	return llm.NewDebugLogProvider(provider, file,
+		cfg.Provider.Anthropic.APIKey,
+		cfg.Provider.OpenAI.APIKey,
+		cfg.Provider.Gemini.APIKey,
+	), nil
+}
+
 func buildProviderFromConfig(cfg config.Config) (llm.Provider, string, error) {
 	switch strings.ToLower(strings.TrimSpace(cfg.Provider.Default)) {
 	case "", "anthropic":
@@ -122,14 +378,111 @@ func buildProviderFromConfig(cfg config.Config) (llm.Provider, string, error) {
 			},
 		})
 		return provider, settings.Model, nil
+	case "openai":
+		settings, err := cfg.OpenAISettings()
+		if err != nil {
+			return nil, "", fmt.Errorf("resolve openai settings: %w", err)
+		}
+		if strings.TrimSpace(settings.APIKey) == "" {
+			return nil, "", llm.ErrMissingAPIKey
+		}
+
+		provider := llm.NewOpenAIProvider(llm.OpenAIConfig{
+			APIKey:  settings.APIKey,
+			BaseURL: settings.BaseURL,
+			Retry: llm.RetryPolicy{
+				MaxRetries: settings.Retry.MaxRetries,
+				BaseDelay:  settings.Retry.BaseDelay,
+				MaxDelay:   settings.Retry.MaxDelay,
+			},
+		})
+		return provider, settings.Model, nil
+	case "gemini":
+		settings, err := cfg.GeminiSettings()
+		if err != nil {
+			return nil, "", fmt.Errorf("resolve gemini settings: %w", err)
+		}
+		if strings.TrimSpace(settings.APIKey) == "" {
+			return nil, "", llm.ErrMissingAPIKey
+		}
+
+		provider := llm.NewGeminiProvider(llm.GeminiConfig{
+			APIKey:  settings.APIKey,
+			BaseURL: settings.BaseURL,
+			Retry: llm.RetryPolicy{
+				MaxRetries: settings.Retry.MaxRetries,
+				BaseDelay:  settings.Retry.BaseDelay,
+				MaxDelay:   settings.Retry.MaxDelay,
+			},
+		})
+		return provider, settings.Model, nil
+	case "bedrock":
+		settings, err := cfg.BedrockSettings()
+		if err != nil {
+			return nil, "", fmt.Errorf("resolve bedrock settings: %w", err)
+		}
+
+		provider, err := llm.NewBedrockProvider(context.Background(), llm.BedrockConfig{
+			Region:  settings.Region,
+			Profile: settings.Profile,
+			BaseURL: settings.BaseURL,
+			Retry: llm.RetryPolicy{
+				MaxRetries: settings.Retry.MaxRetries,
+				BaseDelay:  settings.Retry.BaseDelay,
+				MaxDelay:   settings.Retry.MaxDelay,
+			},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		return provider, settings.Model, nil
 	default:
 		return nil, "", fmt.Errorf("%w: %s", errUnsupportedProvider, cfg.Provider.Default)
 	}
 }
 
-func buildToolRegistry() (*agenttool.Registry, error) {
+// buildTools returns the built-in tools, with bash configured by bashOpts,
+// followed by the configured external tools.
+func buildTools(workspaceRoot string, bashOpts agenttool.BashOptions, editOpts agenttool.EditOptions, external []config.ExternalToolSettings) ([]agenttool.Tool, error) {
+	bashOpts.WorkspaceRoot = workspaceRoot
+	bash, err := agenttool.NewBashToolWithOptions(bashOpts)
+	if err != nil {
+		return nil, err
+	}
+	editOpts.WorkspaceRoot = workspaceRoot
+	configured := map[string]agenttool.Tool{
+		bash.Name(): bash,
+		"edit":      agenttool.NewEditToolWithOptions(editOpts),
+		"multiedit": agenttool.NewMultiEditToolWithOptions(editOpts),
+	}
+
+	tools := builtinTools(workspaceRoot)
+	for i, tool := range tools {
+		if replacement, ok := configured[tool.Name()]; ok {
+			tools[i] = replacement
+		}
+	}
+	for _, settings := range external {
+		tool, err := agenttool.NewExternalTool(agenttool.ExternalToolSpec{
+			Name:          settings.Name,
+			Description:   settings.Description,
+			Schema:        settings.Schema,
+			Command:       settings.Command,
+			Timeout:       settings.Timeout,
+			WorkspaceRoot: workspaceRoot,
+		})
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+func buildToolRegistry(tools []agenttool.Tool, retry llm.RetryPolicy) (*agenttool.Registry, error) {
 	registry := agenttool.NewRegistry()
-	for _, tool := range builtinTools() {
+	registry.SetRetryPolicy(retry)
+	for _, tool := range tools {
 		if err := registry.Register(tool); err != nil {
 			return nil, fmt.Errorf("register %s: %w", tool.Name(), err)
 		}
@@ -137,10 +490,9 @@ func buildToolRegistry() (*agenttool.Registry, error) {
 	return registry, nil
 }
 
-func buildToolSpecs() []llm.ToolSpec {
-	builtin := builtinTools()
-	specs := make([]llm.ToolSpec, 0, len(builtin))
-	for _, tool := range builtin {
+func buildToolSpecs(tools []agenttool.Tool) []llm.ToolSpec {
+	specs := make([]llm.ToolSpec, 0, len(tools))
+	for _, tool := range tools {
 		schema := tool.Schema()
 		specs = append(specs, llm.ToolSpec{
 			Name:        tool.Name(),
@@ -151,6 +503,6 @@ func buildToolSpecs() []llm.ToolSpec {
 	return specs
 }
 
-func builtinTools() []agenttool.Tool {
-	return codingtool.NewCodingTools()
+func builtinTools(workspaceRoot string) []agenttool.Tool {
+	return codingtool.NewCodingToolsAt(workspaceRoot)
 }
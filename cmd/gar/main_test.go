@@ -1,9 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	agenttool "gar/internal/agent/tool"
 	"gar/internal/config"
 	"gar/internal/llm"
 )
@@ -33,11 +37,62 @@ func TestBuildProviderFromConfigAnthropic(t *testing.T) {
 	}
 }
 
-func TestBuildProviderFromConfigUnsupportedProvider(t *testing.T) {
+func TestBuildProviderFromConfigOpenAI(t *testing.T) {
 	t.Parallel()
 
 	cfg := config.Default()
 	cfg.Provider.Default = "openai"
+	cfg.Provider.OpenAI.APIKey = "test-key"
+	cfg.Provider.OpenAI.Model = "gpt-4o-mini"
+	cfg.Provider.OpenAI.BaseURL = "https://api.example/v1"
+
+	provider, model, err := buildProviderFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("buildProviderFromConfig() error = %v", err)
+	}
+	if provider == nil {
+		t.Fatalf("expected provider, got nil")
+	}
+	if model != "gpt-4o-mini" {
+		t.Fatalf("model = %q, want %q", model, "gpt-4o-mini")
+	}
+
+	cfg.Provider.OpenAI.APIKey = ""
+	if _, _, err := buildProviderFromConfig(cfg); !errors.Is(err, llm.ErrMissingAPIKey) {
+		t.Fatalf("expected llm.ErrMissingAPIKey, got %v", err)
+	}
+}
+
+func TestBuildProviderFromConfigGemini(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Provider.Default = "gemini"
+	cfg.Provider.Gemini.APIKey = "test-key"
+	cfg.Provider.Gemini.Model = "gemini-2.5-pro"
+
+	provider, model, err := buildProviderFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("buildProviderFromConfig() error = %v", err)
+	}
+	if _, ok := provider.(*llm.GeminiProvider); !ok {
+		t.Fatalf("provider = %T, want *llm.GeminiProvider", provider)
+	}
+	if model != "gemini-2.5-pro" {
+		t.Fatalf("model = %q, want %q", model, "gemini-2.5-pro")
+	}
+
+	cfg.Provider.Gemini.APIKey = ""
+	if _, _, err := buildProviderFromConfig(cfg); !errors.Is(err, llm.ErrMissingAPIKey) {
+		t.Fatalf("expected llm.ErrMissingAPIKey, got %v", err)
+	}
+}
+
+func TestBuildProviderFromConfigUnsupportedProvider(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Provider.Default = "ollama"
 
 	_, _, err := buildProviderFromConfig(cfg)
 	if !errors.Is(err, errUnsupportedProvider) {
@@ -61,14 +116,81 @@ func TestBuildProviderFromConfigMissingAPIKeyFailsFast(t *testing.T) {
 func TestBuildToolRegistryRegistersBuiltins(t *testing.T) {
 	t.Parallel()
 
-	registry, err := buildToolRegistry()
+	tools, err := buildTools(t.TempDir(), agenttool.BashOptions{}, agenttool.EditOptions{}, nil)
+	if err != nil {
+		t.Fatalf("buildTools() error = %v", err)
+	}
+	registry, err := buildToolRegistry(tools, llm.RetryPolicy{})
 	if err != nil {
 		t.Fatalf("buildToolRegistry() error = %v", err)
 	}
 
-	for _, name := range []string{"read", "write", "edit", "bash"} {
+	for _, name := range []string{"read", "write", "edit", "multiedit", "apply_patch", "move", "delete", "bash", "ls", "tree", "git", "think"} {
 		if _, err := registry.Get(name); err != nil {
 			t.Fatalf("registry.Get(%q) error = %v", name, err)
 		}
 	}
 }
+
+func TestBuildToolsAppendsExternalTools(t *testing.T) {
+	t.Parallel()
+
+	tools, err := buildTools(t.TempDir(), agenttool.BashOptions{}, agenttool.EditOptions{}, []config.ExternalToolSettings{{
+		Name:        "lint",
+		Description: "Run the project linter",
+		Schema:      json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"}}}`),
+		Command:     []string{"golangci-lint-json"},
+	}})
+	if err != nil {
+		t.Fatalf("buildTools() error = %v", err)
+	}
+	specs := buildToolSpecs(tools)
+	last := specs[len(specs)-1]
+	if last.Name != "lint" || last.Description != "Run the project linter" || !strings.Contains(string(last.Schema), `"path"`) {
+		t.Fatalf("last tool spec = %#v, want external lint tool", last)
+	}
+
+	registry, err := buildToolRegistry(tools, llm.RetryPolicy{})
+	if err != nil {
+		t.Fatalf("buildToolRegistry() error = %v", err)
+	}
+	if _, err := registry.Get("lint"); err != nil {
+		t.Fatalf("registry.Get(lint) error = %v", err)
+	}
+
+	duplicate, err := buildTools(t.TempDir(), agenttool.BashOptions{}, agenttool.EditOptions{}, []config.ExternalToolSettings{{Name: "read", Command: []string{"cat"}}})
+	if err != nil {
+		t.Fatalf("buildTools(duplicate) error = %v", err)
+	}
+	if _, err := buildToolRegistry(duplicate, llm.RetryPolicy{}); !errors.Is(err, agenttool.ErrToolAlreadyRegistered) {
+		t.Fatalf("buildToolRegistry(duplicate) error = %v, want ErrToolAlreadyRegistered", err)
+	}
+}
+
+func TestBuildProviderFromConfigBedrock(t *testing.T) {
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	cfg := config.Default()
+	cfg.Provider.Default = "bedrock"
+	cfg.Provider.Bedrock.Region = "us-east-1"
+	cfg.Provider.Bedrock.Model = "anthropic.claude-3-5-sonnet-20240620-v1:0"
+
+	provider, model, err := buildProviderFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("buildProviderFromConfig() error = %v", err)
+	}
+	if provider == nil {
+		t.Fatalf("expected provider, got nil")
+	}
+	if model != "anthropic.claude-3-5-sonnet-20240620-v1:0" {
+		t.Fatalf("model = %q, want bedrock model id", model)
+	}
+
+	cfg.Provider.Bedrock.Region = ""
+	if _, _, err := buildProviderFromConfig(cfg); !errors.Is(err, llm.ErrBedrockRegionRequired) {
+		t.Fatalf("expected llm.ErrBedrockRegionRequired, got %v", err)
+	}
+}
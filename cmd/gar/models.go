@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gar/internal/config"
+	"gar/internal/llm"
+
+	"github.com/spf13/cobra"
+)
+
+// modelCheckTimeout bounds the startup lookup behind provider.validate_model.
+const modelCheckTimeout = 5 * time.Second
+
+func newModelsCmd(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "models",
+		Short: "List the models the configured provider serves",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(config.LoadOptions{Path: strings.TrimSpace(*configPath)})
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			provider, model, err := buildProviderFromConfig(cfg)
+			if err != nil {
+				return fmt.Errorf("build provider: %w", err)
+			}
+			lister, ok := provider.(llm.ModelLister)
+			if !ok {
+				return fmt.Errorf("provider %s does not support listing models", providerName(cfg))
+			}
+			models, err := lister.ListModels(cmd.Context())
+			if err != nil {
+				return err
+			}
+			contextGuard, err := cfg.ContextGuardSettings()
+			if err != nil {
+				return fmt.Errorf("resolve context guard: %w", err)
+			}
+			return printModels(cmd.OutOrStdout(), models, model, contextGuard.Windows)
+		},
+	}
+}
+
+// printModels writes one row per model, marking the configured model and
+// filling in context windows the provider did not report from windows.
+func printModels(w io.Writer, models []llm.ModelInfo, configured string, windows map[string]int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "\tMODEL\tNAME\tCONTEXT")
+	for _, model := range models {
+		marker := ""
+		if model.ID == configured {
+			marker = "*"
+		}
+		window := model.ContextWindow
+		if window == 0 {
+			window = contextWindowFor(windows, model.ID)
+		}
+		size := "-"
+		if window > 0 {
+			size = fmt.Sprintf("%d", window)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", marker, model.ID, model.DisplayName, size)
+	}
+	return tw.Flush()
+}
+
+// contextWindowFor returns the window of the longest prefix in windows that
+// matches model, or zero.
+func contextWindowFor(windows map[string]int, model string) int {
+	window, matched := 0, 0
+	for prefix, size := range windows {
+		if len(prefix) > matched && strings.HasPrefix(model, prefix) {
+			window, matched = size, len(prefix)
+		}
+	}
+	return window
+}
+
+// warnUnknownModel writes a warning to w when provider lists its models and
+// model is not among them. Listing failures are reported but never fatal.
+func warnUnknownModel(ctx context.Context, provider llm.Provider, model string, w io.Writer) {
+	lister, ok := provider.(llm.ModelLister)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, modelCheckTimeout)
+	defer cancel()
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "gar: warning: could not check model %q: %v\n", model, err)
+		return
+	}
+	if !slices.ContainsFunc(models, func(info llm.ModelInfo) bool { return info.ID == model }) {
+		_, _ = fmt.Fprintf(w, "gar: warning: model %q is not in the provider's model list; run `gar models` to see available models\n", model)
+	}
+}
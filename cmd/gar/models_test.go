@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gar/internal/llm"
+)
+
+type listingProvider struct {
+	scriptedProvider
+	models []llm.ModelInfo
+	err    error
+}
+
+func (p *listingProvider) ListModels(ctx context.Context) ([]llm.ModelInfo, error) {
+	return p.models, p.err
+}
+
+func TestPrintModelsMarksConfiguredModelAndWindows(t *testing.T) {
+	t.Parallel()
+
+	models := []llm.ModelInfo{
+		{ID: "claude-sonnet-4-5", DisplayName: "Claude Sonnet 4.5"},
+		{ID: "claude-opus-4-1", DisplayName: "Claude Opus 4.1", ContextWindow: 500000},
+		{ID: "mystery-model", DisplayName: "Mystery"},
+	}
+	var buf bytes.Buffer
+	if err := printModels(&buf, models, "claude-sonnet-4-5", map[string]int{"claude-": 200000, "claude-sonnet-4": 1000000}); err != nil {
+		t.Fatalf("printModels() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("printModels() = %q, want header and three rows", buf.String())
+	}
+	for i, want := range [][]string{
+		{"*", "claude-sonnet-4-5", "1000000"},
+		{"claude-opus-4-1", "500000"},
+		{"mystery-model", "-"},
+	} {
+		fields := strings.Fields(lines[i+1])
+		if fields[0] != want[0] || fields[len(fields)-1] != want[len(want)-1] {
+			t.Fatalf("row %d = %q, want %v", i, lines[i+1], want)
+		}
+	}
+}
+
+func TestWarnUnknownModel(t *testing.T) {
+	t.Parallel()
+
+	provider := &listingProvider{models: []llm.ModelInfo{{ID: "claude-sonnet-4-5"}}}
+	var buf bytes.Buffer
+	warnUnknownModel(context.Background(), provider, "claude-sonnet-4-5", &buf)
+	if buf.Len() != 0 {
+		t.Fatalf("warning for listed model = %q, want none", buf.String())
+	}
+
+	warnUnknownModel(context.Background(), provider, "claude-sonet-4-5", &buf)
+	if !strings.Contains(buf.String(), `model "claude-sonet-4-5" is not in the provider's model list`) {
+		t.Fatalf("warning = %q, want unknown model warning", buf.String())
+	}
+
+	buf.Reset()
+	provider.err = errors.New("unauthorized")
+	warnUnknownModel(context.Background(), provider, "claude-sonnet-4-5", &buf)
+	if !strings.Contains(buf.String(), "could not check model") {
+		t.Fatalf("warning = %q, want list failure reported", buf.String())
+	}
+
+	buf.Reset()
+	warnUnknownModel(context.Background(), &scriptedProvider{}, "anything", &buf)
+	if buf.Len() != 0 {
+		t.Fatalf("warning for non-listing provider = %q, want none", buf.String())
+	}
+}
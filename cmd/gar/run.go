@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gar/internal/agent"
+	agentsession "gar/internal/agent/session"
+	"gar/internal/config"
+	"gar/internal/llm"
+
+	"github.com/spf13/cobra"
+)
+
+var errRunFailed = errors.New("run failed")
+
+// eventRunner starts one agent run; *agent.Agent satisfies it.
+type eventRunner interface {
+	Run(ctx context.Context, req *llm.Request) (<-chan llm.Event, error)
+}
+
+func newRunCmd(configPath *string) *cobra.Command {
+	var prompt string
+	var jsonOutput bool
+	var stopSequences []string
+
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run one prompt headlessly and print the result",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(prompt) == "" {
+				return errors.New("--prompt is required")
+			}
+
+			cfg, err := config.Load(config.LoadOptions{Path: strings.TrimSpace(*configPath)})
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			// Nobody is around to answer approval prompts in a headless run.
+			rt, err := buildAgentRuntime(cfg, approvalNone)
+			if err != nil {
+				return err
+			}
+
+			req := newRunRequest(rt, prompt)
+			req.StopSequences = stopSequences
+			return runHeadless(cmd.Context(), rt.agent, req, jsonOutput, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&prompt, "prompt", "", "Prompt to send")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the final text, tool calls, usage, and stop reason as JSON")
+	cmd.Flags().StringArrayVar(&stopSequences, "stop", nil, "Stop generating when the model outputs this sequence (repeatable)")
+	return cmd
+}
+
+// newRunRequest builds the single-prompt request used by headless runs.
+func newRunRequest(rt agentRuntime, prompt string) *llm.Request {
+	return &llm.Request{
+		Model:          rt.model,
+		System:         agentsession.ReadProjectPrompt(rt.projectPromptFiles),
+		MaxTokens:      defaultRunMaxTokens,
+		Temperature:    rt.temperature,
+		ThinkingBudget: rt.thinkingBudget,
+		CacheSystem:    rt.cacheSystem,
+		Tools:          buildToolSpecs(rt.tools),
+		Messages: []llm.Message{{
+			Role:    llm.RoleUser,
+			Content: []llm.ContentBlock{{Type: llm.ContentTypeText, Text: prompt}},
+		}},
+	}
+}
+
+// readPrompt reads a batch-mode prompt from path, or from stdin when path
+// is empty or "-".
+func readPrompt(path string, stdin io.Reader) (string, error) {
+	var data []byte
+	var err error
+	if path == "" || path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read prompt: %w", err)
+	}
+	prompt := strings.TrimSpace(string(data))
+	if prompt == "" {
+		return "", errors.New("prompt is empty")
+	}
+	return prompt, nil
+}
+
+// runOnce runs the prompt read from promptFile or stdin and prints the
+// plain-text answer, for use in shell pipelines.
+func runOnce(ctx context.Context, rt agentRuntime, promptFile string, stdin io.Reader, w io.Writer) error {
+	prompt, err := readPrompt(promptFile, stdin)
+	if err != nil {
+		return err
+	}
+	return runHeadless(ctx, rt.agent, newRunRequest(rt, prompt), false, w)
+}
+
+// runHeadless drives one run to completion and writes its outcome to w.
+// It returns errRunFailed when the run stops with StopReasonError so the
+// process exits non-zero after the output has been written.
+func runHeadless(ctx context.Context, runner eventRunner, req *llm.Request, jsonOutput bool, w io.Writer) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	stream, err := runner.Run(ctx, req)
+	if err != nil {
+		return fmt.Errorf("start run: %w", err)
+	}
+	out := agent.CollectRun(stream)
+
+	if jsonOutput {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(out); err != nil {
+			return fmt.Errorf("write json output: %w", err)
+		}
+	} else if out.Text != "" {
+		if _, err := fmt.Fprintln(w, out.Text); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+	}
+
+	if out.StopReason == llm.StopReasonError {
+		if out.Error != "" {
+			return fmt.Errorf("%w: %s", errRunFailed, out.Error)
+		}
+		return errRunFailed
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gar/internal/agent"
+	agenttool "gar/internal/agent/tool"
+	"gar/internal/llm"
+)
+
+// scriptedProvider replays one event script per Stream call.
+type scriptedProvider struct {
+	mu       sync.Mutex
+	scripts  [][]llm.Event
+	requests []*llm.Request
+}
+
+func (p *scriptedProvider) Stream(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+	_ = ctx
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	out := make(chan llm.Event, 8)
+	if len(p.scripts) > 0 {
+		for _, ev := range p.scripts[0] {
+			out <- ev
+		}
+		p.scripts = p.scripts[1:]
+	}
+	close(out)
+	return out, nil
+}
+
+func TestRunHeadlessPrintsJSONOutput(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "note.txt"), []byte("hello from file"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	tools, err := buildTools(workspace, agenttool.BashOptions{}, agenttool.EditOptions{}, nil)
+	if err != nil {
+		t.Fatalf("buildTools() error = %v", err)
+	}
+	registry, err := buildToolRegistry(tools, llm.RetryPolicy{})
+	if err != nil {
+		t.Fatalf("buildToolRegistry() error = %v", err)
+	}
+
+	call := llm.ToolCall{ID: "call_1", Name: "read", Arguments: json.RawMessage(`{"path":"note.txt"}`)}
+	provider := &scriptedProvider{scripts: [][]llm.Event{
+		{
+			{Type: llm.EventTextDelta, TextDelta: "Let me look."},
+			{Type: llm.EventToolCallEnd, ToolCall: &call},
+			{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonToolUse, Usage: llm.Usage{InputTokens: 10, OutputTokens: 2}}},
+		},
+		{
+			{Type: llm.EventTextDelta, TextDelta: "The file says hello."},
+			{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop, Usage: llm.Usage{InputTokens: 20, OutputTokens: 5}}},
+		},
+	}}
+	ag, err := agent.New(agent.Config{Provider: provider, ToolRegistry: registry, MaxTurns: 4})
+	if err != nil {
+		t.Fatalf("agent.New() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	req := &llm.Request{Model: "test", Messages: []llm.Message{{Role: llm.RoleUser, Content: []llm.ContentBlock{{Type: llm.ContentTypeText, Text: "read note.txt"}}}}}
+	if err := runHeadless(context.Background(), ag, req, true, &buf); err != nil {
+		t.Fatalf("runHeadless() error = %v", err)
+	}
+
+	var got struct {
+		Text      string `json:"text"`
+		ToolCalls []struct {
+			ID        string         `json:"id"`
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+			Result    string         `json:"result"`
+			IsError   bool           `json:"is_error"`
+		} `json:"tool_calls"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+		StopReason string `json:"stop_reason"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal(%q) error = %v", buf.String(), err)
+	}
+	if got.Text != "The file says hello." || got.StopReason != "stop" {
+		t.Fatalf("text=%q stop_reason=%q, want final answer and stop", got.Text, got.StopReason)
+	}
+	if len(got.ToolCalls) != 1 {
+		t.Fatalf("tool_calls = %+v, want one call", got.ToolCalls)
+	}
+	tc := got.ToolCalls[0]
+	if tc.ID != "call_1" || tc.Name != "read" || tc.Arguments["path"] != "note.txt" || tc.Result != "hello from file" || tc.IsError {
+		t.Fatalf("tool_calls[0] = %+v, want read of note.txt with its content", tc)
+	}
+	if got.Usage.InputTokens != 30 || got.Usage.OutputTokens != 7 {
+		t.Fatalf("usage = %+v, want totals across turns", got.Usage)
+	}
+}
+
+func TestRunHeadlessFailsOnErrorStop(t *testing.T) {
+	t.Parallel()
+
+	provider := &scriptedProvider{scripts: [][]llm.Event{{
+		{Type: llm.EventError, Done: &llm.DonePayload{Reason: llm.StopReasonError}, Err: errors.New("overloaded")},
+	}}}
+	ag, err := agent.New(agent.Config{Provider: provider})
+	if err != nil {
+		t.Fatalf("agent.New() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = runHeadless(context.Background(), ag, &llm.Request{Model: "test"}, true, &buf)
+	if !errors.Is(err, errRunFailed) {
+		t.Fatalf("runHeadless() error = %v, want errRunFailed", err)
+	}
+	var got agent.RunResult
+	if jsonErr := json.Unmarshal(buf.Bytes(), &got); jsonErr != nil {
+		t.Fatalf("json.Unmarshal(%q) error = %v", buf.String(), jsonErr)
+	}
+	if got.StopReason != llm.StopReasonError || got.Error != "overloaded" {
+		t.Fatalf("output = %+v, want error stop reason and message", got)
+	}
+}
+
+func TestRunHeadlessReportsStopSequence(t *testing.T) {
+	t.Parallel()
+
+	provider := &scriptedProvider{scripts: [][]llm.Event{{
+		{Type: llm.EventTextDelta, TextDelta: "<answer>42"},
+		{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop, StopSequence: "</answer>"}},
+	}}}
+	ag, err := agent.New(agent.Config{Provider: provider})
+	if err != nil {
+		t.Fatalf("agent.New() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	req := &llm.Request{Model: "test", StopSequences: []string{"</answer>"}}
+	if err := runHeadless(context.Background(), ag, req, true, &buf); err != nil {
+		t.Fatalf("runHeadless() error = %v", err)
+	}
+	if len(provider.requests) != 1 || len(provider.requests[0].StopSequences) != 1 || provider.requests[0].StopSequences[0] != "</answer>" {
+		t.Fatalf("provider requests = %+v, want stop sequences passed through", provider.requests)
+	}
+	var got agent.RunResult
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal(%q) error = %v", buf.String(), err)
+	}
+	if got.Text != "<answer>42" || got.StopSequence != "</answer>" {
+		t.Fatalf("output = %+v, want answer stopped at </answer>", got)
+	}
+}
+
+func TestRunOncePrintsAnswerForPipedPrompt(t *testing.T) {
+	t.Parallel()
+
+	provider := &scriptedProvider{scripts: [][]llm.Event{{
+		{Type: llm.EventTextDelta, TextDelta: "Four."},
+		{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}},
+	}}}
+	ag, err := agent.New(agent.Config{Provider: provider})
+	if err != nil {
+		t.Fatalf("agent.New() error = %v", err)
+	}
+	rt := agentRuntime{provider: provider, model: "test", agent: ag}
+
+	var stdout bytes.Buffer
+	if err := runOnce(context.Background(), rt, "", strings.NewReader("what is 2+2?\n"), &stdout); err != nil {
+		t.Fatalf("runOnce() error = %v", err)
+	}
+	if stdout.String() != "Four.\n" {
+		t.Fatalf("stdout = %q, want the plain answer", stdout.String())
+	}
+	req := provider.requests[0]
+	if first := req.Messages[0]; first.Content[0].Text != "what is 2+2?" || req.Model != "test" {
+		t.Fatalf("request = %+v, want the piped prompt", req)
+	}
+
+	promptFile := filepath.Join(t.TempDir(), "prompt.txt")
+	if err := os.WriteFile(promptFile, []byte("  \n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := runOnce(context.Background(), rt, promptFile, strings.NewReader("ignored"), &stdout); err == nil || !strings.Contains(err.Error(), "prompt is empty") {
+		t.Fatalf("runOnce(empty file) error = %v, want prompt is empty", err)
+	}
+}
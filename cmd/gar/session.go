@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gar/internal/config"
+	sessionstore "gar/internal/session"
+)
+
+var (
+	errNoSessionToContinue = errors.New("no previous session to continue")
+	errSessionsDisabled    = errors.New("session persistence is disabled (session.enabled = false)")
+)
+
+// openSessionStore returns the store sessions are persisted to, or nil when
+// persistence is disabled. The directory is created on the first write.
+func openSessionStore(cfg config.Config, cwd string) (*sessionstore.Store, error) {
+	if !cfg.Session.Enabled {
+		return nil, nil
+	}
+	dir, err := cfg.SessionDir()
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		dir = sessionstore.DefaultDir(cwd)
+	}
+	store, err := sessionstore.NewStore(dir)
+	if err != nil {
+		return nil, fmt.Errorf("create session store: %w", err)
+	}
+	store.SetCompression(cfg.Session.Compress)
+	store.SetMaxEntries(cfg.Session.MaxEntries)
+	store.SetFsync(cfg.Session.Fsync)
+	return store, nil
+}
+
+// startupSession picks the session the TUI opens with: the one named by
+// --resume, the most recently updated one for --continue, or a fresh
+// timestamped ID.
+func startupSession(ctx context.Context, store *sessionstore.Store, resumeID string, continueLatest bool, now time.Time) (string, error) {
+	resumeID = strings.TrimSpace(resumeID)
+	if resumeID == "" && !continueLatest {
+		return now.UTC().Format("20060102-150405"), nil
+	}
+	if store == nil {
+		return "", errSessionsDisabled
+	}
+
+	infos, err := store.List(ctx)
+	if err != nil {
+		return "", fmt.Errorf("list sessions: %w", err)
+	}
+	if continueLatest {
+		if len(infos) == 0 {
+			return "", errNoSessionToContinue
+		}
+		return infos[0].ID, nil
+	}
+	for _, info := range infos {
+		if info.ID == resumeID {
+			return resumeID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", sessionstore.ErrSessionNotFound, resumeID)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gar/internal/agent"
+	agentsession "gar/internal/agent/session"
+	"gar/internal/config"
+	"gar/internal/llm"
+	sessionstore "gar/internal/session"
+)
+
+func newPopulatedStore(t *testing.T) *sessionstore.Store {
+	t.Helper()
+
+	dir := t.TempDir()
+	store, err := sessionstore.NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, id := range []string{"older", "newest", "middle"} {
+		if err := store.Append(context.Background(), id, sessionstore.Entry{ID: "e1", Type: "user", Content: id}); err != nil {
+			t.Fatalf("Append(%s) error = %v", id, err)
+		}
+		modTime := base.Add(time.Duration([]int{0, 2, 1}[i]) * time.Hour)
+		if err := os.Chtimes(filepath.Join(dir, id+".jsonl"), modTime, modTime); err != nil {
+			t.Fatalf("Chtimes(%s) error = %v", id, err)
+		}
+	}
+	return store
+}
+
+func TestStartupSessionContinuePicksNewest(t *testing.T) {
+	t.Parallel()
+
+	store := newPopulatedStore(t)
+	got, err := startupSession(context.Background(), store, "", true, time.Now())
+	if err != nil {
+		t.Fatalf("startupSession() error = %v", err)
+	}
+	if got != "newest" {
+		t.Fatalf("startupSession() = %q, want newest", got)
+	}
+}
+
+func TestStartupSessionResumeByID(t *testing.T) {
+	t.Parallel()
+
+	store := newPopulatedStore(t)
+	got, err := startupSession(context.Background(), store, " older ", false, time.Now())
+	if err != nil {
+		t.Fatalf("startupSession() error = %v", err)
+	}
+	if got != "older" {
+		t.Fatalf("startupSession() = %q, want older", got)
+	}
+
+	_, err = startupSession(context.Background(), store, "missing", false, time.Now())
+	if !errors.Is(err, sessionstore.ErrSessionNotFound) {
+		t.Fatalf("startupSession(missing) error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestStartupSessionDefaultsToNewID(t *testing.T) {
+	t.Parallel()
+
+	store := newPopulatedStore(t)
+	now := time.Date(2026, 5, 6, 7, 8, 9, 0, time.UTC)
+	got, err := startupSession(context.Background(), store, "", false, now)
+	if err != nil {
+		t.Fatalf("startupSession() error = %v", err)
+	}
+	if got != "20260506-070809" {
+		t.Fatalf("startupSession() = %q, want timestamp ID", got)
+	}
+
+	empty, err := sessionstore.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if _, err := startupSession(context.Background(), empty, "", true, now); !errors.Is(err, errNoSessionToContinue) {
+		t.Fatalf("startupSession(continue, empty) error = %v, want errNoSessionToContinue", err)
+	}
+}
+
+func TestOpenSessionStorePersistsRunToConfiguredDir(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "sessions")
+	cfg := config.Default()
+	cfg.Session.Dir = dir
+	store, err := openSessionStore(cfg, t.TempDir())
+	if err != nil {
+		t.Fatalf("openSessionStore() error = %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("Stat(session dir) error = %v, want directory created lazily", err)
+	}
+
+	provider := &scriptedProvider{scripts: [][]llm.Event{{
+		{Type: llm.EventTextDelta, TextDelta: "hi there"},
+		{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}},
+	}}}
+	runner, err := agent.New(agent.Config{Provider: provider, MaxTurns: 1})
+	if err != nil {
+		t.Fatalf("agent.New() error = %v", err)
+	}
+	session, err := agentsession.New(context.Background(), agentsession.Config{
+		Runner:    runner,
+		Store:     store,
+		SessionID: "run-1",
+		Model:     "test-model",
+		MaxTokens: 64,
+	})
+	if err != nil {
+		t.Fatalf("agentsession.New() error = %v", err)
+	}
+	stream, err := session.Submit(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	for ev := range stream {
+		if err := session.RecordEvent(context.Background(), ev); err != nil {
+			t.Fatalf("RecordEvent() error = %v", err)
+		}
+	}
+
+	entries, err := store.Load(context.Background(), "run-1")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Content != "hello" || entries[1].Content != "hi there" {
+		t.Fatalf("entries = %#v, want user and assistant turns", entries)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run-1.jsonl")); err != nil {
+		t.Fatalf("Stat(session file) error = %v", err)
+	}
+}
+
+func TestOpenSessionStoreDisabled(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Session.Enabled = false
+	store, err := openSessionStore(cfg, t.TempDir())
+	if err != nil || store != nil {
+		t.Fatalf("openSessionStore() = %v, %v; want nil store", store, err)
+	}
+	if _, err := startupSession(context.Background(), nil, "", true, time.Now()); !errors.Is(err, errSessionsDisabled) {
+		t.Fatalf("startupSession(continue, nil store) error = %v, want errSessionsDisabled", err)
+	}
+}
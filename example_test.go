@@ -0,0 +1,50 @@
+package gar_test
+
+import (
+	"context"
+	"fmt"
+
+	"gar"
+)
+
+// echoProvider answers every request by repeating the last user message.
+type echoProvider struct{}
+
+func (echoProvider) Stream(_ context.Context, req *gar.Request) (<-chan gar.Event, error) {
+	last := req.Messages[len(req.Messages)-1]
+	out := make(chan gar.Event, 2)
+	out <- gar.Event{Type: gar.EventTextDelta, TextDelta: "echo: " + last.Content[0].Text}
+	out <- gar.Event{Type: gar.EventDone, Done: &gar.DonePayload{Reason: gar.StopReasonStop}}
+	close(out)
+	return out, nil
+}
+
+func ExampleClient_Ask() {
+	client, err := gar.New(gar.Options{Provider: echoProvider{}, Model: "echo"})
+	if err != nil {
+		panic(err)
+	}
+	resp, err := client.Ask(context.Background(), "hello")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(resp.Text)
+	// Output: echo: hello
+}
+
+func ExampleClient_Stream() {
+	client, err := gar.New(gar.Options{Provider: echoProvider{}, Model: "echo"})
+	if err != nil {
+		panic(err)
+	}
+	stream, err := client.Stream(context.Background(), "hi")
+	if err != nil {
+		panic(err)
+	}
+	for ev := range stream {
+		if ev.Type == gar.EventTextDelta {
+			fmt.Println(ev.TextDelta)
+		}
+	}
+	// Output: echo: hi
+}
@@ -0,0 +1,288 @@
+// Package gar embeds the gar agent loop in other Go programs.
+//
+// A Client pairs an LLM provider with a tool set and keeps one conversation.
+// Each Ask or Stream call adds a user message to it, runs the model/tool
+// loop until the model stops, and records the reply so the next call
+// continues the same conversation:
+//
+//	client, err := gar.New(gar.Options{
+//		Provider: gar.NewAnthropicProvider(gar.AnthropicConfig{APIKey: os.Getenv("ANTHROPIC_API_KEY")}),
+//		Model:    "claude-sonnet-4-5",
+//		Tools:    gar.DefaultTools("."),
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	resp, err := client.Ask(ctx, "Summarize README.md")
+//
+// Set Options.SessionDir to persist the conversation as a JSONL session
+// that the gar CLI can resume.
+package gar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gar/internal/agent"
+	agentsession "gar/internal/agent/session"
+	agenttool "gar/internal/agent/tool"
+	codingtool "gar/internal/coding-agent/tool"
+	"gar/internal/llm"
+	sessionstore "gar/internal/session"
+)
+
+const defaultMaxTokens = 4096
+
+type (
+	// Provider streams model responses; see NewAnthropicProvider,
+	// NewOpenAIProvider, and NewGeminiProvider.
+	Provider = llm.Provider
+	// Request is one model request passed to Provider.Stream.
+	Request = llm.Request
+	// Event is one stream event emitted by Stream.
+	Event = llm.Event
+	// EventType identifies an Event variant.
+	EventType = llm.EventType
+	// DonePayload ends one model turn in an EventDone event.
+	DonePayload = llm.DonePayload
+	// Usage reports token counts and cost.
+	Usage = llm.Usage
+	// StopReason reports why the model stopped.
+	StopReason = llm.StopReason
+	// Tool is one capability the model can call.
+	Tool = agenttool.Tool
+	// ToolResult is the outcome of one Tool call.
+	ToolResult = agenttool.Result
+
+	// AnthropicConfig configures NewAnthropicProvider.
+	AnthropicConfig = llm.AnthropicConfig
+	// OpenAIConfig configures NewOpenAIProvider.
+	OpenAIConfig = llm.OpenAIConfig
+	// GeminiConfig configures NewGeminiProvider.
+	GeminiConfig = llm.GeminiConfig
+)
+
+// Event types and stop reasons most callers inspect.
+const (
+	EventTextDelta   = llm.EventTextDelta
+	EventToolCallEnd = llm.EventToolCallEnd
+	EventToolResult  = llm.EventToolResult
+	EventDone        = llm.EventDone
+	EventError       = llm.EventError
+
+	StopReasonStop    = llm.StopReasonStop
+	StopReasonToolUse = llm.StopReasonToolUse
+	StopReasonError   = llm.StopReasonError
+)
+
+var (
+	// ErrProviderRequired is returned by New without Options.Provider.
+	ErrProviderRequired = agent.ErrProviderRequired
+	// ErrModelRequired is returned by New without Options.Model.
+	ErrModelRequired = errors.New("model is required")
+	// ErrPromptRequired is returned by Ask and Stream for a blank prompt.
+	ErrPromptRequired = errors.New("prompt is required")
+	// ErrRunFailed wraps the error reported when a run stops with StopReasonError.
+	ErrRunFailed = errors.New("run failed")
+)
+
+// NewAnthropicProvider constructs the Anthropic Messages API provider.
+func NewAnthropicProvider(cfg AnthropicConfig) Provider { return llm.NewAnthropicProvider(cfg) }
+
+// NewOpenAIProvider constructs the OpenAI Chat Completions provider.
+func NewOpenAIProvider(cfg OpenAIConfig) Provider { return llm.NewOpenAIProvider(cfg) }
+
+// NewGeminiProvider constructs the Gemini provider.
+func NewGeminiProvider(cfg GeminiConfig) Provider { return llm.NewGeminiProvider(cfg) }
+
+// DefaultTools returns the coding tools the gar CLI uses (read, write, edit,
+// bash, and friends), sandboxed to workspaceRoot.
+func DefaultTools(workspaceRoot string) []Tool {
+	return codingtool.NewCodingToolsAt(workspaceRoot)
+}
+
+// Options configures New.
+type Options struct {
+	Provider Provider
+	Model    string
+	// Tools are offered to the model; nil runs without tools.
+	Tools        []Tool
+	SystemPrompt string
+	// MaxTokens caps each model response; zero uses 4096.
+	MaxTokens int
+	// MaxTurns bounds model/tool round trips per prompt; zero uses the agent default.
+	MaxTurns int
+	// ToolTimeout bounds each tool call; zero disables the limit.
+	ToolTimeout time.Duration
+
+	// SessionDir, when set, persists the conversation as JSONL in that
+	// directory. SessionID picks the session to create or continue; it
+	// defaults to a timestamp.
+	SessionDir string
+	SessionID  string
+}
+
+// ToolCall pairs one executed tool call with its result.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments json.RawMessage
+	Result    string
+	IsError   bool
+}
+
+// Response is the outcome of one Ask call.
+type Response struct {
+	// Text is the final assistant answer, without text from tool-use turns.
+	Text       string
+	ToolCalls  []ToolCall
+	Usage      Usage
+	StopReason StopReason
+}
+
+// Client runs prompts against one conversation. Calls are serialized: a
+// second Ask or Stream waits until the previous stream is drained.
+type Client struct {
+	session *agentsession.AgentSession
+	// running is held from the start of a run until its stream is drained.
+	running sync.Mutex
+}
+
+// New wires the provider, tools, and optional session store into a Client.
+func New(opts Options) (*Client, error) {
+	if opts.Provider == nil {
+		return nil, ErrProviderRequired
+	}
+	model := strings.TrimSpace(opts.Model)
+	if model == "" {
+		return nil, ErrModelRequired
+	}
+
+	registry := agenttool.NewRegistry()
+	specs := make([]llm.ToolSpec, 0, len(opts.Tools))
+	for _, tool := range opts.Tools {
+		if err := registry.Register(tool); err != nil {
+			return nil, fmt.Errorf("register %s: %w", tool.Name(), err)
+		}
+		specs = append(specs, llm.ToolSpec{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Schema:      tool.Schema(),
+		})
+	}
+	ag, err := agent.New(agent.Config{
+		Provider:     opts.Provider,
+		ToolRegistry: registry,
+		MaxTurns:     opts.MaxTurns,
+		ToolTimeout:  opts.ToolTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create agent: %w", err)
+	}
+
+	var store *sessionstore.Store
+	if dir := strings.TrimSpace(opts.SessionDir); dir != "" {
+		if store, err = sessionstore.NewStore(dir); err != nil {
+			return nil, fmt.Errorf("open session store: %w", err)
+		}
+	}
+	sessionID := strings.TrimSpace(opts.SessionID)
+	if sessionID == "" {
+		sessionID = time.Now().UTC().Format("20060102-150405")
+	}
+	maxTokens := opts.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	ctx := context.Background()
+	session, err := agentsession.New(ctx, agentsession.Config{
+		Runner:    ag,
+		Store:     store,
+		SessionID: sessionID,
+		Model:     model,
+		MaxTokens: maxTokens,
+		Tools:     specs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create session: %w", err)
+	}
+	if prompt := strings.TrimSpace(opts.SystemPrompt); prompt != "" {
+		if err := session.SetSystemPrompt(ctx, prompt); err != nil {
+			return nil, fmt.Errorf("set system prompt: %w", err)
+		}
+	}
+	return &Client{session: session}, nil
+}
+
+// SessionID returns the id of the client's conversation.
+func (c *Client) SessionID() string {
+	return c.session.SessionID()
+}
+
+// Stream sends prompt and returns the run's events. The channel closes when
+// the run ends. Callers must drain it, or cancel ctx, before the next call.
+func (c *Client) Stream(ctx context.Context, prompt string) (<-chan Event, error) {
+	if strings.TrimSpace(prompt) == "" {
+		return nil, ErrPromptRequired
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	c.running.Lock()
+	stream, err := c.session.Submit(ctx, prompt)
+	if err != nil {
+		c.running.Unlock()
+		return nil, err
+	}
+
+	out := make(chan Event, 16)
+	go func() {
+		defer c.running.Unlock()
+		defer close(out)
+		for ev := range stream {
+			// Recording errors only affect persistence; the caller still
+			// gets the event and the run's own outcome.
+			_ = c.session.RecordEvent(context.Background(), ev)
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				// The caller stopped reading; keep draining so the run is recorded.
+			}
+		}
+		_ = c.session.Finalize(context.Background())
+	}()
+	return out, nil
+}
+
+// Ask sends prompt, waits for the run to finish, and returns the final
+// answer. A run that stops with StopReasonError returns its partial
+// Response together with an error wrapping ErrRunFailed.
+func (c *Client) Ask(ctx context.Context, prompt string) (Response, error) {
+	stream, err := c.Stream(ctx, prompt)
+	if err != nil {
+		return Response{}, err
+	}
+	result := agent.CollectRun(stream)
+	resp := Response{
+		Text:       result.Text,
+		Usage:      result.Usage,
+		StopReason: result.StopReason,
+	}
+	for _, call := range result.ToolCalls {
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall(call))
+	}
+	if resp.StopReason == StopReasonError {
+		if result.Error != "" {
+			return resp, fmt.Errorf("%w: %s", ErrRunFailed, result.Error)
+		}
+		return resp, ErrRunFailed
+	}
+	return resp, nil
+}
@@ -0,0 +1,162 @@
+package gar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gar/internal/llm"
+)
+
+// scriptedProvider replays one event script per Stream call and records requests.
+type scriptedProvider struct {
+	mu       sync.Mutex
+	scripts  [][]llm.Event
+	requests []*llm.Request
+}
+
+func (p *scriptedProvider) Stream(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+	_ = ctx
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// The agent loop keeps appending to req.Messages, so keep a snapshot.
+	snapshot := *req
+	snapshot.Messages = append([]llm.Message(nil), req.Messages...)
+	p.requests = append(p.requests, &snapshot)
+	out := make(chan llm.Event, 8)
+	if len(p.scripts) > 0 {
+		for _, ev := range p.scripts[0] {
+			out <- ev
+		}
+		p.scripts = p.scripts[1:]
+	}
+	close(out)
+	return out, nil
+}
+
+func textReply(text string, usage llm.Usage) []llm.Event {
+	return []llm.Event{
+		{Type: llm.EventTextDelta, TextDelta: text},
+		{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop, Usage: usage}},
+	}
+}
+
+func TestClientAskRunsToolsAndContinuesConversation(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "note.txt"), []byte("hello from file"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	call := llm.ToolCall{ID: "call_1", Name: "read", Arguments: json.RawMessage(`{"path":"note.txt"}`)}
+	provider := &scriptedProvider{scripts: [][]llm.Event{
+		{
+			{Type: llm.EventTextDelta, TextDelta: "Let me look."},
+			{Type: llm.EventToolCallEnd, ToolCall: &call},
+			{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonToolUse, Usage: llm.Usage{InputTokens: 10, OutputTokens: 2}}},
+		},
+		textReply("The file says hello.", llm.Usage{InputTokens: 20, OutputTokens: 5}),
+		textReply("Second answer.", llm.Usage{InputTokens: 30, OutputTokens: 1}),
+	}}
+
+	sessionDir := t.TempDir()
+	client, err := New(Options{
+		Provider:     provider,
+		Model:        "test-model",
+		Tools:        DefaultTools(workspace),
+		SystemPrompt: "Be brief.",
+		SessionDir:   sessionDir,
+		SessionID:    "embedded",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	resp, err := client.Ask(context.Background(), "read note.txt")
+	if err != nil {
+		t.Fatalf("Ask() error = %v", err)
+	}
+	if resp.Text != "The file says hello." || resp.StopReason != StopReasonStop {
+		t.Fatalf("Ask() = %#v, want final answer only", resp)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "read" || !strings.Contains(resp.ToolCalls[0].Result, "hello from file") {
+		t.Fatalf("ToolCalls = %#v, want read result", resp.ToolCalls)
+	}
+	if resp.Usage.InputTokens != 30 || resp.Usage.OutputTokens != 7 {
+		t.Fatalf("Usage = %#v, want summed usage", resp.Usage)
+	}
+
+	if _, err := client.Ask(context.Background(), "and again?"); err != nil {
+		t.Fatalf("second Ask() error = %v", err)
+	}
+	last := provider.requests[len(provider.requests)-1]
+	if last.Model != "test-model" || last.System != "Be brief." || len(last.Tools) == 0 {
+		t.Fatalf("request = model %q system %q tools %d, want configured values", last.Model, last.System, len(last.Tools))
+	}
+	first, final := last.Messages[0], last.Messages[len(last.Messages)-1]
+	if first.Content[0].Text != "read note.txt" || final.Content[0].Text != "and again?" {
+		t.Fatalf("request messages = %#v, want earlier turns replayed before the new prompt", last.Messages)
+	}
+	if _, err := os.Stat(filepath.Join(sessionDir, "embedded.jsonl")); err != nil {
+		t.Fatalf("Stat(session file) error = %v, want persisted session", err)
+	}
+}
+
+func TestClientStreamForwardsEvents(t *testing.T) {
+	t.Parallel()
+
+	provider := &scriptedProvider{scripts: [][]llm.Event{textReply("streamed", llm.Usage{})}}
+	client, err := New(Options{Provider: provider, Model: "test-model"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	stream, err := client.Stream(context.Background(), "hi")
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var text strings.Builder
+	var done bool
+	for ev := range stream {
+		switch ev.Type {
+		case EventTextDelta:
+			text.WriteString(ev.TextDelta)
+		case EventDone:
+			done = true
+		}
+	}
+	if text.String() != "streamed" || !done {
+		t.Fatalf("stream text = %q, done = %v; want text and done event", text.String(), done)
+	}
+}
+
+func TestClientValidatesInputs(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(Options{Model: "m"}); !errors.Is(err, ErrProviderRequired) {
+		t.Fatalf("New(no provider) error = %v, want ErrProviderRequired", err)
+	}
+	if _, err := New(Options{Provider: &scriptedProvider{}}); !errors.Is(err, ErrModelRequired) {
+		t.Fatalf("New(no model) error = %v, want ErrModelRequired", err)
+	}
+
+	client, err := New(Options{Provider: &scriptedProvider{scripts: [][]llm.Event{{
+		{Type: llm.EventError, Err: errors.New("boom")},
+	}}}, Model: "m"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := client.Stream(context.Background(), "  "); !errors.Is(err, ErrPromptRequired) {
+		t.Fatalf("Stream(blank) error = %v, want ErrPromptRequired", err)
+	}
+	resp, err := client.Ask(context.Background(), "fail")
+	if !errors.Is(err, ErrRunFailed) || !strings.Contains(err.Error(), "boom") || resp.StopReason != StopReasonError {
+		t.Fatalf("Ask() = %#v, %v; want ErrRunFailed with provider error", resp, err)
+	}
+}
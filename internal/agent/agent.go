@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	agenttool "gar/internal/agent/tool"
 	"gar/internal/llm"
@@ -13,12 +15,8 @@ import (
 
 const defaultMaxTurns = 50
 
-const (
-	maxToolResultContentLen = 10_000
-	toolResultHeadLen       = 4_000
-	toolResultTailLen       = 4_000
-	toolResultTruncateMark  = "\n...[truncated]...\n"
-)
+// parallelToolWorkers bounds concurrent tool executions when Config.ParallelTools is set.
+const parallelToolWorkers = 4
 
 // QueueMode controls how queued messages are dequeued between turns.
 type QueueMode string
@@ -43,8 +41,19 @@ var (
 	ErrInvalidQueueMode = errors.New("invalid queue mode")
 	// ErrNoMessagesToContinue indicates Continue requires an existing conversation tail.
 	ErrNoMessagesToContinue = errors.New("no messages to continue from")
+	// ErrApprovalNotPending indicates Approve/Deny for a tool call that is not awaiting a decision.
+	ErrApprovalNotPending = errors.New("tool call is not awaiting approval")
 	// ErrContinueFromAssistantTail indicates assistant-tail continue requires queued user input.
 	ErrContinueFromAssistantTail = errors.New("cannot continue from assistant tail without queued messages")
+	// ErrToolTimeout indicates a tool call exceeded Config.ToolTimeout.
+	ErrToolTimeout = errors.New("tool timed out")
+	// ErrStreamIdleTimeout indicates the provider stream delivered no event
+	// within Config.StreamIdleTimeout.
+	ErrStreamIdleTimeout = errors.New("stream idle timeout")
+	// ErrInvalidTruncation indicates an unknown tool result truncation mode.
+	ErrInvalidTruncation = errors.New("invalid tool result truncation")
+	// ErrToolDisabledInPlanMode reports a call to a mutating tool in plan mode.
+	ErrToolDisabledInPlanMode = errors.New("tool disabled in plan mode")
 )
 
 // Config configures Agent creation.
@@ -54,6 +63,40 @@ type Config struct {
 	MaxTurns     int
 	SteeringMode QueueMode
 	FollowUpMode QueueMode
+
+	// RequireApproval pauses before each tool call not listed in AutoApprove
+	// until Approve or Deny is called. "*" in AutoApprove approves every tool.
+	RequireApproval bool
+	AutoApprove     []string
+	// ConfirmTools always pause for approval, whether or not RequireApproval
+	// is set and even when AutoApprove lists them.
+	ConfirmTools []string
+	// DenyTools maps tools that are refused without running, for tools that
+	// need a confirmation nobody is around to give, to the reason reported to
+	// the model as the tool result.
+	DenyTools map[string]string
+
+	// ParallelTools runs the tool calls of one assistant turn concurrently.
+	// Results are still reported in call order.
+	ParallelTools bool
+
+	// ToolTimeout bounds each tool call from the moment it holds its registry
+	// concurrency slot. A call that runs past it becomes an error tool result
+	// instead of aborting the run. Zero disables the limit.
+	ToolTimeout time.Duration
+
+	// StreamIdleTimeout aborts the run with ErrStreamIdleTimeout when the
+	// provider stream goes this long without an event. Zero disables it.
+	StreamIdleTimeout time.Duration
+
+	// MaxToolResultBytes bounds the tool result content sent to the model;
+	// zero uses 10,000. Longer results are cut by ToolResultTruncation,
+	// which ToolResultTruncationByTool overrides per tool name, and saved
+	// in full under ToolResultDir (the system temp directory when empty).
+	MaxToolResultBytes         int
+	ToolResultTruncation       Truncation
+	ToolResultTruncationByTool map[string]Truncation
+	ToolResultDir              string
 }
 
 // Agent orchestrates the model/tool loop and exposes stream events.
@@ -64,11 +107,24 @@ type Agent struct {
 	steeringMode QueueMode
 	followUpMode QueueMode
 
-	mu            sync.Mutex
-	state         State
-	cancel        context.CancelFunc
-	steeringQueue []llm.Message
-	followUpQueue []llm.Message
+	requireApproval bool
+	autoApprove     map[string]struct{}
+	confirmTools    map[string]struct{}
+	denyTools       map[string]string
+	parallelTools   bool
+	toolTimeout     time.Duration
+	streamIdle      time.Duration
+	truncator       toolResultTruncator
+
+	mu               sync.Mutex
+	state            State
+	cancel           context.CancelFunc
+	steeringQueue    []llm.Message
+	followUpQueue    []llm.Message
+	pendingApprovals map[string]chan bool
+	activeTools      int
+	// planMode offers and runs only read-only tools; see agenttool.ReadOnly.
+	planMode bool
 }
 
 // New creates an agent with explicit dependencies.
@@ -91,13 +147,54 @@ func New(cfg Config) (*Agent, error) {
 		return nil, fmt.Errorf("configure follow-up mode: %w", err)
 	}
 
+	truncation, err := normalizeTruncation(cfg.ToolResultTruncation)
+	if err != nil {
+		return nil, fmt.Errorf("configure tool result truncation: %w", err)
+	}
+	byTool := make(map[string]Truncation, len(cfg.ToolResultTruncationByTool))
+	for name, mode := range cfg.ToolResultTruncationByTool {
+		if byTool[name], err = normalizeTruncation(mode); err != nil {
+			return nil, fmt.Errorf("configure tool result truncation for %s: %w", name, err)
+		}
+	}
+	truncator := toolResultTruncator{
+		maxBytes: cfg.MaxToolResultBytes,
+		mode:     truncation,
+		byTool:   byTool,
+		dir:      cfg.ToolResultDir,
+	}
+	if truncator.maxBytes <= 0 {
+		truncator.maxBytes = defaultMaxToolResultBytes
+	}
+
+	autoApprove := make(map[string]struct{}, len(cfg.AutoApprove))
+	for _, name := range cfg.AutoApprove {
+		autoApprove[name] = struct{}{}
+	}
+	confirmTools := make(map[string]struct{}, len(cfg.ConfirmTools))
+	for _, name := range cfg.ConfirmTools {
+		confirmTools[name] = struct{}{}
+	}
+	denyTools := make(map[string]string, len(cfg.DenyTools))
+	for name, reason := range cfg.DenyTools {
+		denyTools[name] = reason
+	}
+
 	return &Agent{
-		provider:     cfg.Provider,
-		toolRegistry: cfg.ToolRegistry,
-		maxTurns:     maxTurns,
-		steeringMode: steeringMode,
-		followUpMode: followUpMode,
-		state:        StateIdle,
+		provider:        cfg.Provider,
+		toolRegistry:    cfg.ToolRegistry,
+		maxTurns:        maxTurns,
+		steeringMode:    steeringMode,
+		followUpMode:    followUpMode,
+		requireApproval: cfg.RequireApproval,
+		autoApprove:     autoApprove,
+		confirmTools:    confirmTools,
+		denyTools:       denyTools,
+		parallelTools:   cfg.ParallelTools,
+		toolTimeout:     cfg.ToolTimeout,
+		streamIdle:      cfg.StreamIdleTimeout,
+		truncator:       truncator,
+		state:           StateIdle,
 	}, nil
 }
 
@@ -114,6 +211,9 @@ func (a *Agent) Run(ctx context.Context, req *llm.Request) (<-chan llm.Event, er
 	}
 
 	request := cloneRequest(req)
+	if a.planMode {
+		a.restrictToReadOnlyTools(request)
+	}
 	runCtx, cancel := context.WithCancel(ctx)
 	a.cancel = cancel
 	a.state = StateStreaming
@@ -125,17 +225,24 @@ func (a *Agent) Run(ctx context.Context, req *llm.Request) (<-chan llm.Event, er
 
 	go func() {
 		defer close(forwardDone)
-		forwardEvents(forwardedOut, out)
+		forwardEvents(runCtx, forwardedOut, out)
 	}()
 
 	go func() {
 		hooks := runLoopHooks{
 			dequeueSteeringMessages: a.dequeueSteeringMessages,
 			dequeueFollowUpMessages: a.dequeueFollowUpMessages,
+			streamIdleTimeout:       a.streamIdle,
 		}
 		if a.toolRegistry != nil {
 			hooks.executeToolCall = a.executeToolCall
 		}
+		if a.requireApproval || len(a.confirmTools) > 0 {
+			hooks.requestApproval = a.requestApproval
+		}
+		if a.parallelTools {
+			hooks.toolWorkers = parallelToolWorkers
+		}
 
 		terminalForwarded, err := runLoop(runCtx, a.provider, request, a.maxTurns, forwardedOut, hooks)
 		if err != nil && !terminalForwarded {
@@ -189,6 +296,54 @@ func (a *Agent) Cancel() {
 	}
 }
 
+// SetPlanMode toggles plan mode. While it is on, runs offer the model only
+// read-only tools and refuse calls to any other tool.
+func (a *Agent) SetPlanMode(enabled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.planMode = enabled
+}
+
+// PlanMode reports whether plan mode is on.
+func (a *Agent) PlanMode() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.planMode
+}
+
+// restrictToReadOnlyTools drops the tools of req that are not registered as
+// read-only, and a tool choice naming one of them.
+func (a *Agent) restrictToReadOnlyTools(req *llm.Request) {
+	kept := req.Tools[:0]
+	for _, spec := range req.Tools {
+		if a.isReadOnlyTool(spec.Name) {
+			kept = append(kept, spec)
+		}
+	}
+	req.Tools = kept
+	if req.ToolChoice.Type == llm.ToolChoiceTool && !a.isReadOnlyTool(req.ToolChoice.Name) {
+		req.ToolChoice = llm.ToolChoice{}
+	}
+}
+
+func (a *Agent) isReadOnlyTool(name string) bool {
+	if a.toolRegistry == nil {
+		return false
+	}
+	tool, err := a.toolRegistry.Get(name)
+	return err == nil && agenttool.IsReadOnly(tool)
+}
+
+// Approve lets a tool call awaiting approval execute.
+func (a *Agent) Approve(callID string) error {
+	return a.resolveApproval(callID, true)
+}
+
+// Deny rejects a tool call awaiting approval; the model receives an error tool result.
+func (a *Agent) Deny(callID string) error {
+	return a.resolveApproval(callID, false)
+}
+
 // Steer queues a high-priority message for the next turn.
 func (a *Agent) Steer(msg llm.Message) {
 	a.mu.Lock()
@@ -203,6 +358,22 @@ func (a *Agent) FollowUp(msg llm.Message) {
 	a.followUpQueue = append(a.followUpQueue, cloneMessage(msg))
 }
 
+// RemoveSteering drops the most recently queued steering message whose text
+// is text and reports whether one was still queued.
+func (a *Agent) RemoveSteering(text string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return removeQueuedMessage(&a.steeringQueue, text)
+}
+
+// RemoveFollowUp drops the most recently queued follow-up message whose text
+// is text and reports whether one was still queued.
+func (a *Agent) RemoveFollowUp(text string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return removeQueuedMessage(&a.followUpQueue, text)
+}
+
 // HasQueuedMessages reports whether any steering/follow-up messages are queued.
 func (a *Agent) HasQueuedMessages() bool {
 	a.mu.Lock()
@@ -243,9 +414,54 @@ func (a *Agent) finishRun() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	a.cancel = nil
+	a.pendingApprovals = nil
+	a.activeTools = 0
 	a.state = StateIdle
 }
 
+// requestApproval registers a pending decision for call when its tool must be
+// confirmed, or when approval is required and the tool is not auto-approved.
+// Denied tools are refused by executeToolCall without asking.
+func (a *Agent) requestApproval(call llm.ToolCall) (<-chan bool, bool) {
+	if _, deny := a.denyTools[call.Name]; deny {
+		return nil, false
+	}
+	if _, confirm := a.confirmTools[call.Name]; !confirm {
+		if !a.requireApproval {
+			return nil, false
+		}
+		if _, ok := a.autoApprove["*"]; ok {
+			return nil, false
+		}
+		if _, ok := a.autoApprove[call.Name]; ok {
+			return nil, false
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pendingApprovals == nil {
+		a.pendingApprovals = make(map[string]chan bool)
+	}
+	decision := make(chan bool, 1)
+	a.pendingApprovals[call.ID] = decision
+	a.state = StateAwaitingApproval
+	return decision, true
+}
+
+func (a *Agent) resolveApproval(callID string, approved bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	decision, ok := a.pendingApprovals[callID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrApprovalNotPending, callID)
+	}
+	delete(a.pendingApprovals, callID)
+	a.state = StateStreaming
+	decision <- approved
+	return nil
+}
+
 func (a *Agent) dequeueSteeringMessages() []llm.Message {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -261,11 +477,37 @@ func (a *Agent) dequeueFollowUpMessages() []llm.Message {
 }
 
 func (a *Agent) executeToolCall(ctx context.Context, call llm.ToolCall) (llm.Message, error) {
-	a.setState(StateToolExecuting)
-	defer a.setState(StateStreaming)
+	a.beginToolExecution()
+	defer a.endToolExecution()
+
+	if reason, deny := a.denyTools[call.Name]; deny {
+		return llm.Message{
+			Role: llm.RoleTool,
+			ToolResult: &llm.ToolResult{
+				ToolCallID: call.ID,
+				ToolName:   call.Name,
+				Content:    reason,
+				IsError:    true,
+			},
+		}, nil
+	}
+	if a.PlanMode() && !a.isReadOnlyTool(call.Name) {
+		return llm.Message{
+			Role: llm.RoleTool,
+			ToolResult: &llm.ToolResult{
+				ToolCallID: call.ID,
+				ToolName:   call.Name,
+				Content:    fmt.Sprintf("error: %s: %v", call.Name, ErrToolDisabledInPlanMode),
+				IsError:    true,
+			},
+		}, nil
+	}
 
-	result, err := a.toolRegistry.Execute(ctx, call.Name, call.Arguments)
-	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+	result, err := a.toolRegistry.ExecuteWithTimeout(ctx, call.Name, call.Arguments, a.toolTimeout)
+	if errors.Is(err, agenttool.ErrTimeout) {
+		// Only the per-tool deadline fired; report it to the model and keep going.
+		err = fmt.Errorf("%w after %s", ErrToolTimeout, a.toolTimeout)
+	} else if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
 		return llm.Message{}, err
 	}
 
@@ -281,28 +523,46 @@ func (a *Agent) executeToolCall(ctx context.Context, call llm.ToolCall) (llm.Mes
 		content = "ok"
 	}
 
+	var display *llm.ToolDisplay
+	if result.Display.Type != "" {
+		display = &llm.ToolDisplay{Type: result.Display.Type, Payload: result.Display.Payload}
+	}
+
 	return llm.Message{
 		Role: llm.RoleTool,
 		ToolResult: &llm.ToolResult{
 			ToolCallID: call.ID,
 			ToolName:   call.Name,
-			Content:    truncateToolResultContent(content),
+			Content:    a.truncator.truncate(call.Name, content),
 			IsError:    err != nil,
+			Display:    display,
 		},
 	}, nil
 }
 
-func (a *Agent) setState(next State) {
+// beginToolExecution and endToolExecution keep StateToolExecuting while any
+// tool call is still running, including concurrent ones.
+func (a *Agent) beginToolExecution() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	a.state = next
+	a.activeTools++
+	a.state = StateToolExecuting
 }
 
-func truncateToolResultContent(content string) string {
-	if len(content) <= maxToolResultContentLen {
-		return content
+func (a *Agent) endToolExecution() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.activeTools--
+	if a.activeTools <= 0 {
+		a.activeTools = 0
+		a.state = StateStreaming
 	}
-	return content[:toolResultHeadLen] + toolResultTruncateMark + content[len(content)-toolResultTailLen:]
+}
+
+func (a *Agent) setState(next State) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.state = next
 }
 
 func dequeueQueuedMessages(queue *[]llm.Message, mode QueueMode) []llm.Message {
@@ -322,6 +582,25 @@ func dequeueQueuedMessages(queue *[]llm.Message, mode QueueMode) []llm.Message {
 	}
 }
 
+// removeQueuedMessage deletes the last message in queue whose text blocks
+// join to text. Equal messages are interchangeable, so the latest is taken as
+// the one least likely to be delivered next.
+func removeQueuedMessage(queue *[]llm.Message, text string) bool {
+	for i := len(*queue) - 1; i >= 0; i-- {
+		var parts []string
+		for _, block := range (*queue)[i].Content {
+			if block.Type == llm.ContentTypeText {
+				parts = append(parts, block.Text)
+			}
+		}
+		if strings.TrimSpace(strings.Join(parts, "")) == strings.TrimSpace(text) {
+			*queue = append((*queue)[:i:i], (*queue)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 func normalizeQueueMode(mode QueueMode) (QueueMode, error) {
 	switch mode {
 	case "", QueueModeOneAtATime:
@@ -342,6 +621,7 @@ func cloneRequest(req *llm.Request) *llm.Request {
 	cloned.Messages = cloneMessages(req.Messages)
 	cloned.Tools = cloneTools(req.Tools)
 	cloned.Metadata = cloneMetadata(req.Metadata)
+	cloned.StopSequences = append([]string(nil), req.StopSequences...)
 	if req.Temperature != nil {
 		value := *req.Temperature
 		cloned.Temperature = &value
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -21,12 +24,15 @@ func (p fakeProvider) Stream(ctx context.Context, req *llm.Request) (<-chan llm.
 }
 
 type fakeTool struct {
-	name string
-	run  func(ctx context.Context, params json.RawMessage) (agenttool.Result, error)
+	name     string
+	readOnly bool
+	run      func(ctx context.Context, params json.RawMessage) (agenttool.Result, error)
 }
 
 func (f fakeTool) Name() string { return f.name }
 
+func (f fakeTool) ReadOnly() bool { return f.readOnly }
+
 func (f fakeTool) Description() string { return "fake tool" }
 
 func (f fakeTool) Schema() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
@@ -244,6 +250,99 @@ func TestRunReturnsToIdleWhenTerminalEventCannotBeDelivered(t *testing.T) {
 	})
 }
 
+func TestRunDeliversTerminalEventToSlowConsumer(t *testing.T) {
+	t.Parallel()
+
+	provider := fakeProvider{
+		streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			_ = req
+			out := make(chan llm.Event, 202)
+			out <- llm.Event{Type: llm.EventStart}
+			for range 200 {
+				out <- llm.Event{Type: llm.EventTextDelta, TextDelta: "x"}
+			}
+			out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}
+			close(out)
+			return out, nil
+		},
+	}
+
+	a, err := New(Config{Provider: provider})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	stream, err := a.Run(context.Background(), &llm.Request{
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 32,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Fall behind long enough for the flush to give up on the queued deltas,
+	// and well past any fixed wait on the terminal event.
+	time.Sleep(10 * forwardFlushWait)
+	var last llm.Event
+	for ev := range stream {
+		last = ev
+	}
+	if last.Type != llm.EventDone {
+		t.Fatalf("last event = %s, want %s", last.Type, llm.EventDone)
+	}
+}
+
+func TestRunAbortsSilentProviderStreamAfterIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	providerCanceled := make(chan struct{})
+	provider := fakeProvider{
+		streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = req
+			out := make(chan llm.Event, 1)
+			out <- llm.Event{Type: llm.EventStart}
+			go func() {
+				defer close(out)
+				<-ctx.Done()
+				close(providerCanceled)
+			}()
+			return out, nil
+		},
+	}
+	a, err := New(Config{Provider: provider, StreamIdleTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	stream, err := a.Run(context.Background(), &llm.Request{
+		Model:     "claude-sonnet-4-20250514",
+		Messages:  []llm.Message{{Role: llm.RoleUser, Content: []llm.ContentBlock{{Type: llm.ContentTypeText, Text: "hello"}}}},
+		MaxTokens: 32,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	var last llm.Event
+	for ev := range stream {
+		last = ev
+	}
+
+	if last.Type != llm.EventError || !errors.Is(last.Err, ErrStreamIdleTimeout) {
+		t.Fatalf("last event = %#v, want EventError with ErrStreamIdleTimeout", last)
+	}
+	if last.Done == nil || last.Done.Reason != llm.StopReasonError {
+		t.Fatalf("last event done = %#v, want error reason distinct from a user abort", last.Done)
+	}
+	select {
+	case <-providerCanceled:
+	case <-time.After(time.Second):
+		t.Fatalf("provider stream context was not canceled after the idle timeout")
+	}
+	eventually(t, 1*time.Second, func() bool {
+		return a.State() == StateIdle
+	})
+}
+
 func TestRunReturnsToIdleWhenCallerAbandonsMultiEventStream(t *testing.T) {
 	t.Parallel()
 
@@ -277,7 +376,8 @@ func TestRunReturnsToIdleWhenCallerAbandonsMultiEventStream(t *testing.T) {
 		t.Fatalf("Run() error = %v", err)
 	}
 	_ = stream // Intentionally abandon the stream after Run() starts.
-	defer a.Cancel()
+	// The terminal event waits for the consumer until the run is cancelled.
+	a.Cancel()
 
 	eventually(t, 1*time.Second, func() bool {
 		return a.State() == StateIdle
@@ -316,6 +416,7 @@ func TestStateTransitionsToErrorOnProviderTerminalProtocolFailure(t *testing.T)
 	eventually(t, 1*time.Second, func() bool {
 		return a.State() == StateError
 	})
+	a.Cancel()
 
 	eventually(t, 1*time.Second, func() bool {
 		return a.State() == StateIdle
@@ -867,6 +968,129 @@ func TestRunExecutesToolUseAndContinues(t *testing.T) {
 	}
 }
 
+func TestRunRelaxesForcedToolChoiceAfterToolTurn(t *testing.T) {
+	t.Parallel()
+
+	var choices []llm.ToolChoice
+	provider := fakeProvider{
+		streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			choices = append(choices, req.ToolChoice)
+			out := make(chan llm.Event, 2)
+			if len(choices) == 1 {
+				out <- llm.Event{
+					Type:     llm.EventToolCallEnd,
+					ToolCall: &llm.ToolCall{ID: "call-1", Name: "echo", Arguments: json.RawMessage(`{}`)},
+				}
+				out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonToolUse}}
+			} else {
+				out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}
+			}
+			close(out)
+			return out, nil
+		},
+	}
+	registry := agenttool.NewRegistry()
+	if err := registry.Register(fakeTool{
+		name: "echo",
+		run: func(ctx context.Context, params json.RawMessage) (agenttool.Result, error) {
+			_ = ctx
+			return agenttool.Result{Content: "ok"}, nil
+		},
+	}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	a, err := New(Config{Provider: provider, MaxTurns: 5, ToolRegistry: registry})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	stream, err := a.Run(context.Background(), &llm.Request{
+		Model:      "claude-sonnet-4-20250514",
+		Messages:   []llm.Message{{Role: llm.RoleUser, Content: []llm.ContentBlock{{Type: llm.ContentTypeText, Text: "run tool"}}}},
+		MaxTokens:  32,
+		ToolChoice: llm.ToolChoice{Type: llm.ToolChoiceTool, Name: "echo"},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for range stream {
+	}
+
+	want := []llm.ToolChoice{{Type: llm.ToolChoiceTool, Name: "echo"}, {Type: llm.ToolChoiceAuto}}
+	if !reflect.DeepEqual(choices, want) {
+		t.Fatalf("request tool choices = %#v, want %#v", choices, want)
+	}
+}
+
+func TestRunPlanModeOffersOnlyReadOnlyToolsAndRefusesOthers(t *testing.T) {
+	t.Parallel()
+
+	var offered [][]string
+	var results []*llm.ToolResult
+	provider := fakeProvider{
+		streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			names := make([]string, 0, len(req.Tools))
+			for _, spec := range req.Tools {
+				names = append(names, spec.Name)
+			}
+			offered = append(offered, names)
+			out := make(chan llm.Event, 2)
+			if len(offered) == 1 {
+				out <- llm.Event{
+					Type:     llm.EventToolCallEnd,
+					ToolCall: &llm.ToolCall{ID: "call-1", Name: "write", Arguments: json.RawMessage(`{}`)},
+				}
+				out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonToolUse}}
+			} else {
+				out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}
+			}
+			close(out)
+			return out, nil
+		},
+	}
+	var wrote bool
+	registry := agenttool.NewRegistry(
+		fakeTool{name: "read", readOnly: true},
+		fakeTool{name: "write", run: func(ctx context.Context, params json.RawMessage) (agenttool.Result, error) {
+			_ = ctx
+			wrote = true
+			return agenttool.Result{Content: "wrote"}, nil
+		}},
+	)
+	a, err := New(Config{Provider: provider, MaxTurns: 5, ToolRegistry: registry})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	a.SetPlanMode(true)
+
+	stream, err := a.Run(context.Background(), &llm.Request{
+		Model:     "claude-sonnet-4-20250514",
+		Messages:  []llm.Message{{Role: llm.RoleUser, Content: []llm.ContentBlock{{Type: llm.ContentTypeText, Text: "plan"}}}},
+		MaxTokens: 32,
+		Tools:     []llm.ToolSpec{{Name: "read"}, {Name: "write"}},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for ev := range stream {
+		if ev.Type == llm.EventToolResult {
+			results = append(results, ev.ToolResult)
+		}
+	}
+
+	if len(offered) != 2 || !reflect.DeepEqual(offered[0], []string{"read"}) {
+		t.Fatalf("offered tools = %#v, want only read", offered)
+	}
+	if wrote {
+		t.Fatalf("write tool ran in plan mode")
+	}
+	if len(results) != 1 || !results[0].IsError || !strings.Contains(results[0].Content, ErrToolDisabledInPlanMode.Error()) {
+		t.Fatalf("tool results = %#v, want write refused in plan mode", results)
+	}
+}
+
 func TestRunSkipsRemainingToolCallsWhenSteeringQueuedAfterTool(t *testing.T) {
 	t.Parallel()
 
@@ -1200,3 +1424,743 @@ func eventually(t *testing.T, timeout time.Duration, fn func() bool) {
 	}
 	t.Fatalf("condition not met within %s", timeout)
 }
+
+func TestRunToolApproval(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		autoApprove []string
+		denyTools   map[string]string
+		approve     bool
+		wantPrompt  bool
+		wantRan     bool
+		wantContent string
+		wantIsError bool
+	}{
+		{
+			name:        "approved",
+			approve:     true,
+			wantPrompt:  true,
+			wantRan:     true,
+			wantContent: "ran",
+		},
+		{
+			name:        "denied",
+			approve:     false,
+			wantPrompt:  true,
+			wantContent: deniedToolCallMessage,
+			wantIsError: true,
+		},
+		{
+			name:        "auto approved",
+			autoApprove: []string{"echo"},
+			wantRan:     true,
+			wantContent: "ran",
+		},
+		{
+			name:        "wildcard",
+			autoApprove: []string{"*"},
+			wantRan:     true,
+			wantContent: "ran",
+		},
+		{
+			name:        "deny tools",
+			autoApprove: []string{"*"},
+			denyTools:   map[string]string{"echo": "echo requires interactive confirmation and this run cannot ask for it"},
+			wantContent: "echo requires interactive confirmation and this run cannot ask for it",
+			wantIsError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ran bool
+			registry := agenttool.NewRegistry()
+			if err := registry.Register(fakeTool{
+				name: "echo",
+				run: func(ctx context.Context, params json.RawMessage) (agenttool.Result, error) {
+					ran = true
+					return agenttool.Result{Content: "ran"}, nil
+				},
+			}); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+
+			a, err := New(Config{
+				Provider:        toolUseThenStopProvider("call-1", "echo"),
+				MaxTurns:        5,
+				ToolRegistry:    registry,
+				RequireApproval: true,
+				AutoApprove:     tc.autoApprove,
+				DenyTools:       tc.denyTools,
+			})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			stream, err := a.Run(context.Background(), &llm.Request{Model: "claude-sonnet-4-20250514", MaxTokens: 32})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			var sawPrompt bool
+			var result *llm.ToolResult
+			for ev := range stream {
+				switch ev.Type {
+				case llm.EventToolApprovalRequired:
+					sawPrompt = true
+					if got := a.State(); got != StateAwaitingApproval {
+						t.Fatalf("State() = %s, want %s", got, StateAwaitingApproval)
+					}
+					decide := a.Deny
+					if tc.approve {
+						decide = a.Approve
+					}
+					if err := decide(ev.ToolCall.ID); err != nil {
+						t.Fatalf("decide() error = %v", err)
+					}
+				case llm.EventToolResult:
+					result = ev.ToolResult
+				}
+			}
+
+			if sawPrompt != tc.wantPrompt {
+				t.Fatalf("saw approval prompt = %v, want %v", sawPrompt, tc.wantPrompt)
+			}
+			if ran != tc.wantRan {
+				t.Fatalf("tool ran = %v, want %v", ran, tc.wantRan)
+			}
+			if result == nil {
+				t.Fatalf("expected EventToolResult in stream")
+			}
+			if result.Content != tc.wantContent || result.IsError != tc.wantIsError {
+				t.Fatalf("tool result = %#v, want content %q isError %v", result, tc.wantContent, tc.wantIsError)
+			}
+			if got := a.State(); got != StateIdle {
+				t.Fatalf("State() = %s, want %s", got, StateIdle)
+			}
+		})
+	}
+}
+
+func TestCancelWhileAwaitingApprovalReturnsToIdle(t *testing.T) {
+	t.Parallel()
+
+	registry := agenttool.NewRegistry()
+	if err := registry.Register(fakeTool{name: "echo"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	a, err := New(Config{
+		Provider:        toolUseThenStopProvider("call-1", "echo"),
+		MaxTurns:        5,
+		ToolRegistry:    registry,
+		RequireApproval: true,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	stream, err := a.Run(context.Background(), &llm.Request{Model: "claude-sonnet-4-20250514", MaxTokens: 32})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for ev := range stream {
+		if ev.Type == llm.EventToolApprovalRequired {
+			a.Cancel()
+		}
+		if ev.Type == llm.EventToolResult {
+			t.Fatalf("unexpected tool result after cancel: %#v", ev.ToolResult)
+		}
+	}
+
+	if got := a.State(); got != StateIdle {
+		t.Fatalf("State() = %s, want %s", got, StateIdle)
+	}
+	if err := a.Approve("call-1"); !errors.Is(err, ErrApprovalNotPending) {
+		t.Fatalf("Approve() error = %v, want %v", err, ErrApprovalNotPending)
+	}
+}
+
+func TestApproveUnknownCallReturnsNotPending(t *testing.T) {
+	t.Parallel()
+
+	a, err := New(Config{Provider: toolUseThenStopProvider("call-1", "echo"), RequireApproval: true})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := a.Approve("missing"); !errors.Is(err, ErrApprovalNotPending) {
+		t.Fatalf("Approve() error = %v, want %v", err, ErrApprovalNotPending)
+	}
+	if err := a.Deny("missing"); !errors.Is(err, ErrApprovalNotPending) {
+		t.Fatalf("Deny() error = %v, want %v", err, ErrApprovalNotPending)
+	}
+}
+
+func TestRunConfirmToolsPausesEvenWhenAutoApproved(t *testing.T) {
+	t.Parallel()
+
+	var ran atomic.Bool
+	registry := agenttool.NewRegistry()
+	if err := registry.Register(fakeTool{
+		name: "bash",
+		run: func(ctx context.Context, params json.RawMessage) (agenttool.Result, error) {
+			ran.Store(true)
+			return agenttool.Result{Content: "ran"}, nil
+		},
+	}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	a, err := New(Config{
+		Provider:     toolUseThenStopProvider("call-1", "bash"),
+		MaxTurns:     5,
+		ToolRegistry: registry,
+		AutoApprove:  []string{"*"},
+		ConfirmTools: []string{"bash"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	stream, err := a.Run(context.Background(), &llm.Request{Model: "claude-sonnet-4-20250514", MaxTokens: 32})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var sawPrompt bool
+	var result *llm.ToolResult
+	for ev := range stream {
+		switch ev.Type {
+		case llm.EventToolApprovalRequired:
+			sawPrompt = true
+			if ran.Load() {
+				t.Fatalf("bash ran before approval")
+			}
+			if err := a.Approve(ev.ToolCall.ID); err != nil {
+				t.Fatalf("Approve() error = %v", err)
+			}
+		case llm.EventToolResult:
+			result = ev.ToolResult
+		}
+	}
+
+	if !sawPrompt {
+		t.Fatalf("expected EventToolApprovalRequired for a confirmed tool")
+	}
+	if !ran.Load() || result == nil || result.Content != "ran" || result.IsError {
+		t.Fatalf("ran = %v result = %#v, want bash to run after approval", ran.Load(), result)
+	}
+}
+
+func toolUseThenStopProvider(callID, toolName string) fakeProvider {
+	return multiToolThenStopProvider([]llm.ToolCall{
+		{ID: callID, Name: toolName, Arguments: json.RawMessage(`{}`)},
+	})
+}
+
+func TestRunParallelToolsRunsConcurrentlyAndReportsInOrder(t *testing.T) {
+	t.Parallel()
+
+	calls := []llm.ToolCall{
+		{ID: "call-1", Name: "slow", Arguments: json.RawMessage(`{}`)},
+		{ID: "call-2", Name: "fast", Arguments: json.RawMessage(`{}`)},
+	}
+
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+	waitBoth := func() error {
+		barrier.Done()
+		done := make(chan struct{})
+		go func() {
+			barrier.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-time.After(time.Second):
+			return errors.New("tools did not run concurrently")
+		}
+	}
+	fastDone := make(chan struct{})
+
+	registry := agenttool.NewRegistry()
+	for _, tool := range []fakeTool{
+		{name: "slow", run: func(ctx context.Context, params json.RawMessage) (agenttool.Result, error) {
+			if err := waitBoth(); err != nil {
+				return agenttool.Result{}, err
+			}
+			<-fastDone
+			return agenttool.Result{Content: "slow-ok"}, nil
+		}},
+		{name: "fast", run: func(ctx context.Context, params json.RawMessage) (agenttool.Result, error) {
+			defer close(fastDone)
+			if err := waitBoth(); err != nil {
+				return agenttool.Result{}, err
+			}
+			return agenttool.Result{Content: "fast-ok"}, nil
+		}},
+	} {
+		if err := registry.Register(tool); err != nil {
+			t.Fatalf("Register(%s) error = %v", tool.name, err)
+		}
+	}
+
+	a, err := New(Config{
+		Provider:      multiToolThenStopProvider(calls),
+		MaxTurns:      5,
+		ToolRegistry:  registry,
+		ParallelTools: true,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	stream, err := a.Run(context.Background(), &llm.Request{Model: "claude-sonnet-4-20250514", MaxTokens: 32})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var results []llm.ToolResult
+	for ev := range stream {
+		if ev.Type == llm.EventToolResult && ev.ToolResult != nil {
+			results = append(results, *ev.ToolResult)
+		}
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("tool results = %#v, want 2", results)
+	}
+	if results[0].ToolCallID != "call-1" || results[0].Content != "slow-ok" || results[0].IsError {
+		t.Fatalf("results[0] = %#v, want call-1 slow-ok", results[0])
+	}
+	if results[1].ToolCallID != "call-2" || results[1].Content != "fast-ok" || results[1].IsError {
+		t.Fatalf("results[1] = %#v, want call-2 fast-ok", results[1])
+	}
+	if got := a.State(); got != StateIdle {
+		t.Fatalf("State() = %s, want %s", got, StateIdle)
+	}
+}
+
+func TestRunParallelToolsSteeringLetsRunningCallsFinish(t *testing.T) {
+	t.Parallel()
+
+	// Four workers pick up calls 1-4; call-5 waits for a free worker and may
+	// or may not start before steering stops dispatch.
+	calls := []llm.ToolCall{
+		{ID: "call-1", Name: "first", Arguments: json.RawMessage(`{}`)},
+		{ID: "call-2", Name: "running", Arguments: json.RawMessage(`{}`)},
+		{ID: "call-3", Name: "running", Arguments: json.RawMessage(`{}`)},
+		{ID: "call-4", Name: "running", Arguments: json.RawMessage(`{}`)},
+		{ID: "call-5", Name: "late", Arguments: json.RawMessage(`{}`)},
+	}
+
+	started := make(chan struct{}, len(calls))
+	releaseFirst := make(chan struct{})
+	releaseRest := make(chan struct{})
+	var lateRan atomic.Bool
+	registry := agenttool.NewRegistry()
+	for _, tool := range []fakeTool{
+		{name: "first", run: func(ctx context.Context, params json.RawMessage) (agenttool.Result, error) {
+			started <- struct{}{}
+			<-releaseFirst
+			return agenttool.Result{Content: "first-ok"}, nil
+		}},
+		{name: "running", run: func(ctx context.Context, params json.RawMessage) (agenttool.Result, error) {
+			started <- struct{}{}
+			select {
+			case <-ctx.Done():
+				return agenttool.Result{}, ctx.Err()
+			case <-releaseRest:
+				return agenttool.Result{Content: "running-ok"}, nil
+			}
+		}},
+		{name: "late", run: func(ctx context.Context, params json.RawMessage) (agenttool.Result, error) {
+			lateRan.Store(true)
+			return agenttool.Result{Content: "late-ok"}, nil
+		}},
+	} {
+		if err := registry.Register(tool); err != nil {
+			t.Fatalf("Register(%s) error = %v", tool.name, err)
+		}
+	}
+
+	a, err := New(Config{
+		Provider:      multiToolThenStopProvider(calls),
+		MaxTurns:      5,
+		ToolRegistry:  registry,
+		ParallelTools: true,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	stream, err := a.Run(context.Background(), &llm.Request{Model: "claude-sonnet-4-20250514", MaxTokens: 32})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatalf("tools did not start in time")
+		}
+	}
+	a.Steer(llm.Message{
+		Role:    llm.RoleUser,
+		Content: []llm.ContentBlock{{Type: llm.ContentTypeText, Text: "interrupt"}},
+	})
+	close(releaseFirst)
+
+	var results []llm.ToolResult
+	for ev := range stream {
+		if ev.Type == llm.EventToolResult && ev.ToolResult != nil {
+			results = append(results, *ev.ToolResult)
+			if ev.ToolResult.ToolCallID == "call-1" {
+				close(releaseRest)
+			}
+		}
+	}
+
+	if len(results) != len(calls) {
+		t.Fatalf("tool results = %#v, want %d", results, len(calls))
+	}
+	for i, want := range []string{"first-ok", "running-ok", "running-ok", "running-ok"} {
+		if results[i].ToolCallID != calls[i].ID || results[i].Content != want || results[i].IsError {
+			t.Fatalf("results[%d] = %#v, want %s finished with %s", i, results[i], calls[i].ID, want)
+		}
+	}
+	late := results[4]
+	if lateRan.Load() {
+		if late.Content != "late-ok" || late.IsError {
+			t.Fatalf("results[4] = %#v, want the real result of a call that ran", late)
+		}
+	} else if late.Content != skippedToolCallMessage || !late.IsError {
+		t.Fatalf("results[4] = %#v, want call-5 skipped", late)
+	}
+	if got := a.State(); got != StateIdle {
+		t.Fatalf("State() = %s, want %s", got, StateIdle)
+	}
+}
+
+func multiToolThenStopProvider(calls []llm.ToolCall) fakeProvider {
+	var mu sync.Mutex
+	var streams int
+	return fakeProvider{
+		streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			mu.Lock()
+			streams++
+			first := streams == 1
+			mu.Unlock()
+
+			out := make(chan llm.Event, len(calls)+2)
+			out <- llm.Event{Type: llm.EventStart}
+			if first {
+				for i := range calls {
+					call := calls[i]
+					out <- llm.Event{Type: llm.EventToolCallEnd, ToolCall: &call}
+				}
+				out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonToolUse}}
+			} else {
+				out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}
+			}
+			close(out)
+			return out, nil
+		},
+	}
+}
+
+func TestRunReplaysThinkingWithinToolUseTurn(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var snapshots [][]llm.Message
+	provider := fakeProvider{
+		streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			mu.Lock()
+			snapshots = append(snapshots, cloneMessagesForTest(req.Messages))
+			first := len(snapshots) == 1
+			mu.Unlock()
+
+			if !first {
+				return scriptedEvents(llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}), nil
+			}
+			return scriptedEvents(
+				llm.Event{Type: llm.EventContentBlockStart, ContentBlockStart: &llm.ContentBlockStart{Type: "thinking"}},
+				llm.Event{Type: llm.EventThinkingDelta, ThinkingDelta: "plan"},
+				llm.Event{Type: llm.EventThinkingDelta, SignatureDelta: "sig"},
+				llm.Event{Type: llm.EventToolCallEnd, ToolCall: &llm.ToolCall{ID: "call-1", Name: "echo", Arguments: json.RawMessage(`{}`)}},
+				llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonToolUse}},
+			), nil
+		},
+	}
+
+	registry := agenttool.NewRegistry()
+	if err := registry.Register(fakeTool{name: "echo"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	a, err := New(Config{Provider: provider, MaxTurns: 5, ToolRegistry: registry})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	stream, err := a.Run(context.Background(), &llm.Request{Model: "claude-sonnet-4-20250514", MaxTokens: 32})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for range stream {
+	}
+
+	if len(snapshots) != 2 {
+		t.Fatalf("provider stream calls = %d, want 2", len(snapshots))
+	}
+	assistant := snapshots[1][0]
+	if assistant.Role != llm.RoleAssistant || len(assistant.Content) != 2 {
+		t.Fatalf("assistant message = %#v, want a thinking block and the tool call", assistant)
+	}
+	block := assistant.Content[0]
+	if block.Type != llm.ContentTypeThinking || block.Thinking != "plan" || block.Signature != "sig" {
+		t.Fatalf("thinking block = %#v, want replayed plan/sig", block)
+	}
+	if marker := assistant.Content[1]; marker.Type != llm.ContentTypeToolUse || marker.ToolCallID != "call-1" {
+		t.Fatalf("second block = %#v, want tool_use marker for call-1", marker)
+	}
+}
+
+func TestRunPreservesInterleavedContentBlockOrder(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var snapshots [][]llm.Message
+	provider := fakeProvider{
+		streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			mu.Lock()
+			snapshots = append(snapshots, cloneMessagesForTest(req.Messages))
+			first := len(snapshots) == 1
+			mu.Unlock()
+
+			if !first {
+				return scriptedEvents(llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}), nil
+			}
+			call := llm.ToolCall{ID: "call-1", Name: "echo", Arguments: json.RawMessage(`{}`)}
+			return scriptedEvents(
+				llm.Event{Type: llm.EventContentBlockStart, ContentBlockStart: &llm.ContentBlockStart{Index: 0, Type: "text", Text: "Let me "}},
+				llm.Event{Type: llm.EventTextDelta, TextDelta: "check."},
+				llm.Event{Type: llm.EventContentBlockStop, ContentBlockStop: &llm.ContentBlockStop{Index: 0}},
+				llm.Event{Type: llm.EventContentBlockStart, ContentBlockStart: &llm.ContentBlockStart{Index: 1, Type: "tool_use", ID: "call-1", Name: "echo"}},
+				llm.Event{Type: llm.EventToolCallStart, ToolCall: &call},
+				llm.Event{Type: llm.EventContentBlockStop, ContentBlockStop: &llm.ContentBlockStop{Index: 1}},
+				llm.Event{Type: llm.EventToolCallEnd, ToolCall: &call},
+				llm.Event{Type: llm.EventContentBlockStart, ContentBlockStart: &llm.ContentBlockStart{Index: 2, Type: "text"}},
+				llm.Event{Type: llm.EventTextDelta, TextDelta: "Then report."},
+				llm.Event{Type: llm.EventContentBlockStop, ContentBlockStop: &llm.ContentBlockStop{Index: 2}},
+				llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonToolUse}},
+			), nil
+		},
+	}
+
+	registry := agenttool.NewRegistry()
+	if err := registry.Register(fakeTool{name: "echo"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	a, err := New(Config{Provider: provider, MaxTurns: 5, ToolRegistry: registry})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	stream, err := a.Run(context.Background(), &llm.Request{Model: "claude-sonnet-4-20250514", MaxTokens: 32})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for range stream {
+	}
+
+	if len(snapshots) != 2 {
+		t.Fatalf("provider stream calls = %d, want 2", len(snapshots))
+	}
+	assistant := snapshots[1][0]
+	want := []llm.ContentBlock{
+		{Type: llm.ContentTypeText, Text: "Let me check."},
+		{Type: llm.ContentTypeToolUse, ToolCallID: "call-1"},
+		{Type: llm.ContentTypeText, Text: "Then report."},
+	}
+	if !reflect.DeepEqual(assistant.Content, want) {
+		t.Fatalf("assistant content = %#v, want %#v", assistant.Content, want)
+	}
+	if len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].ID != "call-1" {
+		t.Fatalf("assistant tool calls = %#v, want call-1", assistant.ToolCalls)
+	}
+}
+
+func scriptedEvents(events ...llm.Event) <-chan llm.Event {
+	out := make(chan llm.Event, len(events))
+	for _, ev := range events {
+		out <- ev
+	}
+	close(out)
+	return out
+}
+
+type idempotentFakeTool struct {
+	fakeTool
+}
+
+func (idempotentFakeTool) Idempotent() bool { return true }
+
+func TestRunEmitsToolRetryEvents(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	registry := agenttool.NewRegistry()
+	if err := registry.Register(idempotentFakeTool{fakeTool{name: "read", run: func(context.Context, json.RawMessage) (agenttool.Result, error) {
+		calls++
+		if calls == 1 {
+			return agenttool.Result{}, errors.New("transient")
+		}
+		return agenttool.Result{Content: "contents"}, nil
+	}}}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	registry.SetRetryPolicy(llm.RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	a, err := New(Config{Provider: toolUseThenStopProvider("call-1", "read"), MaxTurns: 5, ToolRegistry: registry})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	stream, err := a.Run(context.Background(), &llm.Request{Model: "claude-sonnet-4-20250514", MaxTokens: 32})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var retries []llm.Event
+	var result *llm.ToolResult
+	for ev := range stream {
+		switch ev.Type {
+		case llm.EventToolRetry:
+			retries = append(retries, ev)
+		case llm.EventToolResult:
+			result = ev.ToolResult
+		}
+	}
+
+	if len(retries) != 1 || retries[0].ToolCall == nil || retries[0].ToolCall.ID != "call-1" ||
+		retries[0].ToolRetry == nil || retries[0].ToolRetry.Attempt != 1 {
+		t.Fatalf("retry events = %#v, want one attempt for call-1", retries)
+	}
+	if result == nil || result.IsError || result.Content != "contents" {
+		t.Fatalf("tool result = %#v, want successful retried result", result)
+	}
+}
+
+func TestRunToolTimeoutReportsErrorResult(t *testing.T) {
+	t.Parallel()
+
+	registry := agenttool.NewRegistry()
+	if err := registry.Register(fakeTool{name: "slow", run: func(ctx context.Context, _ json.RawMessage) (agenttool.Result, error) {
+		<-ctx.Done()
+		return agenttool.Result{}, ctx.Err()
+	}}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	a, err := New(Config{
+		Provider:     toolUseThenStopProvider("call-1", "slow"),
+		MaxTurns:     5,
+		ToolRegistry: registry,
+		ToolTimeout:  20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	stream, err := a.Run(context.Background(), &llm.Request{Model: "claude-sonnet-4-20250514", MaxTokens: 32})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var result *llm.ToolResult
+	var last llm.Event
+	for ev := range stream {
+		if ev.Type == llm.EventToolResult {
+			result = ev.ToolResult
+		}
+		last = ev
+	}
+
+	if result == nil || !result.IsError || !strings.Contains(result.Content, "tool timed out after 20ms") {
+		t.Fatalf("tool result = %#v, want timeout error result", result)
+	}
+	if last.Type != llm.EventDone || last.Done == nil || last.Done.Reason != llm.StopReasonStop {
+		t.Fatalf("last event = %#v, want run to finish normally", last)
+	}
+}
+
+func TestRunTruncatesToolResultsToConfiguredLimit(t *testing.T) {
+	t.Parallel()
+
+	registry := agenttool.NewRegistry()
+	if err := registry.Register(fakeTool{name: "dump", run: func(context.Context, json.RawMessage) (agenttool.Result, error) {
+		return agenttool.Result{Content: strings.Repeat("x", 500) + "END"}, nil
+	}}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if _, err := New(Config{Provider: toolUseThenStopProvider("call-1", "dump"), ToolResultTruncation: "sideways"}); !errors.Is(err, ErrInvalidTruncation) {
+		t.Fatalf("New(bad truncation) error = %v, want ErrInvalidTruncation", err)
+	}
+	a, err := New(Config{
+		Provider:             toolUseThenStopProvider("call-1", "dump"),
+		ToolRegistry:         registry,
+		MaxToolResultBytes:   100,
+		ToolResultTruncation: TruncateTail,
+		ToolResultDir:        t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	stream, err := a.Run(context.Background(), &llm.Request{Model: "claude-sonnet-4-20250514", MaxTokens: 32})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var result *llm.ToolResult
+	for ev := range stream {
+		if ev.Type == llm.EventToolResult {
+			result = ev.ToolResult
+		}
+	}
+	if result == nil || !strings.HasPrefix(result.Content, "[403 bytes truncated; full output: ") || !strings.HasSuffix(result.Content, strings.Repeat("x", 97)+"END") {
+		t.Fatalf("tool result = %#v, want the last 100 bytes after an elision note", result)
+	}
+}
+
+func TestRemoveQueuedDropsOnlyStillQueuedMessage(t *testing.T) {
+	t.Parallel()
+
+	a, err := New(Config{Provider: fakeProvider{}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, text := range []string{"one", "two", "one"} {
+		a.Steer(llm.Message{Role: llm.RoleUser, Content: []llm.ContentBlock{{Type: llm.ContentTypeText, Text: text}}})
+	}
+	a.FollowUp(llm.Message{Role: llm.RoleUser, Content: []llm.ContentBlock{{Type: llm.ContentTypeText, Text: "later"}}})
+
+	if !a.RemoveSteering("one") || !a.RemoveFollowUp("later") {
+		t.Fatal("Remove*() = false, want queued messages removed")
+	}
+	if a.RemoveSteering("missing") || a.RemoveFollowUp("later") {
+		t.Fatal("Remove*() = true for a message no longer queued")
+	}
+	var texts []string
+	for _, msg := range a.dequeueSteeringMessages() {
+		texts = append(texts, msg.Content[0].Text)
+	}
+	if got := strings.Join(texts, ","); got != "one" {
+		t.Fatalf("next steering = %q, want the earlier one", got)
+	}
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+
+	"gar/internal/llm"
+)
+
+// RunResult is the outcome of one run collected from its event stream. The
+// JSON shape is the document printed by `gar run --json`.
+type RunResult struct {
+	// Text is the final assistant answer, without text from tool-use turns.
+	Text         string         `json:"text"`
+	ToolCalls    []RunToolCall  `json:"tool_calls"`
+	Usage        llm.Usage      `json:"usage"`
+	StopReason   llm.StopReason `json:"stop_reason"`
+	StopSequence string         `json:"stop_sequence,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// RunToolCall pairs one executed tool call with its result.
+type RunToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+	Result    string          `json:"result"`
+	IsError   bool            `json:"is_error"`
+}
+
+// CollectRun consumes stream into a RunResult. Usage is summed across turns
+// and text is reset after each tool-use turn, so only the final answer
+// remains. A stream that ends without a terminal event stops with
+// StopReasonError.
+func CollectRun(stream <-chan llm.Event) RunResult {
+	out := RunResult{ToolCalls: []RunToolCall{}}
+	index := make(map[string]int)
+	var text strings.Builder
+
+	for ev := range stream {
+		switch ev.Type {
+		case llm.EventContentBlockStart:
+			if ev.ContentBlockStart != nil && llm.ContentType(ev.ContentBlockStart.Type) == llm.ContentTypeText {
+				text.WriteString(ev.ContentBlockStart.Text)
+			}
+		case llm.EventTextDelta:
+			text.WriteString(ev.TextDelta)
+		case llm.EventToolCallEnd:
+			if ev.ToolCall == nil {
+				continue
+			}
+			call := RunToolCall{
+				ID:        ev.ToolCall.ID,
+				Name:      ev.ToolCall.Name,
+				Arguments: append(json.RawMessage(nil), ev.ToolCall.Arguments...),
+			}
+			if i, ok := index[call.ID]; ok {
+				call.Result, call.IsError = out.ToolCalls[i].Result, out.ToolCalls[i].IsError
+				out.ToolCalls[i] = call
+				continue
+			}
+			index[call.ID] = len(out.ToolCalls)
+			out.ToolCalls = append(out.ToolCalls, call)
+		case llm.EventToolResult:
+			if ev.ToolResult == nil {
+				continue
+			}
+			i, ok := index[ev.ToolResult.ToolCallID]
+			if !ok {
+				i = len(out.ToolCalls)
+				index[ev.ToolResult.ToolCallID] = i
+				out.ToolCalls = append(out.ToolCalls, RunToolCall{ID: ev.ToolResult.ToolCallID, Name: ev.ToolResult.ToolName})
+			}
+			out.ToolCalls[i].Result = ev.ToolResult.Content
+			out.ToolCalls[i].IsError = ev.ToolResult.IsError
+		case llm.EventDone:
+			if ev.Done == nil {
+				continue
+			}
+			out.Usage = out.Usage.Add(ev.Done.Usage)
+			out.StopReason = ev.Done.Reason
+			out.StopSequence = ev.Done.StopSequence
+			if ev.Done.Reason == llm.StopReasonToolUse {
+				text.Reset()
+			}
+		case llm.EventError:
+			out.StopReason = llm.StopReasonError
+			if ev.Done != nil && ev.Done.Reason != "" {
+				out.StopReason = ev.Done.Reason
+			}
+			if ev.Err != nil {
+				out.Error = ev.Err.Error()
+			}
+		}
+	}
+
+	out.Text = strings.TrimSpace(text.String())
+	if out.StopReason == "" {
+		out.StopReason = llm.StopReasonError
+		if out.Error == "" {
+			out.Error = "stream ended without a terminal event"
+		}
+	}
+	return out
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"strings"
+	"fmt"
+	"sync"
 	"time"
 
+	agenttool "gar/internal/agent/tool"
 	"gar/internal/llm"
 )
 
@@ -14,6 +16,12 @@ type runLoopHooks struct {
 	dequeueSteeringMessages func() []llm.Message
 	dequeueFollowUpMessages func() []llm.Message
 	executeToolCall         func(ctx context.Context, call llm.ToolCall) (llm.Message, error)
+	requestApproval         func(call llm.ToolCall) (decision <-chan bool, required bool)
+
+	// toolWorkers > 1 runs one turn's tool calls concurrently on that many workers.
+	toolWorkers int
+	// streamIdleTimeout > 0 aborts a provider stream that stays silent that long.
+	streamIdleTimeout time.Duration
 }
 
 func runLoop(
@@ -53,12 +61,16 @@ func runLoop(
 			pendingMessages = nil
 		}
 
-		stream, err := provider.Stream(ctx, req)
+		streamCtx, cancelStream := context.WithCancel(ctx)
+		stream, err := provider.Stream(streamCtx, req)
 		if err != nil {
+			cancelStream()
 			return false, err
 		}
 
-		terminal, hasTerminal, assistantMessage, err := forwardProviderEvents(ctx, stream, out)
+		terminal, hasTerminal, assistantMessage, err := forwardProviderEvents(ctx, stream, out, hooks.streamIdleTimeout)
+		// Stops a provider still streaming after an idle timeout.
+		cancelStream()
 		if err != nil {
 			return false, err
 		}
@@ -79,56 +91,22 @@ func runLoop(
 				return true, nil
 			}
 
-			for i, toolCall := range assistantMessage.ToolCalls {
-				call := cloneToolCall(toolCall)
-				if err := sendStreamEvent(ctx, out, llm.Event{
-					Type:     llm.EventToolCallStart,
-					ToolCall: &call,
-				}); err != nil {
-					return false, err
-				}
-
-				toolResultMessage, err := hooks.executeToolCall(ctx, call)
-				if err != nil {
-					return false, err
-				}
-				if err := appendAndEmitToolResult(ctx, out, req, toolResultMessage); err != nil {
-					return false, err
-				}
-
-				if err := sendStreamEvent(ctx, out, llm.Event{
-					Type:     llm.EventToolCallEnd,
-					ToolCall: &call,
-				}); err != nil {
-					return false, err
-				}
-
-				if steering := dequeueMessages(hooks.dequeueSteeringMessages); len(steering) > 0 {
-					pendingMessages = steering
-					remainingCalls := assistantMessage.ToolCalls[i+1:]
-					for _, remaining := range remainingCalls {
-						skippedCall := cloneToolCall(remaining)
-						if err := sendStreamEvent(ctx, out, llm.Event{
-							Type:     llm.EventToolCallStart,
-							ToolCall: &skippedCall,
-						}); err != nil {
-							return false, err
-						}
-
-						skippedResultMessage := skipToolCall(skippedCall)
-						if err := appendAndEmitToolResult(ctx, out, req, skippedResultMessage); err != nil {
-							return false, err
-						}
-
-						if err := sendStreamEvent(ctx, out, llm.Event{
-							Type:     llm.EventToolCallEnd,
-							ToolCall: &skippedCall,
-						}); err != nil {
-							return false, err
-						}
-					}
-					break
-				}
+			var steering []llm.Message
+			if hooks.toolWorkers > 1 && len(assistantMessage.ToolCalls) > 1 {
+				steering, err = executeToolCallsParallel(ctx, out, req, assistantMessage.ToolCalls, hooks)
+			} else {
+				steering, err = executeToolCallsSequential(ctx, out, req, assistantMessage.ToolCalls, hooks)
+			}
+			if err != nil {
+				return false, err
+			}
+			if len(steering) > 0 {
+				pendingMessages = steering
+			}
+			// A forced tool choice covers the reply that uses the tool; later
+			// turns must be free to answer with its results.
+			if req.ToolChoice.Type == llm.ToolChoiceAny || req.ToolChoice.Type == llm.ToolChoiceTool {
+				req.ToolChoice = llm.ToolChoice{Type: llm.ToolChoiceAuto}
 			}
 			continue
 		}
@@ -148,17 +126,228 @@ func runLoop(
 	return false, ErrMaxTurnsExceeded
 }
 
+// executeToolCallsSequential runs calls one at a time. When steering arrives after a
+// call, the remaining calls are skipped and the steering messages are returned.
+func executeToolCallsSequential(
+	ctx context.Context,
+	out chan<- llm.Event,
+	req *llm.Request,
+	calls []llm.ToolCall,
+	hooks runLoopHooks,
+) ([]llm.Message, error) {
+	for i, toolCall := range calls {
+		call := cloneToolCall(toolCall)
+		if err := sendStreamEvent(ctx, out, llm.Event{
+			Type:     llm.EventToolCallStart,
+			ToolCall: &call,
+		}); err != nil {
+			return nil, err
+		}
+
+		approved, err := awaitToolApproval(ctx, out, hooks.requestApproval, call)
+		if err != nil {
+			return nil, err
+		}
+		toolResultMessage := denyToolCall(call)
+		if approved {
+			toolResultMessage, err = hooks.executeToolCall(withToolEvents(ctx, out, call), call)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := emitToolCallResult(ctx, out, req, call, toolResultMessage); err != nil {
+			return nil, err
+		}
+
+		if steering := dequeueMessages(hooks.dequeueSteeringMessages); len(steering) > 0 {
+			return steering, skipToolCalls(ctx, out, req, calls[i+1:], true)
+		}
+	}
+	return nil, nil
+}
+
+// executeToolCallsParallel starts every call, resolves approvals in order, then runs
+// approved calls on hooks.toolWorkers workers. Results are emitted in call order so
+// the conversation stays deterministic. Steering stops dispatching new calls: those
+// already running finish and report their real results, and only calls that never
+// started are skipped.
+func executeToolCallsParallel(
+	ctx context.Context,
+	out chan<- llm.Event,
+	req *llm.Request,
+	calls []llm.ToolCall,
+	hooks runLoopHooks,
+) ([]llm.Message, error) {
+	results := make([]llm.Message, len(calls))
+	errs := make([]error, len(calls))
+	done := make([]chan struct{}, len(calls))
+	approved := make([]int, 0, len(calls))
+
+	for i, toolCall := range calls {
+		call := cloneToolCall(toolCall)
+		done[i] = make(chan struct{})
+		if err := sendStreamEvent(ctx, out, llm.Event{
+			Type:     llm.EventToolCallStart,
+			ToolCall: &call,
+		}); err != nil {
+			return nil, err
+		}
+
+		ok, err := awaitToolApproval(ctx, out, hooks.requestApproval, call)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			results[i] = denyToolCall(call)
+			close(done[i])
+			continue
+		}
+		approved = append(approved, i)
+	}
+
+	var wg sync.WaitGroup
+	toolCtx, cancelTools := context.WithCancel(ctx)
+	defer func() {
+		cancelTools()
+		wg.Wait()
+	}()
+
+	jobs := make(chan int)
+	stopDispatch := make(chan struct{})
+	dispatchDone := make(chan struct{})
+	go func() {
+		defer close(dispatchDone)
+		defer close(jobs)
+		for _, i := range approved {
+			select {
+			case <-stopDispatch:
+				return
+			default:
+			}
+			select {
+			case <-toolCtx.Done():
+				return
+			case <-stopDispatch:
+				return
+			case jobs <- i:
+			}
+		}
+	}()
+
+	workers := hooks.toolWorkers
+	if workers > len(approved) {
+		workers = len(approved)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				call := cloneToolCall(calls[i])
+				results[i], errs[i] = hooks.executeToolCall(withToolEvents(toolCtx, out, call), call)
+				close(done[i])
+			}
+		}()
+	}
+
+	for i := range calls {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-done[i]:
+		}
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if err := emitToolCallResult(ctx, out, req, cloneToolCall(calls[i]), results[i]); err != nil {
+			return nil, err
+		}
+
+		if steering := dequeueMessages(hooks.dequeueSteeringMessages); len(steering) > 0 {
+			close(stopDispatch)
+			<-dispatchDone
+			wg.Wait()
+			return steering, emitSettledToolCalls(ctx, out, req, calls[i+1:], results[i+1:], errs[i+1:], done[i+1:])
+		}
+	}
+	return nil, nil
+}
+
+// emitSettledToolCalls reports calls after dispatch has stopped and every
+// worker has returned: calls that were denied or ran report their results,
+// and calls that never started are skipped.
+func emitSettledToolCalls(ctx context.Context, out chan<- llm.Event, req *llm.Request, calls []llm.ToolCall, results []llm.Message, errs []error, done []chan struct{}) error {
+	for i := range calls {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		select {
+		case <-done[i]:
+			if err := emitToolCallResult(ctx, out, req, cloneToolCall(calls[i]), results[i]); err != nil {
+				return err
+			}
+		default:
+			if err := skipToolCalls(ctx, out, req, calls[i:i+1], false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipToolCalls reports calls as skipped; emitStart is false when their start
+// events were already sent.
+func skipToolCalls(ctx context.Context, out chan<- llm.Event, req *llm.Request, calls []llm.ToolCall, emitStart bool) error {
+	for _, remaining := range calls {
+		skippedCall := cloneToolCall(remaining)
+		if emitStart {
+			if err := sendStreamEvent(ctx, out, llm.Event{
+				Type:     llm.EventToolCallStart,
+				ToolCall: &skippedCall,
+			}); err != nil {
+				return err
+			}
+		}
+		if err := emitToolCallResult(ctx, out, req, skippedCall, skipToolCall(skippedCall)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func emitToolCallResult(ctx context.Context, out chan<- llm.Event, req *llm.Request, call llm.ToolCall, msg llm.Message) error {
+	if err := appendAndEmitToolResult(ctx, out, req, msg); err != nil {
+		return err
+	}
+	return sendStreamEvent(ctx, out, llm.Event{
+		Type:     llm.EventToolCallEnd,
+		ToolCall: &call,
+	})
+}
+
 func forwardProviderEvents(
 	ctx context.Context,
 	stream <-chan llm.Event,
 	out chan<- llm.Event,
+	idleTimeout time.Duration,
 ) (terminal llm.Event, hasTerminal bool, assistantMessage *llm.Message, err error) {
 	accumulator := newAssistantAccumulator()
 
+	// idle stays nil, and never fires, when the idle timeout is disabled.
+	var idle <-chan time.Time
+	var idleTimer *time.Timer
+	if idleTimeout > 0 {
+		idleTimer = time.NewTimer(idleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return llm.Event{}, false, nil, ctx.Err()
+		case <-idle:
+			return llm.Event{}, false, nil, fmt.Errorf("%w: no event for %s", ErrStreamIdleTimeout, idleTimeout)
 		case ev, ok := <-stream:
 			if !ok {
 				return llm.Event{}, false, nil, nil
@@ -167,6 +356,10 @@ func forwardProviderEvents(
 			if err := sendStreamEvent(ctx, out, ev); err != nil {
 				return llm.Event{}, false, nil, err
 			}
+			// Time spent waiting on a slow consumer does not count as idle.
+			if idleTimer != nil {
+				idleTimer.Reset(idleTimeout)
+			}
 
 			accumulator.consume(ev)
 			if ev.Type == llm.EventDone || ev.Type == llm.EventError {
@@ -177,9 +370,9 @@ func forwardProviderEvents(
 }
 
 // forwardEvents decouples producer and consumer backpressure so abandoned
-// consumers do not block loop teardown. It flushes remaining queued events
-// on close only while the output channel can accept without blocking.
-func forwardEvents(in <-chan llm.Event, out chan<- llm.Event) {
+// consumers do not block loop teardown. Events still queued when the producer
+// closes are flushed by flushForwardQueue.
+func forwardEvents(ctx context.Context, in <-chan llm.Event, out chan<- llm.Event) {
 	queue := make([]llm.Event, 0, 8)
 
 	for {
@@ -193,18 +386,7 @@ func forwardEvents(in <-chan llm.Event, out chan<- llm.Event) {
 		select {
 		case ev, ok := <-in:
 			if !ok {
-				for len(queue) > 0 {
-					timer := time.NewTimer(forwardFlushWait)
-					select {
-					case out <- queue[0]:
-						queue = queue[1:]
-						if !timer.Stop() {
-							<-timer.C
-						}
-					case <-timer.C:
-						return
-					}
-				}
+				flushForwardQueue(ctx, queue, out)
 				return
 			}
 			queue = append(queue, ev)
@@ -214,6 +396,77 @@ func forwardEvents(in <-chan llm.Event, out chan<- llm.Event) {
 	}
 }
 
+// flushForwardQueue delivers queued events while the consumer keeps up, giving
+// each delta forwardFlushWait. Once it falls behind, the remaining deltas are
+// dropped, but terminal events are still delivered until ctx is done, so a slow
+// consumer always sees the run end.
+func flushForwardQueue(ctx context.Context, queue []llm.Event, out chan<- llm.Event) {
+	behind := false
+	for _, ev := range queue {
+		if isTerminalEvent(ev) {
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		if behind {
+			continue
+		}
+		if !sendWithin(out, ev, forwardFlushWait) {
+			behind = true
+		}
+	}
+}
+
+func sendWithin(out chan<- llm.Event, ev llm.Event, wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case out <- ev:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func isTerminalEvent(ev llm.Event) bool {
+	return ev.Type == llm.EventDone || ev.Type == llm.EventError
+}
+
+// awaitToolApproval emits an approval request for call when required and blocks
+// until a decision arrives or ctx is cancelled.
+func awaitToolApproval(
+	ctx context.Context,
+	out chan<- llm.Event,
+	requestApproval func(call llm.ToolCall) (<-chan bool, bool),
+	call llm.ToolCall,
+) (bool, error) {
+	if requestApproval == nil {
+		return true, nil
+	}
+	decision, required := requestApproval(call)
+	if !required {
+		return true, nil
+	}
+
+	pending := cloneToolCall(call)
+	if err := sendStreamEvent(ctx, out, llm.Event{
+		Type:     llm.EventToolApprovalRequired,
+		ToolCall: &pending,
+	}); err != nil {
+		return false, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case approved := <-decision:
+		return approved, nil
+	}
+}
+
 func dequeueMessages(fn func() []llm.Message) []llm.Message {
 	if fn == nil {
 		return nil
@@ -221,6 +474,39 @@ func dequeueMessages(fn func() []llm.Message) []llm.Message {
 	return fn()
 }
 
+// withToolEvents reports retries and partial output of call as events.
+func withToolEvents(ctx context.Context, out chan<- llm.Event, call llm.ToolCall) context.Context {
+	return withToolProgressEvents(withToolRetryEvents(ctx, out, call), out, call)
+}
+
+// withToolProgressEvents reports partial tool output of call as EventToolProgress.
+func withToolProgressEvents(ctx context.Context, out chan<- llm.Event, call llm.ToolCall) context.Context {
+	return agenttool.WithProgressReporter(ctx, func(chunk string) {
+		running := cloneToolCall(call)
+		_ = sendStreamEvent(ctx, out, llm.Event{
+			Type:            llm.EventToolProgress,
+			ToolCall:        &running,
+			ToolOutputDelta: chunk,
+		})
+	})
+}
+
+// withToolRetryEvents reports registry retries of call as EventToolRetry.
+func withToolRetryEvents(ctx context.Context, out chan<- llm.Event, call llm.ToolCall) context.Context {
+	return agenttool.WithRetryObserver(ctx, func(attempt agenttool.RetryAttempt) {
+		retried := cloneToolCall(call)
+		_ = sendStreamEvent(ctx, out, llm.Event{
+			Type:     llm.EventToolRetry,
+			ToolCall: &retried,
+			ToolRetry: &llm.ToolRetry{
+				Attempt: attempt.Attempt,
+				Delay:   attempt.Delay,
+				Err:     attempt.Err,
+			},
+		})
+	})
+}
+
 func sendStreamEvent(ctx context.Context, out chan<- llm.Event, ev llm.Event) error {
 	select {
 	case <-ctx.Done():
@@ -248,14 +534,24 @@ func appendAndEmitToolResult(
 	})
 }
 
+// assistantAccumulator rebuilds the assistant message from stream events,
+// keeping text, thinking, and tool calls in the order the model produced
+// them. Provider block indices tie deltas and stops to their block; text
+// deltas from providers without block events extend the last open text block.
 type assistantAccumulator struct {
-	text          strings.Builder
+	blocks []llm.ContentBlock
+	// indices maps open provider block indices to positions in blocks.
+	indices map[int64]int
+	// openText is the position of the text block receiving deltas, or -1.
+	openText      int
 	toolCallOrder []string
 	toolCallsByID map[string]llm.ToolCall
 }
 
 func newAssistantAccumulator() *assistantAccumulator {
 	return &assistantAccumulator{
+		indices:       make(map[int64]int),
+		openText:      -1,
 		toolCallsByID: make(map[string]llm.ToolCall),
 	}
 }
@@ -263,11 +559,46 @@ func newAssistantAccumulator() *assistantAccumulator {
 func (a *assistantAccumulator) consume(ev llm.Event) {
 	switch ev.Type {
 	case llm.EventContentBlockStart:
-		if ev.ContentBlockStart != nil && ev.ContentBlockStart.Type == "text" && ev.ContentBlockStart.Text != "" {
-			a.text.WriteString(ev.ContentBlockStart.Text)
+		if ev.ContentBlockStart == nil {
+			return
+		}
+		start := ev.ContentBlockStart
+		switch llm.ContentType(start.Type) {
+		case llm.ContentTypeText:
+			a.openText = a.appendBlock(start.Index, llm.ContentBlock{Type: llm.ContentTypeText, Text: start.Text})
+		case llm.ContentTypeThinking:
+			a.appendBlock(start.Index, llm.ContentBlock{
+				Type:      llm.ContentTypeThinking,
+				Thinking:  start.Thinking,
+				Signature: start.Signature,
+			})
+		case llm.ContentTypeRedactedThinking:
+			a.appendBlock(start.Index, llm.ContentBlock{
+				Type: llm.ContentTypeRedactedThinking,
+				Data: start.Data,
+			})
+		}
+	case llm.EventContentBlockStop:
+		if ev.ContentBlockStop == nil {
+			return
+		}
+		if pos, ok := a.indices[ev.ContentBlockStop.Index]; ok {
+			delete(a.indices, ev.ContentBlockStop.Index)
+			if pos == a.openText {
+				a.openText = -1
+			}
+		}
+	case llm.EventThinkingDelta:
+		if pos := a.lastBlock(llm.ContentTypeThinking); pos >= 0 {
+			a.blocks[pos].Thinking += ev.ThinkingDelta
+			a.blocks[pos].Signature += ev.SignatureDelta
 		}
 	case llm.EventTextDelta:
-		a.text.WriteString(ev.TextDelta)
+		if a.openText < 0 {
+			a.openText = len(a.blocks)
+			a.blocks = append(a.blocks, llm.ContentBlock{Type: llm.ContentTypeText})
+		}
+		a.blocks[a.openText].Text += ev.TextDelta
 	case llm.EventToolCallStart, llm.EventToolCallEnd:
 		if ev.ToolCall != nil {
 			a.upsertToolCall(*ev.ToolCall)
@@ -275,9 +606,28 @@ func (a *assistantAccumulator) consume(ev llm.Event) {
 	}
 }
 
+// appendBlock adds block, records it under the provider index, and returns
+// its position.
+func (a *assistantAccumulator) appendBlock(index int64, block llm.ContentBlock) int {
+	a.blocks = append(a.blocks, block)
+	a.indices[index] = len(a.blocks) - 1
+	return len(a.blocks) - 1
+}
+
+// lastBlock returns the position of the last block of type kind, or -1.
+func (a *assistantAccumulator) lastBlock(kind llm.ContentType) int {
+	if n := len(a.blocks); n > 0 && a.blocks[n-1].Type == kind {
+		return n - 1
+	}
+	return -1
+}
+
 func (a *assistantAccumulator) upsertToolCall(call llm.ToolCall) {
 	if _, exists := a.toolCallsByID[call.ID]; !exists {
 		a.toolCallOrder = append(a.toolCallOrder, call.ID)
+		a.blocks = append(a.blocks, llm.ContentBlock{Type: llm.ContentTypeToolUse, ToolCallID: call.ID})
+		// Text after a tool call starts a new block.
+		a.openText = -1
 	}
 	a.toolCallsByID[call.ID] = cloneToolCall(call)
 }
@@ -295,24 +645,26 @@ func (a *assistantAccumulator) buildMessage() *llm.Message {
 		}
 	}
 
-	if a.text.Len() == 0 && len(toolCalls) == 0 {
+	var content []llm.ContentBlock
+	hasText := false
+	for _, block := range a.blocks {
+		if block.Type == llm.ContentTypeText {
+			if block.Text == "" {
+				continue
+			}
+			hasText = true
+		}
+		content = append(content, block)
+	}
+	if !hasText && len(toolCalls) == 0 {
 		return nil
 	}
 
-	message := llm.Message{
+	return &llm.Message{
 		Role:      llm.RoleAssistant,
+		Content:   content,
 		ToolCalls: toolCalls,
 	}
-	if a.text.Len() > 0 {
-		message.Content = []llm.ContentBlock{
-			{
-				Type: llm.ContentTypeText,
-				Text: a.text.String(),
-			},
-		}
-	}
-
-	return &message
 }
 
 func cloneToolCall(call llm.ToolCall) llm.ToolCall {
@@ -333,5 +685,18 @@ func skipToolCall(call llm.ToolCall) llm.Message {
 	}
 }
 
+func denyToolCall(call llm.ToolCall) llm.Message {
+	return llm.Message{
+		Role: llm.RoleTool,
+		ToolResult: &llm.ToolResult{
+			ToolCallID: call.ID,
+			ToolName:   call.Name,
+			Content:    deniedToolCallMessage,
+			IsError:    true,
+		},
+	}
+}
+
 const forwardFlushWait = 50 * time.Millisecond
 const skippedToolCallMessage = "Skipped due to queued user message."
+const deniedToolCallMessage = "Tool call denied by user."
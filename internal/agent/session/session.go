@@ -2,15 +2,23 @@ package session
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	agenttool "gar/internal/agent/tool"
 	"gar/internal/llm"
 	sessionstore "gar/internal/session"
 )
@@ -20,6 +28,43 @@ const (
 	defaultCompactionKeep      = 24
 	compactionSummaryMaxLines  = 40
 	compactionSummaryMaxChars  = 6000
+
+	summaryMaxTokens = 1024
+
+	// summaryEntryMaxRunes bounds each entry in a summary transcript, and
+	// sessionSummaryMaxRunes the whole transcript Summarize sends, so long
+	// sessions stay well inside the context window.
+	summaryEntryMaxRunes   = 2000
+	sessionSummaryMaxRunes = 120000
+
+	// maxUndoEntries bounds the in-memory stack of file changes /undo can revert.
+	maxUndoEntries = 20
+
+	// defaultContextWarnRatio is the share of the context window at which a
+	// request triggers EventContextWarning.
+	defaultContextWarnRatio = 0.9
+	// charsPerToken and imageTokenEstimate drive EstimateTokens.
+	charsPerToken      = 4
+	imageTokenEstimate = 1600
+
+	// autoNameMaxRunes bounds a session name derived from the first message.
+	autoNameMaxRunes = 48
+
+	// compressPendingBytes is how much plaintext a compressed session may
+	// collect before Finalize folds it into the gzip file. Compacting after
+	// every turn would rewrite the whole session each time.
+	compressPendingBytes = 256 << 10
+
+	// projectPromptMaxBytes bounds how much of a project prompt file is sent
+	// with every request, so a large CLAUDE.md cannot crowd out the context.
+	projectPromptMaxBytes = 32 << 10
+)
+
+// Queue kinds name the steering and follow-up queues, as recorded in queue
+// entries and accepted by CancelQueued.
+const (
+	QueueSteering = "steering"
+	QueueFollowUp = "follow_up"
 )
 
 var (
@@ -27,8 +72,25 @@ var (
 	ErrSessionIDRequired    = errors.New("agent session id is required")
 	ErrSessionStoreRequired = errors.New("session store is required")
 	ErrQueueUnsupported     = errors.New("runner does not support queued messages")
+	ErrAlreadyQueued        = errors.New("message is already queued")
+	ErrCancelUnsupported    = errors.New("runner does not support cancellation")
 	ErrBranchTargetNotFound = errors.New("branch target not found")
+	ErrSessionExists        = errors.New("session already exists")
 	ErrCompactionNotNeeded  = errors.New("compaction not needed")
+	ErrNoUserMessage        = errors.New("no user message on current branch")
+	ErrModelRequired        = errors.New("model name is required")
+	ErrBookmarkNameRequired = errors.New("bookmark name is required")
+	ErrBookmarkNotFound     = errors.New("bookmark not found")
+	ErrNothingToBookmark    = errors.New("session has no entries to bookmark")
+	ErrNothingToUndo        = errors.New("no file change to undo")
+	ErrImageRequired        = errors.New("image data and media type are required")
+	ErrUnknownTool          = errors.New("unknown tool")
+	ErrInvalidToolChoice    = errors.New("invalid tool choice")
+	ErrPlanModeUnsupported  = errors.New("runner does not support plan mode")
+	ErrQueuedNotFound       = errors.New("queued message not found")
+	ErrQueuedDelivered      = errors.New("queued message was already delivered")
+	ErrNothingToSummarize   = errors.New("no conversation to summarize")
+	ErrCompactionConflict   = errors.New("session changed during compaction")
 )
 
 // Runner executes one LLM request as an event stream.
@@ -43,6 +105,26 @@ type QueueRunner interface {
 	ClearAllQueues()
 }
 
+// QueueRemover is the optional contract for withdrawing one queued message,
+// matched by its text, before the runner delivers it. It reports false when
+// the message is no longer queued.
+type QueueRemover interface {
+	RemoveSteering(text string) bool
+	RemoveFollowUp(text string) bool
+}
+
+// CancelRunner is the optional run cancellation contract.
+type CancelRunner interface {
+	Cancel()
+}
+
+// PlanRunner is the optional plan mode contract: while enabled, the runner
+// offers and executes only read-only tools.
+type PlanRunner interface {
+	SetPlanMode(enabled bool)
+	PlanMode() bool
+}
+
 // Config configures one AgentSession.
 type Config struct {
 	Runner              Runner
@@ -54,6 +136,59 @@ type Config struct {
 	Meta                map[string]any
 	AutoCompactMessages int
 	CompactionKeep      int
+
+	// AutoCompactTokens, when positive, triggers auto-compaction once the latest
+	// reported input tokens exceed it instead of using AutoCompactMessages.
+	AutoCompactTokens int
+
+	// ContextWindows maps model names, or model name prefixes, to their context
+	// window in tokens. A request estimated above ContextWarnRatio of the window
+	// (0.9 when unset) emits EventContextWarning ahead of the run's events.
+	ContextWindows   map[string]int
+	ContextWarnRatio float64
+	// CompactOnContextWarning compacts before sending a request that would
+	// trigger the context warning.
+	CompactOnContextWarning bool
+
+	// PersistThinking records extended thinking as "thinking" entries. They are
+	// kept for inspection only and never rebuilt into model context.
+	PersistThinking bool
+
+	// Summarizer, when set, writes compaction summaries with a one-shot model request.
+	Summarizer Runner
+
+	// Provider names the provider behind Runner; it is recorded with Model
+	// on assistant entries.
+	Provider string
+
+	// DedupeQueue makes QueueSteer and QueueFollowUp return ErrAlreadyQueued
+	// instead of queueing a message identical to one already waiting in the
+	// same queue.
+	DedupeQueue bool
+
+	// Temperature, when set, is sent with every request the session builds.
+	Temperature *float64
+	// ThinkingBudget enables extended thinking in every request the session
+	// builds. Zero disables it.
+	ThinkingBudget int
+	// CacheSystem asks the provider to cache the system prompt and tool
+	// definitions of every request the session builds.
+	CacheSystem bool
+
+	// ProjectPromptFiles are candidate project instruction files. The first
+	// one present is read whenever a session loads and prepended to the
+	// session system prompt in every request, so edits apply to the next
+	// session. See ReadProjectPrompt.
+	ProjectPromptFiles []string
+
+	// Metadata is sent with every request the session builds. Providers only
+	// accept user_id, which defaults to an opaque tag derived from the
+	// current session ID so provider dashboards can group its requests.
+	Metadata map[string]string
+
+	// AutoName names an unnamed session after the first line of its first
+	// user message, as if /name had been run.
+	AutoName bool
 }
 
 // CompactionResult reports one compaction run.
@@ -84,20 +219,60 @@ type TreeNode struct {
 	Children []TreeNode
 }
 
+// Bookmark is a named pointer to one entry in the session tree.
+type Bookmark struct {
+	Name    string
+	EntryID string
+}
+
+// UndoResult reports one file change reverted by Undo.
+type UndoResult struct {
+	ToolName string
+	Path     string
+	// Removed is true when the undone tool call had created the file.
+	Removed bool
+}
+
+// undoEntry is one file change recorded from a tool result.
+type undoEntry struct {
+	toolName string
+	snapshot agenttool.UndoSnapshot
+}
+
 // AgentSession is the core coding-agent loop abstraction for gar.
 type AgentSession struct {
 	runner      Runner
+	summarizer  Runner
 	queueRunner QueueRunner
+	canceler    CancelRunner
+	planner     PlanRunner
 	store       *sessionstore.Store
 
-	sessionID string
-	model     string
-	maxTokens int
-	tools     []llm.ToolSpec
-	baseMeta  map[string]any
+	sessionID      string
+	model          string
+	provider       string
+	maxTokens      int
+	temperature    *float64
+	thinkingBudget int
+	cacheSystem    bool
+	tools          []llm.ToolSpec
+	baseMeta       map[string]any
+	metadata       map[string]string
+
+	projectPromptFiles []string
+	// projectPrompt is the project file content read at the last load.
+	projectPrompt string
 
 	autoCompactMessages int
+	autoCompactTokens   int
 	compactionKeep      int
+	persistThinking     bool
+	dedupeQueue         bool
+	autoName            bool
+
+	contextWindows          map[string]int
+	contextWarnRatio        float64
+	compactOnContextWarning bool
 
 	mu              sync.Mutex
 	entries         []sessionstore.Entry
@@ -106,10 +281,22 @@ type AgentSession struct {
 	nextEntryID     int
 	conversation    []llm.Message
 	assistantBuffer strings.Builder
+	thinkingBuffer  strings.Builder
 	latestUsage     *llm.Usage
+	inputTokens     int
 	steeringQueued  []string
 	followUpQueued  []string
 	sessionName     string
+	systemPrompt    string
+	bookmarks       []Bookmark
+	undoStack       []undoEntry
+	modifiedFiles   []agenttool.UndoSnapshot
+	pendingImages   []llm.ContentBlock
+	skippedLines    []sessionstore.LineError
+	// toolChoice applies to the next run, then reverts to the provider default
+	// unless toolChoiceSticky is set. The zero value leaves it to the provider.
+	toolChoice       llm.ToolChoice
+	toolChoiceSticky bool
 }
 
 // New constructs an AgentSession and loads any existing JSONL entries.
@@ -124,15 +311,33 @@ func New(ctx context.Context, cfg Config) (*AgentSession, error) {
 
 	s := &AgentSession{
 		runner:              cfg.Runner,
+		summarizer:          cfg.Summarizer,
 		store:               cfg.Store,
 		sessionID:           id,
 		model:               strings.TrimSpace(cfg.Model),
+		provider:            strings.TrimSpace(cfg.Provider),
 		maxTokens:           cfg.MaxTokens,
+		temperature:         cloneTemperature(cfg.Temperature),
+		thinkingBudget:      cfg.ThinkingBudget,
+		cacheSystem:         cfg.CacheSystem,
 		tools:               cloneToolSpecs(cfg.Tools),
 		baseMeta:            cloneMeta(cfg.Meta),
+		metadata:            cloneMetadata(cfg.Metadata),
+		projectPromptFiles:  append([]string(nil), cfg.ProjectPromptFiles...),
 		autoCompactMessages: cfg.AutoCompactMessages,
+		autoCompactTokens:   cfg.AutoCompactTokens,
+		persistThinking:     cfg.PersistThinking,
 		compactionKeep:      cfg.CompactionKeep,
+		dedupeQueue:         cfg.DedupeQueue,
+		autoName:            cfg.AutoName,
 		byID:                make(map[string]sessionstore.Entry),
+
+		contextWindows:          cloneContextWindows(cfg.ContextWindows),
+		contextWarnRatio:        cfg.ContextWarnRatio,
+		compactOnContextWarning: cfg.CompactOnContextWarning,
+	}
+	if s.contextWarnRatio <= 0 || s.contextWarnRatio > 1 {
+		s.contextWarnRatio = defaultContextWarnRatio
 	}
 	if s.autoCompactMessages <= 0 {
 		s.autoCompactMessages = defaultAutoCompactMessages
@@ -144,19 +349,28 @@ func New(ctx context.Context, cfg Config) (*AgentSession, error) {
 	if runner, ok := cfg.Runner.(QueueRunner); ok {
 		s.queueRunner = runner
 	}
+	if runner, ok := cfg.Runner.(CancelRunner); ok {
+		s.canceler = runner
+	}
+	if runner, ok := cfg.Runner.(PlanRunner); ok {
+		s.planner = runner
+	}
 
 	if cfg.Store != nil {
-		loaded, err := cfg.Store.Load(ctx, id)
+		loaded, skipped, err := cfg.Store.LoadLenient(ctx, id)
 		if err != nil && !errors.Is(err, sessionstore.ErrSessionNotFound) {
 			return nil, err
 		}
 		if len(loaded) > 0 {
 			s.entries = append(s.entries, loaded...)
 		}
+		s.skippedLines = skipped
 	}
 
 	s.reindexLocked()
 	s.conversation = s.rebuildConversationLocked()
+	s.replayQueuesLocked()
+	s.projectPrompt = ReadProjectPrompt(s.projectPromptFiles)
 
 	if len(s.entries) == 0 && len(s.baseMeta) > 0 {
 		if err := s.AppendMeta(ctx, s.baseMeta); err != nil {
@@ -195,6 +409,15 @@ func (s *AgentSession) Messages() []llm.Message {
 	return cloneMessages(s.conversation)
 }
 
+// MessagesWithModels returns the current branch conversation together with
+// the model that wrote each message; entries without a recorded model, and
+// non-assistant messages, map to "".
+func (s *AgentSession) MessagesWithModels() ([]llm.Message, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rebuildConversationWithModelsLocked()
+}
+
 // Entries returns a defensive copy of all known session entries.
 func (s *AgentSession) Entries() []sessionstore.Entry {
 	s.mu.Lock()
@@ -254,7 +477,10 @@ func (s *AgentSession) AppendMeta(ctx context.Context, data map[string]any) erro
 func (s *AgentSession) SetSessionName(ctx context.Context, name string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.setSessionNameLocked(ctx, name)
+}
 
+func (s *AgentSession) setSessionNameLocked(ctx context.Context, name string) error {
 	trimmed := strings.TrimSpace(name)
 	if err := s.appendEntryLocked(ctx, sessionstore.Entry{
 		Type: "session_info",
@@ -266,6 +492,109 @@ func (s *AgentSession) SetSessionName(ctx context.Context, name string) error {
 	return nil
 }
 
+// Model returns the model used for subsequent requests.
+func (s *AgentSession) Model() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.model
+}
+
+// ToolChoice returns the tool choice for the next run and whether it applies
+// to every run rather than just the next one.
+func (s *AgentSession) ToolChoice() (llm.ToolChoice, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.toolChoice, s.toolChoiceSticky
+}
+
+// SetToolChoice controls how the model may use tools, e.g. ToolChoiceNone for
+// a plan-only turn. Unless sticky, the choice applies to the next run only.
+// A ToolChoiceTool choice must name one of the session's tools.
+func (s *AgentSession) SetToolChoice(choice llm.ToolChoice, sticky bool) error {
+	choice.Name = strings.TrimSpace(choice.Name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch choice.Type {
+	case "", llm.ToolChoiceAuto:
+		choice = llm.ToolChoice{}
+	case llm.ToolChoiceAny, llm.ToolChoiceNone:
+		choice.Name = ""
+	case llm.ToolChoiceTool:
+		if !slices.ContainsFunc(s.tools, func(spec llm.ToolSpec) bool { return spec.Name == choice.Name }) {
+			return fmt.Errorf("%w: %q", ErrUnknownTool, choice.Name)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidToolChoice, choice.Type)
+	}
+	s.toolChoice = choice
+	s.toolChoiceSticky = sticky && choice.Type != ""
+	return nil
+}
+
+// ToolNames returns the names of the tools offered to the model.
+func (s *AgentSession) ToolNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.tools))
+	for _, spec := range s.tools {
+		names = append(names, spec.Name)
+	}
+	return names
+}
+
+// SetModel switches the model for subsequent requests and records the switch as a meta entry.
+func (s *AgentSession) SetModel(ctx context.Context, name string) error {
+	trimmed := strings.TrimSpace(name)
+	if trimmed == "" {
+		return ErrModelRequired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := json.Marshal(map[string]any{
+		"model":          trimmed,
+		"previous_model": s.model,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal meta: %w", err)
+	}
+	if err := s.appendEntryLocked(ctx, sessionstore.Entry{
+		Type: "meta",
+		Data: raw,
+	}); err != nil {
+		return err
+	}
+	s.model = trimmed
+	return nil
+}
+
+// SystemPrompt returns the session system prompt sent with every request.
+func (s *AgentSession) SystemPrompt() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.systemPrompt
+}
+
+// SetSystemPrompt stores one system prompt entry and updates in-memory state.
+// An empty text clears the prompt.
+func (s *AgentSession) SetSystemPrompt(ctx context.Context, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trimmed := strings.TrimSpace(text)
+	if err := s.appendEntryLocked(ctx, sessionstore.Entry{
+		Type:    "system",
+		Content: trimmed,
+	}); err != nil {
+		return err
+	}
+	s.systemPrompt = trimmed
+	return nil
+}
+
 // ListSessions returns persisted sessions sorted by newest first.
 func (s *AgentSession) ListSessions(ctx context.Context) ([]sessionstore.SessionInfo, error) {
 	if s.store == nil {
@@ -274,6 +603,14 @@ func (s *AgentSession) ListSessions(ctx context.Context) ([]sessionstore.Session
 	return s.store.List(ctx)
 }
 
+// SearchSessions scans all persisted sessions for entries matching query.
+func (s *AgentSession) SearchSessions(ctx context.Context, query string) ([]sessionstore.SearchHit, error) {
+	if s.store == nil {
+		return nil, ErrSessionStoreRequired
+	}
+	return s.store.Search(ctx, query)
+}
+
 // SwitchSession loads another session file into the current runtime.
 func (s *AgentSession) SwitchSession(ctx context.Context, sessionID string) error {
 	if s.store == nil {
@@ -284,17 +621,39 @@ func (s *AgentSession) SwitchSession(ctx context.Context, sessionID string) erro
 		return ErrSessionIDRequired
 	}
 
-	loaded, err := s.store.Load(ctx, target)
+	loaded, skipped, err := s.store.LoadLenient(ctx, target)
 	if err != nil {
 		return err
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	// Leaving the session closes it; compaction is best effort.
+	_ = s.compressLocked(ctx, 1)
 	s.switchSessionLocked(target, loaded)
+	s.skippedLines = skipped
 	return nil
 }
 
+// LoadWarning describes session lines skipped as corrupt when the current
+// session was loaded, or returns "" when every line decoded.
+func (s *AgentSession) LoadWarning() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.skippedLines) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(s.skippedLines))
+	for _, skipped := range s.skippedLines {
+		lines = append(lines, strconv.Itoa(skipped.Line))
+	}
+	noun := "line"
+	if len(lines) > 1 {
+		noun = "lines"
+	}
+	return fmt.Sprintf("Warning: skipped %d corrupt %s in session %s (%s %s).", len(lines), noun, s.sessionID, noun, strings.Join(lines, ", "))
+}
+
 // NewSession resets state to a fresh logical session id.
 func (s *AgentSession) NewSession(ctx context.Context, requestedID string) (string, error) {
 	id := strings.TrimSpace(requestedID)
@@ -305,6 +664,8 @@ func (s *AgentSession) NewSession(ctx context.Context, requestedID string) (stri
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// Leaving the session closes it; compaction is best effort.
+	_ = s.compressLocked(ctx, 1)
 	s.switchSessionLocked(id, nil)
 	if len(s.baseMeta) > 0 {
 		rawMeta, err := json.Marshal(s.baseMeta)
@@ -321,6 +682,24 @@ func (s *AgentSession) NewSession(ctx context.Context, requestedID string) (stri
 	return s.sessionID, nil
 }
 
+// AttachImage queues a base64 image to send with the next submitted user
+// message and returns how many images are now pending.
+func (s *AgentSession) AttachImage(mediaType, data string) (int, error) {
+	mediaType = strings.TrimSpace(mediaType)
+	if mediaType == "" || data == "" {
+		return 0, ErrImageRequired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pendingImages = append(s.pendingImages, llm.ContentBlock{
+		Type:      llm.ContentTypeImage,
+		MediaType: mediaType,
+		Data:      data,
+	})
+	return len(s.pendingImages), nil
+}
+
 // Submit appends a user message and starts one run.
 func (s *AgentSession) Submit(ctx context.Context, text string) (<-chan llm.Event, error) {
 	content := strings.TrimSpace(text)
@@ -329,30 +708,187 @@ func (s *AgentSession) Submit(ctx context.Context, text string) (<-chan llm.Even
 	}
 
 	s.mu.Lock()
-	if err := s.appendUserLocked(ctx, content); err != nil {
+	nameSession := s.autoName && s.sessionName == "" && !s.hasUserEntryLocked()
+	if err := s.appendUserLocked(ctx, content, s.pendingImages); err != nil {
 		s.mu.Unlock()
 		return nil, err
 	}
-	if _, err := s.compactLocked(ctx, s.autoCompactMessages, s.compactionKeep, ""); err != nil && !errors.Is(err, ErrCompactionNotNeeded) {
-		s.mu.Unlock()
-		return nil, err
+	s.pendingImages = nil
+	if name := autoSessionName(content); nameSession && name != "" {
+		if err := s.setSessionNameLocked(ctx, name); err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
 	}
-	req := s.buildRequestLocked()
+	req, warning, err := s.prepareRunLocked(ctx)
 	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startRun(ctx, req, warning)
+}
 
-	return s.runner.Run(ctx, req)
+// hasUserEntryLocked reports whether any user message was ever recorded, on
+// any branch, so auto-naming only considers a session's first message.
+func (s *AgentSession) hasUserEntryLocked() bool {
+	for _, entry := range s.entries {
+		if entry.Type == "user" {
+			return true
+		}
+	}
+	return false
+}
+
+// autoSessionName derives a display name from the first line of text,
+// collapsing whitespace, dropping control characters, and truncating to
+// autoNameMaxRunes.
+func autoSessionName(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	line = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, line)
+	name := strings.Join(strings.Fields(line), " ")
+	if runes := []rune(name); len(runes) > autoNameMaxRunes {
+		name = strings.TrimSpace(string(runes[:autoNameMaxRunes-1])) + "…"
+	}
+	return name
 }
 
 // Run starts one run without appending a new user message.
 func (s *AgentSession) Run(ctx context.Context) (<-chan llm.Event, error) {
 	s.mu.Lock()
-	if _, err := s.compactLocked(ctx, s.autoCompactMessages, s.compactionKeep, ""); err != nil && !errors.Is(err, ErrCompactionNotNeeded) {
-		s.mu.Unlock()
+	req, warning, err := s.prepareRunLocked(ctx)
+	s.mu.Unlock()
+	if err != nil {
 		return nil, err
 	}
+	return s.startRun(ctx, req, warning)
+}
+
+// prepareRunLocked compacts as configured and builds the next request,
+// returning a context warning when it is estimated to be near the window.
+func (s *AgentSession) prepareRunLocked(ctx context.Context) (*llm.Request, *llm.ContextWarning, error) {
+	if err := s.autoCompactLocked(ctx); err != nil {
+		return nil, nil, err
+	}
 	req := s.buildRequestLocked()
-	s.mu.Unlock()
-	return s.runner.Run(ctx, req)
+	warning := s.contextWarningLocked(req)
+	if warning != nil && s.compactOnContextWarning {
+		if _, err := s.compactLocked(ctx, 0, s.compactionKeep, ""); err != nil && !skippableCompactionError(err) {
+			return nil, nil, err
+		}
+		req = s.buildRequestLocked()
+		warning = s.contextWarningLocked(req)
+	}
+	if !s.toolChoiceSticky {
+		s.toolChoice = llm.ToolChoice{}
+	}
+	return req, warning, nil
+}
+
+// startRun starts the runner, emitting warning ahead of the run's own events.
+func (s *AgentSession) startRun(ctx context.Context, req *llm.Request, warning *llm.ContextWarning) (<-chan llm.Event, error) {
+	stream, err := s.runner.Run(ctx, req)
+	if err != nil || warning == nil {
+		return stream, err
+	}
+	out := make(chan llm.Event, 1)
+	go func() {
+		defer close(out)
+		out <- llm.Event{Type: llm.EventContextWarning, ContextWarning: warning}
+		for ev := range stream {
+			out <- ev
+		}
+	}()
+	return out, nil
+}
+
+// contextWarningLocked returns a warning when req is estimated above the
+// warn ratio of the current model's context window.
+func (s *AgentSession) contextWarningLocked(req *llm.Request) *llm.ContextWarning {
+	window := s.contextWindowLocked()
+	if window <= 0 {
+		return nil
+	}
+	estimated := estimateRequestTokens(req)
+	if float64(estimated) < s.contextWarnRatio*float64(window) {
+		return nil
+	}
+	return &llm.ContextWarning{EstimatedTokens: estimated, ContextWindow: window}
+}
+
+// contextWindowLocked looks up the current model by exact name, then by the
+// longest configured prefix.
+func (s *AgentSession) contextWindowLocked() int {
+	if window, ok := s.contextWindows[s.model]; ok {
+		return window
+	}
+	window, matched := 0, 0
+	for prefix, size := range s.contextWindows {
+		if len(prefix) > matched && strings.HasPrefix(s.model, prefix) {
+			window, matched = size, len(prefix)
+		}
+	}
+	return window
+}
+
+// EstimateTokens approximates the token count of messages at four characters
+// per token, with a flat estimate per image.
+func EstimateTokens(messages []llm.Message) int {
+	chars, images := 0, 0
+	for _, message := range messages {
+		for _, block := range message.Content {
+			if block.Type == llm.ContentTypeImage {
+				images++
+				continue
+			}
+			chars += len(block.Text) + len(block.Thinking)
+		}
+		for _, call := range message.ToolCalls {
+			chars += len(call.Name) + len(call.Arguments)
+		}
+		if message.ToolResult != nil {
+			chars += len(message.ToolResult.Content)
+		}
+	}
+	return (chars+charsPerToken-1)/charsPerToken + images*imageTokenEstimate
+}
+
+func estimateRequestTokens(req *llm.Request) int {
+	chars := len(req.System)
+	for _, tool := range req.Tools {
+		chars += len(tool.Name) + len(tool.Description) + len(tool.Schema)
+	}
+	return EstimateTokens(req.Messages) + (chars+charsPerToken-1)/charsPerToken
+}
+
+// Cancel asks the runner to abort the in-flight run. The run's stream still
+// delivers its terminal event, which RecordEvent uses to flush partial output.
+func (s *AgentSession) Cancel() error {
+	if s.canceler == nil {
+		return ErrCancelUnsupported
+	}
+	s.canceler.Cancel()
+	return nil
+}
+
+// SetPlanMode toggles the runner's plan mode, which keeps mutating tools such
+// as write, edit, and bash away from the model.
+func (s *AgentSession) SetPlanMode(enabled bool) error {
+	if s.planner == nil {
+		return ErrPlanModeUnsupported
+	}
+	s.planner.SetPlanMode(enabled)
+	return nil
+}
+
+// PlanMode reports whether the runner is in plan mode.
+func (s *AgentSession) PlanMode() bool {
+	return s.planner != nil && s.planner.PlanMode()
 }
 
 // QueueSteer queues a high-priority user message when the runner supports queues.
@@ -367,6 +903,12 @@ func (s *AgentSession) QueueSteer(text string) error {
 	if s.queueRunner == nil {
 		return ErrQueueUnsupported
 	}
+	if s.dedupeQueue && slices.Contains(s.steeringQueued, content) {
+		return ErrAlreadyQueued
+	}
+	if err := s.appendQueueEntryLocked(context.Background(), "queued", QueueSteering, content); err != nil {
+		return err
+	}
 	s.steeringQueued = append(s.steeringQueued, content)
 	s.queueRunner.Steer(userTextMessage(content))
 	return nil
@@ -384,6 +926,12 @@ func (s *AgentSession) QueueFollowUp(text string) error {
 	if s.queueRunner == nil {
 		return ErrQueueUnsupported
 	}
+	if s.dedupeQueue && slices.Contains(s.followUpQueued, content) {
+		return ErrAlreadyQueued
+	}
+	if err := s.appendQueueEntryLocked(context.Background(), "queued", QueueFollowUp, content); err != nil {
+		return err
+	}
 	s.followUpQueued = append(s.followUpQueued, content)
 	s.queueRunner.FollowUp(userTextMessage(content))
 	return nil
@@ -409,6 +957,10 @@ func (s *AgentSession) ClearQueue() (steering []string, followUp []string) {
 	defer s.mu.Unlock()
 	steering = append([]string(nil), s.steeringQueued...)
 	followUp = append([]string(nil), s.followUpQueued...)
+	if len(steering) > 0 || len(followUp) > 0 {
+		// Best effort: a failed tombstone only means cleared messages reappear on reload.
+		_ = s.appendEntryLocked(context.Background(), sessionstore.Entry{Type: "queue_cleared"})
+	}
 	s.steeringQueued = nil
 	s.followUpQueued = nil
 	if s.queueRunner != nil {
@@ -417,6 +969,50 @@ func (s *AgentSession) ClearQueue() (steering []string, followUp []string) {
 	return steering, followUp
 }
 
+// CancelQueued removes the message at index (0-based) from the kind queue and
+// returns it, leaving the other queued messages in order. The message is
+// withdrawn from the runner's queue first; when the runner has already taken
+// it, CancelQueued fails with ErrQueuedDelivered and changes nothing.
+func (s *AgentSession) CancelQueued(kind string, index int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var queue *[]string
+	switch kind {
+	case QueueSteering:
+		queue = &s.steeringQueued
+	case QueueFollowUp:
+		queue = &s.followUpQueued
+	default:
+		return "", fmt.Errorf("%w: unknown queue %q", ErrQueuedNotFound, kind)
+	}
+	if index < 0 || index >= len(*queue) {
+		return "", fmt.Errorf("%w: %s #%d (%d queued)", ErrQueuedNotFound, kind, index+1, len(*queue))
+	}
+	text := (*queue)[index]
+	if s.queueRunner != nil {
+		remover, ok := s.queueRunner.(QueueRemover)
+		if !ok {
+			return "", fmt.Errorf("%w: cannot withdraw queued messages", ErrQueueUnsupported)
+		}
+		remove, requeue := remover.RemoveSteering, s.queueRunner.Steer
+		if kind == QueueFollowUp {
+			remove, requeue = remover.RemoveFollowUp, s.queueRunner.FollowUp
+		}
+		if !remove(text) {
+			return "", fmt.Errorf("%w: %s #%d", ErrQueuedDelivered, kind, index+1)
+		}
+		if err := s.appendQueueEntryLocked(context.Background(), "queue_cancelled", kind, text); err != nil {
+			requeue(userTextMessage(text))
+			return "", err
+		}
+	} else if err := s.appendQueueEntryLocked(context.Background(), "queue_cancelled", kind, text); err != nil {
+		return "", err
+	}
+	*queue = slices.Delete(slices.Clone(*queue), index, index+1)
+	return text, nil
+}
+
 // RecordEvent consumes one stream event and updates session state.
 func (s *AgentSession) RecordEvent(ctx context.Context, ev llm.Event) error {
 	s.mu.Lock()
@@ -431,11 +1027,28 @@ func (s *AgentSession) RecordEvent(ctx context.Context, ev llm.Event) error {
 		if text == "" {
 			return nil
 		}
-		s.dequeueDeliveredLocked(text)
-		return s.appendUserLocked(ctx, text)
+		if kind, ok := s.dequeueDeliveredLocked(text); ok {
+			if err := s.appendQueueEntryLocked(ctx, "queue_consumed", kind, text); err != nil {
+				return err
+			}
+		}
+		return s.appendUserLocked(ctx, text, nil)
 	case llm.EventContentBlockStart:
-		if ev.ContentBlockStart != nil && ev.ContentBlockStart.Type == "text" && ev.ContentBlockStart.Text != "" {
+		if ev.ContentBlockStart == nil {
+			return nil
+		}
+		switch llm.ContentType(ev.ContentBlockStart.Type) {
+		case llm.ContentTypeText:
 			s.assistantBuffer.WriteString(ev.ContentBlockStart.Text)
+		case llm.ContentTypeThinking:
+			if s.persistThinking {
+				s.thinkingBuffer.WriteString(ev.ContentBlockStart.Thinking)
+			}
+		}
+		return nil
+	case llm.EventThinkingDelta:
+		if s.persistThinking {
+			s.thinkingBuffer.WriteString(ev.ThinkingDelta)
 		}
 		return nil
 	case llm.EventTextDelta:
@@ -445,16 +1058,25 @@ func (s *AgentSession) RecordEvent(ctx context.Context, ev llm.Event) error {
 		if ev.ToolCall == nil {
 			return nil
 		}
+		if err := s.flushThinkingLocked(ctx); err != nil {
+			return err
+		}
 		return s.appendEntryLocked(ctx, sessionstore.Entry{
-			Type:   "tool_call",
-			Name:   ev.ToolCall.Name,
-			Params: append(json.RawMessage(nil), ev.ToolCall.Arguments...),
+			Type:       "tool_call",
+			Name:       ev.ToolCall.Name,
+			ToolCallID: ev.ToolCall.ID,
+			Params:     append(json.RawMessage(nil), ev.ToolCall.Arguments...),
 		})
 	case llm.EventToolResult:
 		if ev.ToolResult == nil {
 			return nil
 		}
-		state, err := json.Marshal(map[string]any{"is_error": ev.ToolResult.IsError})
+		display := s.recordUndoLocked(ev.ToolResult)
+		stateData := map[string]any{"is_error": ev.ToolResult.IsError}
+		if display != nil {
+			stateData["display"] = display
+		}
+		state, err := json.Marshal(stateData)
 		if err != nil {
 			return fmt.Errorf("marshal tool_result state: %w", err)
 		}
@@ -465,6 +1087,7 @@ func (s *AgentSession) RecordEvent(ctx context.Context, ev llm.Event) error {
 				ToolName:   ev.ToolResult.ToolName,
 				Content:    ev.ToolResult.Content,
 				IsError:    ev.ToolResult.IsError,
+				Display:    display,
 			},
 		})
 		return s.appendEntryLocked(ctx, sessionstore.Entry{
@@ -478,6 +1101,7 @@ func (s *AgentSession) RecordEvent(ctx context.Context, ev llm.Event) error {
 		if ev.Usage != nil {
 			usage := *ev.Usage
 			s.latestUsage = &usage
+			s.inputTokens = contextTokens(usage)
 		}
 		return nil
 	case llm.EventDone, llm.EventError:
@@ -487,11 +1111,43 @@ func (s *AgentSession) RecordEvent(ctx context.Context, ev llm.Event) error {
 	}
 }
 
-// Finalize flushes any buffered assistant text.
+// Finalize flushes any buffered assistant text and, when the store has
+// compression enabled and enough plaintext has built up since the last
+// compaction, compacts the session file.
 func (s *AgentSession) Finalize(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.flushAssistantLocked(ctx)
+	if err := s.flushAssistantLocked(ctx); err != nil {
+		return err
+	}
+	return s.compressLocked(ctx, compressPendingBytes)
+}
+
+// Close flushes buffered assistant text and, when the store has compression
+// enabled, compacts the session file. Call it once the session is done with.
+func (s *AgentSession) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.flushAssistantLocked(ctx); err != nil {
+		return err
+	}
+	return s.compressLocked(ctx, 1)
+}
+
+// compressLocked compacts the current session file once at least minPending
+// bytes of plaintext await compression.
+func (s *AgentSession) compressLocked(ctx context.Context, minPending int64) error {
+	if s.store == nil || !s.store.Compression() {
+		return nil
+	}
+	pending, err := s.store.PendingSize(s.sessionID)
+	if err != nil || pending < minPending {
+		return err
+	}
+	if err := s.store.Compact(ctx, s.sessionID); err != nil && !errors.Is(err, sessionstore.ErrSessionNotFound) {
+		return err
+	}
+	return nil
 }
 
 // Compact runs manual compaction keeping the newest keepMessages conversation messages.
@@ -505,6 +1161,129 @@ func (s *AgentSession) Compact(ctx context.Context, keepMessages int, instructio
 	return s.compactLocked(ctx, 0, keepMessages, instructions)
 }
 
+// PreviewCompaction reports what Compact would drop and a digest summary of
+// it without summarizing through the model, persisting, or rebuilding context.
+func (s *AgentSession) PreviewCompaction(keepMessages int, instructions string) (CompactionResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dropped, firstKeptID, err := s.compactionPlanLocked(keepMessages)
+	if err != nil {
+		return CompactionResult{}, err
+	}
+	return CompactionResult{
+		Summary:         buildCompactionSummary(dropped, instructions),
+		DroppedMessages: len(dropped),
+		FirstKeptEntry:  firstKeptID,
+	}, nil
+}
+
+// Summarize asks the model for a short bullet summary of the current branch
+// for the user to read. Unlike Compact it records nothing and leaves the model
+// context as it is. The request goes to the summarizer when one is configured
+// and to the runner otherwise. Only what the model currently sees is sent:
+// the latest compaction summary and the messages after it, trimmed from the
+// oldest end to sessionSummaryMaxRunes.
+func (s *AgentSession) Summarize(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	transcript := s.summaryTranscriptLocked()
+	runner := s.summarizer
+	if runner == nil {
+		runner = s.runner
+	}
+	req := &llm.Request{
+		Model:     s.model,
+		System:    sessionSummarySystemPrompt,
+		Messages:  []llm.Message{userTextMessage(buildSummaryPrompt(transcript, ""))},
+		MaxTokens: summaryMaxTokens,
+	}
+	s.mu.Unlock()
+	if len(transcript) == 0 {
+		return "", ErrNothingToSummarize
+	}
+
+	stream, err := runner.Run(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("summarize session: %w", err)
+	}
+	var text strings.Builder
+	var streamErr error
+	for ev := range stream {
+		switch ev.Type {
+		case llm.EventTextDelta:
+			text.WriteString(ev.TextDelta)
+		case llm.EventError:
+			if streamErr == nil {
+				streamErr = ev.Err
+			}
+			if streamErr == nil {
+				streamErr = errors.New("model stream failed")
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if streamErr != nil {
+		return "", fmt.Errorf("summarize session: %w", streamErr)
+	}
+	summary := strings.TrimSpace(text.String())
+	if summary == "" {
+		return "", errors.New("summarize session: model returned no text")
+	}
+	return summary, nil
+}
+
+// summaryTranscriptLocked picks the entries Summarize sends. See Summarize.
+func (s *AgentSession) summaryTranscriptLocked() []sessionstore.Entry {
+	branch := s.branchEntriesLocked(s.leafID)
+	var compaction *sessionstore.Entry
+	visible := branch
+	for i := len(branch) - 1; i >= 0; i-- {
+		if branch[i].Type != "compaction" {
+			continue
+		}
+		compaction = &branch[i]
+		start := i
+		if firstKeptID := compactionFirstKeptID(branch[i]); firstKeptID != "" {
+			for j := 0; j < i; j++ {
+				if branch[j].ID == firstKeptID {
+					start = j
+					break
+				}
+			}
+		}
+		visible = append(slices.Clone(branch[start:i]), branch[i+1:]...)
+		break
+	}
+
+	var messages []sessionstore.Entry
+	for _, entry := range visible {
+		switch entry.Type {
+		case "user", "assistant", "tool_result":
+			messages = append(messages, entry)
+		}
+	}
+	budget := sessionSummaryMaxRunes
+	if compaction != nil {
+		budget -= summaryEntryRunes(*compaction)
+	}
+	first := len(messages)
+	for first > 0 && budget >= summaryEntryRunes(messages[first-1]) {
+		first--
+		budget -= summaryEntryRunes(messages[first])
+	}
+	if compaction == nil {
+		return messages[first:]
+	}
+	return append([]sessionstore.Entry{*compaction}, messages[first:]...)
+}
+
+// summaryEntryRunes estimates the runes entry adds to a summary transcript.
+func summaryEntryRunes(entry sessionstore.Entry) int {
+	return min(utf8.RuneCountInString(strings.TrimSpace(entry.Content)), summaryEntryMaxRunes) + len(entry.Type) + len(entry.Name) + 8
+}
+
 // SwitchBranch moves the leaf pointer to targetID and rebuilds conversation context.
 func (s *AgentSession) SwitchBranch(targetID string) error {
 	target := strings.TrimSpace(targetID)
@@ -515,72 +1294,454 @@ func (s *AgentSession) SwitchBranch(targetID string) error {
 		s.leafID = ""
 		s.conversation = nil
 		s.assistantBuffer.Reset()
+		s.thinkingBuffer.Reset()
 		s.latestUsage = nil
+		s.inputTokens = 0
 		return nil
 	}
-	if _, ok := s.byID[target]; !ok {
-		return fmt.Errorf("%w: %s", ErrBranchTargetNotFound, target)
+	if _, ok := s.byID[target]; !ok {
+		return fmt.Errorf("%w: %s", ErrBranchTargetNotFound, target)
+	}
+	s.leafID = target
+	s.conversation = s.rebuildConversationLocked()
+	s.assistantBuffer.Reset()
+	s.thinkingBuffer.Reset()
+	s.latestUsage = nil
+	s.inputTokens = 0
+	return nil
+}
+
+// ForkSession copies the branch ending at entryID into a new session file and
+// switches to it, leaving the original session untouched. An empty newID
+// generates one. It returns the new session id.
+func (s *AgentSession) ForkSession(ctx context.Context, entryID, newID string) (string, error) {
+	if s.store == nil {
+		return "", ErrSessionStoreRequired
+	}
+	target := strings.TrimSpace(entryID)
+	id := strings.TrimSpace(newID)
+	if id == "" {
+		id = s.generateSessionID(ctx)
+	}
+	if _, err := s.store.Load(ctx, id); err == nil {
+		return "", fmt.Errorf("%w: %s", ErrSessionExists, id)
+	} else if !errors.Is(err, sessionstore.ErrSessionNotFound) {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[target]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrBranchTargetNotFound, target)
+	}
+	branch := s.branchEntriesLocked(target)
+	for _, entry := range branch {
+		if err := s.store.Append(ctx, id, entry); err != nil {
+			return "", fmt.Errorf("write fork %s: %w", id, err)
+		}
+	}
+	s.switchSessionLocked(id, branch)
+	return s.sessionID, nil
+}
+
+// UsageTotals sums the usage recorded on assistant entries of the current branch.
+func (s *AgentSession) UsageTotals() llm.Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sumEntryUsage(s.branchEntriesLocked(s.leafID))
+}
+
+// Metrics summarizes the current branch for the inspector.
+type Metrics struct {
+	// Turns counts user messages.
+	Turns int
+	// ToolCounts counts tool calls by tool name.
+	ToolCounts map[string]int
+	// Usage sums the usage recorded on assistant entries, including cost.
+	Usage llm.Usage
+}
+
+// Metrics derives turn, tool-call, and usage counters from the stored
+// entries of the current branch, so a resumed session shows its history.
+func (s *AgentSession) Metrics() Metrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	branch := s.branchEntriesLocked(s.leafID)
+	metrics := Metrics{
+		ToolCounts: make(map[string]int),
+		Usage:      sumEntryUsage(branch),
+	}
+	for _, entry := range branch {
+		switch entry.Type {
+		case "user":
+			metrics.Turns++
+		case "tool_call":
+			name := strings.TrimSpace(entry.Name)
+			if name == "" {
+				name = "unknown"
+			}
+			metrics.ToolCounts[name]++
+		}
+	}
+	return metrics
+}
+
+func sumEntryUsage(entries []sessionstore.Entry) llm.Usage {
+	var totals llm.Usage
+	for _, entry := range entries {
+		if entry.Type != "assistant" || len(entry.Usage) == 0 {
+			continue
+		}
+		var usage llm.Usage
+		if err := json.Unmarshal(entry.Usage, &usage); err != nil {
+			continue
+		}
+		totals = totals.Add(usage)
+	}
+	return totals
+}
+
+// RewindToLastUser moves the leaf back to the latest user entry on the current
+// branch, dropping the reply that followed it from context, and returns its text.
+func (s *AgentSession) RewindToLastUser() (string, error) {
+	return s.rewindToLastUser(true)
+}
+
+// RewindBeforeLastUser moves the leaf to the parent of the most recent user
+// entry on the current branch and returns that entry's text. Submitting
+// again starts a sibling branch, so the original message stays in the tree.
+func (s *AgentSession) RewindBeforeLastUser() (string, error) {
+	return s.rewindToLastUser(false)
+}
+
+func (s *AgentSession) rewindToLastUser(keepUser bool) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	branch := s.branchEntriesLocked(s.leafID)
+	for i := len(branch) - 1; i >= 0; i-- {
+		if branch[i].Type != "user" {
+			continue
+		}
+		s.leafID = branch[i].ID
+		if !keepUser {
+			s.leafID = branch[i].ParentID
+		}
+		s.conversation = s.rebuildConversationLocked()
+		s.assistantBuffer.Reset()
+		s.thinkingBuffer.Reset()
+		s.latestUsage = nil
+		s.inputTokens = 0
+		return branch[i].Content, nil
+	}
+	return "", ErrNoUserMessage
+}
+
+// AddBookmark records a "bookmark" entry naming the current leaf. Reusing a
+// name moves the bookmark to the current leaf.
+func (s *AgentSession) AddBookmark(ctx context.Context, name string) error {
+	trimmed := strings.TrimSpace(name)
+	if trimmed == "" {
+		return ErrBookmarkNameRequired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target := s.leafID
+	if target == "" {
+		return ErrNothingToBookmark
+	}
+	raw, err := json.Marshal(map[string]string{"target": target})
+	if err != nil {
+		return fmt.Errorf("marshal bookmark: %w", err)
+	}
+	if err := s.appendEntryLocked(ctx, sessionstore.Entry{
+		Type: "bookmark",
+		Name: trimmed,
+		Data: raw,
+	}); err != nil {
+		return err
+	}
+	s.setBookmarkLocked(trimmed, target)
+	return nil
+}
+
+// Bookmarks returns the session bookmarks in creation order.
+func (s *AgentSession) Bookmarks() []Bookmark {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Bookmark(nil), s.bookmarks...)
+}
+
+// GotoBookmark switches the branch to the entry named by a bookmark.
+func (s *AgentSession) GotoBookmark(name string) (string, error) {
+	trimmed := strings.TrimSpace(name)
+
+	s.mu.Lock()
+	target := ""
+	for _, bookmark := range s.bookmarks {
+		if bookmark.Name == trimmed {
+			target = bookmark.EntryID
+		}
+	}
+	s.mu.Unlock()
+
+	if target == "" {
+		return "", fmt.Errorf("%w: %s", ErrBookmarkNotFound, trimmed)
+	}
+	if err := s.SwitchBranch(target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// Undo restores the file changed by the most recent file-modifying tool call
+// and pops it from the undo stack. Files the tool created are removed.
+func (s *AgentSession) Undo() (UndoResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.undoStack) == 0 {
+		return UndoResult{}, ErrNothingToUndo
+	}
+	last := s.undoStack[len(s.undoStack)-1]
+	if err := last.snapshot.Restore(); err != nil {
+		return UndoResult{}, err
+	}
+	s.undoStack = s.undoStack[:len(s.undoStack)-1]
+	return UndoResult{
+		ToolName: last.toolName,
+		Path:     last.snapshot.Path,
+		Removed:  !last.snapshot.PreviousExists,
+	}, nil
+}
+
+// ModifiedFiles returns the files changed by tools in this session, in the
+// order they were first modified. Each snapshot holds the file's state
+// before its first change, so it survives later edits and undos.
+func (s *AgentSession) ModifiedFiles() []agenttool.UndoSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]agenttool.UndoSnapshot(nil), s.modifiedFiles...)
+}
+
+// Tree returns the current session entry tree.
+func (s *AgentSession) Tree() []TreeNode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return buildTree(s.entries, s.byID)
+}
+
+// TreeLines renders the tree for slash command output.
+func (s *AgentSession) TreeLines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	roots := buildTree(s.entries, s.byID)
+	if len(roots) == 0 {
+		return nil
+	}
+	labels := make(map[string][]string, len(s.bookmarks))
+	for _, bookmark := range s.bookmarks {
+		labels[bookmark.EntryID] = append(labels[bookmark.EntryID], bookmark.Name)
+	}
+	lines := make([]string, 0, len(s.entries))
+	var walk func(node TreeNode, depth int)
+	walk = func(node TreeNode, depth int) {
+		indent := strings.Repeat("  ", depth)
+		marker := " "
+		if node.Entry.ID == s.leafID {
+			marker = "*"
+		}
+		line := fmt.Sprintf("%s %s%s %s", marker, indent, node.Entry.ID, EntryPreview(node.Entry))
+		if names := labels[node.Entry.ID]; len(names) > 0 {
+			line += " [" + strings.Join(names, ", ") + "]"
+		}
+		lines = append(lines, line)
+		for _, child := range node.Children {
+			walk(child, depth+1)
+		}
+	}
+	for _, root := range roots {
+		walk(root, 0)
+	}
+	return lines
+}
+
+// recordUndoLocked pushes the undo snapshot carried by a successful tool
+// result and returns its display with the snapshot stripped, so prior file
+// contents stay in memory only.
+func (s *AgentSession) recordUndoLocked(result *llm.ToolResult) *llm.ToolDisplay {
+	if result.Display == nil {
+		return nil
+	}
+	snapshot, ok := agenttool.UndoSnapshotFromPayload(result.Display.Payload)
+	if !ok {
+		return result.Display
+	}
+	if !result.IsError {
+		s.undoStack = append(s.undoStack, undoEntry{toolName: result.ToolName, snapshot: snapshot})
+		if len(s.undoStack) > maxUndoEntries {
+			s.undoStack = s.undoStack[len(s.undoStack)-maxUndoEntries:]
+		}
+		s.trackModifiedLocked(snapshot)
+	}
+	return &llm.ToolDisplay{
+		Type:    result.Display.Type,
+		Payload: agenttool.WithoutUndoSnapshot(result.Display.Payload),
+	}
+}
+
+// trackModifiedLocked records snapshot unless its file was already modified.
+func (s *AgentSession) trackModifiedLocked(snapshot agenttool.UndoSnapshot) {
+	for _, existing := range s.modifiedFiles {
+		if existing.Path == snapshot.Path {
+			return
+		}
+	}
+	s.modifiedFiles = append(s.modifiedFiles, snapshot)
+}
+
+func (s *AgentSession) setBookmarkLocked(name, entryID string) {
+	for i := range s.bookmarks {
+		if s.bookmarks[i].Name == name {
+			s.bookmarks[i].EntryID = entryID
+			return
+		}
+	}
+	s.bookmarks = append(s.bookmarks, Bookmark{Name: name, EntryID: entryID})
+}
+
+func (s *AgentSession) buildRequestLocked() *llm.Request {
+	return &llm.Request{
+		Model:          s.model,
+		System:         joinSystemPrompts(s.projectPrompt, s.systemPrompt),
+		Messages:       cloneMessages(s.conversation),
+		Tools:          cloneToolSpecs(s.tools),
+		MaxTokens:      s.maxTokens,
+		Temperature:    cloneTemperature(s.temperature),
+		ToolChoice:     s.toolChoice,
+		Metadata:       s.requestMetadataLocked(),
+		ThinkingBudget: s.thinkingBudget,
+		CacheSystem:    s.cacheSystem,
+	}
+}
+
+// ReadProjectPrompt returns the trimmed content of the first readable,
+// non-empty file in paths, or "" when there is none. Only the first
+// projectPromptMaxBytes of the file are used, followed by a note that the
+// rest was left out.
+func ReadProjectPrompt(paths []string) string {
+	for _, path := range paths {
+		data, truncated, err := readFileHead(path, projectPromptMaxBytes)
+		if err != nil {
+			continue
+		}
+		text := strings.TrimSpace(string(data))
+		if text == "" {
+			continue
+		}
+		if truncated {
+			text += fmt.Sprintf("\n\n[%s truncated to its first %d bytes]", path, projectPromptMaxBytes)
+		}
+		return text
+	}
+	return ""
+}
+
+// readFileHead reads at most limit bytes of path, cut back to a rune
+// boundary, and reports whether the file had more.
+func readFileHead(path string, limit int) ([]byte, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, int64(limit)+1))
+	if err != nil {
+		return nil, false, err
 	}
-	s.leafID = target
-	s.conversation = s.rebuildConversationLocked()
-	s.assistantBuffer.Reset()
-	s.latestUsage = nil
-	return nil
+	if len(data) <= limit {
+		return data, false, nil
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(data[cut]) {
+		cut--
+	}
+	return data[:cut], true, nil
 }
 
-// Tree returns the current session entry tree.
-func (s *AgentSession) Tree() []TreeNode {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	return buildTree(s.entries, s.byID)
+// joinSystemPrompts joins the non-empty prompts with a blank line.
+func joinSystemPrompts(prompts ...string) string {
+	kept := make([]string, 0, len(prompts))
+	for _, prompt := range prompts {
+		if prompt != "" {
+			kept = append(kept, prompt)
+		}
+	}
+	return strings.Join(kept, "\n\n")
 }
 
-// TreeLines renders the tree for slash command output.
-func (s *AgentSession) TreeLines() []string {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// requestMetadataLocked returns the configured request metadata, with
+// user_id defaulting to a stable tag for the current session. The tag is a
+// hash so the session ID itself never leaves the machine.
+func (s *AgentSession) requestMetadataLocked() map[string]string {
+	metadata := make(map[string]string, len(s.metadata)+1)
+	for key, value := range s.metadata {
+		metadata[key] = value
+	}
+	if strings.TrimSpace(metadata["user_id"]) == "" && s.sessionID != "" {
+		sum := sha256.Sum256([]byte(s.sessionID))
+		metadata["user_id"] = "gar-session-" + hex.EncodeToString(sum[:8])
+	}
+	return metadata
+}
 
-	roots := buildTree(s.entries, s.byID)
-	if len(roots) == 0 {
-		return nil
+// appendUserLocked records a user message with images placed ahead of the
+// text. Images are persisted in the entry data.
+func (s *AgentSession) appendUserLocked(ctx context.Context, content string, images []llm.ContentBlock) error {
+	entry := sessionstore.Entry{
+		Type:    "user",
+		Content: content,
 	}
-	lines := make([]string, 0, len(s.entries))
-	var walk func(node TreeNode, depth int)
-	walk = func(node TreeNode, depth int) {
-		indent := strings.Repeat("  ", depth)
-		marker := " "
-		if node.Entry.ID == s.leafID {
-			marker = "*"
-		}
-		lines = append(lines, fmt.Sprintf("%s %s%s %s", marker, indent, node.Entry.ID, entryPreview(node.Entry)))
-		for _, child := range node.Children {
-			walk(child, depth+1)
+	message := userTextMessage(content)
+	if len(images) > 0 {
+		raw, err := json.Marshal(map[string]any{"images": images})
+		if err != nil {
+			return fmt.Errorf("marshal user images: %w", err)
 		}
+		entry.Data = raw
+		message.Content = append(append([]llm.ContentBlock(nil), images...), message.Content...)
 	}
-	for _, root := range roots {
-		walk(root, 0)
-	}
-	return lines
-}
 
-func (s *AgentSession) buildRequestLocked() *llm.Request {
-	return &llm.Request{
-		Model:     s.model,
-		Messages:  cloneMessages(s.conversation),
-		Tools:     cloneToolSpecs(s.tools),
-		MaxTokens: s.maxTokens,
+	if err := s.appendEntryLocked(ctx, entry); err != nil {
+		return err
 	}
+	s.conversation = append(s.conversation, message)
+	return nil
 }
 
-func (s *AgentSession) appendUserLocked(ctx context.Context, content string) error {
-	s.conversation = append(s.conversation, userTextMessage(content))
+// flushThinkingLocked appends buffered thinking as a "thinking" entry ahead of
+// the content it preceded.
+func (s *AgentSession) flushThinkingLocked(ctx context.Context) error {
+	text := strings.TrimSpace(s.thinkingBuffer.String())
+	s.thinkingBuffer.Reset()
+	if text == "" {
+		return nil
+	}
 	return s.appendEntryLocked(ctx, sessionstore.Entry{
-		Type:    "user",
-		Content: content,
+		Type:    "thinking",
+		Content: text,
 	})
 }
 
 func (s *AgentSession) flushAssistantLocked(ctx context.Context) error {
+	if err := s.flushThinkingLocked(ctx); err != nil {
+		return err
+	}
 	text := strings.TrimSpace(s.assistantBuffer.String())
 	if text == "" {
 		return nil
@@ -589,6 +1750,13 @@ func (s *AgentSession) flushAssistantLocked(ctx context.Context) error {
 		Type:    "assistant",
 		Content: text,
 	}
+	if s.model != "" {
+		raw, err := json.Marshal(assistantEntryData{Model: s.model, Provider: s.provider})
+		if err != nil {
+			return fmt.Errorf("marshal assistant data: %w", err)
+		}
+		entry.Data = raw
+	}
 	if s.latestUsage != nil {
 		raw, err := json.Marshal(s.latestUsage)
 		if err != nil {
@@ -614,6 +1782,32 @@ func (s *AgentSession) flushAssistantLocked(ctx context.Context) error {
 	return nil
 }
 
+// autoCompactLocked compacts before a run when the latest input tokens exceed
+// the token budget or, without a budget, when the message count threshold is hit.
+func (s *AgentSession) autoCompactLocked(ctx context.Context) error {
+	threshold := s.autoCompactMessages
+	if s.autoCompactTokens > 0 {
+		if s.inputTokens <= s.autoCompactTokens {
+			return nil
+		}
+		threshold = 0
+	}
+	if _, err := s.compactLocked(ctx, threshold, s.compactionKeep, ""); err != nil && !skippableCompactionError(err) {
+		return err
+	}
+	return nil
+}
+
+// skippableCompactionError reports whether automatic compaction may carry on
+// without compacting: nothing needed dropping, or the branch moved while the
+// summary was written and the next run will try again.
+func skippableCompactionError(err error) bool {
+	return errors.Is(err, ErrCompactionNotNeeded) || errors.Is(err, ErrCompactionConflict)
+}
+
+// compactLocked must be called with s.mu held. It releases the lock while the
+// summarizer model runs, so other session calls are not held up by the round
+// trip, and fails with ErrCompactionConflict when the branch moved meanwhile.
 func (s *AgentSession) compactLocked(
 	ctx context.Context,
 	threshold int,
@@ -627,25 +1821,23 @@ func (s *AgentSession) compactLocked(
 		}
 	}
 
-	if keepMessages <= 0 {
-		keepMessages = s.compactionKeep
+	dropped, firstKeptID, err := s.compactionPlanLocked(keepMessages)
+	if err != nil {
+		return CompactionResult{}, err
 	}
-
-	branch := s.branchEntriesLocked(s.leafID)
-	messageEntries := make([]sessionstore.Entry, 0, len(branch))
-	for _, entry := range branch {
-		if isMessageEntry(entry) {
-			messageEntries = append(messageEntries, entry)
+	summary := buildCompactionSummary(dropped, instructions)
+	if summarizer := s.summarizer; summarizer != nil {
+		leafID, model := s.leafID, s.model
+		s.mu.Unlock()
+		summary, err = summarizeCompaction(ctx, summarizer, model, dropped, instructions, summary)
+		s.mu.Lock()
+		if err != nil {
+			return CompactionResult{}, err
+		}
+		if s.leafID != leafID {
+			return CompactionResult{}, ErrCompactionConflict
 		}
 	}
-	if len(messageEntries) <= keepMessages {
-		return CompactionResult{}, ErrCompactionNotNeeded
-	}
-
-	firstKept := messageEntries[len(messageEntries)-keepMessages]
-	dropped := messageEntries[:len(messageEntries)-keepMessages]
-	firstKeptID := firstKept.ID
-	summary := buildCompactionSummary(dropped, instructions)
 
 	details := map[string]any{
 		"first_kept_entry_id": firstKeptID,
@@ -668,6 +1860,7 @@ func (s *AgentSession) compactLocked(
 	}
 
 	s.conversation = s.rebuildConversationLocked()
+	s.inputTokens = 0
 	return CompactionResult{
 		Summary:         summary,
 		DroppedMessages: len(dropped),
@@ -675,6 +1868,76 @@ func (s *AgentSession) compactLocked(
 	}, nil
 }
 
+// compactionPlanLocked picks the message entries compaction would drop when
+// keeping the newest keepMessages, and the id of the first kept entry.
+func (s *AgentSession) compactionPlanLocked(keepMessages int) ([]sessionstore.Entry, string, error) {
+	if keepMessages <= 0 {
+		keepMessages = s.compactionKeep
+	}
+
+	branch := s.branchEntriesLocked(s.leafID)
+	messageIndexes := make([]int, 0, len(branch))
+	for i, entry := range branch {
+		if isMessageEntry(entry) {
+			messageIndexes = append(messageIndexes, i)
+		}
+	}
+	if len(messageIndexes) <= keepMessages {
+		return nil, "", ErrCompactionNotNeeded
+	}
+
+	boundary := toolTurnStart(branch, messageIndexes[len(messageIndexes)-keepMessages])
+	dropped := make([]sessionstore.Entry, 0, len(messageIndexes))
+	for _, i := range messageIndexes {
+		if i >= boundary {
+			break
+		}
+		dropped = append(dropped, branch[i])
+	}
+	if len(dropped) == 0 {
+		return nil, "", ErrCompactionNotNeeded
+	}
+	return dropped, branch[boundary].ID, nil
+}
+
+// summarizeCompaction asks summarizer for a compaction summary and falls back
+// to the naive transcript digest when the request fails. Only context
+// cancellation is reported as an error. It runs without s.mu held.
+func summarizeCompaction(ctx context.Context, summarizer Runner, model string, dropped []sessionstore.Entry, instructions, fallback string) (string, error) {
+	stream, err := summarizer.Run(ctx, &llm.Request{
+		Model:     model,
+		System:    compactionSystemPrompt,
+		Messages:  []llm.Message{userTextMessage(buildSummaryPrompt(dropped, instructions))},
+		MaxTokens: summaryMaxTokens,
+	})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		return fallback, nil
+	}
+
+	var text strings.Builder
+	failed := false
+	for ev := range stream {
+		switch ev.Type {
+		case llm.EventTextDelta:
+			text.WriteString(ev.TextDelta)
+		case llm.EventError:
+			failed = true
+		}
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return "", ctxErr
+	}
+
+	summary := strings.TrimSpace(text.String())
+	if failed || summary == "" {
+		return fallback, nil
+	}
+	return capRunes("[Context Compact Summary]\n"+summary, compactionSummaryMaxChars), nil
+}
+
 func (s *AgentSession) appendEntryLocked(ctx context.Context, entry sessionstore.Entry) error {
 	entry.ID = fmt.Sprintf("%06d", s.nextEntryID)
 	entry.ParentID = s.leafID
@@ -725,9 +1988,16 @@ func (s *AgentSession) branchEntriesLocked(leafID string) []sessionstore.Entry {
 }
 
 func (s *AgentSession) rebuildConversationLocked() []llm.Message {
+	messages, _ := s.rebuildConversationWithModelsLocked()
+	return messages
+}
+
+// rebuildConversationWithModelsLocked rebuilds the branch conversation along
+// with the model recorded for each message, "" where none was recorded.
+func (s *AgentSession) rebuildConversationWithModelsLocked() ([]llm.Message, []string) {
 	branch := s.branchEntriesLocked(s.leafID)
 	if len(branch) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	latestCompactionIndex := -1
@@ -744,19 +2014,21 @@ func (s *AgentSession) rebuildConversationLocked() []llm.Message {
 	}
 
 	messages := make([]llm.Message, 0, len(branch))
+	models := make([]string, 0, len(branch))
 	appendEntryMessage := func(entry sessionstore.Entry) {
 		msg, ok := entryToMessage(entry)
 		if !ok {
 			return
 		}
 		messages = append(messages, msg)
+		models = append(models, entryModel(entry))
 	}
 
 	if latestCompactionIndex < 0 {
 		for _, entry := range branch {
 			appendEntryMessage(entry)
 		}
-		return messages
+		return messages, models
 	}
 
 	if compactionSummary != "" {
@@ -767,6 +2039,7 @@ func (s *AgentSession) rebuildConversationLocked() []llm.Message {
 				Text: compactionSummary,
 			}},
 		})
+		models = append(models, "")
 	}
 
 	start := latestCompactionIndex
@@ -785,36 +2058,84 @@ func (s *AgentSession) rebuildConversationLocked() []llm.Message {
 		appendEntryMessage(branch[i])
 	}
 
-	return messages
+	return messages, models
 }
 
-func (s *AgentSession) dequeueDeliveredLocked(text string) {
-	for i, queued := range s.steeringQueued {
-		if queued != text {
-			continue
-		}
-		s.steeringQueued = append(append([]string(nil), s.steeringQueued[:i]...), s.steeringQueued[i+1:]...)
-		return
+func (s *AgentSession) dequeueDeliveredLocked(text string) (string, bool) {
+	if queue, ok := removeQueued(s.steeringQueued, text); ok {
+		s.steeringQueued = queue
+		return QueueSteering, true
 	}
-	for i, queued := range s.followUpQueued {
-		if queued != text {
-			continue
-		}
-		s.followUpQueued = append(append([]string(nil), s.followUpQueued[:i]...), s.followUpQueued[i+1:]...)
+	if queue, ok := removeQueued(s.followUpQueued, text); ok {
+		s.followUpQueued = queue
+		return QueueFollowUp, true
+	}
+	return "", false
+}
+
+func (s *AgentSession) appendQueueEntryLocked(ctx context.Context, entryType, kind, text string) error {
+	raw, err := json.Marshal(map[string]string{"kind": kind})
+	if err != nil {
+		return fmt.Errorf("marshal queue entry: %w", err)
+	}
+	return s.appendEntryLocked(ctx, sessionstore.Entry{
+		Type:    entryType,
+		Content: text,
+		Data:    raw,
+	})
+}
+
+// replayQueuesLocked hands rehydrated queued messages back to the runner.
+func (s *AgentSession) replayQueuesLocked() {
+	if s.queueRunner == nil {
 		return
 	}
+	for _, text := range s.steeringQueued {
+		s.queueRunner.Steer(userTextMessage(text))
+	}
+	for _, text := range s.followUpQueued {
+		s.queueRunner.FollowUp(userTextMessage(text))
+	}
 }
 
 func (s *AgentSession) reindexLocked() {
 	s.byID = make(map[string]sessionstore.Entry, len(s.entries))
 	s.leafID = ""
 	s.sessionName = ""
+	s.systemPrompt = ""
+	s.steeringQueued = nil
+	s.followUpQueued = nil
+	s.bookmarks = nil
 	maxNumericID := 0
 	for _, entry := range s.entries {
 		s.byID[entry.ID] = entry
 		s.leafID = entry.ID
-		if entry.Type == "session_info" {
+		switch entry.Type {
+		case "session_info":
 			s.sessionName = strings.TrimSpace(entry.Name)
+		case "system":
+			s.systemPrompt = strings.TrimSpace(entry.Content)
+		case "queued":
+			switch queueEntryKind(entry) {
+			case QueueSteering:
+				s.steeringQueued = append(s.steeringQueued, entry.Content)
+			case QueueFollowUp:
+				s.followUpQueued = append(s.followUpQueued, entry.Content)
+			}
+		case "queue_consumed", "queue_cancelled":
+			switch queueEntryKind(entry) {
+			case QueueSteering:
+				s.steeringQueued, _ = removeQueued(s.steeringQueued, entry.Content)
+			case QueueFollowUp:
+				s.followUpQueued, _ = removeQueued(s.followUpQueued, entry.Content)
+			}
+		case "queue_cleared":
+			s.steeringQueued = nil
+			s.followUpQueued = nil
+		case "bookmark":
+			if target := bookmarkTarget(entry); target != "" {
+				s.setBookmarkLocked(strings.TrimSpace(entry.Name), target)
+			}
 		}
 		if parsed, err := strconv.Atoi(entry.ID); err == nil && parsed > maxNumericID {
 			maxNumericID = parsed
@@ -827,17 +2148,23 @@ func (s *AgentSession) reindexLocked() {
 }
 
 func (s *AgentSession) switchSessionLocked(sessionID string, entries []sessionstore.Entry) {
+	if s.queueRunner != nil {
+		s.queueRunner.ClearAllQueues()
+	}
 	s.sessionID = strings.TrimSpace(sessionID)
 	s.entries = append([]sessionstore.Entry(nil), entries...)
+	s.undoStack = nil
+	s.modifiedFiles = nil
+	s.pendingImages = nil
+	s.skippedLines = nil
 	s.reindexLocked()
 	s.conversation = s.rebuildConversationLocked()
+	s.projectPrompt = ReadProjectPrompt(s.projectPromptFiles)
 	s.assistantBuffer.Reset()
+	s.thinkingBuffer.Reset()
 	s.latestUsage = nil
-	s.steeringQueued = nil
-	s.followUpQueued = nil
-	if s.queueRunner != nil {
-		s.queueRunner.ClearAllQueues()
-	}
+	s.inputTokens = 0
+	s.replayQueuesLocked()
 }
 
 func (s *AgentSession) generateSessionID(ctx context.Context) string {
@@ -868,6 +2195,25 @@ func (s *AgentSession) generateSessionID(ctx context.Context) string {
 	return fmt.Sprintf("%s-%d", base, time.Now().UTC().UnixNano())
 }
 
+// assistantEntryData is the Data of an "assistant" entry. Entries written
+// before models were recorded have no Data.
+type assistantEntryData struct {
+	Model    string `json:"model,omitempty"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// entryModel returns the model recorded on an assistant entry, or "".
+func entryModel(entry sessionstore.Entry) string {
+	if entry.Type != "assistant" || len(entry.Data) == 0 {
+		return ""
+	}
+	var data assistantEntryData
+	if err := json.Unmarshal(entry.Data, &data); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(data.Model)
+}
+
 func entryToMessage(entry sessionstore.Entry) (llm.Message, bool) {
 	switch entry.Type {
 	case "user":
@@ -875,7 +2221,11 @@ func entryToMessage(entry sessionstore.Entry) (llm.Message, bool) {
 		if text == "" {
 			return llm.Message{}, false
 		}
-		return userTextMessage(text), true
+		message := userTextMessage(text)
+		if images := userEntryImages(entry); len(images) > 0 {
+			message.Content = append(images, message.Content...)
+		}
+		return message, true
 	case "assistant":
 		text := strings.TrimSpace(entry.Content)
 		if text == "" {
@@ -889,14 +2239,12 @@ func entryToMessage(entry sessionstore.Entry) (llm.Message, bool) {
 			}},
 		}, true
 	case "tool_result":
-		isError := false
+		var state struct {
+			IsError bool             `json:"is_error"`
+			Display *llm.ToolDisplay `json:"display"`
+		}
 		if len(entry.Data) > 0 {
-			var state struct {
-				IsError bool `json:"is_error"`
-			}
-			if err := json.Unmarshal(entry.Data, &state); err == nil {
-				isError = state.IsError
-			}
+			_ = json.Unmarshal(entry.Data, &state)
 		}
 		return llm.Message{
 			Role: llm.RoleTool,
@@ -904,7 +2252,8 @@ func entryToMessage(entry sessionstore.Entry) (llm.Message, bool) {
 				ToolCallID: entry.ToolCallID,
 				ToolName:   entry.Name,
 				Content:    entry.Content,
-				IsError:    isError,
+				IsError:    state.IsError,
+				Display:    state.Display,
 			},
 		}, true
 	default:
@@ -912,6 +2261,55 @@ func entryToMessage(entry sessionstore.Entry) (llm.Message, bool) {
 	}
 }
 
+func queueEntryKind(entry sessionstore.Entry) string {
+	if len(entry.Data) == 0 {
+		return ""
+	}
+	var payload struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(entry.Data, &payload); err != nil {
+		return ""
+	}
+	return payload.Kind
+}
+
+func userEntryImages(entry sessionstore.Entry) []llm.ContentBlock {
+	if len(entry.Data) == 0 {
+		return nil
+	}
+	var payload struct {
+		Images []llm.ContentBlock `json:"images"`
+	}
+	if err := json.Unmarshal(entry.Data, &payload); err != nil {
+		return nil
+	}
+	return payload.Images
+}
+
+func bookmarkTarget(entry sessionstore.Entry) string {
+	if len(entry.Data) == 0 {
+		return ""
+	}
+	var payload struct {
+		Target string `json:"target"`
+	}
+	if err := json.Unmarshal(entry.Data, &payload); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(payload.Target)
+}
+
+func removeQueued(queue []string, text string) ([]string, bool) {
+	for i, queued := range queue {
+		if queued != text {
+			continue
+		}
+		return append(append([]string(nil), queue[:i]...), queue[i+1:]...), true
+	}
+	return queue, false
+}
+
 func isMessageEntry(entry sessionstore.Entry) bool {
 	switch entry.Type {
 	case "user", "assistant", "tool_result":
@@ -921,6 +2319,49 @@ func isMessageEntry(entry sessionstore.Entry) bool {
 	}
 }
 
+// toolTurnStart moves a compaction boundary at branch[i] back to the start of
+// the tool-use turn it falls inside, so kept tool results never lose the
+// tool calls and assistant text they answer. Boundaries outside a tool-use
+// turn are returned unchanged.
+//
+// A turn is recorded as the tool calls the model streamed, its text, and
+// then the executed calls: the loop sends each call's start event again as it
+// runs, so every call ID appears once more next to its result.
+func toolTurnStart(branch []sessionstore.Entry, i int) int {
+	j := i
+	switch branch[i].Type {
+	case "tool_result":
+		// Step back over the executed calls and their results. A call ID
+		// seen twice marks the calls the model streamed.
+		seen := make(map[string]bool)
+		for j > 0 {
+			prev := branch[j-1]
+			if prev.Type == "tool_call" && !seen[prev.ToolCallID] {
+				seen[prev.ToolCallID] = true
+			} else if prev.Type != "tool_result" {
+				break
+			}
+			j--
+		}
+		for j > 0 && (branch[j-1].Type == "assistant" || branch[j-1].Type == "thinking") {
+			j--
+		}
+	case "assistant":
+		for j > 0 && branch[j-1].Type == "thinking" {
+			j--
+		}
+		if j == 0 || branch[j-1].Type != "tool_call" {
+			return i
+		}
+	default:
+		return i
+	}
+	for j > 0 && (branch[j-1].Type == "tool_call" || branch[j-1].Type == "thinking") {
+		j--
+	}
+	return j
+}
+
 func buildCompactionSummary(entries []sessionstore.Entry, instructions string) string {
 	lines := make([]string, 0, len(entries)+3)
 	lines = append(lines, "[Context Compact Summary]")
@@ -955,11 +2396,33 @@ func buildCompactionSummary(entries []sessionstore.Entry, instructions string) s
 		lines = append(lines, "- (no textual messages)")
 	}
 
-	summary := strings.Join(lines, "\n")
-	if len(summary) > compactionSummaryMaxChars {
-		summary = summary[:compactionSummaryMaxChars]
+	return capRunes(strings.Join(lines, "\n"), compactionSummaryMaxChars)
+}
+
+const sessionSummarySystemPrompt = "You summarize coding-agent sessions for the person running them. Reply with a few concise bullet points covering the goal, what was done, key decisions, files changed, and anything left open. Reply with the bullets only."
+
+const compactionSystemPrompt = "You summarize coding-agent conversations so they can continue after older messages are dropped. Preserve goals, decisions, file paths, commands, errors and open tasks. Reply with the summary only."
+
+func buildSummaryPrompt(entries []sessionstore.Entry, instructions string) string {
+	var b strings.Builder
+	b.WriteString("Summarize the following conversation transcript.\n")
+	if trimmed := strings.TrimSpace(instructions); trimmed != "" {
+		b.WriteString("Additional instructions: " + trimmed + "\n")
+	}
+	b.WriteString("\n<transcript>\n")
+	for _, entry := range entries {
+		role := entry.Type
+		if entry.Type == "tool_result" && strings.TrimSpace(entry.Name) != "" {
+			role = "tool:" + strings.TrimSpace(entry.Name)
+		}
+		text := strings.TrimSpace(entry.Content)
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "[%s]\n%s\n\n", role, truncateRunes(text, summaryEntryMaxRunes))
 	}
-	return summary
+	b.WriteString("</transcript>")
+	return b.String()
 }
 
 func compactionFirstKeptID(entry sessionstore.Entry) string {
@@ -1040,7 +2503,9 @@ func buildTree(entries []sessionstore.Entry, byID map[string]sessionstore.Entry)
 	return out
 }
 
-func entryPreview(entry sessionstore.Entry) string {
+// EntryPreview returns the entry type followed by a short snippet of its
+// content or name, as shown by /tree and /history.
+func EntryPreview(entry sessionstore.Entry) string {
 	typeName := strings.TrimSpace(entry.Type)
 	if typeName == "" {
 		typeName = "entry"
@@ -1048,9 +2513,9 @@ func entryPreview(entry sessionstore.Entry) string {
 
 	snippet := ""
 	switch entry.Type {
-	case "user", "assistant", "compaction":
+	case "user", "assistant", "compaction", "queued", "queue_consumed", "queue_cancelled", "system":
 		snippet = strings.TrimSpace(entry.Content)
-	case "session_info":
+	case "session_info", "bookmark":
 		snippet = strings.TrimSpace(entry.Name)
 	case "tool_call", "tool_result":
 		snippet = strings.TrimSpace(entry.Name)
@@ -1074,6 +2539,11 @@ func countConversationMessages(messages []llm.Message) int {
 	return count
 }
 
+// contextTokens returns the prompt size reported by usage, including cached input.
+func contextTokens(usage llm.Usage) int {
+	return usage.InputTokens + usage.CacheReadTokens + usage.CacheWriteTokens
+}
+
 func truncateRunes(text string, max int) string {
 	if max <= 0 {
 		return ""
@@ -1085,6 +2555,27 @@ func truncateRunes(text string, max int) string {
 	return string(runes[:max]) + "..."
 }
 
+// capRunes cuts text to at most max runes without splitting a character.
+func capRunes(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	if runes := []rune(text); len(runes) > max {
+		return string(runes[:max])
+	}
+	return text
+}
+
+func cloneContextWindows(windows map[string]int) map[string]int {
+	out := make(map[string]int, len(windows))
+	for model, size := range windows {
+		if model = strings.TrimSpace(model); model != "" && size > 0 {
+			out[model] = size
+		}
+	}
+	return out
+}
+
 func cloneMessages(messages []llm.Message) []llm.Message {
 	if len(messages) == 0 {
 		return nil
@@ -1105,6 +2596,14 @@ func cloneMessages(messages []llm.Message) []llm.Message {
 	return out
 }
 
+func cloneTemperature(value *float64) *float64 {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
+
 func cloneToolSpecs(specs []llm.ToolSpec) []llm.ToolSpec {
 	if len(specs) == 0 {
 		return nil
@@ -1126,6 +2625,16 @@ func cloneEntry(entry sessionstore.Entry) sessionstore.Entry {
 	return cloned
 }
 
+func cloneMetadata(metadata map[string]string) map[string]string {
+	out := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		if key, value = strings.TrimSpace(key), strings.TrimSpace(value); key != "" && value != "" {
+			out[key] = value
+		}
+	}
+	return out
+}
+
 func cloneMeta(meta map[string]any) map[string]any {
 	if len(meta) == 0 {
 		return nil
@@ -1137,7 +2646,8 @@ func cloneMeta(meta map[string]any) map[string]any {
 	return out
 }
 
-// SortEntriesByTimestampDesc sorts entries newest first. Useful for future UI commands.
+// SortEntriesByTimestampDesc sorts entries newest first, breaking ties by
+// descending ID. /history uses it to list recent entries.
 func SortEntriesByTimestampDesc(entries []sessionstore.Entry) {
 	sort.Slice(entries, func(i, j int) bool {
 		if entries[i].TS == entries[j].TS {
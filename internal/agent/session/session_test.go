@@ -2,11 +2,17 @@ package session
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
+	agenttool "gar/internal/agent/tool"
 	"gar/internal/llm"
 	sessionstore "gar/internal/session"
 )
@@ -41,6 +47,24 @@ func (f *fakeRunner) ClearAllQueues() {
 	f.followCalls = nil
 }
 
+func (f *fakeRunner) RemoveSteering(text string) bool {
+	return removeFakeQueued(&f.steeringCalls, text)
+}
+
+func (f *fakeRunner) RemoveFollowUp(text string) bool {
+	return removeFakeQueued(&f.followCalls, text)
+}
+
+func removeFakeQueued(queue *[]llm.Message, text string) bool {
+	for i := len(*queue) - 1; i >= 0; i-- {
+		if messageText((*queue)[i]) == text {
+			*queue = append((*queue)[:i:i], (*queue)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 func TestNewRequiresRunnerAndSessionID(t *testing.T) {
 	t.Parallel()
 
@@ -166,6 +190,198 @@ func TestQueueDeliveryEventDequeuesAndAppendsMessage(t *testing.T) {
 	}
 }
 
+func TestQueueDedupeSkipsIdenticalMessages(t *testing.T) {
+	t.Parallel()
+
+	runner := &fakeRunner{}
+	session, err := New(context.Background(), Config{Runner: runner, SessionID: "queue-dedupe", DedupeQueue: true})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	if err := session.QueueSteer("stop and run tests"); err != nil {
+		t.Fatalf("QueueSteer() err = %v", err)
+	}
+	if err := session.QueueSteer("  stop and run tests "); !errors.Is(err, ErrAlreadyQueued) {
+		t.Fatalf("QueueSteer(duplicate) err = %v, want ErrAlreadyQueued", err)
+	}
+	if err := session.QueueFollowUp("stop and run tests"); err != nil {
+		t.Fatalf("QueueFollowUp(same text, other queue) err = %v", err)
+	}
+	if got := session.SteeringQueued(); len(got) != 1 {
+		t.Fatalf("steering queued = %v, want one message", got)
+	}
+	if len(runner.steeringCalls) != 1 || len(runner.followCalls) != 1 {
+		t.Fatalf("runner steer/follow-up calls = %d/%d, want 1/1", len(runner.steeringCalls), len(runner.followCalls))
+	}
+	queued := 0
+	for _, entry := range session.Entries() {
+		if entry.Type == "queued" {
+			queued++
+		}
+	}
+	if queued != 2 {
+		t.Fatalf("queued entries = %d, want 2", queued)
+	}
+
+	plain, err := New(context.Background(), Config{Runner: &fakeRunner{}, SessionID: "queue-plain"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	for range 2 {
+		if err := plain.QueueSteer("again"); err != nil {
+			t.Fatalf("QueueSteer() without dedupe err = %v", err)
+		}
+	}
+	if got := plain.SteeringQueued(); len(got) != 2 {
+		t.Fatalf("steering queued without dedupe = %v, want both messages", got)
+	}
+}
+
+func TestQueuedMessagesSurviveReload(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+
+	session, err := New(context.Background(), Config{
+		Runner:    &fakeRunner{},
+		Store:     store,
+		SessionID: "queue-reload",
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	for _, text := range []string{"steer-1", "steer-2", "steer-3"} {
+		if err := session.QueueSteer(text); err != nil {
+			t.Fatalf("QueueSteer(%q) err = %v", text, err)
+		}
+	}
+	if err := session.QueueFollowUp("follow-1"); err != nil {
+		t.Fatalf("QueueFollowUp() err = %v", err)
+	}
+	if err := session.RecordEvent(context.Background(), llm.Event{
+		Type:    llm.EventQueuedMessage,
+		Message: &llm.Message{Role: llm.RoleUser, Content: []llm.ContentBlock{{Type: llm.ContentTypeText, Text: "steer-1"}}},
+	}); err != nil {
+		t.Fatalf("RecordEvent(queued_message) err = %v", err)
+	}
+
+	runner := &fakeRunner{}
+	reloaded, err := New(context.Background(), Config{
+		Runner:    runner,
+		Store:     store,
+		SessionID: "queue-reload",
+	})
+	if err != nil {
+		t.Fatalf("New(reload) err = %v", err)
+	}
+
+	steering := reloaded.SteeringQueued()
+	if strings.Join(steering, ",") != "steer-2,steer-3" {
+		t.Fatalf("SteeringQueued() = %v, want [steer-2 steer-3]", steering)
+	}
+	if followUp := reloaded.FollowUpQueued(); len(followUp) != 1 || followUp[0] != "follow-1" {
+		t.Fatalf("FollowUpQueued() = %v, want [follow-1]", followUp)
+	}
+	if len(runner.steeringCalls) != 2 || len(runner.followCalls) != 1 {
+		t.Fatalf("runner queues = %d/%d, want 2/1 replayed", len(runner.steeringCalls), len(runner.followCalls))
+	}
+	if messages := reloaded.Messages(); len(messages) != 1 || messages[0].Content[0].Text != "steer-1" {
+		t.Fatalf("Messages() = %#v, want only delivered steer-1", messages)
+	}
+
+	reloaded.ClearQueue()
+	cleared, err := New(context.Background(), Config{
+		Runner:    &fakeRunner{},
+		Store:     store,
+		SessionID: "queue-reload",
+	})
+	if err != nil {
+		t.Fatalf("New(after clear) err = %v", err)
+	}
+	if got := len(cleared.SteeringQueued()) + len(cleared.FollowUpQueued()); got != 0 {
+		t.Fatalf("queued after clear = %d, want 0", got)
+	}
+}
+
+func TestCancelQueuedRemovesMiddleMessageFromSessionAndRunner(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+	runner := &fakeRunner{}
+	session, err := New(context.Background(), Config{
+		Runner:    runner,
+		Store:     store,
+		SessionID: "queue-cancel",
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	for _, text := range []string{"steer-1", "steer-2", "steer-3"} {
+		if err := session.QueueSteer(text); err != nil {
+			t.Fatalf("QueueSteer(%q) err = %v", text, err)
+		}
+	}
+	if err := session.QueueFollowUp("follow-1"); err != nil {
+		t.Fatalf("QueueFollowUp() err = %v", err)
+	}
+
+	removed, err := session.CancelQueued(QueueSteering, 1)
+	if err != nil {
+		t.Fatalf("CancelQueued() err = %v", err)
+	}
+	if removed != "steer-2" {
+		t.Fatalf("CancelQueued() = %q, want steer-2", removed)
+	}
+	if got := strings.Join(session.SteeringQueued(), ","); got != "steer-1,steer-3" {
+		t.Fatalf("SteeringQueued() = %v, want [steer-1 steer-3]", got)
+	}
+	runnerSteering := make([]string, 0, len(runner.steeringCalls))
+	for _, msg := range runner.steeringCalls {
+		runnerSteering = append(runnerSteering, messageText(msg))
+	}
+	if got := strings.Join(runnerSteering, ","); got != "steer-1,steer-3" || len(runner.followCalls) != 1 {
+		t.Fatalf("runner queues = %v / %d follow-ups, want [steer-1 steer-3] / 1", runnerSteering, len(runner.followCalls))
+	}
+
+	// A message the runner already took is not queued again or cancelled.
+	runner.steeringCalls = runner.steeringCalls[1:]
+	if _, err := session.CancelQueued(QueueSteering, 0); !errors.Is(err, ErrQueuedDelivered) {
+		t.Fatalf("CancelQueued(delivered) err = %v, want ErrQueuedDelivered", err)
+	}
+	if got := strings.Join(session.SteeringQueued(), ","); got != "steer-1,steer-3" {
+		t.Fatalf("SteeringQueued() after delivered cancel = %v, want unchanged", got)
+	}
+	if len(runner.steeringCalls) != 1 || messageText(runner.steeringCalls[0]) != "steer-3" {
+		t.Fatalf("runner steering = %v, want only steer-3 left", runner.steeringCalls)
+	}
+
+	if _, err := session.CancelQueued(QueueFollowUp, 1); !errors.Is(err, ErrQueuedNotFound) {
+		t.Fatalf("CancelQueued(out of range) err = %v, want ErrQueuedNotFound", err)
+	}
+	if _, err := session.CancelQueued("later", 0); !errors.Is(err, ErrQueuedNotFound) {
+		t.Fatalf("CancelQueued(unknown kind) err = %v, want ErrQueuedNotFound", err)
+	}
+
+	reloaded, err := New(context.Background(), Config{
+		Runner:    &fakeRunner{},
+		Store:     store,
+		SessionID: "queue-cancel",
+	})
+	if err != nil {
+		t.Fatalf("New(reload) err = %v", err)
+	}
+	if got := strings.Join(reloaded.SteeringQueued(), ","); got != "steer-1,steer-3" {
+		t.Fatalf("reloaded SteeringQueued() = %v, want [steer-1 steer-3]", got)
+	}
+}
+
 func TestSwitchBranchCreatesDivergentTree(t *testing.T) {
 	t.Parallel()
 
@@ -216,6 +432,66 @@ func TestSwitchBranchCreatesDivergentTree(t *testing.T) {
 	}
 }
 
+func TestThinkingEntriesPersistButStayOutOfContext(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+	cfg := Config{
+		Runner:          &fakeRunner{},
+		Store:           store,
+		SessionID:       "thinking-1",
+		PersistThinking: true,
+	}
+	session, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	drainSubmit(t, session, "hello")
+	for _, ev := range []llm.Event{
+		{Type: llm.EventContentBlockStart, ContentBlockStart: &llm.ContentBlockStart{Type: "thinking", Thinking: "let me "}},
+		{Type: llm.EventThinkingDelta, ThinkingDelta: "think"},
+		{Type: llm.EventThinkingDelta, SignatureDelta: "sig"},
+		{Type: llm.EventTextDelta, TextDelta: "answer"},
+		{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}},
+	} {
+		if err := session.RecordEvent(context.Background(), ev); err != nil {
+			t.Fatalf("RecordEvent(%s) err = %v", ev.Type, err)
+		}
+	}
+
+	entries := session.Entries()
+	var types []string
+	for _, entry := range entries {
+		types = append(types, entry.Type)
+	}
+	if strings.Join(types, ",") != "user,thinking,assistant" {
+		t.Fatalf("entry types = %v, want user,thinking,assistant", types)
+	}
+	if entries[1].Content != "let me think" {
+		t.Fatalf("thinking entry = %q, want let me think", entries[1].Content)
+	}
+
+	reloaded, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New(reload) err = %v", err)
+	}
+	for _, s := range []*AgentSession{session, reloaded} {
+		messages := s.Messages()
+		if len(messages) != 2 {
+			t.Fatalf("messages len = %d, want 2 (thinking excluded)", len(messages))
+		}
+		for _, message := range messages {
+			if strings.Contains(messageText(message), "think") {
+				t.Fatalf("message %#v leaked thinking into context", message)
+			}
+		}
+	}
+}
+
 func TestCompactAddsSummaryAndKeepsTail(t *testing.T) {
 	t.Parallel()
 
@@ -275,104 +551,1811 @@ func TestCompactAddsSummaryAndKeepsTail(t *testing.T) {
 	}
 }
 
-func TestSessionManagementNewSwitchAndName(t *testing.T) {
+func TestPreviewCompactionDoesNotPersist(t *testing.T) {
 	t.Parallel()
 
-	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
-	if err != nil {
-		t.Fatalf("NewStore() err = %v", err)
-	}
-
-	runner := &fakeRunner{}
-	session, err := New(context.Background(), Config{
-		Runner:    runner,
-		Store:     store,
-		SessionID: "sess-a",
-		Meta:      map[string]any{"model": "claude"},
-	})
+	session, err := New(context.Background(), Config{Runner: &fakeRunner{}, SessionID: "compact-preview"})
 	if err != nil {
 		t.Fatalf("New() err = %v", err)
 	}
+	for i := 1; i <= 3; i++ {
+		stream, err := session.Submit(context.Background(), fmt.Sprintf("question %d", i))
+		if err != nil {
+			t.Fatalf("Submit(%d) err = %v", i, err)
+		}
+		drain(stream)
+		if err := session.RecordEvent(context.Background(), llm.Event{Type: llm.EventTextDelta, TextDelta: "answer"}); err != nil {
+			t.Fatalf("RecordEvent(delta %d) err = %v", i, err)
+		}
+		if err := session.RecordEvent(context.Background(), llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}); err != nil {
+			t.Fatalf("RecordEvent(done %d) err = %v", i, err)
+		}
+	}
+	entries := session.Entries()
+	messages := session.Messages()
 
-	stream, err := session.Submit(context.Background(), "hello")
+	preview, err := session.PreviewCompaction(2, "")
 	if err != nil {
-		t.Fatalf("Submit() err = %v", err)
+		t.Fatalf("PreviewCompaction() err = %v", err)
 	}
-	drain(stream)
-
-	if err := session.SetSessionName(context.Background(), "alpha"); err != nil {
-		t.Fatalf("SetSessionName() err = %v", err)
+	if preview.DroppedMessages != 4 || !strings.Contains(preview.Summary, "question 1") {
+		t.Fatalf("PreviewCompaction() = %+v, want 4 dropped and a summary mentioning question 1", preview)
 	}
-	if got := session.SessionName(); got != "alpha" {
-		t.Fatalf("SessionName() = %q, want alpha", got)
+	if got := session.Entries(); len(got) != len(entries) {
+		t.Fatalf("Entries() len = %d after preview, want unchanged %d", len(got), len(entries))
 	}
-	if got := session.Stats().SessionName; got != "alpha" {
-		t.Fatalf("Stats().SessionName = %q, want alpha", got)
+	if got := session.Messages(); len(got) != len(messages) {
+		t.Fatalf("Messages() len = %d after preview, want unchanged %d", len(got), len(messages))
 	}
 
-	listed, err := session.ListSessions(context.Background())
+	result, err := session.Compact(context.Background(), 2, "")
 	if err != nil {
-		t.Fatalf("ListSessions() err = %v", err)
+		t.Fatalf("Compact() err = %v", err)
 	}
-	if len(listed) != 1 || listed[0].ID != "sess-a" {
-		t.Fatalf("ListSessions() = %#v, want sess-a", listed)
+	if result.DroppedMessages != preview.DroppedMessages || result.FirstKeptEntry != preview.FirstKeptEntry {
+		t.Fatalf("Compact() = %+v, want the previewed plan %+v", result, preview)
 	}
+}
 
-	newID, err := session.NewSession(context.Background(), "sess-b")
+func TestCompactKeepsToolCallsWithTheirResults(t *testing.T) {
+	t.Parallel()
+
+	session, err := New(context.Background(), Config{Runner: &fakeRunner{}, SessionID: "compact-tools"})
 	if err != nil {
-		t.Fatalf("NewSession() err = %v", err)
+		t.Fatalf("New() err = %v", err)
 	}
-	if newID != "sess-b" {
-		t.Fatalf("NewSession() id = %q, want sess-b", newID)
+	record := func(ev llm.Event) {
+		t.Helper()
+		if err := session.RecordEvent(context.Background(), ev); err != nil {
+			t.Fatalf("RecordEvent(%s) err = %v", ev.Type, err)
+		}
 	}
-	if got := session.SessionID(); got != "sess-b" {
-		t.Fatalf("SessionID() = %q, want sess-b", got)
+	for _, prompt := range []string{"first", "second"} {
+		stream, err := session.Submit(context.Background(), prompt)
+		if err != nil {
+			t.Fatalf("Submit(%s) err = %v", prompt, err)
+		}
+		drain(stream)
+		if prompt == "first" {
+			record(llm.Event{Type: llm.EventTextDelta, TextDelta: "answer"})
+			record(llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}})
+		}
 	}
-	if len(session.Messages()) != 0 {
-		t.Fatalf("Messages() should be empty on new session")
+	record(llm.Event{Type: llm.EventTextDelta, TextDelta: "Reading both files."})
+	for _, id := range []string{"call_1", "call_2"} {
+		record(llm.Event{Type: llm.EventToolCallStart, ToolCall: &llm.ToolCall{ID: id, Name: "read"}})
 	}
-	if got := session.SessionName(); got != "" {
-		t.Fatalf("SessionName() = %q, want empty after NewSession", got)
+	record(llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonToolUse}})
+	// The sequential loop sends each call's start again just before it runs.
+	for _, id := range []string{"call_1", "call_2"} {
+		record(llm.Event{Type: llm.EventToolCallStart, ToolCall: &llm.ToolCall{ID: id, Name: "read"}})
+		record(llm.Event{Type: llm.EventToolResult, ToolResult: &llm.ToolResult{ToolCallID: id, ToolName: "read", Content: "contents"}})
 	}
+	record(llm.Event{Type: llm.EventTextDelta, TextDelta: "Done."})
+	record(llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}})
 
-	if err := session.SwitchSession(context.Background(), "sess-a"); err != nil {
-		t.Fatalf("SwitchSession(sess-a) err = %v", err)
+	// Keeping two messages would start at call_2's result, splitting the turn.
+	result, err := session.Compact(context.Background(), 2, "")
+	if err != nil {
+		t.Fatalf("Compact() err = %v", err)
 	}
-	if got := session.SessionID(); got != "sess-a" {
-		t.Fatalf("SessionID() after switch = %q, want sess-a", got)
+	if result.DroppedMessages != 3 {
+		t.Fatalf("DroppedMessages = %d, want 3 (two prompts and the first answer)", result.DroppedMessages)
 	}
-	if got := session.SessionName(); got != "alpha" {
-		t.Fatalf("SessionName() after switch = %q, want alpha", got)
+
+	kept := false
+	calls := make(map[string]bool)
+	for _, entry := range session.Entries() {
+		if entry.ID == result.FirstKeptEntry {
+			kept = true
+			if entry.Type != "tool_call" || entry.ToolCallID != "call_1" {
+				t.Fatalf("first kept entry = %s %s, want tool_call call_1", entry.Type, entry.ToolCallID)
+			}
+		}
+		if !kept {
+			continue
+		}
+		switch entry.Type {
+		case "tool_call":
+			calls[entry.ToolCallID] = true
+		case "tool_result":
+			if !calls[entry.ToolCallID] {
+				t.Fatalf("kept tool_result %s without its tool_call", entry.ToolCallID)
+			}
+		}
 	}
+
 	messages := session.Messages()
-	if len(messages) != 1 || messages[0].Role != llm.RoleUser || messages[0].Content[0].Text != "hello" {
-		t.Fatalf("Messages() after switch = %#v, want persisted user hello", messages)
+	var roles []string
+	for _, message := range messages {
+		roles = append(roles, string(message.Role))
+	}
+	if got := strings.Join(roles, ","); got != "assistant,assistant,tool,tool,assistant" {
+		t.Fatalf("rebuilt roles = %s, want summary, tool-use text, both results, answer", got)
+	}
+	if messageText(messages[1]) != "Reading both files." {
+		t.Fatalf("messages[1] = %q, want the tool-use turn text", messageText(messages[1]))
 	}
 }
 
-func TestListSessionsRequiresStore(t *testing.T) {
+func TestCompactKeepsOnlyTheLastOfConsecutiveToolTurns(t *testing.T) {
 	t.Parallel()
 
-	session, err := New(context.Background(), Config{
-		Runner:    &fakeRunner{},
-		SessionID: "ephemeral-1",
-	})
+	session, err := New(context.Background(), Config{Runner: &fakeRunner{}, SessionID: "compact-tool-turns"})
 	if err != nil {
 		t.Fatalf("New() err = %v", err)
 	}
+	record := func(ev llm.Event) {
+		t.Helper()
+		if err := session.RecordEvent(context.Background(), ev); err != nil {
+			t.Fatalf("RecordEvent(%s) err = %v", ev.Type, err)
+		}
+	}
+	stream, err := session.Submit(context.Background(), "go")
+	if err != nil {
+		t.Fatalf("Submit() err = %v", err)
+	}
+	drain(stream)
+	// Two tool-only turns, each streamed and then run by the sequential loop.
+	for _, id := range []string{"call_1", "call_2"} {
+		record(llm.Event{Type: llm.EventToolCallStart, ToolCall: &llm.ToolCall{ID: id, Name: "read"}})
+		record(llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonToolUse}})
+		record(llm.Event{Type: llm.EventToolCallStart, ToolCall: &llm.ToolCall{ID: id, Name: "read"}})
+		record(llm.Event{Type: llm.EventToolResult, ToolResult: &llm.ToolResult{ToolCallID: id, ToolName: "read", Content: "contents"}})
+	}
+	record(llm.Event{Type: llm.EventTextDelta, TextDelta: "Done."})
+	record(llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}})
 
-	if _, err := session.ListSessions(context.Background()); !errors.Is(err, ErrSessionStoreRequired) {
-		t.Fatalf("ListSessions() err = %v, want ErrSessionStoreRequired", err)
+	result, err := session.Compact(context.Background(), 2, "")
+	if err != nil {
+		t.Fatalf("Compact() err = %v", err)
 	}
-	if err := session.SwitchSession(context.Background(), "x"); !errors.Is(err, ErrSessionStoreRequired) {
-		t.Fatalf("SwitchSession() err = %v, want ErrSessionStoreRequired", err)
+	if result.DroppedMessages != 2 {
+		t.Fatalf("DroppedMessages = %d, want 2 (the prompt and the first turn's result)", result.DroppedMessages)
+	}
+	for _, entry := range session.Entries() {
+		if entry.ID != result.FirstKeptEntry {
+			continue
+		}
+		if entry.Type != "tool_call" || entry.ToolCallID != "call_2" {
+			t.Fatalf("first kept entry = %s %s, want tool_call call_2", entry.Type, entry.ToolCallID)
+		}
 	}
 }
 
-func drain(stream <-chan llm.Event) {
-	if stream == nil {
-		return
+func TestSubmitAutoCompactsWhenInputTokensExceedBudget(t *testing.T) {
+	t.Parallel()
+
+	runner := &fakeRunner{}
+	session, err := New(context.Background(), Config{
+		Runner:              runner,
+		SessionID:           "compact-tokens",
+		CompactionKeep:      2,
+		AutoCompactMessages: 1,
+		AutoCompactTokens:   1000,
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	countCompactions := func() int {
+		count := 0
+		for _, entry := range session.Entries() {
+			if entry.Type == "compaction" {
+				count++
+			}
+		}
+		return count
+	}
+
+	usages := []llm.Usage{
+		{InputTokens: 300},
+		{InputTokens: 500, CacheReadTokens: 400},
+		{InputTokens: 200, CacheReadTokens: 700, CacheWriteTokens: 300},
+	}
+	for i, usage := range usages {
+		drainSubmit(t, session, fmt.Sprintf("user-%d", i))
+		if got := countCompactions(); got != 0 {
+			t.Fatalf("compactions after submit %d = %d, want 0 while under budget", i, got)
+		}
+		usage := usage
+		for _, ev := range []llm.Event{
+			{Type: llm.EventTextDelta, TextDelta: "assistant"},
+			{Type: llm.EventUsage, Usage: &usage},
+			{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}},
+		} {
+			if err := session.RecordEvent(context.Background(), ev); err != nil {
+				t.Fatalf("RecordEvent(%s) err = %v", ev.Type, err)
+			}
+		}
+	}
+
+	drainSubmit(t, session, "over budget")
+	if got := countCompactions(); got != 1 {
+		t.Fatalf("compactions after exceeding budget = %d, want 1", got)
+	}
+
+	drainSubmit(t, session, "after compaction")
+	if got := countCompactions(); got != 1 {
+		t.Fatalf("compactions after reset = %d, want 1", got)
+	}
+}
+
+func TestRewindToLastUserDropsAssistantTail(t *testing.T) {
+	t.Parallel()
+
+	runner := &fakeRunner{}
+	session, err := New(context.Background(), Config{Runner: runner, SessionID: "rewind-1"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	if _, err := session.RewindToLastUser(); !errors.Is(err, ErrNoUserMessage) {
+		t.Fatalf("RewindToLastUser() err = %v, want ErrNoUserMessage", err)
+	}
+
+	for _, text := range []string{"first", "second"} {
+		drainSubmit(t, session, text)
+		for _, ev := range []llm.Event{
+			{Type: llm.EventTextDelta, TextDelta: "reply to " + text},
+			{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}},
+		} {
+			if err := session.RecordEvent(context.Background(), ev); err != nil {
+				t.Fatalf("RecordEvent(%s) err = %v", ev.Type, err)
+			}
+		}
+	}
+
+	content, err := session.RewindToLastUser()
+	if err != nil {
+		t.Fatalf("RewindToLastUser() err = %v", err)
+	}
+	if content != "second" {
+		t.Fatalf("RewindToLastUser() = %q, want second", content)
+	}
+
+	messages := session.Messages()
+	if len(messages) != 3 {
+		t.Fatalf("messages len = %d, want 3", len(messages))
+	}
+	last := messages[len(messages)-1]
+	if last.Role != llm.RoleUser || last.Content[0].Text != "second" {
+		t.Fatalf("last message = %#v, want user second", last)
+	}
+
+	stream, err := session.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() err = %v", err)
+	}
+	drain(stream)
+	sent := runner.captured[len(runner.captured)-1]
+	if len(sent) != 3 || sent[2].Role != llm.RoleUser {
+		t.Fatalf("retry request messages = %#v, want history ending in user second", sent)
+	}
+}
+
+func TestUsageTotalsSumsAssistantTurnsOnBranch(t *testing.T) {
+	t.Parallel()
+
+	session, err := New(context.Background(), Config{Runner: &fakeRunner{}, SessionID: "usage-1"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	usages := []llm.Usage{
+		{InputTokens: 100, OutputTokens: 20, CacheReadTokens: 50, CostUSD: 0.01},
+		{InputTokens: 200, OutputTokens: 40, CacheWriteTokens: 10, TotalTokens: 250, CostUSD: 0.02},
+	}
+	for i, usage := range usages {
+		drainSubmit(t, session, fmt.Sprintf("turn-%d", i))
+		usage := usage
+		for _, ev := range []llm.Event{
+			{Type: llm.EventTextDelta, TextDelta: "reply"},
+			{Type: llm.EventUsage, Usage: &usage},
+			{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}},
+		} {
+			if err := session.RecordEvent(context.Background(), ev); err != nil {
+				t.Fatalf("RecordEvent(%s) err = %v", ev.Type, err)
+			}
+		}
+	}
+
+	got := session.UsageTotals()
+	if got.InputTokens != 300 || got.OutputTokens != 60 || got.CacheReadTokens != 50 || got.CacheWriteTokens != 10 || got.TotalTokens != 250 {
+		t.Fatalf("UsageTotals() = %#v, want summed token counts", got)
+	}
+	if got.CostUSD < 0.0299 || got.CostUSD > 0.0301 {
+		t.Fatalf("UsageTotals().CostUSD = %v, want 0.03", got.CostUSD)
+	}
+}
+
+func TestMetricsDerivedFromStoredSession(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+	session, err := New(context.Background(), Config{Runner: &fakeRunner{}, Store: store, SessionID: "metrics-1"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	read := llm.ToolCall{ID: "call-1", Name: "read", Arguments: json.RawMessage(`{"path":"a.go"}`)}
+	bash := llm.ToolCall{ID: "call-2", Name: "bash", Arguments: json.RawMessage(`{"command":"ls"}`)}
+	read2 := llm.ToolCall{ID: "call-3", Name: "read", Arguments: json.RawMessage(`{"path":"b.go"}`)}
+	turns := [][]llm.Event{
+		{
+			{Type: llm.EventTextDelta, TextDelta: "checking"},
+			{Type: llm.EventToolCallStart, ToolCall: &read},
+			{Type: llm.EventToolCallStart, ToolCall: &bash},
+			{Type: llm.EventUsage, Usage: &llm.Usage{InputTokens: 100, OutputTokens: 10, CostUSD: 0.01}},
+			{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonToolUse}},
+			{Type: llm.EventTextDelta, TextDelta: "done"},
+			{Type: llm.EventUsage, Usage: &llm.Usage{InputTokens: 150, OutputTokens: 5, CostUSD: 0.02}},
+			{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}},
+		},
+		{
+			{Type: llm.EventToolCallStart, ToolCall: &read2},
+			{Type: llm.EventTextDelta, TextDelta: "read it"},
+			{Type: llm.EventUsage, Usage: &llm.Usage{InputTokens: 200, OutputTokens: 8, CostUSD: 0.03}},
+			{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}},
+		},
+	}
+	for i, events := range turns {
+		drainSubmit(t, session, fmt.Sprintf("turn-%d", i))
+		for _, ev := range events {
+			if err := session.RecordEvent(context.Background(), ev); err != nil {
+				t.Fatalf("RecordEvent(%s) err = %v", ev.Type, err)
+			}
+		}
+	}
+
+	reopened, err := New(context.Background(), Config{Runner: &fakeRunner{}, Store: store, SessionID: "metrics-1"})
+	if err != nil {
+		t.Fatalf("New(reopen) err = %v", err)
+	}
+	got := reopened.Metrics()
+	if got.Turns != 2 {
+		t.Fatalf("Metrics().Turns = %d, want 2", got.Turns)
+	}
+	if len(got.ToolCounts) != 2 || got.ToolCounts["read"] != 2 || got.ToolCounts["bash"] != 1 {
+		t.Fatalf("Metrics().ToolCounts = %v, want read 2, bash 1", got.ToolCounts)
+	}
+	if got.Usage.InputTokens != 450 || got.Usage.OutputTokens != 23 || got.Usage.CostUSD < 0.0599 || got.Usage.CostUSD > 0.0601 {
+		t.Fatalf("Metrics().Usage = %+v, want summed tokens and $0.06", got.Usage)
+	}
+}
+
+func TestCompactUsesSummarizerAndFallsBack(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		runFn       func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error)
+		wantSummary string
+	}{
+		{
+			name: "model summary",
+			runFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+				return scriptedStream(
+					llm.Event{Type: llm.EventTextDelta, TextDelta: "User asked twice; "},
+					llm.Event{Type: llm.EventTextDelta, TextDelta: "assistant answered."},
+					llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}},
+				), nil
+			},
+			wantSummary: "User asked twice; assistant answered.",
+		},
+		{
+			name: "request error falls back",
+			runFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+				return nil, errors.New("provider down")
+			},
+			wantSummary: "Earlier conversation highlights:",
+		},
+		{
+			name: "stream error falls back",
+			runFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+				return scriptedStream(
+					llm.Event{Type: llm.EventTextDelta, TextDelta: "partial"},
+					llm.Event{Type: llm.EventError, Err: errors.New("boom")},
+				), nil
+			},
+			wantSummary: "Earlier conversation highlights:",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			summarizer := &fakeRunner{runFn: tc.runFn}
+			session, err := New(context.Background(), Config{
+				Runner:     &fakeRunner{},
+				Summarizer: summarizer,
+				SessionID:  "compact-llm",
+			})
+			if err != nil {
+				t.Fatalf("New() err = %v", err)
+			}
+			for i := 0; i < 2; i++ {
+				drainSubmit(t, session, "question")
+				if err := session.RecordEvent(context.Background(), llm.Event{Type: llm.EventTextDelta, TextDelta: "answer"}); err != nil {
+					t.Fatalf("RecordEvent(delta) err = %v", err)
+				}
+				if err := session.RecordEvent(context.Background(), llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}); err != nil {
+					t.Fatalf("RecordEvent(done) err = %v", err)
+				}
+			}
+
+			result, err := session.Compact(context.Background(), 1, "focus on answers")
+			if err != nil {
+				t.Fatalf("Compact() err = %v", err)
+			}
+			if !strings.Contains(result.Summary, tc.wantSummary) {
+				t.Fatalf("Summary = %q, want contains %q", result.Summary, tc.wantSummary)
+			}
+			if result.DroppedMessages != 3 || result.FirstKeptEntry == "" {
+				t.Fatalf("result = %+v, want 3 dropped with first kept entry", result)
+			}
+			if len(summarizer.captured) != 1 {
+				t.Fatalf("summarizer calls = %d, want 1", len(summarizer.captured))
+			}
+			prompt := summarizer.captured[0][0].Content[0].Text
+			if !strings.Contains(prompt, "focus on answers") || !strings.Contains(prompt, "question") {
+				t.Fatalf("summary prompt = %q, want instructions and transcript", prompt)
+			}
+
+			messages := session.Messages()
+			if len(messages) != 2 || !strings.Contains(messages[0].Content[0].Text, tc.wantSummary) {
+				t.Fatalf("messages = %#v, want summary plus kept tail", messages)
+			}
+		})
+	}
+}
+
+func TestCompactReturnsContextCancellation(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	summarizer := &fakeRunner{runFn: func(context.Context, *llm.Request) (<-chan llm.Event, error) {
+		cancel()
+		return nil, context.Canceled
+	}}
+	session, err := New(context.Background(), Config{
+		Runner:     &fakeRunner{},
+		Summarizer: summarizer,
+		SessionID:  "compact-cancel",
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	drainSubmit(t, session, "one")
+	drainSubmit(t, session, "two")
+
+	if _, err := session.Compact(ctx, 1, ""); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Compact() err = %v, want context.Canceled", err)
+	}
+	for _, entry := range session.Entries() {
+		if entry.Type == "compaction" {
+			t.Fatalf("unexpected compaction entry after cancellation")
+		}
+	}
+}
+
+func TestCompactSummarizesWithoutHoldingSessionLock(t *testing.T) {
+	t.Parallel()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	summary := strings.Repeat("é", compactionSummaryMaxChars)
+	summarizer := &fakeRunner{runFn: func(context.Context, *llm.Request) (<-chan llm.Event, error) {
+		close(entered)
+		<-release
+		return scriptedStream(
+			llm.Event{Type: llm.EventTextDelta, TextDelta: summary},
+			llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}},
+		), nil
+	}}
+	session, err := New(context.Background(), Config{
+		Runner:     &fakeRunner{},
+		Summarizer: summarizer,
+		SessionID:  "compact-unlocked",
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	drainSubmit(t, session, "one")
+	drainSubmit(t, session, "two")
+
+	type compactResult struct {
+		result CompactionResult
+		err    error
+	}
+	done := make(chan compactResult, 1)
+	go func() {
+		result, err := session.Compact(context.Background(), 1, "")
+		done <- compactResult{result, err}
+	}()
+	<-entered
+	// The session stays usable while the summarizer runs.
+	leaf := session.LeafID()
+	close(release)
+	got := <-done
+	if got.err != nil {
+		t.Fatalf("Compact() err = %v", got.err)
+	}
+	if !utf8.ValidString(got.result.Summary) || utf8.RuneCountInString(got.result.Summary) != compactionSummaryMaxChars {
+		t.Fatalf("Summary has %d runes, valid UTF-8 %v; want %d runes cut cleanly", utf8.RuneCountInString(got.result.Summary), utf8.ValidString(got.result.Summary), compactionSummaryMaxChars)
+	}
+	if leaf == "" {
+		t.Fatal("LeafID() during compaction = empty, want current leaf")
+	}
+}
+
+func TestCompactFailsWhenBranchMovesDuringSummary(t *testing.T) {
+	t.Parallel()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	summarizer := &fakeRunner{runFn: func(context.Context, *llm.Request) (<-chan llm.Event, error) {
+		close(entered)
+		<-release
+		return scriptedStream(
+			llm.Event{Type: llm.EventTextDelta, TextDelta: "summary"},
+			llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}},
+		), nil
+	}}
+	session, err := New(context.Background(), Config{
+		Runner:     &fakeRunner{},
+		Summarizer: summarizer,
+		SessionID:  "compact-conflict",
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	drainSubmit(t, session, "one")
+	drainSubmit(t, session, "two")
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := session.Compact(context.Background(), 1, "")
+		done <- err
+	}()
+	<-entered
+	if err := session.AppendMeta(context.Background(), map[string]any{"note": "moved"}); err != nil {
+		t.Fatalf("AppendMeta() err = %v", err)
+	}
+	close(release)
+	if err := <-done; !errors.Is(err, ErrCompactionConflict) {
+		t.Fatalf("Compact() err = %v, want ErrCompactionConflict", err)
+	}
+	for _, entry := range session.Entries() {
+		if entry.Type == "compaction" {
+			t.Fatalf("unexpected compaction entry after conflict")
+		}
+	}
+}
+
+func TestSessionManagementNewSwitchAndName(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+
+	runner := &fakeRunner{}
+	session, err := New(context.Background(), Config{
+		Runner:    runner,
+		Store:     store,
+		SessionID: "sess-a",
+		Meta:      map[string]any{"model": "claude"},
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	stream, err := session.Submit(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Submit() err = %v", err)
+	}
+	drain(stream)
+
+	if err := session.SetSessionName(context.Background(), "alpha"); err != nil {
+		t.Fatalf("SetSessionName() err = %v", err)
+	}
+	if got := session.SessionName(); got != "alpha" {
+		t.Fatalf("SessionName() = %q, want alpha", got)
+	}
+	if got := session.Stats().SessionName; got != "alpha" {
+		t.Fatalf("Stats().SessionName = %q, want alpha", got)
+	}
+
+	listed, err := session.ListSessions(context.Background())
+	if err != nil {
+		t.Fatalf("ListSessions() err = %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != "sess-a" {
+		t.Fatalf("ListSessions() = %#v, want sess-a", listed)
+	}
+
+	newID, err := session.NewSession(context.Background(), "sess-b")
+	if err != nil {
+		t.Fatalf("NewSession() err = %v", err)
+	}
+	if newID != "sess-b" {
+		t.Fatalf("NewSession() id = %q, want sess-b", newID)
+	}
+	if got := session.SessionID(); got != "sess-b" {
+		t.Fatalf("SessionID() = %q, want sess-b", got)
+	}
+	if len(session.Messages()) != 0 {
+		t.Fatalf("Messages() should be empty on new session")
+	}
+	if got := session.SessionName(); got != "" {
+		t.Fatalf("SessionName() = %q, want empty after NewSession", got)
+	}
+
+	if err := session.SwitchSession(context.Background(), "sess-a"); err != nil {
+		t.Fatalf("SwitchSession(sess-a) err = %v", err)
+	}
+	if got := session.SessionID(); got != "sess-a" {
+		t.Fatalf("SessionID() after switch = %q, want sess-a", got)
+	}
+	if got := session.SessionName(); got != "alpha" {
+		t.Fatalf("SessionName() after switch = %q, want alpha", got)
+	}
+	messages := session.Messages()
+	if len(messages) != 1 || messages[0].Role != llm.RoleUser || messages[0].Content[0].Text != "hello" {
+		t.Fatalf("Messages() after switch = %#v, want persisted user hello", messages)
+	}
+}
+
+func TestSubmitAutoNamesSessionFromFirstMessage(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+	session, err := New(context.Background(), Config{
+		Runner:    &fakeRunner{},
+		Store:     store,
+		SessionID: "sess-a",
+		AutoName:  true,
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	first := "  Fix the\tflaky   retry test in internal/llm/core\x07 and keep the backoff jitter\nsecond line"
+	for _, text := range []string{first, "another message"} {
+		stream, err := session.Submit(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Submit() err = %v", err)
+		}
+		drain(stream)
+	}
+	want := "Fix the flaky retry test in internal/llm/core a…"
+	if got := session.SessionName(); got != want {
+		t.Fatalf("SessionName() = %q, want %q", got, want)
+	}
+	listed, err := session.ListSessions(context.Background())
+	if err != nil {
+		t.Fatalf("ListSessions() err = %v", err)
+	}
+	if len(listed) != 1 || listed[0].Name != want {
+		t.Fatalf("ListSessions() = %#v, want name %q", listed, want)
+	}
+
+	if _, err := session.NewSession(context.Background(), "sess-b"); err != nil {
+		t.Fatalf("NewSession() err = %v", err)
+	}
+	if err := session.SetSessionName(context.Background(), "chosen"); err != nil {
+		t.Fatalf("SetSessionName() err = %v", err)
+	}
+	stream, err := session.Submit(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Submit() err = %v", err)
+	}
+	drain(stream)
+	if got := session.SessionName(); got != "chosen" {
+		t.Fatalf("SessionName() = %q, want pre-set name kept", got)
+	}
+}
+
+func TestSummarizeReportsEmptySessionsAndStreamErrors(t *testing.T) {
+	t.Parallel()
+
+	runner := &fakeRunner{}
+	session, err := New(context.Background(), Config{Runner: runner, SessionID: "sess-a"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	if _, err := session.Summarize(context.Background()); !errors.Is(err, ErrNothingToSummarize) {
+		t.Fatalf("Summarize() err = %v, want ErrNothingToSummarize", err)
+	}
+
+	stream, err := session.Submit(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Submit() err = %v", err)
+	}
+	drain(stream)
+	runner.runFn = func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+		out := make(chan llm.Event, 1)
+		out <- llm.Event{Type: llm.EventError, Err: errors.New("overloaded")}
+		close(out)
+		return out, nil
+	}
+	if _, err := session.Summarize(context.Background()); err == nil || !strings.Contains(err.Error(), "overloaded") {
+		t.Fatalf("Summarize() err = %v, want stream error", err)
+	}
+}
+
+func TestSummarizeSendsOnlyRecentBoundedTranscript(t *testing.T) {
+	t.Parallel()
+
+	runner := &fakeRunner{}
+	session, err := New(context.Background(), Config{Runner: runner, SessionID: "summary-bounds"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	drainSubmit(t, session, "before compaction")
+	drainSubmit(t, session, "kept")
+	if _, err := session.Compact(context.Background(), 1, ""); err != nil {
+		t.Fatalf("Compact() err = %v", err)
+	}
+	long := strings.Repeat("x", summaryEntryMaxRunes)
+	for i := 0; i < 2*sessionSummaryMaxRunes/summaryEntryMaxRunes; i++ {
+		drainSubmit(t, session, fmt.Sprintf("turn-%03d %s", i, long))
+	}
+
+	var prompt string
+	runner.runFn = func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+		prompt = req.Messages[0].Content[0].Text
+		return scriptedStream(llm.Event{Type: llm.EventTextDelta, TextDelta: "- done"}), nil
+	}
+	if _, err := session.Summarize(context.Background()); err != nil {
+		t.Fatalf("Summarize() err = %v", err)
+	}
+	if strings.Contains(prompt, "[user]\nbefore compaction") || !strings.Contains(prompt, "[compaction]") {
+		t.Fatalf("prompt = %.200q, want the compaction summary in place of earlier entries", prompt)
+	}
+	if strings.Contains(prompt, "[user]\nturn-000 ") || !strings.Contains(prompt, fmt.Sprintf("turn-%03d ", 2*sessionSummaryMaxRunes/summaryEntryMaxRunes-1)) {
+		t.Fatal("prompt should drop the oldest turns and keep the newest")
+	}
+	if n := utf8.RuneCountInString(prompt); n > sessionSummaryMaxRunes+1000 {
+		t.Fatalf("prompt = %d runes, want about %d at most", n, sessionSummaryMaxRunes)
+	}
+}
+
+func TestForkSessionCopiesBranchPrefixIntoNewFile(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+	session, err := New(context.Background(), Config{
+		Runner:    &fakeRunner{},
+		Store:     store,
+		SessionID: "origin",
+		Meta:      map[string]any{"model": "claude"},
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	for _, text := range []string{"first", "second"} {
+		stream, err := session.Submit(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Submit(%q) err = %v", text, err)
+		}
+		drain(stream)
+	}
+	original, err := store.Load(context.Background(), "origin")
+	if err != nil {
+		t.Fatalf("Load(origin) err = %v", err)
+	}
+	forkAt := ""
+	for _, entry := range original {
+		if entry.Type == "user" && entry.Content == "first" {
+			forkAt = entry.ID
+		}
+	}
+	if forkAt == "" {
+		t.Fatalf("no user entry for %q in %#v", "first", original)
+	}
+
+	if _, err := session.ForkSession(context.Background(), "missing", "fork"); !errors.Is(err, ErrBranchTargetNotFound) {
+		t.Fatalf("ForkSession(missing) err = %v, want ErrBranchTargetNotFound", err)
+	}
+	if _, err := session.ForkSession(context.Background(), forkAt, "origin"); !errors.Is(err, ErrSessionExists) {
+		t.Fatalf("ForkSession(existing id) err = %v, want ErrSessionExists", err)
+	}
+	id, err := session.ForkSession(context.Background(), forkAt, "fork")
+	if err != nil {
+		t.Fatalf("ForkSession() err = %v", err)
+	}
+	if id != "fork" || session.SessionID() != "fork" {
+		t.Fatalf("ForkSession() = %q, SessionID() = %q; want fork", id, session.SessionID())
+	}
+
+	forked, err := store.Load(context.Background(), "fork")
+	if err != nil {
+		t.Fatalf("Load(fork) err = %v", err)
+	}
+	var prefix []sessionstore.Entry
+	for _, entry := range original {
+		prefix = append(prefix, entry)
+		if entry.ID == forkAt {
+			break
+		}
+	}
+	if !reflect.DeepEqual(forked, prefix) {
+		t.Fatalf("fork entries = %#v, want %#v", forked, prefix)
+	}
+
+	stream, err := session.Submit(context.Background(), "diverge")
+	if err != nil {
+		t.Fatalf("Submit(diverge) err = %v", err)
+	}
+	drain(stream)
+	after, err := store.Load(context.Background(), "origin")
+	if err != nil {
+		t.Fatalf("Load(origin) err = %v", err)
+	}
+	if !reflect.DeepEqual(after, original) {
+		t.Fatalf("original session changed after fork:\n got %#v\nwant %#v", after, original)
+	}
+}
+
+func TestListSessionsRequiresStore(t *testing.T) {
+	t.Parallel()
+
+	session, err := New(context.Background(), Config{
+		Runner:    &fakeRunner{},
+		SessionID: "ephemeral-1",
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	if _, err := session.ListSessions(context.Background()); !errors.Is(err, ErrSessionStoreRequired) {
+		t.Fatalf("ListSessions() err = %v, want ErrSessionStoreRequired", err)
+	}
+	if _, err := session.SearchSessions(context.Background(), "x"); !errors.Is(err, ErrSessionStoreRequired) {
+		t.Fatalf("SearchSessions() err = %v, want ErrSessionStoreRequired", err)
+	}
+	if err := session.SwitchSession(context.Background(), "x"); !errors.Is(err, ErrSessionStoreRequired) {
+		t.Fatalf("SwitchSession() err = %v, want ErrSessionStoreRequired", err)
+	}
+}
+
+func drainSubmit(t *testing.T, session *AgentSession, text string) {
+	t.Helper()
+	stream, err := session.Submit(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Submit(%q) err = %v", text, err)
+	}
+	drain(stream)
+}
+
+func scriptedStream(events ...llm.Event) <-chan llm.Event {
+	out := make(chan llm.Event, len(events))
+	for _, ev := range events {
+		out <- ev
+	}
+	close(out)
+	return out
+}
+
+func drain(stream <-chan llm.Event) {
+	if stream == nil {
+		return
+	}
+	for range stream {
+	}
+}
+
+func TestSetModelSwitchesRequestModelAndRecordsMeta(t *testing.T) {
+	t.Parallel()
+
+	var models []string
+	runner := &fakeRunner{
+		runFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			models = append(models, req.Model)
+			out := make(chan llm.Event)
+			close(out)
+			return out, nil
+		},
+	}
+	session, err := New(context.Background(), Config{
+		Runner:    runner,
+		SessionID: "model-switch",
+		Model:     "big-model",
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	if err := session.SetModel(context.Background(), "  "); !errors.Is(err, ErrModelRequired) {
+		t.Fatalf("SetModel(blank) err = %v, want ErrModelRequired", err)
+	}
+	if err := session.SetModel(context.Background(), " small-model "); err != nil {
+		t.Fatalf("SetModel() err = %v", err)
+	}
+	if got := session.Model(); got != "small-model" {
+		t.Fatalf("Model() = %q, want small-model", got)
+	}
+
+	stream, err := session.Submit(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Submit() err = %v", err)
+	}
+	drain(stream)
+	if len(models) != 1 || models[0] != "small-model" {
+		t.Fatalf("request models = %v, want [small-model]", models)
+	}
+
+	entries := session.Entries()
+	if len(entries) == 0 || entries[0].Type != "meta" {
+		t.Fatalf("entries = %#v, want leading meta entry", entries)
+	}
+	data := string(entries[0].Data)
+	if !strings.Contains(data, `"model":"small-model"`) || !strings.Contains(data, `"previous_model":"big-model"`) {
+		t.Fatalf("meta data = %s, want model switch recorded", data)
+	}
+}
+
+func TestSubmitSendsConfiguredThinkingAndCaching(t *testing.T) {
+	t.Parallel()
+
+	var budget int
+	var cacheSystem bool
+	runner := &fakeRunner{
+		runFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			budget = req.ThinkingBudget
+			cacheSystem = req.CacheSystem
+			out := make(chan llm.Event)
+			close(out)
+			return out, nil
+		},
+	}
+	session, err := New(context.Background(), Config{
+		Runner:         runner,
+		SessionID:      "thinking",
+		ThinkingBudget: 4096,
+		CacheSystem:    true,
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	stream, err := session.Submit(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Submit() err = %v", err)
 	}
 	for range stream {
 	}
+	if budget != 4096 {
+		t.Fatalf("request thinking budget = %d, want 4096", budget)
+	}
+	if !cacheSystem {
+		t.Fatalf("request CacheSystem = false, want the configured prompt caching")
+	}
+}
+
+func TestSubmitSendsConfiguredTemperature(t *testing.T) {
+	t.Parallel()
+
+	var temperatures []*float64
+	runner := &fakeRunner{
+		runFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			temperatures = append(temperatures, req.Temperature)
+			out := make(chan llm.Event)
+			close(out)
+			return out, nil
+		},
+	}
+	temperature := 0.2
+	session, err := New(context.Background(), Config{
+		Runner:      runner,
+		SessionID:   "temperature",
+		Temperature: &temperature,
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	temperature = 1.5
+
+	for range 2 {
+		stream, err := session.Submit(context.Background(), "hello")
+		if err != nil {
+			t.Fatalf("Submit() err = %v", err)
+		}
+		for range stream {
+		}
+	}
+	if len(temperatures) != 2 {
+		t.Fatalf("requests = %d, want 2", len(temperatures))
+	}
+	for i, got := range temperatures {
+		if got == nil || *got != 0.2 {
+			t.Fatalf("request %d temperature = %v, want 0.2", i, got)
+		}
+	}
+	if temperatures[0] == temperatures[1] {
+		t.Fatalf("requests share a temperature pointer; want a copy per request")
+	}
+}
+
+func TestReadProjectPromptCapsLargeFiles(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "CLAUDE.md")
+	content := strings.Repeat("é", projectPromptMaxBytes)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write project prompt: %v", err)
+	}
+
+	got := ReadProjectPrompt([]string{path})
+	body, note, ok := strings.Cut(got, "\n\n[")
+	if !ok || !strings.Contains(note, "truncated") {
+		t.Fatalf("ReadProjectPrompt() ends %q, want a truncation note", got[max(0, len(got)-80):])
+	}
+	if len(body) > projectPromptMaxBytes || !utf8.ValidString(body) || !strings.HasPrefix(content, body) {
+		t.Fatalf("prompt body = %d bytes, valid %v; want a clean prefix of at most %d bytes", len(body), utf8.ValidString(body), projectPromptMaxBytes)
+	}
+}
+
+func TestSubmitPrependsProjectPromptToSystemPrompt(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	projectFile := filepath.Join(dir, "AGENTS.md")
+	if err := os.WriteFile(projectFile, []byte("Run go test before committing.\n"), 0o644); err != nil {
+		t.Fatalf("write project prompt: %v", err)
+	}
+
+	var systems []string
+	runner := &fakeRunner{
+		runFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			systems = append(systems, req.System)
+			out := make(chan llm.Event)
+			close(out)
+			return out, nil
+		},
+	}
+	session, err := New(context.Background(), Config{
+		Runner:             runner,
+		SessionID:          "project",
+		ProjectPromptFiles: []string{filepath.Join(dir, ".gar", "system.md"), projectFile},
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	if err := session.SetSystemPrompt(context.Background(), "Answer tersely."); err != nil {
+		t.Fatalf("SetSystemPrompt() err = %v", err)
+	}
+	submit := func() {
+		t.Helper()
+		stream, err := session.Submit(context.Background(), "hello")
+		if err != nil {
+			t.Fatalf("Submit() err = %v", err)
+		}
+		for range stream {
+		}
+	}
+
+	submit()
+	if want := "Run go test before committing.\n\nAnswer tersely."; systems[0] != want {
+		t.Fatalf("system = %q, want %q", systems[0], want)
+	}
+
+	if err := os.WriteFile(projectFile, []byte("Use tabs."), 0o644); err != nil {
+		t.Fatalf("rewrite project prompt: %v", err)
+	}
+	submit()
+	if systems[1] != systems[0] {
+		t.Fatalf("system = %q, want the prompt read at load until the next session", systems[1])
+	}
+
+	if _, err := session.NewSession(context.Background(), "next"); err != nil {
+		t.Fatalf("NewSession() err = %v", err)
+	}
+	submit()
+	if systems[2] != "Use tabs." {
+		t.Fatalf("system = %q, want the edited project prompt in the new session", systems[2])
+	}
+}
+
+func TestSubmitTagsRequestMetadataWithSessionUserID(t *testing.T) {
+	t.Parallel()
+
+	submit := func(sessionID string, metadata map[string]string) map[string]string {
+		var got map[string]string
+		runner := &fakeRunner{
+			runFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+				_ = ctx
+				got = req.Metadata
+				out := make(chan llm.Event)
+				close(out)
+				return out, nil
+			},
+		}
+		session, err := New(context.Background(), Config{Runner: runner, SessionID: sessionID, Metadata: metadata})
+		if err != nil {
+			t.Fatalf("New() err = %v", err)
+		}
+		stream, err := session.Submit(context.Background(), "hello")
+		if err != nil {
+			t.Fatalf("Submit() err = %v", err)
+		}
+		for range stream {
+		}
+		return got
+	}
+
+	configured := submit("tagged", map[string]string{"user_id": "user-123"})
+	if want := map[string]string{"user_id": "user-123"}; !reflect.DeepEqual(configured, want) {
+		t.Fatalf("metadata = %#v, want %#v", configured, want)
+	}
+
+	first := submit("tagged", nil)
+	userID := first["user_id"]
+	if len(first) != 1 || !strings.HasPrefix(userID, "gar-session-") || strings.Contains(userID, "tagged") {
+		t.Fatalf("metadata = %#v, want only an opaque session user_id", first)
+	}
+	if again := submit("tagged", nil); again["user_id"] != userID {
+		t.Fatalf("user_id = %q, want stable %q for the same session", again["user_id"], userID)
+	}
+	if other := submit("other", nil); other["user_id"] == userID {
+		t.Fatalf("user_id = %q for a different session, want a distinct tag", other["user_id"])
+	}
+}
+
+func TestFinalizeCompactsSessionOnlyOncePlaintextBuildsUp(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), ".gar", "sessions")
+	store, err := sessionstore.NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+	store.SetCompression(true)
+
+	session, err := New(context.Background(), Config{
+		Runner:    &fakeRunner{},
+		Store:     store,
+		SessionID: "compressed",
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	gzPath := filepath.Join(dir, "compressed.jsonl.gz")
+	stream, err := session.Submit(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Submit() err = %v", err)
+	}
+	drain(stream)
+
+	// A short turn stays plaintext rather than rewriting the gzip file.
+	if err := session.Finalize(context.Background()); err != nil {
+		t.Fatalf("Finalize() err = %v", err)
+	}
+	if _, err := os.Stat(gzPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("compressed session stat err = %v, want no compaction after a short turn", err)
+	}
+
+	stream, err = session.Submit(context.Background(), strings.Repeat("x", compressPendingBytes))
+	if err != nil {
+		t.Fatalf("Submit(large) err = %v", err)
+	}
+	drain(stream)
+	if err := session.Finalize(context.Background()); err != nil {
+		t.Fatalf("Finalize() err = %v", err)
+	}
+	if _, err := os.Stat(gzPath); err != nil {
+		t.Fatalf("compressed session stat err = %v, want compaction past the threshold", err)
+	}
+	if pending, err := store.PendingSize("compressed"); err != nil || pending != 0 {
+		t.Fatalf("PendingSize() = %d, %v; want 0 after compaction", pending, err)
+	}
+
+	// Close compacts whatever plaintext is left.
+	stream, err = session.Submit(context.Background(), "bye")
+	if err != nil {
+		t.Fatalf("Submit() err = %v", err)
+	}
+	drain(stream)
+	if err := session.Close(context.Background()); err != nil {
+		t.Fatalf("Close() err = %v", err)
+	}
+	if pending, err := store.PendingSize("compressed"); err != nil || pending != 0 {
+		t.Fatalf("PendingSize() after Close() = %d, %v; want 0", pending, err)
+	}
+
+	if err := session.SwitchSession(context.Background(), "compressed"); err != nil {
+		t.Fatalf("SwitchSession() err = %v", err)
+	}
+	messages := session.Messages()
+	if len(messages) != 3 || messages[0].Content[0].Text != "hello" || messages[2].Content[0].Text != "bye" {
+		t.Fatalf("Messages() after reload = %d messages, want the three persisted prompts", len(messages))
+	}
+}
+
+func TestSystemPromptIsSentAndSurvivesReload(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+
+	var systems []string
+	runner := &fakeRunner{
+		runFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			systems = append(systems, req.System)
+			out := make(chan llm.Event)
+			close(out)
+			return out, nil
+		},
+	}
+	session, err := New(context.Background(), Config{Runner: runner, Store: store, SessionID: "sys"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	if err := session.SetSystemPrompt(context.Background(), "draft"); err != nil {
+		t.Fatalf("SetSystemPrompt(draft) err = %v", err)
+	}
+	if err := session.SetSystemPrompt(context.Background(), " Use gofmt. "); err != nil {
+		t.Fatalf("SetSystemPrompt() err = %v", err)
+	}
+	stream, err := session.Submit(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Submit() err = %v", err)
+	}
+	drain(stream)
+
+	reloaded, err := New(context.Background(), Config{Runner: runner, Store: store, SessionID: "sys"})
+	if err != nil {
+		t.Fatalf("New(reload) err = %v", err)
+	}
+	if got := reloaded.SystemPrompt(); got != "Use gofmt." {
+		t.Fatalf("SystemPrompt() after reload = %q, want latest prompt", got)
+	}
+	stream, err = reloaded.Submit(context.Background(), "again")
+	if err != nil {
+		t.Fatalf("Submit(reload) err = %v", err)
+	}
+	drain(stream)
+
+	if len(systems) != 2 || systems[0] != "Use gofmt." || systems[1] != "Use gofmt." {
+		t.Fatalf("request system prompts = %q, want latest prompt on both", systems)
+	}
+
+	if err := reloaded.SetSystemPrompt(context.Background(), ""); err != nil {
+		t.Fatalf("SetSystemPrompt(clear) err = %v", err)
+	}
+	if got := reloaded.SystemPrompt(); got != "" {
+		t.Fatalf("SystemPrompt() after clear = %q, want empty", got)
+	}
+}
+
+type cancelRunner struct {
+	fakeRunner
+	cancels int
+}
+
+func (r *cancelRunner) Cancel() { r.cancels++ }
+
+func TestCancelForwardsToRunnerWhenSupported(t *testing.T) {
+	t.Parallel()
+
+	plain, err := New(context.Background(), Config{Runner: &fakeRunner{}, SessionID: "plain"})
+	if err != nil {
+		t.Fatalf("New(plain) err = %v", err)
+	}
+	if err := plain.Cancel(); !errors.Is(err, ErrCancelUnsupported) {
+		t.Fatalf("Cancel() err = %v, want ErrCancelUnsupported", err)
+	}
+
+	runner := &cancelRunner{}
+	session, err := New(context.Background(), Config{Runner: runner, SessionID: "cancel"})
+	if err != nil {
+		t.Fatalf("New(cancel) err = %v", err)
+	}
+	if err := session.Cancel(); err != nil {
+		t.Fatalf("Cancel() err = %v", err)
+	}
+	if runner.cancels != 1 {
+		t.Fatalf("runner cancels = %d, want 1", runner.cancels)
+	}
+}
+
+func TestToolResultDisplaySurvivesReload(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+	session, err := New(context.Background(), Config{Runner: &fakeRunner{}, Store: store, SessionID: "display"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	display := &llm.ToolDisplay{Type: "file_content", Payload: json.RawMessage(`{"path":"main.go"}`)}
+	if err := session.RecordEvent(context.Background(), llm.Event{
+		Type:       llm.EventToolResult,
+		ToolResult: &llm.ToolResult{ToolCallID: "call_1", ToolName: "read", Content: "package main", Display: display},
+	}); err != nil {
+		t.Fatalf("RecordEvent(tool_result) err = %v", err)
+	}
+
+	reloaded, err := New(context.Background(), Config{Runner: &fakeRunner{}, Store: store, SessionID: "display"})
+	if err != nil {
+		t.Fatalf("New(reload) err = %v", err)
+	}
+	messages := reloaded.Messages()
+	if len(messages) != 1 || messages[0].ToolResult == nil {
+		t.Fatalf("messages = %#v, want one tool result", messages)
+	}
+	got := messages[0].ToolResult.Display
+	if got == nil || got.Type != "file_content" || string(got.Payload) != `{"path":"main.go"}` {
+		t.Fatalf("Display after reload = %#v, want file_content payload", got)
+	}
+}
+
+func TestRewindBeforeLastUserBranchesFromParent(t *testing.T) {
+	t.Parallel()
+
+	runner := &fakeRunner{}
+	session, err := New(context.Background(), Config{Runner: runner, SessionID: "edit-last"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	drainSubmit(t, session, "first")
+	if err := session.RecordEvent(context.Background(), llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}); err != nil {
+		t.Fatalf("RecordEvent(done) err = %v", err)
+	}
+	drainSubmit(t, session, "draft")
+	original := session.Stats().LeafID
+
+	text, err := session.RewindBeforeLastUser()
+	if err != nil {
+		t.Fatalf("RewindBeforeLastUser() err = %v", err)
+	}
+	if text != "draft" {
+		t.Fatalf("RewindBeforeLastUser() = %q, want draft", text)
+	}
+	if messages := session.Messages(); len(messages) != 1 || messages[0].Content[0].Text != "first" {
+		t.Fatalf("messages = %#v, want only first", messages)
+	}
+
+	drainSubmit(t, session, "second")
+	var siblings int
+	for _, root := range session.Tree() {
+		if len(root.Children) == 2 {
+			siblings = 2
+		}
+	}
+	if siblings != 2 {
+		t.Fatalf("tree = %#v, want edited message as a sibling of %s", session.Tree(), original)
+	}
+}
+
+func TestBookmarksSurviveReloadAndSwitchBranch(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+	session, err := New(context.Background(), Config{Runner: &fakeRunner{}, Store: store, SessionID: "bookmarks"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	if err := session.AddBookmark(context.Background(), "empty"); !errors.Is(err, ErrNothingToBookmark) {
+		t.Fatalf("AddBookmark(empty session) err = %v, want ErrNothingToBookmark", err)
+	}
+
+	drainSubmit(t, session, "first")
+	marked := session.LeafID()
+	if err := session.AddBookmark(context.Background(), "start"); err != nil {
+		t.Fatalf("AddBookmark() err = %v", err)
+	}
+	drainSubmit(t, session, "second")
+
+	reloaded, err := New(context.Background(), Config{Runner: &fakeRunner{}, Store: store, SessionID: "bookmarks"})
+	if err != nil {
+		t.Fatalf("New(reload) err = %v", err)
+	}
+	bookmarks := reloaded.Bookmarks()
+	if len(bookmarks) != 1 || bookmarks[0] != (Bookmark{Name: "start", EntryID: marked}) {
+		t.Fatalf("Bookmarks() = %#v, want start -> %s", bookmarks, marked)
+	}
+
+	var labelled bool
+	for _, line := range reloaded.TreeLines() {
+		if strings.Contains(line, marked+" user first") && strings.HasSuffix(line, "[start]") {
+			labelled = true
+		}
+	}
+	if !labelled {
+		t.Fatalf("TreeLines() = %q, want %s labelled [start]", reloaded.TreeLines(), marked)
+	}
+
+	target, err := reloaded.GotoBookmark("start")
+	if err != nil {
+		t.Fatalf("GotoBookmark() err = %v", err)
+	}
+	if target != marked || reloaded.LeafID() != marked {
+		t.Fatalf("GotoBookmark() = %q leaf = %q, want %s", target, reloaded.LeafID(), marked)
+	}
+	if messages := reloaded.Messages(); len(messages) != 1 || messages[0].Content[0].Text != "first" {
+		t.Fatalf("Messages() = %#v, want only first", messages)
+	}
+	if _, err := reloaded.GotoBookmark("missing"); !errors.Is(err, ErrBookmarkNotFound) {
+		t.Fatalf("GotoBookmark(missing) err = %v, want ErrBookmarkNotFound", err)
+	}
+}
+
+func TestUndoRestoresFileFromToolResult(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "notes.txt")
+	if err := os.WriteFile(path, []byte("before\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() err = %v", err)
+	}
+	result, err := agenttool.NewEditToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"path":"notes.txt","oldText":"before","newText":"after"}`))
+	if err != nil {
+		t.Fatalf("edit Execute() err = %v", err)
+	}
+
+	session, err := New(context.Background(), Config{Runner: &fakeRunner{}, SessionID: "undo"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	if err := session.RecordEvent(context.Background(), llm.Event{
+		Type: llm.EventToolResult,
+		ToolResult: &llm.ToolResult{
+			ToolCallID: "call-1",
+			ToolName:   "edit",
+			Content:    result.Content,
+			Display:    &llm.ToolDisplay{Type: result.Display.Type, Payload: result.Display.Payload},
+		},
+	}); err != nil {
+		t.Fatalf("RecordEvent(tool_result) err = %v", err)
+	}
+
+	entries := session.Entries()
+	if data := string(entries[len(entries)-1].Data); strings.Contains(data, "previous_content") || !strings.Contains(data, "diff") {
+		t.Fatalf("tool_result data = %s, want display without prior content", data)
+	}
+	if modified := session.ModifiedFiles(); len(modified) != 1 || modified[0].Path != path || string(modified[0].PreviousContent) != "before\n" {
+		t.Fatalf("ModifiedFiles() = %#v, want notes.txt with original content", modified)
+	}
+
+	undone, err := session.Undo()
+	if err != nil {
+		t.Fatalf("Undo() err = %v", err)
+	}
+	if undone.ToolName != "edit" || !strings.HasSuffix(undone.Path, "notes.txt") || undone.Removed {
+		t.Fatalf("Undo() = %#v, want edit of notes.txt restored", undone)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "before\n" {
+		t.Fatalf("file content = %q, want original", got)
+	}
+	if _, err := session.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("Undo() err = %v, want ErrNothingToUndo", err)
+	}
+}
+
+func TestAttachImageIsSentAndPersistedWithNextUserMessage(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+	runner := &fakeRunner{}
+	session, err := New(context.Background(), Config{Runner: runner, Store: store, SessionID: "images"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	if _, err := session.AttachImage("", "data"); !errors.Is(err, ErrImageRequired) {
+		t.Fatalf("AttachImage(no media type) err = %v, want ErrImageRequired", err)
+	}
+	if pending, err := session.AttachImage("image/png", "iVBORw0KGgo="); err != nil || pending != 1 {
+		t.Fatalf("AttachImage() = %d, %v, want 1 pending", pending, err)
+	}
+
+	drainSubmit(t, session, "describe this")
+	wantContent := []llm.ContentBlock{
+		{Type: llm.ContentTypeImage, MediaType: "image/png", Data: "iVBORw0KGgo="},
+		{Type: llm.ContentTypeText, Text: "describe this"},
+	}
+	sent := runner.captured[0]
+	if len(sent) != 1 || fmt.Sprint(sent[0].Content) != fmt.Sprint(wantContent) {
+		t.Fatalf("sent messages = %#v, want image ahead of text", sent)
+	}
+
+	drainSubmit(t, session, "and again")
+	if second := runner.captured[1]; len(second[len(second)-1].Content) != 1 {
+		t.Fatalf("second message content = %#v, want attachments consumed", second[len(second)-1].Content)
+	}
+
+	reloaded, err := New(context.Background(), Config{Runner: &fakeRunner{}, Store: store, SessionID: "images"})
+	if err != nil {
+		t.Fatalf("New(reload) err = %v", err)
+	}
+	if messages := reloaded.Messages(); len(messages) != 2 || fmt.Sprint(messages[0].Content) != fmt.Sprint(wantContent) {
+		t.Fatalf("reloaded messages = %#v, want persisted image block", messages)
+	}
+}
+
+func TestNewSkipsCorruptLinesAndReportsWarning(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), ".gar", "sessions")
+	store, err := sessionstore.NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("MkdirAll() err = %v", err)
+	}
+	content := `{"id":"000001","type":"user","content":"hello","ts":1}
+{"id":"000002","type":"assistant","parent_id":"000001","content":"hi","ts":2}
+{"id":"000003","type":"user","parent_id":"000002","con
+`
+	if err := os.WriteFile(filepath.Join(dir, "crashed.jsonl"), []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() err = %v", err)
+	}
+
+	session, err := New(context.Background(), Config{Runner: &fakeRunner{}, Store: store, SessionID: "crashed"})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	if got := len(session.Messages()); got != 2 {
+		t.Fatalf("messages len = %d, want 2", got)
+	}
+	if warning := session.LoadWarning(); !strings.Contains(warning, "skipped 1 corrupt line") || !strings.Contains(warning, "line 3") {
+		t.Fatalf("LoadWarning() = %q, want skipped line 3", warning)
+	}
+
+	if _, err := session.NewSession(context.Background(), "fresh"); err != nil {
+		t.Fatalf("NewSession() err = %v", err)
+	}
+	if warning := session.LoadWarning(); warning != "" {
+		t.Fatalf("LoadWarning() after NewSession = %q, want empty", warning)
+	}
+}
+
+func TestEstimateTokensUsesCharsPerTokenAndImageEstimate(t *testing.T) {
+	t.Parallel()
+
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: []llm.ContentBlock{
+			{Type: llm.ContentTypeImage, MediaType: "image/png", Data: strings.Repeat("A", 4000)},
+			{Type: llm.ContentTypeText, Text: strings.Repeat("a", 10)},
+		}},
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "c1", Name: "read", Arguments: json.RawMessage(`{"path":"x"}`)}}},
+		{Role: llm.RoleTool, ToolResult: &llm.ToolResult{ToolCallID: "c1", Content: "abcdef"}},
+	}
+	// 10 + 4 + 12 + 6 = 32 chars -> 8 tokens, plus one image.
+	if got, want := EstimateTokens(messages), 8+imageTokenEstimate; got != want {
+		t.Fatalf("EstimateTokens() = %d, want %d", got, want)
+	}
+	if got := EstimateTokens([]llm.Message{userTextMessage("abcde")}); got != 2 {
+		t.Fatalf("EstimateTokens(5 chars) = %d, want 2", got)
+	}
+}
+
+func TestSubmitEmitsContextWarningAboveThreshold(t *testing.T) {
+	t.Parallel()
+
+	session, err := New(context.Background(), Config{
+		Runner:         &fakeRunner{},
+		SessionID:      "s-1",
+		Model:          "claude-test",
+		ContextWindows: map[string]int{"claude": 100, "claude-other": 1},
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+
+	firstEvent := func(text string) *llm.Event {
+		t.Helper()
+		stream, err := session.Submit(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Submit() err = %v", err)
+		}
+		var first *llm.Event
+		for ev := range stream {
+			if first == nil {
+				ev := ev
+				first = &ev
+			}
+		}
+		return first
+	}
+
+	// 80 tokens is below 90% of the 100-token window.
+	if ev := firstEvent(strings.Repeat("a", 320)); ev != nil {
+		t.Fatalf("first event = %#v, want no warning below threshold", ev)
+	}
+	// The conversation now holds 80 + 20 tokens.
+	ev := firstEvent(strings.Repeat("b", 80))
+	if ev == nil || ev.Type != llm.EventContextWarning || ev.ContextWarning == nil {
+		t.Fatalf("first event = %#v, want context warning", ev)
+	}
+	if ev.ContextWarning.EstimatedTokens != 100 || ev.ContextWarning.ContextWindow != 100 {
+		t.Fatalf("ContextWarning = %#v, want 100/100", ev.ContextWarning)
+	}
+}
+
+func TestSubmitCompactsOnContextWarningWhenEnabled(t *testing.T) {
+	t.Parallel()
+
+	runner := &fakeRunner{}
+	session, err := New(context.Background(), Config{
+		Runner:                  runner,
+		SessionID:               "s-1",
+		Model:                   "small-model",
+		ContextWindows:          map[string]int{"small-model": 100},
+		CompactOnContextWarning: true,
+		CompactionKeep:          1,
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		drainSubmit(t, session, strings.Repeat(string(rune('a'+i)), 160))
+	}
+
+	// 80 tokens per message: the third submit crosses 90 and compacts first.
+	if got := len(runner.captured[1]); got != 2 {
+		t.Fatalf("second request messages = %d, want 2 uncompacted", got)
+	}
+	last := runner.captured[2]
+	if len(last) != 2 || messageText(last[0]) == strings.Repeat("a", 160) {
+		t.Fatalf("last request = %#v, want summary plus newest message", last)
+	}
+	if messageText(last[1]) != strings.Repeat("c", 160) {
+		t.Fatalf("last request = %#v, want newest user message kept", last)
+	}
+}
+
+func TestAssistantEntriesRecordModelAcrossSetModel(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+	session, err := New(context.Background(), Config{
+		Runner:    &fakeRunner{},
+		Store:     store,
+		SessionID: "models",
+		Model:     "big-model",
+		Provider:  "anthropic",
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	reply := func(text string) {
+		t.Helper()
+		drainSubmit(t, session, "question")
+		if err := session.RecordEvent(context.Background(), llm.Event{Type: llm.EventTextDelta, TextDelta: text}); err != nil {
+			t.Fatalf("RecordEvent(text_delta) err = %v", err)
+		}
+		if err := session.RecordEvent(context.Background(), llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}); err != nil {
+			t.Fatalf("RecordEvent(done) err = %v", err)
+		}
+	}
+
+	reply("from big")
+	if err := session.SetModel(context.Background(), "small-model"); err != nil {
+		t.Fatalf("SetModel() err = %v", err)
+	}
+	reply("from small")
+
+	var assistants []sessionstore.Entry
+	for _, entry := range session.Entries() {
+		if entry.Type == "assistant" {
+			assistants = append(assistants, entry)
+		}
+	}
+	if len(assistants) != 2 {
+		t.Fatalf("assistant entries = %d, want 2", len(assistants))
+	}
+	if got := string(assistants[1].Data); got != `{"model":"small-model","provider":"anthropic"}` {
+		t.Fatalf("assistant[1].Data = %s, want small-model tag", got)
+	}
+
+	// Older entries without Data still load, with no model attributed.
+	if err := store.Append(context.Background(), "models", sessionstore.Entry{
+		ID: "legacy", ParentID: session.LeafID(), Type: "assistant", Content: "from before",
+	}); err != nil {
+		t.Fatalf("Append(legacy) err = %v", err)
+	}
+	reloaded, err := New(context.Background(), Config{Runner: &fakeRunner{}, Store: store, SessionID: "models"})
+	if err != nil {
+		t.Fatalf("New(reload) err = %v", err)
+	}
+	messages, models := reloaded.MessagesWithModels()
+	if len(messages) != len(models) {
+		t.Fatalf("MessagesWithModels() lengths = %d/%d, want equal", len(messages), len(models))
+	}
+	want := []string{"", "big-model", "", "small-model", ""}
+	if strings.Join(models, ",") != strings.Join(want, ",") {
+		t.Fatalf("models = %q, want %q", models, want)
+	}
+}
+
+func TestSetToolChoiceAppliesToNextRunUnlessSticky(t *testing.T) {
+	t.Parallel()
+
+	var choices []llm.ToolChoice
+	runner := &fakeRunner{
+		runFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			choices = append(choices, req.ToolChoice)
+			out := make(chan llm.Event)
+			close(out)
+			return out, nil
+		},
+	}
+	session, err := New(context.Background(), Config{
+		Runner:    runner,
+		SessionID: "tool-choice",
+		Tools:     []llm.ToolSpec{{Name: "read"}},
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	submit := func() {
+		t.Helper()
+		stream, err := session.Submit(context.Background(), "hello")
+		if err != nil {
+			t.Fatalf("Submit() err = %v", err)
+		}
+		for range stream {
+		}
+	}
+
+	if err := session.SetToolChoice(llm.ToolChoice{Type: llm.ToolChoiceTool, Name: "write"}, false); !errors.Is(err, ErrUnknownTool) {
+		t.Fatalf("SetToolChoice(write) err = %v, want ErrUnknownTool", err)
+	}
+	if err := session.SetToolChoice(llm.ToolChoice{Type: llm.ToolChoiceNone}, false); err != nil {
+		t.Fatalf("SetToolChoice(none) err = %v", err)
+	}
+	submit()
+	submit()
+	if err := session.SetToolChoice(llm.ToolChoice{Type: llm.ToolChoiceTool, Name: "read"}, true); err != nil {
+		t.Fatalf("SetToolChoice(read) err = %v", err)
+	}
+	submit()
+	submit()
+
+	want := []llm.ToolChoice{
+		{Type: llm.ToolChoiceNone},
+		{},
+		{Type: llm.ToolChoiceTool, Name: "read"},
+		{Type: llm.ToolChoiceTool, Name: "read"},
+	}
+	if !reflect.DeepEqual(choices, want) {
+		t.Fatalf("request tool choices = %#v, want %#v", choices, want)
+	}
 }
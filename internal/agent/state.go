@@ -4,8 +4,9 @@ package agent
 type State string
 
 const (
-	StateIdle          State = "idle"
-	StateStreaming     State = "streaming"
-	StateToolExecuting State = "tool_executing"
-	StateError         State = "error"
+	StateIdle             State = "idle"
+	StateStreaming        State = "streaming"
+	StateToolExecuting    State = "tool_executing"
+	StateAwaitingApproval State = "awaiting_approval"
+	StateError            State = "error"
 )
@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"regexp"
 	"runtime"
 	"strings"
 	"time"
@@ -15,32 +17,99 @@ import (
 
 const bashToolName = "bash"
 
-// BashTool executes shell commands synchronously.
+// ErrCommandBlocked is returned when a command is refused by the bash allow/deny lists.
+var ErrCommandBlocked = errors.New("command blocked by bash policy")
+
+// BashOptions configures the bash tool.
+type BashOptions struct {
+	// WorkspaceRoot is the working directory for commands; empty uses the cwd.
+	WorkspaceRoot string
+	// Deny lists regex patterns; a command matching any of them is refused.
+	Deny []string
+	// Allow lists regex patterns; when non-empty, only matching commands run.
+	Allow []string
+}
+
+// BashTool executes shell commands synchronously, streaming their output to
+// the context's ProgressReporter when one is set.
 type BashTool struct {
+	workspaceRoot  string
 	maxOutputLines int
 	maxOutputBytes int
+	deny           []*regexp.Regexp
+	allow          []*regexp.Regexp
 }
 
 // NewBashTool constructs bash tool with sensible defaults.
-func NewBashTool() BashTool {
+func NewBashTool() BashTool { return NewBashToolAt("") }
+
+// NewBashToolAt constructs the bash tool running commands in workspaceRoot.
+// An empty root uses the current working directory.
+func NewBashToolAt(workspaceRoot string) BashTool {
 	return BashTool{
+		workspaceRoot:  workspaceRoot,
 		maxOutputLines: defaultMaxLines,
 		maxOutputBytes: defaultMaxBytes,
 	}
 }
 
+// NewBashToolWithOptions constructs the bash tool with command allow/deny lists.
+// Empty lists leave commands unrestricted.
+func NewBashToolWithOptions(opts BashOptions) (BashTool, error) {
+	tool := NewBashToolAt(opts.WorkspaceRoot)
+	var err error
+	if tool.deny, err = compileCommandPatterns(opts.Deny); err != nil {
+		return BashTool{}, fmt.Errorf("compile bash deny list: %w", err)
+	}
+	if tool.allow, err = compileCommandPatterns(opts.Allow); err != nil {
+		return BashTool{}, fmt.Errorf("compile bash allow list: %w", err)
+	}
+	return tool, nil
+}
+
+func compileCommandPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// checkCommand refuses commands matching a deny pattern, or matching no
+// allow pattern when an allow list is configured.
+func (b BashTool) checkCommand(command string) error {
+	for _, re := range b.deny {
+		if re.MatchString(command) {
+			return fmt.Errorf("%w: matches deny pattern %q", ErrCommandBlocked, re.String())
+		}
+	}
+	if len(b.allow) == 0 {
+		return nil
+	}
+	for _, re := range b.allow {
+		if re.MatchString(command) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: does not match any allow pattern", ErrCommandBlocked)
+}
+
 func (BashTool) Name() string { return bashToolName }
 
 func (BashTool) Description() string {
 	return fmt.Sprintf(
-		"Execute a bash command in the current working directory. Returns stdout and stderr. Output is truncated to last %d lines or %dKB (whichever is hit first). If truncated, full output is saved to a temp file. Optionally provide a timeout in seconds.",
+		"Execute a bash command in the workspace root directory. Returns stdout and stderr. Output is truncated to last %d lines or %dKB (whichever is hit first). If truncated, full output is saved to a temp file. Optionally provide a timeout in seconds.",
 		defaultMaxLines,
 		defaultMaxBytes/1024,
 	)
 }
 
 func (BashTool) Schema() json.RawMessage {
-	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what this command does (shown to user)"},"command":{"type":"string","description":"Bash command to execute"},"timeout":{"type":"number","description":"Timeout in seconds (optional, no default timeout)"}},"required":["label","command"]}`)
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what this command does (shown to user)"},"command":{"type":"string","description":"Bash command to execute"},"timeout":{"type":"number","description":"Timeout in seconds (optional, no default timeout)"}},"required":["command"]}`)
 }
 
 func (b BashTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
@@ -64,6 +133,9 @@ func (b BashTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 	if command == "" {
 		return Result{}, errors.New("command is required")
 	}
+	if err := b.checkCommand(command); err != nil {
+		return Result{}, err
+	}
 
 	timeoutSeconds := 0
 	if input.Timeout != nil {
@@ -83,11 +155,19 @@ func (b BashTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 	defer cancel()
 
 	cmd := shellCommand(runCtx, command)
+	if strings.TrimSpace(b.workspaceRoot) != "" {
+		cmd.Dir = b.workspaceRoot
+	}
 
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	if report := progressReporterFrom(ctx); report != nil {
+		progress := &progressWriter{report: report}
+		cmd.Stdout = io.MultiWriter(&stdout, progress)
+		cmd.Stderr = io.MultiWriter(&stderr, progress)
+	}
 
 	runErr := cmd.Run()
 	output := combineStdoutStderr(stdout.String(), stderr.String())
@@ -3,6 +3,7 @@ package tool
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -22,6 +23,46 @@ func TestBashToolRunsCommand(t *testing.T) {
 	}
 }
 
+func TestBashToolStreamsProgressChunks(t *testing.T) {
+	t.Parallel()
+
+	var chunks []string
+	ctx := WithProgressReporter(context.Background(), func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	tool := NewBashTool()
+	got, err := tool.Execute(ctx, json.RawMessage(`{"command":"echo one; sleep 0.2; echo two"}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("progress chunks = %q, want output streamed over time", chunks)
+	}
+	if streamed := strings.Join(chunks, ""); streamed != "one\ntwo\n" {
+		t.Fatalf("streamed output = %q, want one and two in order", streamed)
+	}
+	if strings.TrimSpace(got.Content) != "one\ntwo" {
+		t.Fatalf("Execute().Content = %q, want full output", got.Content)
+	}
+}
+
+func TestBashToolRunsInWorkspaceRoot(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "marker.txt"), []byte("here"), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	got, err := NewBashToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"command":"cat marker.txt"}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if strings.TrimSpace(got.Content) != "here" {
+		t.Fatalf("Execute().Content = %q, want marker contents", got.Content)
+	}
+}
+
 func TestBashToolHonorsTimeout(t *testing.T) {
 	t.Parallel()
 
@@ -94,3 +135,50 @@ func TestBashToolTruncatesOutputAndPersistsFullOutput(t *testing.T) {
 		t.Fatalf("os.Stat(%q) error = %v", d.FullOutputPath, err)
 	}
 }
+
+func TestBashToolDenyAndAllowLists(t *testing.T) {
+	t.Parallel()
+
+	denied, err := NewBashToolWithOptions(BashOptions{Deny: []string{`rm\s+-rf\s+/`, `mkfs`}})
+	if err != nil {
+		t.Fatalf("NewBashToolWithOptions(deny) error = %v", err)
+	}
+	workspace := t.TempDir()
+	marker := filepath.Join(workspace, "marker")
+	if err := os.WriteFile(marker, []byte("x"), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	params, _ := json.Marshal(map[string]string{"command": "rm -rf " + marker})
+	if _, err := denied.Execute(context.Background(), params); !errors.Is(err, ErrCommandBlocked) {
+		t.Fatalf("Execute(denied) error = %v, want ErrCommandBlocked", err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("denied command ran: %v", err)
+	}
+	if got, err := denied.Execute(context.Background(), json.RawMessage(`{"command":"printf ok"}`)); err != nil || got.Content != "ok" {
+		t.Fatalf("Execute(not denied) = %q, %v, want ok", got.Content, err)
+	}
+
+	allowed, err := NewBashToolWithOptions(BashOptions{Allow: []string{`^(printf|echo)\b`}})
+	if err != nil {
+		t.Fatalf("NewBashToolWithOptions(allow) error = %v", err)
+	}
+	if got, err := allowed.Execute(context.Background(), json.RawMessage(`{"command":"echo hi"}`)); err != nil || strings.TrimSpace(got.Content) != "hi" {
+		t.Fatalf("Execute(allowed) = %q, %v, want hi", got.Content, err)
+	}
+	if _, err := allowed.Execute(context.Background(), json.RawMessage(`{"command":"ls"}`)); !errors.Is(err, ErrCommandBlocked) {
+		t.Fatalf("Execute(not allowed) error = %v, want ErrCommandBlocked", err)
+	}
+
+	unrestricted, err := NewBashToolWithOptions(BashOptions{})
+	if err != nil {
+		t.Fatalf("NewBashToolWithOptions() error = %v", err)
+	}
+	if got, err := unrestricted.Execute(context.Background(), json.RawMessage(`{"command":"printf 'mkfs'"}`)); err != nil || got.Content != "mkfs" {
+		t.Fatalf("Execute(unrestricted) = %q, %v, want mkfs", got.Content, err)
+	}
+
+	if _, err := NewBashToolWithOptions(BashOptions{Deny: []string{"("}}); err == nil {
+		t.Fatalf("NewBashToolWithOptions(invalid) error = nil, want compile error")
+	}
+}
@@ -0,0 +1,69 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ChangesDiff renders one consolidated diff for the files in changes, where
+// each snapshot holds a file's state before it was first modified. Files
+// tracked by git are diffed with `git diff`; other files are diffed against
+// the snapshot's prior content. Unchanged files are omitted.
+func ChangesDiff(ctx context.Context, changes []UndoSnapshot) (string, error) {
+	_, lookErr := exec.LookPath("git")
+	sections := make([]string, 0, len(changes))
+	for _, change := range changes {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		var section string
+		var err error
+		if lookErr == nil && gitTracked(ctx, change.Path) {
+			section, err = runGit(ctx, filepath.Dir(change.Path), "diff", "--no-color", "--", filepath.Base(change.Path))
+		} else {
+			section, err = snapshotDiff(change)
+		}
+		if err != nil {
+			return "", err
+		}
+		if section = strings.TrimRight(section, "\n"); section != "" {
+			sections = append(sections, section)
+		}
+	}
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// gitTracked reports whether path is in the index of the repository containing it.
+func gitTracked(ctx context.Context, path string) bool {
+	_, err := runGit(ctx, filepath.Dir(path), "ls-files", "--error-unmatch", "--", filepath.Base(path))
+	return err == nil
+}
+
+// snapshotDiff compares the snapshot's prior content with the file on disk.
+func snapshotDiff(change UndoSnapshot) (string, error) {
+	current, err := os.ReadFile(change.Path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("read %s: %w", change.Path, err)
+	}
+	if exists == change.PreviousExists && bytes.Equal(current, change.PreviousContent) {
+		return "", nil
+	}
+
+	oldName, newName := change.Path, change.Path
+	if !change.PreviousExists {
+		oldName = "/dev/null"
+	}
+	if !exists {
+		newName = "/dev/null"
+	}
+	header := fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName)
+	return header + generateDiffString(string(change.PreviousContent), string(current), 4), nil
+}
@@ -0,0 +1,70 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChangesDiffUsesGitForTrackedFiles(t *testing.T) {
+	t.Parallel()
+
+	repo := initGitFixture(t)
+	tracked := filepath.Join(repo, "main.go")
+	created := filepath.Join(repo, "new.txt")
+	if err := os.WriteFile(tracked, []byte("package app\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(created, []byte("hello\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	got, err := ChangesDiff(context.Background(), []UndoSnapshot{
+		{Path: tracked, PreviousContent: []byte("package main\n"), PreviousExists: true},
+		{Path: created},
+	})
+	if err != nil {
+		t.Fatalf("ChangesDiff() error = %v", err)
+	}
+	if !strings.Contains(got, "diff --git a/main.go b/main.go") || !strings.Contains(got, "+package app") {
+		t.Fatalf("ChangesDiff() = %q, want git diff of main.go", got)
+	}
+	if !strings.Contains(got, "--- /dev/null\n+++ "+created) || !strings.Contains(got, "+1 hello") {
+		t.Fatalf("ChangesDiff() = %q, want snapshot diff of untracked new.txt", got)
+	}
+}
+
+func TestChangesDiffOutsideRepository(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	edited := filepath.Join(dir, "notes.txt")
+	unchanged := filepath.Join(dir, "same.txt")
+	removed := filepath.Join(dir, "gone.txt")
+	if err := os.WriteFile(edited, []byte("after\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(unchanged, []byte("same\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	got, err := ChangesDiff(context.Background(), []UndoSnapshot{
+		{Path: edited, PreviousContent: []byte("before\n"), PreviousExists: true},
+		{Path: unchanged, PreviousContent: []byte("same\n"), PreviousExists: true},
+		{Path: removed, PreviousContent: []byte("old\n"), PreviousExists: true},
+	})
+	if err != nil {
+		t.Fatalf("ChangesDiff() error = %v", err)
+	}
+	if !strings.Contains(got, "-1 before") || !strings.Contains(got, "+1 after") {
+		t.Fatalf("ChangesDiff() = %q, want notes.txt change", got)
+	}
+	if strings.Contains(got, unchanged) {
+		t.Fatalf("ChangesDiff() = %q, want unchanged file omitted", got)
+	}
+	if !strings.Contains(got, "--- "+removed+"\n+++ /dev/null") {
+		t.Fatalf("ChangesDiff() = %q, want deletion of gone.txt", got)
+	}
+}
@@ -0,0 +1,75 @@
+package tool
+
+import (
+	"context"
+	"strings"
+)
+
+// semaphore bounds concurrent tool executions. A nil semaphore is unlimited.
+type semaphore chan struct{}
+
+func newSemaphore(limit int) semaphore {
+	if limit <= 0 {
+		return nil
+	}
+	return make(semaphore, limit)
+}
+
+// acquire blocks until a slot is free or ctx is done.
+func (s semaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+// SetConcurrencyLimits bounds how many executions run at once: global caps
+// all tools together and byTool caps individual tools by name. A limit <= 0
+// leaves that scope unlimited. Executions already running keep the slots of
+// the limits they started under.
+func (r *Registry) SetConcurrencyLimits(global int, byTool map[string]int) {
+	perTool := make(map[string]semaphore, len(byTool))
+	for name, limit := range byTool {
+		if sem := newSemaphore(limit); sem != nil {
+			perTool[strings.TrimSpace(name)] = sem
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.globalSem = newSemaphore(global)
+	r.toolSems = perTool
+}
+
+// acquireSlots waits for name's per-tool slot, then a global slot, and
+// returns a func releasing both. Taking them in a fixed order keeps waiting
+// executions from deadlocking each other.
+func (r *Registry) acquireSlots(ctx context.Context, name string) (func(), error) {
+	r.mu.RLock()
+	toolSem := r.toolSems[name]
+	globalSem := r.globalSem
+	r.mu.RUnlock()
+
+	if err := toolSem.acquire(ctx); err != nil {
+		return nil, err
+	}
+	if err := globalSem.acquire(ctx); err != nil {
+		toolSem.release()
+		return nil, err
+	}
+	return func() {
+		globalSem.release()
+		toolSem.release()
+	}, nil
+}
@@ -0,0 +1,151 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistryExecuteSerializesToolWithLimitOne(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	reg := NewRegistry(NewBashToolAt(workspace))
+	reg.SetConcurrencyLimits(0, map[string]int{"bash": 1})
+
+	params := json.RawMessage(`{"command":"echo start >> log.txt; sleep 0.2; echo end >> log.txt"}`)
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := reg.Execute(context.Background(), "bash", params)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+
+	raw, err := os.ReadFile(filepath.Join(workspace, "log.txt"))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if got, want := strings.Fields(string(raw)), []string{"start", "end", "start", "end"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("log = %v, want %v (runs must not overlap)", got, want)
+	}
+}
+
+func TestRegistryExecuteLimitLeavesOtherToolsFree(t *testing.T) {
+	t.Parallel()
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	reg := NewRegistry(
+		fakeTool{name: "bash", run: func(ctx context.Context, _ json.RawMessage) (Result, error) {
+			close(started)
+			<-block
+			return Result{}, nil
+		}},
+		fakeTool{name: "read", run: func(ctx context.Context, _ json.RawMessage) (Result, error) {
+			return Result{Content: "ok"}, nil
+		}},
+	)
+	reg.SetConcurrencyLimits(0, map[string]int{"bash": 1})
+
+	go func() { _, _ = reg.Execute(context.Background(), "bash", nil) }()
+	<-started
+	defer close(block)
+
+	got, err := reg.Execute(context.Background(), "read", nil)
+	if err != nil || got.Content != "ok" {
+		t.Fatalf("Execute(read) = %q, %v, want ok while bash holds its slot", got.Content, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := reg.Execute(ctx, "bash", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Execute(bash) error = %v, want DeadlineExceeded while waiting for a slot", err)
+	}
+}
+
+func TestRegistryExecuteGlobalLimitCapsAllTools(t *testing.T) {
+	t.Parallel()
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	reg := NewRegistry(
+		fakeTool{name: "slow", run: func(ctx context.Context, _ json.RawMessage) (Result, error) {
+			close(started)
+			<-block
+			return Result{}, nil
+		}},
+		fakeTool{name: "read"},
+	)
+	reg.SetConcurrencyLimits(1, nil)
+
+	go func() { _, _ = reg.Execute(context.Background(), "slow", nil) }()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := reg.Execute(ctx, "read", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Execute(read) error = %v, want DeadlineExceeded under global limit", err)
+	}
+
+	close(block)
+	if _, err := reg.Execute(context.Background(), "read", nil); err != nil {
+		t.Fatalf("Execute(read) after release error = %v", err)
+	}
+}
+
+func TestRegistryExecuteWithTimeoutStartsOnceSlotIsHeld(t *testing.T) {
+	t.Parallel()
+
+	block := make(chan struct{})
+	started := make(chan struct{}, 2)
+	reg := NewRegistry(fakeTool{name: "bash", run: func(ctx context.Context, _ json.RawMessage) (Result, error) {
+		started <- struct{}{}
+		select {
+		case <-block:
+			return Result{Content: "ok"}, nil
+		case <-ctx.Done():
+			return Result{}, ctx.Err()
+		}
+	}})
+	reg.SetConcurrencyLimits(0, map[string]int{"bash": 1})
+
+	go func() { _, _ = reg.Execute(context.Background(), "bash", nil) }()
+	<-started
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := reg.ExecuteWithTimeout(context.Background(), "bash", nil, 50*time.Millisecond)
+		done <- err
+	}()
+	// Queue the second call well past its timeout before freeing the slot.
+	time.Sleep(100 * time.Millisecond)
+	close(block)
+	if err := <-done; err != nil {
+		t.Fatalf("ExecuteWithTimeout() error = %v, want time spent queued not to count", err)
+	}
+
+	stuck := NewRegistry(fakeTool{name: "bash", run: func(ctx context.Context, _ json.RawMessage) (Result, error) {
+		<-ctx.Done()
+		return Result{}, ctx.Err()
+	}})
+	if _, err := stuck.ExecuteWithTimeout(context.Background(), "bash", nil, 20*time.Millisecond); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("ExecuteWithTimeout() error = %v, want ErrTimeout", err)
+	}
+}
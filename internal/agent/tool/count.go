@@ -0,0 +1,237 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	countToolName = "count"
+	// defaultCountLimit bounds how many files one directory count visits.
+	defaultCountLimit = 200
+	// countSniffBytes is how much of a file is checked for NUL bytes to
+	// classify it as binary, as git does.
+	countSniffBytes = 8000
+)
+
+// fileCount is one file's entry in the count display payload. Lines and
+// words are zero for binary files.
+type fileCount struct {
+	Path   string `json:"path"`
+	Lines  int    `json:"lines"`
+	Words  int    `json:"words"`
+	Bytes  int64  `json:"bytes"`
+	Binary bool   `json:"binary,omitempty"`
+}
+
+// CountTool reports line, word, and byte counts for a file, or for each file
+// under a directory. Lines include a final line without a trailing newline
+// and words are runs of non-space bytes, so results do not depend on the
+// platform's wc.
+type CountTool struct {
+	workspaceRoot string
+}
+
+// NewCountTool constructs the count tool.
+func NewCountTool() CountTool { return NewCountToolAt("") }
+
+// NewCountToolAt constructs the count tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewCountToolAt(workspaceRoot string) CountTool {
+	return CountTool{workspaceRoot: workspaceRoot}
+}
+
+func (CountTool) Name() string { return countToolName }
+
+// Idempotent marks count as safe to retry after a failure.
+func (CountTool) Idempotent() bool { return true }
+
+// ReadOnly marks count as available in plan mode.
+func (CountTool) ReadOnly() bool { return true }
+
+func (CountTool) Description() string {
+	return fmt.Sprintf(
+		"Count lines, words, and bytes of a file, and report whether it is binary. For a directory, counts each file below it (skipping .git, node_modules, and .gitignore'd paths) up to limit files (default %d) and prints a total.",
+		defaultCountLimit,
+	)
+}
+
+func (CountTool) Schema() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're counting (shown to user)"},"path":{"type":"string","description":"File or directory to count (relative or absolute)"},"limit":{"type":"number","description":"For directories: maximum number of files to count (default: 200)"}},"required":["path"]}`)
+}
+
+func (c CountTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	default:
+	}
+
+	var input struct {
+		Label string `json:"label"`
+		Path  string `json:"path"`
+		Limit *int   `json:"limit"`
+	}
+	if err := decodeParams(params, &input); err != nil {
+		return Result{}, fmt.Errorf("decode count params: %w", err)
+	}
+	pathArg := strings.TrimSpace(input.Path)
+	if pathArg == "" {
+		return Result{}, errors.New("path is required")
+	}
+	limit := defaultCountLimit
+	if input.Limit != nil {
+		if *input.Limit <= 0 {
+			return Result{}, errors.New("limit must be > 0")
+		}
+		limit = *input.Limit
+	}
+
+	path, err := resolveWorkspacePath(c.workspaceRoot, pathArg, false)
+	if err != nil {
+		return Result{}, fmt.Errorf("resolve count path: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("stat %s: %w", pathArg, err)
+	}
+
+	if !info.IsDir() {
+		count, err := countFile(path)
+		if err != nil {
+			return Result{}, fmt.Errorf("count %s: %w", pathArg, err)
+		}
+		count.Path = pathArg
+		return countResult(formatFileCount(count), map[string]any{"files": []fileCount{count}}), nil
+	}
+
+	var counts []fileCount
+	limitReached := false
+	walkErr := walkWorkspace(ctx, path, false, func(filePath string, d fs.DirEntry) error {
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(counts) >= limit {
+			limitReached = true
+			return filepath.SkipAll
+		}
+		count, err := countFile(filePath)
+		if err != nil {
+			return fmt.Errorf("count %s: %w", filePath, err)
+		}
+		rel, err := filepath.Rel(path, filePath)
+		if err != nil {
+			rel = filePath
+		}
+		count.Path = filepath.ToSlash(rel)
+		counts = append(counts, count)
+		return nil
+	})
+	if walkErr != nil {
+		return Result{}, walkErr
+	}
+	if len(counts) == 0 {
+		return countResult("(no files)", map[string]any{"files": []fileCount{}}), nil
+	}
+
+	total := fileCount{Path: "total"}
+	lines := make([]string, 0, len(counts)+2)
+	for _, count := range counts {
+		total.Lines += count.Lines
+		total.Words += count.Words
+		total.Bytes += count.Bytes
+		lines = append(lines, formatFileCount(count))
+	}
+	lines = append(lines, fmt.Sprintf("%s (%d files)", formatFileCount(total), len(counts)))
+	if limitReached {
+		lines = append(lines, fmt.Sprintf("[Stopped after %d files. Use limit=%d for more]", limit, limit*2))
+	}
+
+	output := strings.Join(lines, "\n")
+	truncation := truncateHead(output, truncationOptions{MaxLines: maxIntValue, MaxBytes: defaultMaxBytes})
+	if truncation.Truncated {
+		output = truncation.Content + fmt.Sprintf("\n[%s limit reached]", formatSize(defaultMaxBytes))
+	}
+	return countResult(output, map[string]any{
+		"files":     counts,
+		"total":     total,
+		"truncated": limitReached,
+	}), nil
+}
+
+func countResult(content string, details map[string]any) Result {
+	payload, _ := json.Marshal(details)
+	return Result{
+		Content: content,
+		Display: DisplayData{Type: "file_counts", Payload: payload},
+	}
+}
+
+func formatFileCount(count fileCount) string {
+	if count.Binary {
+		return fmt.Sprintf("%s: binary, %d bytes", count.Path, count.Bytes)
+	}
+	return fmt.Sprintf("%s: %d lines, %d words, %d bytes", count.Path, count.Lines, count.Words, count.Bytes)
+}
+
+// countFile streams path once, counting bytes, lines, and words. A NUL byte in
+// the first countSniffBytes marks the file binary.
+func countFile(path string) (fileCount, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return fileCount{}, err
+	}
+	defer file.Close()
+
+	var count fileCount
+	buf := make([]byte, 32*1024)
+	inWord := false
+	lastByte := byte('\n')
+	for {
+		n, err := file.Read(buf)
+		chunk := buf[:n]
+		if count.Bytes < countSniffBytes {
+			sniff := chunk[:min(int64(n), countSniffBytes-count.Bytes)]
+			if bytes.IndexByte(sniff, 0) >= 0 {
+				count.Binary = true
+			}
+		}
+		count.Bytes += int64(n)
+		if !count.Binary {
+			for _, b := range chunk {
+				if b == '\n' {
+					count.Lines++
+				}
+				space := b == ' ' || (b >= '\t' && b <= '\r')
+				if !space && !inWord {
+					count.Words++
+				}
+				inWord = !space
+			}
+			if n > 0 {
+				lastByte = chunk[n-1]
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fileCount{}, err
+		}
+	}
+
+	if count.Binary {
+		count.Lines, count.Words = 0, 0
+	} else if lastByte != '\n' {
+		count.Lines++
+	}
+	return count, nil
+}
@@ -0,0 +1,93 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCountToolCountsTextFile(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "notes.txt"), []byte("one two\n  three\tfour\nfive"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	got, err := NewCountToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"path":"notes.txt"}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got.Content != "notes.txt: 3 lines, 5 words, 25 bytes" {
+		t.Fatalf("Execute().Content = %q, want 3 lines, 5 words, 25 bytes", got.Content)
+	}
+	var payload struct {
+		Files []fileCount `json:"files"`
+	}
+	if err := json.Unmarshal(got.Display.Payload, &payload); err != nil {
+		t.Fatalf("Unmarshal(payload) error = %v", err)
+	}
+	want := fileCount{Path: "notes.txt", Lines: 3, Words: 5, Bytes: 25}
+	if got.Display.Type != "file_counts" || len(payload.Files) != 1 || payload.Files[0] != want {
+		t.Fatalf("Display = %s %s, want file_counts with %+v", got.Display.Type, got.Display.Payload, want)
+	}
+}
+
+func TestCountToolReportsBinaryAndAggregatesDirectories(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	files := map[string][]byte{
+		"src/a.go":      []byte("package a\n"),
+		"src/b.go":      []byte("package b\n\nfunc B() {}\n"),
+		"src/image.png": {0x89, 'P', 'N', 'G', 0x00, 0x01, '\n', 0x02},
+	}
+	for name, body := range files {
+		path := filepath.Join(workspace, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, body, 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	got, err := NewCountToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"path":"src"}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	for _, want := range []string{
+		"a.go: 1 lines, 2 words, 10 bytes",
+		"b.go: 3 lines, 5 words, 23 bytes",
+		"image.png: binary, 8 bytes",
+		"total: 4 lines, 7 words, 41 bytes (3 files)",
+	} {
+		if !strings.Contains(got.Content, want) {
+			t.Fatalf("Execute().Content = %q, want it to contain %q", got.Content, want)
+		}
+	}
+
+	limited, err := NewCountToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"path":"src","limit":1}`))
+	if err != nil {
+		t.Fatalf("Execute(limit=1) error = %v", err)
+	}
+	if !strings.Contains(limited.Content, "(1 files)") || !strings.Contains(limited.Content, "[Stopped after 1 files.") {
+		t.Fatalf("Execute(limit=1).Content = %q, want one file and a stop notice", limited.Content)
+	}
+}
+
+func TestCountToolRejectsPathOutsideWorkspace(t *testing.T) {
+	t.Parallel()
+
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+	if err := os.WriteFile(outside, []byte("x"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	params, _ := json.Marshal(map[string]string{"path": outside})
+	if _, err := NewCountToolAt(t.TempDir()).Execute(context.Background(), params); err == nil {
+		t.Fatalf("Execute() error = nil, want workspace sandbox error")
+	}
+}
@@ -0,0 +1,131 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const deleteToolName = "delete"
+
+// DeleteTool removes a file, or a directory tree when asked explicitly.
+type DeleteTool struct {
+	workspaceRoot string
+}
+
+// NewDeleteTool constructs the delete tool.
+func NewDeleteTool() DeleteTool { return NewDeleteToolAt("") }
+
+// NewDeleteToolAt constructs the delete tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewDeleteToolAt(workspaceRoot string) DeleteTool {
+	return DeleteTool{workspaceRoot: workspaceRoot}
+}
+
+func (DeleteTool) Name() string { return deleteToolName }
+
+func (DeleteTool) Description() string {
+	return "Delete a file in the workspace. Directories are refused unless recursive is true, which deletes the directory and everything in it."
+}
+
+func (DeleteTool) Schema() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're deleting (shown to user)"},"path":{"type":"string","description":"Path to the file or directory to delete (relative or absolute)"},"recursive":{"type":"boolean","description":"Required to delete a directory and its contents (default: false)"}},"required":["path"]}`)
+}
+
+func (d DeleteTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	default:
+	}
+
+	var input struct {
+		Label     string `json:"label"`
+		Path      string `json:"path"`
+		Recursive bool   `json:"recursive"`
+	}
+	if err := decodeParams(params, &input); err != nil {
+		return Result{}, fmt.Errorf("decode delete params: %w", err)
+	}
+
+	pathArg := strings.TrimSpace(input.Path)
+	if pathArg == "" {
+		return Result{}, errors.New("path is required")
+	}
+
+	root, path, err := resolveWorkspaceEntry(d.workspaceRoot, pathArg)
+	if err != nil {
+		return Result{}, fmt.Errorf("resolve delete path: %w", err)
+	}
+	if path == root {
+		return Result{}, errors.New("cannot delete the workspace root")
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("stat %s: %w", pathArg, err)
+	}
+
+	payload := map[string]any{"path": pathArg}
+	var content string
+	if info.IsDir() {
+		if !input.Recursive {
+			return Result{}, fmt.Errorf("%s is a directory (set recursive to delete it and its contents)", pathArg)
+		}
+		count, err := countEntries(path)
+		if err != nil {
+			return Result{}, fmt.Errorf("scan %s: %w", pathArg, err)
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return Result{}, fmt.Errorf("delete %s: %w", pathArg, err)
+		}
+		payload["kind"] = "directory"
+		payload["entries"] = count
+		content = fmt.Sprintf("Deleted directory %s (%d entries)", pathArg, count)
+	} else {
+		var prior []byte
+		if info.Mode().IsRegular() {
+			if prior, err = os.ReadFile(path); err != nil {
+				return Result{}, fmt.Errorf("read %s: %w", pathArg, err)
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			return Result{}, fmt.Errorf("delete %s: %w", pathArg, err)
+		}
+		payload["kind"] = "file"
+		payload["bytes"] = info.Size()
+		if info.Mode().IsRegular() {
+			payload = withUndoSnapshot(payload, path, prior, true)
+		}
+		content = fmt.Sprintf("Deleted file %s (%s)", pathArg, formatSize(int(info.Size())))
+	}
+
+	details, _ := json.Marshal(payload)
+	return Result{
+		Content: content,
+		Display: DisplayData{
+			Type:    "delete_result",
+			Payload: details,
+		},
+	}, nil
+}
+
+// countEntries counts the files and directories below dir.
+func countEntries(dir string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir {
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,93 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeleteToolRemovesFileWithUndoSnapshot(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "gone.txt")
+	if err := os.WriteFile(path, []byte("bye"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	got, err := NewDeleteToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"path":"gone.txt"}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.HasPrefix(got.Content, "Deleted file gone.txt") {
+		t.Fatalf("Execute().Content = %q, want delete summary", got.Content)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Stat() error = %v, want not exist", err)
+	}
+
+	snapshot, ok := UndoSnapshotFromPayload(got.Display.Payload)
+	if !ok || !snapshot.PreviousExists || string(snapshot.PreviousContent) != "bye" {
+		t.Fatalf("UndoSnapshotFromPayload() = %#v, %v; want prior content", snapshot, ok)
+	}
+	if err := snapshot.Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if raw, err := os.ReadFile(path); err != nil || string(raw) != "bye" {
+		t.Fatalf("ReadFile() after restore = %q, %v; want bye", raw, err)
+	}
+}
+
+func TestDeleteToolRequiresRecursiveForDirectories(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	dir := filepath.Join(workspace, "build")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "a.o"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	tool := NewDeleteToolAt(workspace)
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"build"}`)); err == nil || !strings.Contains(err.Error(), "recursive") {
+		t.Fatalf("Execute() error = %v, want recursive required", err)
+	}
+	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"build","recursive":true}`))
+	if err != nil {
+		t.Fatalf("Execute(recursive) error = %v", err)
+	}
+	if got.Content != "Deleted directory build (2 entries)" {
+		t.Fatalf("Execute(recursive).Content = %q, want directory summary", got.Content)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("Stat() error = %v, want not exist", err)
+	}
+}
+
+func TestDeleteToolRejectsWorkspaceRootAndOutsidePaths(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	tool := NewDeleteToolAt(workspace)
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"path":".","recursive":true}`)); err == nil || !strings.Contains(err.Error(), "workspace root") {
+		t.Fatalf("Execute(.) error = %v, want workspace root refused", err)
+	}
+
+	outside := filepath.Join(t.TempDir(), "keep.txt")
+	if err := os.WriteFile(outside, []byte("x"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	params, _ := json.Marshal(map[string]string{"path": outside})
+	if _, err := tool.Execute(context.Background(), params); !errors.Is(err, ErrPathOutsideWorkspace) {
+		t.Fatalf("Execute(outside) error = %v, want ErrPathOutsideWorkspace", err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Fatalf("Stat(outside) error = %v, want file kept", err)
+	}
+}
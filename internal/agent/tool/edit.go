@@ -12,26 +12,43 @@ import (
 
 const editToolName = "edit"
 
+// EditOptions configures the edit and multiedit tools.
+type EditOptions struct {
+	// WorkspaceRoot sandboxes file access; empty uses the cwd.
+	WorkspaceRoot string
+	// ExactMatch disables the fallback that matches oldText after
+	// normalizing quotes, dashes, and trailing whitespace.
+	ExactMatch bool
+}
+
 // EditTool performs string replacement in an existing file.
 type EditTool struct {
 	workspaceRoot string
+	exactMatch    bool
 }
 
 // NewEditTool constructs the edit tool.
-func NewEditTool() EditTool { return newEditTool("") }
+func NewEditTool() EditTool { return NewEditToolAt("") }
 
-func newEditTool(workspaceRoot string) EditTool {
+// NewEditToolAt constructs the edit tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewEditToolAt(workspaceRoot string) EditTool {
 	return EditTool{workspaceRoot: workspaceRoot}
 }
 
+// NewEditToolWithOptions constructs the edit tool from opts.
+func NewEditToolWithOptions(opts EditOptions) EditTool {
+	return EditTool{workspaceRoot: opts.WorkspaceRoot, exactMatch: opts.ExactMatch}
+}
+
 func (EditTool) Name() string { return editToolName }
 
 func (EditTool) Description() string {
-	return "Edit a file by replacing exact text. The oldText must match exactly (including whitespace). Use this for precise, surgical edits."
+	return "Edit a file by replacing exact text. The oldText must match exactly (including whitespace) and be unique unless replaceAll is true, which replaces every exact occurrence (use for mechanical renames). Use this for precise, surgical edits."
 }
 
 func (EditTool) Schema() json.RawMessage {
-	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of the edit you're making (shown to user)"},"path":{"type":"string","description":"Path to the file to edit (relative or absolute)"},"oldText":{"type":"string","description":"Exact text to find and replace (must match exactly)"},"newText":{"type":"string","description":"New text to replace the old text with"}},"required":["label","path","oldText","newText"]}`)
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of the edit you're making (shown to user)"},"path":{"type":"string","description":"Path to the file to edit (relative or absolute)"},"oldText":{"type":"string","description":"Exact text to find and replace (must match exactly)"},"newText":{"type":"string","description":"New text to replace the old text with"},"replaceAll":{"type":"boolean","description":"Replace every exact occurrence of oldText instead of requiring it to be unique (default: false)"}},"required":["path","oldText","newText"]}`)
 }
 
 func (e EditTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
@@ -46,8 +63,9 @@ func (e EditTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 		Path    string `json:"path"`
 		OldText string `json:"oldText"`
 		NewText string `json:"newText"`
-		Old     string `json:"old"`
-		New     string `json:"new"`
+		// ReplaceAll replaces every exact occurrence instead of requiring
+		// oldText to be unique.
+		ReplaceAll bool `json:"replaceAll"`
 	}
 	if err := decodeParams(params, &input); err != nil {
 		return Result{}, fmt.Errorf("decode edit params: %w", err)
@@ -58,15 +76,7 @@ func (e EditTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 		return Result{}, errors.New("path is required")
 	}
 
-	oldText := input.OldText
-	if oldText == "" {
-		oldText = input.Old
-	}
-	newText := input.NewText
-	if newText == "" && input.New != "" {
-		newText = input.New
-	}
-
+	oldText, newText := input.OldText, input.NewText
 	if oldText == "" {
 		return Result{}, errors.New("oldText is required")
 	}
@@ -87,28 +97,17 @@ func (e EditTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 	normalizedOldText := normalizeToLF(oldText)
 	normalizedNewText := normalizeToLF(newText)
 
-	match := fuzzyFindText(normalizedContent, normalizedOldText)
-	if !match.Found {
-		return Result{}, fmt.Errorf(
-			"Could not find the exact text in %s. The old text must match exactly including all whitespace and newlines.",
-			pathArg,
-		)
+	var updated string
+	replacements := 1
+	if input.ReplaceAll {
+		updated, replacements, err = replaceAllText(normalizedContent, normalizedOldText, normalizedNewText, pathArg)
+	} else {
+		updated, err = replaceUniqueText(normalizedContent, normalizedOldText, normalizedNewText, pathArg, !e.exactMatch)
 	}
-
-	fuzzyContent := normalizeForFuzzyMatch(normalizedContent)
-	fuzzyOldText := normalizeForFuzzyMatch(normalizedOldText)
-	occurrences := strings.Count(fuzzyContent, fuzzyOldText)
-	if occurrences > 1 {
-		return Result{}, fmt.Errorf(
-			"Found %d occurrences of the text in %s. The text must be unique. Please provide more context to make it unique.",
-			occurrences,
-			pathArg,
-		)
+	if err != nil {
+		return Result{}, err
 	}
-
-	baseContent := match.ContentForReplacement
-	updated := baseContent[:match.Index] + normalizedNewText + baseContent[match.Index+match.MatchLength:]
-	if baseContent == updated {
+	if normalizedContent == updated {
 		return Result{}, fmt.Errorf(
 			"No changes made to %s. The replacement produced identical content. This might indicate an issue with special characters or the text not existing as expected.",
 			pathArg,
@@ -124,15 +123,25 @@ func (e EditTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 		return Result{}, fmt.Errorf("write %s: %w", pathArg, err)
 	}
 
-	diff := generateDiffString(baseContent, updated, 4)
-	details, _ := json.Marshal(map[string]any{"diff": diff})
-	return Result{
-		Content: fmt.Sprintf(
-			"Successfully replaced text in %s. Changed %d characters to %d characters.",
+	diff := generateDiffString(normalizedContent, updated, 4)
+	details, _ := json.Marshal(withUndoSnapshot(map[string]any{"diff": diff, "replacements": replacements}, path, raw, true))
+	summary := fmt.Sprintf(
+		"Successfully replaced text in %s. Changed %d characters to %d characters.",
+		pathArg,
+		len(normalizedOldText),
+		len(normalizedNewText),
+	)
+	if input.ReplaceAll {
+		summary = fmt.Sprintf(
+			"Successfully replaced %d occurrences in %s. Changed %d characters to %d characters each.",
+			replacements,
 			pathArg,
 			len(normalizedOldText),
 			len(normalizedNewText),
-		),
+		)
+	}
+	return Result{
+		Content: summary,
 		Display: DisplayData{
 			Type:    "edit_result",
 			Payload: details,
@@ -140,6 +149,49 @@ func (e EditTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 	}, nil
 }
 
+// replaceUniqueText replaces the single occurrence of oldText in content.
+// When fuzzy is set and no exact match exists, oldText is matched after
+// normalizing quotes, dashes, and trailing whitespace; only the matched
+// region is replaced and the rest of content keeps its original bytes.
+func replaceUniqueText(content, oldText, newText, pathArg string, fuzzy bool) (string, error) {
+	match := fuzzyFindText(content, oldText, fuzzy)
+	if !match.Found {
+		return "", fmt.Errorf(
+			"Could not find the exact text in %s. The old text must match exactly including all whitespace and newlines.",
+			pathArg,
+		)
+	}
+	if match.Occurrences > 1 {
+		qualifier := ""
+		if match.Fuzzy {
+			qualifier = " after normalizing quotes, dashes, and trailing whitespace"
+		}
+		return "", fmt.Errorf(
+			"Found %d occurrences of the text in %s%s. The text must be unique. Please provide more context to make it unique.",
+			match.Occurrences,
+			pathArg,
+			qualifier,
+		)
+	}
+
+	return content[:match.Index] + newText + content[match.Index+match.MatchLength:], nil
+}
+
+// replaceAllText replaces every exact occurrence of oldText in content and
+// returns the result with the number of replacements. Unlike
+// replaceUniqueText it never matches after normalization, so a mass edit only
+// touches text the caller spelled out.
+func replaceAllText(content, oldText, newText, pathArg string) (string, int, error) {
+	count := strings.Count(content, oldText)
+	if count == 0 {
+		return "", 0, fmt.Errorf(
+			"Could not find the exact text in %s. With replaceAll the old text must match exactly including all whitespace and newlines.",
+			pathArg,
+		)
+	}
+	return strings.ReplaceAll(content, oldText, newText), count, nil
+}
+
 type lineDiffPart struct {
 	added   bool
 	removed bool
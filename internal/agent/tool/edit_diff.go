@@ -3,13 +3,19 @@ package tool
 import (
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
+// fuzzyMatchResult locates oldText in the original content. Index and
+// MatchLength are byte offsets into that content even for fuzzy matches.
 type fuzzyMatchResult struct {
-	Found                 bool
-	Index                 int
-	MatchLength           int
-	ContentForReplacement string
+	Found       bool
+	Index       int
+	MatchLength int
+	// Occurrences counts matches of the winning strategy.
+	Occurrences int
+	// Fuzzy reports that only the normalized texts matched.
+	Fuzzy bool
 }
 
 func detectLineEnding(content string) string {
@@ -36,73 +42,129 @@ func restoreLineEndings(text string, ending string) string {
 	return text
 }
 
+// fuzzyReplacements maps typographic quotes, dashes, and spaces to the ASCII
+// characters they are matched as.
+var fuzzyReplacements = map[rune]string{
+	'\u2018': "'",
+	'\u2019': "'",
+	'\u201A': "'",
+	'\u201B': "'",
+	'\u201C': "\"",
+	'\u201D': "\"",
+	'\u201E': "\"",
+	'\u201F': "\"",
+	'\u2010': "-",
+	'\u2011': "-",
+	'\u2012': "-",
+	'\u2013': "-",
+	'\u2014': "-",
+	'\u2015': "-",
+	'\u2212': "-",
+	'\u00A0': " ",
+	'\u2002': " ",
+	'\u2003': " ",
+	'\u2004': " ",
+	'\u2005': " ",
+	'\u2006': " ",
+	'\u2007': " ",
+	'\u2008': " ",
+	'\u2009': " ",
+	'\u200A': " ",
+	'\u202F': " ",
+	'\u205F': " ",
+	'\u3000': " ",
+}
+
+// normalizeForFuzzyMatch trims trailing whitespace from each line and maps
+// typographic quotes, dashes, and spaces to ASCII. text must use LF endings.
 func normalizeForFuzzyMatch(text string) string {
-	lines := strings.Split(text, "\n")
-	for i, line := range lines {
-		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
-	}
-	normalized := strings.Join(lines, "\n")
+	normalized, _ := normalizeWithOffsets(text)
+	return normalized
+}
 
-	replacer := strings.NewReplacer(
-		"\u2018", "'",
-		"\u2019", "'",
-		"\u201A", "'",
-		"\u201B", "'",
-		"\u201C", "\"",
-		"\u201D", "\"",
-		"\u201E", "\"",
-		"\u201F", "\"",
-		"\u2010", "-",
-		"\u2011", "-",
-		"\u2012", "-",
-		"\u2013", "-",
-		"\u2014", "-",
-		"\u2015", "-",
-		"\u2212", "-",
-		"\u00A0", " ",
-		"\u2002", " ",
-		"\u2003", " ",
-		"\u2004", " ",
-		"\u2005", " ",
-		"\u2006", " ",
-		"\u2007", " ",
-		"\u2008", " ",
-		"\u2009", " ",
-		"\u200A", " ",
-		"\u202F", " ",
-		"\u205F", " ",
-		"\u3000", " ",
-	)
-	return replacer.Replace(normalized)
+// normalizeWithOffsets applies normalizeForFuzzyMatch and also returns, for
+// each byte of the result, the offset in text of the byte it came from. The
+// slice has one extra element holding len(text).
+func normalizeWithOffsets(text string) (string, []int) {
+	var b strings.Builder
+	b.Grow(len(text))
+	offsets := make([]int, 0, len(text)+1)
+
+	lineStart := 0
+	for {
+		lineEnd := strings.IndexByte(text[lineStart:], '\n')
+		last := lineEnd < 0
+		if last {
+			lineEnd = len(text)
+		} else {
+			lineEnd += lineStart
+		}
+
+		kept := strings.TrimRightFunc(text[lineStart:lineEnd], unicode.IsSpace)
+		// Decode by hand rather than ranging over kept: an invalid byte
+		// decodes as U+FFFD, whose encoded length is not the byte's.
+		for i := 0; i < len(kept); {
+			r, size := utf8.DecodeRuneInString(kept[i:])
+			offset := lineStart + i
+			i += size
+			if replacement, ok := fuzzyReplacements[r]; ok {
+				b.WriteString(replacement)
+				for range replacement {
+					offsets = append(offsets, offset)
+				}
+				continue
+			}
+			b.WriteString(text[offset : offset+size])
+			for j := 0; j < size; j++ {
+				offsets = append(offsets, offset+j)
+			}
+		}
+		if last {
+			break
+		}
+		b.WriteByte('\n')
+		offsets = append(offsets, lineEnd)
+		lineStart = lineEnd + 1
+	}
+	offsets = append(offsets, len(text))
+	return b.String(), offsets
 }
 
-func fuzzyFindText(content string, oldText string) fuzzyMatchResult {
-	if idx := strings.Index(content, oldText); idx >= 0 {
+// fuzzyFindText finds oldText in content, exactly first and then, when fuzzy
+// is set, after normalizing both with normalizeForFuzzyMatch. A fuzzy match
+// is mapped back to the original bytes so the rest of the content is kept.
+func fuzzyFindText(content, oldText string, fuzzy bool) fuzzyMatchResult {
+	if oldText == "" {
+		return fuzzyMatchResult{Index: -1}
+	}
+	if count := strings.Count(content, oldText); count > 0 {
 		return fuzzyMatchResult{
-			Found:                 true,
-			Index:                 idx,
-			MatchLength:           len(oldText),
-			ContentForReplacement: content,
+			Found:       true,
+			Index:       strings.Index(content, oldText),
+			MatchLength: len(oldText),
+			Occurrences: count,
 		}
 	}
+	if !fuzzy {
+		return fuzzyMatchResult{Index: -1}
+	}
 
-	fuzzyContent := normalizeForFuzzyMatch(content)
+	fuzzyContent, offsets := normalizeWithOffsets(content)
 	fuzzyOldText := normalizeForFuzzyMatch(oldText)
+	if fuzzyOldText == "" {
+		return fuzzyMatchResult{Index: -1}
+	}
 	idx := strings.Index(fuzzyContent, fuzzyOldText)
 	if idx < 0 {
-		return fuzzyMatchResult{
-			Found:                 false,
-			Index:                 -1,
-			MatchLength:           0,
-			ContentForReplacement: content,
-		}
+		return fuzzyMatchResult{Index: -1}
 	}
-
+	start, end := offsets[idx], offsets[idx+len(fuzzyOldText)]
 	return fuzzyMatchResult{
-		Found:                 true,
-		Index:                 idx,
-		MatchLength:           len(fuzzyOldText),
-		ContentForReplacement: fuzzyContent,
+		Found:       true,
+		Index:       start,
+		MatchLength: end - start,
+		Occurrences: strings.Count(fuzzyContent, fuzzyOldText),
+		Fuzzy:       true,
 	}
 }
 
@@ -19,7 +19,7 @@ func TestEditToolReplacesSingleOccurrence(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newEditTool(workspace)
+	tool := NewEditToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"file.txt","oldText":"world","newText":"gar"}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -49,7 +49,7 @@ func TestEditToolFailsWhenOldTextNotFound(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newEditTool(workspace)
+	tool := NewEditToolAt(workspace)
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"file.txt","oldText":"zzz","newText":"x"}`))
 	if err == nil || !strings.Contains(strings.ToLower(err.Error()), "could not find the exact text") {
 		t.Fatalf("Execute() error = %v, want not found error", err)
@@ -65,27 +65,52 @@ func TestEditToolFailsWhenOldTextNotUnique(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newEditTool(workspace)
+	tool := NewEditToolAt(workspace)
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"file.txt","oldText":"x","newText":"z"}`))
 	if err == nil || !strings.Contains(err.Error(), "must be unique") {
 		t.Fatalf("Execute() error = %v, want unique-match error", err)
 	}
 }
 
-func TestEditToolSupportsLegacyOldNewFields(t *testing.T) {
+func TestEditToolReplaceAllReplacesEveryOccurrence(t *testing.T) {
 	t.Parallel()
 
 	workspace := t.TempDir()
-	path := filepath.Join(workspace, "file.txt")
-	if err := os.WriteFile(path, []byte("foo"), 0o644); err != nil {
+	path := filepath.Join(workspace, "file.go")
+	if err := os.WriteFile(path, []byte("oldName()\nkeep\noldName()\nx := oldName\n"), 0o644); err != nil {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newEditTool(workspace)
-	_, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"file.txt","old":"foo","new":"bar"}`))
+	tool := NewEditToolAt(workspace)
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"file.go","oldText":"oldName","newText":"newName","replaceAll":false}`)); err == nil || !strings.Contains(err.Error(), "must be unique") {
+		t.Fatalf("Execute(replaceAll=false) error = %v, want unique-match error", err)
+	}
+
+	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"file.go","oldText":"oldName","newText":"newName","replaceAll":true}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
 	}
+	if !strings.Contains(got.Content, "replaced 3 occurrences in file.go") {
+		t.Fatalf("Execute().Content = %q, want replacement count", got.Content)
+	}
+	var payload struct {
+		Diff         string `json:"diff"`
+		Replacements int    `json:"replacements"`
+	}
+	if err := json.Unmarshal(got.Display.Payload, &payload); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if payload.Replacements != 3 || !strings.Contains(payload.Diff, "-1 oldName()") || !strings.Contains(payload.Diff, "+4 x := newName") {
+		t.Fatalf("payload = %+v, want 3 replacements and a diff covering the first and last", payload)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(raw) != "newName()\nkeep\nnewName()\nx := newName\n" {
+		t.Fatalf("edited content = %q, want every oldName renamed", string(raw))
+	}
 }
 
 func TestEditToolRejectsPathOutsideWorkspace(t *testing.T) {
@@ -97,7 +122,7 @@ func TestEditToolRejectsPathOutsideWorkspace(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newEditTool(workspace)
+	tool := NewEditToolAt(workspace)
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"`+outside+`","oldText":"foo","newText":"bar"}`))
 	if err == nil || !strings.Contains(strings.ToLower(err.Error()), "workspace") {
 		t.Fatalf("Execute() error = %v, want workspace restriction error", err)
@@ -113,7 +138,7 @@ func TestEditToolFuzzyMatchesUnicodeQuotes(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newEditTool(workspace)
+	tool := NewEditToolAt(workspace)
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"fuzzy.txt","oldText":"title: \"hello\"","newText":"title: \"world\""}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -128,6 +153,53 @@ func TestEditToolFuzzyMatchesUnicodeQuotes(t *testing.T) {
 	}
 }
 
+func TestEditToolFuzzyMatchHandlesInvalidUTF8(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "latin-1 byte after match",
+			content: "say \"hi\"\ncaf\xe9",
+			want:    "say \"bye\"\ncaf\xe9",
+		},
+		{
+			name:    "latin-1 byte before match",
+			content: "caf\xe9 say \"hi\" na\xefve",
+			want:    "caf\xe9 say \"bye\" na\xefve",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			workspace := t.TempDir()
+			path := filepath.Join(workspace, "latin1.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			tool := NewEditToolAt(workspace)
+			_, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"latin1.txt","oldText":"say “hi”","newText":"say \"bye\""}`))
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if string(raw) != tt.want {
+				t.Fatalf("edited content = %q, want %q", string(raw), tt.want)
+			}
+		})
+	}
+}
+
 func TestEditToolPreservesBOMAndCRLF(t *testing.T) {
 	t.Parallel()
 
@@ -138,7 +210,7 @@ func TestEditToolPreservesBOMAndCRLF(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newEditTool(workspace)
+	tool := NewEditToolAt(workspace)
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"bom-crlf.txt","oldText":"line2\n","newText":"lineX\n"}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -155,3 +227,101 @@ func TestEditToolPreservesBOMAndCRLF(t *testing.T) {
 		t.Fatalf("edited content = %q, want CRLF-preserved replacement", string(raw))
 	}
 }
+
+func TestEditToolFuzzyMatchNormalizationClasses(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		original string
+		oldText  string
+		newText  string
+		want     string
+	}{
+		{
+			name:     "curly quotes",
+			original: "a := ‘x’\nb := “y”\n",
+			oldText:  "b := \"y\"",
+			newText:  "b := \"z\"",
+			want:     "a := ‘x’\nb := \"z\"\n",
+		},
+		{
+			name:     "dashes",
+			original: "// step 1 — read\n// step 2 – write\n",
+			oldText:  "// step 2 - write",
+			newText:  "// step 2 - save",
+			want:     "// step 1 — read\n// step 2 - save\n",
+		},
+		{
+			name:     "non-breaking spaces",
+			original: "keep\u00a0this\nvalue\u00a0=\u00a01\n",
+			oldText:  "value = 1",
+			newText:  "value = 2",
+			want:     "keep\u00a0this\nvalue = 2\n",
+		},
+		{
+			name:     "trailing whitespace",
+			original: "func a() {  \n\treturn 1\t\n}\nkeep  \n",
+			oldText:  "func a() {\n\treturn 1\n}",
+			newText:  "func a() {\n\treturn 2\n}",
+			want:     "func a() {\n\treturn 2\n}\nkeep  \n",
+		},
+		{
+			name:     "crlf",
+			original: "one\r\ntwo \r\nthree\r\n",
+			oldText:  "two\r\nthree",
+			newText:  "2\nthree",
+			want:     "one\r\n2\r\nthree\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			workspace := t.TempDir()
+			path := filepath.Join(workspace, "file.txt")
+			if err := os.WriteFile(path, []byte(tt.original), 0o644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			params, _ := json.Marshal(map[string]string{"path": "file.txt", "oldText": tt.oldText, "newText": tt.newText})
+			if _, err := NewEditToolAt(workspace).Execute(context.Background(), params); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if string(raw) != tt.want {
+				t.Fatalf("edited content = %q, want %q", raw, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditToolFuzzyMatchRejectsAmbiguousMatches(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "dup.txt"), []byte("say “hi”\nsay \"hi\"  \n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	_, err := NewEditToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"path":"dup.txt","oldText":"say \"hi\"\n","newText":"x\n"}`))
+	if err == nil || !strings.Contains(err.Error(), "Found 2 occurrences") || !strings.Contains(err.Error(), "normalizing") {
+		t.Fatalf("Execute() error = %v, want ambiguous fuzzy match error", err)
+	}
+}
+
+func TestEditToolExactMatchDisablesFuzzyFallback(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "quotes.txt"), []byte("title: “hello”\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	tool := NewEditToolWithOptions(EditOptions{WorkspaceRoot: workspace, ExactMatch: true})
+	_, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"quotes.txt","oldText":"title: \"hello\"","newText":"x"}`))
+	if err == nil || !strings.Contains(err.Error(), "Could not find the exact text") {
+		t.Fatalf("Execute() error = %v, want exact match failure", err)
+	}
+}
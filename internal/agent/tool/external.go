@@ -0,0 +1,129 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultExternalToolTimeout bounds an external tool call when its spec sets no timeout.
+const defaultExternalToolTimeout = 30 * time.Second
+
+// ErrExternalToolCommandRequired is returned for an external tool spec without a command.
+var ErrExternalToolCommandRequired = errors.New("external tool command is required")
+
+// ExternalToolSpec declares a tool implemented by a subprocess.
+type ExternalToolSpec struct {
+	Name        string
+	Description string
+	// Schema is the JSON schema for the tool parameters; empty accepts any object.
+	Schema json.RawMessage
+	// Command is the program and its arguments. It runs in WorkspaceRoot.
+	Command       []string
+	Timeout       time.Duration
+	WorkspaceRoot string
+}
+
+// ExternalTool runs a subprocess per call. The call parameters are written
+// to its stdin as JSON and it must print {"content": "...", "is_error": bool}
+// to stdout.
+type ExternalTool struct {
+	spec ExternalToolSpec
+}
+
+// NewExternalTool validates spec and constructs the tool.
+func NewExternalTool(spec ExternalToolSpec) (ExternalTool, error) {
+	spec.Name = strings.TrimSpace(spec.Name)
+	if spec.Name == "" {
+		return ExternalTool{}, ErrToolNameRequired
+	}
+	if len(spec.Command) == 0 || strings.TrimSpace(spec.Command[0]) == "" {
+		return ExternalTool{}, fmt.Errorf("%w: %s", ErrExternalToolCommandRequired, spec.Name)
+	}
+	if len(bytes.TrimSpace(spec.Schema)) == 0 {
+		spec.Schema = json.RawMessage(`{"type":"object"}`)
+	}
+	if !json.Valid(spec.Schema) {
+		return ExternalTool{}, fmt.Errorf("external tool %s: schema is not valid JSON", spec.Name)
+	}
+	if spec.Timeout <= 0 {
+		spec.Timeout = defaultExternalToolTimeout
+	}
+	spec.Command = append([]string(nil), spec.Command...)
+	return ExternalTool{spec: spec}, nil
+}
+
+func (e ExternalTool) Name() string { return e.spec.Name }
+
+func (e ExternalTool) Description() string { return e.spec.Description }
+
+func (e ExternalTool) Schema() json.RawMessage {
+	return append(json.RawMessage(nil), e.spec.Schema...)
+}
+
+func (e ExternalTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	default:
+	}
+
+	input := bytes.TrimSpace(params)
+	if len(input) == 0 {
+		input = []byte("{}")
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, e.spec.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, e.spec.Command[0], e.spec.Command[1:]...)
+	cmd.Dir = e.spec.WorkspaceRoot
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Children that outlive a killed command would otherwise hold the pipes open.
+	cmd.WaitDelay = time.Second
+
+	runErr := cmd.Run()
+	if ctx.Err() != nil {
+		return Result{}, ctx.Err()
+	}
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return Result{}, fmt.Errorf("external tool %s timed out after %s%s", e.spec.Name, e.spec.Timeout, stderrSuffix(stderr.String()))
+	}
+	if runErr != nil {
+		return Result{}, fmt.Errorf("external tool %s: %v%s", e.spec.Name, runErr, stderrSuffix(stderr.String()))
+	}
+
+	var output struct {
+		Content string `json:"content"`
+		IsError bool   `json:"is_error"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), &output); err != nil {
+		return Result{}, fmt.Errorf("external tool %s: decode stdout: %v%s", e.spec.Name, err, stderrSuffix(stderr.String()))
+	}
+	if output.IsError {
+		return Result{}, fmt.Errorf("external tool %s reported an error: %s", e.spec.Name, output.Content)
+	}
+
+	truncation := truncateHead(output.Content, truncationOptions{MaxLines: defaultMaxLines, MaxBytes: defaultMaxBytes})
+	content := truncation.Content
+	if truncation.Truncated {
+		content += fmt.Sprintf("\n\n[Output truncated to %d lines or %s]", defaultMaxLines, formatSize(defaultMaxBytes))
+	}
+	return Result{Content: content}, nil
+}
+
+func stderrSuffix(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+	return "\nstderr:\n" + truncateTail(stderr, truncationOptions{MaxLines: 50, MaxBytes: 4 * 1024}).Content
+}
@@ -0,0 +1,100 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const externalToolScript = `#!/bin/sh
+input=$(cat)
+case "$input" in
+  *crash*) echo "lint crashed" >&2; exit 3 ;;
+  *slow*) sleep 5 ;;
+  *report*) printf '{"content":"2 issues found","is_error":true}' ;;
+  *) printf '{"content":"checked %s"}' "$(printf '%s' "$input" | wc -c | tr -d ' ')" ;;
+esac
+`
+
+func newScriptTool(t *testing.T, timeout time.Duration) ExternalTool {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("external tool script needs /bin/sh")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "lint.sh")
+	if err := os.WriteFile(script, []byte(externalToolScript), 0o755); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	tool, err := NewExternalTool(ExternalToolSpec{
+		Name:          "lint",
+		Description:   "Run the project linter",
+		Command:       []string{"/bin/sh", script},
+		Timeout:       timeout,
+		WorkspaceRoot: dir,
+	})
+	if err != nil {
+		t.Fatalf("NewExternalTool() error = %v", err)
+	}
+	return tool
+}
+
+func TestExternalToolPassesParamsOnStdin(t *testing.T) {
+	t.Parallel()
+
+	tool := newScriptTool(t, time.Second)
+	params := `{"path":"main.go"}`
+	got, err := tool.Execute(context.Background(), json.RawMessage(params))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := "checked " + strconv.Itoa(len(params)); got.Content != want {
+		t.Fatalf("Execute().Content = %q, want %q", got.Content, want)
+	}
+	if string(tool.Schema()) != `{"type":"object"}` {
+		t.Fatalf("Schema() = %s, want default object schema", tool.Schema())
+	}
+}
+
+func TestExternalToolReportsErrors(t *testing.T) {
+	t.Parallel()
+
+	tool := newScriptTool(t, 200*time.Millisecond)
+
+	_, err := tool.Execute(context.Background(), json.RawMessage(`{"mode":"report"}`))
+	if err == nil || !strings.Contains(err.Error(), "reported an error: 2 issues found") {
+		t.Fatalf("Execute(report) error = %v, want reported error", err)
+	}
+
+	_, err = tool.Execute(context.Background(), json.RawMessage(`{"mode":"crash"}`))
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "lint crashed") {
+		t.Fatalf("Execute(crash) error = %v, want exit status with stderr", err)
+	}
+
+	_, err = tool.Execute(context.Background(), json.RawMessage(`{"mode":"slow"}`))
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Fatalf("Execute(slow) error = %v, want timeout", err)
+	}
+}
+
+func TestNewExternalToolValidatesSpec(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewExternalTool(ExternalToolSpec{Name: "lint"}); !errors.Is(err, ErrExternalToolCommandRequired) {
+		t.Fatalf("NewExternalTool(no command) error = %v, want ErrExternalToolCommandRequired", err)
+	}
+	if _, err := NewExternalTool(ExternalToolSpec{Command: []string{"true"}}); !errors.Is(err, ErrToolNameRequired) {
+		t.Fatalf("NewExternalTool(no name) error = %v, want ErrToolNameRequired", err)
+	}
+	if _, err := NewExternalTool(ExternalToolSpec{Name: "lint", Command: []string{"true"}, Schema: json.RawMessage(`{`)}); err == nil {
+		t.Fatal("NewExternalTool(bad schema) error = nil, want invalid schema error")
+	}
+}
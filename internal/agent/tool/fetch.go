@@ -0,0 +1,247 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	fetchToolName           = "fetch"
+	fetchDisplayTypeKey     = "fetch_result"
+	defaultFetchTimeout     = 30 * time.Second
+	defaultFetchMaxBytes    = 5 * 1024 * 1024
+	defaultFetchMaxRedirect = 5
+)
+
+var (
+	// ErrFetchScheme is returned for URLs that are not http or https.
+	ErrFetchScheme = errors.New("fetch only supports http and https URLs")
+
+	// htmlDropPattern matches elements whose content is never readable text.
+	htmlDropPattern = regexp.MustCompile(`(?is)<(script|style|noscript|head|svg|template)\b.*?</(script|style|noscript|head|svg|template)\s*>|<!--.*?-->`)
+	// htmlBlockPattern matches tags that start a new line of text.
+	htmlBlockPattern = regexp.MustCompile(`(?i)</?(p|div|br|hr|h[1-6]|li|ul|ol|tr|table|pre|blockquote|section|article|header|footer|nav|main|dt|dd)\b[^>]*>`)
+	htmlTagPattern   = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// FetchOptions configures the fetch tool. Zero values use the defaults.
+type FetchOptions struct {
+	// Timeout bounds the whole request, including redirects and the body.
+	Timeout time.Duration
+	// MaxBytes caps how much of the response body is read.
+	MaxBytes int
+	// MaxRedirects caps how many redirects are followed.
+	MaxRedirects int
+	// Client overrides the HTTP client; its CheckRedirect is replaced.
+	Client *http.Client
+}
+
+// FetchTool retrieves a web page over HTTP(S) and returns it as text.
+type FetchTool struct {
+	opts FetchOptions
+}
+
+// NewFetchTool constructs the fetch tool with default limits.
+func NewFetchTool() FetchTool { return NewFetchToolWithOptions(FetchOptions{}) }
+
+// NewFetchToolWithOptions constructs the fetch tool with opts.
+func NewFetchToolWithOptions(opts FetchOptions) FetchTool {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultFetchTimeout
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultFetchMaxBytes
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = defaultFetchMaxRedirect
+	}
+	return FetchTool{opts: opts}
+}
+
+func (FetchTool) Name() string { return fetchToolName }
+
+// Idempotent marks fetch as safe to retry after a failure; it only issues GETs.
+func (FetchTool) Idempotent() bool { return true }
+
+// ReadOnly marks fetch as available in plan mode.
+func (FetchTool) ReadOnly() bool { return true }
+
+func (FetchTool) Description() string {
+	return fmt.Sprintf(
+		"Fetch a URL over http or https and return the response as text. HTML pages are converted to readable text. Use it to read documentation or other pages the user links. Output is truncated to %d lines or %dKB.",
+		defaultMaxLines,
+		defaultMaxBytes/1024,
+	)
+}
+
+func (FetchTool) Schema() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"url":{"type":"string","description":"The http or https URL to fetch"}},"required":["url"]}`)
+}
+
+func (f FetchTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	default:
+	}
+
+	var input struct {
+		URL string `json:"url"`
+	}
+	if err := decodeParams(params, &input); err != nil {
+		return Result{}, fmt.Errorf("decode fetch params: %w", err)
+	}
+	target, err := parseFetchURL(input.URL)
+	if err != nil {
+		return Result{}, err
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, f.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(fetchCtx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("build fetch request: %w", err)
+	}
+	req.Header.Set("Accept", "text/html, text/plain, text/markdown, application/json;q=0.9, */*;q=0.5")
+
+	resp, err := f.client().Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return Result{}, ctx.Err()
+		}
+		if errors.Is(fetchCtx.Err(), context.DeadlineExceeded) {
+			return Result{}, fmt.Errorf("fetch %s timed out after %s", target, f.opts.Timeout)
+		}
+		return Result{}, fmt.Errorf("fetch %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return Result{}, fmt.Errorf("fetch %s: %s", target, resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !isTextMediaType(mediaType) {
+		return Result{}, fmt.Errorf("fetch %s: unsupported content type %q", target, mediaType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(f.opts.MaxBytes)+1))
+	if err != nil {
+		return Result{}, fmt.Errorf("read %s: %w", target, err)
+	}
+	bodyTruncated := len(body) > f.opts.MaxBytes
+	if bodyTruncated {
+		body = body[:f.opts.MaxBytes]
+	}
+
+	text := string(body)
+	if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		text = htmlToText(text)
+	}
+	truncation := truncateHead(text, truncationOptions{MaxLines: defaultMaxLines, MaxBytes: defaultMaxBytes})
+	content := truncation.Content
+	switch {
+	case truncation.Truncated:
+		content += fmt.Sprintf("\n\n[Output truncated to %d lines or %s]", defaultMaxLines, formatSize(defaultMaxBytes))
+	case bodyTruncated:
+		content += fmt.Sprintf("\n\n[Response truncated at %s]", formatSize(f.opts.MaxBytes))
+	}
+
+	finalURL := resp.Request.URL.String()
+	details, _ := json.Marshal(map[string]any{
+		"url":          finalURL,
+		"status":       resp.StatusCode,
+		"content_type": mediaType,
+		"truncated":    truncation.Truncated || bodyTruncated,
+	})
+	return Result{
+		Content: content,
+		Display: DisplayData{
+			Type:    fetchDisplayTypeKey,
+			Payload: details,
+		},
+	}, nil
+}
+
+// client returns the configured HTTP client with redirects bounded and kept
+// on http(s).
+func (f FetchTool) client() *http.Client {
+	client := &http.Client{}
+	if f.opts.Client != nil {
+		copied := *f.opts.Client
+		client = &copied
+	}
+	maxRedirects := f.opts.MaxRedirects
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("%w: redirect to %s", ErrFetchScheme, req.URL)
+		}
+		return nil
+	}
+	return client
+}
+
+func parseFetchURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errors.New("url is required")
+	}
+	target, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %s", ErrFetchScheme, raw)
+	}
+	if target.Host == "" {
+		return nil, fmt.Errorf("url %s has no host", raw)
+	}
+	return target, nil
+}
+
+// isTextMediaType reports whether a response of mediaType can be returned as text.
+// A missing Content-Type is treated as text.
+func isTextMediaType(mediaType string) bool {
+	switch {
+	case mediaType == "", strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return true
+	case mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"):
+		return true
+	case mediaType == "application/javascript", mediaType == "application/x-yaml", mediaType == "application/yaml":
+		return true
+	default:
+		return false
+	}
+}
+
+// htmlToText strips markup from an HTML document, keeping one line per block
+// element and collapsing runs of whitespace and blank lines.
+func htmlToText(document string) string {
+	document = htmlDropPattern.ReplaceAllString(document, "")
+	document = htmlBlockPattern.ReplaceAllString(document, "\n")
+	document = html.UnescapeString(htmlTagPattern.ReplaceAllString(document, ""))
+
+	var lines []string
+	for _, line := range strings.Split(document, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" && (len(lines) == 0 || lines[len(lines)-1] == "") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
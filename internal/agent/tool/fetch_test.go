@@ -0,0 +1,127 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFetchServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<!doctype html><html><head><title>Docs</title><style>body{color:red}</style></head>
+<body><script>alert("x")</script><h1>Getting  started</h1>
+<p>Install with <code>go install</code> &amp; run.</p><ul><li>One</li><li>Two</li></ul></body></html>`)
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "line one\n<not html>\n")
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/plain", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, strings.Repeat("x", 100))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte{0x89, 'P', 'N', 'G'})
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func fetchParams(url string) json.RawMessage {
+	params, _ := json.Marshal(map[string]string{"url": url})
+	return params
+}
+
+func TestFetchToolConvertsHTMLToText(t *testing.T) {
+	t.Parallel()
+
+	server := newFetchServer(t)
+	got, err := NewFetchTool().Execute(context.Background(), fetchParams(server.URL+"/page"))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := "Getting started\n\nInstall with go install & run.\n\nOne\n\nTwo"
+	if got.Content != want {
+		t.Fatalf("Execute().Content = %q, want %q", got.Content, want)
+	}
+	if got.Display.Type != "fetch_result" {
+		t.Fatalf("Execute().Display.Type = %q, want fetch_result", got.Display.Type)
+	}
+}
+
+func TestFetchToolReturnsPlainTextAndFollowsRedirects(t *testing.T) {
+	t.Parallel()
+
+	server := newFetchServer(t)
+	got, err := NewFetchTool().Execute(context.Background(), fetchParams(server.URL+"/moved"))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got.Content != "line one\n<not html>\n" {
+		t.Fatalf("Execute().Content = %q, want the plain body unchanged", got.Content)
+	}
+	var details struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(got.Display.Payload, &details); err != nil || details.URL != server.URL+"/plain" {
+		t.Fatalf("Display.Payload = %s (%v), want the redirected URL", got.Display.Payload, err)
+	}
+
+	if _, err := NewFetchTool().Execute(context.Background(), fetchParams(server.URL+"/loop")); err == nil || !strings.Contains(err.Error(), "redirects") {
+		t.Fatalf("Execute(redirect loop) error = %v, want redirect limit", err)
+	}
+}
+
+func TestFetchToolEnforcesLimits(t *testing.T) {
+	t.Parallel()
+
+	server := newFetchServer(t)
+	tool := NewFetchToolWithOptions(FetchOptions{MaxBytes: 10, Timeout: 100 * time.Millisecond})
+
+	got, err := tool.Execute(context.Background(), fetchParams(server.URL+"/big"))
+	if err != nil {
+		t.Fatalf("Execute(big) error = %v", err)
+	}
+	if !strings.HasPrefix(got.Content, strings.Repeat("x", 10)+"\n\n[Response truncated at 10B]") {
+		t.Fatalf("Execute(big).Content = %q, want body cut at 10 bytes", got.Content)
+	}
+
+	if _, err := tool.Execute(context.Background(), fetchParams(server.URL+"/slow")); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Execute(slow) error = %v, want timeout", err)
+	}
+	if _, err := tool.Execute(context.Background(), fetchParams(server.URL+"/image")); err == nil || !strings.Contains(err.Error(), "unsupported content type") {
+		t.Fatalf("Execute(image) error = %v, want unsupported content type", err)
+	}
+	if _, err := tool.Execute(context.Background(), fetchParams(server.URL+"/missing")); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("Execute(missing) error = %v, want 404", err)
+	}
+	for _, url := range []string{"file:///etc/passwd", "ftp://example.com/x"} {
+		if _, err := tool.Execute(context.Background(), fetchParams(url)); !errors.Is(err, ErrFetchScheme) {
+			t.Fatalf("Execute(%s) error = %v, want ErrFetchScheme", url, err)
+		}
+	}
+}
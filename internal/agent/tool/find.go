@@ -25,24 +25,32 @@ type FindTool struct {
 }
 
 // NewFindTool constructs find tool.
-func NewFindTool() FindTool { return newFindTool("") }
+func NewFindTool() FindTool { return NewFindToolAt("") }
 
-func newFindTool(workspaceRoot string) FindTool {
+// NewFindToolAt constructs the find tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewFindToolAt(workspaceRoot string) FindTool {
 	return FindTool{workspaceRoot: workspaceRoot}
 }
 
 func (FindTool) Name() string { return findToolName }
 
+// Idempotent marks find as safe to retry after a failure.
+func (FindTool) Idempotent() bool { return true }
+
+// ReadOnly marks find as available in plan mode.
+func (FindTool) ReadOnly() bool { return true }
+
 func (FindTool) Description() string {
 	return fmt.Sprintf(
-		"Search for files by glob pattern. Returns matching file paths relative to the search directory. Respects common ignore folders. Output is truncated to %d results or %dKB (whichever is hit first).",
+		"Search for files by glob pattern. Returns matching file paths relative to the search directory. Skips .git, node_modules, and paths excluded by .gitignore unless includeIgnored is true. Output is truncated to %d results or %dKB (whichever is hit first).",
 		defaultFindLimit,
 		defaultMaxBytes/1024,
 	)
 }
 
 func (FindTool) Schema() json.RawMessage {
-	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're searching for (shown to user)"},"pattern":{"type":"string","description":"Glob pattern to match files, e.g. '*.ts', '**/*.json', or 'src/**/*.spec.ts'"},"path":{"type":"string","description":"Directory to search in (default: current directory)"},"limit":{"type":"number","description":"Maximum number of results (default: 1000)"}},"required":["pattern"]}`)
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're searching for (shown to user)"},"pattern":{"type":"string","description":"Glob pattern to match files, e.g. '*.ts', '**/*.json', or 'src/**/*.spec.ts'"},"path":{"type":"string","description":"Directory to search in (default: current directory)"},"limit":{"type":"number","description":"Maximum number of results (default: 1000)"},"includeIgnored":{"type":"boolean","description":"Include paths excluded by .gitignore (default: false)"}},"required":["pattern"]}`)
 }
 
 func (f FindTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
@@ -53,10 +61,11 @@ func (f FindTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 	}
 
 	var input struct {
-		Label   string `json:"label"`
-		Pattern string `json:"pattern"`
-		Path    string `json:"path"`
-		Limit   *int   `json:"limit"`
+		Label          string `json:"label"`
+		Pattern        string `json:"pattern"`
+		Path           string `json:"path"`
+		Limit          *int   `json:"limit"`
+		IncludeIgnored bool   `json:"includeIgnored"`
 	}
 	if err := decodeParams(params, &input); err != nil {
 		return Result{}, fmt.Errorf("decode find params: %w", err)
@@ -94,25 +103,7 @@ func (f FindTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 	}
 
 	results := make([]string, 0, min(effectiveLimit, 128))
-	walkErr := filepath.WalkDir(searchPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		if path == searchPath {
-			return nil
-		}
-
-		name := d.Name()
-		if d.IsDir() && (name == ".git" || name == "node_modules") {
-			return filepath.SkipDir
-		}
-
+	walkErr := walkWorkspace(ctx, searchPath, input.IncludeIgnored, func(path string, d fs.DirEntry) error {
 		rel, err := filepath.Rel(searchPath, path)
 		if err != nil {
 			return err
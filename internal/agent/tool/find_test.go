@@ -26,7 +26,7 @@ func TestFindToolMatchesGlobUnderPath(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newFindTool(workspace)
+	tool := NewFindToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"pattern":"*.go","path":"src"}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -50,7 +50,7 @@ func TestFindToolHonorsLimit(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newFindTool(workspace)
+	tool := NewFindToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"pattern":"*.md","limit":1}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -66,7 +66,7 @@ func TestFindToolRejectsPathOutsideWorkspace(t *testing.T) {
 	workspace := t.TempDir()
 	outside := t.TempDir()
 
-	tool := newFindTool(workspace)
+	tool := NewFindToolAt(workspace)
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{"pattern":"*","path":"`+outside+`"}`))
 	if err == nil || !strings.Contains(strings.ToLower(err.Error()), "workspace") {
 		t.Fatalf("Execute() error = %v, want workspace restriction error", err)
@@ -0,0 +1,295 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	gitToolName       = "git"
+	gitDisplayTypeKey = "git_result"
+	defaultGitLogMax  = 20
+)
+
+// ErrNotGitRepository is returned when the git tool runs outside a repository.
+var ErrNotGitRepository = errors.New("not a git repository")
+
+// GitTool runs read-only git queries (status, diff, log, show) in the workspace.
+type GitTool struct {
+	workspaceRoot string
+}
+
+// NewGitTool constructs git tool.
+func NewGitTool() GitTool { return NewGitToolAt("") }
+
+// NewGitToolAt constructs the git tool running in workspaceRoot.
+// An empty root uses the current working directory.
+func NewGitToolAt(workspaceRoot string) GitTool {
+	return GitTool{workspaceRoot: workspaceRoot}
+}
+
+func (GitTool) Name() string { return gitToolName }
+
+// Idempotent marks git as safe to retry after a failure; every op is read-only.
+func (GitTool) Idempotent() bool { return true }
+
+// ReadOnly marks git as available in plan mode.
+func (GitTool) ReadOnly() bool { return true }
+
+func (GitTool) Description() string {
+	return fmt.Sprintf(
+		"Inspect the workspace git repository. op is one of: status (branch and changed files), diff (unstaged changes, or staged with staged=true, optionally limited to path), log (recent commits, optionally for path), show (a commit's summary and patch, default HEAD). Output is truncated to %d lines or %dKB.",
+		defaultMaxLines,
+		defaultMaxBytes/1024,
+	)
+}
+
+func (GitTool) Schema() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're checking (shown to user)"},"op":{"type":"string","enum":["status","diff","log","show"],"description":"Git operation to run"},"path":{"type":"string","description":"Limit diff or log to this file or directory (optional)"},"staged":{"type":"boolean","description":"For diff: show staged changes instead of unstaged (default: false)"},"ref":{"type":"string","description":"For show: commit to display (default: HEAD)"},"maxCount":{"type":"number","description":"For log: maximum number of commits (default: 20)"}},"required":["op"]}`)
+}
+
+// gitFileStatus is one changed path reported by git status.
+type gitFileStatus struct {
+	Path     string `json:"path"`
+	Index    string `json:"index"`
+	Worktree string `json:"worktree"`
+}
+
+// gitCommit is one entry reported by git log.
+type gitCommit struct {
+	Hash    string `json:"hash"`
+	Date    string `json:"date"`
+	Author  string `json:"author"`
+	Subject string `json:"subject"`
+}
+
+func (g GitTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	default:
+	}
+
+	var input struct {
+		Label    string `json:"label"`
+		Op       string `json:"op"`
+		Path     string `json:"path"`
+		Staged   bool   `json:"staged"`
+		Ref      string `json:"ref"`
+		MaxCount *int   `json:"maxCount"`
+	}
+	if err := decodeParams(params, &input); err != nil {
+		return Result{}, fmt.Errorf("decode git params: %w", err)
+	}
+
+	root, err := normalizeWorkspaceRoot(g.workspaceRoot)
+	if err != nil {
+		return Result{}, err
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return Result{}, errors.New("git is not installed or not on PATH")
+	}
+	if _, err := runGit(ctx, root, "rev-parse", "--is-inside-work-tree"); err != nil {
+		if ctx.Err() != nil {
+			return Result{}, ctx.Err()
+		}
+		return Result{}, fmt.Errorf("%w: %s", ErrNotGitRepository, root)
+	}
+
+	pathspec := ""
+	if strings.TrimSpace(input.Path) != "" {
+		resolved, err := resolveWorkspacePath(root, input.Path, true)
+		if err != nil {
+			return Result{}, fmt.Errorf("resolve git path: %w", err)
+		}
+		if pathspec, err = filepath.Rel(root, resolved); err != nil {
+			return Result{}, fmt.Errorf("resolve git path: %w", err)
+		}
+		pathspec = filepath.ToSlash(pathspec)
+	}
+
+	op := strings.ToLower(strings.TrimSpace(input.Op))
+	payload := map[string]any{"op": op}
+	var output string
+	switch op {
+	case "status":
+		raw, err := runGit(ctx, root, "status", "--porcelain=v1", "--branch", "--untracked-files=all")
+		if err != nil {
+			return Result{}, err
+		}
+		branch, files := parseGitStatus(raw)
+		payload["branch"] = branch
+		payload["files"] = files
+		output = formatGitStatus(branch, files)
+	case "diff":
+		args := []string{"diff", "--no-color"}
+		if input.Staged {
+			args = append(args, "--cached")
+		}
+		if pathspec != "" {
+			args = append(args, "--", pathspec)
+		}
+		if output, err = runGit(ctx, root, args...); err != nil {
+			return Result{}, err
+		}
+		payload["staged"] = input.Staged
+		payload["path"] = pathspec
+		if strings.TrimSpace(output) == "" {
+			output = "No changes."
+		} else {
+			payload["diff"] = output
+		}
+	case "log":
+		maxCount := defaultGitLogMax
+		if input.MaxCount != nil {
+			if *input.MaxCount <= 0 {
+				return Result{}, errors.New("maxCount must be > 0")
+			}
+			maxCount = *input.MaxCount
+		}
+		args := []string{"log", fmt.Sprintf("--max-count=%d", maxCount), "--date=short", "--pretty=format:%h%x1f%ad%x1f%an%x1f%s"}
+		if pathspec != "" {
+			args = append(args, "--", pathspec)
+		}
+		raw, err := runGit(ctx, root, args...)
+		if err != nil {
+			return Result{}, err
+		}
+		commits := parseGitLog(raw)
+		payload["commits"] = commits
+		output = formatGitLog(commits)
+	case "show":
+		ref := strings.TrimSpace(input.Ref)
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if strings.HasPrefix(ref, "-") {
+			return Result{}, fmt.Errorf("invalid ref %q", ref)
+		}
+		if output, err = runGit(ctx, root, "show", "--no-color", "--stat", "--patch", ref, "--"); err != nil {
+			return Result{}, err
+		}
+		payload["ref"] = ref
+		payload["diff"] = output
+	case "":
+		return Result{}, errors.New("op is required")
+	default:
+		return Result{}, fmt.Errorf("unknown git op %q (want status, diff, log, or show)", input.Op)
+	}
+
+	output = strings.TrimRight(output, "\n")
+	truncation := truncateHead(output, truncationOptions{MaxLines: defaultMaxLines, MaxBytes: defaultMaxBytes})
+	if truncation.Truncated {
+		output = truncation.Content + fmt.Sprintf("\n\n[Output truncated to %d lines or %s. Use path to narrow the result]", defaultMaxLines, formatSize(defaultMaxBytes))
+		payload["truncation"] = truncation
+		if _, ok := payload["diff"]; ok {
+			payload["diff"] = truncation.Content
+		}
+	}
+
+	details, _ := json.Marshal(payload)
+	return Result{
+		Content: output,
+		Display: DisplayData{
+			Type:    gitDisplayTypeKey,
+			Payload: details,
+		},
+	}, nil
+}
+
+// runGit runs git in dir and returns stdout, folding stderr into the error.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], message)
+	}
+	return stdout.String(), nil
+}
+
+// parseGitStatus reads `git status --porcelain=v1 --branch` output.
+func parseGitStatus(raw string) (string, []gitFileStatus) {
+	branch := ""
+	files := []gitFileStatus{}
+	for _, line := range strings.Split(raw, "\n") {
+		if strings.HasPrefix(line, "## ") {
+			branch = strings.TrimPrefix(line, "## ")
+			continue
+		}
+		if len(line) < 4 {
+			continue
+		}
+		path := line[3:]
+		// Renames are reported as "old -> new"; the new path is the one that exists.
+		if _, renamed, ok := strings.Cut(path, " -> "); ok {
+			path = renamed
+		}
+		files = append(files, gitFileStatus{
+			Path:     strings.Trim(path, `"`),
+			Index:    strings.TrimSpace(line[0:1]),
+			Worktree: strings.TrimSpace(line[1:2]),
+		})
+	}
+	return branch, files
+}
+
+func formatGitStatus(branch string, files []gitFileStatus) string {
+	var b strings.Builder
+	if branch != "" {
+		b.WriteString("Branch: " + branch + "\n")
+	}
+	if len(files) == 0 {
+		b.WriteString("Working tree clean.")
+		return b.String()
+	}
+	for _, file := range files {
+		code := file.Index + file.Worktree
+		if file.Index == "" {
+			code = " " + file.Worktree
+		} else if file.Worktree == "" {
+			code = file.Index + " "
+		}
+		b.WriteString(code + " " + file.Path + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// parseGitLog reads unit-separator delimited `git log` lines.
+func parseGitLog(raw string) []gitCommit {
+	commits := []gitCommit{}
+	for _, line := range strings.Split(strings.TrimSpace(raw), "\n") {
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		commits = append(commits, gitCommit{Hash: fields[0], Date: fields[1], Author: fields[2], Subject: fields[3]})
+	}
+	return commits
+}
+
+func formatGitLog(commits []gitCommit) string {
+	if len(commits) == 0 {
+		return "No commits."
+	}
+	lines := make([]string, 0, len(commits))
+	for _, commit := range commits {
+		lines = append(lines, fmt.Sprintf("%s %s %s: %s", commit.Hash, commit.Date, commit.Author, commit.Subject))
+	}
+	return strings.Join(lines, "\n")
+}
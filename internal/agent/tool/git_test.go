@@ -0,0 +1,147 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func initGitFixture(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v error = %v\n%s", args, err, out)
+		}
+	}
+
+	git("init", "-q", "-b", "main")
+	if err := os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	git("add", "main.go")
+	git("commit", "-q", "-m", "Initial commit")
+	return repo
+}
+
+func TestGitToolStatusListsChangedFiles(t *testing.T) {
+	t.Parallel()
+
+	repo := initGitFixture(t)
+	if err := os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "new.txt"), []byte("new\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	got, err := NewGitToolAt(repo).Execute(context.Background(), json.RawMessage(`{"op":"status"}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(got.Content, " M main.go") || !strings.Contains(got.Content, "?? new.txt") {
+		t.Fatalf("Execute().Content = %q, want modified and untracked files", got.Content)
+	}
+	if got.Display.Type != "git_result" {
+		t.Fatalf("Execute().Display.Type = %q, want git_result", got.Display.Type)
+	}
+
+	var payload struct {
+		Branch string          `json:"branch"`
+		Files  []gitFileStatus `json:"files"`
+	}
+	if err := json.Unmarshal(got.Display.Payload, &payload); err != nil {
+		t.Fatalf("Unmarshal(payload) error = %v", err)
+	}
+	if !strings.HasPrefix(payload.Branch, "main") || len(payload.Files) != 2 {
+		t.Fatalf("payload = %#v, want branch main and two files", payload)
+	}
+	if payload.Files[0] != (gitFileStatus{Path: "main.go", Worktree: "M"}) {
+		t.Fatalf("payload.Files[0] = %#v, want unstaged main.go modification", payload.Files[0])
+	}
+}
+
+func TestGitToolDiffHonorsStagedAndPath(t *testing.T) {
+	t.Parallel()
+
+	repo := initGitFixture(t)
+	if err := os.WriteFile(filepath.Join(repo, "main.go"), []byte("package app\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	tool := NewGitToolAt(repo)
+
+	unstaged, err := tool.Execute(context.Background(), json.RawMessage(`{"op":"diff","path":"main.go"}`))
+	if err != nil {
+		t.Fatalf("Execute(diff) error = %v", err)
+	}
+	if !strings.Contains(unstaged.Content, "-package main") || !strings.Contains(unstaged.Content, "+package app") {
+		t.Fatalf("Execute(diff).Content = %q, want main.go change", unstaged.Content)
+	}
+
+	staged, err := tool.Execute(context.Background(), json.RawMessage(`{"op":"diff","staged":true}`))
+	if err != nil {
+		t.Fatalf("Execute(staged diff) error = %v", err)
+	}
+	if staged.Content != "No changes." {
+		t.Fatalf("Execute(staged diff).Content = %q, want no staged changes", staged.Content)
+	}
+}
+
+func TestGitToolLogAndShow(t *testing.T) {
+	t.Parallel()
+
+	repo := initGitFixture(t)
+	tool := NewGitToolAt(repo)
+
+	log, err := tool.Execute(context.Background(), json.RawMessage(`{"op":"log","maxCount":5}`))
+	if err != nil {
+		t.Fatalf("Execute(log) error = %v", err)
+	}
+	if !strings.Contains(log.Content, "Test: Initial commit") {
+		t.Fatalf("Execute(log).Content = %q, want initial commit", log.Content)
+	}
+
+	show, err := tool.Execute(context.Background(), json.RawMessage(`{"op":"show"}`))
+	if err != nil {
+		t.Fatalf("Execute(show) error = %v", err)
+	}
+	if !strings.Contains(show.Content, "Initial commit") || !strings.Contains(show.Content, "+package main") {
+		t.Fatalf("Execute(show).Content = %q, want commit message and patch", show.Content)
+	}
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"op":"show","ref":"--output=/tmp/x"}`)); err == nil {
+		t.Fatal("Execute(show) error = nil, want option-like ref rejected")
+	}
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"op":"push"}`)); err == nil {
+		t.Fatal("Execute(push) error = nil, want unknown op error")
+	}
+}
+
+func TestGitToolOutsideRepository(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	_, err := NewGitToolAt(t.TempDir()).Execute(context.Background(), json.RawMessage(`{"op":"status"}`))
+	if !errors.Is(err, ErrNotGitRepository) {
+		t.Fatalf("Execute() error = %v, want ErrNotGitRepository", err)
+	}
+}
@@ -0,0 +1,217 @@
+package tool
+
+import (
+	"bufio"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const gitignoreFileName = ".gitignore"
+
+// gitignoreRule is one pattern line from a .gitignore file.
+type gitignoreRule struct {
+	// base is the directory holding the .gitignore; patterns match paths relative to it.
+	base    string
+	pattern *regexp.Regexp
+	// anchored patterns contain a slash and match the whole relative path;
+	// the rest match the entry name at any depth.
+	anchored bool
+	negate   bool
+	dirOnly  bool
+}
+
+// gitignoreMatcher answers whether paths are excluded by the .gitignore
+// files seen so far. Rules are kept in load order, so deeper files loaded
+// later override their parents, and the last matching rule wins as in git.
+type gitignoreMatcher struct {
+	rules []gitignoreRule
+}
+
+// newGitignoreMatcher loads .gitignore files from root and each ancestor up
+// to the enclosing repository root (the nearest directory containing .git).
+// Without an enclosing repository only root's own .gitignore is used.
+func newGitignoreMatcher(root string) *gitignoreMatcher {
+	dirs := []string{root}
+	for dir := root; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			dirs = dirs[:1]
+			break
+		}
+		dir = parent
+		dirs = append(dirs, dir)
+	}
+
+	m := &gitignoreMatcher{}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		m.loadDir(dirs[i])
+	}
+	return m
+}
+
+// loadDir appends the rules of dir/.gitignore, if present.
+func (m *gitignoreMatcher) loadDir(dir string) {
+	file, err := os.Open(filepath.Join(dir, gitignoreFileName))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseGitignoreLine(dir, scanner.Text()); ok {
+			m.rules = append(m.rules, rule)
+		}
+	}
+}
+
+func parseGitignoreLine(base, line string) (gitignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return gitignoreRule{}, false
+	}
+
+	rule := gitignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return gitignoreRule{}, false
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	pattern, err := compileGitignorePattern(line)
+	if err != nil {
+		return gitignoreRule{}, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+// compileGitignorePattern translates gitignore glob syntax, including "**"
+// segments and bracket classes, into an anchored regular expression.
+func compileGitignorePattern(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "/**") && i+3 == len(pattern):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case ch == '*':
+			b.WriteString("[^/]*")
+		case ch == '?':
+			b.WriteString("[^/]")
+		case ch == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case ch == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// Ignored reports whether path is excluded by the loaded rules.
+func (m *gitignoreMatcher) Ignored(path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(rule.base, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		subject := rel
+		if !rule.anchored {
+			subject = filepath.Base(path)
+		}
+		if rule.pattern.MatchString(subject) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// walkWorkspace walks root like filepath.WalkDir, calling fn for every entry
+// below root. It always skips .git and node_modules, and unless
+// includeIgnored is set it also skips paths excluded by .gitignore files.
+// fn may return filepath.SkipDir to prune a directory.
+func walkWorkspace(ctx context.Context, root string, includeIgnored bool, fn func(path string, d fs.DirEntry) error) error {
+	var ignore *gitignoreMatcher
+	if !includeIgnored {
+		ignore = newGitignoreMatcher(root)
+	}
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if path == root {
+			return nil
+		}
+		if d.IsDir() && (d.Name() == ".git" || d.Name() == "node_modules") {
+			return filepath.SkipDir
+		}
+		if ignore != nil {
+			if ignore.Ignored(path, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				ignore.loadDir(path)
+			}
+		}
+		return fn(path, d)
+	})
+}
@@ -0,0 +1,118 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeIgnoredRepo(t *testing.T) string {
+	t.Helper()
+
+	workspace := t.TempDir()
+	files := map[string]string{
+		".gitignore":              "# build output\ndist/\n*.log\n!keep.log\n",
+		"main.go":                 "package main // needle",
+		"debug.log":               "needle",
+		"keep.log":                "needle",
+		"dist/bundle.js":          "needle",
+		"src/app.go":              "package src // needle",
+		"src/.gitignore":          "/generated.go\n",
+		"src/generated.go":        "package src // needle",
+		"src/nested/generated.go": "package nested // needle",
+	}
+	for name, content := range files {
+		path := filepath.Join(workspace, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	return workspace
+}
+
+func TestGitignoreMatcherRules(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	content := "build/\n*.tmp\n!important.tmp\n/top.txt\ndocs/**/draft.md\n\\#hash\n"
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	matcher := newGitignoreMatcher(root)
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "build", isDir: true, want: true},
+		{path: "src/build", isDir: true, want: true},
+		{path: "build", isDir: false, want: false},
+		{path: "a/b/c.tmp", want: true},
+		{path: "important.tmp", want: false},
+		{path: "top.txt", want: true},
+		{path: "sub/top.txt", want: false},
+		{path: "docs/draft.md", want: true},
+		{path: "docs/a/b/draft.md", want: true},
+		{path: "notes/draft.md", want: false},
+		{path: "#hash", want: true},
+		{path: "main.go", want: false},
+	}
+	for _, tc := range tests {
+		if got := matcher.Ignored(filepath.Join(root, tc.path), tc.isDir); got != tc.want {
+			t.Fatalf("Ignored(%q, dir=%v) = %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+}
+
+func TestWalkersRespectGitignore(t *testing.T) {
+	t.Parallel()
+
+	workspace := writeIgnoredRepo(t)
+	ignored := []string{"debug.log", "dist", "src/generated.go"}
+
+	find, err := NewFindToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"pattern":"**"}`))
+	if err != nil {
+		t.Fatalf("find Execute() error = %v", err)
+	}
+	grep, err := NewGrepToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"pattern":"needle"}`))
+	if err != nil {
+		t.Fatalf("grep Execute() error = %v", err)
+	}
+	tree, err := NewTreeToolAt(workspace).Execute(context.Background(), json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("tree Execute() error = %v", err)
+	}
+
+	for name, output := range map[string]string{"find": find.Content, "grep": grep.Content} {
+		for _, path := range ignored {
+			if strings.Contains(output, path) {
+				t.Fatalf("%s output = %q, should not include ignored %s", name, output, path)
+			}
+		}
+		for _, path := range []string{"main.go", "keep.log", "src/app.go", "src/nested/generated.go"} {
+			if !strings.Contains(output, path) {
+				t.Fatalf("%s output = %q, want %s", name, output, path)
+			}
+		}
+	}
+	if strings.Contains(tree.Content, "dist/") || strings.Contains(tree.Content, "debug.log") || !strings.Contains(tree.Content, "keep.log") {
+		t.Fatalf("tree output = %q, want gitignore applied", tree.Content)
+	}
+
+	all, err := NewFindToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"pattern":"**","includeIgnored":true}`))
+	if err != nil {
+		t.Fatalf("find Execute(includeIgnored) error = %v", err)
+	}
+	for _, path := range ignored {
+		if !strings.Contains(all.Content, path) {
+			t.Fatalf("find includeIgnored output = %q, want %s", all.Content, path)
+		}
+	}
+}
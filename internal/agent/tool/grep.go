@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -18,9 +19,14 @@ const (
 	grepDisplayTypeKey = "grep_result"
 )
 
+// grepMatch spans lines Line through EndLine of File; they differ only for
+// multiline matches. Preview holds the replaced lines when previewReplace is
+// set.
 type grepMatch struct {
-	File string
-	Line int
+	File    string
+	Line    int
+	EndLine int
+	Preview []string
 }
 
 // GrepTool searches file content by pattern.
@@ -29,17 +35,25 @@ type GrepTool struct {
 }
 
 // NewGrepTool constructs grep tool.
-func NewGrepTool() GrepTool { return newGrepTool("") }
+func NewGrepTool() GrepTool { return NewGrepToolAt("") }
 
-func newGrepTool(workspaceRoot string) GrepTool {
+// NewGrepToolAt constructs the grep tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewGrepToolAt(workspaceRoot string) GrepTool {
 	return GrepTool{workspaceRoot: workspaceRoot}
 }
 
 func (GrepTool) Name() string { return grepToolName }
 
+// Idempotent marks grep as safe to retry after a failure.
+func (GrepTool) Idempotent() bool { return true }
+
+// ReadOnly marks grep as available in plan mode.
+func (GrepTool) ReadOnly() bool { return true }
+
 func (GrepTool) Description() string {
 	return fmt.Sprintf(
-		"Search file contents for a pattern. Returns matching lines with file paths and line numbers. Output is truncated to %d matches or %dKB (whichever is hit first). Long lines are truncated to %d chars.",
+		"Search file contents for a pattern. Returns matching lines with file paths and line numbers. With multiline, the pattern runs against whole files with . matching newlines, and each match is reported from its start line. With previewReplace, each match is followed by the lines a regex replacement would produce (path:line> text, $1 expands groups); nothing is written. Skips .git, node_modules, and paths excluded by .gitignore unless includeIgnored is true. Output is truncated to %d matches or %dKB (whichever is hit first). Long lines are truncated to %d chars.",
 		defaultGrepLimit,
 		defaultMaxBytes/1024,
 		grepMaxLineLen,
@@ -47,7 +61,7 @@ func (GrepTool) Description() string {
 }
 
 func (GrepTool) Schema() json.RawMessage {
-	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're searching for (shown to user)"},"pattern":{"type":"string","description":"Search pattern (regex or literal string)"},"path":{"type":"string","description":"Directory or file to search (default: current directory)"},"glob":{"type":"string","description":"Filter files by glob pattern, e.g. '*.ts' or '**/*.spec.ts'"},"ignoreCase":{"type":"boolean","description":"Case-insensitive search (default: false)"},"literal":{"type":"boolean","description":"Treat pattern as literal string instead of regex (default: false)"},"context":{"type":"number","description":"Number of lines to show before and after each match (default: 0)"},"limit":{"type":"number","description":"Maximum number of matches to return (default: 100)"}},"required":["pattern"]}`)
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're searching for (shown to user)"},"pattern":{"type":"string","description":"Search pattern (regex or literal string)"},"path":{"type":"string","description":"Directory or file to search (default: current directory)"},"glob":{"type":"string","description":"Filter files by glob pattern, e.g. '*.ts' or '**/*.spec.ts'"},"ignoreCase":{"type":"boolean","description":"Case-insensitive search (default: false)"},"literal":{"type":"boolean","description":"Treat pattern as literal string instead of regex (default: false)"},"context":{"type":"number","description":"Number of lines to show before and after each match (default: 0)"},"limit":{"type":"number","description":"Maximum number of matches to return (default: 100)"},"includeIgnored":{"type":"boolean","description":"Include paths excluded by .gitignore (default: false)"},"multiline":{"type":"boolean","description":"Match the pattern against whole files so it can span lines; . also matches newlines (default: false)"},"previewReplace":{"type":"string","description":"Show what replacing each match with this text would produce, without writing. Supports $1 and ${name} group references"}},"required":["pattern"]}`)
 }
 
 func (g GrepTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
@@ -58,14 +72,17 @@ func (g GrepTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 	}
 
 	var input struct {
-		Label      string `json:"label"`
-		Pattern    string `json:"pattern"`
-		Path       string `json:"path"`
-		Glob       string `json:"glob"`
-		IgnoreCase bool   `json:"ignoreCase"`
-		Literal    bool   `json:"literal"`
-		Context    *int   `json:"context"`
-		Limit      *int   `json:"limit"`
+		Label          string  `json:"label"`
+		Pattern        string  `json:"pattern"`
+		Path           string  `json:"path"`
+		Glob           string  `json:"glob"`
+		IgnoreCase     bool    `json:"ignoreCase"`
+		Literal        bool    `json:"literal"`
+		Context        *int    `json:"context"`
+		Limit          *int    `json:"limit"`
+		IncludeIgnored bool    `json:"includeIgnored"`
+		Multiline      bool    `json:"multiline"`
+		PreviewReplace *string `json:"previewReplace"`
 	}
 	if err := decodeParams(params, &input); err != nil {
 		return Result{}, fmt.Errorf("decode grep params: %w", err)
@@ -115,13 +132,16 @@ func (g GrepTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 	if input.IgnoreCase {
 		patternExpr = "(?i)" + patternExpr
 	}
+	if input.Multiline {
+		patternExpr = "(?s)" + patternExpr
+	}
 
 	re, err := regexp.Compile(patternExpr)
 	if err != nil {
 		return Result{}, fmt.Errorf("invalid pattern: %w", err)
 	}
 
-	files, err := collectGrepFiles(ctx, searchPath, searchIsDir)
+	files, err := collectGrepFiles(ctx, searchPath, searchIsDir, input.IncludeIgnored)
 	if err != nil {
 		return Result{}, err
 	}
@@ -152,18 +172,32 @@ matchLoop:
 		if readErr != nil {
 			continue
 		}
-		lines := strings.Split(normalizeToLF(string(raw)), "\n")
+		text := normalizeToLF(string(raw))
+		lines := strings.Split(text, "\n")
 		fileLines[file] = lines
 
-		for idx, line := range lines {
-			if !re.MatchString(line) {
-				continue
-			}
-			matches = append(matches, grepMatch{File: file, Line: idx + 1})
-			if len(matches) >= effectiveLimit {
-				break matchLoop
+		var found []grepMatch
+		if input.Multiline {
+			found = grepMultiline(re, file, text, lines, input.PreviewReplace, effectiveLimit-len(matches))
+		} else {
+			for idx, line := range lines {
+				if !re.MatchString(line) {
+					continue
+				}
+				match := grepMatch{File: file, Line: idx + 1, EndLine: idx + 1}
+				if input.PreviewReplace != nil {
+					match.Preview = []string{re.ReplaceAllString(line, *input.PreviewReplace)}
+				}
+				found = append(found, match)
+				if len(matches)+len(found) >= effectiveLimit {
+					break
+				}
 			}
 		}
+		matches = append(matches, found...)
+		if len(matches) >= effectiveLimit {
+			break matchLoop
+		}
 	}
 
 	if len(matches) == 0 {
@@ -189,10 +223,10 @@ matchLoop:
 		}
 
 		start := match.Line
-		end := match.Line
+		end := match.EndLine
 		if contextLines > 0 {
 			start = max(1, match.Line-contextLines)
-			end = min(len(lines), match.Line+contextLines)
+			end = min(len(lines), match.EndLine+contextLines)
 		}
 
 		for lineNumber := start; lineNumber <= end; lineNumber++ {
@@ -202,11 +236,21 @@ matchLoop:
 				linesTruncated = true
 			}
 
-			if lineNumber == match.Line {
+			if lineNumber >= match.Line && lineNumber <= match.EndLine {
 				linesOut = append(linesOut, fmt.Sprintf("%s:%d: %s", pathDisplay, lineNumber, trimmed))
 			} else {
 				linesOut = append(linesOut, fmt.Sprintf("%s-%d- %s", pathDisplay, lineNumber, trimmed))
 			}
+			if lineNumber != match.EndLine {
+				continue
+			}
+			for offset, replaced := range match.Preview {
+				trimmed, wasTruncated := truncateLine(replaced, grepMaxLineLen)
+				if wasTruncated {
+					linesTruncated = true
+				}
+				linesOut = append(linesOut, fmt.Sprintf("%s:%d> %s", pathDisplay, match.Line+offset, trimmed))
+			}
 		}
 	}
 
@@ -243,32 +287,54 @@ matchLoop:
 	}, nil
 }
 
-func collectGrepFiles(ctx context.Context, searchPath string, searchIsDir bool) ([]string, error) {
-	if !searchIsDir {
-		return []string{searchPath}, nil
+// grepMultiline finds up to limit matches of re in text, the whole content
+// of file split into lines. A match starting on a line already covered by
+// the previous one is skipped so each line is reported once.
+func grepMultiline(re *regexp.Regexp, file, text string, lines []string, replacement *string, limit int) []grepMatch {
+	lineStarts := make([]int, len(lines))
+	offset := 0
+	for i, line := range lines {
+		lineStarts[i] = offset
+		offset += len(line) + 1
+	}
+	lineAt := func(pos int) int {
+		return sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > pos })
 	}
 
-	files := make([]string, 0, 256)
-	walkErr := filepath.WalkDir(searchPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+	var found []grepMatch
+	for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+		first := lineAt(loc[0])
+		last := first
+		if loc[1] > loc[0] {
+			last = lineAt(loc[1] - 1)
 		}
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+		if len(found) > 0 && first <= found[len(found)-1].EndLine {
+			continue
 		}
-
-		if path == searchPath {
-			return nil
+		match := grepMatch{File: file, Line: first, EndLine: last}
+		if replacement != nil {
+			lineEnd := lineStarts[last-1] + len(lines[last-1])
+			replaced := text[lineStarts[first-1]:loc[0]] + string(re.ExpandString(nil, *replacement, text, loc)) + text[loc[1]:max(lineEnd, loc[1])]
+			match.Preview = strings.Split(replaced, "\n")
 		}
-		if d.IsDir() {
-			if d.Name() == ".git" || d.Name() == "node_modules" {
-				return filepath.SkipDir
-			}
-			return nil
+		found = append(found, match)
+		if len(found) >= limit {
+			break
+		}
+	}
+	return found
+}
+
+func collectGrepFiles(ctx context.Context, searchPath string, searchIsDir bool, includeIgnored bool) ([]string, error) {
+	if !searchIsDir {
+		return []string{searchPath}, nil
+	}
+
+	files := make([]string, 0, 256)
+	walkErr := walkWorkspace(ctx, searchPath, includeIgnored, func(path string, d fs.DirEntry) error {
+		if !d.IsDir() {
+			files = append(files, path)
 		}
-		files = append(files, path)
 		return nil
 	})
 	if walkErr != nil {
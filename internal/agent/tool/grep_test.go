@@ -24,7 +24,7 @@ func TestGrepToolFindsMatchesWithContext(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newGrepTool(workspace)
+	tool := NewGrepToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"pattern":"error","path":".","literal":true,"context":1}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -47,7 +47,7 @@ func TestGrepToolHonorsLimit(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newGrepTool(workspace)
+	tool := NewGrepToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"pattern":"error","path":".","literal":true,"limit":1}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -65,7 +65,7 @@ func TestGrepToolSupportsIgnoreCase(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newGrepTool(workspace)
+	tool := NewGrepToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"pattern":"error","ignoreCase":true}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -81,9 +81,88 @@ func TestGrepToolRejectsPathOutsideWorkspace(t *testing.T) {
 	workspace := t.TempDir()
 	outside := t.TempDir()
 
-	tool := newGrepTool(workspace)
+	tool := NewGrepToolAt(workspace)
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{"pattern":"x","path":"`+outside+`"}`))
 	if err == nil || !strings.Contains(strings.ToLower(err.Error()), "workspace") {
 		t.Fatalf("Execute() error = %v, want workspace restriction error", err)
 	}
 }
+
+func TestGrepToolMultilineMatchesAcrossLines(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	content := strings.Join([]string{
+		"package main",
+		"",
+		"func handle(",
+		"\tctx context.Context,",
+		") error {",
+		"\treturn nil",
+		"}",
+	}, "\n")
+	if err := os.WriteFile(filepath.Join(workspace, "main.go"), []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tool := NewGrepToolAt(workspace)
+	got, err := tool.Execute(context.Background(), json.RawMessage(`{"pattern":"func handle\\(\\s*ctx","path":"."}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got.Content != "No matches found" {
+		t.Fatalf("Execute().Content = %q, want no per-line match", got.Content)
+	}
+
+	got, err = tool.Execute(context.Background(), json.RawMessage(`{"pattern":"func handle\\(.*?\\) error","path":".","multiline":true}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := "main.go:3: func handle(\nmain.go:4: \tctx context.Context,\nmain.go:5: ) error {"
+	if got.Content != want {
+		t.Fatalf("Execute().Content = %q, want %q", got.Content, want)
+	}
+}
+
+func TestGrepToolPreviewsReplacementWithoutWriting(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "app.go")
+	content := "x := oldName(1)\ny := 2\nz := oldName(3) + oldName(4)\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tool := NewGrepToolAt(workspace)
+	got, err := tool.Execute(context.Background(), json.RawMessage(`{"pattern":"oldName\\((\\d)\\)","path":".","previewReplace":"newName(ctx, $1)"}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := strings.Join([]string{
+		"app.go:1: x := oldName(1)",
+		"app.go:1> x := newName(ctx, 1)",
+		"app.go:3: z := oldName(3) + oldName(4)",
+		"app.go:3> z := newName(ctx, 3) + newName(ctx, 4)",
+	}, "\n")
+	if got.Content != want {
+		t.Fatalf("Execute().Content = %q, want %q", got.Content, want)
+	}
+
+	got, err = tool.Execute(context.Background(), json.RawMessage(`{"pattern":"oldName\\(1\\)\\ny","path":".","multiline":true,"previewReplace":"call()\nw"}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want = "app.go:1: x := oldName(1)\napp.go:2: y := 2\napp.go:1> x := call()\napp.go:2> w := 2"
+	if got.Content != want {
+		t.Fatalf("Execute(multiline).Content = %q, want %q", got.Content, want)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(raw) != content {
+		t.Fatalf("file content = %q, want unchanged", string(raw))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 )
@@ -14,33 +15,48 @@ const (
 	lsToolName       = "ls"
 	defaultLsLimit   = 500
 	maxIntValue      = int(^uint(0) >> 1)
-	lsDisplayTypeKey = "ls_result"
+	lsDisplayTypeKey = "list_result"
+	defaultLsDepth   = 1
 )
 
+// lsEntry is one listed path relative to the requested directory.
+type lsEntry struct {
+	Path string `json:"path"`
+	Dir  bool   `json:"dir"`
+}
+
 // LsTool lists directory contents.
 type LsTool struct {
 	workspaceRoot string
 }
 
 // NewLsTool constructs ls tool.
-func NewLsTool() LsTool { return newLsTool("") }
+func NewLsTool() LsTool { return NewLsToolAt("") }
 
-func newLsTool(workspaceRoot string) LsTool {
+// NewLsToolAt constructs the ls tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewLsToolAt(workspaceRoot string) LsTool {
 	return LsTool{workspaceRoot: workspaceRoot}
 }
 
 func (LsTool) Name() string { return lsToolName }
 
+// Idempotent marks ls as safe to retry after a failure.
+func (LsTool) Idempotent() bool { return true }
+
+// ReadOnly marks ls as available in plan mode.
+func (LsTool) ReadOnly() bool { return true }
+
 func (LsTool) Description() string {
 	return fmt.Sprintf(
-		"List directory contents. Returns entries sorted alphabetically, with '/' suffix for directories. Includes dotfiles. Output is truncated to %d entries or %dKB (whichever is hit first).",
+		"List directory contents. Returns relative paths sorted alphabetically as a tree, with '/' suffix for directories. Use depth to recurse into subdirectories (.git and node_modules are never expanded). Includes dotfiles unless showHidden is false. Output is truncated to %d entries or %dKB (whichever is hit first).",
 		defaultLsLimit,
 		defaultMaxBytes/1024,
 	)
 }
 
 func (LsTool) Schema() json.RawMessage {
-	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're listing (shown to user)"},"path":{"type":"string","description":"Directory to list (default: current directory)"},"limit":{"type":"number","description":"Maximum number of entries to return (default: 500)"}}}`)
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're listing (shown to user)"},"path":{"type":"string","description":"Directory to list (default: current directory)"},"depth":{"type":"number","description":"How many directory levels to list (default: 1)"},"showHidden":{"type":"boolean","description":"Include dotfiles and dot-directories (default: true)"},"limit":{"type":"number","description":"Maximum number of entries to return (default: 500)"}}}`)
 }
 
 func (l LsTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
@@ -51,9 +67,11 @@ func (l LsTool) Execute(ctx context.Context, params json.RawMessage) (Result, er
 	}
 
 	var input struct {
-		Label string `json:"label"`
-		Path  string `json:"path"`
-		Limit *int   `json:"limit"`
+		Label      string `json:"label"`
+		Path       string `json:"path"`
+		Depth      *int   `json:"depth"`
+		ShowHidden *bool  `json:"showHidden"`
+		Limit      *int   `json:"limit"`
 	}
 	if err := decodeParams(params, &input); err != nil {
 		return Result{}, fmt.Errorf("decode ls params: %w", err)
@@ -71,6 +89,14 @@ func (l LsTool) Execute(ctx context.Context, params json.RawMessage) (Result, er
 		}
 		effectiveLimit = *input.Limit
 	}
+	depth := defaultLsDepth
+	if input.Depth != nil {
+		if *input.Depth <= 0 {
+			return Result{}, errors.New("depth must be > 0")
+		}
+		depth = *input.Depth
+	}
+	showHidden := input.ShowHidden == nil || *input.ShowHidden
 
 	dirPath, err := resolveWorkspacePath(l.workspaceRoot, pathArg, false)
 	if err != nil {
@@ -85,23 +111,16 @@ func (l LsTool) Execute(ctx context.Context, params json.RawMessage) (Result, er
 		return Result{}, fmt.Errorf("not a directory: %s", pathArg)
 	}
 
-	entries, err := os.ReadDir(dirPath)
+	entries := make([]lsEntry, 0, 64)
+	entryLimitReached, err := collectLsEntries(ctx, dirPath, "", depth, showHidden, effectiveLimit, &entries)
 	if err != nil {
-		return Result{}, fmt.Errorf("cannot read directory: %w", err)
+		return Result{}, err
 	}
-	sort.Slice(entries, func(i, j int) bool {
-		return strings.ToLower(entries[i].Name()) < strings.ToLower(entries[j].Name())
-	})
 
-	results := make([]string, 0, min(len(entries), effectiveLimit))
-	entryLimitReached := false
+	results := make([]string, 0, len(entries))
 	for _, entry := range entries {
-		if len(results) >= effectiveLimit {
-			entryLimitReached = true
-			break
-		}
-		name := entry.Name()
-		if entry.IsDir() {
+		name := entry.Path
+		if entry.Dir {
 			name += "/"
 		}
 		results = append(results, name)
@@ -118,7 +137,7 @@ func (l LsTool) Execute(ctx context.Context, params json.RawMessage) (Result, er
 	truncation := truncateHead(rawOutput, truncationOptions{MaxLines: maxIntValue, MaxBytes: defaultMaxBytes})
 
 	output := truncation.Content
-	detailsPayload := map[string]any{}
+	detailsPayload := map[string]any{"entries": entries}
 	notices := make([]string, 0, 2)
 
 	if entryLimitReached {
@@ -142,3 +161,52 @@ func (l LsTool) Execute(ctx context.Context, params json.RawMessage) (Result, er
 		},
 	}, nil
 }
+
+// collectLsEntries appends a depth-first, alphabetically sorted listing of dir
+// into out and reports whether the entry limit cut the listing short.
+func collectLsEntries(
+	ctx context.Context,
+	dir string,
+	prefix string,
+	depth int,
+	showHidden bool,
+	limit int,
+	out *[]lsEntry,
+) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, fmt.Errorf("cannot read directory: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Name()) < strings.ToLower(entries[j].Name())
+	})
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !showHidden && strings.HasPrefix(name, ".") {
+			continue
+		}
+		if len(*out) >= limit {
+			return true, nil
+		}
+
+		relative := name
+		if prefix != "" {
+			relative = prefix + "/" + name
+		}
+		*out = append(*out, lsEntry{Path: relative, Dir: entry.IsDir()})
+
+		if !entry.IsDir() || depth <= 1 || name == ".git" || name == "node_modules" {
+			continue
+		}
+		limitReached, err := collectLsEntries(ctx, filepath.Join(dir, name), relative, depth-1, showHidden, limit, out)
+		if err != nil || limitReached {
+			return limitReached, err
+		}
+	}
+	return false, nil
+}
@@ -23,7 +23,7 @@ func TestLsToolListsDirectorySortedWithSuffix(t *testing.T) {
 		t.Fatalf("MkdirAll() error = %v", err)
 	}
 
-	tool := newLsTool(workspace)
+	tool := NewLsToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"."}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -49,7 +49,7 @@ func TestLsToolHonorsLimit(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	tool := newLsTool(workspace)
+	tool := NewLsToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":".","limit":1}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -65,9 +65,63 @@ func TestLsToolRejectsPathOutsideWorkspace(t *testing.T) {
 	workspace := t.TempDir()
 	outside := t.TempDir()
 
-	tool := newLsTool(workspace)
+	tool := NewLsToolAt(workspace)
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"`+outside+`"}`))
 	if err == nil || !strings.Contains(strings.ToLower(err.Error()), "workspace") {
 		t.Fatalf("Execute() error = %v, want workspace restriction error", err)
 	}
 }
+
+func TestLsToolRecursesWithDepthAndSkipsHidden(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	for _, dir := range []string{"src/pkg", ".git/objects", "node_modules/lib"} {
+		if err := os.MkdirAll(filepath.Join(workspace, dir), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+	}
+	for _, file := range []string{"README.md", ".env", "src/main.go", "src/pkg/util.go", "node_modules/lib/index.js"} {
+		if err := os.WriteFile(filepath.Join(workspace, file), []byte("x"), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	tool := NewLsToolAt(workspace)
+	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":".","depth":3}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := ".env\n.git/\nnode_modules/\nREADME.md\nsrc/\nsrc/main.go\nsrc/pkg/\nsrc/pkg/util.go"
+	if got.Content != want {
+		t.Fatalf("Execute().Content = %q, want %q", got.Content, want)
+	}
+	if got.Display.Type != "list_result" {
+		t.Fatalf("Execute().Display.Type = %q, want list_result", got.Display.Type)
+	}
+	var payload struct {
+		Entries []lsEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(got.Display.Payload, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if len(payload.Entries) != 8 || payload.Entries[4] != (lsEntry{Path: "src", Dir: true}) {
+		t.Fatalf("payload entries = %#v, want structured tree entries", payload.Entries)
+	}
+
+	got, err = tool.Execute(context.Background(), json.RawMessage(`{"path":"src","showHidden":false}`))
+	if err != nil {
+		t.Fatalf("Execute(showHidden=false) error = %v", err)
+	}
+	if got.Content != "main.go\npkg/" {
+		t.Fatalf("Execute(src).Content = %q, want depth-1 listing", got.Content)
+	}
+
+	got, err = tool.Execute(context.Background(), json.RawMessage(`{"showHidden":false}`))
+	if err != nil {
+		t.Fatalf("Execute(showHidden=false) error = %v", err)
+	}
+	if strings.Contains(got.Content, ".env") || strings.Contains(got.Content, ".git/") {
+		t.Fatalf("Execute().Content = %q, want hidden entries omitted", got.Content)
+	}
+}
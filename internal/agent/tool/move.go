@@ -0,0 +1,125 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const moveToolName = "move"
+
+// MoveTool renames or moves a file or directory within the workspace.
+type MoveTool struct {
+	workspaceRoot string
+}
+
+// NewMoveTool constructs the move tool.
+func NewMoveTool() MoveTool { return NewMoveToolAt("") }
+
+// NewMoveToolAt constructs the move tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewMoveToolAt(workspaceRoot string) MoveTool {
+	return MoveTool{workspaceRoot: workspaceRoot}
+}
+
+func (MoveTool) Name() string { return moveToolName }
+
+func (MoveTool) Description() string {
+	return "Rename or move a file or directory within the workspace. Creates missing parent directories of the destination. Fails if the destination exists unless overwrite is true, which only replaces files."
+}
+
+func (MoveTool) Schema() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're moving (shown to user)"},"source":{"type":"string","description":"Path of the file or directory to move (relative or absolute)"},"destination":{"type":"string","description":"New path (relative or absolute)"},"overwrite":{"type":"boolean","description":"Replace an existing destination file (default: false)"}},"required":["source","destination"]}`)
+}
+
+func (m MoveTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	default:
+	}
+
+	var input struct {
+		Label       string `json:"label"`
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+		Overwrite   bool   `json:"overwrite"`
+	}
+	if err := decodeParams(params, &input); err != nil {
+		return Result{}, fmt.Errorf("decode move params: %w", err)
+	}
+
+	sourceArg := strings.TrimSpace(input.Source)
+	destArg := strings.TrimSpace(input.Destination)
+	if sourceArg == "" {
+		return Result{}, errors.New("source is required")
+	}
+	if destArg == "" {
+		return Result{}, errors.New("destination is required")
+	}
+
+	root, source, err := resolveWorkspaceEntry(m.workspaceRoot, sourceArg)
+	if err != nil {
+		return Result{}, fmt.Errorf("resolve move source: %w", err)
+	}
+	_, dest, err := resolveWorkspaceEntry(m.workspaceRoot, destArg)
+	if err != nil {
+		return Result{}, fmt.Errorf("resolve move destination: %w", err)
+	}
+	if source == root {
+		return Result{}, errors.New("cannot move the workspace root")
+	}
+	if source == dest {
+		return Result{}, fmt.Errorf("source and destination are the same: %s", sourceArg)
+	}
+
+	info, err := os.Lstat(source)
+	if err != nil {
+		return Result{}, fmt.Errorf("stat %s: %w", sourceArg, err)
+	}
+	if info.IsDir() && isWithinWorkspace(source, dest) {
+		return Result{}, fmt.Errorf("cannot move %s into itself", sourceArg)
+	}
+
+	destInfo, err := os.Lstat(dest)
+	switch {
+	case err == nil:
+		if !input.Overwrite {
+			return Result{}, fmt.Errorf("destination %s already exists (set overwrite to replace it)", destArg)
+		}
+		if destInfo.IsDir() || info.IsDir() {
+			return Result{}, fmt.Errorf("overwrite only replaces files: %s", destArg)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return Result{}, fmt.Errorf("stat %s: %w", destArg, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return Result{}, fmt.Errorf("mkdir parent for %s: %w", destArg, err)
+	}
+	if err := os.Rename(source, dest); err != nil {
+		return Result{}, fmt.Errorf("move %s to %s: %w", sourceArg, destArg, err)
+	}
+
+	kind := "file"
+	if info.IsDir() {
+		kind = "directory"
+	}
+	details, _ := json.Marshal(map[string]any{
+		"source":      sourceArg,
+		"destination": destArg,
+		"kind":        kind,
+	})
+	return Result{
+		Content: fmt.Sprintf("Moved %s %s to %s", kind, sourceArg, destArg),
+		Display: DisplayData{
+			Type:    "move_result",
+			Payload: details,
+		},
+	}, nil
+}
@@ -0,0 +1,87 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMoveToolRenamesWithinWorkspace(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "old.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	got, err := NewMoveToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"source":"old.txt","destination":"nested/new.txt"}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got.Content != "Moved file old.txt to nested/new.txt" {
+		t.Fatalf("Execute().Content = %q, want move summary", got.Content)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "old.txt")); !os.IsNotExist(err) {
+		t.Fatalf("Stat(old.txt) error = %v, want not exist", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(workspace, "nested", "new.txt"))
+	if err != nil || string(raw) != "hello" {
+		t.Fatalf("ReadFile(new.txt) = %q, %v; want hello", raw, err)
+	}
+}
+
+func TestMoveToolRefusesExistingDestinationWithoutOverwrite(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	for name, body := range map[string]string{"a.txt": "a", "b.txt": "b"} {
+		if err := os.WriteFile(filepath.Join(workspace, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	tool := NewMoveToolAt(workspace)
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"source":"a.txt","destination":"b.txt"}`)); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("Execute() error = %v, want destination exists error", err)
+	}
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"source":"a.txt","destination":"b.txt","overwrite":true}`)); err != nil {
+		t.Fatalf("Execute(overwrite) error = %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(workspace, "b.txt"))
+	if err != nil || string(raw) != "a" {
+		t.Fatalf("ReadFile(b.txt) = %q, %v; want a", raw, err)
+	}
+}
+
+func TestMoveToolRejectsPathsOutsideWorkspace(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "in.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "out.txt"), []byte("y"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	tool := NewMoveToolAt(workspace)
+
+	params, _ := json.Marshal(map[string]string{"source": "in.txt", "destination": filepath.Join(outside, "moved.txt")})
+	if _, err := tool.Execute(context.Background(), params); !errors.Is(err, ErrPathOutsideWorkspace) {
+		t.Fatalf("Execute(out of workspace destination) error = %v, want ErrPathOutsideWorkspace", err)
+	}
+	params, _ = json.Marshal(map[string]string{"source": filepath.Join(outside, "out.txt"), "destination": "in2.txt"})
+	if _, err := tool.Execute(context.Background(), params); !errors.Is(err, ErrPathOutsideWorkspace) {
+		t.Fatalf("Execute(out of workspace source) error = %v, want ErrPathOutsideWorkspace", err)
+	}
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"source":"in.txt","destination":"../escaped.txt"}`)); !errors.Is(err, ErrPathOutsideWorkspace) {
+		t.Fatalf("Execute(../ destination) error = %v, want ErrPathOutsideWorkspace", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "in.txt")); err != nil {
+		t.Fatalf("Stat(in.txt) error = %v, want source untouched", err)
+	}
+}
@@ -0,0 +1,117 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const multiEditToolName = "multiedit"
+
+// MultiEditTool applies several exact-text replacements to one file atomically.
+type MultiEditTool struct {
+	workspaceRoot string
+	exactMatch    bool
+}
+
+// NewMultiEditTool constructs the multiedit tool.
+func NewMultiEditTool() MultiEditTool { return NewMultiEditToolAt("") }
+
+// NewMultiEditToolAt constructs the multiedit tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewMultiEditToolAt(workspaceRoot string) MultiEditTool {
+	return MultiEditTool{workspaceRoot: workspaceRoot}
+}
+
+// NewMultiEditToolWithOptions constructs the multiedit tool from opts.
+func NewMultiEditToolWithOptions(opts EditOptions) MultiEditTool {
+	return MultiEditTool{workspaceRoot: opts.WorkspaceRoot, exactMatch: opts.ExactMatch}
+}
+
+func (MultiEditTool) Name() string { return multiEditToolName }
+
+func (MultiEditTool) Description() string {
+	return "Apply multiple exact-text edits to one file in a single operation. Edits are applied in order against the result of the previous edit; if any edit fails, the file is left untouched."
+}
+
+func (MultiEditTool) Schema() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of the edits you're making (shown to user)"},"path":{"type":"string","description":"Path to the file to edit (relative or absolute)"},"edits":{"type":"array","description":"Ordered list of replacements to apply","items":{"type":"object","properties":{"oldText":{"type":"string","description":"Exact text to find and replace (must match exactly and be unique)"},"newText":{"type":"string","description":"New text to replace the old text with"}},"required":["oldText","newText"]}}},"required":["path","edits"]}`)
+}
+
+func (m MultiEditTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	default:
+	}
+
+	var input struct {
+		Label string `json:"label"`
+		Path  string `json:"path"`
+		Edits []struct {
+			OldText string `json:"oldText"`
+			NewText string `json:"newText"`
+		} `json:"edits"`
+	}
+	if err := decodeParams(params, &input); err != nil {
+		return Result{}, fmt.Errorf("decode multiedit params: %w", err)
+	}
+
+	pathArg := strings.TrimSpace(input.Path)
+	if pathArg == "" {
+		return Result{}, errors.New("path is required")
+	}
+	if len(input.Edits) == 0 {
+		return Result{}, errors.New("edits must contain at least one edit")
+	}
+
+	path, err := resolveWorkspacePath(m.workspaceRoot, pathArg, false)
+	if err != nil {
+		return Result{}, fmt.Errorf("resolve multiedit path: %w", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("read %s: %w", pathArg, err)
+	}
+	bom, content := stripBOM(string(raw))
+	originalEnding := detectLineEnding(content)
+	original := normalizeToLF(content)
+
+	updated := original
+	for i, edit := range input.Edits {
+		if edit.OldText == "" {
+			return Result{}, fmt.Errorf("edit %d: oldText is required", i)
+		}
+		next, err := replaceUniqueText(updated, normalizeToLF(edit.OldText), normalizeToLF(edit.NewText), pathArg, !m.exactMatch)
+		if err != nil {
+			return Result{}, fmt.Errorf("edit %d: %w", i, err)
+		}
+		updated = next
+	}
+	if updated == original {
+		return Result{}, fmt.Errorf("No changes made to %s. The edits produced identical content.", pathArg)
+	}
+	finalContent := bom + restoreLineEndings(updated, originalEnding)
+
+	mode := os.FileMode(0o644)
+	if info, statErr := os.Stat(path); statErr == nil {
+		mode = info.Mode()
+	}
+	if err := os.WriteFile(path, []byte(finalContent), mode); err != nil {
+		return Result{}, fmt.Errorf("write %s: %w", pathArg, err)
+	}
+
+	diff := generateDiffString(original, updated, 4)
+	details, _ := json.Marshal(withUndoSnapshot(map[string]any{"diff": diff}, path, raw, true))
+	return Result{
+		Content: fmt.Sprintf("Successfully applied %d edits to %s.", len(input.Edits), pathArg),
+		Display: DisplayData{
+			Type:    "edit_result",
+			Payload: details,
+		},
+	}, nil
+}
@@ -0,0 +1,109 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMultiEditToolAppliesEditsSequentially(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "file.txt")
+	if err := os.WriteFile(path, []byte("alpha\nbeta\ngamma\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tool := NewMultiEditToolAt(workspace)
+	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"file.txt","edits":[{"oldText":"alpha","newText":"one"},{"oldText":"one\nbeta","newText":"one\ntwo"},{"oldText":"gamma","newText":"three"}]}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(got.Content, "applied 3 edits") {
+		t.Fatalf("Execute().Content = %q, want success message", got.Content)
+	}
+	if got.Display.Type != "edit_result" {
+		t.Fatalf("Execute().Display.Type = %q, want edit_result", got.Display.Type)
+	}
+	for _, want := range []string{"-1 alpha", "+1 one", "+3 three"} {
+		if !strings.Contains(string(got.Display.Payload), want) {
+			t.Fatalf("Execute().Display.Payload = %q, want %q", string(got.Display.Payload), want)
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(raw) != "one\ntwo\nthree\n" {
+		t.Fatalf("edited content = %q, want one/two/three", string(raw))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestMultiEditToolFailsAtomically(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		params  string
+		wantErr string
+	}{
+		{
+			name:    "missing",
+			params:  `{"path":"file.txt","edits":[{"oldText":"x","newText":"y"},{"oldText":"zzz","newText":"w"}]}`,
+			wantErr: "edit 1: Could not find the exact text",
+		},
+		{
+			name:    "not unique",
+			params:  `{"path":"file.txt","edits":[{"oldText":"y","newText":"x"}]}`,
+			wantErr: "edit 0: Found 2 occurrences",
+		},
+		{
+			name:    "empty oldText",
+			params:  `{"path":"file.txt","edits":[{"oldText":"x","newText":"q"},{"oldText":"","newText":"w"}]}`,
+			wantErr: "edit 1: oldText is required",
+		},
+		{
+			name:    "no edits",
+			params:  `{"path":"file.txt","edits":[]}`,
+			wantErr: "at least one edit",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			workspace := t.TempDir()
+			path := filepath.Join(workspace, "file.txt")
+			if err := os.WriteFile(path, []byte("x\ny\ny\n"), 0o644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			_, err := NewMultiEditToolAt(workspace).Execute(context.Background(), json.RawMessage(tc.params))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Execute() error = %v, want %q", err, tc.wantErr)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if string(raw) != "x\ny\ny\n" {
+				t.Fatalf("file content = %q, want untouched", string(raw))
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const applyPatchToolName = "apply_patch"
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ApplyPatchTool applies a unified diff to one file atomically.
+type ApplyPatchTool struct {
+	workspaceRoot string
+}
+
+// NewApplyPatchTool constructs the apply_patch tool.
+func NewApplyPatchTool() ApplyPatchTool { return NewApplyPatchToolAt("") }
+
+// NewApplyPatchToolAt constructs the apply_patch tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewApplyPatchToolAt(workspaceRoot string) ApplyPatchTool {
+	return ApplyPatchTool{workspaceRoot: workspaceRoot}
+}
+
+func (ApplyPatchTool) Name() string { return applyPatchToolName }
+
+func (ApplyPatchTool) Description() string {
+	return "Apply a unified diff (---/+++ headers and @@ hunks) to an existing file. Every hunk's context and removed lines must match the current file; if any hunk fails, the file is left untouched."
+}
+
+func (ApplyPatchTool) Schema() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of the change you're making (shown to user)"},"path":{"type":"string","description":"Path to the file to patch (relative or absolute)"},"patch":{"type":"string","description":"Unified diff to apply, containing one or more @@ hunks"}},"required":["path","patch"]}`)
+}
+
+func (a ApplyPatchTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	default:
+	}
+
+	var input struct {
+		Label string `json:"label"`
+		Path  string `json:"path"`
+		Patch string `json:"patch"`
+	}
+	if err := decodeParams(params, &input); err != nil {
+		return Result{}, fmt.Errorf("decode apply_patch params: %w", err)
+	}
+
+	pathArg := strings.TrimSpace(input.Path)
+	if pathArg == "" {
+		return Result{}, errors.New("path is required")
+	}
+	hunks, err := parseUnifiedDiff(normalizeToLF(input.Patch))
+	if err != nil {
+		return Result{}, err
+	}
+
+	path, err := resolveWorkspacePath(a.workspaceRoot, pathArg, false)
+	if err != nil {
+		return Result{}, fmt.Errorf("resolve apply_patch path: %w", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("read %s: %w", pathArg, err)
+	}
+	bom, content := stripBOM(string(raw))
+	originalEnding := detectLineEnding(content)
+	original := normalizeToLF(content)
+
+	updated, err := applyHunks(original, hunks, pathArg)
+	if err != nil {
+		return Result{}, err
+	}
+	if updated == original {
+		return Result{}, fmt.Errorf("No changes made to %s. The patch produced identical content.", pathArg)
+	}
+	finalContent := bom + restoreLineEndings(updated, originalEnding)
+
+	mode := os.FileMode(0o644)
+	if info, statErr := os.Stat(path); statErr == nil {
+		mode = info.Mode()
+	}
+	if err := os.WriteFile(path, []byte(finalContent), mode); err != nil {
+		return Result{}, fmt.Errorf("write %s: %w", pathArg, err)
+	}
+
+	diff := generateDiffString(original, updated, 4)
+	details, _ := json.Marshal(withUndoSnapshot(map[string]any{"diff": diff}, path, raw, true))
+	return Result{
+		Content: fmt.Sprintf("Successfully applied %d hunks to %s.", len(hunks), pathArg),
+		Display: DisplayData{
+			Type:    "edit_result",
+			Payload: details,
+		},
+	}, nil
+}
+
+// patchHunk is one @@ section of a unified diff.
+type patchHunk struct {
+	oldStart int
+	oldLines []string
+	newLines []string
+	// oldNoEOL and newNoEOL record "\ No newline at end of file" markers.
+	oldNoEOL bool
+	newNoEOL bool
+}
+
+// parseUnifiedDiff extracts hunks from a unified diff. Each hunk runs for
+// the line counts in its @@ header, so body lines that look like file headers
+// (a removed "-- comment" reads "--- comment") stay in the hunk. File headers
+// are recognized only between hunks, and any preamble before the first hunk
+// is ignored. Body lines past the counts still extend the hunk because models
+// frequently miscount them.
+func parseUnifiedDiff(patch string) ([]patchHunk, error) {
+	var hunks []patchHunk
+	var current *patchHunk
+	// oldLeft and newLeft are the lines the current hunk header still promises.
+	oldLeft, newLeft := 0, 0
+	lastKind := byte(' ')
+	blankTail, blankPastCounts := 0, 0
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		// Trailing blank lines past the declared counts are usually an artifact
+		// of how the patch was quoted. When the counts were never met they are
+		// wrong anyway, so every trailing blank line is dropped.
+		trim := blankPastCounts
+		if oldLeft > 0 || newLeft > 0 {
+			trim = blankTail
+		}
+		current.oldLines = current.oldLines[:len(current.oldLines)-trim]
+		current.newLines = current.newLines[:len(current.newLines)-trim]
+		hunks = append(hunks, *current)
+		current = nil
+		blankTail, blankPastCounts = 0, 0
+	}
+	consume := func(fromOld, fromNew bool) {
+		if fromOld && oldLeft > 0 {
+			oldLeft--
+		}
+		if fromNew && newLeft > 0 {
+			newLeft--
+		}
+	}
+
+	for _, line := range strings.Split(patch, "\n") {
+		if match := hunkHeaderPattern.FindStringSubmatch(line); match != nil {
+			flush()
+			oldStart, _ := strconv.Atoi(match[1])
+			current = &patchHunk{oldStart: oldStart}
+			oldLeft, newLeft = hunkLineCount(match[2]), hunkLineCount(match[4])
+			lastKind = ' '
+			continue
+		}
+		inCounts := current != nil && (oldLeft > 0 || newLeft > 0)
+		if !inCounts && isPatchFileHeader(line) {
+			flush()
+			continue
+		}
+		if current == nil {
+			if len(hunks) > 0 && line != "" && strings.ContainsRune(" -+", rune(line[0])) {
+				return nil, fmt.Errorf("invalid patch line %q: hunk lines must follow an @@ header", line)
+			}
+			continue
+		}
+
+		if line == "" {
+			// Editors often strip the leading space from blank context lines.
+			current.oldLines = append(current.oldLines, "")
+			current.newLines = append(current.newLines, "")
+			consume(true, true)
+			lastKind = ' '
+			blankTail++
+			if !inCounts {
+				blankPastCounts++
+			}
+			continue
+		}
+		blankTail, blankPastCounts = 0, 0
+		switch line[0] {
+		case ' ':
+			current.oldLines = append(current.oldLines, line[1:])
+			current.newLines = append(current.newLines, line[1:])
+			consume(true, true)
+		case '-':
+			current.oldLines = append(current.oldLines, line[1:])
+			consume(true, false)
+		case '+':
+			current.newLines = append(current.newLines, line[1:])
+			consume(false, true)
+		case '\\':
+			switch lastKind {
+			case '-':
+				current.oldNoEOL = true
+			case '+':
+				current.newNoEOL = true
+			default:
+				current.oldNoEOL = true
+				current.newNoEOL = true
+			}
+			continue
+		default:
+			return nil, fmt.Errorf("invalid patch line %q: hunk lines must start with ' ', '-', or '+'", line)
+		}
+		lastKind = line[0]
+	}
+	flush()
+
+	if len(hunks) == 0 {
+		return nil, errors.New("patch contains no @@ hunks")
+	}
+	return hunks, nil
+}
+
+// hunkLineCount parses an optional @@ header count, which defaults to 1.
+func hunkLineCount(raw string) int {
+	if raw == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(raw)
+	return n
+}
+
+func isPatchFileHeader(line string) bool {
+	return strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "diff ")
+}
+
+// applyHunks applies hunks in order against content and returns the result.
+// Each hunk must match exactly; it is looked up at its declared line first,
+// then at the nearest matching position after the previous hunk.
+func applyHunks(content string, hunks []patchHunk, pathArg string) (string, error) {
+	trailingNewline := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	out := make([]string, 0, len(lines))
+	cursor := 0
+	offset := 0
+	for i, hunk := range hunks {
+		// Pure insertions name the line they follow rather than the first line replaced.
+		hint := hunk.oldStart - 1
+		if len(hunk.oldLines) == 0 {
+			hint = hunk.oldStart
+		}
+		at := findHunk(lines, hunk.oldLines, cursor, hint+offset)
+		if at < 0 {
+			return "", fmt.Errorf(
+				"hunk %d (@@ -%d) does not match %s. Context and removed lines must match the current file exactly; no changes were made.",
+				i+1,
+				hunk.oldStart,
+				pathArg,
+			)
+		}
+		out = append(out, lines[cursor:at]...)
+		out = append(out, hunk.newLines...)
+		cursor = at + len(hunk.oldLines)
+		offset = at - hint
+
+		if cursor == len(lines) {
+			if hunk.newNoEOL {
+				trailingNewline = false
+			} else if hunk.oldNoEOL {
+				trailingNewline = true
+			}
+		}
+	}
+	out = append(out, lines[cursor:]...)
+
+	updated := strings.Join(out, "\n")
+	if trailingNewline && len(out) > 0 {
+		updated += "\n"
+	}
+	return updated, nil
+}
+
+// findHunk returns the index where want matches lines at or after from,
+// preferring hint and then the closest match to it, or -1 when absent.
+func findHunk(lines, want []string, from, hint int) int {
+	last := len(lines) - len(want)
+	if last < from {
+		return -1
+	}
+	hint = min(max(hint, from), last)
+	for distance := 0; hint-distance >= from || hint+distance <= last; distance++ {
+		for _, at := range []int{hint - distance, hint + distance} {
+			if at >= from && at <= last && slices.Equal(lines[at:at+len(want)], want) {
+				return at
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,208 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyPatchToolAppliesCleanHunk(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"), 0o755); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	patch := strings.Join([]string{
+		"--- a/main.go",
+		"+++ b/main.go",
+		"@@ -3,3 +3,3 @@",
+		" func main() {",
+		"-\tprintln(\"hi\")",
+		"+\tprintln(\"hello\")",
+		" }",
+	}, "\n")
+	got, err := NewApplyPatchToolAt(workspace).Execute(context.Background(), mustPatchParams(t, "main.go", patch))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got.Content != "Successfully applied 1 hunks to main.go." {
+		t.Fatalf("Execute().Content = %q, want success message", got.Content)
+	}
+	if got.Display.Type != "edit_result" || !strings.Contains(string(got.Display.Payload), `println(\"hello\")`) {
+		t.Fatalf("Execute().Display = %#v, want edit_result diff payload", got.Display)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if want := "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"; string(raw) != want {
+		t.Fatalf("patched content = %q, want %q", string(raw), want)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0o755 {
+		t.Fatalf("file mode = %v, want 0755 preserved", info.Mode().Perm())
+	}
+}
+
+func TestApplyPatchToolRejectsContextMismatch(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "file.txt")
+	original := "one\ntwo\nthree\nfour\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// The first hunk applies cleanly; the second does not, so nothing may be written.
+	patch := strings.Join([]string{
+		"@@ -1,2 +1,2 @@",
+		"-one",
+		"+ONE",
+		" two",
+		"@@ -3,2 +3,2 @@",
+		" three",
+		"-five",
+		"+FIVE",
+	}, "\n")
+	_, err := NewApplyPatchToolAt(workspace).Execute(context.Background(), mustPatchParams(t, "file.txt", patch))
+	if err == nil || !strings.Contains(err.Error(), "hunk 2") {
+		t.Fatalf("Execute() error = %v, want hunk 2 mismatch", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(raw) != original {
+		t.Fatalf("content after failed patch = %q, want untouched %q", string(raw), original)
+	}
+}
+
+func TestApplyPatchToolAppliesMultipleHunks(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "list.txt")
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, "line"+strings.Repeat("x", i%3)+string(rune('a'+i-1)))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// Hunk line numbers are stale by one; hunks still apply at the nearest match.
+	patch := strings.Join([]string{
+		"@@ -3,2 +3,3 @@",
+		" " + lines[1],
+		"+inserted",
+		" " + lines[2],
+		"@@ -16,3 +17,2 @@",
+		" " + lines[14],
+		"-" + lines[15],
+		" " + lines[16],
+		"@@ -20,1 +20,1 @@",
+		"-" + lines[19],
+		"+last",
+		`\ No newline at end of file`,
+	}, "\n")
+	got, err := NewApplyPatchToolAt(workspace).Execute(context.Background(), mustPatchParams(t, "list.txt", patch))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(got.Content, "3 hunks") {
+		t.Fatalf("Execute().Content = %q, want 3 hunks", got.Content)
+	}
+
+	want := append([]string(nil), lines[:2]...)
+	want = append(want, "inserted")
+	want = append(want, lines[2:15]...)
+	want = append(want, lines[16:19]...)
+	want = append(want, "last")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(raw) != strings.Join(want, "\n") {
+		t.Fatalf("patched content = %q, want %q", string(raw), strings.Join(want, "\n"))
+	}
+}
+
+func TestApplyPatchToolKeepsHeaderLikeLinesInsideHunks(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "schema.sql")
+	if err := os.WriteFile(path, []byte("-- x\nselect 1;\n-- y\nselect 2;\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// Removing "-- x" yields "--- x", which must not end the hunk early.
+	patch := strings.Join([]string{
+		"diff --git a/schema.sql b/schema.sql",
+		"--- a/schema.sql",
+		"+++ b/schema.sql",
+		"@@ -1,4 +1,3 @@",
+		"--- x",
+		" select 1;",
+		"--- y",
+		"+++ z",
+		" select 2;",
+	}, "\n")
+	if _, err := NewApplyPatchToolAt(workspace).Execute(context.Background(), mustPatchParams(t, "schema.sql", patch)); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if want := "select 1;\n++ z\nselect 2;\n"; string(raw) != want {
+		t.Fatalf("patched content = %q, want %q", string(raw), want)
+	}
+
+	// Body lines after a file header that ends the hunk are an error, not dropped.
+	stray := strings.Join([]string{
+		"@@ -1,1 +1,1 @@",
+		"-select 1;",
+		"+select 3;",
+		"--- a/schema.sql",
+		"-select 2;",
+	}, "\n")
+	if _, err := NewApplyPatchToolAt(workspace).Execute(context.Background(), mustPatchParams(t, "schema.sql", stray)); err == nil || !strings.Contains(err.Error(), "must follow an @@ header") {
+		t.Fatalf("Execute() error = %v, want stray hunk line rejected", err)
+	}
+}
+
+func TestApplyPatchToolRejectsPatchWithoutHunks(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "file.txt"), []byte("x\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	_, err := NewApplyPatchToolAt(workspace).Execute(context.Background(), mustPatchParams(t, "file.txt", "--- a/file.txt\n+++ b/file.txt\n"))
+	if err == nil || !strings.Contains(err.Error(), "no @@ hunks") {
+		t.Fatalf("Execute() error = %v, want missing hunks error", err)
+	}
+}
+
+func mustPatchParams(t *testing.T, path, patch string) json.RawMessage {
+	t.Helper()
+
+	raw, err := json.Marshal(map[string]string{"label": "patch", "path": path, "patch": patch})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return raw
+}
@@ -0,0 +1,39 @@
+package tool
+
+import (
+	"context"
+	"sync"
+)
+
+// ProgressReporter receives incremental output from a running tool.
+type ProgressReporter func(chunk string)
+
+type progressReporterKey struct{}
+
+// WithProgressReporter returns a context whose tool executions stream partial
+// output to report while they run. Tools without incremental output ignore it.
+func WithProgressReporter(ctx context.Context, report ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressReporterKey{}, report)
+}
+
+func progressReporterFrom(ctx context.Context) ProgressReporter {
+	report, _ := ctx.Value(progressReporterKey{}).(ProgressReporter)
+	return report
+}
+
+// progressWriter forwards writes to a ProgressReporter. It is shared by a
+// command's stdout and stderr, which exec copies from separate goroutines.
+type progressWriter struct {
+	mu     sync.Mutex
+	report ProgressReporter
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.report(string(p))
+	return len(p), nil
+}
@@ -21,9 +21,11 @@ type ReadTool struct {
 }
 
 // NewReadTool constructs the read tool.
-func NewReadTool() ReadTool { return newReadTool("") }
+func NewReadTool() ReadTool { return NewReadToolAt("") }
 
-func newReadTool(workspaceRoot string) ReadTool {
+// NewReadToolAt constructs the read tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewReadToolAt(workspaceRoot string) ReadTool {
 	return ReadTool{
 		workspaceRoot: workspaceRoot,
 		maxLines:      defaultMaxLines,
@@ -33,16 +35,22 @@ func newReadTool(workspaceRoot string) ReadTool {
 
 func (ReadTool) Name() string { return readToolName }
 
+// Idempotent marks read as safe to retry after a failure.
+func (ReadTool) Idempotent() bool { return true }
+
+// ReadOnly marks read as available in plan mode.
+func (ReadTool) ReadOnly() bool { return true }
+
 func (ReadTool) Description() string {
 	return fmt.Sprintf(
-		"Read the contents of a file. Supports text files and images (jpg, png, gif, webp). For text files, output is truncated to %d lines or %dKB (whichever is hit first). Use offset/limit for large files.",
+		"Read the contents of a file. Supports text files and images (jpg, png, gif, webp). For text files, output is truncated to %d lines or %dKB (whichever is hit first). Use offset/limit for large files, including the full output files that truncated bash and tool results point to.",
 		defaultMaxLines,
 		defaultMaxBytes/1024,
 	)
 }
 
 func (ReadTool) Schema() json.RawMessage {
-	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're reading and why (shown to user)"},"path":{"type":"string","description":"Path to the file to read (relative or absolute)"},"offset":{"type":"number","description":"Line number to start reading from (1-indexed)"},"limit":{"type":"number","description":"Maximum number of lines to read"}},"required":["label","path"]}`)
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're reading and why (shown to user)"},"path":{"type":"string","description":"Path to the file to read (relative or absolute)"},"offset":{"type":"number","description":"Line number to start reading from (1-indexed)"},"limit":{"type":"number","description":"Maximum number of lines to read"}},"required":["path"]}`)
 }
 
 func (r ReadTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
@@ -67,12 +75,12 @@ func (r ReadTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 		return Result{}, errors.New("path is required")
 	}
 
-	path, err := resolveWorkspacePath(r.workspaceRoot, pathArg, false)
+	path, err := resolveReadPath(r.workspaceRoot, pathArg)
 	if err != nil {
 		return Result{}, fmt.Errorf("resolve read path: %w", err)
 	}
 
-	if mimeType, ok := imageMimeTypes[strings.ToLower(filepath.Ext(path))]; ok {
+	if mimeType, ok := ImageMediaType(path); ok {
 		raw, err := os.ReadFile(path)
 		if err != nil {
 			return Result{}, fmt.Errorf("read image %s: %w", pathArg, err)
@@ -200,6 +208,12 @@ func (r ReadTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 	}, nil
 }
 
+// ImageMediaType returns the media type of a supported image file, judged by extension.
+func ImageMediaType(path string) (string, bool) {
+	mimeType, ok := imageMimeTypes[strings.ToLower(filepath.Ext(path))]
+	return mimeType, ok
+}
+
 var imageMimeTypes = map[string]string{
 	".jpg":  "image/jpeg",
 	".jpeg": "image/jpeg",
@@ -0,0 +1,170 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	readManyToolName = "read_many"
+	// readManyMaxPaths bounds how many files one call may read.
+	readManyMaxPaths = 20
+	// readManyMinFileLines and readManyMinFileBytes floor each file's share
+	// of the output limits so many small reads stay useful.
+	readManyMinFileLines = 200
+	readManyMinFileBytes = 4 * 1024
+)
+
+// ReadManyTool reads several text files in one call. Each file is truncated
+// to its own share of the output limits so one large file cannot crowd out
+// the rest, and per-file failures are reported inline.
+type ReadManyTool struct {
+	workspaceRoot string
+	maxLines      int
+	maxBytes      int
+}
+
+// NewReadManyTool constructs the read_many tool.
+func NewReadManyTool() ReadManyTool { return NewReadManyToolAt("") }
+
+// NewReadManyToolAt constructs the read_many tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewReadManyToolAt(workspaceRoot string) ReadManyTool {
+	return ReadManyTool{
+		workspaceRoot: workspaceRoot,
+		maxLines:      defaultMaxLines,
+		maxBytes:      defaultMaxBytes,
+	}
+}
+
+func (ReadManyTool) Name() string { return readManyToolName }
+
+// Idempotent marks read_many as safe to retry after a failure.
+func (ReadManyTool) Idempotent() bool { return true }
+
+// ReadOnly marks read_many as available in plan mode.
+func (ReadManyTool) ReadOnly() bool { return true }
+
+func (ReadManyTool) Description() string {
+	return fmt.Sprintf(
+		"Read up to %d text files in one call. Each file is printed under a '==> path <==' header and truncated to its share of %d lines or %dKB. Missing or unreadable files are reported inline. Use read for images or to page through a large file.",
+		readManyMaxPaths,
+		defaultMaxLines,
+		defaultMaxBytes/1024,
+	)
+}
+
+func (ReadManyTool) Schema() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're reading and why (shown to user)"},"paths":{"type":"array","items":{"type":"string"},"description":"Paths of the files to read (relative or absolute)"}},"required":["paths"]}`)
+}
+
+// readManyFile is one file's entry in the read_many display payload.
+type readManyFile struct {
+	Path      string `json:"path"`
+	Bytes     int    `json:"bytes"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (r ReadManyTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	default:
+	}
+
+	var input struct {
+		Label string   `json:"label"`
+		Paths []string `json:"paths"`
+	}
+	if err := decodeParams(params, &input); err != nil {
+		return Result{}, fmt.Errorf("decode read_many params: %w", err)
+	}
+
+	paths := make([]string, 0, len(input.Paths))
+	for _, path := range input.Paths {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return Result{}, errors.New("paths is required")
+	}
+	if len(paths) > readManyMaxPaths {
+		return Result{}, fmt.Errorf("at most %d paths per call, got %d", readManyMaxPaths, len(paths))
+	}
+
+	limits := truncationOptions{
+		MaxLines: max(r.maxLines/len(paths), readManyMinFileLines),
+		MaxBytes: max(r.maxBytes/len(paths), readManyMinFileBytes),
+	}
+	sections := make([]string, 0, len(paths))
+	files := make([]readManyFile, 0, len(paths))
+	for _, pathArg := range paths {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		text, file := r.readOne(pathArg, limits)
+		sections = append(sections, fmt.Sprintf("==> %s <==\n%s", pathArg, text))
+		files = append(files, file)
+	}
+
+	details, _ := json.Marshal(map[string]any{"files": files})
+	return Result{
+		Content: strings.Join(sections, "\n\n"),
+		Display: DisplayData{
+			Type:    "file_contents",
+			Payload: details,
+		},
+	}, nil
+}
+
+// readOne returns the text shown for pathArg, or an inline error message.
+func (r ReadManyTool) readOne(pathArg string, limits truncationOptions) (string, readManyFile) {
+	file := readManyFile{Path: pathArg}
+	fail := func(err error) (string, readManyFile) {
+		file.Error = err.Error()
+		return "[error: " + file.Error + "]", file
+	}
+
+	path, err := resolveWorkspacePath(r.workspaceRoot, pathArg, false)
+	if err != nil {
+		return fail(fmt.Errorf("resolve read path: %w", err))
+	}
+	if _, ok := ImageMediaType(path); ok {
+		return fail(errors.New("image files are not supported; use read"))
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fail(fmt.Errorf("read %s: %w", pathArg, err))
+	}
+	file.Bytes = len(raw)
+
+	truncation := truncateHead(string(raw), limits)
+	switch {
+	case truncation.FirstLineExceedsLimit:
+		file.Truncated = true
+		firstLine, _, _ := strings.Cut(string(raw), "\n")
+		return fmt.Sprintf(
+			"[Line 1 is %s, exceeds %s limit. Use bash: sed -n '1p' %s | head -c %d]",
+			formatSize(len(firstLine)),
+			formatSize(limits.MaxBytes),
+			pathArg,
+			limits.MaxBytes,
+		), file
+	case truncation.Truncated:
+		file.Truncated = true
+		return truncation.Content + fmt.Sprintf(
+			"\n[Showing lines 1-%d of %d. Use read with offset=%d to continue]",
+			truncation.OutputLines,
+			truncation.TotalLines,
+			truncation.OutputLines+1,
+		), file
+	default:
+		return truncation.Content, file
+	}
+}
@@ -0,0 +1,95 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadManyToolReadsFilesAndReportsMissingInline(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	for name, body := range map[string]string{"a.txt": "alpha\n", "b.txt": "beta"} {
+		if err := os.WriteFile(filepath.Join(workspace, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	got, err := NewReadManyToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"label":"x","paths":["a.txt","missing.txt","b.txt"]}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	for _, want := range []string{"==> a.txt <==\nalpha\n", "==> missing.txt <==\n[error: ", "no such file or directory]", "==> b.txt <==\nbeta"} {
+		if !strings.Contains(got.Content, want) {
+			t.Fatalf("Execute().Content = %q, want it to contain %q", got.Content, want)
+		}
+	}
+	if strings.Index(got.Content, "a.txt") > strings.Index(got.Content, "b.txt") {
+		t.Fatalf("Execute().Content = %q, want files in request order", got.Content)
+	}
+
+	var payload struct {
+		Files []readManyFile `json:"files"`
+	}
+	if err := json.Unmarshal(got.Display.Payload, &payload); err != nil {
+		t.Fatalf("Unmarshal(payload) error = %v", err)
+	}
+	if len(payload.Files) != 3 {
+		t.Fatalf("payload files = %#v, want 3", payload.Files)
+	}
+	if payload.Files[0].Bytes != 6 || payload.Files[2].Bytes != 4 {
+		t.Fatalf("payload bytes = %d, %d; want 6, 4", payload.Files[0].Bytes, payload.Files[2].Bytes)
+	}
+	if payload.Files[1].Error == "" || payload.Files[1].Bytes != 0 {
+		t.Fatalf("payload missing file = %#v, want error and no bytes", payload.Files[1])
+	}
+}
+
+func TestReadManyToolTruncatesEachFileSeparately(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	huge := strings.Repeat("line\n", 5000)
+	if err := os.WriteFile(filepath.Join(workspace, "huge.txt"), []byte(huge), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "small.txt"), []byte("tail end"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	got, err := NewReadManyToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"paths":["huge.txt","small.txt"]}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(got.Content, "[Showing lines 1-1000 of 5001. Use read with offset=1001 to continue]") {
+		t.Fatalf("Execute().Content lacks huge.txt truncation notice:\n%s", got.Content[len(got.Content)-200:])
+	}
+	if !strings.HasSuffix(got.Content, "==> small.txt <==\ntail end") {
+		t.Fatalf("Execute().Content does not end with small.txt: %q", got.Content[len(got.Content)-100:])
+	}
+}
+
+func TestReadManyToolSandboxesEachPath(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "inside.txt"), []byte("ok"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	got, err := NewReadManyToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"paths":["inside.txt","../outside.txt"]}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(got.Content, "==> inside.txt <==\nok") || !strings.Contains(got.Content, "==> ../outside.txt <==\n[error: resolve read path:") {
+		t.Fatalf("Execute().Content = %q, want inside read and outside rejected inline", got.Content)
+	}
+
+	if _, err := NewReadManyToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"paths":[" "]}`)); err == nil || !strings.Contains(err.Error(), "paths is required") {
+		t.Fatalf("Execute(no paths) error = %v, want paths required", err)
+	}
+}
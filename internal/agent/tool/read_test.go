@@ -21,7 +21,7 @@ func TestReadToolReadsFile(t *testing.T) {
 		t.Fatalf("write fixture: %v", err)
 	}
 
-	tool := newReadTool(workspace)
+	tool := NewReadToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"main.go"}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -40,7 +40,7 @@ func TestReadToolSupportsOffsetAndLimit(t *testing.T) {
 		t.Fatalf("write fixture: %v", err)
 	}
 
-	tool := newReadTool(workspace)
+	tool := NewReadToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"file.txt","offset":2,"limit":2}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -66,7 +66,7 @@ func TestReadToolTruncatesByLineLimit(t *testing.T) {
 		t.Fatalf("write fixture: %v", err)
 	}
 
-	tool := newReadTool(workspace)
+	tool := NewReadToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"long.txt"}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -87,7 +87,7 @@ func TestReadToolRejectsPathOutsideWorkspace(t *testing.T) {
 		t.Fatalf("write fixture: %v", err)
 	}
 
-	tool := newReadTool(workspace)
+	tool := NewReadToolAt(workspace)
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"`+outside+`"}`))
 	if err == nil || !strings.Contains(strings.ToLower(err.Error()), "workspace") {
 		t.Fatalf("Execute() error = %v, want workspace restriction error", err)
@@ -103,7 +103,7 @@ func TestReadToolSupportsAtPrefixedPath(t *testing.T) {
 		t.Fatalf("write fixture: %v", err)
 	}
 
-	tool := newReadTool(workspace)
+	tool := NewReadToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"@main.go"}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -116,7 +116,7 @@ func TestReadToolSupportsAtPrefixedPath(t *testing.T) {
 func TestReadToolRequiresPath(t *testing.T) {
 	t.Parallel()
 
-	tool := newReadTool(t.TempDir())
+	tool := NewReadToolAt(t.TempDir())
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{}`))
 	if err == nil || !strings.Contains(err.Error(), "path") {
 		t.Fatalf("Execute() error = %v, want path validation error", err)
@@ -132,7 +132,7 @@ func TestReadToolRejectsOffsetBeyondEOF(t *testing.T) {
 		t.Fatalf("write fixture: %v", err)
 	}
 
-	tool := newReadTool(workspace)
+	tool := NewReadToolAt(workspace)
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"file.txt","offset":3}`))
 	if err == nil || !strings.Contains(err.Error(), "beyond end of file") {
 		t.Fatalf("Execute() error = %v, want beyond end of file error", err)
@@ -149,7 +149,7 @@ func TestReadToolFirstLineOverByteLimitSuggestsBash(t *testing.T) {
 		t.Fatalf("write fixture: %v", err)
 	}
 
-	tool := newReadTool(workspace)
+	tool := NewReadToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"huge.txt"}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -160,3 +160,46 @@ func TestReadToolFirstLineOverByteLimitSuggestsBash(t *testing.T) {
 		t.Fatalf("Execute().Content = %q, want substring %q", got.Content, want)
 	}
 }
+
+func TestReadToolPagesSavedBashOutput(t *testing.T) {
+	t.Parallel()
+
+	bash := BashTool{maxOutputLines: 3, maxOutputBytes: defaultMaxBytes}
+	got, err := bash.Execute(context.Background(), json.RawMessage(`{"command":"printf '1\n2\n3\n4\n5\n'"}`))
+	if err != nil {
+		t.Fatalf("bash Execute() error = %v", err)
+	}
+	var display struct {
+		FullOutputPath string `json:"full_output_path"`
+	}
+	if err := json.Unmarshal(got.Display.Payload, &display); err != nil || display.FullOutputPath == "" {
+		t.Fatalf("bash payload = %s, want full_output_path (err %v)", got.Display.Payload, err)
+	}
+	t.Cleanup(func() { _ = os.Remove(display.FullOutputPath) })
+
+	params, _ := json.Marshal(map[string]any{"path": display.FullOutputPath, "offset": 2, "limit": 2})
+	page, err := NewReadToolAt(t.TempDir()).Execute(context.Background(), params)
+	if err != nil {
+		t.Fatalf("read Execute() error = %v", err)
+	}
+	if !strings.HasPrefix(page.Content, "2\n3\n\n") || !strings.Contains(page.Content, "offset=4") {
+		t.Fatalf("read Execute().Content = %q, want lines 2-3 of the saved output", page.Content)
+	}
+}
+
+func TestReadToolRejectsOtherTempFiles(t *testing.T) {
+	t.Parallel()
+
+	file, err := os.CreateTemp("", "not-gar-*.log")
+	if err != nil {
+		t.Fatalf("CreateTemp() error = %v", err)
+	}
+	_ = file.Close()
+	t.Cleanup(func() { _ = os.Remove(file.Name()) })
+
+	params, _ := json.Marshal(map[string]any{"path": file.Name()})
+	_, err = NewReadToolAt(t.TempDir()).Execute(context.Background(), params)
+	if err == nil || !strings.Contains(strings.ToLower(err.Error()), "workspace") {
+		t.Fatalf("Execute() error = %v, want workspace restriction error", err)
+	}
+}
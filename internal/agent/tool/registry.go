@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
+
+	"gar/internal/llm/core"
 )
 
 var (
@@ -14,6 +17,9 @@ var (
 	ErrToolNameRequired      = errors.New("tool name is required")
 	ErrToolAlreadyRegistered = errors.New("tool already registered")
 	ErrToolNotFound          = errors.New("tool not found")
+	// ErrTimeout marks a call that ran past the timeout given to
+	// ExecuteWithTimeout.
+	ErrTimeout = errors.New("tool timed out")
 )
 
 // DisplayData carries UI-facing structured tool output.
@@ -36,10 +42,26 @@ type Tool interface {
 	Execute(ctx context.Context, params json.RawMessage) (Result, error)
 }
 
+// ReadOnly is implemented by tools that never modify the workspace or run
+// commands. Plan mode offers and executes only read-only tools.
+type ReadOnly interface {
+	ReadOnly() bool
+}
+
+// IsReadOnly reports whether tool declares itself read-only.
+func IsReadOnly(tool Tool) bool {
+	readOnly, ok := tool.(ReadOnly)
+	return ok && readOnly.ReadOnly()
+}
+
 // Registry stores tools by name and executes them by lookup.
 type Registry struct {
 	mu    sync.RWMutex
 	tools map[string]Tool
+	retry core.RetryPolicy
+
+	globalSem semaphore
+	toolSems  map[string]semaphore
 }
 
 // NewRegistry constructs an empty tool registry and optionally registers tools.
@@ -91,10 +113,45 @@ func (r *Registry) Get(name string) (Tool, error) {
 }
 
 // Execute resolves a named tool and runs it with provided raw JSON params.
+// Params that do not match the tool's schema fail with a *ValidationError
+// before the tool runs. It waits for a free slot under the registry
+// concurrency limits, giving up when ctx is done. Failed idempotent tools are
+// retried according to the registry retry policy.
 func (r *Registry) Execute(ctx context.Context, name string, params json.RawMessage) (Result, error) {
+	return r.ExecuteWithTimeout(ctx, name, params, 0)
+}
+
+// ExecuteWithTimeout is Execute with a limit on how long the tool runs,
+// retries included. The clock starts once a concurrency slot is held, so time
+// spent queued behind other calls does not count. A call cut off by the
+// limit, rather than by ctx, fails with an error wrapping ErrTimeout. Zero
+// disables the limit.
+func (r *Registry) ExecuteWithTimeout(ctx context.Context, name string, params json.RawMessage, timeout time.Duration) (Result, error) {
 	tool, err := r.Get(name)
 	if err != nil {
 		return Result{}, err
 	}
-	return tool.Execute(ctx, params)
+	if err := validateArguments(tool.Name(), tool.Schema(), params); err != nil {
+		return Result{}, err
+	}
+	release, err := r.acquireSlots(ctx, strings.TrimSpace(name))
+	if err != nil {
+		return Result{}, err
+	}
+	defer release()
+
+	r.mu.RLock()
+	policy := r.retry
+	r.mu.RUnlock()
+
+	if timeout <= 0 {
+		return executeWithRetry(ctx, tool, policy, params)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := executeWithRetry(runCtx, tool, policy, params)
+	if err != nil && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%w after %s", ErrTimeout, timeout)
+	}
+	return result, err
 }
@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
+
+	"gar/internal/llm/core"
 )
 
 type fakeTool struct {
@@ -87,3 +90,71 @@ func TestRegistryExecuteUnknownTool(t *testing.T) {
 		t.Fatalf("Execute() error = %v, want ErrToolNotFound", err)
 	}
 }
+
+type idempotentFakeTool struct {
+	fakeTool
+}
+
+func (idempotentFakeTool) Idempotent() bool { return true }
+
+func TestRegistryExecuteRetriesIdempotentTools(t *testing.T) {
+	t.Parallel()
+
+	flaky := func(calls *int) func(context.Context, json.RawMessage) (Result, error) {
+		return func(context.Context, json.RawMessage) (Result, error) {
+			*calls++
+			if *calls <= 2 {
+				return Result{}, errors.New("resource temporarily unavailable")
+			}
+			return Result{Content: "ok"}, nil
+		}
+	}
+
+	var readCalls, writeCalls int
+	registry := NewRegistry(
+		idempotentFakeTool{fakeTool{name: "read", run: flaky(&readCalls)}},
+		fakeTool{name: "write", run: flaky(&writeCalls)},
+	)
+	registry.SetRetryPolicy(core.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	var attempts []RetryAttempt
+	ctx := WithRetryObserver(context.Background(), func(attempt RetryAttempt) {
+		attempts = append(attempts, attempt)
+	})
+
+	got, err := registry.Execute(ctx, "read", nil)
+	if err != nil {
+		t.Fatalf("Execute(read) error = %v", err)
+	}
+	if got.Content != "ok" || readCalls != 3 {
+		t.Fatalf("Execute(read) = %q after %d calls, want ok after 3", got.Content, readCalls)
+	}
+	if len(attempts) != 2 || attempts[0].Attempt != 1 || attempts[1].Attempt != 2 || attempts[0].Tool != "read" || attempts[0].Err == nil {
+		t.Fatalf("retry attempts = %#v, want two observed read retries", attempts)
+	}
+
+	if _, err := registry.Execute(ctx, "write", nil); err == nil {
+		t.Fatal("Execute(write) error = nil, want first failure without retry")
+	}
+	if writeCalls != 1 {
+		t.Fatalf("write calls = %d, want 1 (non-idempotent tools never retry)", writeCalls)
+	}
+}
+
+func TestRegistryExecuteDoesNotRetryCanceledContext(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	registry := NewRegistry(idempotentFakeTool{fakeTool{name: "read", run: func(ctx context.Context, _ json.RawMessage) (Result, error) {
+		calls++
+		return Result{}, context.Canceled
+	}}})
+	registry.SetRetryPolicy(core.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	if _, err := registry.Execute(context.Background(), "read", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Execute() error = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+}
@@ -0,0 +1,79 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gar/internal/llm/core"
+)
+
+// Idempotent is implemented by tools that are safe to run again after a
+// failure. Only idempotent tools are retried by Registry.Execute.
+type Idempotent interface {
+	Idempotent() bool
+}
+
+// RetryAttempt describes one scheduled retry of a failed tool execution.
+type RetryAttempt struct {
+	Tool    string
+	Attempt int
+	Delay   time.Duration
+	Err     error
+}
+
+type retryObserverKey struct{}
+
+// WithRetryObserver returns a context whose tool executions report each retry
+// attempt to observe before backing off.
+func WithRetryObserver(ctx context.Context, observe func(RetryAttempt)) context.Context {
+	return context.WithValue(ctx, retryObserverKey{}, observe)
+}
+
+func retryObserverFrom(ctx context.Context) func(RetryAttempt) {
+	observe, _ := ctx.Value(retryObserverKey{}).(func(RetryAttempt))
+	return observe
+}
+
+// SetRetryPolicy configures retries for idempotent tools. MaxRetries <= 0
+// disables retries; unset delays fall back to the provider retry defaults.
+func (r *Registry) SetRetryPolicy(policy core.RetryPolicy) {
+	if policy.MaxRetries > 0 {
+		policy = core.NormalizeRetryPolicy(policy)
+	} else {
+		policy = core.RetryPolicy{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retry = policy
+}
+
+func isIdempotent(tool Tool) bool {
+	marker, ok := tool.(Idempotent)
+	return ok && marker.Idempotent()
+}
+
+func executeWithRetry(ctx context.Context, tool Tool, policy core.RetryPolicy, params json.RawMessage) (Result, error) {
+	result, err := tool.Execute(ctx, params)
+	if policy.MaxRetries <= 0 || !isIdempotent(tool) {
+		return result, err
+	}
+
+	for attempt := 0; err != nil && attempt < policy.MaxRetries; attempt++ {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			return result, err
+		}
+
+		delay := core.ComputeBackoffDelay(policy, attempt)
+		if observe := retryObserverFrom(ctx); observe != nil {
+			observe(RetryAttempt{Tool: tool.Name(), Attempt: attempt + 1, Delay: delay, Err: err})
+		}
+		if sleepErr := core.SleepContext(ctx, delay); sleepErr != nil {
+			return result, sleepErr
+		}
+		result, err = tool.Execute(ctx, params)
+	}
+	return result, err
+}
@@ -0,0 +1,197 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidArguments marks tool calls whose arguments do not match the
+// tool's declared schema.
+var ErrInvalidArguments = errors.New("invalid tool arguments")
+
+// ValidationError lists every way one call's arguments break its tool schema,
+// so the model can fix them all in a single retry.
+type ValidationError struct {
+	Tool   string
+	Issues []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Tool, strings.Join(e.Issues, "; "))
+}
+
+func (e *ValidationError) Unwrap() error { return ErrInvalidArguments }
+
+// jsonSchema is the subset of JSON Schema tools declare: types, object
+// properties and required fields, array items, and enums. Other keywords are
+// ignored.
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []json.RawMessage      `json:"enum"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+}
+
+// schemaTypes accepts "type" as either one name or a list of names.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+// validateArguments checks params against schema and returns a
+// *ValidationError describing each mismatch. Empty params are treated as {},
+// as decodeParams does. A schema that cannot be parsed is not enforced.
+func validateArguments(toolName string, schema, params json.RawMessage) error {
+	var root jsonSchema
+	if len(bytes.TrimSpace(schema)) == 0 || json.Unmarshal(schema, &root) != nil {
+		return nil
+	}
+
+	trimmed := bytes.TrimSpace(params)
+	if len(trimmed) == 0 {
+		trimmed = []byte("{}")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return &ValidationError{Tool: toolName, Issues: []string{"arguments are not valid JSON: " + err.Error()}}
+	}
+
+	var issues []string
+	root.validate(value, "", &issues)
+	if len(issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Tool: toolName, Issues: issues}
+}
+
+func (s *jsonSchema) validate(value any, path string, issues *[]string) {
+	report := func(format string, args ...any) {
+		where := path
+		if where == "" {
+			where = "arguments"
+		}
+		*issues = append(*issues, where+": "+fmt.Sprintf(format, args...))
+	}
+
+	actual := jsonTypeOf(value)
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(want string) bool { return typeMatches(want, actual, value) }) {
+		report("expected %s, got %s", strings.Join(s.Type, " or "), actual)
+		return
+	}
+	if len(s.Enum) > 0 && !s.enumContains(value) {
+		allowed := make([]string, 0, len(s.Enum))
+		for _, option := range s.Enum {
+			allowed = append(allowed, string(option))
+		}
+		report("must be one of %s", strings.Join(allowed, ", "))
+		return
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := typed[name]; !ok {
+				report("missing required field %q", name)
+			}
+		}
+		names := make([]string, 0, len(typed))
+		for name := range typed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			// Some models send null for optional fields they leave unset.
+			// decodeParams reads those as absent, so validation does too.
+			if typed[name] == nil && !slices.Contains(s.Required, name) {
+				continue
+			}
+			child, ok := s.Properties[name]
+			switch {
+			case ok && child != nil:
+				child.validate(typed[name], joinSchemaPath(path, name), issues)
+			case !ok && s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				report("unknown field %q", name)
+			}
+		}
+	case []any:
+		if s.Items == nil {
+			return
+		}
+		for i, item := range typed {
+			s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), issues)
+		}
+	}
+}
+
+func (s *jsonSchema) enumContains(value any) bool {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	for _, option := range s.Enum {
+		var compact bytes.Buffer
+		if json.Compact(&compact, option) == nil && bytes.Equal(compact.Bytes(), encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinSchemaPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// jsonTypeOf names the JSON type of a value decoded with UseNumber.
+func jsonTypeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func typeMatches(want, actual string, value any) bool {
+	if want == "integer" {
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := number.Int64()
+		return err == nil
+	}
+	return want == actual
+}
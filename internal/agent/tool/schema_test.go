@@ -0,0 +1,149 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegistryExecuteRejectsArgumentsNotMatchingSchema(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "file.txt")
+	if err := os.WriteFile(path, []byte("x\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	reg := NewRegistry(NewEditToolAt(workspace), NewMultiEditToolAt(workspace), NewGitToolAt(workspace))
+
+	tests := []struct {
+		name   string
+		tool   string
+		params string
+		issues []string
+	}{
+		{
+			name:   "missing required and wrong type",
+			tool:   "edit",
+			params: `{"path":"file.txt","oldText":"x","replaceAll":"yes"}`,
+			issues: []string{`arguments: missing required field "newText"`, "replaceAll: expected boolean, got string"},
+		},
+		{
+			name:   "old and new are not aliases",
+			tool:   "edit",
+			params: `{"path":"file.txt","old":"x","new":"y"}`,
+			issues: []string{`arguments: missing required field "oldText"`, `arguments: missing required field "newText"`},
+		},
+		{
+			name:   "null required field",
+			tool:   "edit",
+			params: `{"path":"file.txt","oldText":"x","newText":null}`,
+			issues: []string{"newText: expected string, got null"},
+		},
+		{
+			name:   "array items",
+			tool:   "multiedit",
+			params: `{"path":"file.txt","edits":[{"oldText":"x","newText":"y"},{"oldText":1}]}`,
+			issues: []string{`edits[1]: missing required field "newText"`, "edits[1].oldText: expected string, got number"},
+		},
+		{
+			name:   "enum",
+			tool:   "git",
+			params: `{"op":"push"}`,
+			issues: []string{`op: must be one of "status", "diff", "log", "show"`},
+		},
+		{
+			name:   "not an object",
+			tool:   "edit",
+			params: `["file.txt"]`,
+			issues: []string{"arguments: expected object, got array"},
+		},
+	}
+	for _, tt := range tests {
+		_, err := reg.Execute(context.Background(), tt.tool, json.RawMessage(tt.params))
+		var validation *ValidationError
+		if !errors.As(err, &validation) || !errors.Is(err, ErrInvalidArguments) {
+			t.Fatalf("%s: Execute() error = %v, want ValidationError", tt.name, err)
+		}
+		if validation.Tool != tt.tool || !reflect.DeepEqual(validation.Issues, tt.issues) {
+			t.Fatalf("%s: ValidationError = %+v, want issues %q", tt.name, validation, tt.issues)
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(raw) != "x\n" {
+		t.Fatalf("file content = %q, want untouched by rejected calls", string(raw))
+	}
+}
+
+func TestRegistryExecuteRunsToolWithValidArguments(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "file.txt")
+	if err := os.WriteFile(path, []byte("x\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	reg := NewRegistry(NewEditToolAt(workspace))
+
+	// label is optional, and unknown fields are allowed unless
+	// the schema sets additionalProperties to false.
+	if _, err := reg.Execute(context.Background(), "edit", json.RawMessage(`{"path":"file.txt","oldText":"x","newText":"y","replaceAll":true,"note":1}`)); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(raw) != "y\n" {
+		t.Fatalf("file content = %q, want edited", string(raw))
+	}
+}
+
+func TestRegistryExecuteTreatsNullOptionalFieldsAsAbsent(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "file.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	reg := NewRegistry(NewGrepToolAt(workspace))
+
+	result, err := reg.Execute(context.Background(), "grep", json.RawMessage(`{"pattern":"a","limit":null,"glob":null,"ignoreCase":null}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(result.Content, "file.txt") {
+		t.Fatalf("Execute() content = %q, want the match in file.txt", result.Content)
+	}
+}
+
+func TestValidateArgumentsHandlesIntegersAndClosedObjects(t *testing.T) {
+	t.Parallel()
+
+	schema := json.RawMessage(`{"type":"object","properties":{"count":{"type":"integer"},"name":{"type":["string","null"]}},"additionalProperties":false}`)
+	if err := validateArguments("t", schema, json.RawMessage(`{"count":3,"name":null}`)); err != nil {
+		t.Fatalf("validateArguments() error = %v", err)
+	}
+	if err := validateArguments("t", schema, nil); err != nil {
+		t.Fatalf("validateArguments(empty) error = %v", err)
+	}
+
+	err := validateArguments("t", schema, json.RawMessage(`{"count":1.5,"extra":true}`))
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("validateArguments() error = %v, want ValidationError", err)
+	}
+	want := []string{"count: expected integer, got number", `arguments: unknown field "extra"`}
+	if !reflect.DeepEqual(validation.Issues, want) {
+		t.Fatalf("Issues = %q, want %q", validation.Issues, want)
+	}
+}
@@ -0,0 +1,65 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	thinkToolName       = "think"
+	thinkDisplayTypeKey = "thought"
+)
+
+// ThinkTool is a scratchpad: it echoes the model's thought back without side
+// effects, so reasoning is recorded in the transcript and shown apart from
+// replies.
+type ThinkTool struct{}
+
+// NewThinkTool constructs the think tool.
+func NewThinkTool() ThinkTool { return ThinkTool{} }
+
+func (ThinkTool) Name() string { return thinkToolName }
+
+// Idempotent marks think as safe to retry; it has no side effects.
+func (ThinkTool) Idempotent() bool { return true }
+
+// ReadOnly marks think as available in plan mode.
+func (ThinkTool) ReadOnly() bool { return true }
+
+func (ThinkTool) Description() string {
+	return "Record a thought, plan, or intermediate reasoning step. It changes nothing and returns the thought unchanged. Use it to work through a problem before acting, e.g. after reading tool output and before deciding what to do next."
+}
+
+func (ThinkTool) Schema() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"thought":{"type":"string","description":"The reasoning to record"}},"required":["thought"]}`)
+}
+
+func (ThinkTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	default:
+	}
+
+	var input struct {
+		Thought string `json:"thought"`
+	}
+	if err := decodeParams(params, &input); err != nil {
+		return Result{}, fmt.Errorf("decode think params: %w", err)
+	}
+	if strings.TrimSpace(input.Thought) == "" {
+		return Result{}, errors.New("thought is required")
+	}
+
+	details, _ := json.Marshal(map[string]any{"thought": input.Thought})
+	return Result{
+		Content: input.Thought,
+		Display: DisplayData{
+			Type:    thinkDisplayTypeKey,
+			Payload: details,
+		},
+	}, nil
+}
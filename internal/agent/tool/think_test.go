@@ -0,0 +1,46 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestThinkToolEchoesThought(t *testing.T) {
+	t.Parallel()
+
+	before, err := os.ReadDir(".")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	thought := "First read main.go,\nthen check the tests."
+	params, _ := json.Marshal(map[string]string{"thought": thought})
+	got, err := NewThinkTool().Execute(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got.Content != thought {
+		t.Fatalf("Execute().Content = %q, want the thought unchanged", got.Content)
+	}
+	if got.Display.Type != "thought" {
+		t.Fatalf("Execute().Display.Type = %q, want thought", got.Display.Type)
+	}
+	var payload struct {
+		Thought string `json:"thought"`
+	}
+	if err := json.Unmarshal(got.Display.Payload, &payload); err != nil || payload.Thought != thought {
+		t.Fatalf("Display.Payload = %s (%v), want the thought", got.Display.Payload, err)
+	}
+	after, err := os.ReadDir(".")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("directory entries changed from %d to %d, want no filesystem side effects", len(before), len(after))
+	}
+
+	if _, err := NewThinkTool().Execute(context.Background(), json.RawMessage(`{"thought":"  "}`)); err == nil {
+		t.Fatal("Execute(blank thought) error = nil, want error")
+	}
+}
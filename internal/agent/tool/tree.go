@@ -0,0 +1,182 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	treeToolName       = "tree"
+	defaultTreeDepth   = 3
+	treeDisplayTypeKey = "tree_result"
+	treeIndent         = "  "
+)
+
+// TreeTool prints an indented directory tree.
+type TreeTool struct {
+	workspaceRoot string
+}
+
+// NewTreeTool constructs tree tool.
+func NewTreeTool() TreeTool { return NewTreeToolAt("") }
+
+// NewTreeToolAt constructs the tree tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewTreeToolAt(workspaceRoot string) TreeTool {
+	return TreeTool{workspaceRoot: workspaceRoot}
+}
+
+func (TreeTool) Name() string { return treeToolName }
+
+// Idempotent marks tree as safe to retry after a failure.
+func (TreeTool) Idempotent() bool { return true }
+
+// ReadOnly marks tree as available in plan mode.
+func (TreeTool) ReadOnly() bool { return true }
+
+func (TreeTool) Description() string {
+	return fmt.Sprintf(
+		"Show the directory structure under a path as an indented tree, directories first then files, sorted alphabetically. Skips .git, node_modules, and paths excluded by .gitignore unless includeIgnored is true. Descends %d levels by default. Output is truncated to %dKB.",
+		defaultTreeDepth,
+		defaultMaxBytes/1024,
+	)
+}
+
+func (TreeTool) Schema() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're exploring (shown to user)"},"path":{"type":"string","description":"Directory to show (default: current directory)"},"maxDepth":{"type":"number","description":"Maximum directory depth to descend (default: 3)"},"includeIgnored":{"type":"boolean","description":"Include paths excluded by .gitignore (default: false)"}}}`)
+}
+
+// treeNode is one directory entry collected during the walk.
+type treeNode struct {
+	name     string
+	isDir    bool
+	children []*treeNode
+}
+
+func (t TreeTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	default:
+	}
+
+	var input struct {
+		Label          string `json:"label"`
+		Path           string `json:"path"`
+		MaxDepth       *int   `json:"maxDepth"`
+		IncludeIgnored bool   `json:"includeIgnored"`
+	}
+	if err := decodeParams(params, &input); err != nil {
+		return Result{}, fmt.Errorf("decode tree params: %w", err)
+	}
+
+	pathArg := strings.TrimSpace(input.Path)
+	if pathArg == "" {
+		pathArg = "."
+	}
+
+	maxDepth := defaultTreeDepth
+	if input.MaxDepth != nil {
+		if *input.MaxDepth <= 0 {
+			return Result{}, errors.New("maxDepth must be > 0")
+		}
+		maxDepth = *input.MaxDepth
+	}
+
+	rootPath, err := resolveWorkspacePath(t.workspaceRoot, pathArg, false)
+	if err != nil {
+		return Result{}, fmt.Errorf("resolve tree path: %w", err)
+	}
+
+	stat, err := os.Stat(rootPath)
+	if err != nil {
+		return Result{}, fmt.Errorf("stat %s: %w", pathArg, err)
+	}
+	if !stat.IsDir() {
+		return Result{}, fmt.Errorf("not a directory: %s", pathArg)
+	}
+
+	root := &treeNode{isDir: true}
+	nodes := map[string]*treeNode{rootPath: root}
+	dirCount, fileCount := 0, 0
+	walkErr := walkWorkspace(ctx, rootPath, input.IncludeIgnored, func(path string, d fs.DirEntry) error {
+		parent := nodes[filepath.Dir(path)]
+		if parent == nil {
+			return nil
+		}
+		node := &treeNode{name: d.Name(), isDir: d.IsDir()}
+		parent.children = append(parent.children, node)
+		if !node.isDir {
+			fileCount++
+			return nil
+		}
+		dirCount++
+
+		rel, err := filepath.Rel(rootPath, path)
+		if err != nil {
+			return err
+		}
+		if depth := strings.Count(filepath.ToSlash(rel), "/") + 1; depth >= maxDepth {
+			return filepath.SkipDir
+		}
+		nodes[path] = node
+		return nil
+	})
+	if walkErr != nil {
+		return Result{}, fmt.Errorf("tree walk: %w", walkErr)
+	}
+
+	lines := []string{strings.TrimSuffix(filepath.ToSlash(pathArg), "/") + "/"}
+	lines = appendTreeLines(lines, root, 0)
+	rawOutput := strings.Join(lines, "\n")
+	truncation := truncateHead(rawOutput, truncationOptions{MaxLines: maxIntValue, MaxBytes: defaultMaxBytes})
+	output := truncation.Content
+
+	detailsPayload := map[string]any{
+		"directories": dirCount,
+		"files":       fileCount,
+		"max_depth":   maxDepth,
+	}
+	if truncation.Truncated {
+		output += fmt.Sprintf("\n\n[%s limit reached. Use a deeper path or smaller maxDepth]", formatSize(defaultMaxBytes))
+		detailsPayload["truncation"] = truncation
+	}
+
+	details, _ := json.Marshal(detailsPayload)
+	return Result{
+		Content: output,
+		Display: DisplayData{
+			Type:    treeDisplayTypeKey,
+			Payload: details,
+		},
+	}, nil
+}
+
+// appendTreeLines renders node's children, directories first, each level indented one step.
+func appendTreeLines(lines []string, node *treeNode, depth int) []string {
+	sort.Slice(node.children, func(i, j int) bool {
+		a, b := node.children[i], node.children[j]
+		if a.isDir != b.isDir {
+			return a.isDir
+		}
+		return strings.ToLower(a.name) < strings.ToLower(b.name)
+	})
+
+	indent := strings.Repeat(treeIndent, depth+1)
+	for _, child := range node.children {
+		if child.isDir {
+			lines = append(lines, indent+child.name+"/")
+			lines = appendTreeLines(lines, child, depth+1)
+			continue
+		}
+		lines = append(lines, indent+child.name)
+	}
+	return lines
+}
@@ -0,0 +1,86 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTreeFixture(t *testing.T) string {
+	t.Helper()
+
+	workspace := t.TempDir()
+	for _, dir := range []string{"src/pkg/deep", "docs", ".git", "node_modules/lib"} {
+		if err := os.MkdirAll(filepath.Join(workspace, dir), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+	}
+	for _, file := range []string{"main.go", "README.md", "src/a.go", "src/pkg/b.go", "src/pkg/deep/c.go", "docs/guide.md", ".git/HEAD", "node_modules/lib/index.js"} {
+		if err := os.WriteFile(filepath.Join(workspace, file), []byte("x"), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	return workspace
+}
+
+func TestTreeToolIndentsDirectoriesFirst(t *testing.T) {
+	t.Parallel()
+
+	workspace := writeTreeFixture(t)
+	got, err := NewTreeToolAt(workspace).Execute(context.Background(), json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"./",
+		"  docs/",
+		"    guide.md",
+		"  src/",
+		"    pkg/",
+		"      deep/",
+		"      b.go",
+		"    a.go",
+		"  main.go",
+		"  README.md",
+	}, "\n")
+	if got.Content != want {
+		t.Fatalf("Execute().Content =\n%s\nwant\n%s", got.Content, want)
+	}
+	if got.Display.Type != "tree_result" {
+		t.Fatalf("Execute().Display.Type = %q, want tree_result", got.Display.Type)
+	}
+}
+
+func TestTreeToolHonorsMaxDepthAndPath(t *testing.T) {
+	t.Parallel()
+
+	workspace := writeTreeFixture(t)
+	tool := NewTreeToolAt(workspace)
+
+	got, err := tool.Execute(context.Background(), json.RawMessage(`{"maxDepth":1}`))
+	if err != nil {
+		t.Fatalf("Execute(maxDepth=1) error = %v", err)
+	}
+	if got.Content != "./\n  docs/\n  src/\n  main.go\n  README.md" {
+		t.Fatalf("Execute(maxDepth=1).Content = %q", got.Content)
+	}
+
+	got, err = tool.Execute(context.Background(), json.RawMessage(`{"path":"src","maxDepth":5}`))
+	if err != nil {
+		t.Fatalf("Execute(src) error = %v", err)
+	}
+	if got.Content != "src/\n  pkg/\n    deep/\n      c.go\n    b.go\n  a.go" {
+		t.Fatalf("Execute(src).Content = %q", got.Content)
+	}
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"maxDepth":0}`)); err == nil {
+		t.Fatalf("Execute(maxDepth=0) error = nil, want validation error")
+	}
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"main.go"}`)); err == nil {
+		t.Fatalf("Execute(file) error = nil, want not a directory")
+	}
+}
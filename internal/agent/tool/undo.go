@@ -0,0 +1,97 @@
+package tool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// maxUndoContentBytes caps the prior file content carried in a display
+// payload; larger files are modified without an undo snapshot.
+const maxUndoContentBytes = 256 * 1024
+
+const (
+	undoPathKey    = "file_path"
+	undoContentKey = "previous_content"
+	undoExistedKey = "previous_exists"
+)
+
+// UndoSnapshot is the pre-change state of a file reported by the edit,
+// multiedit, apply_patch, write, and delete tools in their display payload.
+type UndoSnapshot struct {
+	Path            string `json:"file_path"`
+	PreviousContent []byte `json:"previous_content"`
+	PreviousExists  bool   `json:"previous_exists"`
+}
+
+// withUndoSnapshot adds the fields UndoSnapshotFromPayload reads to payload.
+// path must be absolute so the snapshot can be restored from any directory.
+func withUndoSnapshot(payload map[string]any, path string, prior []byte, existed bool) map[string]any {
+	if len(prior) > maxUndoContentBytes {
+		return payload
+	}
+	payload[undoPathKey] = path
+	payload[undoContentKey] = prior
+	payload[undoExistedKey] = existed
+	return payload
+}
+
+// UndoSnapshotFromPayload extracts an undo snapshot from a tool display payload.
+func UndoSnapshotFromPayload(payload json.RawMessage) (UndoSnapshot, bool) {
+	if len(payload) == 0 {
+		return UndoSnapshot{}, false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return UndoSnapshot{}, false
+	}
+	if _, ok := fields[undoContentKey]; !ok {
+		return UndoSnapshot{}, false
+	}
+	var snapshot UndoSnapshot
+	if err := json.Unmarshal(payload, &snapshot); err != nil || snapshot.Path == "" {
+		return UndoSnapshot{}, false
+	}
+	return snapshot, true
+}
+
+// WithoutUndoSnapshot returns payload with the undo snapshot fields removed,
+// so prior file contents are not persisted alongside the result.
+func WithoutUndoSnapshot(payload json.RawMessage) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return payload
+	}
+	if _, ok := fields[undoContentKey]; !ok {
+		return payload
+	}
+	delete(fields, undoPathKey)
+	delete(fields, undoContentKey)
+	delete(fields, undoExistedKey)
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return payload
+	}
+	return stripped
+}
+
+// Restore writes the prior content back, or removes the file when the tool created it.
+func (u UndoSnapshot) Restore() error {
+	if !u.PreviousExists {
+		if err := os.Remove(u.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("remove %s: %w", u.Path, err)
+		}
+		return nil
+	}
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(u.Path); err == nil {
+		mode = info.Mode()
+	}
+	if err := os.WriteFile(u.Path, u.PreviousContent, mode); err != nil {
+		return fmt.Errorf("restore %s: %w", u.Path, err)
+	}
+	return nil
+}
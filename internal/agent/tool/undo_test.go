@@ -0,0 +1,85 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditUndoSnapshotRestoresOriginalBytes(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "main.go")
+	original := []byte("\ufeffpackage main\r\n\r\nfunc main() {}\r\n")
+	if err := os.WriteFile(path, original, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	result, err := NewEditToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"path":"main.go","oldText":"func main() {}","newText":"func main() { run() }"}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	snapshot, ok := UndoSnapshotFromPayload(result.Display.Payload)
+	if !ok {
+		t.Fatalf("UndoSnapshotFromPayload(%s) ok = false", result.Display.Payload)
+	}
+	if err := snapshot.Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(got, original) {
+		t.Fatalf("restored content = %q, want %q", got, original)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("restored mode = %v, %v, want 0600", info, err)
+	}
+}
+
+func TestWriteUndoSnapshotRemovesCreatedFile(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	result, err := NewWriteToolAt(workspace).Execute(context.Background(), json.RawMessage(`{"path":"new.txt","content":"hello"}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	snapshot, ok := UndoSnapshotFromPayload(result.Display.Payload)
+	if !ok || snapshot.PreviousExists {
+		t.Fatalf("UndoSnapshotFromPayload() = %#v, %v, want snapshot of missing file", snapshot, ok)
+	}
+	if err := snapshot.Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "new.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Stat() error = %v, want created file removed", err)
+	}
+}
+
+func TestWithoutUndoSnapshotKeepsDisplayFields(t *testing.T) {
+	t.Parallel()
+
+	payload, _ := json.Marshal(withUndoSnapshot(map[string]any{"diff": "+x"}, "/tmp/a", []byte("old"), true))
+	stripped := WithoutUndoSnapshot(payload)
+	if strings.Contains(string(stripped), "previous_content") || !strings.Contains(string(stripped), `"diff":"+x"`) {
+		t.Fatalf("WithoutUndoSnapshot() = %s, want diff only", stripped)
+	}
+	if _, ok := UndoSnapshotFromPayload(stripped); ok {
+		t.Fatal("UndoSnapshotFromPayload(stripped) ok = true, want false")
+	}
+
+	large, _ := json.Marshal(withUndoSnapshot(map[string]any{}, "/tmp/a", make([]byte, maxUndoContentBytes+1), true))
+	if _, ok := UndoSnapshotFromPayload(large); ok {
+		t.Fatal("UndoSnapshotFromPayload(large) ok = true, want oversized content skipped")
+	}
+}
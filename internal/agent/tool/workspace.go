@@ -10,6 +10,10 @@ import (
 
 var ErrPathOutsideWorkspace = errors.New("path is outside workspace")
 
+// savedOutputPatterns match the files gar saves full tool output to in the
+// system temp directory: bash output, and tool results the agent truncated.
+var savedOutputPatterns = []string{"gar-bash-*.log", "gar-tool-*.log"}
+
 func normalizeWorkspaceRoot(root string) (string, error) {
 	trimmed := strings.TrimSpace(root)
 	if trimmed == "" {
@@ -59,6 +63,48 @@ func resolveWorkspacePath(workspaceRoot, inputPath string, allowCreate bool) (st
 	return resolved, nil
 }
 
+// resolveReadPath resolves inputPath like resolveWorkspacePath but also
+// accepts full-output files gar saved in the system temp directory, so a
+// truncated result can be paged back with read.
+func resolveReadPath(workspaceRoot, inputPath string) (string, error) {
+	if path, ok := savedOutputPath(inputPath); ok {
+		return path, nil
+	}
+	return resolveWorkspacePath(workspaceRoot, inputPath, false)
+}
+
+// savedOutputPath reports whether inputPath names an existing saved-output
+// file directly inside the system temp directory.
+func savedOutputPath(inputPath string) (string, bool) {
+	rawPath := normalizeToolPathInput(inputPath)
+	if !filepath.IsAbs(rawPath) {
+		return "", false
+	}
+	matched := false
+	for _, pattern := range savedOutputPatterns {
+		if ok, _ := filepath.Match(pattern, filepath.Base(rawPath)); ok {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return "", false
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(rawPath))
+	if err != nil {
+		return "", false
+	}
+	tempDir, err := filepath.EvalSymlinks(os.TempDir())
+	if err != nil || filepath.Dir(resolved) != filepath.Clean(tempDir) || filepath.Base(resolved) != filepath.Base(rawPath) {
+		return "", false
+	}
+	info, err := os.Stat(resolved)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return resolved, true
+}
+
 func normalizeToolPathInput(path string) string {
 	trimmed := strings.TrimSpace(path)
 	normalizedSpaces := strings.Map(func(r rune) rune {
@@ -137,3 +183,34 @@ func isWithinWorkspace(root, target string) bool {
 	}
 	return !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
+
+// resolveWorkspaceEntry resolves inputPath like resolveWorkspacePath but
+// leaves the final element unresolved, so a symlink names the link itself
+// rather than its target. It also returns the normalized workspace root.
+func resolveWorkspaceEntry(workspaceRoot, inputPath string) (string, string, error) {
+	rawPath := normalizeToolPathInput(inputPath)
+	if strings.TrimSpace(rawPath) == "" {
+		return "", "", errors.New("path is required")
+	}
+
+	root, err := normalizeWorkspaceRoot(workspaceRoot)
+	if err != nil {
+		return "", "", err
+	}
+
+	candidate := rawPath
+	if !filepath.IsAbs(candidate) {
+		candidate = filepath.Join(root, candidate)
+	}
+	candidate = filepath.Clean(candidate)
+
+	parent, err := resolvePathWithOptionalMissing(filepath.Dir(candidate), true)
+	if err != nil {
+		return "", "", fmt.Errorf("resolve path %s: %w", rawPath, err)
+	}
+	resolved := filepath.Join(parent, filepath.Base(candidate))
+	if !isWithinWorkspace(root, resolved) {
+		return "", "", fmt.Errorf("%w: %s (workspace: %s)", ErrPathOutsideWorkspace, rawPath, root)
+	}
+	return root, resolved, nil
+}
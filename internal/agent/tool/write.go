@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -18,20 +19,22 @@ type WriteTool struct {
 }
 
 // NewWriteTool constructs the write tool.
-func NewWriteTool() WriteTool { return newWriteTool("") }
+func NewWriteTool() WriteTool { return NewWriteToolAt("") }
 
-func newWriteTool(workspaceRoot string) WriteTool {
+// NewWriteToolAt constructs the write tool sandboxed to workspaceRoot.
+// An empty root uses the current working directory.
+func NewWriteToolAt(workspaceRoot string) WriteTool {
 	return WriteTool{workspaceRoot: workspaceRoot}
 }
 
 func (WriteTool) Name() string { return writeToolName }
 
 func (WriteTool) Description() string {
-	return "Write content to a file. Creates the file if it doesn't exist, overwrites if it does. Automatically creates parent directories."
+	return "Write content to a file. Creates the file if it doesn't exist, overwrites if it does, or appends when append is true. Automatically creates parent directories."
 }
 
 func (WriteTool) Schema() json.RawMessage {
-	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're writing (shown to user)"},"path":{"type":"string","description":"Path to the file to write (relative or absolute)"},"content":{"type":"string","description":"Content to write to the file"}},"required":["label","path","content"]}`)
+	return json.RawMessage(`{"type":"object","properties":{"label":{"type":"string","description":"Brief description of what you're writing (shown to user)"},"path":{"type":"string","description":"Path to the file to write (relative or absolute)"},"content":{"type":"string","description":"Content to write to the file"},"append":{"type":"boolean","description":"Append to the end of the file instead of overwriting it"}},"required":["path","content"]}`)
 }
 
 func (w WriteTool) Execute(ctx context.Context, params json.RawMessage) (Result, error) {
@@ -45,6 +48,7 @@ func (w WriteTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 		Label   string `json:"label"`
 		Path    string `json:"path"`
 		Content string `json:"content"`
+		Append  bool   `json:"append"`
 	}
 	if err := decodeParams(params, &input); err != nil {
 		return Result{}, fmt.Errorf("decode write params: %w", err)
@@ -60,19 +64,45 @@ func (w WriteTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 		return Result{}, fmt.Errorf("resolve write path: %w", err)
 	}
 
+	prior, existed, err := readPriorContent(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("read %s: %w", pathArg, err)
+	}
+
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return Result{}, fmt.Errorf("mkdir parent for %s: %w", pathArg, err)
 	}
-	if err := os.WriteFile(path, []byte(input.Content), 0o644); err != nil {
-		return Result{}, fmt.Errorf("write %s: %w", pathArg, err)
+
+	action := "created"
+	updated := input.Content
+	switch {
+	case input.Append && existed:
+		action = "appended"
+		updated = prior + input.Content
+		if err := appendFile(path, input.Content); err != nil {
+			return Result{}, fmt.Errorf("append %s: %w", pathArg, err)
+		}
+	default:
+		mode := os.FileMode(0o644)
+		if existed {
+			action = "overwrote"
+			if info, statErr := os.Stat(path); statErr == nil {
+				mode = info.Mode()
+			}
+		}
+		if err := os.WriteFile(path, []byte(input.Content), mode); err != nil {
+			return Result{}, fmt.Errorf("write %s: %w", pathArg, err)
+		}
 	}
 
 	written := len([]byte(input.Content))
-	content := fmt.Sprintf("Successfully wrote %d bytes to %s", written, pathArg)
-	details, _ := json.Marshal(map[string]any{
-		"path":  pathArg,
-		"bytes": written,
-	})
+	content := fmt.Sprintf("Successfully wrote %d bytes to %s (%s)", written, pathArg, action)
+	details, _ := json.Marshal(withUndoSnapshot(map[string]any{
+		"path":   pathArg,
+		"bytes":  written,
+		"action": action,
+		"diff":   generateDiffString(prior, updated, 4),
+	}, path, []byte(prior), existed))
 	return Result{
 		Content: content,
 		Display: DisplayData{
@@ -81,3 +111,27 @@ func (w WriteTool) Execute(ctx context.Context, params json.RawMessage) (Result,
 		},
 	}, nil
 }
+
+// readPriorContent returns the current file content and whether the file exists.
+func readPriorContent(path string) (string, bool, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return string(raw), true, nil
+}
+
+func appendFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -15,7 +15,7 @@ func TestWriteToolWritesFileAndCreatesParent(t *testing.T) {
 	workspace := t.TempDir()
 	path := filepath.Join(workspace, "nested", "out.txt")
 
-	tool := newWriteTool(workspace)
+	tool := NewWriteToolAt(workspace)
 	got, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"nested/out.txt","content":"hello"}`))
 	if err != nil {
 		t.Fatalf("Execute() error = %v", err)
@@ -36,7 +36,7 @@ func TestWriteToolWritesFileAndCreatesParent(t *testing.T) {
 func TestWriteToolRequiresPath(t *testing.T) {
 	t.Parallel()
 
-	tool := newWriteTool(t.TempDir())
+	tool := NewWriteToolAt(t.TempDir())
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{"content":"x"}`))
 	if err == nil || !strings.Contains(err.Error(), "path") {
 		t.Fatalf("Execute() error = %v, want path validation error", err)
@@ -49,9 +49,101 @@ func TestWriteToolRejectsPathOutsideWorkspace(t *testing.T) {
 	workspace := t.TempDir()
 	outside := filepath.Join(t.TempDir(), "outside.txt")
 
-	tool := newWriteTool(workspace)
+	tool := NewWriteToolAt(workspace)
 	_, err := tool.Execute(context.Background(), json.RawMessage(`{"path":"`+outside+`","content":"x"}`))
 	if err == nil || !strings.Contains(strings.ToLower(err.Error()), "workspace") {
 		t.Fatalf("Execute() error = %v, want workspace restriction error", err)
 	}
 }
+
+func TestWriteToolReportsCreateOverwriteAndAppend(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		existing    *string
+		params      string
+		wantAction  string
+		wantContent string
+		wantDiff    []string
+	}{
+		{
+			name:        "create",
+			params:      `{"path":"out.txt","content":"one\n"}`,
+			wantAction:  "created",
+			wantContent: "one\n",
+			wantDiff:    []string{"+1 one"},
+		},
+		{
+			name:        "overwrite with diff",
+			existing:    ptrString("one\ntwo\n"),
+			params:      `{"path":"out.txt","content":"one\nthree\n"}`,
+			wantAction:  "overwrote",
+			wantContent: "one\nthree\n",
+			wantDiff:    []string{"-2 two", "+2 three"},
+		},
+		{
+			name:        "append",
+			existing:    ptrString("one\n"),
+			params:      `{"path":"out.txt","content":"two\n","append":true}`,
+			wantAction:  "appended",
+			wantContent: "one\ntwo\n",
+			wantDiff:    []string{"+2 two"},
+		},
+		{
+			name:        "append creates missing file",
+			params:      `{"path":"out.txt","content":"two\n","append":true}`,
+			wantAction:  "created",
+			wantContent: "two\n",
+			wantDiff:    []string{"+1 two"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			workspace := t.TempDir()
+			path := filepath.Join(workspace, "out.txt")
+			if tc.existing != nil {
+				if err := os.WriteFile(path, []byte(*tc.existing), 0o644); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+			}
+
+			got, err := NewWriteToolAt(workspace).Execute(context.Background(), json.RawMessage(tc.params))
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if !strings.Contains(got.Content, "("+tc.wantAction+")") {
+				t.Fatalf("Execute().Content = %q, want action %q", got.Content, tc.wantAction)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if string(raw) != tc.wantContent {
+				t.Fatalf("written content = %q, want %q", string(raw), tc.wantContent)
+			}
+
+			var details struct {
+				Action string `json:"action"`
+				Diff   string `json:"diff"`
+			}
+			if err := json.Unmarshal(got.Display.Payload, &details); err != nil {
+				t.Fatalf("Unmarshal(payload) error = %v", err)
+			}
+			if details.Action != tc.wantAction {
+				t.Fatalf("payload action = %q, want %q", details.Action, tc.wantAction)
+			}
+			for _, want := range tc.wantDiff {
+				if !strings.Contains(details.Diff, want) {
+					t.Fatalf("payload diff = %q, want substring %q", details.Diff, want)
+				}
+			}
+		})
+	}
+}
+
+func ptrString(s string) *string { return &s }
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"unicode/utf8"
+)
+
+// defaultMaxToolResultBytes bounds tool result content sent back to the model
+// when Config.MaxToolResultBytes is unset.
+const defaultMaxToolResultBytes = 10_000
+
+// Truncation selects which part of an oversized tool result is kept.
+type Truncation string
+
+const (
+	// TruncateHead keeps the beginning of the result.
+	TruncateHead Truncation = "head"
+	// TruncateTail keeps the end of the result.
+	TruncateTail Truncation = "tail"
+	// TruncateMiddleOut keeps both ends and elides the middle.
+	TruncateMiddleOut Truncation = "middle-out"
+)
+
+func normalizeTruncation(mode Truncation) (Truncation, error) {
+	switch mode {
+	case "":
+		return TruncateMiddleOut, nil
+	case TruncateHead, TruncateTail, TruncateMiddleOut:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidTruncation, mode)
+	}
+}
+
+// toolResultTruncator shortens tool results to a byte budget and saves the
+// full content to a file the elision note points at.
+type toolResultTruncator struct {
+	maxBytes int
+	mode     Truncation
+	byTool   map[string]Truncation
+	// dir receives full outputs; empty uses the system temp directory.
+	dir string
+}
+
+// truncate returns content unchanged when it fits, otherwise the part kept
+// by the tool's truncation mode with a note of how many bytes were elided.
+func (t toolResultTruncator) truncate(toolName, content string) string {
+	if len(content) <= t.maxBytes {
+		return content
+	}
+	mode := t.mode
+	if override, ok := t.byTool[toolName]; ok {
+		mode = override
+	}
+
+	var head, tail string
+	switch mode {
+	case TruncateHead:
+		head = content[:runeStartBefore(content, t.maxBytes)]
+	case TruncateTail:
+		tail = content[runeStartAfter(content, len(content)-t.maxBytes):]
+	default:
+		head = content[:runeStartBefore(content, t.maxBytes/2)]
+		tail = content[runeStartAfter(content, len(content)-t.maxBytes/2):]
+	}
+
+	note := fmt.Sprintf("[%d bytes truncated", len(content)-len(head)-len(tail))
+	if path, err := t.saveFullOutput(content); err == nil {
+		note += "; full output: " + path
+	}
+	note += "]"
+
+	switch {
+	case tail == "":
+		return head + "\n...\n" + note
+	case head == "":
+		return note + "\n...\n" + tail
+	default:
+		return head + "\n..." + note + "...\n" + tail
+	}
+}
+
+func (t toolResultTruncator) saveFullOutput(content string) (string, error) {
+	file, err := os.CreateTemp(t.dir, "gar-tool-*.log")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	if _, err := file.WriteString(content); err != nil {
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// runeStartBefore returns the largest rune boundary of s at or before i.
+func runeStartBefore(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+// runeStartAfter returns the smallest rune boundary of s at or after i.
+func runeStartAfter(s string, i int) int {
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var fullOutputPattern = regexp.MustCompile(`full output: (\S+)\]`)
+
+func TestToolResultTruncatorModes(t *testing.T) {
+	t.Parallel()
+
+	content := strings.Repeat("<", 10) + strings.Repeat("#", 80) + strings.Repeat(">", 10)
+	tests := []struct {
+		mode      Truncation
+		prefix    string
+		suffix    string
+		forbidden string
+	}{
+		{mode: TruncateHead, prefix: strings.Repeat("<", 10) + strings.Repeat("#", 10) + "\n...\n[80 bytes truncated", suffix: "]", forbidden: ">"},
+		{mode: TruncateTail, prefix: "[80 bytes truncated", suffix: "]\n...\n" + strings.Repeat("#", 10) + strings.Repeat(">", 10), forbidden: "<"},
+		{mode: TruncateMiddleOut, prefix: strings.Repeat("<", 10) + "\n...[80 bytes truncated", suffix: "]...\n" + strings.Repeat(">", 10), forbidden: "#"},
+	}
+	for _, tt := range tests {
+		truncator := toolResultTruncator{maxBytes: 20, mode: tt.mode, dir: t.TempDir()}
+		got := truncator.truncate("bash", content)
+		if !strings.HasPrefix(got, tt.prefix) || !strings.HasSuffix(got, tt.suffix) || strings.Contains(got, tt.forbidden) {
+			t.Fatalf("truncate(%s) = %q", tt.mode, got)
+		}
+		match := fullOutputPattern.FindStringSubmatch(got)
+		if match == nil {
+			t.Fatalf("truncate(%s) = %q, want a full output path", tt.mode, got)
+		}
+		saved, err := os.ReadFile(match[1])
+		if err != nil || string(saved) != content {
+			t.Fatalf("full output %s = %q (%v), want the whole content", match[1], saved, err)
+		}
+	}
+}
+
+func TestToolResultTruncatorLimitOverridesAndRunes(t *testing.T) {
+	t.Parallel()
+
+	truncator := toolResultTruncator{
+		maxBytes: 7,
+		mode:     TruncateMiddleOut,
+		byTool:   map[string]Truncation{"read": TruncateHead},
+		dir:      t.TempDir(),
+	}
+	if got := truncator.truncate("bash", "short"); got != "short" {
+		t.Fatalf("truncate(within limit) = %q, want unchanged", got)
+	}
+	// Each "é" is two bytes; cuts must not split one.
+	got := truncator.truncate("read", strings.Repeat("é", 10))
+	if !strings.HasPrefix(got, "ééé\n...\n[14 bytes truncated") {
+		t.Fatalf("truncate(read override) = %q, want 3 whole runes kept from the head", got)
+	}
+	got = truncator.truncate("bash", strings.Repeat("é", 10))
+	if !strings.HasPrefix(got, "é\n...[16 bytes truncated") || !strings.HasSuffix(got, "]...\né") {
+		t.Fatalf("truncate(middle-out) = %q, want whole runes on both ends", got)
+	}
+}
+
+func TestNormalizeTruncation(t *testing.T) {
+	t.Parallel()
+
+	if got, err := normalizeTruncation(""); err != nil || got != TruncateMiddleOut {
+		t.Fatalf("normalizeTruncation(\"\") = %q, %v, want middle-out", got, err)
+	}
+	if _, err := normalizeTruncation("middle"); err == nil {
+		t.Fatalf("normalizeTruncation(middle) error = nil, want ErrInvalidTruncation")
+	}
+}
@@ -2,14 +2,33 @@ package agentapp
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+
+	agentsession "gar/internal/agent/session"
+	agenttool "gar/internal/agent/tool"
+	"gar/internal/llm"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-// ExecuteSlashCommand parses and handles one slash command.
+// maxSearchResults caps how many /search hits are printed to the chat.
+const maxSearchResults = 20
+
+// defaultHistoryEntries is how many entries /history lists without an argument.
+const defaultHistoryEntries = 20
+
+// maxAttachmentBytes is the largest image /attach accepts, matching the
+// provider limit for base64 image blocks.
+const maxAttachmentBytes = 5 * 1024 * 1024
+
+// ExecuteSlashCommand parses one slash command and dispatches it through
+// env.Commands, or the built-in commands when env.Commands is nil.
 func ExecuteSlashCommand(content string, env CommandEnv) tea.Cmd {
 	if env.Session == nil {
 		appendError(env, "session is not initialized")
@@ -20,208 +39,723 @@ func ExecuteSlashCommand(content string, env CommandEnv) tea.Cmd {
 	if len(parts) == 0 {
 		return nil
 	}
-	command := strings.TrimPrefix(parts[0], "/")
-	args := parts[1:]
-
-	switch command {
-	case "help":
-		appendAssistant(env, strings.Join([]string{
-			"Slash commands:",
-			"/help",
-			"/session",
-			"/name <display-name>",
-			"/new",
-			"/resume [session-id|latest]",
-			"/tree [entry-id]",
-			"/branch <entry-id>",
-			"/fork <entry-id>",
-			"/compact [keep_messages]",
-			"/queue",
-			"/dequeue",
-		}, "\n"))
-	case "session":
-		stats := env.Session.Stats()
-		appendAssistant(env, fmt.Sprintf(
-			"session=%s name=%q leaf=%s entries=%d user=%d assistant=%d tool_calls=%d tool_results=%d queued=(steer:%d follow_up:%d)",
-			stats.SessionID,
-			stats.SessionName,
-			stats.LeafID,
-			stats.EntryCount,
-			stats.UserMessages,
-			stats.AssistantMsgs,
-			stats.ToolCalls,
-			stats.ToolResults,
-			stats.SteeringQueued,
-			stats.FollowUpQueued,
-		))
-	case "name":
-		if len(args) == 0 {
-			name := strings.TrimSpace(env.Session.SessionName())
-			if name == "" {
-				appendAssistant(env, "Session name is empty. Use /name <display-name>.")
-			} else {
-				appendAssistant(env, fmt.Sprintf("Session name: %q", name))
-			}
-			return nil
-		}
-		name := strings.TrimSpace(strings.Join(args, " "))
-		if name == "-" {
-			name = ""
-		}
-		if err := env.Session.SetSessionName(context.Background(), name); err != nil {
-			appendError(env, err.Error())
-			return nil
+	name := strings.TrimPrefix(parts[0], "/")
+	text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(content), parts[0]))
+
+	cmd, ok := commandsFor(env).Lookup(name)
+	if !ok {
+		appendError(env, "unknown slash command: /"+name)
+		return nil
+	}
+	return cmd.Handler(env, parts[1:], text)
+}
+
+// DefaultCommands returns a registry holding the built-in slash commands.
+func DefaultCommands() *CommandRegistry {
+	registry := NewCommandRegistry()
+	for _, cmd := range []Command{
+		{Name: "help", Description: "List slash commands", Handler: runHelp},
+		{Name: "session", Description: "Show session statistics", Handler: runSession},
+		{Name: "usage", Description: "Show token usage and cost", Handler: runUsage},
+		{Name: "name", ArgHint: "<display-name>", Description: "Show or set the session name", Handler: runName},
+		{Name: "model", ArgHint: "[name]", Description: "Show or switch the model", Handler: runModel},
+		{Name: "tools", ArgHint: "[auto|none|any|<tool>] [sticky]", Description: "Show or set how the model may use tools next turn", Handler: runTools},
+		{Name: "plan", ArgHint: "[on|off]", Description: "Show or toggle plan mode, which allows only read-only tools", Handler: runPlan},
+		{Name: "system", ArgHint: "[text|-]", Description: "Show, set, or clear the system prompt", Handler: runSystem},
+		{Name: "new", Description: "Start a new session", Handler: runNew},
+		{Name: "clear", Description: "Clear the chat view, keeping the session", Handler: runClear},
+		{Name: "resume", ArgHint: "[session-id|latest]", Description: "Resume a saved session", Handler: runResume},
+		{Name: "search", ArgHint: "<query|re:pattern>", Description: "Search saved sessions", Handler: runSearch},
+		{Name: "history", ArgHint: "[n]", Description: "List the newest session entries", Handler: runHistory},
+		{Name: "tree", ArgHint: "[entry-id]", Description: "Browse or switch session branches", Handler: runTree},
+		{Name: "branch", ArgHint: "<entry-id>", Description: "Switch to a branch", Handler: runBranch},
+		{Name: "fork", ArgHint: "<entry-id> [new-id]", Description: "Copy the branch up to an entry into a new session", Handler: runFork},
+		{Name: "bookmark", ArgHint: "[name]", Description: "List bookmarks or bookmark the current entry", Handler: runBookmark},
+		{Name: "goto", ArgHint: "<bookmark>", Description: "Switch to a bookmarked entry", Handler: runGoto},
+		{Name: "compact", ArgHint: "[preview] [keep_messages]", Description: "Summarize older messages, or preview what would be dropped", Handler: runCompact},
+		{Name: "summary", Description: "Ask the model for a bullet summary of the session, leaving history unchanged", Handler: runSummary},
+		{Name: "retry", Description: "Rerun the last turn", Handler: runRetry},
+		{Name: "edit-last", Description: "Edit and resend the last message", Handler: runEditLast},
+		{Name: "undo", Description: "Revert the last file change", Handler: runUndo},
+		{Name: "diff", Description: "Show changes to files modified this session", Handler: runDiff},
+		{Name: "attach", ArgHint: "<image-path>", Description: "Attach an image to the next message", Handler: runAttach},
+		{Name: "queue", Description: "List queued messages", Handler: runQueue},
+		{Name: "dequeue", ArgHint: "[n]", Description: "Move queued messages back to the input, or cancel message n", Handler: runDequeue},
+	} {
+		if err := registry.Register(cmd); err != nil {
+			panic(err)
 		}
+	}
+	return registry
+}
+
+func commandsFor(env CommandEnv) *CommandRegistry {
+	if env.Commands != nil {
+		return env.Commands
+	}
+	return DefaultCommands()
+}
+
+func runHelp(env CommandEnv, _ []string, _ string) tea.Cmd {
+	commands := commandsFor(env).Commands()
+	width := 0
+	for _, cmd := range commands {
+		width = max(width, len(cmd.Usage()))
+	}
+	lines := []string{"Slash commands:"}
+	for _, cmd := range commands {
+		lines = append(lines, strings.TrimRight(fmt.Sprintf("%-*s  %s", width, cmd.Usage(), cmd.Description), " "))
+	}
+	appendAssistant(env, strings.Join(lines, "\n"))
+	return nil
+}
+
+func runSession(env CommandEnv, _ []string, _ string) tea.Cmd {
+	stats := env.Session.Stats()
+	appendAssistant(env, fmt.Sprintf(
+		"session=%s name=%q leaf=%s entries=%d user=%d assistant=%d tool_calls=%d tool_results=%d queued=(steer:%d follow_up:%d)",
+		stats.SessionID,
+		stats.SessionName,
+		stats.LeafID,
+		stats.EntryCount,
+		stats.UserMessages,
+		stats.AssistantMsgs,
+		stats.ToolCalls,
+		stats.ToolResults,
+		stats.SteeringQueued,
+		stats.FollowUpQueued,
+	))
+	return nil
+}
+
+func runUsage(env CommandEnv, _ []string, _ string) tea.Cmd {
+	usage := env.Session.UsageTotals()
+	appendAssistant(env, fmt.Sprintf(
+		"usage input=%d output=%d cache_read=%d cache_write=%d total=%d cost=$%.4f",
+		usage.InputTokens,
+		usage.OutputTokens,
+		usage.CacheReadTokens,
+		usage.CacheWriteTokens,
+		usage.TokenCount(),
+		usage.CostUSD,
+	))
+	return nil
+}
+
+func runName(env CommandEnv, args []string, _ string) tea.Cmd {
+	if len(args) == 0 {
+		name := strings.TrimSpace(env.Session.SessionName())
 		if name == "" {
-			appendAssistant(env, "Session name cleared.")
+			appendAssistant(env, "Session name is empty. Use /name <display-name>.")
 		} else {
-			appendAssistant(env, fmt.Sprintf("Session name set to %q.", name))
+			appendAssistant(env, fmt.Sprintf("Session name: %q", name))
 		}
-	case "new":
-		if env.ActiveStream {
-			appendError(env, "cannot create new session while agent is running")
-			return nil
-		}
-		id, err := env.Session.NewSession(context.Background(), "")
-		if err != nil {
-			appendError(env, err.Error())
-			return nil
+		return nil
+	}
+	name := strings.TrimSpace(strings.Join(args, " "))
+	if name == "-" {
+		name = ""
+	}
+	if err := env.Session.SetSessionName(context.Background(), name); err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	if name == "" {
+		appendAssistant(env, "Session name cleared.")
+	} else {
+		appendAssistant(env, fmt.Sprintf("Session name set to %q.", name))
+	}
+	return nil
+}
+
+func runModel(env CommandEnv, args []string, _ string) tea.Cmd {
+	if len(args) == 0 {
+		appendAssistant(env, fmt.Sprintf("Current model: %s", env.Session.Model()))
+		return nil
+	}
+	if len(args) != 1 {
+		appendError(env, "usage: /model [name]")
+		return nil
+	}
+	if err := env.Session.SetModel(context.Background(), args[0]); err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	refreshStatus(env)
+	appendAssistant(env, fmt.Sprintf("Model set to %s.", env.Session.Model()))
+	return nil
+}
+
+func runTools(env CommandEnv, args []string, _ string) tea.Cmd {
+	if len(args) == 0 {
+		choice, sticky := env.Session.ToolChoice()
+		scope := "next turn"
+		if sticky {
+			scope = "every turn"
 		}
-		rebuildChat(env)
-		refreshStatus(env)
-		appendAssistant(env, "Started new session "+id+".")
-	case "resume":
-		if env.ActiveStream {
-			appendError(env, "cannot resume session while agent is running")
-			return nil
+		appendAssistant(env, fmt.Sprintf("Tool choice: %s (%s). Tools: %s", describeToolChoice(choice), scope, strings.Join(env.Session.ToolNames(), ", ")))
+		return nil
+	}
+	if len(args) > 2 || (len(args) == 2 && args[1] != "sticky") {
+		appendError(env, "usage: /tools [auto|none|any|<tool>] [sticky]")
+		return nil
+	}
+
+	var choice llm.ToolChoice
+	switch args[0] {
+	case "auto", "none", "any":
+		choice.Type = llm.ToolChoiceType(args[0])
+	default:
+		choice = llm.ToolChoice{Type: llm.ToolChoiceTool, Name: args[0]}
+	}
+	sticky := len(args) == 2
+	if err := env.Session.SetToolChoice(choice, sticky); err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	switch {
+	case choice.Type == llm.ToolChoiceAuto:
+		appendAssistant(env, "Tool choice reset to auto.")
+	case sticky:
+		appendAssistant(env, fmt.Sprintf("Tool choice set to %s for every turn.", describeToolChoice(choice)))
+	default:
+		appendAssistant(env, fmt.Sprintf("Tool choice set to %s for the next turn.", describeToolChoice(choice)))
+	}
+	return nil
+}
+
+func runPlan(env CommandEnv, args []string, _ string) tea.Cmd {
+	if len(args) == 0 {
+		state := "off"
+		if env.Session.PlanMode() {
+			state = "on"
 		}
-		if len(args) == 0 {
-			if env.OpenResumeSelector == nil {
-				appendError(env, "resume selector is not available")
-				return nil
-			}
-			return env.OpenResumeSelector()
+		appendAssistant(env, "Plan mode is "+state+".")
+		return nil
+	}
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+		appendError(env, "usage: /plan [on|off]")
+		return nil
+	}
+	enabled := args[0] == "on"
+	if err := env.Session.SetPlanMode(enabled); err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	refreshStatus(env)
+	if enabled {
+		appendAssistant(env, "Plan mode on: only read-only tools are available.")
+	} else {
+		appendAssistant(env, "Plan mode off: all tools are available.")
+	}
+	return nil
+}
+
+// describeToolChoice names a tool choice as /tools accepts it.
+func describeToolChoice(choice llm.ToolChoice) string {
+	switch choice.Type {
+	case "":
+		return string(llm.ToolChoiceAuto)
+	case llm.ToolChoiceTool:
+		return choice.Name
+	default:
+		return string(choice.Type)
+	}
+}
+
+func runSystem(env CommandEnv, args []string, text string) tea.Cmd {
+	if len(args) == 0 {
+		prompt := env.Session.SystemPrompt()
+		if prompt == "" {
+			appendAssistant(env, "System prompt is empty. Use /system <text>.")
+		} else {
+			appendAssistant(env, "System prompt:\n"+prompt)
 		}
+		return nil
+	}
+	prompt := text
+	if prompt == "-" {
+		prompt = ""
+	}
+	if err := env.Session.SetSystemPrompt(context.Background(), prompt); err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	if prompt == "" {
+		appendAssistant(env, "System prompt cleared.")
+	} else {
+		appendAssistant(env, "System prompt set.")
+	}
+	return nil
+}
 
-		targetID := strings.TrimSpace(args[0])
-		if strings.EqualFold(targetID, "latest") {
-			infos, err := env.Session.ListSessions(context.Background())
-			if err != nil {
-				appendError(env, err.Error())
-				return nil
-			}
-			if len(infos) == 0 {
-				appendAssistant(env, "No sessions found.")
-				return nil
-			}
-			current := env.Session.SessionID()
-			targetID = infos[0].ID
-			for _, info := range infos {
-				if info.ID != current {
-					targetID = info.ID
-					break
-				}
-			}
+func runNew(env CommandEnv, _ []string, _ string) tea.Cmd {
+	if env.ActiveStream {
+		appendError(env, "cannot create new session while agent is running")
+		return nil
+	}
+	id, err := env.Session.NewSession(context.Background(), "")
+	if err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	rebuildChat(env)
+	refreshStatus(env)
+	appendAssistant(env, "Started new session "+id+".")
+	return nil
+}
+
+func runClear(env CommandEnv, _ []string, _ string) tea.Cmd {
+	if env.ActiveStream {
+		appendError(env, "cannot clear chat while agent is running")
+		return nil
+	}
+	if env.ClearChat == nil {
+		appendError(env, "clearing the chat is not available")
+		return nil
+	}
+	env.ClearChat()
+	appendAssistant(env, "Chat cleared. The session and its context are unchanged.")
+	return nil
+}
+
+func runResume(env CommandEnv, args []string, _ string) tea.Cmd {
+	if env.ActiveStream {
+		appendError(env, "cannot resume session while agent is running")
+		return nil
+	}
+	if len(args) == 0 {
+		if env.OpenResumeSelector == nil {
+			appendError(env, "resume selector is not available")
+			return nil
 		}
-		if err := env.Session.SwitchSession(context.Background(), targetID); err != nil {
+		return env.OpenResumeSelector()
+	}
+
+	targetID := strings.TrimSpace(args[0])
+	if strings.EqualFold(targetID, "latest") {
+		infos, err := env.Session.ListSessions(context.Background())
+		if err != nil {
 			appendError(env, err.Error())
 			return nil
 		}
-		rebuildChat(env)
-		refreshStatus(env)
-		appendAssistant(env, "Resumed session "+targetID+".")
-	case "tree":
-		if env.ActiveStream {
-			appendError(env, "cannot switch branch while agent is running")
+		if len(infos) == 0 {
+			appendAssistant(env, "No sessions found.")
 			return nil
 		}
-		if len(args) == 0 {
-			if env.OpenTreeSelector == nil {
-				appendError(env, "tree selector is not available")
-				return nil
+		current := env.Session.SessionID()
+		targetID = infos[0].ID
+		for _, info := range infos {
+			if info.ID != current {
+				targetID = info.ID
+				break
 			}
-			return env.OpenTreeSelector()
 		}
-		if len(args) != 1 {
-			appendError(env, "usage: /tree [entry-id]")
-			return nil
+	}
+	if err := env.Session.SwitchSession(context.Background(), targetID); err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	rebuildChat(env)
+	refreshStatus(env)
+	appendAssistant(env, "Resumed session "+targetID+".")
+	if warning := env.Session.LoadWarning(); warning != "" {
+		appendAssistant(env, warning)
+	}
+	return nil
+}
+
+func runSearch(env CommandEnv, _ []string, query string) tea.Cmd {
+	if query == "" {
+		appendError(env, "usage: /search <query|re:pattern>")
+		return nil
+	}
+	hits, err := env.Session.SearchSessions(context.Background(), query)
+	if err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	if len(hits) == 0 {
+		appendAssistant(env, fmt.Sprintf("No matches for %q.", query))
+		return nil
+	}
+	lines := []string{fmt.Sprintf("Found %d matches for %q:", len(hits), query)}
+	for i, hit := range hits {
+		if i == maxSearchResults {
+			lines = append(lines, fmt.Sprintf("... %d more", len(hits)-maxSearchResults))
+			break
 		}
-		if err := env.Session.SwitchBranch(args[0]); err != nil {
-			appendError(env, err.Error())
+		lines = append(lines, fmt.Sprintf("%s %s [%s] %s", hit.SessionID, hit.EntryID, hit.Type, hit.Snippet))
+	}
+	lines = append(lines, "Use /resume <session-id> to open a session.")
+	appendAssistant(env, strings.Join(lines, "\n"))
+	return nil
+}
+
+func runHistory(env CommandEnv, args []string, _ string) tea.Cmd {
+	limit := defaultHistoryEntries
+	if len(args) > 1 {
+		appendError(env, "usage: /history [n]")
+		return nil
+	}
+	if len(args) == 1 {
+		parsed, err := strconv.Atoi(args[0])
+		if err != nil || parsed <= 0 {
+			appendError(env, "usage: /history [n]")
 			return nil
 		}
-		rebuildChat(env)
-		appendAssistant(env, "Switched branch to "+args[0]+".")
-	case "branch", "fork":
-		if env.ActiveStream {
-			appendError(env, "cannot switch branch while agent is running")
+		limit = parsed
+	}
+	entries := env.Session.Entries()
+	if len(entries) == 0 {
+		appendAssistant(env, "No session entries yet.")
+		return nil
+	}
+	agentsession.SortEntriesByTimestampDesc(entries)
+	shown := entries[:min(limit, len(entries))]
+	lines := []string{fmt.Sprintf("Last %d of %d entries, newest first:", len(shown), len(entries))}
+	for _, entry := range shown {
+		lines = append(lines, fmt.Sprintf("  %s %s %s", entry.ID, formatEntryTime(entry.TS), agentsession.EntryPreview(entry)))
+	}
+	appendAssistant(env, strings.Join(lines, "\n"))
+	return nil
+}
+
+// formatEntryTime renders a session entry's Unix timestamp in local time.
+func formatEntryTime(ts int64) string {
+	if ts <= 0 {
+		return "-"
+	}
+	return time.Unix(ts, 0).Format("2006-01-02 15:04:05")
+}
+
+func runTree(env CommandEnv, args []string, _ string) tea.Cmd {
+	if env.ActiveStream {
+		appendError(env, "cannot switch branch while agent is running")
+		return nil
+	}
+	if len(args) == 0 {
+		if env.OpenTreeSelector == nil {
+			appendError(env, "tree selector is not available")
 			return nil
 		}
-		if len(args) != 1 {
-			appendError(env, "usage: /branch <entry-id>")
+		return env.OpenTreeSelector()
+	}
+	if len(args) != 1 {
+		appendError(env, "usage: /tree [entry-id]")
+		return nil
+	}
+	if err := env.Session.SwitchBranch(args[0]); err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	rebuildChat(env)
+	appendAssistant(env, "Switched branch to "+args[0]+".")
+	return nil
+}
+
+func runBranch(env CommandEnv, args []string, _ string) tea.Cmd {
+	if env.ActiveStream {
+		appendError(env, "cannot switch branch while agent is running")
+		return nil
+	}
+	if len(args) != 1 {
+		appendError(env, "usage: /branch <entry-id>")
+		return nil
+	}
+	if err := env.Session.SwitchBranch(args[0]); err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	rebuildChat(env)
+	appendAssistant(env, "Switched branch to "+args[0]+".")
+	return nil
+}
+
+func runFork(env CommandEnv, args []string, _ string) tea.Cmd {
+	if env.ActiveStream {
+		appendError(env, "cannot fork session while agent is running")
+		return nil
+	}
+	if len(args) < 1 || len(args) > 2 {
+		appendError(env, "usage: /fork <entry-id> [new-id]")
+		return nil
+	}
+	newID := ""
+	if len(args) == 2 {
+		newID = args[1]
+	}
+	id, err := env.Session.ForkSession(context.Background(), args[0], newID)
+	if err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	rebuildChat(env)
+	refreshStatus(env)
+	appendAssistant(env, fmt.Sprintf("Forked session %s from entry %s.", id, args[0]))
+	return nil
+}
+
+func runBookmark(env CommandEnv, args []string, _ string) tea.Cmd {
+	if len(args) == 0 {
+		bookmarks := env.Session.Bookmarks()
+		if len(bookmarks) == 0 {
+			appendAssistant(env, "No bookmarks.")
 			return nil
 		}
-		if err := env.Session.SwitchBranch(args[0]); err != nil {
-			appendError(env, err.Error())
-			return nil
+		lines := []string{"Bookmarks:"}
+		for _, bookmark := range bookmarks {
+			lines = append(lines, fmt.Sprintf("  %s -> %s", bookmark.Name, bookmark.EntryID))
 		}
-		rebuildChat(env)
-		appendAssistant(env, "Switched branch to "+args[0]+".")
-	case "compact":
-		if env.ActiveStream {
-			appendError(env, "cannot compact while agent is running")
+		appendAssistant(env, strings.Join(lines, "\n"))
+		return nil
+	}
+	name := strings.Join(args, " ")
+	leaf := env.Session.Stats().LeafID
+	if err := env.Session.AddBookmark(context.Background(), name); err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	refreshStatus(env)
+	appendAssistant(env, fmt.Sprintf("Bookmarked %s as %q.", leaf, name))
+	return nil
+}
+
+func runGoto(env CommandEnv, args []string, _ string) tea.Cmd {
+	if env.ActiveStream {
+		appendError(env, "cannot switch branch while agent is running")
+		return nil
+	}
+	if len(args) == 0 {
+		appendError(env, "usage: /goto <bookmark>")
+		return nil
+	}
+	target, err := env.Session.GotoBookmark(strings.Join(args, " "))
+	if err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	rebuildChat(env)
+	appendAssistant(env, "Switched branch to "+target+".")
+	return nil
+}
+
+func runCompact(env CommandEnv, args []string, _ string) tea.Cmd {
+	if env.ActiveStream {
+		appendError(env, "cannot compact while agent is running")
+		return nil
+	}
+	preview := len(args) > 0 && (args[0] == "preview" || args[0] == "--dry-run")
+	if preview {
+		args = args[1:]
+	}
+	keep := 0
+	if len(args) > 0 {
+		parsed, err := strconv.Atoi(args[0])
+		if err != nil {
+			appendError(env, "usage: /compact [preview] [keep_messages]")
 			return nil
 		}
-		keep := 0
-		if len(args) > 0 {
-			parsed, err := strconv.Atoi(args[0])
-			if err != nil {
-				appendError(env, "usage: /compact [keep_messages]")
-				return nil
-			}
-			keep = parsed
-		}
-		result, err := env.Session.Compact(context.Background(), keep, "")
+		keep = parsed
+	}
+	if preview {
+		result, err := env.Session.PreviewCompaction(keep, "")
 		if err != nil {
 			appendError(env, err.Error())
 			return nil
 		}
-		rebuildChat(env)
-		appendAssistant(env, fmt.Sprintf("Compaction completed. Dropped %d messages.", result.DroppedMessages))
-	case "queue":
-		steering := env.Session.SteeringQueued()
-		followUp := env.Session.FollowUpQueued()
-		if len(steering) == 0 && len(followUp) == 0 {
-			appendAssistant(env, "No queued messages.")
-			return nil
-		}
-		lines := make([]string, 0, len(steering)+len(followUp)+2)
-		lines = append(lines, "Queued messages:")
-		for _, message := range steering {
-			lines = append(lines, "- steer: "+message)
-		}
-		for _, message := range followUp {
-			lines = append(lines, "- follow-up: "+message)
-		}
-		appendAssistant(env, strings.Join(lines, "\n"))
-	case "dequeue":
-		steering, followUp := env.Session.ClearQueue()
-		all := append(append([]string(nil), steering...), followUp...)
-		if len(all) == 0 {
-			appendAssistant(env, "No queued messages to restore.")
-			return nil
-		}
-		prefix := strings.Join(all, "\n\n")
-		current := strings.TrimSpace(getInputValue(env))
-		if current != "" {
-			prefix = prefix + "\n\n" + current
-		}
-		setInputValue(env, prefix)
-		appendAssistant(env, fmt.Sprintf("Restored %d queued messages to input.", len(all)))
-	default:
-		appendError(env, "unknown slash command: /"+command)
+		appendAssistant(env, fmt.Sprintf("Compaction would drop %d messages.\n\n%s", result.DroppedMessages, result.Summary))
+		return nil
+	}
+	result, err := env.Session.Compact(context.Background(), keep, "")
+	if err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	rebuildChat(env)
+	appendAssistant(env, fmt.Sprintf("Compaction completed. Dropped %d messages.", result.DroppedMessages))
+	return nil
+}
+
+func runSummary(env CommandEnv, _ []string, _ string) tea.Cmd {
+	if env.ActiveStream {
+		appendError(env, "cannot summarize while agent is running")
+		return nil
+	}
+	return runTask(env, "summarizing", env.Session.Summarize)
+}
+
+func runRetry(env CommandEnv, _ []string, _ string) tea.Cmd {
+	if env.ActiveStream {
+		appendError(env, "cannot retry while agent is running")
+		return nil
+	}
+	if env.StartRun == nil {
+		appendError(env, "retry is not available")
+		return nil
+	}
+	if _, err := env.Session.RewindToLastUser(); err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	rebuildChat(env)
+	appendAssistant(env, "Retrying last turn.")
+	return env.StartRun()
+}
+
+func runEditLast(env CommandEnv, _ []string, _ string) tea.Cmd {
+	if env.ActiveStream {
+		appendError(env, "cannot edit while agent is running")
+		return nil
+	}
+	text, err := env.Session.RewindBeforeLastUser()
+	if err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	rebuildChat(env)
+	refreshStatus(env)
+	setInputValue(env, text)
+	appendAssistant(env, "Editing last message. Press Enter to resend it on a new branch.")
+	return nil
+}
+
+func runUndo(env CommandEnv, _ []string, _ string) tea.Cmd {
+	if env.ActiveStream {
+		appendError(env, "cannot undo while agent is running")
+		return nil
+	}
+	result, err := env.Session.Undo()
+	if err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	if result.Removed {
+		appendAssistant(env, fmt.Sprintf("Undid %s: removed %s.", result.ToolName, result.Path))
+		return nil
+	}
+	appendAssistant(env, fmt.Sprintf("Undid %s: restored %s.", result.ToolName, result.Path))
+	return nil
+}
+
+func runDiff(env CommandEnv, _ []string, _ string) tea.Cmd {
+	changes := env.Session.ModifiedFiles()
+	if len(changes) == 0 {
+		appendAssistant(env, "No files modified in this session.")
+		return nil
+	}
+	diff, err := agenttool.ChangesDiff(context.Background(), changes)
+	if err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	if diff == "" {
+		appendAssistant(env, fmt.Sprintf("No uncommitted changes in the %d file(s) modified this session.", len(changes)))
+		return nil
+	}
+	appendAssistant(env, fmt.Sprintf("Changes to %d file(s) modified this session:\n%s", len(changes), diff))
+	return nil
+}
+
+func runAttach(env CommandEnv, args []string, _ string) tea.Cmd {
+	if len(args) == 0 {
+		appendError(env, "usage: /attach <image-path>")
+		return nil
+	}
+	path := strings.Join(args, " ")
+	mediaType, data, err := readImageAttachment(path)
+	if err != nil {
+		appendError(env, err.Error())
+		return nil
 	}
+	pending, err := env.Session.AttachImage(mediaType, data)
+	if err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	appendAssistant(env, fmt.Sprintf("Attached %s (%s). %d image(s) will be sent with your next message.", filepath.Base(path), mediaType, pending))
+	return nil
+}
 
+func runQueue(env CommandEnv, _ []string, _ string) tea.Cmd {
+	steering := env.Session.SteeringQueued()
+	followUp := env.Session.FollowUpQueued()
+	if len(steering) == 0 && len(followUp) == 0 {
+		appendAssistant(env, "No queued messages.")
+		return nil
+	}
+	lines := make([]string, 0, len(steering)+len(followUp)+2)
+	lines = append(lines, "Queued messages:")
+	for i, message := range steering {
+		lines = append(lines, fmt.Sprintf("%d. steer: %s", i+1, message))
+	}
+	for i, message := range followUp {
+		lines = append(lines, fmt.Sprintf("%d. follow-up: %s", len(steering)+i+1, message))
+	}
+	lines = append(lines, "Use /dequeue <n> to cancel one message.")
+	appendAssistant(env, strings.Join(lines, "\n"))
+	return nil
+}
+
+func runDequeue(env CommandEnv, args []string, _ string) tea.Cmd {
+	if len(args) > 0 {
+		return cancelQueued(env, args)
+	}
+	steering, followUp := env.Session.ClearQueue()
+	all := append(append([]string(nil), steering...), followUp...)
+	if len(all) == 0 {
+		appendAssistant(env, "No queued messages to restore.")
+		return nil
+	}
+	prefix := strings.Join(all, "\n\n")
+	current := strings.TrimSpace(getInputValue(env))
+	if current != "" {
+		prefix = prefix + "\n\n" + current
+	}
+	setInputValue(env, prefix)
+	appendAssistant(env, fmt.Sprintf("Restored %d queued messages to input.", len(all)))
+	return nil
+}
+
+// cancelQueued removes message n as numbered by /queue: steering messages
+// first, then follow-ups.
+func cancelQueued(env CommandEnv, args []string) tea.Cmd {
+	n, err := strconv.Atoi(args[0])
+	if len(args) != 1 || err != nil || n <= 0 {
+		appendError(env, "usage: /dequeue [n]")
+		return nil
+	}
+	steering := env.Session.SteeringQueued()
+	followUp := env.Session.FollowUpQueued()
+	if total := len(steering) + len(followUp); n > total {
+		appendError(env, fmt.Sprintf("no queued message %d (%d queued)", n, total))
+		return nil
+	}
+	kind, index, label := agentsession.QueueSteering, n-1, "steer"
+	if index >= len(steering) {
+		kind, index, label = agentsession.QueueFollowUp, index-len(steering), "follow-up"
+	}
+	removed, err := env.Session.CancelQueued(kind, index)
+	if err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	appendAssistant(env, fmt.Sprintf("Cancelled queued %s: %s", label, removed))
+	return nil
+}
+
+// runTask hands work to env.RunTask, or runs it inline and shows the result
+// when the UI has no background tasks.
+func runTask(env CommandEnv, label string, work func(ctx context.Context) (string, error)) tea.Cmd {
+	if env.RunTask != nil {
+		return env.RunTask(label, work)
+	}
+	text, err := work(context.Background())
+	if err != nil {
+		appendError(env, err.Error())
+		return nil
+	}
+	appendAssistant(env, text)
 	return nil
 }
 
@@ -243,6 +777,27 @@ func rebuildChat(env CommandEnv) {
 	}
 }
 
+// readImageAttachment reads a supported image file and returns its media type
+// and base64-encoded contents.
+func readImageAttachment(path string) (string, string, error) {
+	mediaType, ok := agenttool.ImageMediaType(path)
+	if !ok {
+		return "", "", fmt.Errorf("unsupported image type %q (want jpg, png, gif, or webp)", filepath.Ext(path))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", "", fmt.Errorf("attach %s: %w", path, err)
+	}
+	if info.Size() > maxAttachmentBytes {
+		return "", "", fmt.Errorf("attach %s: image is %d bytes, limit is %d", path, info.Size(), maxAttachmentBytes)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("attach %s: %w", path, err)
+	}
+	return mediaType, base64.StdEncoding.EncodeToString(raw), nil
+}
+
 func refreshStatus(env CommandEnv) {
 	if env.RefreshSessionStatus != nil {
 		env.RefreshSessionStatus()
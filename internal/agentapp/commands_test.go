@@ -2,38 +2,107 @@ package agentapp
 
 import (
 	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	agentsession "gar/internal/agent/session"
+	agenttool "gar/internal/agent/tool"
+	"gar/internal/llm"
 	sessionstore "gar/internal/session"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 type fakeSession struct {
 	stats     agentsession.Stats
 	name      string
 	sessionID string
+	model     string
+	system    string
 
 	newSessionID string
 	switchID     string
 	branchID     string
+	forkFrom     string
 
 	listInfos []sessionstore.SessionInfo
 
+	searchQuery string
+	searchHits  []sessionstore.SearchHit
+
 	compactResult agentsession.CompactionResult
+	compactCalls  int
+	previewKeep   int
+	usageTotals   llm.Usage
+
+	rewindCount int
+	rewindErr   error
+
+	bookmarks []agentsession.Bookmark
+
+	undoResults []agentsession.UndoResult
+	modified    []agenttool.UndoSnapshot
+
+	attached []string
+
+	loadWarning string
 
 	steering []string
 	followUp []string
+
+	toolChoice       llm.ToolChoice
+	toolChoiceSticky bool
+
+	planMode bool
+
+	entries []sessionstore.Entry
+
+	summary    string
+	summaryErr error
 }
 
 func (f *fakeSession) Stats() agentsession.Stats { return f.stats }
+func (f *fakeSession) UsageTotals() llm.Usage    { return f.usageTotals }
 func (f *fakeSession) SessionName() string       { return f.name }
 func (f *fakeSession) SetSessionName(ctx context.Context, name string) error {
 	_ = ctx
 	f.name = strings.TrimSpace(name)
 	return nil
 }
+func (f *fakeSession) Model() string { return f.model }
+func (f *fakeSession) ToolChoice() (llm.ToolChoice, bool) {
+	return f.toolChoice, f.toolChoiceSticky
+}
+func (f *fakeSession) SetToolChoice(choice llm.ToolChoice, sticky bool) error {
+	f.toolChoice = choice
+	f.toolChoiceSticky = sticky
+	return nil
+}
+func (f *fakeSession) ToolNames() []string { return []string{"read", "bash"} }
+func (f *fakeSession) PlanMode() bool      { return f.planMode }
+func (f *fakeSession) SetPlanMode(enabled bool) error {
+	f.planMode = enabled
+	return nil
+}
+func (f *fakeSession) SetModel(ctx context.Context, name string) error {
+	_ = ctx
+	trimmed := strings.TrimSpace(name)
+	if trimmed == "" {
+		return agentsession.ErrModelRequired
+	}
+	f.model = trimmed
+	return nil
+}
+func (f *fakeSession) SystemPrompt() string { return f.system }
+func (f *fakeSession) SetSystemPrompt(ctx context.Context, text string) error {
+	_ = ctx
+	f.system = strings.TrimSpace(text)
+	return nil
+}
 func (f *fakeSession) NewSession(ctx context.Context, requestedID string) (string, error) {
 	_ = ctx
 	if strings.TrimSpace(requestedID) != "" {
@@ -48,6 +117,11 @@ func (f *fakeSession) ListSessions(ctx context.Context) ([]sessionstore.SessionI
 	_ = ctx
 	return append([]sessionstore.SessionInfo(nil), f.listInfos...), nil
 }
+func (f *fakeSession) SearchSessions(ctx context.Context, query string) ([]sessionstore.SearchHit, error) {
+	_ = ctx
+	f.searchQuery = query
+	return append([]sessionstore.SearchHit(nil), f.searchHits...), nil
+}
 func (f *fakeSession) SessionID() string { return f.sessionID }
 func (f *fakeSession) SwitchSession(ctx context.Context, sessionID string) error {
 	_ = ctx
@@ -55,21 +129,98 @@ func (f *fakeSession) SwitchSession(ctx context.Context, sessionID string) error
 	f.sessionID = f.switchID
 	return nil
 }
+func (f *fakeSession) LoadWarning() string { return f.loadWarning }
+func (f *fakeSession) Entries() []sessionstore.Entry {
+	return append([]sessionstore.Entry(nil), f.entries...)
+}
 func (f *fakeSession) SwitchBranch(targetID string) error {
 	f.branchID = strings.TrimSpace(targetID)
 	return nil
 }
+func (f *fakeSession) ForkSession(ctx context.Context, entryID, newID string) (string, error) {
+	_ = ctx
+	f.forkFrom = strings.TrimSpace(entryID)
+	f.sessionID = strings.TrimSpace(newID)
+	if f.sessionID == "" {
+		f.sessionID = "forked"
+	}
+	return f.sessionID, nil
+}
+func (f *fakeSession) AddBookmark(ctx context.Context, name string) error {
+	_ = ctx
+	f.bookmarks = append(f.bookmarks, agentsession.Bookmark{Name: strings.TrimSpace(name), EntryID: f.stats.LeafID})
+	return nil
+}
+func (f *fakeSession) Bookmarks() []agentsession.Bookmark { return f.bookmarks }
+func (f *fakeSession) GotoBookmark(name string) (string, error) {
+	for _, bookmark := range f.bookmarks {
+		if bookmark.Name == name {
+			f.branchID = bookmark.EntryID
+			return bookmark.EntryID, nil
+		}
+	}
+	return "", agentsession.ErrBookmarkNotFound
+}
+func (f *fakeSession) Undo() (agentsession.UndoResult, error) {
+	if len(f.undoResults) == 0 {
+		return agentsession.UndoResult{}, agentsession.ErrNothingToUndo
+	}
+	result := f.undoResults[len(f.undoResults)-1]
+	f.undoResults = f.undoResults[:len(f.undoResults)-1]
+	return result, nil
+}
+func (f *fakeSession) ModifiedFiles() []agenttool.UndoSnapshot {
+	return append([]agenttool.UndoSnapshot(nil), f.modified...)
+}
+func (f *fakeSession) AttachImage(mediaType, data string) (int, error) {
+	f.attached = append(f.attached, mediaType+":"+data)
+	return len(f.attached), nil
+}
+func (f *fakeSession) RewindToLastUser() (string, error) {
+	f.rewindCount++
+	return "last", f.rewindErr
+}
+func (f *fakeSession) RewindBeforeLastUser() (string, error) {
+	f.rewindCount++
+	if f.rewindErr != nil {
+		return "", f.rewindErr
+	}
+	f.branchID = "parent"
+	return "last prompt", nil
+}
 func (f *fakeSession) Compact(ctx context.Context, keepMessages int, instructions string) (agentsession.CompactionResult, error) {
 	_ = ctx
 	_ = keepMessages
 	_ = instructions
+	f.compactCalls++
 	if f.compactResult.DroppedMessages == 0 {
 		f.compactResult.DroppedMessages = 1
 	}
 	return f.compactResult, nil
 }
+func (f *fakeSession) PreviewCompaction(keepMessages int, instructions string) (agentsession.CompactionResult, error) {
+	_ = instructions
+	f.previewKeep = keepMessages
+	return agentsession.CompactionResult{Summary: "[Context Compact Summary]", DroppedMessages: 4}, nil
+}
+func (f *fakeSession) Summarize(ctx context.Context) (string, error) {
+	_ = ctx
+	return f.summary, f.summaryErr
+}
 func (f *fakeSession) SteeringQueued() []string { return append([]string(nil), f.steering...) }
 func (f *fakeSession) FollowUpQueued() []string { return append([]string(nil), f.followUp...) }
+func (f *fakeSession) CancelQueued(kind string, index int) (string, error) {
+	queue := &f.steering
+	if kind == agentsession.QueueFollowUp {
+		queue = &f.followUp
+	}
+	if index < 0 || index >= len(*queue) {
+		return "", agentsession.ErrQueuedNotFound
+	}
+	removed := (*queue)[index]
+	*queue = append((*queue)[:index:index], (*queue)[index+1:]...)
+	return removed, nil
+}
 func (f *fakeSession) ClearQueue() (steering []string, followUp []string) {
 	steering = append([]string(nil), f.steering...)
 	followUp = append([]string(nil), f.followUp...)
@@ -97,6 +248,68 @@ func TestExecuteSlashCommandHelp(t *testing.T) {
 	}
 }
 
+func TestExecuteSlashCommandToolsSetsToolChoice(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{}
+	var assistant, errs []string
+	env := CommandEnv{
+		Session: session,
+		AppendAssistant: func(text string) {
+			assistant = append(assistant, text)
+		},
+		AppendError: func(errText string) {
+			errs = append(errs, errText)
+		},
+	}
+
+	_ = ExecuteSlashCommand("/tools none", env)
+	if session.toolChoice.Type != llm.ToolChoiceNone || session.toolChoiceSticky {
+		t.Fatalf("tool choice = %#v sticky=%v, want none for the next turn", session.toolChoice, session.toolChoiceSticky)
+	}
+	_ = ExecuteSlashCommand("/tools bash sticky", env)
+	want := llm.ToolChoice{Type: llm.ToolChoiceTool, Name: "bash"}
+	if session.toolChoice != want || !session.toolChoiceSticky {
+		t.Fatalf("tool choice = %#v sticky=%v, want sticky bash", session.toolChoice, session.toolChoiceSticky)
+	}
+	_ = ExecuteSlashCommand("/tools", env)
+	if last := assistant[len(assistant)-1]; !strings.Contains(last, "bash (every turn)") {
+		t.Fatalf("/tools output = %q, want current sticky choice", last)
+	}
+	_ = ExecuteSlashCommand("/tools bash forever", env)
+	if len(errs) != 1 || !strings.Contains(errs[0], "usage: /tools") {
+		t.Fatalf("errors = %#v, want usage error", errs)
+	}
+}
+
+func TestExecuteSlashCommandPlanTogglesPlanMode(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{}
+	var assistant []string
+	refreshed := 0
+	env := CommandEnv{
+		Session:              session,
+		RefreshSessionStatus: func() { refreshed++ },
+		AppendAssistant: func(text string) {
+			assistant = append(assistant, text)
+		},
+	}
+
+	_ = ExecuteSlashCommand("/plan on", env)
+	if !session.planMode || refreshed != 1 {
+		t.Fatalf("planMode = %v, refreshed = %d; want plan mode on and status refreshed", session.planMode, refreshed)
+	}
+	_ = ExecuteSlashCommand("/plan", env)
+	if last := assistant[len(assistant)-1]; last != "Plan mode is on." {
+		t.Fatalf("/plan output = %q, want plan mode on", last)
+	}
+	_ = ExecuteSlashCommand("/plan off", env)
+	if session.planMode {
+		t.Fatalf("planMode = true after /plan off")
+	}
+}
+
 func TestExecuteSlashCommandResumeLatestChoosesNonCurrent(t *testing.T) {
 	t.Parallel()
 
@@ -129,6 +342,44 @@ func TestExecuteSlashCommandResumeLatestChoosesNonCurrent(t *testing.T) {
 	}
 }
 
+func TestExecuteSlashCommandHistoryListsNewestFirst(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{entries: []sessionstore.Entry{
+		{ID: "000001", Type: "user", TS: 100, Content: "first question"},
+		{ID: "000002", Type: "assistant", TS: 200, Content: "first answer"},
+		{ID: "000003", Type: "tool_call", TS: 200, Name: "read"},
+		{ID: "000004", Type: "bookmark", TS: 300, Name: "checkpoint"},
+	}}
+	var assistant []string
+	var errs []string
+	env := CommandEnv{
+		Session:         session,
+		AppendAssistant: func(text string) { assistant = append(assistant, text) },
+		AppendError:     func(errText string) { errs = append(errs, errText) },
+	}
+
+	_ = ExecuteSlashCommand("/history 3", env)
+	if len(errs) != 0 || len(assistant) != 1 {
+		t.Fatalf("assistant = %v errors = %v, want one history message", assistant, errs)
+	}
+	stamp := func(ts int64) string { return time.Unix(ts, 0).Format("2006-01-02 15:04:05") }
+	want := strings.Join([]string{
+		"Last 3 of 4 entries, newest first:",
+		"  000004 " + stamp(300) + " bookmark checkpoint",
+		"  000003 " + stamp(200) + " tool_call read",
+		"  000002 " + stamp(200) + " assistant first answer",
+	}, "\n")
+	if assistant[0] != want {
+		t.Fatalf("history = %q, want %q", assistant[0], want)
+	}
+
+	_ = ExecuteSlashCommand("/history 0", env)
+	if len(errs) != 1 || !strings.Contains(errs[0], "usage: /history") {
+		t.Fatalf("errors = %v, want usage error", errs)
+	}
+}
+
 func TestExecuteSlashCommandTreeWithArgSwitchesBranch(t *testing.T) {
 	t.Parallel()
 
@@ -150,6 +401,228 @@ func TestExecuteSlashCommandTreeWithArgSwitchesBranch(t *testing.T) {
 	}
 }
 
+func TestExecuteSlashCommandForkCreatesSession(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{sessionID: "original"}
+	var messages []string
+	_ = ExecuteSlashCommand("/fork 000004 experiment", CommandEnv{
+		Session:         session,
+		AppendAssistant: func(text string) { messages = append(messages, text) },
+	})
+
+	if session.forkFrom != "000004" || session.sessionID != "experiment" {
+		t.Fatalf("fork = (%q, %q), want (000004, experiment)", session.forkFrom, session.sessionID)
+	}
+	if session.branchID != "" {
+		t.Fatalf("branchID = %q, want /fork not to switch branches in place", session.branchID)
+	}
+	if len(messages) != 1 || !strings.Contains(messages[0], "experiment") {
+		t.Fatalf("messages = %v, want fork confirmation", messages)
+	}
+}
+
+func TestExecuteSlashCommandBookmarkAndGoto(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{stats: agentsession.Stats{LeafID: "000004"}}
+	var assistant []string
+	var errs []string
+	var rebuildCount int
+	env := CommandEnv{
+		Session:                session,
+		RebuildChatFromSession: func() { rebuildCount++ },
+		AppendAssistant:        func(text string) { assistant = append(assistant, text) },
+		AppendError:            func(errText string) { errs = append(errs, errText) },
+	}
+
+	_ = ExecuteSlashCommand("/bookmark before refactor", env)
+	if len(session.bookmarks) != 1 || session.bookmarks[0] != (agentsession.Bookmark{Name: "before refactor", EntryID: "000004"}) {
+		t.Fatalf("bookmarks = %#v, want current leaf bookmarked", session.bookmarks)
+	}
+
+	_ = ExecuteSlashCommand("/bookmark", env)
+	if got := assistant[len(assistant)-1]; !strings.Contains(got, "before refactor -> 000004") {
+		t.Fatalf("bookmark list = %q, want bookmark listed", got)
+	}
+
+	_ = ExecuteSlashCommand("/goto before refactor", env)
+	if session.branchID != "000004" || rebuildCount != 1 {
+		t.Fatalf("branchID = %q rebuilds = %d, want switch to 000004", session.branchID, rebuildCount)
+	}
+
+	_ = ExecuteSlashCommand("/goto missing", env)
+	if len(errs) != 1 || !strings.Contains(errs[0], "bookmark not found") {
+		t.Fatalf("errors = %v, want bookmark not found", errs)
+	}
+}
+
+func TestExecuteSlashCommandUndo(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{undoResults: []agentsession.UndoResult{{ToolName: "edit", Path: "/repo/main.go"}}}
+	var assistant []string
+	var errs []string
+	env := CommandEnv{
+		Session:         session,
+		AppendAssistant: func(text string) { assistant = append(assistant, text) },
+		AppendError:     func(errText string) { errs = append(errs, errText) },
+	}
+
+	_ = ExecuteSlashCommand("/undo", env)
+	if len(assistant) != 1 || assistant[0] != "Undid edit: restored /repo/main.go." {
+		t.Fatalf("assistant = %v, want restore report", assistant)
+	}
+	_ = ExecuteSlashCommand("/undo", env)
+	if len(errs) != 1 || errs[0] != agentsession.ErrNothingToUndo.Error() {
+		t.Fatalf("errors = %v, want nothing to undo", errs)
+	}
+}
+
+func TestExecuteSlashCommandCompactPreviewDoesNotCompact(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{}
+	var assistant []string
+	env := CommandEnv{
+		Session:         session,
+		AppendAssistant: func(text string) { assistant = append(assistant, text) },
+	}
+
+	_ = ExecuteSlashCommand("/compact preview 3", env)
+	if session.compactCalls != 0 {
+		t.Fatalf("compact calls = %d, want 0 for preview", session.compactCalls)
+	}
+	if session.previewKeep != 3 {
+		t.Fatalf("preview keep = %d, want 3", session.previewKeep)
+	}
+	if len(assistant) != 1 || !strings.HasPrefix(assistant[0], "Compaction would drop 4 messages.") || !strings.Contains(assistant[0], "[Context Compact Summary]") {
+		t.Fatalf("assistant = %v, want preview count and summary", assistant)
+	}
+
+	_ = ExecuteSlashCommand("/compact --dry-run", env)
+	if session.compactCalls != 0 || len(assistant) != 2 {
+		t.Fatalf("compact calls = %d, assistant = %v, want --dry-run preview", session.compactCalls, assistant)
+	}
+}
+
+func TestExecuteSlashCommandSummaryAppendsReply(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{summary: "- fixed the retry test"}
+	var assistant, errs []string
+	env := CommandEnv{
+		Session:         session,
+		AppendAssistant: func(text string) { assistant = append(assistant, text) },
+		AppendError:     func(text string) { errs = append(errs, text) },
+	}
+
+	_ = ExecuteSlashCommand("/summary", env)
+	if len(assistant) != 1 || assistant[0] != "- fixed the retry test" || len(errs) != 0 {
+		t.Fatalf("assistant = %v, errors = %v, want the summary", assistant, errs)
+	}
+
+	env.ActiveStream = true
+	_ = ExecuteSlashCommand("/summary", env)
+	if len(assistant) != 1 || len(errs) != 1 || !strings.Contains(errs[0], "while agent is running") {
+		t.Fatalf("assistant = %v, errors = %v, want refusal during a stream", assistant, errs)
+	}
+
+	env.ActiveStream = false
+	session.summaryErr = agentsession.ErrNothingToSummarize
+	_ = ExecuteSlashCommand("/summary", env)
+	if len(assistant) != 1 || len(errs) != 2 || errs[1] != agentsession.ErrNothingToSummarize.Error() {
+		t.Fatalf("assistant = %v, errors = %v, want the summarize error", assistant, errs)
+	}
+
+	// With background tasks the summary is handed off instead of run inline.
+	var label string
+	env.RunTask = func(taskLabel string, work func(ctx context.Context) (string, error)) tea.Cmd {
+		label = taskLabel
+		return func() tea.Msg { return nil }
+	}
+	if cmd := ExecuteSlashCommand("/summary", env); cmd == nil || label != "summarizing" || len(errs) != 2 {
+		t.Fatalf("RunTask label = %q, errors = %v; want /summary handed to RunTask", label, errs)
+	}
+}
+
+func TestExecuteSlashCommandDiffShowsEditedFile(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "notes.txt")
+	if err := os.WriteFile(path, []byte("before\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	result, err := agenttool.NewEditToolAt(workspace).Execute(context.Background(), []byte(`{"path":"notes.txt","oldText":"before","newText":"after"}`))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	snapshot, ok := agenttool.UndoSnapshotFromPayload(result.Display.Payload)
+	if !ok {
+		t.Fatalf("UndoSnapshotFromPayload() ok = false, want snapshot")
+	}
+
+	session := &fakeSession{}
+	var assistant []string
+	env := CommandEnv{
+		Session:         session,
+		AppendAssistant: func(text string) { assistant = append(assistant, text) },
+	}
+
+	_ = ExecuteSlashCommand("/diff", env)
+	if len(assistant) != 1 || assistant[0] != "No files modified in this session." {
+		t.Fatalf("assistant = %v, want no modified files", assistant)
+	}
+
+	session.modified = []agenttool.UndoSnapshot{snapshot}
+	_ = ExecuteSlashCommand("/diff", env)
+	if len(assistant) != 2 {
+		t.Fatalf("assistant = %v, want diff output", assistant)
+	}
+	out := assistant[1]
+	if !strings.Contains(out, path) || !strings.Contains(out, "-1 before") || !strings.Contains(out, "+1 after") {
+		t.Fatalf("diff output = %q, want change to %s", out, path)
+	}
+}
+
+func TestExecuteSlashCommandAttach(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "shot.png")
+	if err := os.WriteFile(imagePath, []byte("png-bytes"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	textPath := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(textPath, []byte("text"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	session := &fakeSession{}
+	var assistant []string
+	var errs []string
+	env := CommandEnv{
+		Session:         session,
+		AppendAssistant: func(text string) { assistant = append(assistant, text) },
+		AppendError:     func(errText string) { errs = append(errs, errText) },
+	}
+
+	_ = ExecuteSlashCommand("/attach "+imagePath, env)
+	want := "image/png:" + base64.StdEncoding.EncodeToString([]byte("png-bytes"))
+	if len(session.attached) != 1 || session.attached[0] != want {
+		t.Fatalf("attached = %v, want %q", session.attached, want)
+	}
+	if len(assistant) != 1 || !strings.Contains(assistant[0], "Attached shot.png (image/png)") {
+		t.Fatalf("assistant = %v, want attach confirmation", assistant)
+	}
+
+	_ = ExecuteSlashCommand("/attach "+textPath, env)
+	if len(session.attached) != 1 || len(errs) != 1 || !strings.Contains(errs[0], "unsupported image type") {
+		t.Fatalf("attached = %v errors = %v, want unsupported type rejected", session.attached, errs)
+	}
+}
+
 func TestExecuteSlashCommandQueueAndDequeue(t *testing.T) {
 	t.Parallel()
 
@@ -191,6 +664,40 @@ func TestExecuteSlashCommandQueueAndDequeue(t *testing.T) {
 	}
 }
 
+func TestExecuteSlashCommandDequeueCancelsOneMessage(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{
+		steering: []string{"s1", "s2"},
+		followUp: []string{"f1", "f2", "f3"},
+	}
+	var assistant []string
+	var errs []string
+	env := CommandEnv{
+		Session:         session,
+		AppendAssistant: func(text string) { assistant = append(assistant, text) },
+		AppendError:     func(errText string) { errs = append(errs, errText) },
+	}
+
+	_ = ExecuteSlashCommand("/queue", env)
+	if len(assistant) != 1 || !strings.Contains(assistant[0], "2. steer: s2") || !strings.Contains(assistant[0], "4. follow-up: f2") {
+		t.Fatalf("queue output = %#v, want numbered queue", assistant)
+	}
+
+	_ = ExecuteSlashCommand("/dequeue 4", env)
+	if got := assistant[len(assistant)-1]; got != "Cancelled queued follow-up: f2" {
+		t.Fatalf("dequeue output = %q, want cancelled f2", got)
+	}
+	if strings.Join(session.steering, ",") != "s1,s2" || strings.Join(session.followUp, ",") != "f1,f3" {
+		t.Fatalf("queues = %v / %v, want s1,s2 / f1,f3", session.steering, session.followUp)
+	}
+
+	_ = ExecuteSlashCommand("/dequeue 5", env)
+	if len(errs) != 1 || !strings.Contains(errs[0], "no queued message 5") {
+		t.Fatalf("errors = %v, want missing message error", errs)
+	}
+}
+
 func TestExecuteSlashCommandUnknownReturnsError(t *testing.T) {
 	t.Parallel()
 
@@ -207,3 +714,216 @@ func TestExecuteSlashCommandUnknownReturnsError(t *testing.T) {
 		t.Fatalf("errText = %q, want unknown slash command", errText)
 	}
 }
+
+func TestExecuteSlashCommandRetry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		activeStream bool
+		rewindErr    error
+		wantRewind   int
+		wantRuns     int
+		wantError    string
+	}{
+		{name: "rewinds and runs", wantRewind: 1, wantRuns: 1},
+		{name: "refuses while streaming", activeStream: true, wantError: "cannot retry while agent is running"},
+		{name: "no user message", rewindErr: agentsession.ErrNoUserMessage, wantRewind: 1, wantError: "no user message"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			session := &fakeSession{rewindErr: tc.rewindErr}
+			var runs, rebuilds int
+			var errs []string
+			_ = ExecuteSlashCommand("/retry", CommandEnv{
+				Session:      session,
+				ActiveStream: tc.activeStream,
+				StartRun: func() tea.Cmd {
+					runs++
+					return nil
+				},
+				RebuildChatFromSession: func() {
+					rebuilds++
+				},
+				AppendError: func(errText string) {
+					errs = append(errs, errText)
+				},
+			})
+
+			if session.rewindCount != tc.wantRewind || runs != tc.wantRuns || rebuilds != tc.wantRuns {
+				t.Fatalf("rewind=%d runs=%d rebuilds=%d, want %d/%d/%d", session.rewindCount, runs, rebuilds, tc.wantRewind, tc.wantRuns, tc.wantRuns)
+			}
+			if tc.wantError == "" && len(errs) != 0 {
+				t.Fatalf("errors = %#v, want none", errs)
+			}
+			if tc.wantError != "" && (len(errs) != 1 || !strings.Contains(errs[0], tc.wantError)) {
+				t.Fatalf("errors = %#v, want %q", errs, tc.wantError)
+			}
+		})
+	}
+}
+
+func TestExecuteSlashCommandEditLast(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{}
+	var input string
+	var rebuilds int
+	var errs []string
+	env := CommandEnv{
+		Session:                session,
+		SetInputValue:          func(value string) { input = value },
+		RebuildChatFromSession: func() { rebuilds++ },
+		AppendAssistant:        func(string) {},
+		AppendError:            func(errText string) { errs = append(errs, errText) },
+	}
+
+	_ = ExecuteSlashCommand("/edit-last", env)
+	if input != "last prompt" || session.branchID != "parent" || rebuilds != 1 || len(errs) != 0 {
+		t.Fatalf("input=%q leaf=%q rebuilds=%d errs=%v, want input restored and leaf moved", input, session.branchID, rebuilds, errs)
+	}
+
+	streaming := &fakeSession{}
+	env.Session = streaming
+	env.ActiveStream = true
+	_ = ExecuteSlashCommand("/edit-last", env)
+	if streaming.rewindCount != 0 || len(errs) != 1 || !strings.Contains(errs[0], "cannot edit while agent is running") {
+		t.Fatalf("rewind=%d errs=%v, want refusal while streaming", streaming.rewindCount, errs)
+	}
+}
+
+func TestExecuteSlashCommandUsage(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{usageTotals: llm.Usage{
+		InputTokens:  1200,
+		OutputTokens: 300,
+		CostUSD:      0.0123,
+	}}
+	var assistant []string
+	_ = ExecuteSlashCommand("/usage", CommandEnv{
+		Session: session,
+		AppendAssistant: func(text string) {
+			assistant = append(assistant, text)
+		},
+	})
+
+	want := "usage input=1200 output=300 cache_read=0 cache_write=0 total=1500 cost=$0.0123"
+	if len(assistant) != 1 || assistant[0] != want {
+		t.Fatalf("assistant output = %#v, want %q", assistant, want)
+	}
+}
+
+func TestExecuteSlashCommandSearch(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{searchHits: []sessionstore.SearchHit{
+		{SessionID: "s1", EntryID: "e2", Type: "user", Snippet: "parser panics on empty input"},
+	}}
+	var assistant []string
+	var errs []string
+	env := CommandEnv{
+		Session: session,
+		AppendAssistant: func(text string) {
+			assistant = append(assistant, text)
+		},
+		AppendError: func(errText string) {
+			errs = append(errs, errText)
+		},
+	}
+
+	_ = ExecuteSlashCommand(`/search re:parser\s+panics`, env)
+	if session.searchQuery != `re:parser\s+panics` {
+		t.Fatalf("search query = %q, want raw regex query", session.searchQuery)
+	}
+	if len(assistant) != 1 ||
+		!strings.Contains(assistant[0], "s1 e2 [user] parser panics on empty input") ||
+		!strings.Contains(assistant[0], "/resume <session-id>") {
+		t.Fatalf("assistant output = %#v, want hit line and resume hint", assistant)
+	}
+
+	_ = ExecuteSlashCommand("/search", env)
+	if len(errs) != 1 || !strings.Contains(errs[0], "usage: /search") {
+		t.Fatalf("errors = %#v, want usage error", errs)
+	}
+}
+
+func TestExecuteSlashCommandModel(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{model: "big-model"}
+	var assistant []string
+	var errs []string
+	refreshed := 0
+	env := CommandEnv{
+		Session: session,
+		RefreshSessionStatus: func() {
+			refreshed++
+		},
+		AppendAssistant: func(text string) {
+			assistant = append(assistant, text)
+		},
+		AppendError: func(errText string) {
+			errs = append(errs, errText)
+		},
+	}
+
+	_ = ExecuteSlashCommand("/model", env)
+	if len(assistant) != 1 || assistant[0] != "Current model: big-model" {
+		t.Fatalf("assistant output = %#v, want current model", assistant)
+	}
+
+	_ = ExecuteSlashCommand("/model small-model", env)
+	if session.model != "small-model" {
+		t.Fatalf("session model = %q, want small-model", session.model)
+	}
+	if refreshed != 1 {
+		t.Fatalf("status refreshed %d times, want 1", refreshed)
+	}
+	if len(assistant) != 2 || assistant[1] != "Model set to small-model." {
+		t.Fatalf("assistant output = %#v, want model switch confirmation", assistant)
+	}
+
+	_ = ExecuteSlashCommand("/model a b", env)
+	if len(errs) != 1 || !strings.Contains(errs[0], "usage: /model") {
+		t.Fatalf("errors = %#v, want usage error", errs)
+	}
+}
+
+func TestExecuteSlashCommandSystem(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{}
+	var assistant []string
+	env := CommandEnv{
+		Session: session,
+		AppendAssistant: func(text string) {
+			assistant = append(assistant, text)
+		},
+	}
+
+	_ = ExecuteSlashCommand("/system", env)
+	_ = ExecuteSlashCommand("/system Prefer   table-driven tests.", env)
+	if session.system != "Prefer   table-driven tests." {
+		t.Fatalf("system prompt = %q, want raw text after command", session.system)
+	}
+	_ = ExecuteSlashCommand("/system", env)
+	_ = ExecuteSlashCommand("/system -", env)
+	if session.system != "" {
+		t.Fatalf("system prompt = %q, want cleared", session.system)
+	}
+
+	want := []string{
+		"System prompt is empty. Use /system <text>.",
+		"System prompt set.",
+		"System prompt:\nPrefer   table-driven tests.",
+		"System prompt cleared.",
+	}
+	if strings.Join(assistant, "|") != strings.Join(want, "|") {
+		t.Fatalf("assistant output = %#v, want %#v", assistant, want)
+	}
+}
@@ -0,0 +1,96 @@
+package agentapp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ErrCommandNameRequired is returned when registering a command without a name.
+var ErrCommandNameRequired = errors.New("command name is required")
+
+// CommandHandler runs one slash command. args are the whitespace-separated
+// arguments and text is the raw argument text after the command name.
+type CommandHandler func(env CommandEnv, args []string, text string) tea.Cmd
+
+// Command is one slash command dispatched by ExecuteSlashCommand.
+type Command struct {
+	// Name is the command without its leading slash.
+	Name string
+	// Aliases are extra names that dispatch to the same handler.
+	Aliases     []string
+	ArgHint     string
+	Description string
+	Handler     CommandHandler
+}
+
+// Usage returns the command as listed by /help, e.g. "/model [name]".
+func (c Command) Usage() string {
+	if c.ArgHint == "" {
+		return "/" + c.Name
+	}
+	return "/" + c.Name + " " + c.ArgHint
+}
+
+// CommandRegistry holds slash commands in registration order.
+type CommandRegistry struct {
+	commands []Command
+	byName   map[string]int
+}
+
+// NewCommandRegistry constructs an empty registry.
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{byName: make(map[string]int)}
+}
+
+// Register adds cmd. Names and aliases must be unique across the registry.
+func (r *CommandRegistry) Register(cmd Command) error {
+	cmd.Name = strings.TrimPrefix(strings.TrimSpace(cmd.Name), "/")
+	if cmd.Name == "" {
+		return ErrCommandNameRequired
+	}
+	if cmd.Handler == nil {
+		return fmt.Errorf("command /%s: handler is required", cmd.Name)
+	}
+	names := append([]string{cmd.Name}, cmd.Aliases...)
+	for _, name := range names {
+		if _, ok := r.byName[name]; ok {
+			return fmt.Errorf("command /%s is already registered", name)
+		}
+	}
+	for _, name := range names {
+		r.byName[name] = len(r.commands)
+	}
+	cmd.Aliases = append([]string(nil), cmd.Aliases...)
+	r.commands = append(r.commands, cmd)
+	return nil
+}
+
+// Lookup finds a command by name or alias.
+func (r *CommandRegistry) Lookup(name string) (Command, bool) {
+	i, ok := r.byName[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return Command{}, false
+	}
+	return r.commands[i], true
+}
+
+// Commands returns the registered commands in registration order.
+func (r *CommandRegistry) Commands() []Command {
+	return append([]Command(nil), r.commands...)
+}
+
+// Complete returns the command names starting with prefix, in registration
+// order. A leading slash on prefix is ignored; aliases are not offered.
+func (r *CommandRegistry) Complete(prefix string) []string {
+	prefix = strings.TrimPrefix(prefix, "/")
+	var matches []string
+	for _, cmd := range r.commands {
+		if strings.HasPrefix(cmd.Name, prefix) {
+			matches = append(matches, cmd.Name)
+		}
+	}
+	return matches
+}
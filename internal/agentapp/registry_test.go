@@ -0,0 +1,101 @@
+package agentapp
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCommandRegistryCompletePrefix(t *testing.T) {
+	t.Parallel()
+
+	registry := DefaultCommands()
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "/re", want: []string{"resume", "retry"}},
+		{prefix: "res", want: []string{"resume"}},
+		{prefix: "/d", want: []string{"diff", "dequeue"}},
+		{prefix: "/zzz", want: nil},
+	}
+	for _, tt := range tests {
+		if got := registry.Complete(tt.prefix); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("Complete(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+	if got := len(registry.Complete("/")); got != len(registry.Commands()) {
+		t.Fatalf("Complete(/) returned %d names, want all %d commands", got, len(registry.Commands()))
+	}
+}
+
+func TestCommandRegistryRegisterValidates(t *testing.T) {
+	t.Parallel()
+
+	noop := func(CommandEnv, []string, string) tea.Cmd { return nil }
+	registry := NewCommandRegistry()
+	if err := registry.Register(Command{Name: "/ping", Aliases: []string{"p"}, Handler: noop}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if _, ok := registry.Lookup("p"); !ok {
+		t.Fatal("Lookup(alias) ok = false, want alias registered")
+	}
+	if err := registry.Register(Command{Name: "p", Handler: noop}); err == nil {
+		t.Fatal("Register(duplicate) error = nil, want conflict")
+	}
+	if err := registry.Register(Command{Name: " ", Handler: noop}); !errors.Is(err, ErrCommandNameRequired) {
+		t.Fatalf("Register(empty) error = %v, want ErrCommandNameRequired", err)
+	}
+	if err := registry.Register(Command{Name: "nohandler"}); err == nil {
+		t.Fatal("Register(no handler) error = nil, want handler required")
+	}
+}
+
+func TestExecuteSlashCommandDispatchesThroughRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := DefaultCommands()
+	var gotArgs []string
+	var gotText string
+	if err := registry.Register(Command{
+		Name:        "echo",
+		ArgHint:     "<text>",
+		Description: "Repeat text",
+		Handler: func(env CommandEnv, args []string, text string) tea.Cmd {
+			gotArgs, gotText = args, text
+			appendAssistant(env, text)
+			return nil
+		},
+	}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	var assistant []string
+	var errs []string
+	env := CommandEnv{
+		Session:         &fakeSession{model: "m1"},
+		Commands:        registry,
+		AppendAssistant: func(text string) { assistant = append(assistant, text) },
+		AppendError:     func(errText string) { errs = append(errs, errText) },
+	}
+
+	_ = ExecuteSlashCommand("/echo  hello   world ", env)
+	if !reflect.DeepEqual(gotArgs, []string{"hello", "world"}) || gotText != "hello   world" {
+		t.Fatalf("handler args = %q, text = %q; want split args and raw text", gotArgs, gotText)
+	}
+	_ = ExecuteSlashCommand("/model", env)
+	if assistant[len(assistant)-1] != "Current model: m1" {
+		t.Fatalf("assistant = %v, want built-in /model preserved", assistant)
+	}
+	_ = ExecuteSlashCommand("/help", env)
+	if help := assistant[len(assistant)-1]; !strings.Contains(help, "/echo <text>") || !strings.Contains(help, "Repeat text") || !strings.Contains(help, "/resume [session-id|latest]") {
+		t.Fatalf("help = %q, want registry commands with hints and descriptions", help)
+	}
+	_ = ExecuteSlashCommand("/nope", env)
+	if len(errs) != 1 || errs[0] != "unknown slash command: /nope" {
+		t.Fatalf("errors = %v, want unknown command", errs)
+	}
+}
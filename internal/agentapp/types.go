@@ -4,6 +4,8 @@ import (
 	"context"
 
 	agentsession "gar/internal/agent/session"
+	agenttool "gar/internal/agent/tool"
+	"gar/internal/llm"
 	sessionstore "gar/internal/session"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -12,17 +14,42 @@ import (
 // SessionController is the shared command-facing session runtime contract.
 type SessionController interface {
 	Stats() agentsession.Stats
+	UsageTotals() llm.Usage
 	SessionName() string
 	SetSessionName(ctx context.Context, name string) error
+	Model() string
+	SetModel(ctx context.Context, name string) error
+	ToolChoice() (llm.ToolChoice, bool)
+	SetToolChoice(choice llm.ToolChoice, sticky bool) error
+	ToolNames() []string
+	PlanMode() bool
+	SetPlanMode(enabled bool) error
+	SystemPrompt() string
+	SetSystemPrompt(ctx context.Context, text string) error
 	NewSession(ctx context.Context, requestedID string) (string, error)
 	ListSessions(ctx context.Context) ([]sessionstore.SessionInfo, error)
+	SearchSessions(ctx context.Context, query string) ([]sessionstore.SearchHit, error)
 	SessionID() string
 	SwitchSession(ctx context.Context, sessionID string) error
+	LoadWarning() string
+	Entries() []sessionstore.Entry
 	SwitchBranch(targetID string) error
+	ForkSession(ctx context.Context, entryID, newID string) (string, error)
+	AddBookmark(ctx context.Context, name string) error
+	Bookmarks() []agentsession.Bookmark
+	GotoBookmark(name string) (string, error)
+	RewindToLastUser() (string, error)
+	RewindBeforeLastUser() (string, error)
+	Undo() (agentsession.UndoResult, error)
+	ModifiedFiles() []agenttool.UndoSnapshot
+	AttachImage(mediaType, data string) (int, error)
 	Compact(ctx context.Context, keepMessages int, instructions string) (agentsession.CompactionResult, error)
+	PreviewCompaction(keepMessages int, instructions string) (agentsession.CompactionResult, error)
+	Summarize(ctx context.Context) (string, error)
 	SteeringQueued() []string
 	FollowUpQueued() []string
 	ClearQueue() (steering []string, followUp []string)
+	CancelQueued(kind string, index int) (string, error)
 }
 
 // CommandEnv provides adapter hooks so command runtime stays UI-framework agnostic.
@@ -31,11 +58,22 @@ type CommandEnv struct {
 
 	ActiveStream bool
 
+	// Commands dispatches slash commands; nil uses DefaultCommands().
+	Commands *CommandRegistry
+
 	OpenResumeSelector func() tea.Cmd
 	OpenTreeSelector   func() tea.Cmd
+	StartRun           func() tea.Cmd
+	// RunTask runs slow work, such as a model call, off the UI loop with a
+	// context the user can cancel, then shows the text it returns as an
+	// assistant message or its error. label names the work in the status bar.
+	// nil runs work inline.
+	RunTask func(label string, work func(ctx context.Context) (string, error)) tea.Cmd
 
 	RebuildChatFromSession func()
 	RefreshSessionStatus   func()
+	// ClearChat empties the chat view without touching the session.
+	ClearChat func()
 
 	GetInputValue func() string
 	SetInputValue func(value string)
@@ -3,34 +3,67 @@ package tool
 import agenttool "gar/internal/agent/tool"
 
 // NewCodingTools returns the default coding tool set.
-func NewCodingTools() []agenttool.Tool {
+func NewCodingTools() []agenttool.Tool { return NewCodingToolsAt("") }
+
+// NewCodingToolsAt returns the default coding tool set sandboxed to workspaceRoot.
+func NewCodingToolsAt(workspaceRoot string) []agenttool.Tool {
 	return []agenttool.Tool{
-		agenttool.NewReadTool(),
-		agenttool.NewBashTool(),
-		agenttool.NewEditTool(),
-		agenttool.NewWriteTool(),
+		agenttool.NewReadToolAt(workspaceRoot),
+		agenttool.NewReadManyToolAt(workspaceRoot),
+		agenttool.NewBashToolAt(workspaceRoot),
+		agenttool.NewEditToolAt(workspaceRoot),
+		agenttool.NewMultiEditToolAt(workspaceRoot),
+		agenttool.NewApplyPatchToolAt(workspaceRoot),
+		agenttool.NewWriteToolAt(workspaceRoot),
+		agenttool.NewMoveToolAt(workspaceRoot),
+		agenttool.NewDeleteToolAt(workspaceRoot),
+		agenttool.NewLsToolAt(workspaceRoot),
+		agenttool.NewTreeToolAt(workspaceRoot),
+		agenttool.NewCountToolAt(workspaceRoot),
+		agenttool.NewGitToolAt(workspaceRoot),
+		agenttool.NewThinkTool(),
 	}
 }
 
 // NewReadOnlyTools returns the read-only exploration tool set.
-func NewReadOnlyTools() []agenttool.Tool {
+func NewReadOnlyTools() []agenttool.Tool { return NewReadOnlyToolsAt("") }
+
+// NewReadOnlyToolsAt returns the read-only exploration tool set sandboxed to workspaceRoot.
+func NewReadOnlyToolsAt(workspaceRoot string) []agenttool.Tool {
 	return []agenttool.Tool{
-		agenttool.NewReadTool(),
-		agenttool.NewGrepTool(),
-		agenttool.NewFindTool(),
-		agenttool.NewLsTool(),
+		agenttool.NewReadToolAt(workspaceRoot),
+		agenttool.NewReadManyToolAt(workspaceRoot),
+		agenttool.NewGrepToolAt(workspaceRoot),
+		agenttool.NewFindToolAt(workspaceRoot),
+		agenttool.NewLsToolAt(workspaceRoot),
+		agenttool.NewTreeToolAt(workspaceRoot),
+		agenttool.NewCountToolAt(workspaceRoot),
+		agenttool.NewGitToolAt(workspaceRoot),
+		agenttool.NewThinkTool(),
 	}
 }
 
 // NewAllTools returns all available built-in tools.
-func NewAllTools() []agenttool.Tool {
+func NewAllTools() []agenttool.Tool { return NewAllToolsAt("") }
+
+// NewAllToolsAt returns all available built-in tools sandboxed to workspaceRoot.
+func NewAllToolsAt(workspaceRoot string) []agenttool.Tool {
 	return []agenttool.Tool{
-		agenttool.NewReadTool(),
-		agenttool.NewBashTool(),
-		agenttool.NewEditTool(),
-		agenttool.NewWriteTool(),
-		agenttool.NewGrepTool(),
-		agenttool.NewFindTool(),
-		agenttool.NewLsTool(),
+		agenttool.NewReadToolAt(workspaceRoot),
+		agenttool.NewReadManyToolAt(workspaceRoot),
+		agenttool.NewBashToolAt(workspaceRoot),
+		agenttool.NewEditToolAt(workspaceRoot),
+		agenttool.NewMultiEditToolAt(workspaceRoot),
+		agenttool.NewApplyPatchToolAt(workspaceRoot),
+		agenttool.NewWriteToolAt(workspaceRoot),
+		agenttool.NewMoveToolAt(workspaceRoot),
+		agenttool.NewDeleteToolAt(workspaceRoot),
+		agenttool.NewGrepToolAt(workspaceRoot),
+		agenttool.NewFindToolAt(workspaceRoot),
+		agenttool.NewLsToolAt(workspaceRoot),
+		agenttool.NewTreeToolAt(workspaceRoot),
+		agenttool.NewCountToolAt(workspaceRoot),
+		agenttool.NewGitToolAt(workspaceRoot),
+		agenttool.NewThinkTool(),
 	}
 }
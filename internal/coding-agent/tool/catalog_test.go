@@ -1,15 +1,24 @@
 package tool
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	agenttool "gar/internal/agent/tool"
+)
 
 func TestNewCodingTools(t *testing.T) {
 	t.Parallel()
 
 	got := NewCodingTools()
-	if len(got) != 4 {
-		t.Fatalf("len(NewCodingTools()) = %d, want 4", len(got))
+	if len(got) != 14 {
+		t.Fatalf("len(NewCodingTools()) = %d, want 14", len(got))
 	}
-	want := []string{"read", "bash", "edit", "write"}
+	want := []string{"read", "read_many", "bash", "edit", "multiedit", "apply_patch", "write", "move", "delete", "ls", "tree", "count", "git", "think"}
 	for i, tool := range got {
 		if tool.Name() != want[i] {
 			t.Fatalf("tool[%d].Name() = %q, want %q", i, tool.Name(), want[i])
@@ -21,10 +30,10 @@ func TestNewReadOnlyTools(t *testing.T) {
 	t.Parallel()
 
 	got := NewReadOnlyTools()
-	if len(got) != 4 {
-		t.Fatalf("len(NewReadOnlyTools()) = %d, want 4", len(got))
+	if len(got) != 9 {
+		t.Fatalf("len(NewReadOnlyTools()) = %d, want 9", len(got))
 	}
-	want := []string{"read", "grep", "find", "ls"}
+	want := []string{"read", "read_many", "grep", "find", "ls", "tree", "count", "git", "think"}
 	for i, tool := range got {
 		if tool.Name() != want[i] {
 			t.Fatalf("tool[%d].Name() = %q, want %q", i, tool.Name(), want[i])
@@ -36,7 +45,43 @@ func TestNewAllTools(t *testing.T) {
 	t.Parallel()
 
 	got := NewAllTools()
-	if len(got) != 7 {
-		t.Fatalf("len(NewAllTools()) = %d, want 7", len(got))
+	if len(got) != 16 {
+		t.Fatalf("len(NewAllTools()) = %d, want 16", len(got))
+	}
+}
+
+func TestNewCodingToolsAtEnforcesWorkspaceRoot(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "inside.txt"), []byte("inside"), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+	if err := os.WriteFile(outside, []byte("outside"), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	var read agenttool.Tool
+	for _, tool := range NewCodingToolsAt(root) {
+		if tool.Name() == "read" {
+			read = tool
+		}
+	}
+	if read == nil {
+		t.Fatal("NewCodingToolsAt() has no read tool")
+	}
+
+	got, err := read.Execute(context.Background(), json.RawMessage(`{"path":"inside.txt"}`))
+	if err != nil {
+		t.Fatalf("Execute(inside) error = %v", err)
+	}
+	if got.Content != "inside" {
+		t.Fatalf("Execute(inside).Content = %q, want file relative to root", got.Content)
+	}
+
+	params, _ := json.Marshal(map[string]string{"path": outside})
+	if _, err := read.Execute(context.Background(), params); !errors.Is(err, agenttool.ErrPathOutsideWorkspace) {
+		t.Fatalf("Execute(outside) error = %v, want ErrPathOutsideWorkspace", err)
 	}
 }
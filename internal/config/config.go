@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	toml "github.com/pelletier/go-toml/v2"
 )
@@ -16,19 +18,34 @@ const (
 	defaultProviderName       = "anthropic"
 	defaultAnthropicModel     = "claude-sonnet-4-20250514"
 	defaultAnthropicVersion   = "2023-06-01"
+	defaultOpenAIModel        = "gpt-4o"
+	defaultGeminiModel        = "gemini-2.5-flash"
+	defaultBedrockModel       = "us.anthropic.claude-sonnet-4-20250514-v1:0"
 	defaultRetryMaxRetries    = 3
 	defaultRetryBaseDelay     = "300ms"
 	defaultRetryMaxDelay      = "5s"
 	defaultAgentMaxTurns      = 50
-	defaultAgentThinkingLevel = "medium"
 	defaultTUITheme           = "dark"
 	defaultTUIShowInspector   = true
+	defaultTUIColor           = true
+	defaultSessionEnabled     = true
+	defaultSessionAutoName    = true
+	defaultPromptCache        = true
 	defaultConfigRelativePath = ".config/gar/config.toml"
 	envProviderDefault        = "GAR_PROVIDER_DEFAULT"
 	envAnthropicAPIKey        = "ANTHROPIC_API_KEY"
 	envAnthropicModel         = "GAR_ANTHROPIC_MODEL"
 	envAnthropicBaseURL       = "GAR_ANTHROPIC_BASE_URL"
 	envAnthropicVersion       = "GAR_ANTHROPIC_VERSION"
+	envOpenAIAPIKey           = "OPENAI_API_KEY"
+	envOpenAIModel            = "GAR_OPENAI_MODEL"
+	envOpenAIBaseURL          = "GAR_OPENAI_BASE_URL"
+	envGeminiAPIKey           = "GEMINI_API_KEY"
+	envGeminiModel            = "GAR_GEMINI_MODEL"
+	envGeminiBaseURL          = "GAR_GEMINI_BASE_URL"
+	envBedrockModel           = "GAR_BEDROCK_MODEL"
+	envBedrockRegion          = "GAR_BEDROCK_REGION"
+	envWorkspaceRoot          = "GAR_WORKSPACE_ROOT"
 	envRetryMaxRetries        = "GAR_ANTHROPIC_RETRY_MAX_RETRIES"
 	envRetryBaseDelay         = "GAR_ANTHROPIC_RETRY_BASE_DELAY"
 	envRetryMaxDelay          = "GAR_ANTHROPIC_RETRY_MAX_DELAY"
@@ -41,15 +58,31 @@ var (
 
 // Config is the application configuration root.
 type Config struct {
-	Provider ProviderConfig `toml:"provider"`
-	Agent    AgentConfig    `toml:"agent"`
-	TUI      TUIConfig      `toml:"tui"`
+	Provider  ProviderConfig  `toml:"provider"`
+	Agent     AgentConfig     `toml:"agent"`
+	TUI       TUIConfig       `toml:"tui"`
+	Workspace WorkspaceConfig `toml:"workspace"`
+	Session   SessionConfig   `toml:"session"`
+	Debug     DebugConfig     `toml:"debug"`
+	Tools     ToolsConfig     `toml:"tools"`
 }
 
 // ProviderConfig configures model providers.
 type ProviderConfig struct {
 	Default   string                  `toml:"default"`
 	Anthropic AnthropicProviderConfig `toml:"anthropic"`
+	OpenAI    OpenAIProviderConfig    `toml:"openai"`
+	Gemini    GeminiProviderConfig    `toml:"gemini"`
+	Bedrock   BedrockProviderConfig   `toml:"bedrock"`
+	// ValidateModel checks the configured model against the provider's model
+	// list at startup and warns when it is missing.
+	ValidateModel bool `toml:"validate_model"`
+	// PromptCache marks the system prompt and tool definitions as cacheable on
+	// providers that support prompt caching.
+	PromptCache bool `toml:"prompt_cache"`
+	// StreamIdleTimeout aborts a response stream that delivers no event for
+	// this long, e.g. "2m". Empty disables the limit.
+	StreamIdleTimeout string `toml:"stream_idle_timeout"`
 }
 
 // AnthropicProviderConfig configures Anthropic-specific runtime values.
@@ -59,6 +92,40 @@ type AnthropicProviderConfig struct {
 	BaseURL string      `toml:"base_url"`
 	Version string      `toml:"version"`
 	Retry   RetryConfig `toml:"retry"`
+	// APIKeyCmd runs through the shell when this is the default provider and
+	// APIKey is otherwise empty, and its trimmed output is used as the key,
+	// e.g. "pass show anthropic".
+	APIKeyCmd string `toml:"api_key_cmd"`
+}
+
+// OpenAIProviderConfig configures OpenAI-compatible chat-completions runtime values.
+type OpenAIProviderConfig struct {
+	APIKey  string      `toml:"api_key"`
+	Model   string      `toml:"model"`
+	BaseURL string      `toml:"base_url"`
+	Retry   RetryConfig `toml:"retry"`
+	// APIKeyCmd fetches the key as AnthropicProviderConfig.APIKeyCmd does.
+	APIKeyCmd string `toml:"api_key_cmd"`
+}
+
+// GeminiProviderConfig configures Google Gemini generateContent runtime values.
+type GeminiProviderConfig struct {
+	APIKey  string      `toml:"api_key"`
+	Model   string      `toml:"model"`
+	BaseURL string      `toml:"base_url"`
+	Retry   RetryConfig `toml:"retry"`
+	// APIKeyCmd fetches the key as AnthropicProviderConfig.APIKeyCmd does.
+	APIKeyCmd string `toml:"api_key_cmd"`
+}
+
+// BedrockProviderConfig configures Anthropic models served through AWS Bedrock.
+// Credentials come from the standard AWS chain rather than this file.
+type BedrockProviderConfig struct {
+	Region  string      `toml:"region"`
+	Profile string      `toml:"profile"`
+	Model   string      `toml:"model"`
+	BaseURL string      `toml:"base_url"`
+	Retry   RetryConfig `toml:"retry"`
 }
 
 // RetryConfig stores retry policy as config-friendly values.
@@ -70,15 +137,198 @@ type RetryConfig struct {
 
 // AgentConfig configures agent-level behavior.
 type AgentConfig struct {
-	AutoApprove   []string `toml:"auto_approve"`
-	MaxTurns      int      `toml:"max_turns"`
-	ThinkingLevel string   `toml:"thinking_level"`
+	AutoApprove []string `toml:"auto_approve"`
+	MaxTurns    int      `toml:"max_turns"`
+	// ThinkingLevel picks a request temperature from thinkingLevelTemperatures
+	// unless Temperature is set. Empty, the default, leaves the temperature to
+	// the provider.
+	ThinkingLevel string `toml:"thinking_level"`
+	// Temperature, when set, is sent with every request and overrides ThinkingLevel.
+	Temperature *float64 `toml:"temperature"`
+	// ThinkingBudget enables extended thinking with this many budget tokens
+	// on providers that support it. Zero disables it.
+	ThinkingBudget int  `toml:"thinking_budget"`
+	ParallelTools  bool `toml:"parallel_tools"`
+	// ToolTimeout bounds each tool call, e.g. "2m", counted from when it holds
+	// its tools.max_concurrent slot. Empty disables the limit.
+	ToolTimeout string `toml:"tool_timeout"`
+	// ToolRetry retries failed idempotent tools (read, grep, find, ls).
+	// max_retries = 0 disables tool retries.
+	ToolRetry RetryConfig `toml:"tool_retry"`
+	Bash      BashConfig  `toml:"bash"`
+	Edit      EditConfig  `toml:"edit"`
+	Fetch     FetchConfig `toml:"fetch"`
+	// ExternalTools registers subprocess tools alongside the built-ins.
+	ExternalTools []ExternalToolConfig `toml:"external_tools"`
+	// ContextWindows maps model names or name prefixes to context window
+	// sizes in tokens, overriding the built-in defaults.
+	ContextWindows map[string]int `toml:"context_windows"`
+	// ContextWarnRatio is the share of the context window at which gar warns
+	// before sending a request. Zero uses 0.9.
+	ContextWarnRatio float64 `toml:"context_warn_ratio"`
+	// CompactOnContextWarning compacts the session instead of only warning.
+	CompactOnContextWarning bool `toml:"compact_on_context_warning"`
+	// DedupeQueue drops a steering or follow-up message identical to one
+	// already queued.
+	DedupeQueue bool `toml:"dedupe_queue"`
+	// MaxToolResultBytes bounds tool output sent to the model. Zero uses 10000.
+	MaxToolResultBytes int `toml:"max_tool_result_bytes"`
+	// ToolResultTruncation picks what longer output keeps: "head", "tail",
+	// or "middle-out" (both ends, the default). ToolResultTruncationByTool
+	// overrides it per tool name.
+	ToolResultTruncation       string            `toml:"tool_result_truncation"`
+	ToolResultTruncationByTool map[string]string `toml:"tool_result_truncation_by_tool"`
+	// ProjectPromptFiles are candidate project instruction files, relative to
+	// the workspace root unless absolute. The first one present, up to 32 KiB,
+	// is prepended to the system prompt each time a session loads. It defaults
+	// to .gar/system.md, AGENTS.md, and CLAUDE.md; empty disables it.
+	ProjectPromptFiles []string `toml:"project_prompt_files"`
+}
+
+// defaultContextWindows are the context window sizes, in tokens, for model
+// name prefixes gar knows about.
+var defaultContextWindows = map[string]int{
+	"claude":              200000,
+	"us.anthropic.claude": 200000,
+	"anthropic.claude":    200000,
+	"gpt-4o":              128000,
+	"gpt-4.1":             1047576,
+	"o3":                  200000,
+	"o4-mini":             200000,
+	"gemini-2.5":          1048576,
+	"gemini-2.0":          1048576,
+	"gemini-1.5-pro":      2097152,
+}
+
+// thinkingLevelTemperatures maps agent.thinking_level to a request temperature.
+var thinkingLevelTemperatures = map[string]float64{
+	"low":    0.2,
+	"medium": 0.7,
+	"high":   1.0,
+}
+
+// ExternalToolConfig declares one subprocess tool. The command receives the
+// call parameters as JSON on stdin and prints {"content", "is_error"} JSON.
+type ExternalToolConfig struct {
+	Name        string `toml:"name"`
+	Description string `toml:"description"`
+	// Schema is the JSON schema for the tool parameters.
+	Schema  string   `toml:"schema"`
+	Command []string `toml:"command"`
+	// Timeout defaults to 30s when empty.
+	Timeout string `toml:"timeout"`
+}
+
+// ExternalToolSettings is one validated external tool declaration.
+type ExternalToolSettings struct {
+	Name        string
+	Description string
+	Schema      json.RawMessage
+	Command     []string
+	Timeout     time.Duration
+}
+
+// BashConfig restricts which commands the bash tool will run.
+type BashConfig struct {
+	// Deny lists regex patterns for commands that are always refused.
+	Deny []string `toml:"deny"`
+	// Allow lists regex patterns; when non-empty, only matching commands run.
+	Allow []string `toml:"allow"`
+	// Confirm shows each command in the TUI and runs it only once approved,
+	// even when auto_approve lists bash. Headless runs refuse every command.
+	Confirm bool `toml:"confirm"`
+}
+
+// EditConfig configures the edit and multiedit tools.
+type EditConfig struct {
+	// ExactMatch turns off matching oldText after normalizing quotes,
+	// dashes, and trailing whitespace.
+	ExactMatch bool `toml:"exact_match"`
+}
+
+// FetchConfig configures the fetch tool, which is off by default because it
+// makes network requests.
+type FetchConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Timeout bounds each fetch, e.g. "30s". Empty uses 30s.
+	Timeout string `toml:"timeout"`
+	// MaxBytes caps the response body read per fetch. Zero uses 5MB.
+	MaxBytes int `toml:"max_bytes"`
+}
+
+// FetchSettings is the validated fetch tool configuration.
+type FetchSettings struct {
+	Enabled  bool
+	Timeout  time.Duration
+	MaxBytes int
+}
+
+// WorkspaceConfig configures the directory file tools are sandboxed to.
+type WorkspaceConfig struct {
+	// Root defaults to the current working directory when empty.
+	Root string `toml:"root"`
+}
+
+// SessionConfig configures session persistence.
+type SessionConfig struct {
+	// Enabled persists sessions to disk; when false they live only in memory.
+	Enabled bool `toml:"enabled"`
+	// Dir overrides the session directory, which defaults to .gar/sessions
+	// under the working directory.
+	Dir string `toml:"dir"`
+	// Compress gzips session files into .jsonl.gz when the TUI exits.
+	Compress bool `toml:"compress"`
+	// MaxEntries caps the live entries in a session file; older entries move
+	// to <id>.archive.jsonl, which is still loaded. Zero disables rotation.
+	MaxEntries int `toml:"max_entries"`
+	// Fsync syncs session files to disk after every append, trading write
+	// latency for durability across crashes and power loss.
+	Fsync bool `toml:"fsync"`
+	// AutoName names an unnamed session after the first line of its first
+	// message, so /resume lists are easier to scan.
+	AutoName bool `toml:"auto_name"`
+	// PersistThinking records extended thinking as "thinking" entries, kept
+	// for inspection only.
+	PersistThinking bool `toml:"persist_thinking"`
+}
+
+// ToolsConfig configures the tool registry.
+type ToolsConfig struct {
+	// MaxConcurrent caps tool executions running at once across all tools;
+	// MaxConcurrentByTool caps individual tools. Zero is unlimited.
+	MaxConcurrent       int            `toml:"max_concurrent"`
+	MaxConcurrentByTool map[string]int `toml:"max_concurrent_by_tool"`
+}
+
+// DebugConfig configures troubleshooting output.
+type DebugConfig struct {
+	// LogFile, when set, receives every provider request and stream event as
+	// JSON lines with API keys redacted.
+	LogFile string `toml:"log_file"`
 }
 
 // TUIConfig configures terminal UI defaults.
 type TUIConfig struct {
 	Theme         string `toml:"theme"`
 	ShowInspector bool   `toml:"show_inspector"`
+	Markdown      bool   `toml:"markdown"`
+	ShowThinking  bool   `toml:"show_thinking"`
+	// ShowInputCount shows a live character and estimated-token count in the input line.
+	ShowInputCount bool `toml:"show_input_count"`
+	// ChatLimit is how many chat messages the TUI keeps in memory. Zero uses 500.
+	ChatLimit int `toml:"chat_limit"`
+	// ThemeFile is a TOML or JSON color map that overrides Theme when it exists.
+	ThemeFile string `toml:"theme_file"`
+	// Welcome replaces the greeting shown in an empty chat.
+	Welcome string `toml:"welcome"`
+	// HideHints suppresses the empty-chat greeting, hints, and first-run tips.
+	HideHints bool `toml:"hide_hints"`
+	// Color turns off every color when false. NO_COLOR or a stdout that is
+	// not a terminal does the same regardless.
+	Color bool `toml:"color"`
+	// Keys maps TUI actions such as "submit" to the keys that trigger them,
+	// replacing that action's default keys.
+	Keys map[string][]string `toml:"keys"`
 }
 
 // LoadOptions controls config loading behavior.
@@ -92,11 +342,36 @@ type AnthropicSettings struct {
 	Model   string
 	BaseURL string
 	Version string
-	Retry   AnthropicRetrySettings
+	Retry   RetrySettings
+}
+
+// OpenAISettings is a validated OpenAI runtime settings snapshot.
+type OpenAISettings struct {
+	APIKey  string
+	Model   string
+	BaseURL string
+	Retry   RetrySettings
 }
 
-// AnthropicRetrySettings is the parsed retry policy.
-type AnthropicRetrySettings struct {
+// GeminiSettings is a validated Gemini runtime settings snapshot.
+type GeminiSettings struct {
+	APIKey  string
+	Model   string
+	BaseURL string
+	Retry   RetrySettings
+}
+
+// BedrockSettings is a validated Bedrock runtime settings snapshot.
+type BedrockSettings struct {
+	Region  string
+	Profile string
+	Model   string
+	BaseURL string
+	Retry   RetrySettings
+}
+
+// RetrySettings is the parsed retry policy.
+type RetrySettings struct {
 	MaxRetries int
 	BaseDelay  time.Duration
 	MaxDelay   time.Duration
@@ -106,7 +381,8 @@ type AnthropicRetrySettings struct {
 func Default() Config {
 	return Config{
 		Provider: ProviderConfig{
-			Default: defaultProviderName,
+			Default:     defaultProviderName,
+			PromptCache: defaultPromptCache,
 			Anthropic: AnthropicProviderConfig{
 				Model:   defaultAnthropicModel,
 				Version: defaultAnthropicVersion,
@@ -116,15 +392,48 @@ func Default() Config {
 					MaxDelay:   defaultRetryMaxDelay,
 				},
 			},
+			OpenAI: OpenAIProviderConfig{
+				Model: defaultOpenAIModel,
+				Retry: RetryConfig{
+					MaxRetries: defaultRetryMaxRetries,
+					BaseDelay:  defaultRetryBaseDelay,
+					MaxDelay:   defaultRetryMaxDelay,
+				},
+			},
+			Gemini: GeminiProviderConfig{
+				Model: defaultGeminiModel,
+				Retry: RetryConfig{
+					MaxRetries: defaultRetryMaxRetries,
+					BaseDelay:  defaultRetryBaseDelay,
+					MaxDelay:   defaultRetryMaxDelay,
+				},
+			},
+			Bedrock: BedrockProviderConfig{
+				Model: defaultBedrockModel,
+				Retry: RetryConfig{
+					MaxRetries: defaultRetryMaxRetries,
+					BaseDelay:  defaultRetryBaseDelay,
+					MaxDelay:   defaultRetryMaxDelay,
+				},
+			},
 		},
 		Agent: AgentConfig{
-			AutoApprove:   []string{"ReadFile"},
-			MaxTurns:      defaultAgentMaxTurns,
-			ThinkingLevel: defaultAgentThinkingLevel,
+			AutoApprove:        []string{"read", "ls"},
+			MaxTurns:           defaultAgentMaxTurns,
+			ProjectPromptFiles: []string{".gar/system.md", "AGENTS.md", "CLAUDE.md"},
+			ToolRetry: RetryConfig{
+				BaseDelay: defaultRetryBaseDelay,
+				MaxDelay:  defaultRetryMaxDelay,
+			},
+		},
+		Session: SessionConfig{
+			Enabled:  defaultSessionEnabled,
+			AutoName: defaultSessionAutoName,
 		},
 		TUI: TUIConfig{
 			Theme:         defaultTUITheme,
 			ShowInspector: defaultTUIShowInspector,
+			Color:         defaultTUIColor,
 		},
 	}
 }
@@ -141,9 +450,15 @@ func Load(opts LoadOptions) (Config, error) {
 	if err := mergeConfigFile(&cfg, path); err != nil {
 		return Config{}, err
 	}
+	if err := expandEnvReferences(&cfg); err != nil {
+		return Config{}, err
+	}
 	if err := applyEnv(&cfg); err != nil {
 		return Config{}, err
 	}
+	if err := resolveSecretCommands(&cfg); err != nil {
+		return Config{}, err
+	}
 	if err := validate(cfg); err != nil {
 		return Config{}, err
 	}
@@ -152,16 +467,9 @@ func Load(opts LoadOptions) (Config, error) {
 
 // AnthropicSettings returns validated settings suitable for runtime wiring.
 func (c Config) AnthropicSettings() (AnthropicSettings, error) {
-	baseDelay, err := time.ParseDuration(strings.TrimSpace(c.Provider.Anthropic.Retry.BaseDelay))
-	if err != nil {
-		return AnthropicSettings{}, fmt.Errorf("%w: parse anthropic retry base_delay: %v", ErrInvalidConfig, err)
-	}
-	maxDelay, err := time.ParseDuration(strings.TrimSpace(c.Provider.Anthropic.Retry.MaxDelay))
+	retry, err := parseRetrySettings("anthropic", c.Provider.Anthropic.Retry)
 	if err != nil {
-		return AnthropicSettings{}, fmt.Errorf("%w: parse anthropic retry max_delay: %v", ErrInvalidConfig, err)
-	}
-	if c.Provider.Anthropic.Retry.MaxRetries < 0 {
-		return AnthropicSettings{}, fmt.Errorf("%w: anthropic retry max_retries must be >= 0", ErrInvalidConfig)
+		return AnthropicSettings{}, err
 	}
 
 	return AnthropicSettings{
@@ -169,11 +477,467 @@ func (c Config) AnthropicSettings() (AnthropicSettings, error) {
 		Model:   strings.TrimSpace(c.Provider.Anthropic.Model),
 		BaseURL: strings.TrimSpace(c.Provider.Anthropic.BaseURL),
 		Version: strings.TrimSpace(c.Provider.Anthropic.Version),
-		Retry: AnthropicRetrySettings{
-			MaxRetries: c.Provider.Anthropic.Retry.MaxRetries,
-			BaseDelay:  baseDelay,
-			MaxDelay:   maxDelay,
-		},
+		Retry:   retry,
+	}, nil
+}
+
+// OpenAISettings returns validated settings suitable for runtime wiring.
+func (c Config) OpenAISettings() (OpenAISettings, error) {
+	retry, err := parseRetrySettings("openai", c.Provider.OpenAI.Retry)
+	if err != nil {
+		return OpenAISettings{}, err
+	}
+
+	return OpenAISettings{
+		APIKey:  strings.TrimSpace(c.Provider.OpenAI.APIKey),
+		Model:   strings.TrimSpace(c.Provider.OpenAI.Model),
+		BaseURL: strings.TrimSpace(c.Provider.OpenAI.BaseURL),
+		Retry:   retry,
+	}, nil
+}
+
+// GeminiSettings returns validated settings suitable for runtime wiring.
+func (c Config) GeminiSettings() (GeminiSettings, error) {
+	retry, err := parseRetrySettings("gemini", c.Provider.Gemini.Retry)
+	if err != nil {
+		return GeminiSettings{}, err
+	}
+
+	return GeminiSettings{
+		APIKey:  strings.TrimSpace(c.Provider.Gemini.APIKey),
+		Model:   strings.TrimSpace(c.Provider.Gemini.Model),
+		BaseURL: strings.TrimSpace(c.Provider.Gemini.BaseURL),
+		Retry:   retry,
+	}, nil
+}
+
+// BedrockSettings returns validated settings suitable for runtime wiring.
+func (c Config) BedrockSettings() (BedrockSettings, error) {
+	retry, err := parseRetrySettings("bedrock", c.Provider.Bedrock.Retry)
+	if err != nil {
+		return BedrockSettings{}, err
+	}
+
+	return BedrockSettings{
+		Region:  strings.TrimSpace(c.Provider.Bedrock.Region),
+		Profile: strings.TrimSpace(c.Provider.Bedrock.Profile),
+		Model:   strings.TrimSpace(c.Provider.Bedrock.Model),
+		BaseURL: strings.TrimSpace(c.Provider.Bedrock.BaseURL),
+		Retry:   retry,
+	}, nil
+}
+
+// WorkspaceRoot returns the absolute workspace root directory.
+func (c Config) WorkspaceRoot() (string, error) {
+	root := strings.TrimSpace(c.Workspace.Root)
+	if root == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("resolve working directory: %w", err)
+		}
+		return cwd, nil
+	}
+	if root == "~" || strings.HasPrefix(root, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("%w: expand workspace.root: %v", ErrInvalidConfig, err)
+		}
+		root = filepath.Join(home, strings.TrimPrefix(root, "~"))
+	}
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("%w: resolve workspace.root: %v", ErrInvalidConfig, err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", fmt.Errorf("%w: workspace.root: %v", ErrInvalidConfig, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%w: workspace.root %s is not a directory", ErrInvalidConfig, abs)
+	}
+	return abs, nil
+}
+
+// SessionDir returns the absolute session directory override, or "" when
+// session.dir is unset and the default location should be used.
+func (c Config) SessionDir() (string, error) {
+	dir := strings.TrimSpace(c.Session.Dir)
+	if dir == "" {
+		return "", nil
+	}
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("%w: expand session.dir: %v", ErrInvalidConfig, err)
+		}
+		dir = filepath.Join(home, strings.TrimPrefix(dir, "~"))
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("%w: resolve session.dir: %v", ErrInvalidConfig, err)
+	}
+	return abs, nil
+}
+
+// ThemeFile returns the absolute tui.theme_file path, or "" when unset.
+func (c Config) ThemeFile() (string, error) {
+	path := strings.TrimSpace(c.TUI.ThemeFile)
+	if path == "" {
+		return "", nil
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("%w: expand tui.theme_file: %v", ErrInvalidConfig, err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: resolve tui.theme_file: %v", ErrInvalidConfig, err)
+	}
+	return abs, nil
+}
+
+// DebugLogFile returns the absolute debug.log_file path, or "" when unset.
+func (c Config) DebugLogFile() (string, error) {
+	path := strings.TrimSpace(c.Debug.LogFile)
+	if path == "" {
+		return "", nil
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("%w: expand debug.log_file: %v", ErrInvalidConfig, err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: resolve debug.log_file: %v", ErrInvalidConfig, err)
+	}
+	return abs, nil
+}
+
+// keyActions lists the TUI actions tui.keys may remap.
+var keyActions = map[string]bool{
+	"quit": true, "quit_when_idle": true, "submit": true, "follow_up": true,
+	"cancel": true, "complete": true, "scroll_up": true, "scroll_down": true,
+	"page_up": true, "page_down": true, "scroll_top": true, "scroll_bottom": true,
+}
+
+// namedKeys are the non-character keys a tui.keys spec may end in.
+var namedKeys = map[string]bool{
+	"enter": true, "tab": true, "esc": true, "space": true, "backspace": true,
+	"delete": true, "insert": true, "up": true, "down": true, "left": true,
+	"right": true, "home": true, "end": true, "pgup": true, "pgdown": true,
+}
+
+// KeyBindings returns the validated tui.keys overrides with key specs
+// lowercased, e.g. {"submit": ["ctrl+s"]}.
+func (c Config) KeyBindings() (map[string][]string, error) {
+	bindings := make(map[string][]string, len(c.TUI.Keys))
+	for action, specs := range c.TUI.Keys {
+		action = strings.TrimSpace(action)
+		if !keyActions[action] {
+			return nil, fmt.Errorf("%w: tui.keys.%s is not a known action", ErrInvalidConfig, action)
+		}
+		if len(specs) == 0 {
+			return nil, fmt.Errorf("%w: tui.keys.%s needs at least one key", ErrInvalidConfig, action)
+		}
+		normalized := make([]string, 0, len(specs))
+		for _, spec := range specs {
+			spec = strings.ToLower(strings.TrimSpace(spec))
+			if !validKeySpec(spec) {
+				return nil, fmt.Errorf("%w: tui.keys.%s has invalid key %q", ErrInvalidConfig, action, spec)
+			}
+			normalized = append(normalized, spec)
+		}
+		bindings[action] = normalized
+	}
+	return bindings, nil
+}
+
+// validKeySpec accepts keys like "q", "enter", "ctrl+s", or "alt+enter":
+// optional ctrl/alt/shift modifiers followed by one character or named key.
+func validKeySpec(spec string) bool {
+	parts := strings.Split(spec, "+")
+	key := parts[len(parts)-1]
+	for _, modifier := range parts[:len(parts)-1] {
+		if modifier != "ctrl" && modifier != "alt" && modifier != "shift" {
+			return false
+		}
+	}
+	if namedKeys[key] {
+		return true
+	}
+	if len(key) > 1 && key[0] == 'f' {
+		n, err := strconv.Atoi(key[1:])
+		return err == nil && n >= 1 && n <= 20
+	}
+	return utf8.RuneCountInString(key) == 1 && key != " "
+}
+
+// ToolRetrySettings returns the validated retry policy for idempotent tools.
+func (c Config) ToolRetrySettings() (RetrySettings, error) {
+	return parseRetrySettings("agent.tool", c.Agent.ToolRetry)
+}
+
+// TemperatureSetting returns the request temperature from agent.temperature,
+// or from agent.thinking_level when no temperature is set. It returns nil,
+// leaving the provider default, when both are empty.
+func (c Config) TemperatureSetting() (*float64, error) {
+	if c.Agent.Temperature != nil {
+		temperature := *c.Agent.Temperature
+		if temperature < 0 || temperature > 2 {
+			return nil, fmt.Errorf("%w: agent.temperature must be between 0 and 2", ErrInvalidConfig)
+		}
+		return &temperature, nil
+	}
+	level := strings.ToLower(strings.TrimSpace(c.Agent.ThinkingLevel))
+	if level == "" {
+		return nil, nil
+	}
+	temperature, ok := thinkingLevelTemperatures[level]
+	if !ok {
+		return nil, fmt.Errorf("%w: agent.thinking_level must be low, medium, or high", ErrInvalidConfig)
+	}
+	return &temperature, nil
+}
+
+// ToolTimeoutSetting returns the parsed agent.tool_timeout, or zero when unset.
+func (c Config) ToolTimeoutSetting() (time.Duration, error) {
+	raw := strings.TrimSpace(c.Agent.ToolTimeout)
+	if raw == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: parse agent.tool_timeout: %v", ErrInvalidConfig, err)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("%w: agent.tool_timeout must be >= 0", ErrInvalidConfig)
+	}
+	return timeout, nil
+}
+
+// StreamIdleTimeoutSetting returns the parsed provider.stream_idle_timeout,
+// or zero when unset.
+func (c Config) StreamIdleTimeoutSetting() (time.Duration, error) {
+	raw := strings.TrimSpace(c.Provider.StreamIdleTimeout)
+	if raw == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: parse provider.stream_idle_timeout: %v", ErrInvalidConfig, err)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("%w: provider.stream_idle_timeout must be >= 0", ErrInvalidConfig)
+	}
+	return timeout, nil
+}
+
+// ToolResultSettings is the validated tool result truncation configuration.
+type ToolResultSettings struct {
+	MaxBytes   int
+	Truncation string
+	ByTool     map[string]string
+}
+
+// ToolResultSettings validates agent.max_tool_result_bytes and the
+// truncation modes.
+func (c Config) ToolResultSettings() (ToolResultSettings, error) {
+	if c.Agent.MaxToolResultBytes < 0 {
+		return ToolResultSettings{}, fmt.Errorf("%w: agent.max_tool_result_bytes must be >= 0", ErrInvalidConfig)
+	}
+	settings := ToolResultSettings{
+		MaxBytes:   c.Agent.MaxToolResultBytes,
+		Truncation: strings.TrimSpace(c.Agent.ToolResultTruncation),
+		ByTool:     make(map[string]string, len(c.Agent.ToolResultTruncationByTool)),
+	}
+	if !validTruncation(settings.Truncation) {
+		return ToolResultSettings{}, fmt.Errorf("%w: agent.tool_result_truncation must be head, tail, or middle-out", ErrInvalidConfig)
+	}
+	for name, mode := range c.Agent.ToolResultTruncationByTool {
+		mode = strings.TrimSpace(mode)
+		if mode == "" || !validTruncation(mode) {
+			return ToolResultSettings{}, fmt.Errorf("%w: agent.tool_result_truncation_by_tool.%s must be head, tail, or middle-out", ErrInvalidConfig, name)
+		}
+		settings.ByTool[strings.TrimSpace(name)] = mode
+	}
+	return settings, nil
+}
+
+// ProjectPromptPaths resolves agent.project_prompt_files against
+// workspaceRoot, keeping their order and dropping blank entries.
+func (c Config) ProjectPromptPaths(workspaceRoot string) []string {
+	paths := make([]string, 0, len(c.Agent.ProjectPromptFiles))
+	for _, path := range c.Agent.ProjectPromptFiles {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workspaceRoot, path)
+		}
+		paths = append(paths, filepath.Clean(path))
+	}
+	return paths
+}
+
+// ToolConcurrencySettings is the validated tool concurrency configuration.
+type ToolConcurrencySettings struct {
+	Max    int
+	ByTool map[string]int
+}
+
+// ToolConcurrencySettings validates tools.max_concurrent and its per-tool
+// overrides.
+func (c Config) ToolConcurrencySettings() (ToolConcurrencySettings, error) {
+	if c.Tools.MaxConcurrent < 0 {
+		return ToolConcurrencySettings{}, fmt.Errorf("%w: tools.max_concurrent must be >= 0", ErrInvalidConfig)
+	}
+	settings := ToolConcurrencySettings{
+		Max:    c.Tools.MaxConcurrent,
+		ByTool: make(map[string]int, len(c.Tools.MaxConcurrentByTool)),
+	}
+	for name, limit := range c.Tools.MaxConcurrentByTool {
+		if limit < 0 {
+			return ToolConcurrencySettings{}, fmt.Errorf("%w: tools.max_concurrent_by_tool.%s must be >= 0", ErrInvalidConfig, name)
+		}
+		settings.ByTool[strings.TrimSpace(name)] = limit
+	}
+	return settings, nil
+}
+
+func validTruncation(mode string) bool {
+	switch mode {
+	case "", "head", "tail", "middle-out":
+		return true
+	default:
+		return false
+	}
+}
+
+// FetchSettings returns the validated agent.fetch configuration.
+func (c Config) FetchSettings() (FetchSettings, error) {
+	settings := FetchSettings{Enabled: c.Agent.Fetch.Enabled}
+	if raw := strings.TrimSpace(c.Agent.Fetch.Timeout); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil {
+			return FetchSettings{}, fmt.Errorf("%w: parse agent.fetch.timeout: %v", ErrInvalidConfig, err)
+		}
+		if timeout < 0 {
+			return FetchSettings{}, fmt.Errorf("%w: agent.fetch.timeout must be >= 0", ErrInvalidConfig)
+		}
+		settings.Timeout = timeout
+	}
+	if c.Agent.Fetch.MaxBytes < 0 {
+		return FetchSettings{}, fmt.Errorf("%w: agent.fetch.max_bytes must be >= 0", ErrInvalidConfig)
+	}
+	settings.MaxBytes = c.Agent.Fetch.MaxBytes
+	return settings, nil
+}
+
+// ContextGuardSettings is the validated context-window guard configuration.
+type ContextGuardSettings struct {
+	// Windows maps model names or name prefixes to context window sizes.
+	Windows          map[string]int
+	WarnRatio        float64
+	CompactOnWarning bool
+}
+
+// ContextGuardSettings merges agent.context_windows over the built-in
+// context window sizes and validates the warn ratio.
+func (c Config) ContextGuardSettings() (ContextGuardSettings, error) {
+	windows := make(map[string]int, len(defaultContextWindows)+len(c.Agent.ContextWindows))
+	for model, size := range defaultContextWindows {
+		windows[model] = size
+	}
+	for model, size := range c.Agent.ContextWindows {
+		model = strings.TrimSpace(model)
+		if model == "" {
+			return ContextGuardSettings{}, fmt.Errorf("%w: agent.context_windows has an empty model name", ErrInvalidConfig)
+		}
+		if size <= 0 {
+			return ContextGuardSettings{}, fmt.Errorf("%w: agent.context_windows.%s must be > 0", ErrInvalidConfig, model)
+		}
+		windows[model] = size
+	}
+	if c.Agent.ContextWarnRatio < 0 || c.Agent.ContextWarnRatio > 1 {
+		return ContextGuardSettings{}, fmt.Errorf("%w: agent.context_warn_ratio must be between 0 and 1", ErrInvalidConfig)
+	}
+	return ContextGuardSettings{
+		Windows:          windows,
+		WarnRatio:        c.Agent.ContextWarnRatio,
+		CompactOnWarning: c.Agent.CompactOnContextWarning,
+	}, nil
+}
+
+// ExternalToolSettings returns the validated agent.external_tools declarations.
+func (c Config) ExternalToolSettings() ([]ExternalToolSettings, error) {
+	settings := make([]ExternalToolSettings, 0, len(c.Agent.ExternalTools))
+	seen := make(map[string]bool, len(c.Agent.ExternalTools))
+	for i, tool := range c.Agent.ExternalTools {
+		name := strings.TrimSpace(tool.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: agent.external_tools[%d].name is required", ErrInvalidConfig, i)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: agent.external_tools name %q is declared twice", ErrInvalidConfig, name)
+		}
+		seen[name] = true
+		if len(tool.Command) == 0 || strings.TrimSpace(tool.Command[0]) == "" {
+			return nil, fmt.Errorf("%w: agent.external_tools %q command is required", ErrInvalidConfig, name)
+		}
+
+		var schema json.RawMessage
+		if raw := strings.TrimSpace(tool.Schema); raw != "" {
+			if !json.Valid([]byte(raw)) {
+				return nil, fmt.Errorf("%w: agent.external_tools %q schema is not valid JSON", ErrInvalidConfig, name)
+			}
+			schema = json.RawMessage(raw)
+		}
+
+		var timeout time.Duration
+		if raw := strings.TrimSpace(tool.Timeout); raw != "" {
+			parsed, err := time.ParseDuration(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%w: parse agent.external_tools %q timeout: %v", ErrInvalidConfig, name, err)
+			}
+			timeout = parsed
+		}
+
+		settings = append(settings, ExternalToolSettings{
+			Name:        name,
+			Description: strings.TrimSpace(tool.Description),
+			Schema:      schema,
+			Command:     append([]string(nil), tool.Command...),
+			Timeout:     timeout,
+		})
+	}
+	return settings, nil
+}
+
+func parseRetrySettings(provider string, retry RetryConfig) (RetrySettings, error) {
+	baseDelay, err := time.ParseDuration(strings.TrimSpace(retry.BaseDelay))
+	if err != nil {
+		return RetrySettings{}, fmt.Errorf("%w: parse %s retry base_delay: %v", ErrInvalidConfig, provider, err)
+	}
+	maxDelay, err := time.ParseDuration(strings.TrimSpace(retry.MaxDelay))
+	if err != nil {
+		return RetrySettings{}, fmt.Errorf("%w: parse %s retry max_delay: %v", ErrInvalidConfig, provider, err)
+	}
+	if retry.MaxRetries < 0 {
+		return RetrySettings{}, fmt.Errorf("%w: %s retry max_retries must be >= 0", ErrInvalidConfig, provider)
+	}
+
+	return RetrySettings{
+		MaxRetries: retry.MaxRetries,
+		BaseDelay:  baseDelay,
+		MaxDelay:   maxDelay,
 	}, nil
 }
 
@@ -212,6 +976,33 @@ func applyEnv(cfg *Config) error {
 	if value, ok := os.LookupEnv(envAnthropicVersion); ok && strings.TrimSpace(value) != "" {
 		cfg.Provider.Anthropic.Version = strings.TrimSpace(value)
 	}
+	if value, ok := os.LookupEnv(envOpenAIAPIKey); ok {
+		cfg.Provider.OpenAI.APIKey = value
+	}
+	if value, ok := os.LookupEnv(envOpenAIModel); ok && strings.TrimSpace(value) != "" {
+		cfg.Provider.OpenAI.Model = strings.TrimSpace(value)
+	}
+	if value, ok := os.LookupEnv(envOpenAIBaseURL); ok && strings.TrimSpace(value) != "" {
+		cfg.Provider.OpenAI.BaseURL = strings.TrimSpace(value)
+	}
+	if value, ok := os.LookupEnv(envGeminiAPIKey); ok {
+		cfg.Provider.Gemini.APIKey = value
+	}
+	if value, ok := os.LookupEnv(envGeminiModel); ok && strings.TrimSpace(value) != "" {
+		cfg.Provider.Gemini.Model = strings.TrimSpace(value)
+	}
+	if value, ok := os.LookupEnv(envGeminiBaseURL); ok && strings.TrimSpace(value) != "" {
+		cfg.Provider.Gemini.BaseURL = strings.TrimSpace(value)
+	}
+	if value, ok := os.LookupEnv(envBedrockModel); ok && strings.TrimSpace(value) != "" {
+		cfg.Provider.Bedrock.Model = strings.TrimSpace(value)
+	}
+	if value, ok := os.LookupEnv(envBedrockRegion); ok && strings.TrimSpace(value) != "" {
+		cfg.Provider.Bedrock.Region = strings.TrimSpace(value)
+	}
+	if value, ok := os.LookupEnv(envWorkspaceRoot); ok && strings.TrimSpace(value) != "" {
+		cfg.Workspace.Root = strings.TrimSpace(value)
+	}
 	if value, ok := os.LookupEnv(envRetryMaxRetries); ok && strings.TrimSpace(value) != "" {
 		parsed, err := strconv.Atoi(strings.TrimSpace(value))
 		if err != nil {
@@ -238,6 +1029,69 @@ func validate(cfg Config) error {
 	if _, err := cfg.AnthropicSettings(); err != nil {
 		return err
 	}
+	if strings.EqualFold(strings.TrimSpace(cfg.Provider.Default), "openai") && strings.TrimSpace(cfg.Provider.OpenAI.Model) == "" {
+		return fmt.Errorf("%w: provider.openai.model is required", ErrInvalidConfig)
+	}
+	if _, err := cfg.OpenAISettings(); err != nil {
+		return err
+	}
+	if strings.EqualFold(strings.TrimSpace(cfg.Provider.Default), "gemini") && strings.TrimSpace(cfg.Provider.Gemini.Model) == "" {
+		return fmt.Errorf("%w: provider.gemini.model is required", ErrInvalidConfig)
+	}
+	if _, err := cfg.GeminiSettings(); err != nil {
+		return err
+	}
+	if strings.EqualFold(strings.TrimSpace(cfg.Provider.Default), "bedrock") && strings.TrimSpace(cfg.Provider.Bedrock.Model) == "" {
+		return fmt.Errorf("%w: provider.bedrock.model is required", ErrInvalidConfig)
+	}
+	if _, err := cfg.BedrockSettings(); err != nil {
+		return err
+	}
+	if _, err := cfg.ToolRetrySettings(); err != nil {
+		return err
+	}
+	if _, err := cfg.SessionDir(); err != nil {
+		return err
+	}
+	if cfg.Session.MaxEntries < 0 {
+		return fmt.Errorf("%w: session.max_entries must be >= 0", ErrInvalidConfig)
+	}
+	if cfg.Agent.ThinkingBudget < 0 {
+		return fmt.Errorf("%w: agent.thinking_budget must be >= 0", ErrInvalidConfig)
+	}
+	if cfg.TUI.ChatLimit < 0 {
+		return fmt.Errorf("%w: tui.chat_limit must be >= 0", ErrInvalidConfig)
+	}
+	if _, err := cfg.ThemeFile(); err != nil {
+		return err
+	}
+	if _, err := cfg.KeyBindings(); err != nil {
+		return err
+	}
+	if _, err := cfg.ContextGuardSettings(); err != nil {
+		return err
+	}
+	if _, err := cfg.ToolTimeoutSetting(); err != nil {
+		return err
+	}
+	if _, err := cfg.StreamIdleTimeoutSetting(); err != nil {
+		return err
+	}
+	if _, err := cfg.TemperatureSetting(); err != nil {
+		return err
+	}
+	if _, err := cfg.ExternalToolSettings(); err != nil {
+		return err
+	}
+	if _, err := cfg.FetchSettings(); err != nil {
+		return err
+	}
+	if _, err := cfg.ToolResultSettings(); err != nil {
+		return err
+	}
+	if _, err := cfg.ToolConcurrencySettings(); err != nil {
+		return err
+	}
 	return nil
 }
 
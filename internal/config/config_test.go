@@ -1,8 +1,10 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -17,6 +19,9 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.Provider.Anthropic.Model != "claude-sonnet-4-20250514" {
 		t.Fatalf("Provider.Anthropic.Model = %q, want %q", cfg.Provider.Anthropic.Model, "claude-sonnet-4-20250514")
 	}
+	if !cfg.Provider.PromptCache {
+		t.Fatalf("Provider.PromptCache = false, want prompt caching on by default")
+	}
 	if cfg.Provider.Anthropic.Retry.MaxRetries != 3 {
 		t.Fatalf("Provider.Anthropic.Retry.MaxRetries = %d, want %d", cfg.Provider.Anthropic.Retry.MaxRetries, 3)
 	}
@@ -86,6 +91,83 @@ max_delay = "9s"
 	}
 }
 
+func TestLoadReadsRetentionLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	content := `
+[session]
+max_entries = 2000
+
+[tui]
+chat_limit = 150
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	cfg, err := Load(LoadOptions{Path: path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Session.MaxEntries != 2000 || cfg.TUI.ChatLimit != 150 {
+		t.Fatalf("MaxEntries, ChatLimit = %d, %d; want 2000, 150", cfg.Session.MaxEntries, cfg.TUI.ChatLimit)
+	}
+
+	if err := os.WriteFile(path, []byte("[tui]\nchat_limit = -1\n"), 0o644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	if _, err := Load(LoadOptions{Path: path}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Load(chat_limit = -1) error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestLoadReadsToolConcurrencyLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	content := `
+[tools]
+max_concurrent = 4
+
+[tools.max_concurrent_by_tool]
+bash = 1
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	cfg, err := Load(LoadOptions{Path: path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Tools.MaxConcurrent != 4 || cfg.Tools.MaxConcurrentByTool["bash"] != 1 {
+		t.Fatalf("Tools = %+v, want max_concurrent 4, bash 1", cfg.Tools)
+	}
+}
+
+func TestLoadReadsThinkingSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	content := `
+[agent]
+thinking_budget = 4096
+
+[session]
+persist_thinking = true
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	cfg, err := Load(LoadOptions{Path: path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Agent.ThinkingBudget != 4096 || !cfg.Session.PersistThinking {
+		t.Fatalf("ThinkingBudget, PersistThinking = %d, %v; want 4096, true", cfg.Agent.ThinkingBudget, cfg.Session.PersistThinking)
+	}
+
+	if err := os.WriteFile(path, []byte("[agent]\nthinking_budget = -1\n"), 0o644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	if _, err := Load(LoadOptions{Path: path}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Load(thinking_budget = -1) error = %v, want ErrInvalidConfig", err)
+	}
+}
+
 func TestAnthropicSettingsParsesRetryDurations(t *testing.T) {
 	t.Parallel()
 
@@ -126,3 +208,484 @@ func TestAnthropicSettingsRejectsInvalidDuration(t *testing.T) {
 		t.Fatalf("expected error for invalid retry base delay")
 	}
 }
+
+func TestToolRetrySettingsDefaultsToDisabled(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	settings, err := cfg.ToolRetrySettings()
+	if err != nil {
+		t.Fatalf("ToolRetrySettings() error = %v", err)
+	}
+	if settings.MaxRetries != 0 {
+		t.Fatalf("Retry.MaxRetries = %d, want 0", settings.MaxRetries)
+	}
+
+	cfg.Agent.ToolRetry = RetryConfig{MaxRetries: 2, BaseDelay: "100ms", MaxDelay: "1s"}
+	settings, err = cfg.ToolRetrySettings()
+	if err != nil {
+		t.Fatalf("ToolRetrySettings() error = %v", err)
+	}
+	if settings.MaxRetries != 2 || settings.BaseDelay != 100*time.Millisecond || settings.MaxDelay != time.Second {
+		t.Fatalf("ToolRetrySettings() = %#v, want 2 retries 100ms..1s", settings)
+	}
+
+	cfg.Agent.ToolRetry.MaxDelay = "soon"
+	if _, err := cfg.ToolRetrySettings(); err == nil {
+		t.Fatal("ToolRetrySettings() error = nil, want invalid duration error")
+	}
+}
+
+func TestToolTimeoutSettingParsesDuration(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	timeout, err := cfg.ToolTimeoutSetting()
+	if err != nil {
+		t.Fatalf("ToolTimeoutSetting() error = %v", err)
+	}
+	if timeout != 0 {
+		t.Fatalf("ToolTimeoutSetting() = %v, want disabled by default", timeout)
+	}
+
+	cfg.Agent.ToolTimeout = "90s"
+	timeout, err = cfg.ToolTimeoutSetting()
+	if err != nil {
+		t.Fatalf("ToolTimeoutSetting() error = %v", err)
+	}
+	if timeout != 90*time.Second {
+		t.Fatalf("ToolTimeoutSetting() = %v, want 90s", timeout)
+	}
+
+	cfg.Agent.ToolTimeout = "eventually"
+	if _, err := cfg.ToolTimeoutSetting(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("ToolTimeoutSetting() error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestStreamIdleTimeoutSettingParsesDuration(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	if timeout, err := cfg.StreamIdleTimeoutSetting(); err != nil || timeout != 0 {
+		t.Fatalf("StreamIdleTimeoutSetting() = %v, %v; want disabled by default", timeout, err)
+	}
+
+	cfg.Provider.StreamIdleTimeout = "2m"
+	timeout, err := cfg.StreamIdleTimeoutSetting()
+	if err != nil {
+		t.Fatalf("StreamIdleTimeoutSetting() error = %v", err)
+	}
+	if timeout != 2*time.Minute {
+		t.Fatalf("StreamIdleTimeoutSetting() = %v, want 2m", timeout)
+	}
+
+	cfg.Provider.StreamIdleTimeout = "-1s"
+	if _, err := cfg.StreamIdleTimeoutSetting(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("StreamIdleTimeoutSetting() error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestTemperatureSettingMapsThinkingLevel(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	if got, err := cfg.TemperatureSetting(); err != nil || got != nil {
+		t.Fatalf("TemperatureSetting(default) = %v, %v; want nil", got, err)
+	}
+
+	cfg.Agent.ThinkingLevel = "medium"
+	got, err := cfg.TemperatureSetting()
+	if err != nil {
+		t.Fatalf("TemperatureSetting() error = %v", err)
+	}
+	if got == nil || *got != 0.7 {
+		t.Fatalf("TemperatureSetting() = %v, want 0.7 for medium", got)
+	}
+
+	cfg.Agent.ThinkingLevel = "High"
+	if got, err := cfg.TemperatureSetting(); err != nil || got == nil || *got != 1.0 {
+		t.Fatalf("TemperatureSetting(high) = %v, %v; want 1.0", got, err)
+	}
+
+	override := 0.3
+	cfg.Agent.Temperature = &override
+	if got, err := cfg.TemperatureSetting(); err != nil || got == nil || *got != 0.3 {
+		t.Fatalf("TemperatureSetting(override) = %v, %v; want 0.3", got, err)
+	}
+
+	cfg.Agent.Temperature = nil
+	cfg.Agent.ThinkingLevel = ""
+	if got, err := cfg.TemperatureSetting(); err != nil || got != nil {
+		t.Fatalf("TemperatureSetting(empty) = %v, %v; want nil", got, err)
+	}
+
+	cfg.Agent.ThinkingLevel = "extreme"
+	if _, err := cfg.TemperatureSetting(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("TemperatureSetting(extreme) error = %v, want ErrInvalidConfig", err)
+	}
+	tooHot := 2.5
+	cfg.Agent.Temperature = &tooHot
+	if _, err := cfg.TemperatureSetting(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("TemperatureSetting(2.5) error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestFetchSettingsDefaultsOffAndValidates(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	settings, err := cfg.FetchSettings()
+	if err != nil {
+		t.Fatalf("FetchSettings() error = %v", err)
+	}
+	if settings.Enabled {
+		t.Fatalf("FetchSettings().Enabled = true, want fetch off by default")
+	}
+
+	cfg.Agent.Fetch = FetchConfig{Enabled: true, Timeout: "10s", MaxBytes: 1024}
+	settings, err = cfg.FetchSettings()
+	if err != nil {
+		t.Fatalf("FetchSettings() error = %v", err)
+	}
+	if !settings.Enabled || settings.Timeout != 10*time.Second || settings.MaxBytes != 1024 {
+		t.Fatalf("FetchSettings() = %+v, want enabled with 10s and 1024 bytes", settings)
+	}
+
+	cfg.Agent.Fetch.Timeout = "soon"
+	if _, err := cfg.FetchSettings(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("FetchSettings(bad timeout) error = %v, want ErrInvalidConfig", err)
+	}
+	cfg.Agent.Fetch = FetchConfig{MaxBytes: -1}
+	if _, err := cfg.FetchSettings(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("FetchSettings(negative max_bytes) error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestToolResultSettingsValidatesModes(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	cfg.Agent.MaxToolResultBytes = 20000
+	cfg.Agent.ToolResultTruncation = "head"
+	cfg.Agent.ToolResultTruncationByTool = map[string]string{"bash": "tail"}
+	settings, err := cfg.ToolResultSettings()
+	if err != nil {
+		t.Fatalf("ToolResultSettings() error = %v", err)
+	}
+	if settings.MaxBytes != 20000 || settings.Truncation != "head" || settings.ByTool["bash"] != "tail" {
+		t.Fatalf("ToolResultSettings() = %+v, want 20000 bytes, head, bash tail", settings)
+	}
+
+	cfg.Agent.ToolResultTruncationByTool = map[string]string{"bash": "middle"}
+	if _, err := cfg.ToolResultSettings(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("ToolResultSettings(bad per-tool mode) error = %v, want ErrInvalidConfig", err)
+	}
+	cfg.Agent.ToolResultTruncationByTool = nil
+	cfg.Agent.MaxToolResultBytes = -1
+	if _, err := cfg.ToolResultSettings(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("ToolResultSettings(negative max) error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestProjectPromptPathsResolveAgainstWorkspaceRoot(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	root := filepath.Join(string(filepath.Separator), "work", "project")
+	if got, want := cfg.ProjectPromptPaths(root), []string{
+		filepath.Join(root, ".gar", "system.md"),
+		filepath.Join(root, "AGENTS.md"),
+		filepath.Join(root, "CLAUDE.md"),
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ProjectPromptPaths() = %v, want %v", got, want)
+	}
+
+	absolute := filepath.Join(string(filepath.Separator), "etc", "gar", "prompt.md")
+	cfg.Agent.ProjectPromptFiles = []string{" ", absolute, "docs/agent.md"}
+	if got, want := cfg.ProjectPromptPaths(root), []string{absolute, filepath.Join(root, "docs", "agent.md")}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ProjectPromptPaths() = %v, want %v", got, want)
+	}
+}
+
+func TestToolConcurrencySettingsValidatesLimits(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	cfg.Tools.MaxConcurrent = 4
+	cfg.Tools.MaxConcurrentByTool = map[string]int{" bash ": 1}
+	settings, err := cfg.ToolConcurrencySettings()
+	if err != nil {
+		t.Fatalf("ToolConcurrencySettings() error = %v", err)
+	}
+	if settings.Max != 4 || settings.ByTool["bash"] != 1 {
+		t.Fatalf("ToolConcurrencySettings() = %+v, want max 4, bash 1", settings)
+	}
+
+	cfg.Tools.MaxConcurrentByTool = map[string]int{"bash": -1}
+	if _, err := cfg.ToolConcurrencySettings(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("ToolConcurrencySettings(negative per-tool) error = %v, want ErrInvalidConfig", err)
+	}
+	cfg.Tools.MaxConcurrentByTool = nil
+	cfg.Tools.MaxConcurrent = -1
+	if _, err := cfg.ToolConcurrencySettings(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("ToolConcurrencySettings(negative max) error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestKeyBindingsValidatesActionsAndKeys(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	cfg.TUI.Keys = map[string][]string{"submit": {"Ctrl+S"}, "scroll_down": {"j", "down", "f5"}}
+	bindings, err := cfg.KeyBindings()
+	if err != nil {
+		t.Fatalf("KeyBindings() error = %v", err)
+	}
+	if got := bindings["submit"]; len(got) != 1 || got[0] != "ctrl+s" {
+		t.Fatalf("KeyBindings()[submit] = %q, want [ctrl+s]", got)
+	}
+
+	for name, keys := range map[string]map[string][]string{
+		"unknown action": {"launch": {"x"}},
+		"no keys":        {"submit": {}},
+		"bad modifier":   {"submit": {"hyper+s"}},
+		"bad key name":   {"submit": {"ctrl+return"}},
+	} {
+		cfg.TUI.Keys = keys
+		if _, err := cfg.KeyBindings(); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("KeyBindings(%s) error = %v, want ErrInvalidConfig", name, err)
+		}
+	}
+}
+
+func TestOpenAISettingsAppliesEnvOverrides(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "openai-key")
+	t.Setenv("GAR_OPENAI_MODEL", "gpt-4o-mini")
+	t.Setenv("GAR_OPENAI_BASE_URL", "https://openai.example/v1")
+
+	cfg, err := Load(LoadOptions{Path: filepath.Join(t.TempDir(), "missing.toml")})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	settings, err := cfg.OpenAISettings()
+	if err != nil {
+		t.Fatalf("OpenAISettings() error = %v", err)
+	}
+	if settings.APIKey != "openai-key" {
+		t.Fatalf("APIKey = %q, want %q", settings.APIKey, "openai-key")
+	}
+	if settings.Model != "gpt-4o-mini" {
+		t.Fatalf("Model = %q, want %q", settings.Model, "gpt-4o-mini")
+	}
+	if settings.BaseURL != "https://openai.example/v1" {
+		t.Fatalf("BaseURL = %q, want %q", settings.BaseURL, "https://openai.example/v1")
+	}
+	if settings.Retry.MaxRetries != 3 || settings.Retry.BaseDelay != 300*time.Millisecond {
+		t.Fatalf("Retry = %+v, want defaults", settings.Retry)
+	}
+}
+
+func TestGeminiSettingsAppliesEnvOverrides(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "gemini-key")
+	t.Setenv("GAR_GEMINI_MODEL", "gemini-2.5-pro")
+	t.Setenv("GAR_GEMINI_BASE_URL", "https://gemini.example/v1beta")
+
+	cfg, err := Load(LoadOptions{Path: filepath.Join(t.TempDir(), "missing.toml")})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	settings, err := cfg.GeminiSettings()
+	if err != nil {
+		t.Fatalf("GeminiSettings() error = %v", err)
+	}
+	if settings.APIKey != "gemini-key" {
+		t.Fatalf("APIKey = %q, want %q", settings.APIKey, "gemini-key")
+	}
+	if settings.Model != "gemini-2.5-pro" {
+		t.Fatalf("Model = %q, want %q", settings.Model, "gemini-2.5-pro")
+	}
+	if settings.BaseURL != "https://gemini.example/v1beta" {
+		t.Fatalf("BaseURL = %q, want %q", settings.BaseURL, "https://gemini.example/v1beta")
+	}
+	if settings.Retry.MaxRetries != 3 || settings.Retry.BaseDelay != 300*time.Millisecond {
+		t.Fatalf("Retry = %+v, want defaults", settings.Retry)
+	}
+}
+
+func TestWorkspaceRootDefaultsToCwdAndValidatesDirectory(t *testing.T) {
+	t.Parallel()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() error = %v", err)
+	}
+	cfg := Default()
+	root, err := cfg.WorkspaceRoot()
+	if err != nil {
+		t.Fatalf("WorkspaceRoot() error = %v", err)
+	}
+	if root != cwd {
+		t.Fatalf("WorkspaceRoot() = %q, want cwd %q", root, cwd)
+	}
+
+	dir := t.TempDir()
+	cfg.Workspace.Root = dir
+	if root, err = cfg.WorkspaceRoot(); err != nil || root != dir {
+		t.Fatalf("WorkspaceRoot() = %q, %v; want %q", root, err, dir)
+	}
+
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	for _, bad := range []string{file, filepath.Join(dir, "missing")} {
+		cfg.Workspace.Root = bad
+		if _, err := cfg.WorkspaceRoot(); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("WorkspaceRoot(%q) error = %v, want ErrInvalidConfig", bad, err)
+		}
+	}
+}
+
+func TestSessionConfigDefaultsAndDirOverride(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	content := `
+[session]
+enabled = false
+dir = "~/gar-sessions"
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	t.Setenv("HOME", dir)
+
+	if cfg := Default(); !cfg.Session.Enabled {
+		t.Fatal("Default().Session.Enabled = false, want true")
+	}
+	if got, err := Default().SessionDir(); err != nil || got != "" {
+		t.Fatalf("Default().SessionDir() = %q, %v; want empty", got, err)
+	}
+
+	cfg, err := Load(LoadOptions{Path: path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Session.Enabled {
+		t.Fatal("Session.Enabled = true, want false from file")
+	}
+	if !cfg.Session.AutoName {
+		t.Fatal("Session.AutoName = false, want default true")
+	}
+	got, err := cfg.SessionDir()
+	if err != nil {
+		t.Fatalf("SessionDir() error = %v", err)
+	}
+	if want := filepath.Join(dir, "gar-sessions"); got != want {
+		t.Fatalf("SessionDir() = %q, want %q", got, want)
+	}
+}
+
+func TestThemeFileResolvesHome(t *testing.T) {
+	t.Parallel()
+
+	if got, err := Default().ThemeFile(); err != nil || got != "" {
+		t.Fatalf("Default().ThemeFile() = %q, %v; want empty", got, err)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("UserHomeDir() error = %v", err)
+	}
+	cfg := Default()
+	cfg.TUI.ThemeFile = "~/.config/gar/theme.toml"
+	got, err := cfg.ThemeFile()
+	if err != nil {
+		t.Fatalf("ThemeFile() error = %v", err)
+	}
+	if want := filepath.Join(home, ".config", "gar", "theme.toml"); got != want {
+		t.Fatalf("ThemeFile() = %q, want %q", got, want)
+	}
+}
+
+func TestDebugLogFileResolvesHome(t *testing.T) {
+	t.Parallel()
+
+	if got, err := Default().DebugLogFile(); err != nil || got != "" {
+		t.Fatalf("Default().DebugLogFile() = %q, %v; want empty", got, err)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("UserHomeDir() error = %v", err)
+	}
+	cfg := Default()
+	cfg.Debug.LogFile = "~/gar-debug.jsonl"
+	got, err := cfg.DebugLogFile()
+	if err != nil {
+		t.Fatalf("DebugLogFile() error = %v", err)
+	}
+	if want := filepath.Join(home, "gar-debug.jsonl"); got != want {
+		t.Fatalf("DebugLogFile() = %q, want %q", got, want)
+	}
+}
+
+func TestContextGuardSettingsMergesDefaults(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	cfg.Agent.ContextWindows = map[string]int{"claude": 1000, "local-llm": 32768}
+	cfg.Agent.ContextWarnRatio = 0.8
+	settings, err := cfg.ContextGuardSettings()
+	if err != nil {
+		t.Fatalf("ContextGuardSettings() error = %v", err)
+	}
+	if settings.Windows["claude"] != 1000 || settings.Windows["local-llm"] != 32768 || settings.Windows["gpt-4o"] != 128000 {
+		t.Fatalf("Windows = %#v, want overrides merged over defaults", settings.Windows)
+	}
+	if settings.WarnRatio != 0.8 {
+		t.Fatalf("WarnRatio = %v, want 0.8", settings.WarnRatio)
+	}
+
+	cfg.Agent.ContextWarnRatio = 1.5
+	if _, err := cfg.ContextGuardSettings(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("ContextGuardSettings(ratio 1.5) error = %v, want ErrInvalidConfig", err)
+	}
+	cfg.Agent.ContextWarnRatio = 0
+	cfg.Agent.ContextWindows = map[string]int{"bad": 0}
+	if _, err := cfg.ContextGuardSettings(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("ContextGuardSettings(zero window) error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestExternalToolSettingsValidatesDeclarations(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	cfg.Agent.ExternalTools = []ExternalToolConfig{{
+		Name:        "lint",
+		Description: "Run the linter",
+		Schema:      `{"type":"object"}`,
+		Command:     []string{"./scripts/lint-json", "--fast"},
+		Timeout:     "45s",
+	}}
+	settings, err := cfg.ExternalToolSettings()
+	if err != nil {
+		t.Fatalf("ExternalToolSettings() error = %v", err)
+	}
+	if len(settings) != 1 || settings[0].Timeout != 45*time.Second || len(settings[0].Command) != 2 || string(settings[0].Schema) != `{"type":"object"}` {
+		t.Fatalf("ExternalToolSettings() = %#v, want parsed lint tool", settings)
+	}
+
+	for name, tool := range map[string]ExternalToolConfig{
+		"missing name":    {Command: []string{"lint"}},
+		"missing command": {Name: "lint"},
+		"bad schema":      {Name: "lint", Command: []string{"lint"}, Schema: "{"},
+		"bad timeout":     {Name: "lint", Command: []string{"lint"}, Timeout: "soon"},
+	} {
+		cfg.Agent.ExternalTools = []ExternalToolConfig{tool}
+		if _, err := cfg.ExternalToolSettings(); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("ExternalToolSettings(%s) error = %v, want ErrInvalidConfig", name, err)
+		}
+	}
+}
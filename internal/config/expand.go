@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// secretCommandTimeout bounds each api_key_cmd, which may wait on a password
+// manager agent.
+const secretCommandTimeout = 30 * time.Second
+
+// envReferencePattern matches ${NAME}, and $${NAME} as its escaped literal form.
+var envReferencePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnvReferences replaces ${NAME} with the value of environment variable
+// NAME in every string value of cfg, including list items and map values.
+// Write $${NAME} for a literal ${NAME}. A reference to an unset variable
+// fails the load, naming the config key that holds it.
+func expandEnvReferences(cfg *Config) error {
+	return expandValue(reflect.ValueOf(cfg).Elem(), "")
+}
+
+func expandValue(value reflect.Value, key string) error {
+	switch value.Kind() {
+	case reflect.String:
+		expanded, err := expandEnvString(value.String())
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidConfig, key, err)
+		}
+		value.SetString(expanded)
+	case reflect.Pointer:
+		if !value.IsNil() {
+			return expandValue(value.Elem(), key)
+		}
+	case reflect.Struct:
+		for i := range value.NumField() {
+			field := value.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			if err := expandValue(value.Field(i), joinConfigKey(key, name)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := range value.Len() {
+			if err := expandValue(value.Index(i), fmt.Sprintf("%s[%d]", key, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if value.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		for _, mapKey := range value.MapKeys() {
+			expanded, err := expandEnvString(value.MapIndex(mapKey).String())
+			if err != nil {
+				return fmt.Errorf("%w: %s.%v: %v", ErrInvalidConfig, key, mapKey.Interface(), err)
+			}
+			value.SetMapIndex(mapKey, reflect.ValueOf(expanded).Convert(value.Type().Elem()))
+		}
+	}
+	return nil
+}
+
+func expandEnvString(raw string) (string, error) {
+	if !strings.Contains(raw, "${") {
+		return raw, nil
+	}
+	var missing []string
+	expanded := envReferencePattern.ReplaceAllStringFunc(raw, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		name := match[2 : len(match)-1]
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+func joinConfigKey(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// resolveSecretCommands runs the active provider's api_key_cmd when its
+// api_key is still empty after the config file and environment are applied,
+// and uses its trimmed stdout as the key. Other providers' commands are left
+// alone, since they may prompt or wait on a password manager.
+func resolveSecretCommands(cfg *Config) error {
+	targets := map[string]struct {
+		key     string
+		command string
+		apiKey  *string
+	}{
+		"anthropic": {"provider.anthropic.api_key_cmd", cfg.Provider.Anthropic.APIKeyCmd, &cfg.Provider.Anthropic.APIKey},
+		"openai":    {"provider.openai.api_key_cmd", cfg.Provider.OpenAI.APIKeyCmd, &cfg.Provider.OpenAI.APIKey},
+		"gemini":    {"provider.gemini.api_key_cmd", cfg.Provider.Gemini.APIKeyCmd, &cfg.Provider.Gemini.APIKey},
+	}
+	active := strings.ToLower(strings.TrimSpace(cfg.Provider.Default))
+	if active == "" {
+		active = "anthropic"
+	}
+	target, ok := targets[active]
+	if !ok {
+		return nil
+	}
+	command := strings.TrimSpace(target.command)
+	if command == "" || strings.TrimSpace(*target.apiKey) != "" {
+		return nil
+	}
+	secret, err := runSecretCommand(command)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidConfig, target.key, err)
+	}
+	*target.apiKey = secret
+	return nil
+}
+
+func runSecretCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/c", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", fmt.Errorf("run %q: %v: %s", command, err, detail)
+		}
+		return "", fmt.Errorf("run %q: %v", command, err)
+	}
+	secret := strings.TrimSpace(string(out))
+	if secret == "" {
+		return "", fmt.Errorf("%q printed nothing", command)
+	}
+	return secret, nil
+}
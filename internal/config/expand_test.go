@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadExpandsEnvironmentReferences(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	content := `
+[provider.anthropic]
+api_key = "${GAR_TEST_SECRET}"
+base_url = "https://${GAR_TEST_HOST}/v1"
+
+[agent]
+project_prompt_files = ["${GAR_TEST_HOST}.md", "$${KEEP}"]
+
+[agent.bash]
+deny = ["rm -rf ${GAR_TEST_HOST}"]
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	os.Unsetenv("ANTHROPIC_API_KEY")
+	t.Setenv("GAR_ANTHROPIC_BASE_URL", "")
+	t.Setenv("GAR_TEST_SECRET", "sk-from-env")
+	t.Setenv("GAR_TEST_HOST", "proxy.example")
+
+	cfg, err := Load(LoadOptions{Path: path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := cfg.Provider.Anthropic.APIKey; got != "sk-from-env" {
+		t.Fatalf("APIKey = %q, want sk-from-env", got)
+	}
+	if got := cfg.Provider.Anthropic.BaseURL; got != "https://proxy.example/v1" {
+		t.Fatalf("BaseURL = %q, want expanded host", got)
+	}
+	if got := cfg.Agent.ProjectPromptFiles; len(got) != 2 || got[0] != "proxy.example.md" || got[1] != "${KEEP}" {
+		t.Fatalf("ProjectPromptFiles = %q, want expanded and escaped entries", got)
+	}
+	if got := cfg.Agent.Bash.Deny; len(got) != 1 || got[0] != "rm -rf proxy.example" {
+		t.Fatalf("Bash.Deny = %q, want expanded pattern", got)
+	}
+}
+
+func TestLoadFailsOnUnsetEnvironmentReference(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("[provider.openai]\nbase_url = \"${GAR_TEST_UNSET_VAR}\"\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	t.Setenv("GAR_TEST_UNSET_VAR", "")
+	os.Unsetenv("GAR_TEST_UNSET_VAR")
+
+	_, err := Load(LoadOptions{Path: path})
+	if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "provider.openai.base_url") || !strings.Contains(err.Error(), "GAR_TEST_UNSET_VAR is not set") {
+		t.Fatalf("Load() error = %v, want unset variable named with its key", err)
+	}
+}
+
+func TestLoadRunsAPIKeyCommand(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	content := `
+[provider.anthropic]
+api_key_cmd = "echo '  sk-from-command  '"
+
+[provider.openai]
+api_key_cmd = "exit 1"
+
+[provider.gemini]
+api_key_cmd = "exit 1"
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	os.Unsetenv("ANTHROPIC_API_KEY")
+	t.Setenv("OPENAI_API_KEY", "")
+	os.Unsetenv("OPENAI_API_KEY")
+	t.Setenv("GEMINI_API_KEY", "")
+	os.Unsetenv("GEMINI_API_KEY")
+
+	cfg, err := Load(LoadOptions{Path: path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := cfg.Provider.Anthropic.APIKey; got != "sk-from-command" {
+		t.Fatalf("Anthropic APIKey = %q, want trimmed command output", got)
+	}
+	if got := cfg.Provider.OpenAI.APIKey; got != "" {
+		t.Fatalf("OpenAI APIKey = %q, want inactive provider's command left unrun", got)
+	}
+}
+
+func TestLoadFailsWhenAPIKeyCommandFails(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("[provider]\ndefault = \"gemini\"\n\n[provider.gemini]\nmodel = \"gemini-2.5-pro\"\napi_key_cmd = \"echo locked >&2; exit 3\"\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	t.Setenv("GEMINI_API_KEY", "")
+	os.Unsetenv("GEMINI_API_KEY")
+
+	_, err := Load(LoadOptions{Path: path})
+	if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "provider.gemini.api_key_cmd") || !strings.Contains(err.Error(), "locked") {
+		t.Fatalf("Load() error = %v, want failing command reported with its stderr", err)
+	}
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redactedSecret = "[REDACTED]"
+
+// DebugLogProvider wraps a Provider and writes each request and every event
+// streamed back as JSON lines, so provider quirks can be reproduced offline.
+// Write failures are ignored; logging never affects the run.
+type DebugLogProvider struct {
+	inner   Provider
+	secrets []string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewDebugLogProvider returns a Provider that logs to w. Any occurrence of a
+// non-empty secret, such as an API key, is redacted from the log.
+func NewDebugLogProvider(inner Provider, w io.Writer, secrets ...string) *DebugLogProvider {
+	kept := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		if strings.TrimSpace(secret) != "" {
+			kept = append(kept, secret)
+		}
+	}
+	return &DebugLogProvider{inner: inner, secrets: kept, w: w}
+}
+
+// debugRecord is one line of the debug log.
+type debugRecord struct {
+	Time    time.Time   `json:"time"`
+	Type    string      `json:"type"`
+	Request *Request    `json:"request,omitempty"`
+	Event   *debugEvent `json:"event,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// debugEvent renders Event.Err as text, which encoding/json cannot marshal.
+type debugEvent struct {
+	Event
+	Err string `json:",omitempty"`
+}
+
+// Stream logs req, forwards it to the wrapped provider, and logs each event
+// before passing it on.
+func (p *DebugLogProvider) Stream(ctx context.Context, req *Request) (<-chan Event, error) {
+	p.write(debugRecord{Type: "request", Request: req})
+	in, err := p.inner.Stream(ctx, req)
+	if err != nil {
+		p.write(debugRecord{Type: "stream_error", Error: err.Error()})
+		return nil, err
+	}
+
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		for ev := range in {
+			logged := debugEvent{Event: ev}
+			if ev.Err != nil {
+				logged.Err = ev.Err.Error()
+			}
+			p.write(debugRecord{Type: "event", Event: &logged})
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (p *DebugLogProvider) write(record debugRecord) {
+	record.Time = time.Now().UTC()
+	data, err := json.Marshal(record)
+	if err != nil {
+		data, _ = json.Marshal(debugRecord{Time: record.Time, Type: record.Type, Error: "marshal: " + err.Error()})
+	}
+	line := string(data)
+	for _, secret := range p.secrets {
+		line = strings.ReplaceAll(line, secret, redactedSecret)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = io.WriteString(p.w, line+"\n")
+}
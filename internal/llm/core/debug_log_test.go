@@ -0,0 +1,74 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type scriptedStreamProvider struct {
+	events []Event
+}
+
+func (p scriptedStreamProvider) Stream(context.Context, *Request) (<-chan Event, error) {
+	out := make(chan Event, len(p.events))
+	for _, ev := range p.events {
+		out <- ev
+	}
+	close(out)
+	return out, nil
+}
+
+func TestDebugLogProviderRecordsRequestAndEvents(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	inner := scriptedStreamProvider{events: []Event{
+		{Type: EventTextDelta, TextDelta: "hi"},
+		{Type: EventError, Err: errors.New("boom")},
+		{Type: EventDone, Done: &DonePayload{Reason: StopReasonStop}},
+	}}
+	provider := NewDebugLogProvider(inner, &buf, "sk-secret", "")
+
+	req := &Request{Model: "m", Messages: []Message{{Role: RoleUser, Content: []ContentBlock{{Type: ContentTypeText, Text: "leak sk-secret"}}}}}
+	stream, err := provider.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var got []Event
+	for ev := range stream {
+		got = append(got, ev)
+	}
+	if len(got) != 3 || got[0].TextDelta != "hi" || got[1].Err == nil {
+		t.Fatalf("forwarded events = %+v, want the inner stream unchanged", got)
+	}
+
+	if strings.Contains(buf.String(), "sk-secret") {
+		t.Fatalf("log leaked secret:\n%s", buf.String())
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("log lines = %d, want 4:\n%s", len(lines), buf.String())
+	}
+	var first debugRecord
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("Unmarshal(request) error = %v", err)
+	}
+	if first.Type != "request" || first.Request == nil || first.Request.Model != "m" {
+		t.Fatalf("first record = %+v, want the request", first)
+	}
+	if text := first.Request.Messages[0].Content[0].Text; text != "leak "+redactedSecret {
+		t.Fatalf("request text = %q, want secret redacted", text)
+	}
+	var errRecord map[string]any
+	if err := json.Unmarshal([]byte(lines[2]), &errRecord); err != nil {
+		t.Fatalf("Unmarshal(error event) error = %v", err)
+	}
+	event, _ := errRecord["event"].(map[string]any)
+	if errRecord["type"] != "event" || event["Type"] != "error" || event["Err"] != "boom" {
+		t.Fatalf("error record = %v, want event with Err text", errRecord)
+	}
+}
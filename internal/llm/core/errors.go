@@ -7,4 +7,39 @@ var (
 	ErrInvalidRequest = errors.New("invalid llm request")
 	// ErrMissingAPIKey indicates missing provider API key.
 	ErrMissingAPIKey = errors.New("missing api key")
+	// ErrRateLimited indicates the provider rejected the request for exceeding
+	// a rate limit.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrContextTooLong indicates the request does not fit the model's
+	// context window.
+	ErrContextTooLong = errors.New("context too long")
+	// ErrAuthFailed indicates the provider rejected the API credentials.
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrOverloaded indicates the provider is temporarily over capacity.
+	ErrOverloaded = errors.New("provider overloaded")
 )
+
+// kindError attaches a classification sentinel to a provider error without
+// changing its message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// WithErrorKind wraps err so errors.Is matches kind, one of the provider
+// error sentinels, as well as everything err already matches. A nil kind
+// returns err unchanged.
+func WithErrorKind(err, kind error) error {
+	if err == nil || kind == nil || errors.Is(err, kind) {
+		return err
+	}
+	return kindError{kind: kind, err: err}
+}
@@ -0,0 +1,27 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWithErrorKindKeepsMessageAndChain(t *testing.T) {
+	t.Parallel()
+
+	base := MarkRetryable(fmt.Errorf("429 Too Many Requests"))
+	err := WithErrorKind(base, ErrRateLimited)
+
+	if err.Error() != base.Error() {
+		t.Fatalf("Error() = %q, want %q", err.Error(), base.Error())
+	}
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("errors.Is(err, ErrRateLimited) = false, want true")
+	}
+	if !IsRetryableError(err) {
+		t.Fatalf("IsRetryableError(err) = false, want wrapped error still retryable")
+	}
+	if got := WithErrorKind(base, nil); got != base {
+		t.Fatalf("WithErrorKind(err, nil) = %v, want err unchanged", got)
+	}
+}
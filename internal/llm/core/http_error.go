@@ -0,0 +1,68 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxErrorBodySize bounds how much of an error response body is kept.
+const maxErrorBodySize = 4 * 1024
+
+// HTTPStatusError is a non-2xx response from a provider's HTTP API.
+type HTTPStatusError struct {
+	// Provider names the API in the error message, such as "openai".
+	Provider   string
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay requested by the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s api error: status %d", e.Provider, e.StatusCode)
+	}
+	return fmt.Sprintf("%s api error: status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// CheckHTTPResponse returns nil for a 2xx resp. Otherwise it returns an
+// *HTTPStatusError holding the start of the body, marked retryable with its
+// Retry-After delay when the status is transient.
+func CheckHTTPResponse(provider string, resp *http.Response) error {
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	apiErr := &HTTPStatusError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(raw)),
+		RetryAfter: ParseRetryAfter(resp.Header, time.Now()),
+	}
+	if IsRetryableStatus(apiErr.StatusCode) {
+		return MarkRetryableAfter(apiErr, apiErr.RetryAfter)
+	}
+	return apiErr
+}
+
+// IsRetryableStatus reports whether an HTTP status is a transient failure:
+// 429 or any 5xx.
+func IsRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// IsRetryableHTTPError identifies transient failures of a plain HTTP
+// provider: a retryable *HTTPStatusError or any network error.
+func IsRetryableHTTPError(err error) bool {
+	var apiErr *HTTPStatusError
+	if errors.As(err, &apiErr) {
+		return IsRetryableStatus(apiErr.StatusCode)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckHTTPResponse(t *testing.T) {
+	t.Parallel()
+
+	response := func(status int, body string, header http.Header) *http.Response {
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	if err := CheckHTTPResponse("openai", response(http.StatusOK, "", nil)); err != nil {
+		t.Fatalf("CheckHTTPResponse(200) error = %v", err)
+	}
+
+	err := CheckHTTPResponse("gemini", response(http.StatusBadRequest, "  bad model\n", nil))
+	var apiErr *HTTPStatusError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("CheckHTTPResponse(400) error = %v, want HTTPStatusError", err)
+	}
+	if IsRetryableError(err) || IsRetryableHTTPError(err) {
+		t.Fatalf("CheckHTTPResponse(400) error = %v, want not retryable", err)
+	}
+	if got := err.Error(); got != "gemini api error: status 400: bad model" {
+		t.Fatalf("Error() = %q", got)
+	}
+
+	err = CheckHTTPResponse("openai", response(http.StatusTooManyRequests, strings.Repeat("x", 2*maxErrorBodySize), http.Header{"Retry-After": {"2"}}))
+	if !errors.As(err, &apiErr) || len(apiErr.Body) != maxErrorBodySize {
+		t.Fatalf("CheckHTTPResponse(429) error = %v, want body capped at %d bytes", err, maxErrorBodySize)
+	}
+	if !IsRetryableError(err) || !IsRetryableHTTPError(err) {
+		t.Fatalf("CheckHTTPResponse(429) error = %v, want retryable", err)
+	}
+	if after, ok := RetryAfterDelay(err); !ok || after != 2*time.Second {
+		t.Fatalf("RetryAfterDelay() = %s, %v, want 2s", after, ok)
+	}
+}
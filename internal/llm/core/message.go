@@ -26,13 +26,27 @@ const (
 type ContentType string
 
 const (
-	ContentTypeText ContentType = "text"
+	ContentTypeText             ContentType = "text"
+	ContentTypeThinking         ContentType = "thinking"
+	ContentTypeRedactedThinking ContentType = "redacted_thinking"
+	ContentTypeImage            ContentType = "image"
+	// ContentTypeToolUse marks where the Message.ToolCalls entry with
+	// ToolCallID appeared among the other content blocks.
+	ContentTypeToolUse ContentType = "tool_use"
 )
 
-// ContentBlock is a canonical content unit. v0.1 supports text only.
+// ContentBlock is a canonical content unit. Thinking blocks carry extended
+// reasoning (with its signature, or opaque Data when redacted) that providers
+// replay verbatim within a tool-use turn. Image blocks carry base64 Data and
+// its MediaType, such as "image/png".
 type ContentBlock struct {
-	Type ContentType `json:"type"`
-	Text string      `json:"text,omitempty"`
+	Type       ContentType `json:"type"`
+	Text       string      `json:"text,omitempty"`
+	Thinking   string      `json:"thinking,omitempty"`
+	Signature  string      `json:"signature,omitempty"`
+	Data       string      `json:"data,omitempty"`
+	MediaType  string      `json:"media_type,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
 }
 
 // ToolCall represents a model-emitted tool invocation.
@@ -44,10 +58,17 @@ type ToolCall struct {
 
 // ToolResult represents the local execution result for a tool call.
 type ToolResult struct {
-	ToolCallID string `json:"tool_call_id"`
-	ToolName   string `json:"tool_name"`
-	Content    string `json:"content"`
-	IsError    bool   `json:"is_error"`
+	ToolCallID string       `json:"tool_call_id"`
+	ToolName   string       `json:"tool_name"`
+	Content    string       `json:"content"`
+	IsError    bool         `json:"is_error"`
+	Display    *ToolDisplay `json:"display,omitempty"`
+}
+
+// ToolDisplay is structured, UI-only tool output. Providers never send it to the model.
+type ToolDisplay struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
 }
 
 // Message is the provider-agnostic conversation record.
@@ -73,6 +94,26 @@ func (u Usage) TokenCount() int {
 	return u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheWriteTokens
 }
 
+// CacheHitRatio returns the share of prompt tokens served from the provider cache.
+func (u Usage) CacheHitRatio() float64 {
+	prompt := u.InputTokens + u.CacheReadTokens + u.CacheWriteTokens
+	if prompt <= 0 {
+		return 0
+	}
+	return float64(u.CacheReadTokens) / float64(prompt)
+}
+
+// Add returns u with other's tokens and cost added to it.
+func (u Usage) Add(other Usage) Usage {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
+	u.TotalTokens += other.TotalTokens
+	u.CostUSD += other.CostUSD
+	return u
+}
+
 // Clone returns a copy safe to share as pointer payload.
 func (u Usage) Clone() *Usage {
 	copied := u
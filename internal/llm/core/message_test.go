@@ -49,6 +49,32 @@ func TestUsageTokenCount(t *testing.T) {
 	}
 }
 
+func TestUsageCacheHitRatio(t *testing.T) {
+	t.Parallel()
+
+	if got := (Usage{}).CacheHitRatio(); got != 0 {
+		t.Fatalf("CacheHitRatio() on empty usage = %v, want 0", got)
+	}
+	usage := Usage{InputTokens: 10, CacheReadTokens: 30, CacheWriteTokens: 0, OutputTokens: 99}
+	if got := usage.CacheHitRatio(); got != 0.75 {
+		t.Fatalf("CacheHitRatio() = %v, want 0.75", got)
+	}
+}
+
+func TestUsageAddSumsEveryField(t *testing.T) {
+	t.Parallel()
+
+	total := Usage{InputTokens: 1, OutputTokens: 2, CacheReadTokens: 3, CacheWriteTokens: 4, TotalTokens: 10, CostUSD: 0.5}
+	got := total.Add(Usage{InputTokens: 10, OutputTokens: 20, CacheReadTokens: 30, CacheWriteTokens: 40, TotalTokens: 100, CostUSD: 0.25})
+	want := Usage{InputTokens: 11, OutputTokens: 22, CacheReadTokens: 33, CacheWriteTokens: 44, TotalTokens: 110, CostUSD: 0.75}
+	if got != want {
+		t.Fatalf("Add() = %+v, want %+v", got, want)
+	}
+	if total.InputTokens != 1 {
+		t.Fatalf("Add() changed its receiver to %+v", total)
+	}
+}
+
 func TestUsageCloneReturnsIndependentCopy(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,21 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// ModelInfo describes one model a provider serves.
+type ModelInfo struct {
+	ID          string
+	DisplayName string
+	CreatedAt   time.Time
+	// ContextWindow is the model's context size in tokens, or zero when the
+	// provider does not report it.
+	ContextWindow int
+}
+
+// ModelLister is implemented by providers that can list their models.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
@@ -18,14 +18,25 @@ const (
 	EventStart             EventType = "start"
 	EventQueuedMessage     EventType = "queued_message"
 	EventContentBlockStart EventType = "content_block_start"
-	EventTextDelta         EventType = "text_delta"
-	EventToolCallStart     EventType = "tool_call_start"
-	EventToolCallDelta     EventType = "tool_call_delta"
-	EventToolCallEnd       EventType = "tool_call_end"
-	EventToolResult        EventType = "tool_result"
-	EventUsage             EventType = "usage"
-	EventDone              EventType = "done"
-	EventError             EventType = "error"
+	// EventContentBlockStop closes the content block opened with the same index.
+	EventContentBlockStop EventType = "content_block_stop"
+	EventTextDelta        EventType = "text_delta"
+	EventThinkingDelta    EventType = "thinking_delta"
+	EventToolCallStart    EventType = "tool_call_start"
+	EventToolCallDelta    EventType = "tool_call_delta"
+	EventToolCallEnd      EventType = "tool_call_end"
+	EventToolResult       EventType = "tool_result"
+	// EventToolRetry reports that a failed idempotent tool call is being retried.
+	EventToolRetry EventType = "tool_retry"
+	// EventToolProgress carries partial output from a tool call that is still running.
+	EventToolProgress EventType = "tool_progress"
+	// EventToolApprovalRequired pauses the agent loop until the tool call is approved or denied.
+	EventToolApprovalRequired EventType = "tool_approval_required"
+	EventUsage                EventType = "usage"
+	EventDone                 EventType = "done"
+	EventError                EventType = "error"
+	// EventContextWarning reports that a request is close to the model context window.
+	EventContextWarning EventType = "context_warning"
 )
 
 // ToolChoiceType defines how the provider may choose tools.
@@ -67,15 +78,26 @@ type Request struct {
 	Tools       []ToolSpec
 	MaxTokens   int
 	Temperature *float64
-	ToolChoice  ToolChoice
-	Metadata    map[string]string
-	Retry       RetryPolicy
+	// StopSequences end the response when the model generates any of them.
+	StopSequences []string
+	ToolChoice    ToolChoice
+	Metadata      map[string]string
+	Retry         RetryPolicy
+	// CacheSystem asks providers that support prompt caching to mark the
+	// system prompt and tool definitions as cacheable breakpoints.
+	CacheSystem bool
+	// ThinkingBudget enables extended thinking with this many budget tokens
+	// on providers that support it. Zero disables thinking.
+	ThinkingBudget int
 }
 
 // DonePayload carries the final status when the stream ends normally.
 type DonePayload struct {
 	Reason StopReason
 	Usage  Usage
+	// StopSequence is the Request.StopSequences entry that ended the
+	// response, when the provider reports it.
+	StopSequence string
 }
 
 // ContentBlockStart describes provider-native content block metadata.
@@ -93,15 +115,39 @@ type ContentBlockStart struct {
 	Raw       json.RawMessage `json:"raw,omitempty"`
 }
 
+// ContentBlockStop identifies the provider-native content block that ended.
+type ContentBlockStop struct {
+	Index int64 `json:"index"`
+}
+
+// ToolRetry describes one retry of a failed tool execution.
+type ToolRetry struct {
+	Attempt int
+	Delay   time.Duration
+	Err     error
+}
+
+// ContextWarning reports an estimated request size near the context window.
+type ContextWarning struct {
+	EstimatedTokens int
+	ContextWindow   int
+}
+
 // Event is the provider-agnostic streaming event.
 type Event struct {
 	Type              EventType
 	Message           *Message
 	ContentBlockStart *ContentBlockStart
+	ContentBlockStop  *ContentBlockStop
 	TextDelta         string
+	ThinkingDelta     string
+	SignatureDelta    string
 	ToolCall          *ToolCall
 	ToolResult        *ToolResult
+	ToolRetry         *ToolRetry
+	ContextWarning    *ContextWarning
 	ToolCallDelta     string
+	ToolOutputDelta   string
 	Usage             *Usage
 	Done              *DonePayload
 	Err               error
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,6 +19,8 @@ const (
 // retryableError marks an error as safe to retry by upstream retry loops.
 type retryableError struct {
 	err error
+	// after is the server-requested delay from Retry-After; zero when absent.
+	after time.Duration
 }
 
 func (e retryableError) Error() string {
@@ -34,12 +39,52 @@ func MarkRetryable(err error) error {
 	return retryableError{err: err}
 }
 
+// MarkRetryableAfter wraps an error like MarkRetryable and records the delay
+// the server asked for, typically parsed with ParseRetryAfter. A non-positive
+// delay leaves the choice to exponential backoff.
+func MarkRetryableAfter(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return retryableError{err: err, after: max(after, 0)}
+}
+
 // IsRetryableError reports whether err has been marked as retryable.
 func IsRetryableError(err error) bool {
 	var target retryableError
 	return errors.As(err, &target)
 }
 
+// RetryAfterDelay returns the server-requested delay recorded on err by
+// MarkRetryableAfter.
+func RetryAfterDelay(err error) (time.Duration, bool) {
+	var target retryableError
+	if !errors.As(err, &target) || target.after <= 0 {
+		return 0, false
+	}
+	return target.after, true
+}
+
+// ParseRetryAfter reads a Retry-After header given as delay seconds or an
+// HTTP date. It returns zero when the header is missing, malformed, or past.
+func ParseRetryAfter(header http.Header, now time.Time) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	at, err := http.ParseTime(value)
+	if err != nil || !at.After(now) {
+		return 0
+	}
+	return at.Sub(now)
+}
+
 // NormalizeRetryPolicy fills unset retry settings with defaults.
 // A negative MaxRetries explicitly disables retries (set to 0).
 // A zero MaxRetries is treated as unset and filled with the default.
@@ -93,6 +138,37 @@ func ComputeBackoffDelay(policy RetryPolicy, attempt int) time.Duration {
 	return time.Duration(float64(delay) * jitter)
 }
 
+// RetryDelay returns how long to wait before retrying after err: the
+// server-requested Retry-After delay capped by MaxDelay when err carries one,
+// otherwise ComputeBackoffDelay.
+func RetryDelay(policy RetryPolicy, attempt int, err error) time.Duration {
+	if after, ok := RetryAfterDelay(err); ok {
+		return min(after, policy.MaxDelay)
+	}
+	return ComputeBackoffDelay(policy, attempt)
+}
+
+// RetryStream calls attempt until it succeeds, retrying errors marked
+// retryable after RetryDelay. It gives up once visible reports that output
+// already reached the caller, since a retry would repeat it.
+func RetryStream(ctx context.Context, policy RetryPolicy, attempt func() error, visible func() bool) error {
+	for n := 0; ; n++ {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if !IsRetryableError(err) || visible() || n >= policy.MaxRetries {
+			return err
+		}
+		if err := SleepContext(ctx, RetryDelay(policy, n, err)); err != nil {
+			return err
+		}
+	}
+}
+
 // SleepContext waits for delay unless the context is canceled first.
 func SleepContext(ctx context.Context, delay time.Duration) error {
 	timer := time.NewTimer(delay)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 )
@@ -120,6 +121,87 @@ func TestComputeBackoffDelayInRange(t *testing.T) {
 	assertDelayRange(4, 500*time.Millisecond)
 }
 
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":     0,
+		"2":    2 * time.Second,
+		"0.5":  500 * time.Millisecond,
+		"-1":   0,
+		"soon": 0,
+		now.Add(3 * time.Second).Format(http.TimeFormat): 3 * time.Second,
+		now.Add(-time.Minute).Format(http.TimeFormat):    0,
+	}
+	for value, want := range tests {
+		header := http.Header{}
+		if value != "" {
+			header.Set("Retry-After", value)
+		}
+		if got := ParseRetryAfter(header, now); got != want {
+			t.Fatalf("ParseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestRetryDelayPrefersRetryAfterCappedByMaxDelay(t *testing.T) {
+	t.Parallel()
+
+	policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 2 * time.Second}
+	err := fmt.Errorf("stream: %w", MarkRetryableAfter(errors.New("rate limited"), time.Second))
+	if !IsRetryableError(err) {
+		t.Fatalf("MarkRetryableAfter() error is not retryable")
+	}
+	if got := RetryDelay(policy, 0, err); got != time.Second {
+		t.Fatalf("RetryDelay(Retry-After 1s) = %v, want 1s", got)
+	}
+	capped := MarkRetryableAfter(errors.New("rate limited"), time.Minute)
+	if got := RetryDelay(policy, 0, capped); got != policy.MaxDelay {
+		t.Fatalf("RetryDelay(Retry-After 1m) = %v, want MaxDelay %v", got, policy.MaxDelay)
+	}
+	if got := RetryDelay(policy, 0, MarkRetryable(errors.New("boom"))); got > 12*time.Millisecond {
+		t.Fatalf("RetryDelay(no Retry-After) = %v, want exponential backoff", got)
+	}
+}
+
+func TestRetryStreamStopsOnceOutputIsVisible(t *testing.T) {
+	t.Parallel()
+
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	transient := MarkRetryable(errors.New("transient"))
+
+	calls := 0
+	err := RetryStream(context.Background(), policy, func() error {
+		calls++
+		if calls < 3 {
+			return transient
+		}
+		return nil
+	}, func() bool { return false })
+	if err != nil || calls != 3 {
+		t.Fatalf("RetryStream() error = %v after %d calls, want success on the third", err, calls)
+	}
+
+	calls = 0
+	err = RetryStream(context.Background(), policy, func() error {
+		calls++
+		return transient
+	}, func() bool { return true })
+	if !errors.Is(err, transient) || calls != 1 {
+		t.Fatalf("RetryStream() error = %v after %d calls, want no retry once output is visible", err, calls)
+	}
+
+	calls = 0
+	err = RetryStream(context.Background(), policy, func() error {
+		calls++
+		return transient
+	}, func() bool { return false })
+	if !errors.Is(err, transient) || calls != policy.MaxRetries+1 {
+		t.Fatalf("RetryStream() error = %v after %d calls, want %d attempts", err, calls, policy.MaxRetries+1)
+	}
+}
+
 func TestSleepContextCanceledAndSuccess(t *testing.T) {
 	t.Parallel()
 
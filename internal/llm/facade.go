@@ -1,8 +1,13 @@
 package llm
 
 import (
+	"context"
+	"io"
+
 	anthropicprovider "gar/internal/llm/providers/anthropic"
+	geminiprovider "gar/internal/llm/providers/gemini"
 	mockprovider "gar/internal/llm/providers/mock"
+	openaiprovider "gar/internal/llm/providers/openai"
 
 	"gar/internal/llm/core"
 )
@@ -24,6 +29,7 @@ type (
 	Request           = core.Request
 	DonePayload       = core.DonePayload
 	ContentBlockStart = core.ContentBlockStart
+	ContentBlockStop  = core.ContentBlockStop
 	Event             = core.Event
 
 	// Conversation-model aliases.
@@ -35,32 +41,58 @@ type (
 	ContentBlock = core.ContentBlock
 	ToolCall     = core.ToolCall
 	ToolResult   = core.ToolResult
+	ToolDisplay  = core.ToolDisplay
+	ToolRetry    = core.ToolRetry
 	Message      = core.Message
 	Usage        = core.Usage
+	// ContextWarning is the payload of EventContextWarning.
+	ContextWarning = core.ContextWarning
 
 	// ModelPricing configures per-model token prices.
 	ModelPricing = core.ModelPricing
+	// ModelInfo and ModelLister describe provider model listings.
+	ModelInfo   = core.ModelInfo
+	ModelLister = core.ModelLister
 
 	// Anthropic* aliases expose provider-specific configuration and implementation.
 	AnthropicConfig   = anthropicprovider.Config
 	AnthropicProvider = anthropicprovider.Provider
+	// BedrockConfig configures the Anthropic provider for AWS Bedrock.
+	BedrockConfig = anthropicprovider.BedrockConfig
+
+	// OpenAI* aliases expose the chat-completions provider configuration and implementation.
+	OpenAIConfig   = openaiprovider.Config
+	OpenAIProvider = openaiprovider.Provider
+
+	// Gemini* aliases expose the Google Gemini provider configuration and implementation.
+	GeminiConfig   = geminiprovider.Config
+	GeminiProvider = geminiprovider.Provider
 
 	// MockProvider emits scripted events for tests.
 	MockProvider = mockprovider.Provider
+
+	// DebugLogProvider logs requests and stream events as JSON lines.
+	DebugLogProvider = core.DebugLogProvider
 )
 
 const (
-	EventStart             = core.EventStart
-	EventQueuedMessage     = core.EventQueuedMessage
-	EventContentBlockStart = core.EventContentBlockStart
-	EventTextDelta         = core.EventTextDelta
-	EventToolCallStart     = core.EventToolCallStart
-	EventToolCallDelta     = core.EventToolCallDelta
-	EventToolCallEnd       = core.EventToolCallEnd
-	EventToolResult        = core.EventToolResult
-	EventUsage             = core.EventUsage
-	EventDone              = core.EventDone
-	EventError             = core.EventError
+	EventStart                = core.EventStart
+	EventQueuedMessage        = core.EventQueuedMessage
+	EventContentBlockStart    = core.EventContentBlockStart
+	EventContentBlockStop     = core.EventContentBlockStop
+	EventTextDelta            = core.EventTextDelta
+	EventThinkingDelta        = core.EventThinkingDelta
+	EventToolCallStart        = core.EventToolCallStart
+	EventToolCallDelta        = core.EventToolCallDelta
+	EventToolCallEnd          = core.EventToolCallEnd
+	EventToolResult           = core.EventToolResult
+	EventToolRetry            = core.EventToolRetry
+	EventToolProgress         = core.EventToolProgress
+	EventToolApprovalRequired = core.EventToolApprovalRequired
+	EventUsage                = core.EventUsage
+	EventDone                 = core.EventDone
+	EventError                = core.EventError
+	EventContextWarning       = core.EventContextWarning
 
 	ToolChoiceAuto = core.ToolChoiceAuto
 	ToolChoiceAny  = core.ToolChoiceAny
@@ -77,14 +109,28 @@ const (
 	StopReasonError   = core.StopReasonError
 	StopReasonAborted = core.StopReasonAborted
 
-	ContentTypeText = core.ContentTypeText
+	ContentTypeText             = core.ContentTypeText
+	ContentTypeThinking         = core.ContentTypeThinking
+	ContentTypeRedactedThinking = core.ContentTypeRedactedThinking
+	ContentTypeImage            = core.ContentTypeImage
+	ContentTypeToolUse          = core.ContentTypeToolUse
 )
 
 var (
 	// ErrInvalidRequest indicates malformed canonical request payloads.
 	ErrInvalidRequest = core.ErrInvalidRequest
-	// ErrMissingAPIKey indicates missing Anthropic API credentials.
+	// ErrMissingAPIKey indicates missing provider API credentials.
 	ErrMissingAPIKey = core.ErrMissingAPIKey
+	// ErrRateLimited indicates the provider rate limited the request.
+	ErrRateLimited = core.ErrRateLimited
+	// ErrContextTooLong indicates the request exceeds the model's context window.
+	ErrContextTooLong = core.ErrContextTooLong
+	// ErrAuthFailed indicates the provider rejected the API credentials.
+	ErrAuthFailed = core.ErrAuthFailed
+	// ErrOverloaded indicates the provider is temporarily over capacity.
+	ErrOverloaded = core.ErrOverloaded
+	// ErrBedrockRegionRequired indicates no AWS region was configured for Bedrock.
+	ErrBedrockRegionRequired = anthropicprovider.ErrBedrockRegionRequired
 )
 
 // NewToolSpecFromStruct reflects a Go struct into a normalized tool schema.
@@ -97,7 +143,27 @@ func CalculateCost(u Usage, p ModelPricing) float64 {
 	return core.CalculateCost(u, p)
 }
 
+// NewDebugLogProvider wraps inner so each request and stream event is logged to w with secrets redacted.
+func NewDebugLogProvider(inner Provider, w io.Writer, secrets ...string) *DebugLogProvider {
+	return core.NewDebugLogProvider(inner, w, secrets...)
+}
+
 // NewAnthropicProvider constructs an Anthropic provider with normalized defaults.
 func NewAnthropicProvider(cfg AnthropicConfig) *AnthropicProvider {
 	return anthropicprovider.New(cfg)
 }
+
+// NewBedrockProvider constructs an Anthropic provider that calls Bedrock with AWS chain credentials.
+func NewBedrockProvider(ctx context.Context, cfg BedrockConfig) (*AnthropicProvider, error) {
+	return anthropicprovider.NewBedrock(ctx, cfg)
+}
+
+// NewOpenAIProvider constructs an OpenAI chat-completions provider with normalized defaults.
+func NewOpenAIProvider(cfg OpenAIConfig) *OpenAIProvider {
+	return openaiprovider.New(cfg)
+}
+
+// NewGeminiProvider constructs a Gemini streamGenerateContent provider with normalized defaults.
+func NewGeminiProvider(cfg GeminiConfig) *GeminiProvider {
+	return geminiprovider.New(cfg)
+}
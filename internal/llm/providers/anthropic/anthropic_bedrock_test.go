@@ -0,0 +1,88 @@
+package anthropicprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"gar/internal/llm/core"
+)
+
+const testBedrockModel = "us.anthropic.claude-sonnet-4-20250514-v1:0"
+
+// TestBedrockSignsRequestAndUsesModelPath verifies Bedrock model IDs map onto the invoke endpoint with SigV4 auth.
+func TestBedrockSignsRequestAndUsesModelPath(t *testing.T) {
+	t.Setenv("AWS_BEARER_TOKEN_BEDROCK", "")
+
+	type captured struct {
+		path string
+		auth string
+		body map[string]any
+	}
+	requests := make(chan captured, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		_ = json.Unmarshal(raw, &body)
+		requests <- captured{path: r.URL.EscapedPath(), auth: r.Header.Get("Authorization"), body: body}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprint(w, `{"message":"stop here"}`)
+	}))
+	defer server.Close()
+
+	p, err := newBedrockWithAWSConfig(BedrockConfig{BaseURL: server.URL}, aws.Config{
+		Region:      "us-west-2",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("newBedrockWithAWSConfig() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := p.Stream(ctx, &core.Request{
+		Model:     testBedrockModel,
+		MaxTokens: 64,
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	for range stream {
+	}
+
+	got := <-requests
+	wantPath := "/model/" + strings.ReplaceAll(testBedrockModel, ":", "%3A") + "/invoke-with-response-stream"
+	if got.path != wantPath {
+		t.Fatalf("request path = %q, want %q", got.path, wantPath)
+	}
+	if !strings.HasPrefix(got.auth, "AWS4-HMAC-SHA256") || !strings.Contains(got.auth, "/us-west-2/bedrock/aws4_request") {
+		t.Fatalf("Authorization = %q, want SigV4 for bedrock in us-west-2", got.auth)
+	}
+	if _, ok := got.body["model"]; ok {
+		t.Fatalf("body = %v, want model moved into the path", got.body)
+	}
+	if got.body["anthropic_version"] == nil {
+		t.Fatalf("body = %v, want anthropic_version set", got.body)
+	}
+}
+
+func TestBedrockRequiresRegion(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newBedrockWithAWSConfig(BedrockConfig{}, aws.Config{}); !errors.Is(err, ErrBedrockRegionRequired) {
+		t.Fatalf("newBedrockWithAWSConfig() error = %v, want ErrBedrockRegionRequired", err)
+	}
+}
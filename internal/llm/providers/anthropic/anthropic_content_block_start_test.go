@@ -101,6 +101,35 @@ func TestContentBlockStartSupportsAllSDKVariants(t *testing.T) {
 	}
 }
 
+func TestHandleSDKStreamEventReportsStopSequence(t *testing.T) {
+	t.Parallel()
+
+	p := &Provider{}
+	state := &streamState{reason: core.StopReasonStop, toolAccumulators: map[int]*toolCallAccumulator{}}
+	events := make(chan core.Event, 4)
+	for _, raw := range []string{
+		`{"type":"message_delta","delta":{"stop_reason":"stop_sequence","stop_sequence":"</answer>"},"usage":{"output_tokens":7}}`,
+		`{"type":"message_stop"}`,
+	} {
+		var sdkEvent anthropic.MessageStreamEventUnion
+		if err := json.Unmarshal([]byte(raw), &sdkEvent); err != nil {
+			t.Fatalf("unmarshal sdk event: %v", err)
+		}
+		if err := p.handleSDKStreamEvent(context.Background(), sdkEvent, "claude-sonnet-4", events, state); err != nil {
+			t.Fatalf("handleSDKStreamEvent() error = %v", err)
+		}
+	}
+
+	gotEvents := drainEvents(events)
+	done := gotEvents[len(gotEvents)-1]
+	if done.Type != core.EventDone || done.Done == nil {
+		t.Fatalf("last event = %+v, want done", done)
+	}
+	if done.Done.Reason != core.StopReasonStop || done.Done.StopSequence != "</answer>" {
+		t.Fatalf("done = %+v, want stop reason with stop sequence </answer>", done.Done)
+	}
+}
+
 // drainEvents reads all currently buffered events from the channel.
 func drainEvents(ch <-chan core.Event) []core.Event {
 	out := make([]core.Event, 0, len(ch))
@@ -0,0 +1,107 @@
+package anthropicprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gar/internal/llm/core"
+)
+
+// TestStreamClassifiesAPIErrors verifies representative error responses map to
+// the matching core sentinel on the terminal error event.
+func TestStreamClassifiesAPIErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{
+			name:   "rate limited",
+			status: http.StatusTooManyRequests,
+			body:   `{"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your per-minute rate limit"}}`,
+			want:   core.ErrRateLimited,
+		},
+		{
+			name:   "invalid api key",
+			status: http.StatusUnauthorized,
+			body:   `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`,
+			want:   core.ErrAuthFailed,
+		},
+		{
+			name:   "overloaded",
+			status: statusOverloaded,
+			body:   `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			want:   core.ErrOverloaded,
+		},
+		{
+			name:   "prompt too long",
+			status: http.StatusBadRequest,
+			body:   `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`,
+			want:   core.ErrContextTooLong,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				_, _ = fmt.Fprint(w, tc.body)
+			}))
+			defer server.Close()
+
+			p := New(Config{
+				APIKey:  "test-key",
+				BaseURL: server.URL,
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			stream, err := p.Stream(ctx, &core.Request{
+				Model: "claude-sonnet-4-20250514",
+				Messages: []core.Message{
+					{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+				},
+				MaxTokens: 128,
+				Retry:     core.RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+			})
+			if err != nil {
+				t.Fatalf("Stream() error = %v", err)
+			}
+
+			var got error
+			for ev := range stream {
+				if ev.Type == core.EventError {
+					got = ev.Err
+				}
+			}
+			if !errors.Is(got, tc.want) {
+				t.Fatalf("EventError.Err = %v, want errors.Is %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestErrorKindClassifiesStreamErrorEvents verifies error events received
+// mid-stream, which the SDK reports as plain text, are classified by body.
+func TestErrorKindClassifiesStreamErrorEvents(t *testing.T) {
+	t.Parallel()
+
+	err := fmt.Errorf("received error while streaming: %s", `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+	if got := classifyError(err); !errors.Is(got, core.ErrOverloaded) {
+		t.Fatalf("classifyError() = %v, want errors.Is ErrOverloaded", got)
+	}
+	if got := classifyError(errors.New("connection reset by peer")); got.Error() != "connection reset by peer" || errors.Is(got, core.ErrOverloaded) {
+		t.Fatalf("classifyError() = %v, want unclassified error unchanged", got)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"math"
+	"reflect"
+	"strings"
 	"testing"
 
 	"gar/internal/llm/core"
@@ -17,8 +19,10 @@ type serializedAnthropicParams struct {
 	Tools       []serializedAnthropicTool    `json:"tools"`
 	System      []serializedAnthropicBlock   `json:"system"`
 	Temperature float64                      `json:"temperature"`
+	StopSeqs    []string                     `json:"stop_sequences"`
 	Metadata    map[string]any               `json:"metadata"`
 	ToolChoice  map[string]any               `json:"tool_choice"`
+	Thinking    map[string]any               `json:"thinking"`
 }
 
 type serializedAnthropicMessage struct {
@@ -35,6 +39,11 @@ type serializedAnthropicBlock struct {
 	ToolUseID string                         `json:"tool_use_id"`
 	IsError   bool                           `json:"is_error"`
 	Content   []serializedAnthropicTextBlock `json:"content"`
+	Cache     map[string]any                 `json:"cache_control"`
+	Thinking  string                         `json:"thinking"`
+	Signature string                         `json:"signature"`
+	Data      string                         `json:"data"`
+	Source    map[string]any                 `json:"source"`
 }
 
 type serializedAnthropicTextBlock struct {
@@ -46,6 +55,7 @@ type serializedAnthropicTool struct {
 	Name        string                        `json:"name"`
 	Description string                        `json:"description"`
 	InputSchema serializedAnthropicToolSchema `json:"input_schema"`
+	Cache       map[string]any                `json:"cache_control"`
 }
 
 type serializedAnthropicToolSchema struct {
@@ -127,6 +137,45 @@ func TestToAnthropicSDKParamsPreservesWhitespaceInTextBlocks(t *testing.T) {
 	}
 }
 
+func TestToAnthropicSDKParamsMapsImageBlocks(t *testing.T) {
+	t.Parallel()
+
+	req := &core.Request{
+		Model: "claude-sonnet-4-20250514",
+		Messages: []core.Message{
+			{
+				Role: core.RoleUser,
+				Content: []core.ContentBlock{
+					{Type: core.ContentTypeImage, MediaType: "image/png", Data: "iVBORw0KGgo="},
+					{Type: core.ContentTypeImage, Data: "missing-media-type"},
+					{Type: core.ContentTypeText, Text: "what is in this screenshot?"},
+				},
+			},
+		},
+		MaxTokens: 128,
+	}
+
+	params, err := toAnthropicSDKParams(req)
+	if err != nil {
+		t.Fatalf("toAnthropicSDKParams() error = %v", err)
+	}
+
+	body := decodeSDKParams(t, params)
+	if len(body.Messages) != 1 || len(body.Messages[0].Content) != 2 {
+		t.Fatalf("unexpected mapped message/content count: %+v", body.Messages)
+	}
+	image := body.Messages[0].Content[0]
+	if image.Type != "image" {
+		t.Fatalf("content[0].type = %q, want image", image.Type)
+	}
+	if image.Source["type"] != "base64" || image.Source["media_type"] != "image/png" || image.Source["data"] != "iVBORw0KGgo=" {
+		t.Fatalf("image source = %#v, want base64 image/png data", image.Source)
+	}
+	if text := body.Messages[0].Content[1]; text.Type != "text" || text.Text != "what is in this screenshot?" {
+		t.Fatalf("content[1] = %+v, want prompt text", text)
+	}
+}
+
 // TestToAnthropicSDKParamsGroupsConsecutiveToolResults ensures adjacent tool results are batched into one user message.
 func TestToAnthropicSDKParamsGroupsConsecutiveToolResults(t *testing.T) {
 	req := &core.Request{
@@ -234,6 +283,97 @@ func TestToAnthropicSDKParamsUsesGeneratedToolSchema(t *testing.T) {
 	}
 }
 
+// TestToAnthropicSDKParamsMarksCacheBreakpoints verifies cache_control lands only on the final system block and tool.
+func TestToAnthropicSDKParamsMarksCacheBreakpoints(t *testing.T) {
+	t.Parallel()
+
+	req := &core.Request{
+		Model:  "claude-sonnet-4-20250514",
+		System: "You are a coding agent.",
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+		},
+		Tools: []core.ToolSpec{
+			{Name: "read", Schema: json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"}}}`)},
+			{Name: "bash", Schema: json.RawMessage(`{"type":"object","properties":{"command":{"type":"string"}}}`)},
+		},
+		CacheSystem: true,
+	}
+
+	params, err := toAnthropicSDKParams(req)
+	if err != nil {
+		t.Fatalf("toAnthropicSDKParams() error = %v", err)
+	}
+
+	body := decodeSDKParams(t, params)
+	if len(body.System) != 1 || body.System[0].Cache["type"] != "ephemeral" {
+		t.Fatalf("expected ephemeral cache_control on system block, got %+v", body.System)
+	}
+	if len(body.Tools) != 2 {
+		t.Fatalf("tool count mismatch: got %d want 2", len(body.Tools))
+	}
+	if body.Tools[0].Cache != nil {
+		t.Fatalf("expected no cache_control on first tool, got %+v", body.Tools[0].Cache)
+	}
+	if body.Tools[1].Cache["type"] != "ephemeral" {
+		t.Fatalf("expected ephemeral cache_control on final tool, got %+v", body.Tools[1].Cache)
+	}
+	for _, msg := range body.Messages {
+		for _, block := range msg.Content {
+			if block.Cache != nil {
+				t.Fatalf("expected no cache_control on message blocks, got %+v", block)
+			}
+		}
+	}
+
+	req.CacheSystem = false
+	params, err = toAnthropicSDKParams(req)
+	if err != nil {
+		t.Fatalf("toAnthropicSDKParams() error = %v", err)
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("marshal params: %v", err)
+	}
+	if bytes.Contains(raw, []byte("cache_control")) {
+		t.Fatalf("expected no cache_control without CacheSystem, got %s", raw)
+	}
+}
+
+func TestToAnthropicSDKParamsMapsStopSequences(t *testing.T) {
+	t.Parallel()
+
+	req := &core.Request{
+		Model: "claude-sonnet-4-20250514",
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+		},
+		StopSequences: []string{"</answer>", "\n\nHuman:"},
+	}
+
+	params, err := toAnthropicSDKParams(req)
+	if err != nil {
+		t.Fatalf("toAnthropicSDKParams() error = %v", err)
+	}
+	body := decodeSDKParams(t, params)
+	if len(body.StopSeqs) != 2 || body.StopSeqs[0] != "</answer>" || body.StopSeqs[1] != "\n\nHuman:" {
+		t.Fatalf("stop_sequences = %q, want %q", body.StopSeqs, req.StopSequences)
+	}
+
+	req.StopSequences = nil
+	params, err = toAnthropicSDKParams(req)
+	if err != nil {
+		t.Fatalf("toAnthropicSDKParams() error = %v", err)
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("marshal params: %v", err)
+	}
+	if bytes.Contains(raw, []byte("stop_sequences")) {
+		t.Fatalf("expected no stop_sequences without StopSequences, got %s", raw)
+	}
+}
+
 // decodeSDKParams marshals and decodes SDK params into assertion-friendly structs.
 func decodeSDKParams(t *testing.T, params any) serializedAnthropicParams {
 	t.Helper()
@@ -375,6 +515,96 @@ func TestToAnthropicSDKParamsMapsAssistantToolCalls(t *testing.T) {
 	}
 }
 
+func TestToAnthropicSDKParamsKeepsInterleavedBlockOrder(t *testing.T) {
+	t.Parallel()
+
+	params, err := toAnthropicSDKParams(&core.Request{
+		Model: "claude-sonnet-4-20250514",
+		Messages: []core.Message{{
+			Role: core.RoleAssistant,
+			Content: []core.ContentBlock{
+				{Type: core.ContentTypeText, Text: "first"},
+				{Type: core.ContentTypeToolUse, ToolCallID: "toolu_1"},
+				{Type: core.ContentTypeText, Text: "second"},
+			},
+			ToolCalls: []core.ToolCall{
+				{ID: "toolu_1", Name: "Read", Arguments: json.RawMessage(`{"path":"a.go"}`)},
+				{ID: "toolu_2", Name: "Read", Arguments: json.RawMessage(`{"path":"b.go"}`)},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("toAnthropicSDKParams() error = %v", err)
+	}
+
+	blocks := decodeSDKParams(t, params).Messages[0].Content
+	var got []string
+	for _, block := range blocks {
+		got = append(got, block.Type+":"+block.Text+block.ID)
+	}
+	want := []string{"text:first", "tool_use:toolu_1", "text:second", "tool_use:toolu_2"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("assistant blocks = %v, want %v", got, want)
+	}
+}
+
+func TestToAnthropicSDKParamsMapsThinking(t *testing.T) {
+	t.Parallel()
+
+	temperature := 0.7
+	params, err := toAnthropicSDKParams(&core.Request{
+		Model:          "claude-sonnet-4-20250514",
+		MaxTokens:      1024,
+		Temperature:    &temperature,
+		ThinkingBudget: 2048,
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+			{
+				Role: core.RoleAssistant,
+				Content: []core.ContentBlock{
+					{Type: core.ContentTypeText, Text: "reading"},
+					{Type: core.ContentTypeThinking, Thinking: "plan", Signature: "sig-1"},
+					{Type: core.ContentTypeThinking, Thinking: "unsigned"},
+					{Type: core.ContentTypeRedactedThinking, Data: "opaque"},
+				},
+				ToolCalls: []core.ToolCall{{ID: "toolu_1", Name: "read", Arguments: json.RawMessage(`{}`)}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("toAnthropicSDKParams() error = %v", err)
+	}
+
+	body := decodeSDKParams(t, params)
+	if body.Thinking["type"] != "enabled" || body.Thinking["budget_tokens"] != float64(2048) {
+		t.Fatalf("thinking mismatch: %+v", body.Thinking)
+	}
+	if body.MaxTokens != 3072 {
+		t.Fatalf("max_tokens = %d, want budget plus response allowance 3072", body.MaxTokens)
+	}
+	if body.Temperature != 0 {
+		t.Fatalf("temperature = %v, want it omitted alongside thinking", body.Temperature)
+	}
+
+	content := body.Messages[1].Content
+	var types []string
+	for _, block := range content {
+		types = append(types, block.Type)
+	}
+	want := []string{"thinking", "redacted_thinking", "text", "tool_use"}
+	if len(types) != len(want) {
+		t.Fatalf("assistant block types = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("assistant block types = %v, want %v", types, want)
+		}
+	}
+	if content[0].Thinking != "plan" || content[0].Signature != "sig-1" || content[1].Data != "opaque" {
+		t.Fatalf("thinking blocks = %+v, want replayed thinking and redacted data", content[:2])
+	}
+}
+
 func TestToAnthropicSDKParamsMapsOptionalFields(t *testing.T) {
 	t.Parallel()
 
@@ -409,6 +639,32 @@ func TestToAnthropicSDKParamsMapsOptionalFields(t *testing.T) {
 	}
 }
 
+func TestToAnthropicSDKParamsSendsOnlyUserIDMetadata(t *testing.T) {
+	t.Parallel()
+
+	params, err := toAnthropicSDKParams(&core.Request{
+		Model: "claude-sonnet-4-20250514",
+		Metadata: map[string]string{
+			"user_id":    "user-123",
+			"session_id": "20260101-120000",
+			"cwd":        "/work/project",
+			"empty":      " ",
+		},
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("toAnthropicSDKParams() error = %v", err)
+	}
+
+	body := decodeSDKParams(t, params)
+	want := map[string]any{"user_id": "user-123"}
+	if !reflect.DeepEqual(body.Metadata, want) {
+		t.Fatalf("metadata = %#v, want %#v", body.Metadata, want)
+	}
+}
+
 func TestToSDKToolChoiceMatrix(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,50 @@
+package anthropicprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gar/internal/llm/core"
+)
+
+func TestListModelsFollowsPages(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("X-Api-Key"); got != "test-key" {
+			t.Errorf("x-api-key = %q, want test-key", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("after_id") == "" {
+			_, _ = fmt.Fprint(w, `{"data":[{"id":"claude-sonnet-4-5","display_name":"Claude Sonnet 4.5","created_at":"2025-09-29T00:00:00Z","type":"model"}],"has_more":true,"first_id":"claude-sonnet-4-5","last_id":"claude-sonnet-4-5"}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"data":[{"id":"claude-3-5-haiku-20241022","display_name":"Claude Haiku 3.5","created_at":"2024-10-22T00:00:00Z","type":"model"}],"has_more":false,"first_id":"claude-3-5-haiku-20241022","last_id":"claude-3-5-haiku-20241022"}`)
+	}))
+	t.Cleanup(server.Close)
+
+	p := New(Config{APIKey: "test-key", BaseURL: server.URL})
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 2 || models[0].ID != "claude-sonnet-4-5" || models[1].ID != "claude-3-5-haiku-20241022" {
+		t.Fatalf("ListModels() = %+v, want both pages in order", models)
+	}
+	if models[0].DisplayName != "Claude Sonnet 4.5" || models[0].CreatedAt.Year() != 2025 {
+		t.Fatalf("ListModels()[0] = %+v, want display name and creation date", models[0])
+	}
+
+	var _ core.ModelLister = p
+	if _, err := New(Config{BaseURL: server.URL}).ListModels(context.Background()); !errors.Is(err, core.ErrMissingAPIKey) {
+		t.Fatalf("ListModels() without key error = %v, want ErrMissingAPIKey", err)
+	}
+}
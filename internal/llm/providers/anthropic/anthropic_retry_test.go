@@ -184,3 +184,79 @@ data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text
 		t.Fatalf("expected only 1 attempt after first delta, got %d", got)
 	}
 }
+
+// TestRetryHonorsRetryAfter verifies a 429 Retry-After header replaces exponential backoff.
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	var firstCall atomic.Int64
+	var retryGap atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now().UnixNano()
+		if calls.Add(1) == 1 {
+			firstCall.Store(now)
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = fmt.Fprint(w, `{"error":"rate limited"}`)
+			return
+		}
+		retryGap.Store(now - firstCall.Load())
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, `event: message_start
+data: {"type":"message_start","message":{"usage":{"input_tokens":1,"output_tokens":0}}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":""},"usage":{"output_tokens":1}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`)
+	}))
+	defer server.Close()
+
+	p := New(Config{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := p.Stream(ctx, &core.Request{
+		Model: "claude-sonnet-4-20250514",
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+		},
+		MaxTokens: 128,
+		Retry: core.RetryPolicy{
+			MaxRetries: 2,
+			BaseDelay:  10 * time.Millisecond,
+			MaxDelay:   5 * time.Second,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	var seenDone bool
+	for ev := range stream {
+		if ev.Type == core.EventError {
+			t.Fatalf("unexpected EventError: %v", ev.Err)
+		}
+		if ev.Type == core.EventDone {
+			seenDone = true
+		}
+	}
+	if !seenDone {
+		t.Fatalf("expected EventDone after retry")
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected 2 attempts, got %d", got)
+	}
+	if gap := time.Duration(retryGap.Load()); gap < 900*time.Millisecond || gap > 3*time.Second {
+		t.Fatalf("retry waited %v, want about the 1s Retry-After", gap)
+	}
+}
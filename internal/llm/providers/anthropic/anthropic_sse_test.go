@@ -88,3 +88,52 @@ data: {"type":"message_stop"}
 		t.Fatalf("expected delta+done events, got delta=%v done=%v", seenDelta, seenDone)
 	}
 }
+
+// TestStreamEmitsThinkingDeltas verifies thinking and signature deltas surface as thinking events.
+func TestStreamEmitsThinkingDeltas(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":10,\"output_tokens\":0}}}\n\n",
+			"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"thinking\",\"thinking\":\"\",\"signature\":\"\"}}\n\n",
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"thinking_delta\",\"thinking\":\"let me think\"}}\n\n",
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"signature_delta\",\"signature\":\"sig\"}}\n\n",
+			"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n",
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":2}}\n\n",
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+		}
+		for _, chunk := range events {
+			_, _ = fmt.Fprint(w, chunk)
+		}
+	}))
+	defer server.Close()
+
+	p := New(Config{APIKey: "test-key", BaseURL: server.URL})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := p.Stream(ctx, &core.Request{
+		Model:          "claude-sonnet-4-20250514",
+		MaxTokens:      128,
+		ThinkingBudget: 1024,
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	var thinking, signature string
+	for ev := range stream {
+		if ev.Type == core.EventThinkingDelta {
+			thinking += ev.ThinkingDelta
+			signature += ev.SignatureDelta
+		}
+	}
+	if thinking != "let me think" || signature != "sig" {
+		t.Fatalf("thinking = %q signature = %q, want streamed deltas", thinking, signature)
+	}
+}
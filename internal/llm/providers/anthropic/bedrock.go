@@ -0,0 +1,86 @@
+package anthropicprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/bedrock"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+
+	"gar/internal/llm/core"
+)
+
+// ErrBedrockRegionRequired is returned when no AWS region is configured for Bedrock.
+var ErrBedrockRegionRequired = errors.New("bedrock region is required")
+
+// BedrockConfig configures the Anthropic provider to call Claude through
+// AWS Bedrock. Credentials come from the standard AWS chain (environment,
+// shared config/credentials files, SSO, and instance roles).
+type BedrockConfig struct {
+	// Region overrides the region from the AWS chain (AWS_REGION, profile).
+	Region string
+	// Profile selects a shared config profile; empty uses the chain default.
+	Profile string
+	// BaseURL overrides the regional bedrock-runtime endpoint, e.g. for VPC endpoints.
+	BaseURL      string
+	HTTPClient   *http.Client
+	Retry        core.RetryPolicy
+	ModelPricing map[string]core.ModelPricing
+}
+
+// NewBedrock constructs a provider that signs Messages API requests with
+// SigV4 and sends them to Bedrock's invoke endpoints. Model names are
+// Bedrock model IDs such as "anthropic.claude-sonnet-4-20250514-v1:0".
+func NewBedrock(ctx context.Context, cfg BedrockConfig) (*Provider, error) {
+	var loadOptions []func(*awsconfig.LoadOptions) error
+	if region := strings.TrimSpace(cfg.Region); region != "" {
+		loadOptions = append(loadOptions, awsconfig.WithRegion(region))
+	}
+	if profile := strings.TrimSpace(cfg.Profile); profile != "" {
+		loadOptions = append(loadOptions, awsconfig.WithSharedConfigProfile(profile))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+	return newBedrockWithAWSConfig(cfg, awsCfg)
+}
+
+func newBedrockWithAWSConfig(cfg BedrockConfig, awsCfg aws.Config) (*Provider, error) {
+	if strings.TrimSpace(awsCfg.Region) == "" {
+		return nil, ErrBedrockRegionRequired
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 90 * time.Second}
+	}
+
+	pricing := cfg.ModelPricing
+	if pricing == nil {
+		pricing = map[string]core.ModelPricing{}
+	}
+
+	clientOptions := []option.RequestOption{
+		bedrock.WithConfig(awsCfg),
+		option.WithHTTPClient(httpClient),
+		option.WithMaxRetries(0), // explicit retry behavior in this package
+	}
+	if baseURL := strings.TrimRight(cfg.BaseURL, "/"); baseURL != "" {
+		clientOptions = append(clientOptions, option.WithBaseURL(baseURL))
+	}
+
+	return &Provider{
+		retry:   core.NormalizeRetryPolicy(cfg.Retry),
+		pricing: pricing,
+		bedrock: true,
+		client:  anthropic.NewClient(clientOptions...),
+	}, nil
+}
@@ -0,0 +1,60 @@
+package anthropicprovider
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+
+	"gar/internal/llm/core"
+)
+
+// statusOverloaded is the non-standard status Anthropic returns when the API
+// is over capacity.
+const statusOverloaded = 529
+
+// classifyError tags err with the core sentinel describing the API failure,
+// such as core.ErrRateLimited, so callers can offer specific guidance.
+func classifyError(err error) error {
+	return core.WithErrorKind(err, errorKind(err))
+}
+
+// errorKind maps an HTTP error response or an error event received
+// mid-stream to a core error sentinel, or nil when none applies.
+func errorKind(err error) error {
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return core.ErrAuthFailed
+		case http.StatusTooManyRequests:
+			return core.ErrRateLimited
+		case http.StatusRequestEntityTooLarge:
+			return core.ErrContextTooLong
+		case statusOverloaded:
+			return core.ErrOverloaded
+		}
+		return errorKindFromBody(apiErr.RawJSON())
+	}
+	// The SDK reports stream error events with the raw event body as text.
+	return errorKindFromBody(err.Error())
+}
+
+// errorKindFromBody classifies an Anthropic error body by its error type and,
+// for invalid requests, by the context-overflow message.
+func errorKindFromBody(body string) error {
+	body = strings.ToLower(body)
+	switch {
+	case strings.Contains(body, "overloaded_error"):
+		return core.ErrOverloaded
+	case strings.Contains(body, "rate_limit_error"):
+		return core.ErrRateLimited
+	case strings.Contains(body, "authentication_error"), strings.Contains(body, "permission_error"):
+		return core.ErrAuthFailed
+	case strings.Contains(body, "prompt is too long"), strings.Contains(body, "exceed context limit"):
+		return core.ErrContextTooLong
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,44 @@
+package anthropicprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+
+	"gar/internal/llm/core"
+)
+
+// ErrModelListUnsupported is returned by ListModels for Bedrock providers,
+// which have no models endpoint.
+var ErrModelListUnsupported = errors.New("model listing is not supported for bedrock")
+
+// ListModels returns every model the API key can use, newest first.
+func (p *Provider) ListModels(ctx context.Context) ([]core.ModelInfo, error) {
+	if p == nil {
+		return nil, fmt.Errorf("anthropic provider is nil")
+	}
+	if p.bedrock {
+		return nil, ErrModelListUnsupported
+	}
+	if strings.TrimSpace(p.apiKey) == "" {
+		return nil, core.ErrMissingAPIKey
+	}
+
+	var models []core.ModelInfo
+	pager := p.client.Models.ListAutoPaging(ctx, anthropic.ModelListParams{})
+	for pager.Next() {
+		model := pager.Current()
+		models = append(models, core.ModelInfo{
+			ID:          model.ID,
+			DisplayName: model.DisplayName,
+			CreatedAt:   model.CreatedAt,
+		})
+	}
+	if err := pager.Err(); err != nil {
+		return nil, fmt.Errorf("list models: %w", err)
+	}
+	return models, nil
+}
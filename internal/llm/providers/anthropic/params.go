@@ -12,6 +12,9 @@ import (
 // defaultMaxTokens is used when callers do not provide an explicit token budget.
 const defaultMaxTokens = 1024
 
+// minThinkingBudget is the smallest thinking budget the Messages API accepts.
+const minThinkingBudget = 1024
+
 // mapStopReason maps Anthropic stop reasons to canonical provider-agnostic values.
 func mapStopReason(reason string) (core.StopReason, error) {
 	switch reason {
@@ -56,9 +59,13 @@ func toAnthropicSDKParams(req *core.Request) (anthropic.MessageNewParams, error)
 	if strings.TrimSpace(req.System) != "" {
 		params.System = []anthropic.TextBlockParam{{Text: req.System}}
 	}
-	if req.Temperature != nil {
+	// The API rejects a temperature alongside extended thinking.
+	if req.Temperature != nil && req.ThinkingBudget <= 0 {
 		params.Temperature = anthropic.Float(*req.Temperature)
 	}
+	if len(req.StopSequences) > 0 {
+		params.StopSequences = append([]string(nil), req.StopSequences...)
+	}
 	if len(req.Tools) > 0 {
 		tools, err := toSDKTools(req.Tools)
 		if err != nil {
@@ -69,16 +76,52 @@ func toAnthropicSDKParams(req *core.Request) (anthropic.MessageNewParams, error)
 	if toolChoice, ok := toSDKToolChoice(req.ToolChoice); ok {
 		params.ToolChoice = toolChoice
 	}
-	metadata := req.Metadata
-	if metadata != nil {
-		if userID := strings.TrimSpace(metadata["user_id"]); userID != "" {
-			params.Metadata = anthropic.MetadataParam{UserID: anthropic.String(userID)}
-		}
+	if req.CacheSystem {
+		applyCacheBreakpoints(&params)
+	}
+	if req.ThinkingBudget > 0 {
+		applyThinkingBudget(&params, req.ThinkingBudget)
+	}
+	if metadata, ok := toSDKMetadata(req.Metadata); ok {
+		params.Metadata = metadata
 	}
 
 	return params, nil
 }
 
+// toSDKMetadata maps user_id, the only metadata key the Messages API
+// accepts. Other keys are dropped rather than rejected by the API.
+func toSDKMetadata(metadata map[string]string) (anthropic.MetadataParam, bool) {
+	userID := strings.TrimSpace(metadata["user_id"])
+	if userID == "" {
+		return anthropic.MetadataParam{}, false
+	}
+	return anthropic.MetadataParam{UserID: anthropic.String(userID)}, true
+}
+
+// applyCacheBreakpoints marks the final system block and final tool definition
+// as ephemeral cache breakpoints so the stable request prefix can be reused.
+func applyCacheBreakpoints(params *anthropic.MessageNewParams) {
+	if n := len(params.System); n > 0 {
+		params.System[n-1].CacheControl = anthropic.NewCacheControlEphemeralParam()
+	}
+	if n := len(params.Tools); n > 0 && params.Tools[n-1].OfTool != nil {
+		params.Tools[n-1].OfTool.CacheControl = anthropic.NewCacheControlEphemeralParam()
+	}
+}
+
+// applyThinkingBudget enables extended thinking. The API requires max_tokens to
+// exceed the budget, so the response allowance is added on top when it does not.
+func applyThinkingBudget(params *anthropic.MessageNewParams, budget int) {
+	if budget < minThinkingBudget {
+		budget = minThinkingBudget
+	}
+	params.Thinking = anthropic.ThinkingConfigParamOfEnabled(int64(budget))
+	if params.MaxTokens <= int64(budget) {
+		params.MaxTokens += int64(budget)
+	}
+}
+
 // toSDKMessages converts canonical conversation messages into Anthropic SDK messages.
 func toSDKMessages(messages []core.Message) ([]anthropic.MessageParam, error) {
 	out := make([]anthropic.MessageParam, 0, len(messages))
@@ -87,7 +130,7 @@ func toSDKMessages(messages []core.Message) ([]anthropic.MessageParam, error) {
 		msg := messages[i]
 		switch msg.Role {
 		case core.RoleUser:
-			blocks := toSDKTextBlocks(msg.Content)
+			blocks := toSDKContentBlocks(msg.Content)
 			if len(blocks) == 0 {
 				continue
 			}
@@ -116,31 +159,78 @@ func toSDKMessages(messages []core.Message) ([]anthropic.MessageParam, error) {
 	return out, nil
 }
 
-// toSDKTextBlocks keeps only non-empty text blocks supported by this integration.
-func toSDKTextBlocks(content []core.ContentBlock) []anthropic.ContentBlockParamUnion {
+// toSDKContentBlocks keeps the non-empty text and base64 image blocks
+// supported by this integration.
+func toSDKContentBlocks(content []core.ContentBlock) []anthropic.ContentBlockParamUnion {
 	blocks := make([]anthropic.ContentBlockParamUnion, 0, len(content))
 	for _, item := range content {
-		if item.Type != core.ContentTypeText {
-			continue
+		switch item.Type {
+		case core.ContentTypeText:
+			if item.Text == "" {
+				continue
+			}
+			blocks = append(blocks, anthropic.NewTextBlock(item.Text))
+		case core.ContentTypeImage:
+			if item.Data == "" || item.MediaType == "" {
+				continue
+			}
+			blocks = append(blocks, anthropic.NewImageBlockBase64(item.MediaType, item.Data))
 		}
-		text := item.Text
-		if text == "" {
-			continue
+	}
+	return blocks
+}
+
+// toSDKThinkingBlocks replays signed and redacted thinking blocks; the API
+// requires them ahead of other assistant content in a tool-use turn.
+func toSDKThinkingBlocks(content []core.ContentBlock) []anthropic.ContentBlockParamUnion {
+	blocks := make([]anthropic.ContentBlockParamUnion, 0)
+	for _, item := range content {
+		switch item.Type {
+		case core.ContentTypeThinking:
+			if item.Signature == "" {
+				continue
+			}
+			blocks = append(blocks, anthropic.NewThinkingBlock(item.Signature, item.Thinking))
+		case core.ContentTypeRedactedThinking:
+			if item.Data == "" {
+				continue
+			}
+			blocks = append(blocks, anthropic.NewRedactedThinkingBlock(item.Data))
 		}
-		blocks = append(blocks, anthropic.NewTextBlock(text))
 	}
 	return blocks
 }
 
-// toSDKAssistantBlocks builds assistant blocks, including tool_use blocks when present.
+// toSDKAssistantBlocks builds assistant blocks, including tool_use blocks when
+// present. Tool calls are placed where their tool_use content block appeared;
+// calls without one follow the other content.
 func toSDKAssistantBlocks(msg core.Message) []anthropic.ContentBlockParamUnion {
-	blocks := toSDKTextBlocks(msg.Content)
+	calls := make(map[string]core.ToolCall, len(msg.ToolCalls))
 	for _, call := range msg.ToolCalls {
 		if strings.TrimSpace(call.ID) == "" || strings.TrimSpace(call.Name) == "" {
 			continue
 		}
-		input := core.DecodeJSONObjectOrEmpty(call.Arguments)
-		blocks = append(blocks, anthropic.NewToolUseBlock(call.ID, input, call.Name))
+		calls[call.ID] = call
+	}
+	toolUse := func(call core.ToolCall) anthropic.ContentBlockParamUnion {
+		delete(calls, call.ID)
+		return anthropic.NewToolUseBlock(call.ID, core.DecodeJSONObjectOrEmpty(call.Arguments), call.Name)
+	}
+
+	blocks := toSDKThinkingBlocks(msg.Content)
+	for i, item := range msg.Content {
+		if item.Type != core.ContentTypeToolUse {
+			blocks = append(blocks, toSDKContentBlocks(msg.Content[i:i+1])...)
+			continue
+		}
+		if call, ok := calls[item.ToolCallID]; ok {
+			blocks = append(blocks, toolUse(call))
+		}
+	}
+	for _, call := range msg.ToolCalls {
+		if _, ok := calls[call.ID]; ok {
+			blocks = append(blocks, toolUse(call))
+		}
 	}
 	return blocks
 }
@@ -31,6 +31,8 @@ type Provider struct {
 	apiKey  string
 	retry   core.RetryPolicy
 	pricing map[string]core.ModelPricing
+	// bedrock providers authenticate with AWS credentials instead of an API key.
+	bedrock bool
 
 	client anthropic.Client
 }
@@ -76,7 +78,7 @@ func (p *Provider) Stream(ctx context.Context, req *core.Request) (<-chan core.E
 	if p == nil {
 		return nil, fmt.Errorf("anthropic provider is nil")
 	}
-	if strings.TrimSpace(p.apiKey) == "" {
+	if !p.bedrock && strings.TrimSpace(p.apiKey) == "" {
 		return nil, core.ErrMissingAPIKey
 	}
 
@@ -91,7 +93,10 @@ func (p *Provider) Stream(ctx context.Context, req *core.Request) (<-chan core.E
 	go func() {
 		defer close(events)
 		state := &streamState{reason: core.StopReasonStop}
-		if err := p.streamWithRetry(ctx, params, req.Model, retry, events, state); err != nil {
+		err := core.RetryStream(ctx, retry, func() error {
+			return p.streamOnce(ctx, params, req.Model, events, state)
+		}, func() bool { return state.emittedVisible })
+		if err != nil {
 			reason := core.StopReasonError
 			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 				reason = core.StopReasonAborted
@@ -102,7 +107,7 @@ func (p *Provider) Stream(ctx context.Context, req *core.Request) (<-chan core.E
 					Reason: reason,
 					Usage:  state.usage,
 				},
-				Err: fmt.Errorf("anthropic stream: %w", err),
+				Err: fmt.Errorf("anthropic stream: %w", classifyError(err)),
 			})
 		}
 	}()
@@ -114,6 +119,7 @@ func (p *Provider) Stream(ctx context.Context, req *core.Request) (<-chan core.E
 type streamState struct {
 	usage            core.Usage
 	reason           core.StopReason
+	stopSequence     string
 	emittedVisible   bool
 	startEmitted     bool
 	emittedDone      bool
@@ -127,36 +133,6 @@ type toolCallAccumulator struct {
 	buf  strings.Builder
 }
 
-// streamWithRetry retries failed streams only when no visible output has been emitted yet.
-func (p *Provider) streamWithRetry(
-	ctx context.Context,
-	params anthropic.MessageNewParams,
-	model string,
-	retry core.RetryPolicy,
-	events chan<- core.Event,
-	state *streamState,
-) error {
-	attempt := 0
-	for {
-		attemptErr := p.streamOnce(ctx, params, model, events, state)
-		if attemptErr == nil {
-			return nil
-		}
-		if errors.Is(attemptErr, context.Canceled) || errors.Is(attemptErr, context.DeadlineExceeded) {
-			return attemptErr
-		}
-		if !core.IsRetryableError(attemptErr) || state.emittedVisible || attempt >= retry.MaxRetries {
-			return attemptErr
-		}
-
-		delay := core.ComputeBackoffDelay(retry, attempt)
-		if err := core.SleepContext(ctx, delay); err != nil {
-			return err
-		}
-		attempt++
-	}
-}
-
 // streamOnce consumes one SDK stream and emits canonical events.
 func (p *Provider) streamOnce(
 	ctx context.Context,
@@ -198,7 +174,7 @@ func (p *Provider) streamOnce(
 	if err := stream.Err(); err != nil {
 		wrapped := fmt.Errorf("anthropic sdk stream: %w", err)
 		if isRetryableProviderError(err) {
-			return core.MarkRetryable(wrapped)
+			return core.MarkRetryableAfter(wrapped, retryAfter(err))
 		}
 		return wrapped
 	}
@@ -338,6 +314,10 @@ func (p *Provider) handleSDKStreamEvent(
 		case anthropic.TextDelta:
 			state.emittedVisible = true
 			return core.SendEvent(ctx, events, core.Event{Type: core.EventTextDelta, TextDelta: delta.Text})
+		case anthropic.ThinkingDelta:
+			return core.SendEvent(ctx, events, core.Event{Type: core.EventThinkingDelta, ThinkingDelta: delta.Thinking})
+		case anthropic.SignatureDelta:
+			return core.SendEvent(ctx, events, core.Event{Type: core.EventThinkingDelta, SignatureDelta: delta.Signature})
 		case anthropic.InputJSONDelta:
 			acc, ok := state.toolAccumulators[int(variant.Index)]
 			if !ok {
@@ -351,6 +331,12 @@ func (p *Provider) handleSDKStreamEvent(
 		}
 
 	case anthropic.ContentBlockStopEvent:
+		if err := core.SendEvent(ctx, events, core.Event{
+			Type:             core.EventContentBlockStop,
+			ContentBlockStop: &core.ContentBlockStop{Index: variant.Index},
+		}); err != nil {
+			return err
+		}
 		acc, ok := state.toolAccumulators[int(variant.Index)]
 		if !ok {
 			return nil
@@ -382,6 +368,7 @@ func (p *Provider) handleSDKStreamEvent(
 				return err
 			}
 			state.reason = reason
+			state.stopSequence = variant.Delta.StopSequence
 		}
 		applyDeltaUsage(&state.usage, variant.Usage)
 		state.usage.TotalTokens = state.usage.TokenCount()
@@ -393,8 +380,9 @@ func (p *Provider) handleSDKStreamEvent(
 		return core.SendEvent(ctx, events, core.Event{
 			Type: core.EventDone,
 			Done: &core.DonePayload{
-				Reason: state.reason,
-				Usage:  state.usage,
+				Reason:       state.reason,
+				Usage:        state.usage,
+				StopSequence: state.stopSequence,
 			},
 		})
 	}
@@ -3,16 +3,18 @@ package anthropicprovider
 import (
 	"errors"
 	"net"
-	"net/http"
+	"time"
 
 	anthropic "github.com/anthropics/anthropic-sdk-go"
+
+	"gar/internal/llm/core"
 )
 
 // isRetryableProviderError identifies transient transport/API failures worth retrying.
 func isRetryableProviderError(err error) bool {
 	var apiErr *anthropic.Error
 	if errors.As(err, &apiErr) {
-		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+		return core.IsRetryableStatus(apiErr.StatusCode)
 	}
 	var netErr net.Error
 	if errors.As(err, &netErr) {
@@ -20,3 +22,12 @@ func isRetryableProviderError(err error) bool {
 	}
 	return false
 }
+
+// retryAfter returns the Retry-After delay of an API error response.
+func retryAfter(err error) time.Duration {
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return 0
+	}
+	return core.ParseRetryAfter(apiErr.Response.Header, time.Now())
+}
@@ -0,0 +1,277 @@
+package geminiprovider
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"gar/internal/llm/core"
+)
+
+type serializedGeminiRequest struct {
+	Contents          []serializedGeminiContent `json:"contents"`
+	SystemInstruction *serializedGeminiContent  `json:"systemInstruction"`
+	Tools             []struct {
+		FunctionDeclarations []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			Parameters  struct {
+				Type       string         `json:"type"`
+				Properties map[string]any `json:"properties"`
+				Required   []string       `json:"required"`
+			} `json:"parameters"`
+		} `json:"functionDeclarations"`
+	} `json:"tools"`
+	ToolConfig *struct {
+		FunctionCallingConfig struct {
+			Mode                 string   `json:"mode"`
+			AllowedFunctionNames []string `json:"allowedFunctionNames"`
+		} `json:"functionCallingConfig"`
+	} `json:"toolConfig"`
+	GenerationConfig struct {
+		MaxOutputTokens int      `json:"maxOutputTokens"`
+		Temperature     *float64 `json:"temperature"`
+	} `json:"generationConfig"`
+}
+
+type serializedGeminiContent struct {
+	Role  string `json:"role"`
+	Parts []struct {
+		Text       string `json:"text"`
+		InlineData *struct {
+			MimeType string `json:"mimeType"`
+			Data     string `json:"data"`
+		} `json:"inlineData"`
+		FunctionCall *struct {
+			ID   string         `json:"id"`
+			Name string         `json:"name"`
+			Args map[string]any `json:"args"`
+		} `json:"functionCall"`
+		FunctionResponse *struct {
+			ID       string         `json:"id"`
+			Name     string         `json:"name"`
+			Response map[string]any `json:"response"`
+		} `json:"functionResponse"`
+	} `json:"parts"`
+}
+
+// TestToGenerateContentRequestTextAndSystem verifies user text and system prompt mapping.
+func TestToGenerateContentRequestTextAndSystem(t *testing.T) {
+	t.Parallel()
+
+	temperature := 0.2
+	params, err := toGenerateContentRequest(&core.Request{
+		Model:       "gemini-2.5-flash",
+		System:      "You are concise.",
+		Temperature: &temperature,
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("toGenerateContentRequest() error = %v", err)
+	}
+
+	body := decodeWireRequest(t, params)
+	if len(body.Contents) != 1 || body.Contents[0].Role != "user" || body.Contents[0].Parts[0].Text != "hello" {
+		t.Fatalf("unexpected contents: %+v", body.Contents)
+	}
+	if body.SystemInstruction == nil || body.SystemInstruction.Parts[0].Text != "You are concise." {
+		t.Fatalf("unexpected system instruction: %+v", body.SystemInstruction)
+	}
+	if body.GenerationConfig.MaxOutputTokens != defaultMaxTokens {
+		t.Fatalf("maxOutputTokens = %d, want %d", body.GenerationConfig.MaxOutputTokens, defaultMaxTokens)
+	}
+	if body.GenerationConfig.Temperature == nil || *body.GenerationConfig.Temperature != 0.2 {
+		t.Fatalf("temperature = %v, want 0.2", body.GenerationConfig.Temperature)
+	}
+}
+
+func TestToGenerateContentRequestMapsImagesToInlineData(t *testing.T) {
+	t.Parallel()
+
+	params, err := toGenerateContentRequest(&core.Request{
+		Model: "gemini-2.5-flash",
+		Messages: []core.Message{
+			{
+				Role: core.RoleUser,
+				Content: []core.ContentBlock{
+					{Type: core.ContentTypeText, Text: "what is this?"},
+					{Type: core.ContentTypeImage, MediaType: "image/png", Data: "iVBORw0KGgo="},
+					{Type: core.ContentTypeImage, Data: "missing-media-type"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("toGenerateContentRequest() error = %v", err)
+	}
+
+	body := decodeWireRequest(t, params)
+	if len(body.Contents) != 1 || len(body.Contents[0].Parts) != 2 {
+		t.Fatalf("unexpected contents: %+v", body.Contents)
+	}
+	parts := body.Contents[0].Parts
+	if parts[0].Text != "what is this?" {
+		t.Fatalf("parts[0] = %+v, want the prompt text", parts[0])
+	}
+	if parts[1].InlineData == nil || parts[1].InlineData.MimeType != "image/png" || parts[1].InlineData.Data != "iVBORw0KGgo=" {
+		t.Fatalf("parts[1] = %+v, want inline png data", parts[1])
+	}
+}
+
+// TestToGenerateContentRequestMapsToolTurns verifies function calls and grouped function responses.
+func TestToGenerateContentRequestMapsToolTurns(t *testing.T) {
+	t.Parallel()
+
+	params, err := toGenerateContentRequest(&core.Request{
+		Model: "gemini-2.5-flash",
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "read both"}}},
+			{
+				Role:    core.RoleAssistant,
+				Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "reading"}},
+				ToolCalls: []core.ToolCall{
+					{ID: "call_1", Name: "read", Arguments: json.RawMessage(`{"path":"a.go"}`)},
+					{ID: "call_2", Name: "read", Arguments: json.RawMessage(`{"path":"b.go"}`)},
+				},
+			},
+			{Role: core.RoleTool, ToolResult: &core.ToolResult{ToolCallID: "call_1", ToolName: "read", Content: "package a"}},
+			{Role: core.RoleTool, ToolResult: &core.ToolResult{ToolCallID: "call_2", ToolName: "read", Content: "missing", IsError: true}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("toGenerateContentRequest() error = %v", err)
+	}
+
+	body := decodeWireRequest(t, params)
+	if len(body.Contents) != 3 {
+		t.Fatalf("content count = %d, want 3", len(body.Contents))
+	}
+
+	model := body.Contents[1]
+	if model.Role != "model" || len(model.Parts) != 3 || model.Parts[0].Text != "reading" {
+		t.Fatalf("unexpected model content: %+v", model)
+	}
+	call := model.Parts[1].FunctionCall
+	if call == nil || call.ID != "call_1" || call.Name != "read" || call.Args["path"] != "a.go" {
+		t.Fatalf("unexpected function call: %+v", call)
+	}
+
+	responses := body.Contents[2]
+	if responses.Role != "user" || len(responses.Parts) != 2 {
+		t.Fatalf("function responses were not grouped: %+v", responses)
+	}
+	first, second := responses.Parts[0].FunctionResponse, responses.Parts[1].FunctionResponse
+	if first == nil || first.Name != "read" || first.Response["output"] != "package a" {
+		t.Fatalf("unexpected first function response: %+v", first)
+	}
+	if second == nil || second.ID != "call_2" || second.Response["error"] != "missing" {
+		t.Fatalf("unexpected error function response: %+v", second)
+	}
+
+	if _, err := toGenerateContentRequest(&core.Request{
+		Model: "gemini-2.5-flash",
+		Messages: []core.Message{
+			{Role: core.RoleTool, ToolResult: &core.ToolResult{ToolCallID: "call_1", Content: "orphan"}},
+		},
+	}); !errors.Is(err, core.ErrInvalidRequest) {
+		t.Fatalf("expected ErrInvalidRequest for missing tool name, got %v", err)
+	}
+}
+
+// TestToGenerateContentRequestMapsToolsAndChoice verifies declarations and function-calling modes.
+func TestToGenerateContentRequestMapsToolsAndChoice(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		choice   core.ToolChoice
+		wantMode string
+		wantOnly []string
+	}{
+		{name: "unset", choice: core.ToolChoice{}},
+		{name: "auto", choice: core.ToolChoice{Type: core.ToolChoiceAuto}, wantMode: "AUTO"},
+		{name: "any", choice: core.ToolChoice{Type: core.ToolChoiceAny}, wantMode: "ANY"},
+		{name: "none", choice: core.ToolChoice{Type: core.ToolChoiceNone}, wantMode: "NONE"},
+		{name: "named", choice: core.ToolChoice{Type: core.ToolChoiceTool, Name: "read"}, wantMode: "ANY", wantOnly: []string{"read"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			params, err := toGenerateContentRequest(&core.Request{
+				Model: "gemini-2.5-flash",
+				Tools: []core.ToolSpec{{
+					Name:        "read",
+					Description: "Read a file",
+					Schema:      json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"}},"required":["path"]}`),
+				}},
+				ToolChoice: tc.choice,
+			})
+			if err != nil {
+				t.Fatalf("toGenerateContentRequest() error = %v", err)
+			}
+
+			body := decodeWireRequest(t, params)
+			if len(body.Tools) != 1 || len(body.Tools[0].FunctionDeclarations) != 1 {
+				t.Fatalf("unexpected tools: %+v", body.Tools)
+			}
+			decl := body.Tools[0].FunctionDeclarations[0]
+			if decl.Name != "read" || decl.Parameters.Type != "object" || len(decl.Parameters.Required) != 1 {
+				t.Fatalf("unexpected declaration: %+v", decl)
+			}
+
+			if tc.wantMode == "" {
+				if body.ToolConfig != nil {
+					t.Fatalf("toolConfig = %+v, want omitted", body.ToolConfig)
+				}
+				return
+			}
+			if body.ToolConfig == nil || body.ToolConfig.FunctionCallingConfig.Mode != tc.wantMode {
+				t.Fatalf("toolConfig = %+v, want mode %s", body.ToolConfig, tc.wantMode)
+			}
+			if len(tc.wantOnly) > 0 && (len(body.ToolConfig.FunctionCallingConfig.AllowedFunctionNames) != 1 ||
+				body.ToolConfig.FunctionCallingConfig.AllowedFunctionNames[0] != tc.wantOnly[0]) {
+				t.Fatalf("allowedFunctionNames = %v, want %v", body.ToolConfig.FunctionCallingConfig.AllowedFunctionNames, tc.wantOnly)
+			}
+		})
+	}
+}
+
+func TestMapStopReason(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]core.StopReason{
+		"STOP":       core.StopReasonStop,
+		"MAX_TOKENS": core.StopReasonLength,
+		"SAFETY":     core.StopReasonError,
+		"OTHER":      core.StopReasonError,
+	}
+	for input, want := range tests {
+		got, err := mapStopReason(input)
+		if err != nil {
+			t.Fatalf("mapStopReason(%q) error = %v", input, err)
+		}
+		if got != want {
+			t.Fatalf("mapStopReason(%q) = %q, want %q", input, got, want)
+		}
+	}
+	if _, err := mapStopReason("SOMETHING_NEW"); err == nil {
+		t.Fatalf("expected error for unknown finish reason")
+	}
+}
+
+func decodeWireRequest(t *testing.T, params generateContentRequest) serializedGeminiRequest {
+	t.Helper()
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("json.Marshal(params) error = %v", err)
+	}
+	var body serializedGeminiRequest
+	if err := json.Unmarshal(raw, &body); err != nil {
+		t.Fatalf("json.Unmarshal(body) error = %v", err)
+	}
+	return body
+}
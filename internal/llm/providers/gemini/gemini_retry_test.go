@@ -0,0 +1,100 @@
+package geminiprovider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gar/internal/llm/core"
+)
+
+// TestRetryOn429BeforeFirstDelta verifies pre-output 429 responses are retried.
+func TestRetryOn429BeforeFirstDelta(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := newSSEServer(t, func(w http.ResponseWriter) bool {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = fmt.Fprint(w, `{"error":{"message":"resource exhausted"}}`)
+			return true
+		}
+		return false
+	}, []string{
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`,
+	})
+	defer server.Close()
+
+	events := collectStream(t, New(Config{APIKey: "test-key", BaseURL: server.URL, Retry: testRetryPolicy()}))
+
+	var startCount, errorCount int
+	var seenDone bool
+	for _, ev := range events {
+		switch ev.Type {
+		case core.EventStart:
+			startCount++
+		case core.EventDone:
+			seenDone = true
+		case core.EventError:
+			errorCount++
+		}
+	}
+	if !seenDone || errorCount != 0 || startCount != 1 {
+		t.Fatalf("done=%v errors=%d starts=%d, want true/0/1", seenDone, errorCount, startCount)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected 2 attempts, got %d", got)
+	}
+}
+
+// TestNoRetryAfterFirstDelta verifies retries stop once visible output has been emitted.
+func TestNoRetryAfterFirstDelta(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Errorf("response writer does not implement flusher")
+			return
+		}
+
+		// Intentionally end without a finishReason after visible text.
+		_, _ = fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"partial\"}]}}]}\n\n")
+		flusher.Flush()
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	events := collectStream(t, New(Config{APIKey: "test-key", BaseURL: server.URL, Retry: testRetryPolicy()}))
+
+	var sawDelta bool
+	var errorCount int
+	for _, ev := range events {
+		switch ev.Type {
+		case core.EventTextDelta:
+			sawDelta = true
+		case core.EventError:
+			errorCount++
+		}
+	}
+	if !sawDelta || errorCount != 1 {
+		t.Fatalf("delta=%v errors=%d, want true/1", sawDelta, errorCount)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected 1 attempt after visible output, got %d", got)
+	}
+}
+
+func testRetryPolicy() core.RetryPolicy {
+	return core.RetryPolicy{
+		MaxRetries: 2,
+		BaseDelay:  10 * time.Millisecond,
+		MaxDelay:   20 * time.Millisecond,
+	}
+}
@@ -0,0 +1,166 @@
+package geminiprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gar/internal/llm/core"
+)
+
+// TestStreamEmitsTextDeltaUsageAndDone verifies basic text streaming emits delta, usage and done events.
+func TestStreamEmitsTextDeltaUsageAndDone(t *testing.T) {
+	t.Parallel()
+
+	server := newSSEServer(t, nil, []string{
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"thinking it over","thought":true}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]}}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":1}}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":" there"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":2,"cachedContentTokenCount":4,"thoughtsTokenCount":3}}`,
+	})
+	defer server.Close()
+
+	events := collectStream(t, New(Config{APIKey: "test-key", BaseURL: server.URL}))
+
+	var text, thinking string
+	var usage *core.Usage
+	var done *core.DonePayload
+	for _, ev := range events {
+		switch ev.Type {
+		case core.EventTextDelta:
+			text += ev.TextDelta
+		case core.EventThinkingDelta:
+			thinking += ev.ThinkingDelta
+		case core.EventUsage:
+			usage = ev.Usage
+		case core.EventDone:
+			done = ev.Done
+		case core.EventError:
+			t.Fatalf("unexpected error event: %v", ev.Err)
+		}
+	}
+	if text != "hi there" || thinking != "thinking it over" {
+		t.Fatalf("text=%q thinking=%q, want streamed deltas", text, thinking)
+	}
+	if usage == nil || usage.InputTokens != 6 || usage.CacheReadTokens != 4 || usage.OutputTokens != 5 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	if done == nil || done.Reason != core.StopReasonStop {
+		t.Fatalf("unexpected done payload: %+v", done)
+	}
+}
+
+// TestStreamEmitsFunctionCallsAsToolUse verifies function-call parts map to tool call events.
+func TestStreamEmitsFunctionCallsAsToolUse(t *testing.T) {
+	t.Parallel()
+
+	server := newSSEServer(t, nil, []string{
+		`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"id":"fc_1","name":"read","args":{"path":"main.go"}}},{"functionCall":{"name":"ls","args":{}}}]},"finishReason":"STOP"}]}`,
+	})
+	defer server.Close()
+
+	events := collectStream(t, New(Config{APIKey: "test-key", BaseURL: server.URL}))
+
+	var starts, deltas int
+	var ends []*core.ToolCall
+	var done *core.DonePayload
+	for _, ev := range events {
+		switch ev.Type {
+		case core.EventToolCallStart:
+			starts++
+		case core.EventToolCallDelta:
+			deltas++
+		case core.EventToolCallEnd:
+			ends = append(ends, ev.ToolCall)
+		case core.EventDone:
+			done = ev.Done
+		case core.EventError:
+			t.Fatalf("unexpected error event: %v", ev.Err)
+		}
+	}
+	if starts != 2 || deltas != 2 || len(ends) != 2 {
+		t.Fatalf("starts=%d deltas=%d ends=%d, want 2/2/2", starts, deltas, len(ends))
+	}
+	if ends[0].ID != "fc_1" || ends[0].Name != "read" || string(ends[0].Arguments) != `{"path":"main.go"}` {
+		t.Fatalf("unexpected first tool call: %+v", ends[0])
+	}
+	if ends[1].ID == "" || ends[1].Name != "ls" || string(ends[1].Arguments) != `{}` {
+		t.Fatalf("unexpected second tool call: %+v", ends[1])
+	}
+	if done == nil || done.Reason != core.StopReasonToolUse {
+		t.Fatalf("unexpected done payload: %+v", done)
+	}
+}
+
+// TestStreamNonRetryableStatusReturnsError verifies 4xx responses surface as terminal errors.
+func TestStreamNonRetryableStatusReturnsError(t *testing.T) {
+	t.Parallel()
+
+	server := newSSEServer(t, func(w http.ResponseWriter) bool {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprint(w, `{"error":{"message":"bad request"}}`)
+		return true
+	}, nil)
+	defer server.Close()
+
+	events := collectStream(t, New(Config{APIKey: "test-key", BaseURL: server.URL}))
+	if len(events) != 1 || events[0].Type != core.EventError {
+		t.Fatalf("expected single error event, got %+v", events)
+	}
+	if events[0].Done == nil || events[0].Done.Reason != core.StopReasonError {
+		t.Fatalf("unexpected error payload: %+v", events[0].Done)
+	}
+}
+
+func newSSEServer(t *testing.T, before func(w http.ResponseWriter) bool, chunks []string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.5-flash:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected url %q", r.URL.String())
+		}
+		if got := r.Header.Get("x-goog-api-key"); got != "test-key" {
+			t.Errorf("unexpected api key header %q", got)
+		}
+		if before != nil && before(w) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Errorf("response writer does not implement flusher")
+			return
+		}
+		for _, chunk := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+			flusher.Flush()
+		}
+	}))
+}
+
+func collectStream(t *testing.T, p *Provider) []core.Event {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := p.Stream(ctx, &core.Request{
+		Model:     "gemini-2.5-flash",
+		MaxTokens: 128,
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	var events []core.Event
+	for ev := range stream {
+		events = append(events, ev)
+	}
+	return events
+}
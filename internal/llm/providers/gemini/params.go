@@ -0,0 +1,281 @@
+package geminiprovider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gar/internal/llm/core"
+)
+
+// defaultMaxTokens is used when callers do not provide an explicit token budget.
+const defaultMaxTokens = 1024
+
+// generateContentRequest is the :streamGenerateContent request body.
+type generateContentRequest struct {
+	Contents          []content         `json:"contents"`
+	SystemInstruction *content          `json:"systemInstruction,omitempty"`
+	Tools             []tool            `json:"tools,omitempty"`
+	ToolConfig        *toolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *generationConfig `json:"generationConfig,omitempty"`
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+type part struct {
+	Text             string            `json:"text,omitempty"`
+	Thought          bool              `json:"thought,omitempty"`
+	FunctionCall     *functionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *functionResponse `json:"functionResponse,omitempty"`
+	InlineData       *inlineData       `json:"inlineData,omitempty"`
+}
+
+// inlineData carries base64 media such as an attached image.
+type inlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type functionCall struct {
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
+
+type functionResponse struct {
+	ID       string         `json:"id,omitempty"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type tool struct {
+	FunctionDeclarations []functionDeclaration `json:"functionDeclarations"`
+}
+
+type functionDeclaration struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Parameters  core.ToolJSONSchema `json:"parameters"`
+}
+
+type toolConfig struct {
+	FunctionCallingConfig functionCallingConfig `json:"functionCallingConfig"`
+}
+
+type functionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type generationConfig struct {
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+}
+
+// mapStopReason maps Gemini finish reasons to canonical provider-agnostic values.
+// Gemini reports STOP even when the turn ends in function calls, so callers
+// upgrade STOP to tool_use when a call was emitted.
+func mapStopReason(reason string) (core.StopReason, error) {
+	switch reason {
+	case "STOP":
+		return core.StopReasonStop, nil
+	case "MAX_TOKENS":
+		return core.StopReasonLength, nil
+	case "SAFETY", "RECITATION", "LANGUAGE", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII",
+		"MALFORMED_FUNCTION_CALL", "UNEXPECTED_TOOL_CALL", "OTHER", "FINISH_REASON_UNSPECIFIED":
+		return core.StopReasonError, nil
+	default:
+		return "", fmt.Errorf("unhandled finish reason: %s", reason)
+	}
+}
+
+// toGenerateContentRequest validates and converts a canonical request into the wire body.
+func toGenerateContentRequest(req *core.Request) (generateContentRequest, error) {
+	if req == nil {
+		return generateContentRequest{}, fmt.Errorf("%w: request is nil", core.ErrInvalidRequest)
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		return generateContentRequest{}, fmt.Errorf("%w: model is required", core.ErrInvalidRequest)
+	}
+
+	contents, err := toWireContents(req.Messages)
+	if err != nil {
+		return generateContentRequest{}, err
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	body := generateContentRequest{
+		Contents:         contents,
+		GenerationConfig: &generationConfig{MaxOutputTokens: maxTokens},
+	}
+	if strings.TrimSpace(req.System) != "" {
+		body.SystemInstruction = &content{Parts: []part{{Text: req.System}}}
+	}
+	if req.Temperature != nil {
+		temperature := *req.Temperature
+		body.GenerationConfig.Temperature = &temperature
+	}
+	if len(req.StopSequences) > 0 {
+		body.GenerationConfig.StopSequences = append([]string(nil), req.StopSequences...)
+	}
+	if len(req.Tools) > 0 {
+		declarations, err := toWireFunctionDeclarations(req.Tools)
+		if err != nil {
+			return generateContentRequest{}, err
+		}
+		body.Tools = []tool{{FunctionDeclarations: declarations}}
+	}
+	if config, ok := toWireToolConfig(req.ToolChoice); ok {
+		body.ToolConfig = config
+	}
+
+	return body, nil
+}
+
+// toWireContents converts canonical conversation messages into Gemini contents.
+// Consecutive messages that map to the same role are merged, which keeps the
+// function responses for one model turn together as Gemini requires.
+func toWireContents(messages []core.Message) ([]content, error) {
+	out := make([]content, 0, len(messages))
+	appendParts := func(role string, parts []part) {
+		if len(parts) == 0 {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Parts = append(out[n-1].Parts, parts...)
+			return
+		}
+		out = append(out, content{Role: role, Parts: parts})
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case core.RoleUser:
+			var parts []part
+			if text := joinText(msg.Content); text != "" {
+				parts = append(parts, part{Text: text})
+			}
+			appendParts("user", append(parts, toImageParts(msg.Content)...))
+		case core.RoleAssistant:
+			var parts []part
+			if text := joinText(msg.Content); text != "" {
+				parts = append(parts, part{Text: text})
+			}
+			for _, call := range msg.ToolCalls {
+				if strings.TrimSpace(call.Name) == "" {
+					continue
+				}
+				parts = append(parts, part{FunctionCall: &functionCall{
+					ID:   call.ID,
+					Name: call.Name,
+					Args: core.DecodeJSONObjectOrEmpty(call.Arguments),
+				}})
+			}
+			appendParts("model", parts)
+		case core.RoleTool:
+			if msg.ToolResult == nil {
+				continue
+			}
+			tr := msg.ToolResult
+			if strings.TrimSpace(tr.ToolName) == "" {
+				return nil, fmt.Errorf("%w: tool result missing tool name", core.ErrInvalidRequest)
+			}
+			key := "output"
+			if tr.IsError {
+				key = "error"
+			}
+			appendParts("user", []part{{FunctionResponse: &functionResponse{
+				ID:       tr.ToolCallID,
+				Name:     tr.ToolName,
+				Response: map[string]any{key: tr.Content},
+			}}})
+		default:
+			return nil, fmt.Errorf("%w: unsupported role %q", core.ErrInvalidRequest, msg.Role)
+		}
+	}
+
+	return out, nil
+}
+
+// joinText concatenates non-empty text blocks supported by this integration.
+func joinText(blocks []core.ContentBlock) string {
+	parts := make([]string, 0, len(blocks))
+	for _, item := range blocks {
+		if item.Type != core.ContentTypeText || item.Text == "" {
+			continue
+		}
+		parts = append(parts, item.Text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// toImageParts converts base64 image blocks into inline data parts.
+func toImageParts(blocks []core.ContentBlock) []part {
+	var parts []part
+	for _, item := range blocks {
+		if item.Type != core.ContentTypeImage || item.Data == "" || item.MediaType == "" {
+			continue
+		}
+		parts = append(parts, part{InlineData: &inlineData{MimeType: item.MediaType, Data: item.Data}})
+	}
+	return parts
+}
+
+// toWireFunctionDeclarations converts canonical tool specs into function declarations.
+func toWireFunctionDeclarations(tools []core.ToolSpec) ([]functionDeclaration, error) {
+	out := make([]functionDeclaration, 0, len(tools))
+	for _, spec := range tools {
+		schema, err := core.DecodeToolJSONSchema(spec.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("decode tool schema for %q: %w", spec.Name, err)
+		}
+		out = append(out, functionDeclaration{
+			Name:        spec.Name,
+			Description: strings.TrimSpace(spec.Description),
+			Parameters:  schema,
+		})
+	}
+	return out, nil
+}
+
+// toWireToolConfig maps canonical tool choice behavior to Gemini function-calling modes.
+func toWireToolConfig(choice core.ToolChoice) (*toolConfig, bool) {
+	switch choice.Type {
+	case core.ToolChoiceAuto:
+		return &toolConfig{FunctionCallingConfig: functionCallingConfig{Mode: "AUTO"}}, true
+	case core.ToolChoiceAny:
+		return &toolConfig{FunctionCallingConfig: functionCallingConfig{Mode: "ANY"}}, true
+	case core.ToolChoiceNone:
+		return &toolConfig{FunctionCallingConfig: functionCallingConfig{Mode: "NONE"}}, true
+	case core.ToolChoiceTool:
+		if strings.TrimSpace(choice.Name) == "" {
+			return nil, false
+		}
+		return &toolConfig{FunctionCallingConfig: functionCallingConfig{
+			Mode:                 "ANY",
+			AllowedFunctionNames: []string{choice.Name},
+		}}, true
+	default:
+		return nil, false
+	}
+}
+
+// marshalArgs encodes streamed function-call args as a canonical JSON object.
+func marshalArgs(args map[string]any) (json.RawMessage, error) {
+	if args == nil {
+		return json.RawMessage("{}"), nil
+	}
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("marshal function call args: %w", err)
+	}
+	return raw, nil
+}
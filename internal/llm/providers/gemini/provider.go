@@ -0,0 +1,358 @@
+package geminiprovider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gar/internal/llm/core"
+)
+
+const (
+	defaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	maxSSELineSize = 1024 * 1024
+	sseDataPrefix  = "data:"
+)
+
+// Config configures the Gemini generateContent provider.
+type Config struct {
+	APIKey       string
+	BaseURL      string
+	HTTPClient   *http.Client
+	Retry        core.RetryPolicy
+	ModelPricing map[string]core.ModelPricing
+}
+
+// Provider is a thin HTTP client for Gemini :streamGenerateContent streaming.
+type Provider struct {
+	apiKey     string
+	baseURL    string
+	retry      core.RetryPolicy
+	pricing    map[string]core.ModelPricing
+	httpClient *http.Client
+}
+
+// New constructs a provider with sane defaults.
+func New(cfg Config) *Provider {
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	pricing := cfg.ModelPricing
+	if pricing == nil {
+		pricing = map[string]core.ModelPricing{}
+	}
+
+	return &Provider{
+		apiKey:     strings.TrimSpace(cfg.APIKey),
+		baseURL:    baseURL,
+		retry:      core.NormalizeRetryPolicy(cfg.Retry),
+		pricing:    pricing,
+		httpClient: httpClient,
+	}
+}
+
+// Stream executes a single streamGenerateContent request.
+func (p *Provider) Stream(ctx context.Context, req *core.Request) (<-chan core.Event, error) {
+	if p == nil {
+		return nil, fmt.Errorf("gemini provider is nil")
+	}
+	if strings.TrimSpace(p.apiKey) == "" {
+		return nil, core.ErrMissingAPIKey
+	}
+
+	body, err := toGenerateContentRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal generate content request: %w", err)
+	}
+
+	events := make(chan core.Event, 1)
+	retry := core.MergeRetryPolicy(p.retry, req.Retry)
+
+	go func() {
+		defer close(events)
+		state := &streamState{reason: core.StopReasonStop}
+		err := core.RetryStream(ctx, retry, func() error {
+			return p.streamOnce(ctx, payload, req.Model, events, state)
+		}, func() bool { return state.emittedVisible })
+		if err != nil {
+			reason := core.StopReasonError
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				reason = core.StopReasonAborted
+			}
+			core.SendTerminalEvent(events, core.Event{
+				Type: core.EventError,
+				Done: &core.DonePayload{
+					Reason: reason,
+					Usage:  state.usage,
+				},
+				Err: fmt.Errorf("gemini stream: %w", err),
+			})
+		}
+	}()
+
+	return events, nil
+}
+
+// streamState tracks incremental response state across one logical stream request.
+type streamState struct {
+	usage          core.Usage
+	reason         core.StopReason
+	finished       bool
+	emittedVisible bool
+	startEmitted   bool
+	emittedTool    bool
+}
+
+// generateContentChunk is one SSE data payload from the streaming endpoint.
+type generateContentChunk struct {
+	Candidates    []candidate    `json:"candidates"`
+	UsageMetadata *usageMetadata `json:"usageMetadata"`
+}
+
+type candidate struct {
+	Index        int     `json:"index"`
+	Content      content `json:"content"`
+	FinishReason string  `json:"finishReason"`
+}
+
+type usageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+}
+
+// streamOnce performs one HTTP request and emits canonical events from its SSE body.
+func (p *Provider) streamOnce(
+	ctx context.Context,
+	payload []byte,
+	model string,
+	events chan<- core.Event,
+	state *streamState,
+) error {
+	endpoint := p.baseURL + "/models/" + url.PathEscape(model) + ":streamGenerateContent?alt=sse"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build generate content request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("x-goog-api-key", p.apiKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		wrapped := fmt.Errorf("gemini request: %w", err)
+		if core.IsRetryableHTTPError(err) {
+			return core.MarkRetryable(wrapped)
+		}
+		return wrapped
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if err := core.CheckHTTPResponse("gemini", resp); err != nil {
+		return err
+	}
+
+	if !state.startEmitted {
+		if err := core.SendEvent(ctx, events, core.Event{Type: core.EventStart}); err != nil {
+			return err
+		}
+		state.startEmitted = true
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELineSize)
+
+	var data strings.Builder
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line := scanner.Text()
+		if line != "" {
+			if strings.HasPrefix(line, sseDataPrefix) {
+				if data.Len() > 0 {
+					data.WriteByte('\n')
+				}
+				data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, sseDataPrefix)))
+			}
+			continue
+		}
+
+		if data.Len() == 0 {
+			continue
+		}
+		raw := data.String()
+		data.Reset()
+		if err := p.handleSSEData(ctx, raw, model, events, state); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		wrapped := fmt.Errorf("gemini read stream: %w", err)
+		if core.IsRetryableHTTPError(err) {
+			return core.MarkRetryable(wrapped)
+		}
+		return wrapped
+	}
+
+	if data.Len() > 0 {
+		if err := p.handleSSEData(ctx, data.String(), model, events, state); err != nil {
+			return err
+		}
+	}
+
+	// Gemini has no terminal sentinel; the stream is complete once a finish reason arrived.
+	if !state.finished {
+		return core.MarkRetryable(errors.New("gemini stream ended without finishReason"))
+	}
+	return p.emitDone(ctx, events, state)
+}
+
+// handleSSEData maps one SSE data payload into canonical event payloads.
+func (p *Provider) handleSSEData(
+	ctx context.Context,
+	raw string,
+	model string,
+	events chan<- core.Event,
+	state *streamState,
+) error {
+	var chunk generateContentChunk
+	if err := json.Unmarshal([]byte(raw), &chunk); err != nil {
+		return fmt.Errorf("decode generate content chunk: %w", err)
+	}
+
+	for _, cand := range chunk.Candidates {
+		if cand.Index != 0 {
+			continue
+		}
+		for _, item := range cand.Content.Parts {
+			if err := p.handlePart(ctx, item, events, state); err != nil {
+				return err
+			}
+		}
+		if cand.FinishReason != "" {
+			reason, err := mapStopReason(cand.FinishReason)
+			if err != nil {
+				return err
+			}
+			if reason == core.StopReasonStop && state.emittedTool {
+				reason = core.StopReasonToolUse
+			}
+			state.reason = reason
+			state.finished = true
+		}
+	}
+
+	if chunk.UsageMetadata != nil {
+		applyUsage(&state.usage, *chunk.UsageMetadata)
+		state.usage.TotalTokens = state.usage.TokenCount()
+		state.usage.CostUSD = p.calculateCost(model, state.usage)
+	}
+	return nil
+}
+
+// handlePart emits text, thought, and function-call events for one response part.
+// Gemini streams function calls whole, so start, delta, and end are emitted together.
+func (p *Provider) handlePart(ctx context.Context, item part, events chan<- core.Event, state *streamState) error {
+	switch {
+	case item.FunctionCall != nil:
+		call := item.FunctionCall
+		if strings.TrimSpace(call.Name) == "" {
+			return errors.New("functionCall missing name")
+		}
+		args, err := marshalArgs(call.Args)
+		if err != nil {
+			return err
+		}
+		id := strings.TrimSpace(call.ID)
+		if id == "" {
+			id = "call_" + rand.Text()
+		}
+
+		state.emittedVisible = true
+		state.emittedTool = true
+		if err := core.SendEvent(ctx, events, core.Event{
+			Type:     core.EventToolCallStart,
+			ToolCall: &core.ToolCall{ID: id, Name: call.Name, Arguments: json.RawMessage("{}")},
+		}); err != nil {
+			return err
+		}
+		if err := core.SendEvent(ctx, events, core.Event{Type: core.EventToolCallDelta, ToolCallDelta: string(args)}); err != nil {
+			return err
+		}
+		return core.SendEvent(ctx, events, core.Event{
+			Type:     core.EventToolCallEnd,
+			ToolCall: &core.ToolCall{ID: id, Name: call.Name, Arguments: args},
+		})
+	case item.Text == "":
+		return nil
+	case item.Thought:
+		state.emittedVisible = true
+		return core.SendEvent(ctx, events, core.Event{Type: core.EventThinkingDelta, ThinkingDelta: item.Text})
+	default:
+		state.emittedVisible = true
+		return core.SendEvent(ctx, events, core.Event{Type: core.EventTextDelta, TextDelta: item.Text})
+	}
+}
+
+// emitDone sends the final usage snapshot and the terminal done event.
+func (p *Provider) emitDone(ctx context.Context, events chan<- core.Event, state *streamState) error {
+	if err := core.SendEvent(ctx, events, core.Event{Type: core.EventUsage, Usage: state.usage.Clone()}); err != nil {
+		return err
+	}
+	return core.SendEvent(ctx, events, core.Event{
+		Type: core.EventDone,
+		Done: &core.DonePayload{
+			Reason: state.reason,
+			Usage:  state.usage,
+		},
+	})
+}
+
+// calculateCost returns computed cost when pricing is configured for the requested model.
+func (p *Provider) calculateCost(model string, usage core.Usage) float64 {
+	pricing, ok := p.pricing[model]
+	if !ok {
+		return 0
+	}
+	return core.CalculateCost(usage, pricing)
+}
+
+// applyUsage maps Gemini usage metadata to canonical usage fields.
+// Cached tokens are a subset of promptTokenCount; thoughts are billed as output.
+func applyUsage(dst *core.Usage, usage usageMetadata) {
+	cached := usage.CachedContentTokenCount
+	dst.InputTokens = max(0, usage.PromptTokenCount-cached)
+	dst.OutputTokens = usage.CandidatesTokenCount + usage.ThoughtsTokenCount
+	dst.CacheReadTokens = cached
+	dst.CacheWriteTokens = 0
+}
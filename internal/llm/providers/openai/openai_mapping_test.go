@@ -0,0 +1,420 @@
+package openaiprovider
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"gar/internal/llm/core"
+)
+
+type serializedOpenAIRequest struct {
+	Model         string                    `json:"model"`
+	MaxTokens     int                       `json:"max_tokens"`
+	Messages      []serializedOpenAIMessage `json:"messages"`
+	Tools         []serializedOpenAITool    `json:"tools"`
+	Temperature   float64                   `json:"temperature"`
+	ToolChoice    json.RawMessage           `json:"tool_choice"`
+	User          string                    `json:"user"`
+	Stream        bool                      `json:"stream"`
+	StreamOptions map[string]any            `json:"stream_options"`
+}
+
+type serializedOpenAIMessage struct {
+	Role       string                     `json:"role"`
+	Content    *string                    `json:"content"`
+	ToolCallID string                     `json:"tool_call_id"`
+	ToolCalls  []serializedOpenAIToolCall `json:"tool_calls"`
+}
+
+type serializedOpenAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type serializedOpenAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Parameters  struct {
+			Type       string         `json:"type"`
+			Properties map[string]any `json:"properties"`
+			Required   []string       `json:"required"`
+		} `json:"parameters"`
+	} `json:"function"`
+}
+
+// TestToChatCompletionRequestTextOnly verifies text-only canonical requests map to one user message.
+func TestToChatCompletionRequestTextOnly(t *testing.T) {
+	req := &core.Request{
+		Model: "gpt-4o",
+		Messages: []core.Message{
+			{
+				Role: core.RoleUser,
+				Content: []core.ContentBlock{
+					{Type: core.ContentTypeText, Text: "hello"},
+				},
+			},
+		},
+		MaxTokens: 512,
+	}
+
+	params, err := toChatCompletionRequest(req)
+	if err != nil {
+		t.Fatalf("toChatCompletionRequest() error = %v", err)
+	}
+
+	body := decodeWireRequest(t, params)
+	if body.Model != req.Model {
+		t.Fatalf("model mismatch: got %q want %q", body.Model, req.Model)
+	}
+	if body.MaxTokens != 512 {
+		t.Fatalf("max_tokens mismatch: got %d want %d", body.MaxTokens, 512)
+	}
+	if !body.Stream || body.StreamOptions["include_usage"] != true {
+		t.Fatalf("expected streaming with usage, got stream=%v options=%v", body.Stream, body.StreamOptions)
+	}
+	if len(body.Messages) != 1 {
+		t.Fatalf("message count mismatch: got %d want 1", len(body.Messages))
+	}
+	got := body.Messages[0]
+	if got.Role != "user" || got.Content == nil || *got.Content != "hello" {
+		t.Fatalf("unexpected message: %+v", got)
+	}
+}
+
+func TestToChatCompletionRequestPrependsSystemMessage(t *testing.T) {
+	t.Parallel()
+
+	params, err := toChatCompletionRequest(&core.Request{
+		Model:  "gpt-4o",
+		System: "You are concise.",
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("toChatCompletionRequest() error = %v", err)
+	}
+
+	body := decodeWireRequest(t, params)
+	if len(body.Messages) != 2 {
+		t.Fatalf("message count mismatch: got %d want 2", len(body.Messages))
+	}
+	if body.Messages[0].Role != "system" || *body.Messages[0].Content != "You are concise." {
+		t.Fatalf("unexpected system message: %+v", body.Messages[0])
+	}
+	if body.MaxTokens != defaultMaxTokens {
+		t.Fatalf("max_tokens mismatch: got %d want %d", body.MaxTokens, defaultMaxTokens)
+	}
+}
+
+func TestToChatCompletionRequestMapsImagesToDataURLParts(t *testing.T) {
+	t.Parallel()
+
+	params, err := toChatCompletionRequest(&core.Request{
+		Model: "gpt-4o",
+		Messages: []core.Message{
+			{
+				Role: core.RoleUser,
+				Content: []core.ContentBlock{
+					{Type: core.ContentTypeText, Text: "what is this?"},
+					{Type: core.ContentTypeImage, MediaType: "image/png", Data: "iVBORw0KGgo="},
+					{Type: core.ContentTypeImage, Data: "missing-media-type"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("toChatCompletionRequest() error = %v", err)
+	}
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	var body struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content []struct {
+				Type     string `json:"type"`
+				Text     string `json:"text"`
+				ImageURL *struct {
+					URL string `json:"url"`
+				} `json:"image_url"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		t.Fatalf("unmarshal request: %v", err)
+	}
+	if len(body.Messages) != 1 || body.Messages[0].Role != "user" {
+		t.Fatalf("unexpected messages: %s", raw)
+	}
+	parts := body.Messages[0].Content
+	if len(parts) != 2 || parts[0].Type != "text" || parts[0].Text != "what is this?" {
+		t.Fatalf("unexpected content parts: %s", raw)
+	}
+	if parts[1].Type != "image_url" || parts[1].ImageURL == nil || parts[1].ImageURL.URL != "data:image/png;base64,iVBORw0KGgo=" {
+		t.Fatalf("unexpected image part: %s", raw)
+	}
+}
+
+// TestToChatCompletionRequestMapsToolResults ensures each tool result becomes its own tool message.
+func TestToChatCompletionRequestMapsToolResults(t *testing.T) {
+	t.Parallel()
+
+	params, err := toChatCompletionRequest(&core.Request{
+		Model: "gpt-4o",
+		Messages: []core.Message{
+			{
+				Role: core.RoleTool,
+				ToolResult: &core.ToolResult{
+					ToolCallID: "call_1",
+					ToolName:   "read",
+					Content:    "file one",
+				},
+			},
+			{
+				Role: core.RoleTool,
+				ToolResult: &core.ToolResult{
+					ToolCallID: "call_2",
+					ToolName:   "read",
+					Content:    "boom",
+					IsError:    true,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("toChatCompletionRequest() error = %v", err)
+	}
+
+	body := decodeWireRequest(t, params)
+	if len(body.Messages) != 2 {
+		t.Fatalf("message count mismatch: got %d want 2", len(body.Messages))
+	}
+	for i, want := range []string{"call_1", "call_2"} {
+		msg := body.Messages[i]
+		if msg.Role != "tool" || msg.ToolCallID != want {
+			t.Fatalf("message %d mismatch: %+v", i, msg)
+		}
+	}
+
+	if _, err := toChatCompletionRequest(&core.Request{
+		Model: "gpt-4o",
+		Messages: []core.Message{
+			{Role: core.RoleTool, ToolResult: &core.ToolResult{Content: "orphan"}},
+		},
+	}); !errors.Is(err, core.ErrInvalidRequest) {
+		t.Fatalf("expected ErrInvalidRequest for missing tool_call_id, got %v", err)
+	}
+}
+
+func TestToChatCompletionRequestMapsAssistantToolCalls(t *testing.T) {
+	t.Parallel()
+
+	params, err := toChatCompletionRequest(&core.Request{
+		Model: "gpt-4o",
+		Messages: []core.Message{
+			{
+				Role: core.RoleAssistant,
+				Content: []core.ContentBlock{
+					{Type: core.ContentTypeText, Text: "let me read that"},
+				},
+				ToolCalls: []core.ToolCall{
+					{ID: "call_1", Name: "read", Arguments: json.RawMessage(`{"path":"main.go"}`)},
+					{ID: "", Name: "Ignored", Arguments: json.RawMessage(`{"path":"skip"}`)},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("toChatCompletionRequest() error = %v", err)
+	}
+
+	body := decodeWireRequest(t, params)
+	if len(body.Messages) != 1 {
+		t.Fatalf("message count mismatch: got %d want 1", len(body.Messages))
+	}
+	msg := body.Messages[0]
+	if msg.Role != "assistant" || msg.Content == nil || *msg.Content != "let me read that" {
+		t.Fatalf("unexpected assistant message: %+v", msg)
+	}
+	if len(msg.ToolCalls) != 1 {
+		t.Fatalf("tool call count mismatch: got %d want 1", len(msg.ToolCalls))
+	}
+	call := msg.ToolCalls[0]
+	if call.ID != "call_1" || call.Type != "function" || call.Function.Name != "read" {
+		t.Fatalf("unexpected tool call identity: %+v", call)
+	}
+	var args map[string]any
+	if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+		t.Fatalf("decode tool call arguments: %v", err)
+	}
+	if args["path"] != "main.go" {
+		t.Fatalf("unexpected tool call arguments: %+v", args)
+	}
+}
+
+func TestToChatCompletionRequestMapsOptionalFields(t *testing.T) {
+	t.Parallel()
+
+	temp := 0.3
+	params, err := toChatCompletionRequest(&core.Request{
+		Model:       "gpt-4o",
+		MaxTokens:   256,
+		Temperature: &temp,
+		Metadata:    map[string]string{"user_id": "user-123"},
+		ToolChoice:  core.ToolChoice{Type: core.ToolChoiceAny},
+		Tools: []core.ToolSpec{
+			{
+				Name:        "read",
+				Description: "Read a file",
+				Schema:      json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"}}}`),
+			},
+		},
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("toChatCompletionRequest() error = %v", err)
+	}
+
+	body := decodeWireRequest(t, params)
+	if math.Abs(body.Temperature-temp) > 1e-12 {
+		t.Fatalf("temperature mismatch: got %v want %v", body.Temperature, temp)
+	}
+	if body.User != "user-123" {
+		t.Fatalf("user mismatch: got %q", body.User)
+	}
+	if string(body.ToolChoice) != `"required"` {
+		t.Fatalf("tool choice mismatch: %s", string(body.ToolChoice))
+	}
+	if len(body.Tools) != 1 {
+		t.Fatalf("tool count mismatch: got %d want 1", len(body.Tools))
+	}
+	tool := body.Tools[0]
+	if tool.Type != "function" || tool.Function.Name != "read" || tool.Function.Description != "Read a file" {
+		t.Fatalf("unexpected tool mapping: %+v", tool)
+	}
+	if tool.Function.Parameters.Type != "object" || tool.Function.Parameters.Properties["path"] == nil {
+		t.Fatalf("unexpected tool parameters: %+v", tool.Function.Parameters)
+	}
+	if tool.Function.Parameters.Required == nil {
+		t.Fatalf("expected required to serialize as an empty array")
+	}
+}
+
+func TestToWireToolChoiceMatrix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		choice   core.ToolChoice
+		wantOK   bool
+		wantJSON string
+	}{
+		{name: "auto", choice: core.ToolChoice{Type: core.ToolChoiceAuto}, wantOK: true, wantJSON: `"auto"`},
+		{name: "any", choice: core.ToolChoice{Type: core.ToolChoiceAny}, wantOK: true, wantJSON: `"required"`},
+		{name: "none", choice: core.ToolChoice{Type: core.ToolChoiceNone}, wantOK: true, wantJSON: `"none"`},
+		{
+			name:     "tool with name",
+			choice:   core.ToolChoice{Type: core.ToolChoiceTool, Name: "read"},
+			wantOK:   true,
+			wantJSON: `{"type":"function","function":{"name":"read"}}`,
+		},
+		{name: "tool without name", choice: core.ToolChoice{Type: core.ToolChoiceTool, Name: "  "}, wantOK: false},
+		{name: "unknown", choice: core.ToolChoice{Type: core.ToolChoiceType("custom")}, wantOK: false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := toWireToolChoice(tc.choice)
+			if ok != tc.wantOK {
+				t.Fatalf("ok mismatch: got %v want %v", ok, tc.wantOK)
+			}
+			if !ok {
+				return
+			}
+			raw, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("marshal tool choice: %v", err)
+			}
+			if string(raw) != tc.wantJSON {
+				t.Fatalf("tool choice json = %s, want %s", string(raw), tc.wantJSON)
+			}
+		})
+	}
+}
+
+// TestMapStopReason verifies OpenAI finish reasons map to canonical stop reasons.
+func TestMapStopReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   core.StopReason
+		hasErr bool
+	}{
+		{name: "stop", input: "stop", want: core.StopReasonStop},
+		{name: "length", input: "length", want: core.StopReasonLength},
+		{name: "tool_calls", input: "tool_calls", want: core.StopReasonToolUse},
+		{name: "content_filter", input: "content_filter", want: core.StopReasonError},
+		{name: "unknown", input: "unknown_reason", hasErr: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := mapStopReason(tc.input)
+			if tc.hasErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mapStopReason() error = %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("mapStopReason() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestToChatCompletionRequestRejectsInvalidRequest(t *testing.T) {
+	t.Parallel()
+
+	if _, err := toChatCompletionRequest(nil); !errors.Is(err, core.ErrInvalidRequest) {
+		t.Fatalf("expected ErrInvalidRequest for nil request, got %v", err)
+	}
+	if _, err := toChatCompletionRequest(&core.Request{Model: "   "}); !errors.Is(err, core.ErrInvalidRequest) {
+		t.Fatalf("expected ErrInvalidRequest for missing model, got %v", err)
+	}
+	if _, err := toWireMessages("", []core.Message{{Role: core.Role("moderator")}}); !errors.Is(err, core.ErrInvalidRequest) {
+		t.Fatalf("expected ErrInvalidRequest for unsupported role, got %v", err)
+	}
+}
+
+func decodeWireRequest(t *testing.T, params chatCompletionRequest) serializedOpenAIRequest {
+	t.Helper()
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	var body serializedOpenAIRequest
+	if err := json.Unmarshal(raw, &body); err != nil {
+		t.Fatalf("unmarshal request: %v", err)
+	}
+	return body
+}
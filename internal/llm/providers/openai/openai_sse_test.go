@@ -0,0 +1,217 @@
+package openaiprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gar/internal/llm/core"
+)
+
+// TestStreamEmitsTextDeltaUsageAndDone verifies basic text streaming emits delta, usage and done events.
+func TestStreamEmitsTextDeltaUsageAndDone(t *testing.T) {
+	t.Parallel()
+
+	server := newSSEServer(t, nil, []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"hi"},"finish_reason":null}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":2,"prompt_tokens_details":{"cached_tokens":4}}}`,
+		`[DONE]`,
+	})
+	defer server.Close()
+
+	events := collectStream(t, New(Config{APIKey: "test-key", BaseURL: server.URL}))
+
+	var sawDelta bool
+	var usage *core.Usage
+	var done *core.DonePayload
+	for _, ev := range events {
+		switch ev.Type {
+		case core.EventTextDelta:
+			sawDelta = sawDelta || ev.TextDelta == "hi"
+		case core.EventUsage:
+			usage = ev.Usage
+		case core.EventDone:
+			done = ev.Done
+		case core.EventError:
+			t.Fatalf("unexpected error event: %v", ev.Err)
+		}
+	}
+	if !sawDelta {
+		t.Fatalf("expected text delta event")
+	}
+	if usage == nil || usage.InputTokens != 6 || usage.CacheReadTokens != 4 || usage.OutputTokens != 2 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	if done == nil || done.Reason != core.StopReasonStop {
+		t.Fatalf("unexpected done payload: %+v", done)
+	}
+}
+
+// TestStreamReassemblesChunkedToolCall verifies chunked tool arguments are reassembled into valid JSON.
+func TestStreamReassemblesChunkedToolCall(t *testing.T) {
+	t.Parallel()
+
+	server := newSSEServer(t, nil, []string{
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"read","arguments":""}}]},"finish_reason":null}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"path\":\""}}]},"finish_reason":null}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"main.go\"}"}}]},"finish_reason":null}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`[DONE]`,
+	})
+	defer server.Close()
+
+	events := collectStream(t, New(Config{APIKey: "test-key", BaseURL: server.URL}))
+
+	var starts, deltas int
+	var end *core.ToolCall
+	var done *core.DonePayload
+	for _, ev := range events {
+		switch ev.Type {
+		case core.EventToolCallStart:
+			starts++
+		case core.EventToolCallDelta:
+			deltas++
+		case core.EventToolCallEnd:
+			end = ev.ToolCall
+		case core.EventDone:
+			done = ev.Done
+		case core.EventError:
+			t.Fatalf("unexpected error event: %v", ev.Err)
+		}
+	}
+	if starts != 1 || deltas != 2 {
+		t.Fatalf("starts=%d deltas=%d, want 1/2", starts, deltas)
+	}
+	if end == nil || end.ID != "call_1" || end.Name != "read" || string(end.Arguments) != `{"path":"main.go"}` {
+		t.Fatalf("unexpected tool call end: %+v", end)
+	}
+	if done == nil || done.Reason != core.StopReasonToolUse {
+		t.Fatalf("unexpected done payload: %+v", done)
+	}
+}
+
+// TestRetryOn429BeforeFirstDelta verifies pre-output 429 responses are retried.
+func TestRetryOn429BeforeFirstDelta(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := newSSEServer(t, func(w http.ResponseWriter) bool {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = fmt.Fprint(w, `{"error":{"message":"rate limited"}}`)
+			return true
+		}
+		return false
+	}, []string{
+		`{"choices":[{"index":0,"delta":{"content":"ok"},"finish_reason":"stop"}]}`,
+		`[DONE]`,
+	})
+	defer server.Close()
+
+	p := New(Config{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+		Retry: core.RetryPolicy{
+			MaxRetries: 2,
+			BaseDelay:  10 * time.Millisecond,
+			MaxDelay:   20 * time.Millisecond,
+		},
+	})
+	events := collectStream(t, p)
+
+	var startCount, errorCount int
+	var seenDone bool
+	for _, ev := range events {
+		switch ev.Type {
+		case core.EventStart:
+			startCount++
+		case core.EventDone:
+			seenDone = true
+		case core.EventError:
+			errorCount++
+		}
+	}
+	if !seenDone || errorCount != 0 || startCount != 1 {
+		t.Fatalf("done=%v errors=%d starts=%d, want true/0/1", seenDone, errorCount, startCount)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected 2 attempts, got %d", got)
+	}
+}
+
+// TestStreamNonRetryableStatusReturnsError verifies 4xx responses surface as terminal errors.
+func TestStreamNonRetryableStatusReturnsError(t *testing.T) {
+	t.Parallel()
+
+	server := newSSEServer(t, func(w http.ResponseWriter) bool {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = fmt.Fprint(w, `{"error":{"message":"bad key"}}`)
+		return true
+	}, nil)
+	defer server.Close()
+
+	events := collectStream(t, New(Config{APIKey: "test-key", BaseURL: server.URL}))
+	if len(events) != 1 || events[0].Type != core.EventError {
+		t.Fatalf("expected single error event, got %+v", events)
+	}
+	if events[0].Done == nil || events[0].Done.Reason != core.StopReasonError {
+		t.Fatalf("unexpected error payload: %+v", events[0].Done)
+	}
+}
+
+func newSSEServer(t *testing.T, before func(w http.ResponseWriter) bool, chunks []string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("unexpected authorization header %q", got)
+		}
+		if before != nil && before(w) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Errorf("response writer does not implement flusher")
+			return
+		}
+		for _, chunk := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+			flusher.Flush()
+		}
+	}))
+}
+
+func collectStream(t *testing.T, p *Provider) []core.Event {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := p.Stream(ctx, &core.Request{
+		Model:     "gpt-4o",
+		MaxTokens: 128,
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.ContentTypeText, Text: "hello"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	var events []core.Event
+	for ev := range stream {
+		events = append(events, ev)
+	}
+	return events
+}
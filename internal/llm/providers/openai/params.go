@@ -0,0 +1,313 @@
+package openaiprovider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gar/internal/llm/core"
+)
+
+// defaultMaxTokens is used when callers do not provide an explicit token budget.
+const defaultMaxTokens = 1024
+
+// chatCompletionRequest is the /v1/chat/completions streaming request body.
+type chatCompletionRequest struct {
+	Model         string         `json:"model"`
+	Messages      []chatMessage  `json:"messages"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Temperature   *float64       `json:"temperature,omitempty"`
+	Stop          []string       `json:"stop,omitempty"`
+	Tools         []chatTool     `json:"tools,omitempty"`
+	ToolChoice    any            `json:"tool_choice,omitempty"`
+	User          string         `json:"user,omitempty"`
+	Stream        bool           `json:"stream"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatMessage struct {
+	Role    string  `json:"role"`
+	Content *string `json:"content"`
+	// Parts is sent as the content instead of Content when a user message
+	// carries images.
+	Parts      []chatContentPart `json:"-"`
+	ToolCalls  []chatToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID string            `json:"tool_call_id,omitempty"`
+}
+
+// MarshalJSON sends Parts as the content array when set.
+func (m chatMessage) MarshalJSON() ([]byte, error) {
+	type plain chatMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		plain
+		Content []chatContentPart `json:"content"`
+	}{plain(m), m.Parts})
+}
+
+type chatContentPart struct {
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	ImageURL *chatImageURL `json:"image_url,omitempty"`
+}
+
+type chatImageURL struct {
+	URL string `json:"url"`
+}
+
+type chatToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function chatFunctionCall `json:"function"`
+}
+
+type chatFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type chatTool struct {
+	Type     string           `json:"type"`
+	Function chatToolFunction `json:"function"`
+}
+
+type chatToolFunction struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Parameters  core.ToolJSONSchema `json:"parameters"`
+}
+
+type chatToolChoiceFunction struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
+// mapStopReason maps OpenAI finish reasons to canonical provider-agnostic values.
+func mapStopReason(reason string) (core.StopReason, error) {
+	switch reason {
+	case "stop":
+		return core.StopReasonStop, nil
+	case "length":
+		return core.StopReasonLength, nil
+	case "tool_calls", "function_call":
+		return core.StopReasonToolUse, nil
+	case "content_filter":
+		return core.StopReasonError, nil
+	default:
+		return "", fmt.Errorf("unhandled finish reason: %s", reason)
+	}
+}
+
+// toChatCompletionRequest validates and converts a canonical request into the wire body.
+func toChatCompletionRequest(req *core.Request) (chatCompletionRequest, error) {
+	if req == nil {
+		return chatCompletionRequest{}, fmt.Errorf("%w: request is nil", core.ErrInvalidRequest)
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		return chatCompletionRequest{}, fmt.Errorf("%w: model is required", core.ErrInvalidRequest)
+	}
+
+	messages, err := toWireMessages(req.System, req.Messages)
+	if err != nil {
+		return chatCompletionRequest{}, err
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	body := chatCompletionRequest{
+		Model:         req.Model,
+		Messages:      messages,
+		MaxTokens:     maxTokens,
+		Stream:        true,
+		StreamOptions: &streamOptions{IncludeUsage: true},
+	}
+	if req.Temperature != nil {
+		temperature := *req.Temperature
+		body.Temperature = &temperature
+	}
+	if len(req.StopSequences) > 0 {
+		body.Stop = append([]string(nil), req.StopSequences...)
+	}
+	if len(req.Tools) > 0 {
+		tools, err := toWireTools(req.Tools)
+		if err != nil {
+			return chatCompletionRequest{}, err
+		}
+		body.Tools = tools
+	}
+	if toolChoice, ok := toWireToolChoice(req.ToolChoice); ok {
+		body.ToolChoice = toolChoice
+	}
+	if req.Metadata != nil {
+		body.User = strings.TrimSpace(req.Metadata["user_id"])
+	}
+
+	return body, nil
+}
+
+// toWireMessages converts canonical conversation messages into chat-completions messages.
+func toWireMessages(system string, messages []core.Message) ([]chatMessage, error) {
+	out := make([]chatMessage, 0, len(messages)+1)
+	if strings.TrimSpace(system) != "" {
+		out = append(out, chatMessage{Role: "system", Content: stringPtr(system)})
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case core.RoleUser:
+			if parts := toContentParts(msg.Content); parts != nil {
+				out = append(out, chatMessage{Role: "user", Parts: parts})
+				continue
+			}
+			text := joinText(msg.Content)
+			if text == "" {
+				continue
+			}
+			out = append(out, chatMessage{Role: "user", Content: stringPtr(text)})
+		case core.RoleAssistant:
+			wire := chatMessage{Role: "assistant"}
+			if text := joinText(msg.Content); text != "" {
+				wire.Content = stringPtr(text)
+			}
+			for _, call := range msg.ToolCalls {
+				if strings.TrimSpace(call.ID) == "" || strings.TrimSpace(call.Name) == "" {
+					continue
+				}
+				args, err := json.Marshal(core.DecodeJSONObjectOrEmpty(call.Arguments))
+				if err != nil {
+					return nil, fmt.Errorf("marshal tool call arguments for %q: %w", call.Name, err)
+				}
+				wire.ToolCalls = append(wire.ToolCalls, chatToolCall{
+					ID:   call.ID,
+					Type: "function",
+					Function: chatFunctionCall{
+						Name:      call.Name,
+						Arguments: string(args),
+					},
+				})
+			}
+			if wire.Content == nil && len(wire.ToolCalls) == 0 {
+				continue
+			}
+			out = append(out, wire)
+		case core.RoleTool:
+			if msg.ToolResult == nil {
+				continue
+			}
+			tr := msg.ToolResult
+			if strings.TrimSpace(tr.ToolCallID) == "" {
+				return nil, fmt.Errorf("%w: tool result missing tool_call_id", core.ErrInvalidRequest)
+			}
+			out = append(out, chatMessage{
+				Role:       "tool",
+				Content:    stringPtr(tr.Content),
+				ToolCallID: tr.ToolCallID,
+			})
+		default:
+			return nil, fmt.Errorf("%w: unsupported role %q", core.ErrInvalidRequest, msg.Role)
+		}
+	}
+
+	return out, nil
+}
+
+// joinText concatenates non-empty text blocks supported by this integration.
+func joinText(content []core.ContentBlock) string {
+	parts := make([]string, 0, len(content))
+	for _, item := range content {
+		if item.Type != core.ContentTypeText || item.Text == "" {
+			continue
+		}
+		parts = append(parts, item.Text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// toContentParts converts content holding base64 images into text and
+// image_url parts, keeping their order. It returns nil when there are no
+// images, so plain text can be sent as a string.
+func toContentParts(content []core.ContentBlock) []chatContentPart {
+	var parts []chatContentPart
+	hasImage := false
+	for _, item := range content {
+		switch item.Type {
+		case core.ContentTypeText:
+			if item.Text == "" {
+				continue
+			}
+			parts = append(parts, chatContentPart{Type: "text", Text: item.Text})
+		case core.ContentTypeImage:
+			if item.Data == "" || item.MediaType == "" {
+				continue
+			}
+			hasImage = true
+			parts = append(parts, chatContentPart{
+				Type:     "image_url",
+				ImageURL: &chatImageURL{URL: "data:" + item.MediaType + ";base64," + item.Data},
+			})
+		}
+	}
+	if !hasImage {
+		return nil
+	}
+	return parts
+}
+
+// toWireTools converts canonical tool specs into function tool definitions.
+func toWireTools(tools []core.ToolSpec) ([]chatTool, error) {
+	out := make([]chatTool, 0, len(tools))
+	for _, tool := range tools {
+		schema, err := core.DecodeToolJSONSchema(tool.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("decode tool schema for %q: %w", tool.Name, err)
+		}
+		if schema.Required == nil {
+			schema.Required = []string{}
+		}
+		out = append(out, chatTool{
+			Type: "function",
+			Function: chatToolFunction{
+				Name:        tool.Name,
+				Description: strings.TrimSpace(tool.Description),
+				Parameters:  schema,
+			},
+		})
+	}
+	return out, nil
+}
+
+// toWireToolChoice maps canonical tool choice behavior to the chat-completions union.
+func toWireToolChoice(choice core.ToolChoice) (any, bool) {
+	switch choice.Type {
+	case core.ToolChoiceAuto:
+		return "auto", true
+	case core.ToolChoiceAny:
+		return "required", true
+	case core.ToolChoiceNone:
+		return "none", true
+	case core.ToolChoiceTool:
+		if strings.TrimSpace(choice.Name) == "" {
+			return nil, false
+		}
+		named := chatToolChoiceFunction{Type: "function"}
+		named.Function.Name = choice.Name
+		return named, true
+	default:
+		return nil, false
+	}
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
@@ -0,0 +1,429 @@
+package openaiprovider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gar/internal/llm/core"
+)
+
+const (
+	defaultBaseURL  = "https://api.openai.com/v1"
+	maxSSELineSize  = 1024 * 1024
+	sseDataPrefix   = "data:"
+	sseDoneSentinel = "[DONE]"
+)
+
+// Config configures the OpenAI chat-completions provider.
+type Config struct {
+	APIKey       string
+	BaseURL      string
+	HTTPClient   *http.Client
+	Retry        core.RetryPolicy
+	ModelPricing map[string]core.ModelPricing
+}
+
+// Provider is a thin HTTP client for OpenAI-compatible /chat/completions streaming.
+type Provider struct {
+	apiKey     string
+	baseURL    string
+	retry      core.RetryPolicy
+	pricing    map[string]core.ModelPricing
+	httpClient *http.Client
+}
+
+// New constructs a provider with sane defaults.
+func New(cfg Config) *Provider {
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	pricing := cfg.ModelPricing
+	if pricing == nil {
+		pricing = map[string]core.ModelPricing{}
+	}
+
+	return &Provider{
+		apiKey:     strings.TrimSpace(cfg.APIKey),
+		baseURL:    baseURL,
+		retry:      core.NormalizeRetryPolicy(cfg.Retry),
+		pricing:    pricing,
+		httpClient: httpClient,
+	}
+}
+
+// Stream executes a single chat-completions streaming request.
+func (p *Provider) Stream(ctx context.Context, req *core.Request) (<-chan core.Event, error) {
+	if p == nil {
+		return nil, fmt.Errorf("openai provider is nil")
+	}
+	if strings.TrimSpace(p.apiKey) == "" {
+		return nil, core.ErrMissingAPIKey
+	}
+
+	body, err := toChatCompletionRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal chat completion request: %w", err)
+	}
+
+	events := make(chan core.Event, 1)
+	retry := core.MergeRetryPolicy(p.retry, req.Retry)
+
+	go func() {
+		defer close(events)
+		state := &streamState{reason: core.StopReasonStop}
+		err := core.RetryStream(ctx, retry, func() error {
+			return p.streamOnce(ctx, payload, req.Model, events, state)
+		}, func() bool { return state.emittedVisible })
+		if err != nil {
+			reason := core.StopReasonError
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				reason = core.StopReasonAborted
+			}
+			core.SendTerminalEvent(events, core.Event{
+				Type: core.EventError,
+				Done: &core.DonePayload{
+					Reason: reason,
+					Usage:  state.usage,
+				},
+				Err: fmt.Errorf("openai stream: %w", err),
+			})
+		}
+	}()
+
+	return events, nil
+}
+
+// streamState tracks incremental response state across one logical stream request.
+type streamState struct {
+	usage            core.Usage
+	reason           core.StopReason
+	finished         bool
+	emittedVisible   bool
+	startEmitted     bool
+	emittedDone      bool
+	toolAccumulators map[int]*toolCallAccumulator
+}
+
+// toolCallAccumulator incrementally reconstructs chunked JSON tool arguments.
+type toolCallAccumulator struct {
+	id   string
+	name string
+	buf  strings.Builder
+}
+
+// chatCompletionChunk is one SSE data payload from the streaming endpoint.
+type chatCompletionChunk struct {
+	Choices []chunkChoice `json:"choices"`
+	Usage   *chunkUsage   `json:"usage"`
+}
+
+type chunkChoice struct {
+	Index        int        `json:"index"`
+	Delta        chunkDelta `json:"delta"`
+	FinishReason *string    `json:"finish_reason"`
+}
+
+type chunkDelta struct {
+	Content   string          `json:"content"`
+	ToolCalls []chunkToolCall `json:"tool_calls"`
+}
+
+type chunkToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chunkUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// streamOnce performs one HTTP request and emits canonical events from its SSE body.
+func (p *Provider) streamOnce(
+	ctx context.Context,
+	payload []byte,
+	model string,
+	events chan<- core.Event,
+	state *streamState,
+) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build chat completion request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		wrapped := fmt.Errorf("openai request: %w", err)
+		if core.IsRetryableHTTPError(err) {
+			return core.MarkRetryable(wrapped)
+		}
+		return wrapped
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if err := core.CheckHTTPResponse("openai", resp); err != nil {
+		return err
+	}
+
+	if !state.startEmitted {
+		if err := core.SendEvent(ctx, events, core.Event{Type: core.EventStart}); err != nil {
+			return err
+		}
+		state.startEmitted = true
+	}
+
+	if state.toolAccumulators == nil {
+		state.toolAccumulators = map[int]*toolCallAccumulator{}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELineSize)
+
+	var data strings.Builder
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line := scanner.Text()
+		if line != "" {
+			if strings.HasPrefix(line, sseDataPrefix) {
+				if data.Len() > 0 {
+					data.WriteByte('\n')
+				}
+				data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, sseDataPrefix)))
+			}
+			continue
+		}
+
+		if data.Len() == 0 {
+			continue
+		}
+		raw := data.String()
+		data.Reset()
+		if err := p.handleSSEData(ctx, raw, model, events, state); err != nil {
+			return err
+		}
+		if state.emittedDone {
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		wrapped := fmt.Errorf("openai read stream: %w", err)
+		if core.IsRetryableHTTPError(err) {
+			return core.MarkRetryable(wrapped)
+		}
+		return wrapped
+	}
+
+	if data.Len() > 0 {
+		if err := p.handleSSEData(ctx, data.String(), model, events, state); err != nil {
+			return err
+		}
+		if state.emittedDone {
+			return nil
+		}
+	}
+
+	// Some OpenAI-compatible servers close the stream without a [DONE] sentinel.
+	if state.finished {
+		return p.emitDone(ctx, events, state)
+	}
+
+	return core.MarkRetryable(errors.New("openai stream ended without finish_reason"))
+}
+
+// handleSSEData maps one SSE data payload into canonical event payloads.
+func (p *Provider) handleSSEData(
+	ctx context.Context,
+	raw string,
+	model string,
+	events chan<- core.Event,
+	state *streamState,
+) error {
+	if raw == sseDoneSentinel {
+		if !state.finished {
+			return core.MarkRetryable(errors.New("openai stream ended without finish_reason"))
+		}
+		return p.emitDone(ctx, events, state)
+	}
+
+	var chunk chatCompletionChunk
+	if err := json.Unmarshal([]byte(raw), &chunk); err != nil {
+		return fmt.Errorf("decode chat completion chunk: %w", err)
+	}
+
+	for _, choice := range chunk.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		if choice.Delta.Content != "" {
+			state.emittedVisible = true
+			if err := core.SendEvent(ctx, events, core.Event{Type: core.EventTextDelta, TextDelta: choice.Delta.Content}); err != nil {
+				return err
+			}
+		}
+		for _, delta := range choice.Delta.ToolCalls {
+			if err := p.handleToolCallDelta(ctx, delta, events, state); err != nil {
+				return err
+			}
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			reason, err := mapStopReason(*choice.FinishReason)
+			if err != nil {
+				return err
+			}
+			state.reason = reason
+			state.finished = true
+			if err := p.flushToolCalls(ctx, events, state); err != nil {
+				return err
+			}
+		}
+	}
+
+	if chunk.Usage != nil {
+		applyUsage(&state.usage, *chunk.Usage)
+		state.usage.TotalTokens = state.usage.TokenCount()
+		state.usage.CostUSD = p.calculateCost(model, state.usage)
+		return core.SendEvent(ctx, events, core.Event{Type: core.EventUsage, Usage: state.usage.Clone()})
+	}
+	return nil
+}
+
+// handleToolCallDelta starts or extends the accumulator for one streamed tool call.
+func (p *Provider) handleToolCallDelta(
+	ctx context.Context,
+	delta chunkToolCall,
+	events chan<- core.Event,
+	state *streamState,
+) error {
+	acc, ok := state.toolAccumulators[delta.Index]
+	if !ok {
+		if strings.TrimSpace(delta.ID) == "" || strings.TrimSpace(delta.Function.Name) == "" {
+			return fmt.Errorf("tool_call start missing id or name for index %d", delta.Index)
+		}
+		acc = &toolCallAccumulator{id: delta.ID, name: delta.Function.Name}
+		state.toolAccumulators[delta.Index] = acc
+		state.emittedVisible = true
+		if err := core.SendEvent(ctx, events, core.Event{
+			Type: core.EventToolCallStart,
+			ToolCall: &core.ToolCall{
+				ID:        acc.id,
+				Name:      acc.name,
+				Arguments: json.RawMessage("{}"),
+			},
+		}); err != nil {
+			return err
+		}
+	}
+
+	if delta.Function.Arguments == "" {
+		return nil
+	}
+	_, _ = acc.buf.WriteString(delta.Function.Arguments)
+	state.emittedVisible = true
+	return core.SendEvent(ctx, events, core.Event{Type: core.EventToolCallDelta, ToolCallDelta: delta.Function.Arguments})
+}
+
+// flushToolCalls emits EventToolCallEnd for all accumulated tool calls in index order.
+func (p *Provider) flushToolCalls(ctx context.Context, events chan<- core.Event, state *streamState) error {
+	indexes := make([]int, 0, len(state.toolAccumulators))
+	for index := range state.toolAccumulators {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		acc := state.toolAccumulators[index]
+		delete(state.toolAccumulators, index)
+
+		rawArgs := bytes.TrimSpace([]byte(acc.buf.String()))
+		if len(rawArgs) == 0 {
+			rawArgs = []byte("{}")
+		}
+		if !json.Valid(rawArgs) {
+			return fmt.Errorf("tool_call arguments are not valid JSON")
+		}
+
+		if err := core.SendEvent(ctx, events, core.Event{
+			Type: core.EventToolCallEnd,
+			ToolCall: &core.ToolCall{
+				ID:        acc.id,
+				Name:      acc.name,
+				Arguments: append(json.RawMessage(nil), rawArgs...),
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emitDone sends the terminal done event once per logical stream.
+func (p *Provider) emitDone(ctx context.Context, events chan<- core.Event, state *streamState) error {
+	state.emittedDone = true
+	return core.SendEvent(ctx, events, core.Event{
+		Type: core.EventDone,
+		Done: &core.DonePayload{
+			Reason: state.reason,
+			Usage:  state.usage,
+		},
+	})
+}
+
+// calculateCost returns computed cost when pricing is configured for the requested model.
+func (p *Provider) calculateCost(model string, usage core.Usage) float64 {
+	pricing, ok := p.pricing[model]
+	if !ok {
+		return 0
+	}
+	return core.CalculateCost(usage, pricing)
+}
+
+// applyUsage maps chat-completions usage counters to canonical usage fields.
+// OpenAI reports cached prompt tokens as a subset of prompt_tokens.
+func applyUsage(dst *core.Usage, usage chunkUsage) {
+	cached := usage.PromptTokensDetails.CachedTokens
+	dst.InputTokens = max(0, usage.PromptTokens-cached)
+	dst.OutputTokens = usage.CompletionTokens
+	dst.CacheReadTokens = cached
+	dst.CacheWriteTokens = 0
+}
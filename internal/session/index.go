@@ -0,0 +1,166 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const indexFileExt = ".idx"
+
+// Index summarizes one session so listings need not parse the whole file.
+// It is kept in a <id>.idx sidecar next to the session, updated by Append,
+// and rebuilt from the entries whenever it is missing or stale.
+type Index struct {
+	Entries   int       `json:"entries"`
+	LeafID    string    `json:"leaf_id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Sizes records the archive, compressed, and live file sizes the index
+	// describes, -1 for a missing part. Any mismatch marks the index stale.
+	Sizes [3]int64 `json:"sizes"`
+}
+
+// add folds one appended entry into the index.
+func (idx *Index) add(entry Entry) {
+	idx.Entries++
+	idx.LeafID = entry.ID
+	if entry.Type == "session_info" {
+		idx.Name = strings.TrimSpace(entry.Name)
+	}
+	if entry.TS > 0 {
+		idx.UpdatedAt = time.Unix(entry.TS, 0).UTC()
+	}
+}
+
+// Index returns the summary of one session, rebuilding and saving it when
+// the sidecar is missing or no longer matches the session files.
+func (s *Store) Index(ctx context.Context, sessionID string) (Index, error) {
+	if err := ctx.Err(); err != nil {
+		return Index{}, err
+	}
+	path, err := s.sessionPath(sessionID)
+	if err != nil {
+		return Index{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if idx, ok := readIndex(path); ok {
+		return idx, nil
+	}
+	return s.rebuildIndexLocked(ctx, sessionID, path)
+}
+
+// rebuildIndexLocked summarizes every entry of the session and saves the
+// result. Saving is best effort: an unsaved index is rebuilt next time.
+func (s *Store) rebuildIndexLocked(ctx context.Context, sessionID, path string) (Index, error) {
+	entries, _, err := s.load(ctx, sessionID, true)
+	if err != nil {
+		return Index{}, err
+	}
+	var idx Index
+	for _, entry := range entries {
+		idx.add(entry)
+	}
+	if idx.Sizes, err = sessionFileSizes(path); err != nil {
+		return Index{}, err
+	}
+	_ = s.writeIndexLocked(path, idx)
+	return idx, nil
+}
+
+// updateIndexLocked records entry, just appended to the session at path, in
+// its index. prev is the index read before the append, valid when fresh.
+// Failures leave the index stale for the next reader to rebuild.
+func (s *Store) updateIndexLocked(ctx context.Context, sessionID, path string, prev Index, fresh bool, entry Entry) {
+	if !fresh {
+		_, _ = s.rebuildIndexLocked(ctx, sessionID, path)
+		return
+	}
+	prev.add(entry)
+	s.refreshIndexSizesLocked(path, prev, true)
+}
+
+// refreshIndexSizesLocked saves a fresh index against the current session
+// file sizes, keeping it valid across a rewrite that leaves its entries as
+// they are.
+func (s *Store) refreshIndexSizesLocked(path string, prev Index, fresh bool) {
+	if !fresh {
+		return
+	}
+	sizes, err := sessionFileSizes(path)
+	if err != nil {
+		return
+	}
+	prev.Sizes = sizes
+	_ = s.writeIndexLocked(path, prev)
+}
+
+func (s *Store) writeIndexLocked(path string, idx Index) error {
+	raw, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	target := indexPath(path)
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(raw); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// readIndex returns the saved index of the session at path when it exists
+// and still matches the session files.
+func readIndex(path string) (Index, bool) {
+	raw, err := os.ReadFile(indexPath(path))
+	if err != nil {
+		return Index{}, false
+	}
+	var idx Index
+	if err := json.Unmarshal(raw, &idx); err != nil {
+		return Index{}, false
+	}
+	sizes, err := sessionFileSizes(path)
+	if err != nil || sizes != idx.Sizes {
+		return Index{}, false
+	}
+	return idx, true
+}
+
+// sessionFileSizes returns the sizes of the archive, compressed, and live
+// files of the session at path, -1 for each one missing.
+func sessionFileSizes(path string) ([3]int64, error) {
+	var sizes [3]int64
+	for i, candidate := range []string{archivePath(path), path + gzipFileExt, path} {
+		info, err := os.Stat(candidate)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			sizes[i] = -1
+		case err != nil:
+			return sizes, fmt.Errorf("stat session file %s: %w", candidate, err)
+		default:
+			sizes[i] = info.Size()
+		}
+	}
+	return sizes, nil
+}
+
+// indexPath returns the index sidecar for the live session file at path.
+func indexPath(path string) string {
+	return strings.TrimSuffix(path, sessionFileExt) + indexFileExt
+}
@@ -0,0 +1,106 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreAppendMaintainsIndex(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), ".gar", "sessions")
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	ctx := context.Background()
+	for _, entry := range []Entry{
+		{ID: "1", Type: "user", Content: "hello", TS: 1700000001},
+		{ID: "2", Type: "session_info", Name: " alpha ", TS: 1700000002},
+		{ID: "3", Type: "assistant", Content: "hi", TS: 1700000003},
+	} {
+		if err := store.Append(ctx, "s1", entry); err != nil {
+			t.Fatalf("Append(%s) error = %v", entry.ID, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "s1.idx")); err != nil {
+		t.Fatalf("Stat(s1.idx) error = %v, want index written on append", err)
+	}
+	idx, err := store.Index(ctx, "s1")
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if idx.Entries != 3 || idx.LeafID != "3" || idx.Name != "alpha" || !idx.UpdatedAt.Equal(time.Unix(1700000003, 0)) {
+		t.Fatalf("Index() = %+v, want 3 entries, leaf 3, name alpha", idx)
+	}
+
+	// Compaction rewrites the files but keeps the index valid.
+	if err := store.Compact(ctx, "s1"); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if _, ok := readIndex(filepath.Join(dir, "s1.jsonl")); !ok {
+		t.Fatal("readIndex() after Compact() = stale, want fresh")
+	}
+
+	infos, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(infos) != 1 || infos[0].Name != "alpha" || infos[0].Entries != 3 || infos[0].LeafID != "3" {
+		t.Fatalf("List() = %+v, want index details", infos)
+	}
+}
+
+func TestStoreIndexRebuildsWhenMissingOrStale(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), ".gar", "sessions")
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := store.Append(ctx, "s1", Entry{ID: "1", Type: "session_info", Name: "old", TS: 1700000001}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	indexFile := filepath.Join(dir, "s1.idx")
+	if err := os.Remove(indexFile); err != nil {
+		t.Fatalf("Remove(s1.idx) error = %v", err)
+	}
+	infos, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(infos) != 1 || infos[0].Name != "old" || infos[0].Entries != 1 {
+		t.Fatalf("List() without index = %+v, want details rebuilt from entries", infos)
+	}
+	if _, err := os.Stat(indexFile); err != nil {
+		t.Fatalf("Stat(s1.idx) error = %v, want rebuilt index saved", err)
+	}
+
+	// An entry written behind the store's back makes the saved index stale.
+	line := `{"id":"2","type":"session_info","name":"new","ts":1700000002}` + "\n"
+	file, err := os.OpenFile(filepath.Join(dir, "s1.jsonl"), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if _, err := file.WriteString(line); err != nil {
+		t.Fatalf("WriteString() error = %v", err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	idx, err := store.Index(ctx, "s1")
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if idx.Entries != 2 || idx.LeafID != "2" || idx.Name != "new" {
+		t.Fatalf("Index() after external append = %+v, want rebuilt", idx)
+	}
+}
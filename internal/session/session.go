@@ -2,6 +2,7 @@ package session
 
 import (
 	"bufio"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
@@ -9,16 +10,22 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 const (
 	defaultSessionDirName = ".gar/sessions"
 	sessionFileExt        = ".jsonl"
+	gzipFileExt           = ".gz"
+	archiveSuffix         = ".archive"
 	maxJSONLLineSize      = 1024 * 1024
+	searchRegexPrefix     = "re:"
+	searchSnippetRadius   = 40
 )
 
 var (
@@ -28,6 +35,7 @@ var (
 	ErrEntryIDRequired    = errors.New("entry id is required")
 	ErrEntryTypeRequired  = errors.New("entry type is required")
 	ErrSessionNotFound    = errors.New("session not found")
+	ErrSearchQueryEmpty   = errors.New("search query is required")
 )
 
 // Entry is one append-only record in a session JSONL file.
@@ -50,12 +58,38 @@ type SessionInfo struct {
 	Path      string
 	UpdatedAt time.Time
 	SizeBytes int64
+	// Name, Entries, and LeafID come from the session's Index: its latest
+	// display name, its entry count, and the ID of its last entry.
+	Name    string
+	Entries int
+	LeafID  string
+}
+
+// SearchHit is one entry matching a Store.Search query.
+type SearchHit struct {
+	SessionID string
+	EntryID   string
+	Type      string
+	Snippet   string
 }
 
 // Store persists session entries as append-only JSONL files.
+//
+// Live sessions are always appended as plaintext; Compact folds them into a
+// gzipped <id>.jsonl.gz that Load and List read transparently. With a
+// maximum entry count set, Append moves the oldest entries into
+// <id>.archive.jsonl, which Load reads ahead of the live entries. Each
+// session also has a <id>.idx sidecar summarizing it for List; see Index.
 type Store struct {
-	dir string
-	mu  sync.Mutex
+	dir        string
+	compress   bool
+	maxEntries int
+	// fsync flushes each write to stable storage before Append returns.
+	fsync bool
+	// liveCounts caches the number of live (non-archived) entries per
+	// session path once rotation has counted them.
+	liveCounts map[string]int
+	mu         sync.Mutex
 }
 
 // NewStore constructs a session store rooted at dir.
@@ -67,6 +101,37 @@ func NewStore(dir string) (*Store, error) {
 	return &Store{dir: root}, nil
 }
 
+// SetCompression controls whether finished sessions should be gzip-compacted.
+func (s *Store) SetCompression(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.compress = enabled
+}
+
+// SetMaxEntries caps the live entries kept per session file. Once an append
+// exceeds limit, the oldest entries are moved to the session's archive so
+// half of limit stay live. Zero or less disables rotation.
+func (s *Store) SetMaxEntries(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxEntries = limit
+}
+
+// SetFsync makes Append sync session files to disk after every write, so an
+// entry survives a crash or power loss once Append returns.
+func (s *Store) SetFsync(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fsync = enabled
+}
+
+// Compression reports whether finished sessions should be gzip-compacted.
+func (s *Store) Compression() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compress
+}
+
 // DefaultDir returns the canonical sessions directory under a project root.
 func DefaultDir(projectRoot string) string {
 	return filepath.Join(projectRoot, defaultSessionDirName)
@@ -106,50 +171,343 @@ func (s *Store) Append(ctx context.Context, sessionID string, entry Entry) error
 	if err := os.MkdirAll(s.dir, 0o755); err != nil {
 		return fmt.Errorf("create session dir %s: %w", s.dir, err)
 	}
+	prevIndex, freshIndex := readIndex(path)
 
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
 	if err != nil {
 		return fmt.Errorf("open session file %s: %w", path, err)
 	}
 	defer func() { _ = file.Close() }()
 
-	if _, err := file.Write(raw); err != nil {
+	// The entry and its newline go out in one write so concurrent appenders
+	// never interleave within a line. A crash mid-write can still leave a
+	// torn last line; starting on a fresh line keeps it from swallowing this
+	// entry, and LoadLenient skips it. Another process's write still in
+	// flight can look torn too, which costs only a blank line readers skip.
+	line := append(raw, '\n')
+	torn, err := endsMidLine(file)
+	if err != nil {
+		return fmt.Errorf("check session file %s: %w", path, err)
+	}
+	if torn {
+		line = append([]byte{'\n'}, line...)
+	}
+	if _, err := file.Write(line); err != nil {
 		return fmt.Errorf("append session entry: %w", err)
 	}
-	if _, err := file.Write([]byte("\n")); err != nil {
-		return fmt.Errorf("append session newline: %w", err)
+	if s.fsync {
+		if err := file.Sync(); err != nil {
+			return fmt.Errorf("sync session file %s: %w", path, err)
+		}
+	}
+	if err := s.rotateIfFullLocked(path); err != nil {
+		return err
+	}
+	s.updateIndexLocked(ctx, sessionID, path, prevIndex, freshIndex, entry)
+	return nil
+}
+
+// endsMidLine reports whether file is non-empty and lacks a final newline.
+func endsMidLine(file *os.File) (bool, error) {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return false, err
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] != '\n', nil
+}
+
+// rotateIfFullLocked archives the oldest live entries of the session at path
+// when it holds more than maxEntries.
+func (s *Store) rotateIfFullLocked(path string) error {
+	if s.maxEntries <= 0 {
+		return nil
+	}
+	count, ok := s.liveCounts[path]
+	if ok {
+		count++
+	} else {
+		lines, err := readLiveLines(path)
+		if err != nil {
+			return err
+		}
+		count = len(lines)
+	}
+	if s.liveCounts == nil {
+		s.liveCounts = make(map[string]int)
+	}
+	s.liveCounts[path] = count
+	if count <= s.maxEntries {
+		return nil
+	}
+
+	lines, err := readLiveLines(path)
+	if err != nil {
+		return err
+	}
+	keep := min(max(s.maxEntries/2, 1), len(lines))
+	archived, live := lines[:len(lines)-keep], lines[len(lines)-keep:]
+
+	// Archive before trimming: a crash in between duplicates entries, which
+	// loading tolerates, rather than losing them.
+	if err := appendLines(archivePath(path), archived, s.fsync); err != nil {
+		return fmt.Errorf("archive session entries: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create rotated session file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.WriteString(strings.Join(live, "\n") + "\n"); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write rotated session file: %w", err)
+	}
+	if s.fsync {
+		if err := tmp.Sync(); err != nil {
+			_ = tmp.Close()
+			return fmt.Errorf("sync rotated session file: %w", err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close rotated session file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace rotated session file: %w", err)
 	}
+	if err := os.Remove(path + gzipFileExt); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove compacted session file: %w", err)
+	}
+	s.liveCounts[path] = len(live)
 	return nil
 }
 
-// Load reads all entries from one session file.
+// readLiveLines returns the non-empty JSONL lines of the live session at
+// path, compressed part first.
+func readLiveLines(path string) ([]string, error) {
+	var buf strings.Builder
+	for _, candidate := range []string{path + gzipFileExt, path} {
+		if err := copyDecompressed(&buf, candidate); err != nil {
+			return nil, err
+		}
+	}
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// appendLines appends lines to the file at path in one write, syncing it
+// afterwards when sync is set.
+func appendLines(path string, lines []string, sync bool) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if sync {
+		if err := file.Sync(); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
+
+// archivePath returns the archive file for the live session file at path.
+func archivePath(path string) string {
+	return strings.TrimSuffix(path, sessionFileExt) + archiveSuffix + sessionFileExt
+}
+
+// LineError describes one session line that could not be decoded.
+type LineError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+}
+
+func (e LineError) Unwrap() error { return e.Err }
+
+// Load reads all entries from one session, including its archived and
+// compressed parts.
+// Any line that fails to decode fails the whole load.
 func (s *Store) Load(ctx context.Context, sessionID string) ([]Entry, error) {
+	entries, _, err := s.load(ctx, sessionID, false)
+	return entries, err
+}
+
+// LoadLenient reads a session like Load but skips lines that fail to
+// decode, such as a half-written line left by a crash, and reports them.
+func (s *Store) LoadLenient(ctx context.Context, sessionID string) ([]Entry, []LineError, error) {
+	return s.load(ctx, sessionID, true)
+}
+
+func (s *Store) load(ctx context.Context, sessionID string, lenient bool) ([]Entry, []LineError, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	path, err := s.sessionPath(sessionID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	entries := make([]Entry, 0, 64)
+	var skipped []LineError
+	found := false
+	for _, candidate := range []string{archivePath(path), path + gzipFileExt, path} {
+		loaded, bad, ok, err := loadEntries(ctx, candidate, lenient)
+		if err != nil {
+			return nil, nil, err
+		}
+		found = found || ok
+		entries = append(entries, loaded...)
+		skipped = append(skipped, bad...)
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("%w: %s", ErrSessionNotFound, strings.TrimSpace(sessionID))
+	}
+	return entries, skipped, nil
+}
+
+// Compact rewrites one session as gzipped JSONL, folding any plaintext
+// entries appended since the last compaction into the compressed file.
+func (s *Store) Compact(ctx context.Context, sessionID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	path, err := s.sessionPath(sessionID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(path); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("stat session file %s: %w", path, err)
+		}
+		if _, gzErr := os.Stat(path + gzipFileExt); gzErr == nil {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, strings.TrimSpace(sessionID))
+	}
+	prevIndex, freshIndex := readIndex(path)
+
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create compacted session file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	zw := gzip.NewWriter(tmp)
+	for _, candidate := range []string{path + gzipFileExt, path} {
+		if err := copyDecompressed(zw, candidate); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("compress session file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close compacted session file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path+gzipFileExt); err != nil {
+		return fmt.Errorf("replace compacted session file: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove plaintext session file: %w", err)
+	}
+	s.refreshIndexSizesLocked(path, prevIndex, freshIndex)
+	return nil
+}
+
+// PendingSize returns the size in bytes of the plaintext entries appended to
+// one session since its last compaction, or 0 when there are none.
+func (s *Store) PendingSize(sessionID string) (int64, error) {
+	path, err := s.sessionPath(sessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("stat session file %s: %w", path, err)
 	}
+	return info.Size(), nil
+}
 
+// openSessionFile opens path, decompressing it when it carries the gzip extension.
+func openSessionFile(path string) (io.ReadCloser, error) {
 	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, gzipFileExt) {
+		return file, nil
+	}
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("open gzip session file %s: %w", path, err)
+	}
+	return gzipFile{Reader: zr, file: file}, nil
+}
+
+// gzipFile closes both the gzip stream and its underlying file.
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g gzipFile) Close() error {
+	return errors.Join(g.Reader.Close(), g.file.Close())
+}
+
+// loadEntries decodes one session file, reporting false when it does not exist.
+// The line-size guard applies to the decompressed stream. When lenient is
+// set, lines that fail to decode are skipped and returned instead of failing.
+func loadEntries(ctx context.Context, path string, lenient bool) ([]Entry, []LineError, bool, error) {
+	file, err := openSessionFile(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, strings.TrimSpace(sessionID))
+			return nil, nil, false, nil
 		}
-		return nil, fmt.Errorf("open session file %s: %w", path, err)
+		return nil, nil, false, fmt.Errorf("open session file %s: %w", path, err)
 	}
 	defer func() { _ = file.Close() }()
 
 	scanner := bufio.NewScanner(file)
 	scanner.Buffer(make([]byte, 64*1024), maxJSONLLineSize)
 
-	entries := make([]Entry, 0, 64)
+	var entries []Entry
+	var skipped []LineError
 	lineNum := 0
 	for scanner.Scan() {
 		if err := ctx.Err(); err != nil {
-			return nil, err
+			return nil, nil, true, err
 		}
 
 		lineNum++
@@ -160,24 +518,46 @@ func (s *Store) Load(ctx context.Context, sessionID string) ([]Entry, error) {
 
 		var entry Entry
 		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			return nil, fmt.Errorf("decode session line %d: %w", lineNum, err)
+			if lenient {
+				skipped = append(skipped, LineError{Path: path, Line: lineNum, Err: err})
+				continue
+			}
+			return nil, nil, true, fmt.Errorf("decode session line %d: %w", lineNum, err)
 		}
 		entries = append(entries, entry)
 	}
 	if err := scanner.Err(); err != nil {
 		if errors.Is(err, bufio.ErrTooLong) {
-			return nil, fmt.Errorf("decode session line too large (> %d bytes): %w", maxJSONLLineSize, err)
+			return nil, nil, true, fmt.Errorf("decode session line too large (> %d bytes): %w", maxJSONLLineSize, err)
 		}
 		if errors.Is(err, io.EOF) {
-			return entries, nil
+			return entries, skipped, true, nil
+		}
+		return nil, nil, true, fmt.Errorf("scan session file: %w", err)
+	}
+
+	return entries, skipped, true, nil
+}
+
+// copyDecompressed copies the decompressed contents of path to w, skipping missing files.
+func copyDecompressed(w io.Writer, path string) error {
+	file, err := openSessionFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
 		}
-		return nil, fmt.Errorf("scan session file: %w", err)
+		return fmt.Errorf("open session file %s: %w", path, err)
 	}
+	defer func() { _ = file.Close() }()
 
-	return entries, nil
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("copy session file %s: %w", path, err)
+	}
+	return nil
 }
 
-// List returns known session files sorted by newest first.
+// List returns known session files sorted by newest first, with details
+// from each session's index.
 func (s *Store) List(ctx context.Context) ([]SessionInfo, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
@@ -192,11 +572,19 @@ func (s *Store) List(ctx context.Context) ([]SessionInfo, error) {
 	}
 
 	out := make([]SessionInfo, 0, len(items))
+	byID := make(map[string]int, len(items))
 	for _, item := range items {
 		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
-		if item.IsDir() || filepath.Ext(item.Name()) != sessionFileExt {
+		if item.IsDir() {
+			continue
+		}
+		id, ok := strings.CutSuffix(item.Name(), sessionFileExt)
+		if !ok {
+			id, ok = strings.CutSuffix(item.Name(), sessionFileExt+gzipFileExt)
+		}
+		if !ok || id == "" || strings.HasSuffix(id, archiveSuffix) {
 			continue
 		}
 
@@ -205,13 +593,24 @@ func (s *Store) List(ctx context.Context) ([]SessionInfo, error) {
 			return nil, fmt.Errorf("read session file info %s: %w", item.Name(), err)
 		}
 
-		id := strings.TrimSuffix(item.Name(), sessionFileExt)
-		out = append(out, SessionInfo{
+		current := SessionInfo{
 			ID:        id,
 			Path:      filepath.Join(s.dir, item.Name()),
 			UpdatedAt: info.ModTime(),
 			SizeBytes: info.Size(),
-		})
+		}
+		// A session compacted mid-flight has both a .gz and a plaintext tail.
+		if idx, seen := byID[id]; seen {
+			current.SizeBytes += out[idx].SizeBytes
+			if out[idx].UpdatedAt.After(current.UpdatedAt) {
+				current.Path = out[idx].Path
+				current.UpdatedAt = out[idx].UpdatedAt
+			}
+			out[idx] = current
+			continue
+		}
+		byID[id] = len(out)
+		out = append(out, current)
 	}
 
 	sort.Slice(out, func(i, j int) bool {
@@ -220,15 +619,122 @@ func (s *Store) List(ctx context.Context) ([]SessionInfo, error) {
 		}
 		return out[i].UpdatedAt.After(out[j].UpdatedAt)
 	})
+	// Unreadable sessions are still listed, just without index details.
+	for i := range out {
+		if idx, err := s.Index(ctx, out[i].ID); err == nil {
+			out[i].Name = idx.Name
+			out[i].Entries = idx.Entries
+			out[i].LeafID = idx.LeafID
+		}
+	}
 	return out, nil
 }
 
+// Search scans every session file for entries whose Content or Name matches
+// query. Matching is a case-insensitive substring unless query is prefixed with
+// "re:", in which case the remainder is compiled as a regular expression.
+// Hits are grouped by session, newest session first, in file order.
+func (s *Store) Search(ctx context.Context, query string) ([]SearchHit, error) {
+	match, err := compileSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []SearchHit
+	for _, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entries, err := s.Load(ctx, info.ID)
+		if err != nil {
+			return nil, fmt.Errorf("search session %s: %w", info.ID, err)
+		}
+		for _, entry := range entries {
+			for _, field := range []string{entry.Content, entry.Name} {
+				start, end := match(field)
+				if start < 0 {
+					continue
+				}
+				hits = append(hits, SearchHit{
+					SessionID: info.ID,
+					EntryID:   entry.ID,
+					Type:      entry.Type,
+					Snippet:   searchSnippet(field, start, end),
+				})
+				break
+			}
+		}
+	}
+	return hits, nil
+}
+
+// compileSearchQuery returns a matcher reporting the byte span of the first
+// match in a field, or -1 when the field does not match.
+func compileSearchQuery(query string) (func(string) (int, int), error) {
+	query = strings.TrimSpace(query)
+	if pattern, ok := strings.CutPrefix(query, searchRegexPrefix); ok {
+		if pattern == "" {
+			return nil, ErrSearchQueryEmpty
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compile search pattern: %w", err)
+		}
+		return func(field string) (int, int) {
+			loc := re.FindStringIndex(field)
+			if loc == nil || field == "" {
+				return -1, -1
+			}
+			return loc[0], loc[1]
+		}, nil
+	}
+	if query == "" {
+		return nil, ErrSearchQueryEmpty
+	}
+	needle := strings.ToLower(query)
+	return func(field string) (int, int) {
+		// ToLower can change byte lengths for some runes; search the lowered
+		// copy and clamp offsets, which is enough for snippet placement.
+		lowered := strings.ToLower(field)
+		idx := strings.Index(lowered, needle)
+		if idx < 0 {
+			return -1, -1
+		}
+		return min(idx, len(field)), min(idx+len(needle), len(field))
+	}, nil
+}
+
+func searchSnippet(field string, start, end int) string {
+	from := max(0, start-searchSnippetRadius)
+	to := min(len(field), end+searchSnippetRadius)
+	for from > 0 && !utf8.RuneStart(field[from]) {
+		from--
+	}
+	for to < len(field) && !utf8.RuneStart(field[to]) {
+		to++
+	}
+
+	snippet := strings.Join(strings.Fields(field[from:to]), " ")
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(field) {
+		snippet += "…"
+	}
+	return snippet
+}
+
 func (s *Store) sessionPath(sessionID string) (string, error) {
 	id := strings.TrimSpace(sessionID)
 	if id == "" {
 		return "", ErrSessionIDRequired
 	}
-	if strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+	if strings.ContainsAny(id, `/\`) || id == "." || id == ".." || strings.HasSuffix(id, archiveSuffix) {
 		return "", fmt.Errorf("%w: %s", ErrInvalidSessionID, id)
 	}
 	return filepath.Join(s.dir, id+sessionFileExt), nil
@@ -1,11 +1,17 @@
 package session
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -69,6 +75,125 @@ func TestStoreLoadNotFound(t *testing.T) {
 	}
 }
 
+func TestStoreLoadLenientSkipsCorruptLines(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), ".gar", "sessions")
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	content := `{"id":"01","type":"user","content":"first","ts":1}
+{"id":"02","type":"assistant","content":"sec
+{"id":"03","type":"user","content":"third","parent_id":"01","ts":3}
+`
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.jsonl"), []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if _, err := store.Load(context.Background(), "broken"); err == nil {
+		t.Fatal("Load() error = nil, want strict decode error")
+	}
+
+	entries, skipped, err := store.LoadLenient(context.Background(), "broken")
+	if err != nil {
+		t.Fatalf("LoadLenient() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != "01" || entries[1].ID != "03" {
+		t.Fatalf("LoadLenient() entries = %#v, want ids 01 and 03", entries)
+	}
+	if len(skipped) != 1 || skipped[0].Line != 2 || !strings.HasSuffix(skipped[0].Path, "broken.jsonl") {
+		t.Fatalf("LoadLenient() skipped = %#v, want line 2 of broken.jsonl", skipped)
+	}
+}
+
+func TestStoreInterleavedAppendsKeepEachLineValid(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), ".gar", "sessions")
+	// Two stores share no lock, like two gar processes on one session.
+	var stores [2]*Store
+	for i := range stores {
+		store, err := NewStore(dir)
+		if err != nil {
+			t.Fatalf("NewStore() error = %v", err)
+		}
+		store.SetFsync(true)
+		stores[i] = store
+	}
+
+	const perWriter = 50
+	content := strings.Repeat("x", 2048)
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*perWriter)
+	for writer := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				entry := Entry{ID: fmt.Sprintf("%d-%d", writer, i), Type: "user", Content: content}
+				errs <- stores[writer%2].Append(context.Background(), "shared", entry)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, "shared.jsonl"))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	// A write seen mid-flight by the other store may leave a blank line.
+	lines := slices.DeleteFunc(strings.Split(string(raw), "\n"), func(line string) bool { return line == "" })
+	if len(lines) != 4*perWriter {
+		t.Fatalf("lines = %d, want %d", len(lines), 4*perWriter)
+	}
+	for i, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Fatalf("line %d is not valid JSON: %.80q", i+1, line)
+		}
+	}
+}
+
+func TestStoreAppendStartsFreshLineAfterTornWrite(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), ".gar", "sessions")
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	torn := `{"id":"01","type":"user","content":"first","ts":1}` + "\n" + `{"id":"02","type":"assis`
+	if err := os.WriteFile(filepath.Join(dir, "torn.jsonl"), []byte(torn), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if err := store.Append(context.Background(), "torn", Entry{ID: "03", Type: "user", Content: "third"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	entries, skipped, err := store.LoadLenient(context.Background(), "torn")
+	if err != nil {
+		t.Fatalf("LoadLenient() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != "01" || entries[1].ID != "03" {
+		t.Fatalf("LoadLenient() entries = %#v, want ids 01 and 03", entries)
+	}
+	if len(skipped) != 1 || skipped[0].Line != 2 {
+		t.Fatalf("LoadLenient() skipped = %#v, want only the torn line 2", skipped)
+	}
+}
+
 func TestStoreListReturnsSessionFiles(t *testing.T) {
 	t.Parallel()
 
@@ -102,6 +227,165 @@ func TestStoreListReturnsSessionFiles(t *testing.T) {
 	}
 }
 
+func TestStoreCompactRoundTripsGzippedSession(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), ".gar", "sessions")
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if err := store.Append(ctx, "gz", Entry{ID: "1", Type: "user", Content: "first"}); err != nil {
+		t.Fatalf("Append(1) error = %v", err)
+	}
+	if err := store.Compact(ctx, "gz"); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "gz.jsonl")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("plaintext file after Compact() stat error = %v, want not exist", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "gz.jsonl.gz")); err != nil {
+		t.Fatalf("gzip file after Compact() stat error = %v", err)
+	}
+
+	// Appends after compaction land in a plaintext tail that Load merges.
+	if err := store.Append(ctx, "gz", Entry{ID: "2", Type: "assistant", Content: "second", ParentID: "1"}); err != nil {
+		t.Fatalf("Append(2) error = %v", err)
+	}
+	infos, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(infos) != 1 || infos[0].ID != "gz" {
+		t.Fatalf("List() = %#v, want single gz session", infos)
+	}
+
+	entries, err := store.Load(ctx, "gz")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Content != "first" || entries[1].Content != "second" {
+		t.Fatalf("Load() entries = %#v, want first then second", entries)
+	}
+
+	if err := store.Compact(ctx, "gz"); err != nil {
+		t.Fatalf("second Compact() error = %v", err)
+	}
+	entries, err = store.Load(ctx, "gz")
+	if err != nil {
+		t.Fatalf("Load() after second Compact() error = %v", err)
+	}
+	if len(entries) != 2 || entries[1].ParentID != "1" {
+		t.Fatalf("Load() entries after second Compact() = %#v, want both entries", entries)
+	}
+
+	if err := store.Compact(ctx, "missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("Compact(missing) error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestStoreRotatesOldestEntriesIntoLoadableArchive(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), ".gar", "sessions")
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	store.SetMaxEntries(4)
+	ctx := context.Background()
+
+	if err := store.Append(ctx, "rot", Entry{ID: "01", Type: "user", Content: "e01"}); err != nil {
+		t.Fatalf("Append(01) error = %v", err)
+	}
+	if err := store.Compact(ctx, "rot"); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	for i := 2; i <= 10; i++ {
+		id := fmt.Sprintf("%02d", i)
+		entry := Entry{ID: id, ParentID: fmt.Sprintf("%02d", i-1), Type: "user", Content: "e" + id}
+		if err := store.Append(ctx, "rot", entry); err != nil {
+			t.Fatalf("Append(%s) error = %v", id, err)
+		}
+	}
+
+	live, err := os.ReadFile(filepath.Join(dir, "rot.jsonl"))
+	if err != nil {
+		t.Fatalf("ReadFile(live) error = %v", err)
+	}
+	if lines := strings.Count(string(live), "\n"); lines > 4 {
+		t.Fatalf("live file has %d entries, want at most 4", lines)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "rot.archive.jsonl")); err != nil {
+		t.Fatalf("archive stat error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "rot.jsonl.gz")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("gzip file after rotation stat error = %v, want folded into archive", err)
+	}
+
+	entries, err := store.Load(ctx, "rot")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(entries) != 10 {
+		t.Fatalf("Load() returned %d entries, want 10", len(entries))
+	}
+	for i, entry := range entries {
+		if want := fmt.Sprintf("%02d", i+1); entry.ID != want {
+			t.Fatalf("entries[%d].ID = %q, want %q (file order preserved)", i, entry.ID, want)
+		}
+		if i > 0 && entry.ParentID != entries[i-1].ID {
+			t.Fatalf("entries[%d].ParentID = %q, want %q", i, entry.ParentID, entries[i-1].ID)
+		}
+	}
+
+	infos, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(infos) != 1 || infos[0].ID != "rot" {
+		t.Fatalf("List() = %#v, want only the rot session", infos)
+	}
+	if err := store.Append(ctx, "rot.archive", Entry{ID: "1", Type: "user"}); !errors.Is(err, ErrInvalidSessionID) {
+		t.Fatalf("Append(rot.archive) error = %v, want ErrInvalidSessionID", err)
+	}
+}
+
+func TestStoreLoadGzipEnforcesLineSizeLimit(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), ".gar", "sessions")
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+
+	file, err := os.Create(filepath.Join(dir, "big.jsonl.gz"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	zw := gzip.NewWriter(file)
+	line := `{"id":"1","type":"user","content":"` + strings.Repeat("x", maxJSONLLineSize) + `"}` + "\n"
+	if _, err := zw.Write([]byte(line)); err != nil {
+		t.Fatalf("gzip Write() error = %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip Close() error = %v", err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if _, err := store.Load(context.Background(), "big"); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("Load() error = %v, want line too large", err)
+	}
+}
+
 func TestStoreAppendFillsTimestampWhenMissing(t *testing.T) {
 	t.Parallel()
 
@@ -129,6 +413,88 @@ func TestStoreAppendFillsTimestampWhenMissing(t *testing.T) {
 	}
 }
 
+func TestStoreSearchFindsMatchingEntries(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+
+	fixtures := map[string][]Entry{
+		"s1": {
+			{ID: "1", Type: "meta"},
+			{ID: "2", Type: "user", Content: "Why does the Parser panic on empty input?"},
+			{ID: "3", Type: "assistant", Content: "The parser dereferences a nil token."},
+		},
+		"s2": {
+			{ID: "1", Type: "meta"},
+			{ID: "2", Type: "session_info", Name: "parser bug hunt"},
+			{ID: "3", Type: "user", Content: "list files"},
+		},
+	}
+	for id, entries := range fixtures {
+		for _, entry := range entries {
+			if err := store.Append(context.Background(), id, entry); err != nil {
+				t.Fatalf("Append(%s/%s) error = %v", id, entry.ID, err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "substring is case-insensitive", query: "PARSER", want: []string{"s1/2", "s1/3", "s2/2"}},
+		{name: "regex", query: `re:nil\s+token`, want: []string{"s1/3"}},
+		{name: "no match", query: "kubernetes", want: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			hits, err := store.Search(context.Background(), tc.query)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			got := make([]string, 0, len(hits))
+			for _, hit := range hits {
+				if hit.Snippet == "" || hit.Type == "" {
+					t.Fatalf("Search() hit = %#v, want type and snippet", hit)
+				}
+				got = append(got, hit.SessionID+"/"+hit.EntryID)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("Search(%q) hits = %v, want %v", tc.query, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStoreSearchRejectsEmptyAndInvalidQueries(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+
+	if _, err := store.Search(context.Background(), "  "); !errors.Is(err, ErrSearchQueryEmpty) {
+		t.Fatalf("Search(empty) error = %v, want ErrSearchQueryEmpty", err)
+	}
+	if _, err := store.Search(context.Background(), "re:("); err == nil {
+		t.Fatal("Search(invalid regex) error = nil, want compile error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.Search(ctx, "x"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Search(canceled) error = %v, want context.Canceled", err)
+	}
+}
+
 func mustRawJSON(t *testing.T, raw string) json.RawMessage {
 	t.Helper()
 	value := json.RawMessage(raw)
@@ -3,6 +3,7 @@ package tui
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -14,6 +15,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 const (
@@ -21,7 +23,21 @@ const (
 	defaultInspectorWidth   = 36
 	minimumChatPanelWidth   = 40
 	minimumInspectorVisible = 22
-	defaultMaxTokens        = 1024
+	// minimumAppWidth and minimumAppHeight are the smallest terminal the
+	// layout renders in; below either, View shows only a resize notice.
+	minimumAppWidth       = 20
+	minimumAppHeight      = 5
+	defaultMaxTokens      = 1024
+	mouseWheelScrollLines = 3
+	// toolProgressLines is how much running tool output the chat shows.
+	toolProgressLines = 20
+
+	defaultWelcome = "Welcome to gar."
+	emptyChatHint  = "Type a message to start, or /help to list slash commands."
+	firstRunTips   = `Tips:
+  /model switches the model and /resume reopens a saved session.
+  Esc cancels a running reply; messages sent meanwhile are queued.
+  /compact summarizes older messages when the context fills up.`
 )
 
 // StreamRunner executes one request and returns a streaming channel.
@@ -29,18 +45,60 @@ type StreamRunner interface {
 	Run(ctx context.Context, req *llm.Request) (<-chan llm.Event, error)
 }
 
+// ToolApprover resolves tool calls paused by EventToolApprovalRequired.
+type ToolApprover interface {
+	Approve(callID string) error
+	Deny(callID string) error
+}
+
 // AppConfig configures the root BubbleTea model.
 type AppConfig struct {
 	Version       string
 	ModelName     string
+	ProviderName  string
 	CWD           string
 	SessionID     string
 	ThemeName     string
 	ShowInspector bool
-	Runner        StreamRunner
-	MaxTokens     int
-	Tools         []llm.ToolSpec
-	SessionStore  *sessionstore.Store
+	Markdown      bool
+	ShowThinking  bool
+	// ShowInputCount shows a character and estimated-token count in the input line.
+	ShowInputCount bool
+	// ChatLimit caps the chat messages kept in memory; zero uses the default.
+	ChatLimit int
+	// KeyBindings replaces the default keys of the named actions; see KeyMap.
+	KeyBindings map[string][]string
+	Runner      StreamRunner
+	Summarizer  StreamRunner
+	MaxTokens   int
+	Temperature *float64
+	// ThinkingBudget enables extended thinking; PersistThinking records it in
+	// the session.
+	ThinkingBudget  int
+	PersistThinking bool
+	// CacheSystem marks the system prompt and tools as cacheable.
+	CacheSystem  bool
+	Tools        []llm.ToolSpec
+	SessionStore *sessionstore.Store
+	// ContextWindows, ContextWarnRatio, and CompactOnContextWarning configure
+	// the session's context-window guard.
+	ContextWindows          map[string]int
+	ContextWarnRatio        float64
+	CompactOnContextWarning bool
+	// DedupeQueue skips queueing a message identical to one already queued.
+	DedupeQueue bool
+	// Theme overrides ThemeName when set, e.g. with a theme from LoadThemeFile.
+	Theme *Theme
+	// Welcome replaces the default greeting of the empty chat. HideHints
+	// suppresses the greeting, hints, and first-run tips, leaving a plain
+	// placeholder for scripted use.
+	Welcome   string
+	HideHints bool
+	// ProjectPromptFiles are candidate project instruction files; the first
+	// present is prepended to the system prompt whenever a session loads.
+	ProjectPromptFiles []string
+	// AutoNameSessions names an unnamed session after its first user message.
+	AutoNameSessions bool
 }
 
 // StreamEventMsg wraps one llm event for app updates.
@@ -48,11 +106,26 @@ type StreamEventMsg struct {
 	Event llm.Event
 }
 
+// streamReadMsg carries one read from the stream of run Gen.
 type streamReadMsg struct {
+	Gen    int
 	Event  llm.Event
 	Closed bool
 }
 
+// taskDoneMsg carries what the background task Gen returned.
+type taskDoneMsg struct {
+	Gen  int
+	Text string
+	Err  error
+}
+
+// streamDrainedMsg carries the events a cancelled stream emitted after Esc.
+type streamDrainedMsg struct {
+	Gen    int
+	Events []llm.Event
+}
+
 type selectorKind string
 
 const (
@@ -90,11 +163,40 @@ type App struct {
 	input     InputModel
 	inspector InspectorModel
 
+	// commands dispatches slash commands; completions and completionIndex
+	// track the Tab cycle through command names matching the input.
+	commands        *agentapp.CommandRegistry
+	completions     []string
+	completionIndex int
+
 	session         *agentsession.AgentSession
 	sessionInitErr  error
 	selector        *selectorState
 	assistantBuffer strings.Builder
-	activeStream    <-chan llm.Event
+	thinkingBuffer  strings.Builder
+	// toolOutputs keeps the tail of each running call's EventToolProgress
+	// output by tool call ID; the chat shows it until the result arrives.
+	toolOutputs    map[string]*outputTail
+	showThinking   bool
+	activeStream   <-chan llm.Event
+	drainingStream bool
+	// streamGen numbers runs so reads still queued from a cancelled stream
+	// are told apart from the current one. drainGen is the run being drained,
+	// and strayEvents holds events its last queued read returned after Esc.
+	streamGen   int
+	drainGen    int
+	strayEvents []llm.Event
+	// taskCancel cancels the running background task of a slash command,
+	// numbered taskGen; nil when none runs.
+	taskCancel context.CancelFunc
+	taskGen    int
+	keys       KeyMap
+	// ticking is set while a status spinner tick is scheduled.
+	ticking         bool
+	approver        ToolApprover
+	pendingApproval *llm.ToolCall
+	// now is the clock used for inspector timing; tests replace it.
+	now func() time.Time
 }
 
 // NewApp constructs the root TUI model with defaults.
@@ -108,31 +210,62 @@ func NewApp(cfg AppConfig) *App {
 		sessionID = time.Now().UTC().Format("20060102-150405")
 	}
 
+	theme := ResolveTheme(cfg.ThemeName)
+	if cfg.Theme != nil {
+		theme = *cfg.Theme
+	}
+
 	model := &App{
-		theme:         ResolveTheme(cfg.ThemeName),
+		theme:         theme,
 		showInspector: cfg.ShowInspector,
+		showThinking:  cfg.ShowThinking,
 		runner:        cfg.Runner,
 		modelName:     strings.TrimSpace(cfg.ModelName),
 		maxTokens:     maxTokens,
 		tools:         cloneToolSpecs(cfg.Tools),
 		status:        NewStatusModel(cfg.Version, cfg.ModelName, cfg.CWD, sessionID),
-		chat:          NewChatModel(0),
+		chat:          NewChatModel(cfg.ChatLimit),
 		input:         NewInputModel(">", "Type message and press Enter"),
 		inspector:     NewInspectorModel(),
+		commands:      agentapp.DefaultCommands(),
+		keys:          NewKeyMap(cfg.KeyBindings),
+		now:           time.Now,
+	}
+
+	model.chat.SetMarkdown(cfg.Markdown)
+	if !cfg.HideHints {
+		model.chat.SetEmptyText(welcomeText(cfg.Welcome, isFirstRun(cfg.SessionStore)))
 	}
+	model.input.SetShowCount(cfg.ShowInputCount)
 
 	if model.width == 0 {
 		model.width = defaultAppWidth
 	}
 
+	if approver, ok := cfg.Runner.(ToolApprover); ok {
+		model.approver = approver
+	}
+
 	if cfg.Runner != nil {
 		sessionModel, err := agentsession.New(context.Background(), agentsession.Config{
-			Runner:    cfg.Runner,
-			Store:     cfg.SessionStore,
-			SessionID: sessionID,
-			Model:     strings.TrimSpace(cfg.ModelName),
-			MaxTokens: maxTokens,
-			Tools:     cfg.Tools,
+			Runner:                  cfg.Runner,
+			Summarizer:              cfg.Summarizer,
+			Store:                   cfg.SessionStore,
+			SessionID:               sessionID,
+			Model:                   strings.TrimSpace(cfg.ModelName),
+			Provider:                strings.TrimSpace(cfg.ProviderName),
+			MaxTokens:               maxTokens,
+			Temperature:             cfg.Temperature,
+			ThinkingBudget:          cfg.ThinkingBudget,
+			PersistThinking:         cfg.PersistThinking,
+			CacheSystem:             cfg.CacheSystem,
+			Tools:                   cfg.Tools,
+			ContextWindows:          cfg.ContextWindows,
+			ContextWarnRatio:        cfg.ContextWarnRatio,
+			CompactOnContextWarning: cfg.CompactOnContextWarning,
+			DedupeQueue:             cfg.DedupeQueue,
+			ProjectPromptFiles:      cfg.ProjectPromptFiles,
+			AutoName:                cfg.AutoNameSessions,
 			Meta: map[string]any{
 				"model": strings.TrimSpace(cfg.ModelName),
 				"cwd":   strings.TrimSpace(cfg.CWD),
@@ -143,6 +276,9 @@ func NewApp(cfg AppConfig) *App {
 		} else {
 			model.session = sessionModel
 			model.rebuildChatFromSession()
+			if warning := sessionModel.LoadWarning(); warning != "" {
+				model.chat.Append("assistant", warning)
+			}
 		}
 	}
 
@@ -164,47 +300,86 @@ func (m *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.chat.SetViewportHeight(m.chatViewportHeight())
 		return m, nil
 
+	case statusTickMsg:
+		if m.activeStream == nil {
+			m.ticking = false
+			return m, nil
+		}
+		m.status.Tick()
+		return m, statusTickCommand()
+
+	case tea.MouseMsg:
+		if m.selector != nil {
+			return m, m.handleSelectorMouse(msg)
+		}
+		m.handleChatScrollMouse(msg)
+		return m, nil
+
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c":
-			return m, tea.Quit
-		case "q":
+		if m.keys.Matches(msg, KeyActionQuit) {
+			return m, m.quit()
+		}
+		if m.keys.Matches(msg, KeyActionQuitWhenIdle) {
 			if m.selector != nil {
 				return m, m.cancelSelector()
 			}
 			if strings.TrimSpace(m.input.Value()) == "" && m.activeStream == nil {
-				return m, tea.Quit
+				return m, m.quit()
 			}
 		}
 
 		if m.selector != nil {
 			return m, m.handleSelectorKey(msg)
 		}
+		if m.pendingApproval != nil && m.handleApprovalKey(msg) {
+			return m, nil
+		}
+		if m.keys.Matches(msg, KeyActionCancel) && m.activeStream != nil {
+			return m, m.cancelActiveStream()
+		}
+		if m.keys.Matches(msg, KeyActionCancel) && m.taskCancel != nil {
+			m.taskCancel()
+			return m, nil
+		}
 		if m.handleChatScrollKey(msg) {
 			return m, nil
 		}
 
-		if msg.Type == tea.KeyEnter && (msg.Alt || msg.String() == "alt+enter") {
+		if m.keys.Matches(msg, KeyActionComplete) && m.completeSlashCommand() {
+			return m, nil
+		}
+
+		if m.keys.Matches(msg, KeyActionFollowUp) {
 			content := strings.TrimSpace(m.input.Value())
 			m.input.Clear()
 			return m, m.handleInputSubmit(content, true)
 		}
-
-		if submitted := m.input.HandleKey(msg); submitted {
+		if m.keys.Matches(msg, KeyActionSubmit) {
 			content := strings.TrimSpace(m.input.Value())
 			m.input.Clear()
 			return m, m.handleInputSubmit(content, false)
 		}
+
+		// Submission is driven by the keymap, so the input's own Enter
+		// handling is ignored once submit is remapped.
+		_ = m.input.HandleKey(msg)
 		return m, nil
 
 	case StreamEventMsg:
 		m.consumeEvent(msg.Event)
 		if m.activeStream != nil {
-			return m, readStreamEventCommand(m.activeStream)
+			return m, readStreamEventCommand(m.activeStream, m.streamGen)
 		}
 		return m, nil
 
 	case streamReadMsg:
+		if msg.Gen != m.streamGen || m.activeStream == nil {
+			// A read queued before Esc; the drain records the rest of that run.
+			if m.drainingStream && msg.Gen == m.drainGen && !msg.Closed {
+				m.strayEvents = append(m.strayEvents, msg.Event)
+			}
+			return m, nil
+		}
 		if msg.Closed {
 			if m.session != nil {
 				if err := m.session.Finalize(context.Background()); err != nil {
@@ -216,14 +391,54 @@ func (m *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.consumeEvent(msg.Event)
 		if m.activeStream != nil {
-			return m, readStreamEventCommand(m.activeStream)
+			return m, readStreamEventCommand(m.activeStream, m.streamGen)
+		}
+		return m, nil
+
+	case taskDoneMsg:
+		if msg.Gen != m.taskGen || m.taskCancel == nil {
+			return m, nil
+		}
+		m.taskCancel()
+		m.taskCancel = nil
+		if m.activeStream == nil {
+			m.status.SetState("idle")
+		}
+		switch {
+		case errors.Is(msg.Err, context.Canceled):
+			m.chat.Append("assistant", "Cancelled.")
+		case msg.Err != nil:
+			m.appendErrorMessage(msg.Err.Error())
+		default:
+			m.chat.Append("assistant", msg.Text)
 		}
 		return m, nil
 
+	case streamDrainedMsg:
+		if !m.drainingStream || msg.Gen != m.drainGen {
+			return m, nil
+		}
+		// The stray read was queued before the drain began, so its event
+		// came off the stream first.
+		events := append(m.strayEvents, msg.Events...)
+		m.strayEvents = nil
+		if m.session != nil {
+			for _, ev := range events {
+				if err := m.session.RecordEvent(context.Background(), ev); err != nil {
+					m.appendErrorMessage(err.Error())
+				}
+			}
+			if err := m.session.Finalize(context.Background()); err != nil {
+				m.appendErrorMessage(err.Error())
+			}
+		}
+		m.drainingStream = false
+		return m, nil
+
 	case llm.Event:
 		m.consumeEvent(msg)
 		if m.activeStream != nil {
-			return m, readStreamEventCommand(m.activeStream)
+			return m, readStreamEventCommand(m.activeStream, m.streamGen)
 		}
 		return m, nil
 	}
@@ -237,6 +452,9 @@ func (m *App) View() string {
 	if width <= 0 {
 		width = defaultAppWidth
 	}
+	if m.height > 0 && (width < minimumAppWidth || m.height < minimumAppHeight) {
+		return renderTooSmall(width, m.height)
+	}
 
 	statusLine := m.status.Render(width, m.theme)
 	body := m.renderBody(width)
@@ -244,6 +462,20 @@ func (m *App) View() string {
 	return strings.Join([]string{statusLine, body, inputLine}, "\n")
 }
 
+// renderTooSmall fills a width x height terminal with a notice asking for at
+// least minimumAppWidth x minimumAppHeight, cut to fit.
+func renderTooSmall(width, height int) string {
+	notice := []string{
+		"Terminal too small.",
+		fmt.Sprintf("Resize to %dx%d.", minimumAppWidth, minimumAppHeight),
+	}
+	lines := make([]string, 0, height)
+	for _, line := range notice[:min(len(notice), height)] {
+		lines = append(lines, ansi.Truncate(line, max(width, 0), ""))
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (m *App) handleInputSubmit(content string, followUp bool) tea.Cmd {
 	if content == "" {
 		return nil
@@ -261,6 +493,10 @@ func (m *App) handleInputSubmit(content string, followUp bool) tea.Cmd {
 		return m.handleSlashCommand(content)
 	}
 
+	if m.drainingStream {
+		m.appendErrorMessage("previous run is still cancelling; try again in a moment")
+		return nil
+	}
 	if m.activeStream != nil {
 		var err error
 		if followUp {
@@ -268,14 +504,18 @@ func (m *App) handleInputSubmit(content string, followUp bool) tea.Cmd {
 		} else {
 			err = m.session.QueueSteer(content)
 		}
-		if err != nil {
-			m.appendErrorMessage(err.Error())
-			return nil
-		}
 		mode := "steer"
 		if followUp {
 			mode = "follow-up"
 		}
+		if errors.Is(err, agentsession.ErrAlreadyQueued) {
+			m.chat.Append("assistant", fmt.Sprintf("That %s message is already queued.", mode))
+			return nil
+		}
+		if err != nil {
+			m.appendErrorMessage(err.Error())
+			return nil
+		}
 		m.chat.Append("assistant", fmt.Sprintf("Queued %s message.", mode))
 		return nil
 	}
@@ -285,7 +525,11 @@ func (m *App) handleInputSubmit(content string, followUp bool) tea.Cmd {
 
 	stream, err := m.session.Submit(context.Background(), content)
 	if err != nil {
-		m.appendErrorMessage(err.Error())
+		errText := err.Error()
+		if hint := providerErrorHint(err); hint != "" {
+			errText += "\n" + hint
+		}
+		m.appendErrorMessage(errText)
 		return nil
 	}
 	return m.startStream(stream)
@@ -294,19 +538,27 @@ func (m *App) handleInputSubmit(content string, followUp bool) tea.Cmd {
 func (m *App) handleSlashCommand(content string) tea.Cmd {
 	return agentapp.ExecuteSlashCommand(content, agentapp.CommandEnv{
 		Session:      m.session,
-		ActiveStream: m.activeStream != nil,
+		ActiveStream: m.activeStream != nil || m.drainingStream,
+		Commands:     m.commands,
 		OpenResumeSelector: func() tea.Cmd {
 			return m.openResumeSelector()
 		},
 		OpenTreeSelector: func() tea.Cmd {
 			return m.openTreeSelector()
 		},
+		StartRun: func() tea.Cmd {
+			return m.startRunCommand()
+		},
+		RunTask: m.runTask,
 		RebuildChatFromSession: func() {
 			m.rebuildChatFromSession()
 		},
 		RefreshSessionStatus: func() {
 			m.refreshSessionStatus()
 		},
+		ClearChat: func() {
+			m.chat.Clear()
+		},
 		GetInputValue: func() string {
 			return m.input.Value()
 		},
@@ -337,7 +589,10 @@ func (m *App) openResumeSelector() tea.Cmd {
 	current := m.session.SessionID()
 	cursor := 0
 	for index, info := range infos {
-		label := fmt.Sprintf("%s  (%s)", info.ID, info.UpdatedAt.Format(time.DateTime))
+		label := fmt.Sprintf("%s  (%s, %d entries)", info.ID, info.UpdatedAt.Format(time.DateTime), info.Entries)
+		if info.Name != "" {
+			label = info.Name + "  " + label
+		}
 		if info.ID == current {
 			label = label + "  [current]"
 			cursor = index
@@ -421,6 +676,65 @@ func (m *App) handleSelectorKey(msg tea.KeyMsg) tea.Cmd {
 	}
 }
 
+// approvalPrompt asks the user to approve call. A bash call shows its label
+// and exact command; other tools show their raw arguments.
+func approvalPrompt(call llm.ToolCall) string {
+	const keys = "Press Enter to approve, Esc to deny."
+	var bash struct {
+		Label   string `json:"label"`
+		Command string `json:"command"`
+	}
+	if call.Name == "bash" && json.Unmarshal(call.Arguments, &bash) == nil && strings.TrimSpace(bash.Command) != "" {
+		header := "Approve bash?"
+		if label := strings.TrimSpace(bash.Label); label != "" {
+			header = "Approve bash: " + label + "?"
+		}
+		return fmt.Sprintf("%s\n$ %s\n%s", header, bash.Command, keys)
+	}
+	return fmt.Sprintf("Approve %s %s? %s", call.Name, strings.TrimSpace(string(call.Arguments)), keys)
+}
+
+// handleApprovalKey resolves the pending tool approval: Enter approves, Esc denies.
+func (m *App) handleApprovalKey(msg tea.KeyMsg) bool {
+	var approved bool
+	switch msg.Type {
+	case tea.KeyEnter:
+		approved = true
+	case tea.KeyEsc:
+		approved = false
+	default:
+		return false
+	}
+
+	call := m.pendingApproval
+	m.pendingApproval = nil
+	if m.approver == nil {
+		m.appendErrorMessage("tool approval is not supported by runner")
+		return true
+	}
+
+	var err error
+	if approved {
+		err = m.approver.Approve(call.ID)
+	} else {
+		err = m.approver.Deny(call.ID)
+	}
+	if err != nil {
+		m.appendErrorMessage(err.Error())
+		return true
+	}
+	if approved {
+		m.chat.Append("assistant", fmt.Sprintf("Approved %s.", call.Name))
+		m.status.SetState("tool_executing")
+		m.inspector.SetState("tool_executing")
+	} else {
+		m.chat.Append("assistant", fmt.Sprintf("Denied %s.", call.Name))
+		m.status.SetState("streaming")
+		m.inspector.SetState("streaming")
+	}
+	return true
+}
+
 func (m *App) cancelSelector() tea.Cmd {
 	if m.selector == nil {
 		return nil
@@ -484,18 +798,92 @@ func (m *App) startStream(stream <-chan llm.Event) tea.Cmd {
 		return nil
 	}
 	m.activeStream = stream
+	m.streamGen++
+	m.toolOutputs = nil
+	// A context warning, if any, is re-sent at the start of each run.
+	m.status.SetWarning("")
 	m.status.SetState("streaming")
+	m.status.ResetProgress()
 	m.inspector.SetState("streaming")
-	return readStreamEventCommand(stream)
+	if m.ticking {
+		return readStreamEventCommand(stream, m.streamGen)
+	}
+	m.ticking = true
+	return tea.Batch(readStreamEventCommand(stream, m.streamGen), statusTickCommand())
 }
 
-func readStreamEventCommand(stream <-chan llm.Event) tea.Cmd {
+// runTask starts work in the background for a slash command. Esc cancels
+// its context, and the result arrives as a taskDoneMsg.
+func (m *App) runTask(label string, work func(ctx context.Context) (string, error)) tea.Cmd {
+	if m.taskCancel != nil {
+		m.appendErrorMessage("another command is still running; press Esc to cancel it")
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.taskCancel = cancel
+	m.taskGen++
+	gen := m.taskGen
+	m.status.SetState(label)
+	return func() tea.Msg {
+		text, err := work(ctx)
+		return taskDoneMsg{Gen: gen, Text: text, Err: err}
+	}
+}
+
+// quit closes the session, compacting its file when compression is on, and
+// exits. There is no screen left to report a failure on, so it is dropped.
+func (m *App) quit() tea.Cmd {
+	if m.taskCancel != nil {
+		m.taskCancel()
+	}
+	if m.session != nil {
+		_ = m.session.Close(context.Background())
+	}
+	return tea.Quit
+}
+
+// cancelActiveStream aborts the in-flight run and returns the UI to idle. The
+// remaining events are drained in the background so the session still records
+// the aborted turn before the next submit.
+func (m *App) cancelActiveStream() tea.Cmd {
+	if m.session == nil {
+		return nil
+	}
+	if err := m.session.Cancel(); err != nil {
+		m.appendErrorMessage(err.Error())
+		return nil
+	}
+
+	stream := m.activeStream
+	m.activeStream = nil
+	m.drainingStream = true
+	m.drainGen = m.streamGen
+	m.strayEvents = nil
+	m.pendingApproval = nil
+	m.flushAssistantBuffer()
+	m.chat.Append("assistant", "Run cancelled.")
+	m.status.SetState("idle")
+	m.inspector.SetState("idle")
+	return drainStreamCommand(stream, m.drainGen)
+}
+
+func drainStreamCommand(stream <-chan llm.Event, gen int) tea.Cmd {
+	return func() tea.Msg {
+		var events []llm.Event
+		for event := range stream {
+			events = append(events, event)
+		}
+		return streamDrainedMsg{Gen: gen, Events: events}
+	}
+}
+
+func readStreamEventCommand(stream <-chan llm.Event, gen int) tea.Cmd {
 	return func() tea.Msg {
 		event, ok := <-stream
 		if !ok {
-			return streamReadMsg{Closed: true}
+			return streamReadMsg{Gen: gen, Closed: true}
 		}
-		return streamReadMsg{Event: event}
+		return streamReadMsg{Gen: gen, Event: event}
 	}
 }
 
@@ -510,6 +898,7 @@ func (m *App) consumeEvent(ev llm.Event) {
 	case llm.EventStart:
 		m.status.SetState("streaming")
 		m.inspector.SetState("streaming")
+		m.inspector.StartTurnTimer(m.now())
 	case llm.EventQueuedMessage:
 		if ev.Message == nil || ev.Message.Role != llm.RoleUser {
 			return
@@ -523,26 +912,100 @@ func (m *App) consumeEvent(ev llm.Event) {
 		m.status.SetState("streaming")
 		m.inspector.SetState("streaming")
 	case llm.EventContentBlockStart:
+		if ev.ContentBlockStart != nil && ev.ContentBlockStart.Type == "thinking" && m.showThinking {
+			m.thinkingBuffer.WriteString(ev.ContentBlockStart.Thinking)
+		}
 		if ev.ContentBlockStart != nil && ev.ContentBlockStart.Type == "text" && ev.ContentBlockStart.Text != "" {
+			m.flushThinkingBuffer()
 			m.assistantBuffer.WriteString(ev.ContentBlockStart.Text)
+			m.chat.StreamMessage("assistant", m.assistantBuffer.String())
 			m.status.SetState("streaming")
 			m.inspector.SetState("streaming")
 		}
+	case llm.EventThinkingDelta:
+		if m.showThinking {
+			m.thinkingBuffer.WriteString(ev.ThinkingDelta)
+		}
 	case llm.EventTextDelta:
+		m.flushThinkingBuffer()
 		m.assistantBuffer.WriteString(ev.TextDelta)
+		m.chat.StreamMessage("assistant", m.assistantBuffer.String())
 		m.status.SetState("streaming")
 		m.inspector.SetState("streaming")
 	case llm.EventToolCallStart:
+		m.flushThinkingBuffer()
 		if ev.ToolCall != nil {
 			m.inspector.RecordToolCall(ev.ToolCall.Name)
 			m.status.SetState("tool_executing")
 			m.inspector.SetState("tool_executing")
 		}
+	case llm.EventToolProgress:
+		if ev.ToolCall == nil || ev.ToolOutputDelta == "" {
+			return
+		}
+		tail, ok := m.toolOutputs[ev.ToolCall.ID]
+		if !ok {
+			m.flushAssistantBuffer()
+			if m.toolOutputs == nil {
+				m.toolOutputs = make(map[string]*outputTail)
+			}
+			tail = &outputTail{}
+			m.toolOutputs[ev.ToolCall.ID] = tail
+		}
+		tail.Write(ev.ToolOutputDelta)
+		m.chat.StreamToolOutput(ev.ToolCall.ID, ev.ToolCall.Name+": "+tail.String())
+	case llm.EventToolResult:
+		if ev.ToolResult == nil {
+			return
+		}
+		// A result for a call streaming progress replaces its live output.
+		m.flushAssistantBuffer()
+		delete(m.toolOutputs, ev.ToolResult.ToolCallID)
+		m.chat.AppendToolResult(*ev.ToolResult)
+	case llm.EventToolRetry:
+		if ev.ToolCall == nil || ev.ToolRetry == nil {
+			return
+		}
+		m.chat.Append("assistant", fmt.Sprintf(
+			"Retrying %s in %s (attempt %d): %v",
+			ev.ToolCall.Name,
+			ev.ToolRetry.Delay.Round(time.Millisecond),
+			ev.ToolRetry.Attempt,
+			ev.ToolRetry.Err,
+		))
+	case llm.EventToolApprovalRequired:
+		if ev.ToolCall == nil {
+			return
+		}
+		call := *ev.ToolCall
+		m.pendingApproval = &call
+		m.chat.Append("assistant", approvalPrompt(call))
+		m.status.SetState("awaiting_approval")
+		m.inspector.SetState("awaiting_approval")
+	case llm.EventContextWarning:
+		if ev.ContextWarning == nil || ev.ContextWarning.ContextWindow <= 0 {
+			return
+		}
+		m.status.SetWarning(fmt.Sprintf(
+			"context ~%d%% (%d/%d tokens)",
+			ev.ContextWarning.EstimatedTokens*100/ev.ContextWarning.ContextWindow,
+			ev.ContextWarning.EstimatedTokens,
+			ev.ContextWarning.ContextWindow,
+		))
 	case llm.EventUsage:
 		if ev.Usage != nil {
 			m.inspector.SetUsage(*ev.Usage)
+			m.inspector.UpdateThroughput(m.now(), ev.Usage.OutputTokens)
+			m.status.SetTurnOutputTokens(ev.Usage.OutputTokens)
 		}
 	case llm.EventDone:
+		outputTokens := m.inspector.Usage.OutputTokens
+		if ev.Done != nil && ev.Done.Usage.OutputTokens > 0 {
+			outputTokens = ev.Done.Usage.OutputTokens
+		}
+		m.inspector.StopTurnTimer(m.now(), outputTokens)
+		m.status.SetTurnOutputTokens(outputTokens)
+		m.status.FinishTurn()
 		if ev.Done != nil && ev.Done.Reason == llm.StopReasonToolUse {
 			// tool_use is an intermediate terminal from provider turn; agent loop continues.
 			m.flushAssistantBuffer()
@@ -554,11 +1017,16 @@ func (m *App) consumeEvent(ev llm.Event) {
 		m.status.SetState("idle")
 		m.inspector.SetState("idle")
 		m.activeStream = nil
+		m.pendingApproval = nil
 	case llm.EventError:
 		m.flushAssistantBuffer()
+		m.pendingApproval = nil
 		errText := "stream error"
 		if ev.Err != nil {
 			errText = ev.Err.Error()
+			if hint := providerErrorHint(ev.Err); hint != "" {
+				errText += "\n" + hint
+			}
 		}
 		m.appendErrorMessage(errText)
 		m.activeStream = nil
@@ -572,10 +1040,65 @@ func (m *App) appendErrorMessage(errText string) {
 	m.inspector.SetState("error")
 }
 
-func (m *App) flushAssistantBuffer() {
-	text := strings.TrimSpace(m.assistantBuffer.String())
+// welcomeText builds the greeting shown before the first message, adding
+// tips on the first run.
+func welcomeText(welcome string, firstRun bool) string {
+	welcome = strings.TrimSpace(welcome)
+	if welcome == "" {
+		welcome = defaultWelcome
+	}
+	text := welcome + "\n\n" + emptyChatHint
+	if firstRun {
+		text += "\n\n" + firstRunTips
+	}
+	return text
+}
+
+// isFirstRun reports whether store holds no saved sessions yet. Without a
+// store, or when listing fails, it assumes gar has been run before.
+func isFirstRun(store *sessionstore.Store) bool {
+	if store == nil {
+		return false
+	}
+	infos, err := store.List(context.Background())
+	return err == nil && len(infos) == 0
+}
+
+// providerErrorHint suggests what to do about a classified provider error,
+// or returns "" when err has no specific guidance.
+func providerErrorHint(err error) string {
+	switch {
+	case errors.Is(err, llm.ErrContextTooLong):
+		return "The conversation no longer fits the model's context window. Run /compact to summarize older messages, or /new to start over."
+	case errors.Is(err, llm.ErrRateLimited):
+		return "The provider is rate limiting requests. Wait a moment, then /retry."
+	case errors.Is(err, llm.ErrOverloaded):
+		return "The provider is temporarily overloaded. Try again shortly with /retry."
+	case errors.Is(err, llm.ErrAuthFailed):
+		return "The provider rejected the API key. Check the key in your config or environment."
+	case errors.Is(err, llm.ErrMissingAPIKey):
+		return "No API key is configured. Set it in your config or environment."
+	default:
+		return ""
+	}
+}
+
+// flushThinkingBuffer shows buffered thinking as a dimmed chat item.
+func (m *App) flushThinkingBuffer() {
+	text := strings.TrimSpace(m.thinkingBuffer.String())
 	if text != "" {
-		m.chat.Append("assistant", text)
+		m.chat.Append("thinking", text)
+	}
+	m.thinkingBuffer.Reset()
+}
+
+// flushAssistantBuffer finalizes the streamed assistant reply shown by text deltas.
+func (m *App) flushAssistantBuffer() {
+	m.flushThinkingBuffer()
+	m.chat.StreamMessage("assistant", m.assistantBuffer.String())
+	m.chat.FinishStream()
+	if m.session != nil && strings.TrimSpace(m.assistantBuffer.String()) != "" {
+		m.chat.TagModel(m.session.Model())
 	}
 	m.assistantBuffer.Reset()
 }
@@ -585,7 +1108,8 @@ func (m *App) rebuildChatFromSession() {
 		return
 	}
 	m.chat.Clear()
-	for _, message := range m.session.Messages() {
+	messages, models := m.session.MessagesWithModels()
+	for i, message := range messages {
 		switch message.Role {
 		case llm.RoleUser:
 			text := strings.TrimSpace(messageText(message))
@@ -596,18 +1120,16 @@ func (m *App) rebuildChatFromSession() {
 			text := strings.TrimSpace(messageText(message))
 			if text != "" {
 				m.chat.Append("assistant", text)
+				m.chat.TagModel(models[i])
 			}
 		case llm.RoleTool:
 			if message.ToolResult == nil {
 				continue
 			}
-			content := strings.TrimSpace(message.ToolResult.Content)
-			if content == "" {
-				content = "(empty)"
-			}
-			m.chat.Append("tool", fmt.Sprintf("%s: %s", message.ToolResult.ToolName, content))
+			m.chat.AppendToolResult(*message.ToolResult)
 		}
 	}
+	m.inspector.LoadMetrics(m.session.Metrics())
 }
 
 func (m *App) refreshSessionStatus() {
@@ -615,6 +1137,8 @@ func (m *App) refreshSessionStatus() {
 		return
 	}
 	m.status.SessionID = strings.TrimSpace(m.session.SessionID())
+	m.status.ModelName = strings.TrimSpace(m.session.Model())
+	m.status.PlanMode = m.session.PlanMode()
 }
 
 func (m *App) renderBody(width int) string {
@@ -623,27 +1147,7 @@ func (m *App) renderBody(width int) string {
 		return m.renderSelectorBody(width)
 	}
 
-	if !m.showInspector {
-		return m.chat.Render(width, m.theme)
-	}
-
-	inspectorWidth := defaultInspectorWidth
-	if width/3 < inspectorWidth {
-		inspectorWidth = width / 3
-	}
-	if inspectorWidth < minimumInspectorVisible {
-		inspectorWidth = minimumInspectorVisible
-	}
-
-	chatWidth := width - inspectorWidth - 1
-	if chatWidth < minimumChatPanelWidth {
-		chatWidth = minimumChatPanelWidth
-		inspectorWidth = width - chatWidth - 1
-		if inspectorWidth < 0 {
-			inspectorWidth = 0
-		}
-	}
-
+	chatWidth, inspectorWidth := m.splitBodyWidth(width)
 	chatView := m.chat.Render(chatWidth, m.theme)
 	if inspectorWidth <= 0 {
 		return chatView
@@ -654,29 +1158,8 @@ func (m *App) renderBody(width int) string {
 }
 
 func (m *App) renderSelectorBody(width int) string {
-	selectorView := m.renderSelectorPanel(width)
-	if !m.showInspector {
-		return selectorView
-	}
-
-	inspectorWidth := defaultInspectorWidth
-	if width/3 < inspectorWidth {
-		inspectorWidth = width / 3
-	}
-	if inspectorWidth < minimumInspectorVisible {
-		inspectorWidth = minimumInspectorVisible
-	}
-
-	selectorWidth := width - inspectorWidth - 1
-	if selectorWidth < minimumChatPanelWidth {
-		selectorWidth = minimumChatPanelWidth
-		inspectorWidth = width - selectorWidth - 1
-		if inspectorWidth < 0 {
-			inspectorWidth = 0
-		}
-	}
-
-	selectorView = m.renderSelectorPanel(selectorWidth)
+	selectorWidth, inspectorWidth := m.splitBodyWidth(width)
+	selectorView := m.renderSelectorPanel(selectorWidth)
 	if inspectorWidth <= 0 {
 		return selectorView
 	}
@@ -684,6 +1167,22 @@ func (m *App) renderSelectorBody(width int) string {
 	return lipgloss.JoinHorizontal(lipgloss.Top, selectorView, inspectorView)
 }
 
+// splitBodyWidth divides width between the main panel and the inspector,
+// leaving a one-column gap. The inspector is hidden, returning zero for it,
+// when it is off or both panels cannot get their minimum widths.
+func (m *App) splitBodyWidth(width int) (mainWidth, inspectorWidth int) {
+	if !m.showInspector || width < minimumChatPanelWidth+1+minimumInspectorVisible {
+		return width, 0
+	}
+	inspectorWidth = max(min(defaultInspectorWidth, width/3), minimumInspectorVisible)
+	mainWidth = width - inspectorWidth - 1
+	if mainWidth < minimumChatPanelWidth {
+		mainWidth = minimumChatPanelWidth
+		inspectorWidth = width - mainWidth - 1
+	}
+	return mainWidth, inspectorWidth
+}
+
 func (m *App) renderSelectorPanel(width int) string {
 	if m.selector == nil || len(m.selector.Items) == 0 {
 		return renderPanel(width, m.theme.PanelStyle, "No selectable items.")
@@ -701,31 +1200,84 @@ func (m *App) renderSelectorPanel(width int) string {
 	return renderPanel(width, m.theme.PanelStyle, strings.Join(lines, "\n"))
 }
 
+// completeSlashCommand completes a "/name" prefix in the input to a
+// registered command. Repeated Tab presses cycle through the matches in
+// registry order.
+func (m *App) completeSlashCommand() bool {
+	value := m.input.Value()
+	if !strings.HasPrefix(value, "/") || strings.ContainsAny(value, " \t\n") {
+		return false
+	}
+	if n := len(m.completions); n > 0 && value == "/"+m.completions[m.completionIndex] {
+		m.completionIndex = (m.completionIndex + 1) % n
+	} else {
+		m.completions = m.commands.Complete(value)
+		m.completionIndex = 0
+	}
+	if len(m.completions) > 0 {
+		m.input.SetValue("/" + m.completions[m.completionIndex])
+	}
+	return true
+}
+
+// handleChatScrollKey scrolls the chat viewport. Scroll keys only apply while
+// the input is empty, so keys remapped onto printable characters, or onto
+// cursor movement, still edit text once typing has started.
 func (m *App) handleChatScrollKey(msg tea.KeyMsg) bool {
-	switch msg.Type {
-	case tea.KeyUp:
+	if m.input.Value() != "" {
+		return false
+	}
+	switch {
+	case m.keys.Matches(msg, KeyActionScrollUp):
 		m.chat.ScrollUp(1)
-		return true
-	case tea.KeyDown:
+	case m.keys.Matches(msg, KeyActionScrollDown):
 		m.chat.ScrollDown(1)
-		return true
-	case tea.KeyPgUp:
+	case m.keys.Matches(msg, KeyActionPageUp):
 		m.chat.PageUp()
-		return true
-	case tea.KeyPgDown:
+	case m.keys.Matches(msg, KeyActionPageDown):
 		m.chat.PageDown()
-		return true
-	case tea.KeyHome:
+	case m.keys.Matches(msg, KeyActionScrollTop):
 		m.chat.ScrollToTop()
-		return true
-	case tea.KeyEnd:
+	case m.keys.Matches(msg, KeyActionScrollBottom):
 		m.chat.ScrollToBottom()
+	default:
+		return false
+	}
+	return true
+}
+
+// handleChatScrollMouse scrolls the chat viewport on wheel events.
+func (m *App) handleChatScrollMouse(msg tea.MouseMsg) bool {
+	if msg.Action != tea.MouseActionPress {
+		return false
+	}
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		m.chat.ScrollUp(mouseWheelScrollLines)
+		return true
+	case tea.MouseButtonWheelDown:
+		m.chat.ScrollDown(mouseWheelScrollLines)
 		return true
 	default:
 		return false
 	}
 }
 
+// handleSelectorMouse moves the selector cursor on wheel events.
+func (m *App) handleSelectorMouse(msg tea.MouseMsg) tea.Cmd {
+	if msg.Action != tea.MouseActionPress {
+		return nil
+	}
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		return m.handleSelectorKey(tea.KeyMsg{Type: tea.KeyUp})
+	case tea.MouseButtonWheelDown:
+		return m.handleSelectorKey(tea.KeyMsg{Type: tea.KeyDown})
+	default:
+		return nil
+	}
+}
+
 func (m *App) chatViewportHeight() int {
 	if m.height <= 0 {
 		return 0
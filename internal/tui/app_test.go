@@ -2,16 +2,20 @@ package tui
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"gar/internal/llm"
 	sessionstore "gar/internal/session"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
 )
 
 type fakeRunner struct {
@@ -20,6 +24,7 @@ type fakeRunner struct {
 	streamFn func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error)
 	steering []llm.Message
 	followUp []llm.Message
+	onCancel func()
 }
 
 func (r *fakeRunner) Run(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
@@ -41,6 +46,31 @@ func (r *fakeRunner) ClearAllQueues() {
 	r.followUp = nil
 }
 
+func (r *fakeRunner) Cancel() {
+	if r.onCancel != nil {
+		r.onCancel()
+	}
+}
+
+// runCommands runs cmd and the commands its messages produce until none
+// remain. Batches are expanded and status spinner ticks are dropped so the
+// loop ends once the stream is drained.
+func runCommands(app *App, cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		for _, next := range msg {
+			runCommands(app, next)
+		}
+	case statusTickMsg:
+	default:
+		_, next := app.Update(msg)
+		runCommands(app, next)
+	}
+}
+
 func TestInputModelHandleKey(t *testing.T) {
 	t.Parallel()
 
@@ -67,6 +97,87 @@ func TestInputModelHandleKey(t *testing.T) {
 	}
 }
 
+func TestAppHomeEndEditInputWhenNonEmpty(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{})
+	app.chat.SetViewportHeight(1)
+	app.chat.Append("user", "one")
+	app.chat.Append("user", "two")
+
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyHome})
+	if app.chat.scrollTop != 0 {
+		t.Fatalf("scrollTop = %d, want Home to scroll chat while input is empty", app.chat.scrollTop)
+	}
+
+	app.input.SetValue("draft")
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyEnd})
+	if app.chat.scrollTop != 0 {
+		t.Fatalf("scrollTop = %d, want End to stay in the input", app.chat.scrollTop)
+	}
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyHome})
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a ")})
+	if got := app.input.Value(); got != "a draft" {
+		t.Fatalf("input value = %q, want text inserted at start", got)
+	}
+}
+
+func TestAppPrintableScrollKeyTypesOnceInputHasText(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{KeyBindings: map[string][]string{"scroll_up": {"k"}}})
+	app.chat.SetViewportHeight(1)
+	app.chat.Append("user", "one")
+	app.chat.Append("user", "two")
+	bottom := app.chat.scrollTop
+
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")})
+	if app.chat.scrollTop != bottom-1 {
+		t.Fatalf("scrollTop = %d, want k to scroll while input is empty", app.chat.scrollTop)
+	}
+
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")})
+	if got := app.input.Value(); got != "ok" {
+		t.Fatalf("input value = %q, want k typed into the draft", got)
+	}
+	if app.chat.scrollTop != bottom-1 {
+		t.Fatalf("scrollTop = %d, want k to stop scrolling once typing starts", app.chat.scrollTop)
+	}
+}
+
+func TestAppTabCompletesSlashCommands(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{})
+	tab := tea.KeyMsg{Type: tea.KeyTab}
+
+	app.input.SetValue("/re")
+	_, _ = app.Update(tab)
+	if got := app.input.Value(); got != "/resume" {
+		t.Fatalf("input after Tab = %q, want /resume", got)
+	}
+	_, _ = app.Update(tab)
+	if got := app.input.Value(); got != "/retry" {
+		t.Fatalf("input after second Tab = %q, want /retry", got)
+	}
+	_, _ = app.Update(tab)
+	if got := app.input.Value(); got != "/resume" {
+		t.Fatalf("input after third Tab = %q, want cycle back to /resume", got)
+	}
+
+	app.input.SetValue("/resume latest")
+	_, _ = app.Update(tab)
+	if got := app.input.Value(); got != "/resume latest" {
+		t.Fatalf("input = %q, want arguments left alone", got)
+	}
+	app.input.SetValue("/zzz")
+	_, _ = app.Update(tab)
+	if got := app.input.Value(); got != "/zzz" {
+		t.Fatalf("input = %q, want unchanged without matches", got)
+	}
+}
+
 func TestAppFlushesAssistantOnDoneEvent(t *testing.T) {
 	t.Parallel()
 
@@ -97,6 +208,80 @@ func TestAppFlushesAssistantOnDoneEvent(t *testing.T) {
 	}
 }
 
+func TestAppShowsContextWarningInStatus(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{})
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{
+		Type:           llm.EventContextWarning,
+		ContextWarning: &llm.ContextWarning{EstimatedTokens: 185000, ContextWindow: 200000},
+	}})
+	if got := app.status.Warning; got != "context ~92% (185000/200000 tokens)" {
+		t.Fatalf("status.Warning = %q, want context usage", got)
+	}
+	if view := app.status.Render(200, app.theme); !strings.Contains(view, "warning: context ~92%") {
+		t.Fatalf("status.Render() = %q, want warning", view)
+	}
+}
+
+func TestAppStreamsAssistantDeltasBeforeDone(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{})
+
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventTextDelta, TextDelta: "hel"}})
+	messages := app.chat.Messages()
+	if len(messages) != 1 || messages[0].Role != "assistant" || messages[0].Content != "hel" {
+		t.Fatalf("messages after first delta = %#v, want live assistant hel", messages)
+	}
+
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventTextDelta, TextDelta: "lo world"}})
+	messages = app.chat.Messages()
+	if len(messages) != 1 || messages[0].Content != "hello world" {
+		t.Fatalf("messages after second delta = %#v, want live assistant hello world", messages)
+	}
+
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}})
+	messages = app.chat.Messages()
+	if len(messages) != 1 || messages[0].Content != "hello world" {
+		t.Fatalf("messages after done = %#v, want single final assistant message", messages)
+	}
+
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventTextDelta, TextDelta: "next"}})
+	messages = app.chat.Messages()
+	if len(messages) != 2 || messages[1].Content != "next" {
+		t.Fatalf("messages after new reply = %#v, want a second assistant message", messages)
+	}
+}
+
+func TestAppShowThinkingRendersDimmedThinking(t *testing.T) {
+	t.Parallel()
+
+	for _, show := range []bool{true, false} {
+		app := NewApp(AppConfig{ShowThinking: show})
+		for _, ev := range []llm.Event{
+			{Type: llm.EventContentBlockStart, ContentBlockStart: &llm.ContentBlockStart{Type: "thinking"}},
+			{Type: llm.EventThinkingDelta, ThinkingDelta: "pondering"},
+			{Type: llm.EventTextDelta, TextDelta: "answer"},
+			{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}},
+		} {
+			_, _ = app.Update(StreamEventMsg{Event: ev})
+		}
+
+		var roles []string
+		for _, message := range app.chat.Messages() {
+			roles = append(roles, message.Role)
+		}
+		want := "assistant"
+		if show {
+			want = "thinking,assistant"
+		}
+		if got := strings.Join(roles, ","); got != want {
+			t.Fatalf("ShowThinking=%v chat roles = %q, want %q", show, got, want)
+		}
+	}
+}
+
 func TestAppTracksToolCallInInspector(t *testing.T) {
 	t.Parallel()
 
@@ -117,6 +302,243 @@ func TestAppTracksToolCallInInspector(t *testing.T) {
 	}
 }
 
+func TestAppReplacesToolProgressWithResult(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{})
+	call := &llm.ToolCall{ID: "call-1", Name: "bash"}
+	for _, delta := range []string{"one\n", "two\n"} {
+		_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventToolProgress, ToolCall: call, ToolOutputDelta: delta}})
+	}
+	messages := app.chat.Messages()
+	if len(messages) != 1 || messages[0].Content != "bash: one\ntwo" {
+		t.Fatalf("messages during progress = %#v, want one live tool message", messages)
+	}
+
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{
+		Type:       llm.EventToolResult,
+		ToolResult: &llm.ToolResult{ToolCallID: "call-1", ToolName: "bash", Content: "one\ntwo"},
+	}})
+	messages = app.chat.Messages()
+	if len(messages) != 1 || messages[0].Content != "bash: one\ntwo" || messages[0].Tool == nil {
+		t.Fatalf("messages after result = %#v, want the result in place of progress", messages)
+	}
+}
+
+func TestAppKeepsInterleavedToolProgressApart(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{})
+	first := &llm.ToolCall{ID: "call-1", Name: "bash"}
+	second := &llm.ToolCall{ID: "call-2", Name: "bash"}
+	for _, ev := range []struct {
+		call  *llm.ToolCall
+		delta string
+	}{
+		{first, "a1\n"},
+		{second, "b1\n"},
+		{first, "a2\n"},
+		{second, "b2\n"},
+	} {
+		_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventToolProgress, ToolCall: ev.call, ToolOutputDelta: ev.delta}})
+	}
+	messages := app.chat.Messages()
+	if len(messages) != 2 || messages[0].Content != "bash: a1\na2" || messages[1].Content != "bash: b1\nb2" {
+		t.Fatalf("messages during progress = %#v, want one live message per call", messages)
+	}
+
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{
+		Type:       llm.EventToolResult,
+		ToolResult: &llm.ToolResult{ToolCallID: "call-1", ToolName: "bash", Content: "first done"},
+	}})
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventToolProgress, ToolCall: second, ToolOutputDelta: "b3\n"}})
+	messages = app.chat.Messages()
+	if len(messages) != 2 || messages[0].Content != "bash: first done" || messages[0].Tool == nil || messages[1].Content != "bash: b1\nb2\nb3" {
+		t.Fatalf("messages after first result = %#v, want result in place and second still live", messages)
+	}
+}
+
+type fakeApprover struct {
+	approved []string
+	denied   []string
+}
+
+func (a *fakeApprover) Approve(callID string) error {
+	a.approved = append(a.approved, callID)
+	return nil
+}
+
+func (a *fakeApprover) Deny(callID string) error {
+	a.denied = append(a.denied, callID)
+	return nil
+}
+
+func TestAppToolApprovalKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		key          tea.KeyMsg
+		wantApproved int
+		wantDenied   int
+		wantState    string
+	}{
+		{name: "enter approves", key: tea.KeyMsg{Type: tea.KeyEnter}, wantApproved: 1, wantState: "tool_executing"},
+		{name: "esc denies", key: tea.KeyMsg{Type: tea.KeyEsc}, wantDenied: 1, wantState: "streaming"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			approver := &fakeApprover{}
+			app := NewApp(AppConfig{ShowInspector: true})
+			app.approver = approver
+
+			_, _ = app.Update(StreamEventMsg{Event: llm.Event{
+				Type:     llm.EventToolApprovalRequired,
+				ToolCall: &llm.ToolCall{ID: "call-1", Name: "bash", Arguments: []byte(`{"label":"List files","command":"ls -la"}`)},
+			}})
+			if got := app.status.State; got != "awaiting_approval" {
+				t.Fatalf("status state = %q, want awaiting_approval", got)
+			}
+			messages := app.chat.Messages()
+			if len(messages) == 0 || !strings.Contains(messages[len(messages)-1].Content, "Approve bash: List files?\n$ ls -la") {
+				t.Fatalf("chat messages = %#v, want approval prompt with label and command", messages)
+			}
+
+			_, _ = app.Update(tc.key)
+			if len(approver.approved) != tc.wantApproved || len(approver.denied) != tc.wantDenied {
+				t.Fatalf("approved = %v denied = %v, want %d/%d", approver.approved, approver.denied, tc.wantApproved, tc.wantDenied)
+			}
+			if app.pendingApproval != nil {
+				t.Fatalf("pendingApproval = %#v, want nil", app.pendingApproval)
+			}
+			if got := app.status.State; got != tc.wantState {
+				t.Fatalf("status state = %q, want %q", got, tc.wantState)
+			}
+		})
+	}
+}
+
+func TestAppEscCancelsActiveStream(t *testing.T) {
+	t.Parallel()
+
+	stream := make(chan llm.Event, 2)
+	runner := &fakeRunner{
+		streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			_ = req
+			return stream, nil
+		},
+	}
+	runner.onCancel = func() {
+		stream <- llm.Event{
+			Type: llm.EventError,
+			Done: &llm.DonePayload{Reason: llm.StopReasonAborted},
+			Err:  context.Canceled,
+		}
+		close(stream)
+	}
+
+	app := NewApp(AppConfig{ShowInspector: true, Runner: runner, MaxTokens: 64})
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("go")})
+	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || app.activeStream == nil || app.status.State != "streaming" {
+		t.Fatalf("expected active stream after submit, state=%q", app.status.State)
+	}
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventTextDelta, TextDelta: "partial"}})
+
+	_, cmd = app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if app.activeStream != nil {
+		t.Fatalf("activeStream should be cleared after Esc")
+	}
+	if got := app.status.State; got != "idle" {
+		t.Fatalf("status state = %q, want idle", got)
+	}
+	if got := app.inspector.State; got != "idle" {
+		t.Fatalf("inspector state = %q, want idle", got)
+	}
+	messages := app.chat.Messages()
+	if last := messages[len(messages)-1]; last.Content != "Run cancelled." {
+		t.Fatalf("last message = %#v, want Run cancelled.", last)
+	}
+
+	if cmd == nil {
+		t.Fatalf("expected drain command after Esc")
+	}
+	_, _ = app.Update(cmd())
+	if app.drainingStream {
+		t.Fatalf("drainingStream should be false after drain")
+	}
+	if got := app.status.State; got != "idle" {
+		t.Fatalf("status state after drain = %q, want idle", got)
+	}
+
+	runner.streamFn = func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+		_ = ctx
+		_ = req
+		out := make(chan llm.Event, 1)
+		out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}
+		close(out)
+		return out, nil
+	}
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("again")})
+	if _, cmd = app.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+		t.Fatalf("expected submit after cancel to start a new run")
+	}
+	if runner.calls != 2 {
+		t.Fatalf("runner calls = %d, want 2", runner.calls)
+	}
+}
+
+func TestAppIgnoresReadsQueuedFromCancelledStream(t *testing.T) {
+	t.Parallel()
+
+	first := make(chan llm.Event, 1)
+	second := make(chan llm.Event)
+	streams := []chan llm.Event{first, second}
+	runner := &fakeRunner{
+		streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			_ = req
+			next := streams[0]
+			streams = streams[1:]
+			return next, nil
+		},
+	}
+	runner.onCancel = func() { close(first) }
+
+	app := NewApp(AppConfig{Runner: runner, MaxTokens: 64})
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("go")})
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	cancelledGen := app.streamGen
+
+	_, drain := app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	// A read queued before Esc returns after the cancel.
+	_, _ = app.Update(streamReadMsg{Gen: cancelledGen, Event: llm.Event{Type: llm.EventTextDelta, TextDelta: "stale"}})
+	_, _ = app.Update(drain())
+	if app.drainingStream {
+		t.Fatal("drainingStream should be false after drain")
+	}
+
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("again")})
+	if _, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil || app.activeStream == nil {
+		t.Fatal("expected a new run after the drain")
+	}
+	_, _ = app.Update(streamReadMsg{Gen: cancelledGen, Event: llm.Event{Type: llm.EventTextDelta, TextDelta: "late"}})
+	_, _ = app.Update(streamReadMsg{Gen: cancelledGen, Closed: true})
+	if app.activeStream == nil || app.status.State != "streaming" {
+		t.Fatalf("stale close ended the new run: state=%q", app.status.State)
+	}
+	for _, msg := range app.chat.Messages() {
+		if strings.Contains(msg.Content, "stale") || strings.Contains(msg.Content, "late") {
+			t.Fatalf("chat message = %#v, want stale reads ignored", msg)
+		}
+	}
+}
+
 func TestAppSubmitRunsRunnerAndRendersAssistantReply(t *testing.T) {
 	t.Parallel()
 
@@ -150,10 +572,7 @@ func TestAppSubmitRunsRunnerAndRendersAssistantReply(t *testing.T) {
 	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("h")})
 	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")})
 	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
-	for cmd != nil {
-		msg := cmd()
-		_, cmd = app.Update(msg)
-	}
+	runCommands(app, cmd)
 
 	if runner.calls != 1 {
 		t.Fatalf("runner calls = %d, want 1", runner.calls)
@@ -191,6 +610,42 @@ func TestAppSubmitRunsRunnerAndRendersAssistantReply(t *testing.T) {
 	}
 }
 
+func TestAppSlashClearKeepsSessionMessages(t *testing.T) {
+	t.Parallel()
+
+	runner := &fakeRunner{
+		streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			_ = req
+			out := make(chan llm.Event, 2)
+			out <- llm.Event{Type: llm.EventTextDelta, TextDelta: "hello"}
+			out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}
+			close(out)
+			return out, nil
+		},
+	}
+	app := NewApp(AppConfig{ModelName: "claude-sonnet-4-20250514", Runner: runner, MaxTokens: 64})
+
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("hi")})
+	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	runCommands(app, cmd)
+	before := app.session.Messages()
+	if len(before) != 2 {
+		t.Fatalf("session messages = %d, want 2", len(before))
+	}
+
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/clear")})
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	messages := app.chat.Messages()
+	if len(messages) != 1 || !strings.Contains(messages[0].Content, "Chat cleared") {
+		t.Fatalf("chat messages = %#v, want only the /clear confirmation", messages)
+	}
+	if after := app.session.Messages(); !reflect.DeepEqual(after, before) {
+		t.Fatalf("session messages after /clear = %#v, want %#v", after, before)
+	}
+}
+
 func TestAppSubmitShowsRunError(t *testing.T) {
 	t.Parallel()
 
@@ -274,12 +729,7 @@ func TestAppSubmitWhileBusyQueuesSteeringMessage(t *testing.T) {
 	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyEnter})
 	close(block)
 
-	msg := cmd()
-	_, cmd = app.Update(msg)
-	for cmd != nil {
-		msg = cmd()
-		_, cmd = app.Update(msg)
-	}
+	runCommands(app, cmd)
 
 	if runner.calls != 1 {
 		t.Fatalf("runner calls = %d, want 1", runner.calls)
@@ -319,6 +769,22 @@ func TestAppConsumeEventErrorWithoutErrorValue(t *testing.T) {
 	}
 }
 
+func TestAppErrorEventSuggestsCompactOnContextOverflow(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{})
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{
+		Type: llm.EventError,
+		Done: &llm.DonePayload{Reason: llm.StopReasonError},
+		Err:  fmt.Errorf("anthropic stream: %w", llm.ErrContextTooLong),
+	}})
+
+	messages := app.chat.Messages()
+	if len(messages) != 1 || !strings.Contains(messages[0].Content, "/compact") {
+		t.Fatalf("messages = %#v, want error with /compact guidance", messages)
+	}
+}
+
 func TestAppDoneToolUseDoesNotTerminateActiveStream(t *testing.T) {
 	t.Parallel()
 
@@ -373,6 +839,83 @@ func TestAppArrowKeysScrollChat(t *testing.T) {
 	}
 }
 
+func TestAppViewDegradesGracefullyInTinyTerminals(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{ShowInspector: true})
+	for i := 1; i <= 5; i++ {
+		app.chat.Append("user", fmt.Sprintf("message %d with enough words to wrap in a narrow panel", i))
+	}
+	app.selector = &selectorState{Title: "Select Session", Items: []selectorItem{{Value: "a", Label: "a session label"}}}
+
+	for _, selector := range []*selectorState{app.selector, nil} {
+		app.selector = selector
+		for width := 1; width <= 70; width++ {
+			for height := 1; height <= 8; height++ {
+				_, _ = app.Update(tea.WindowSizeMsg{Width: width, Height: height})
+				view := app.View()
+				small := width < minimumAppWidth || height < minimumAppHeight
+				if small != strings.Contains(view, "Terminal too small") && width >= len("Terminal too small") {
+					t.Fatalf("View() at %dx%d = %q, want notice only below %dx%d", width, height, view, minimumAppWidth, minimumAppHeight)
+				}
+				lines := strings.Split(view, "\n")
+				if small {
+					if len(lines) > height {
+						t.Fatalf("View() at %dx%d has %d lines, want at most %d", width, height, len(lines), height)
+					}
+					for _, line := range lines {
+						if ansi.StringWidth(line) > width {
+							t.Fatalf("View() at %dx%d has line %q wider than the terminal", width, height, line)
+						}
+					}
+					continue
+				}
+				mainWidth, inspectorWidth := app.splitBodyWidth(width)
+				if mainWidth <= 0 || inspectorWidth < 0 || (inspectorWidth > 0 && mainWidth+1+inspectorWidth != width) {
+					t.Fatalf("splitBodyWidth(%d) = %d, %d; want positive panels filling the width", width, mainWidth, inspectorWidth)
+				}
+			}
+		}
+	}
+
+	_, _ = app.Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+	view := app.View()
+	if strings.Contains(view, "Terminal too small") || !strings.Contains(view, "message 5") {
+		t.Fatalf("View() after resizing back = %q, want normal layout", view)
+	}
+}
+
+func TestAppMouseWheelScrollsChat(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{ShowInspector: false})
+	_, _ = app.Update(tea.WindowSizeMsg{Width: 100, Height: 8})
+	for i := 1; i <= 12; i++ {
+		app.chat.Append("user", fmt.Sprintf("line-%d", i))
+	}
+
+	_ = app.View() // primes viewport sizing
+	initialTop := app.chat.scrollTop
+	if initialTop < 3 {
+		t.Fatalf("expected initial scrollTop >= 3 with overflowing chat, got %d", initialTop)
+	}
+
+	_, _ = app.Update(tea.MouseMsg{Button: tea.MouseButtonWheelUp, Action: tea.MouseActionPress})
+	if app.chat.scrollTop != initialTop-3 {
+		t.Fatalf("scrollTop after wheel up = %d, want %d", app.chat.scrollTop, initialTop-3)
+	}
+
+	_, _ = app.Update(tea.MouseMsg{Button: tea.MouseButtonLeft, Action: tea.MouseActionPress})
+	if app.chat.scrollTop != initialTop-3 {
+		t.Fatalf("scrollTop after click = %d, want %d", app.chat.scrollTop, initialTop-3)
+	}
+
+	_, _ = app.Update(tea.MouseMsg{Button: tea.MouseButtonWheelDown, Action: tea.MouseActionPress})
+	if app.chat.scrollTop != initialTop {
+		t.Fatalf("scrollTop after wheel down = %d, want %d", app.chat.scrollTop, initialTop)
+	}
+}
+
 func TestAppSlashHelpShowsCommands(t *testing.T) {
 	t.Parallel()
 
@@ -411,6 +954,103 @@ func TestAppSlashHelpShowsCommands(t *testing.T) {
 	}
 }
 
+func TestAppSlashSummaryShowsReplyWithoutCompacting(t *testing.T) {
+	t.Parallel()
+
+	runner := &fakeRunner{
+		streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			_ = ctx
+			reply := "hi there"
+			if len(req.Tools) == 0 && strings.Contains(req.System, "summarize") {
+				reply = "- greeted the user"
+			}
+			out := make(chan llm.Event, 2)
+			out <- llm.Event{Type: llm.EventTextDelta, TextDelta: reply}
+			out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}
+			close(out)
+			return out, nil
+		},
+	}
+	app := NewApp(AppConfig{
+		ModelName: "claude-sonnet-4-20250514",
+		Runner:    runner,
+		Tools:     []llm.ToolSpec{{Name: "read"}},
+		MaxTokens: 64,
+	})
+
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("hello")})
+	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	runCommands(app, cmd)
+	entriesBefore := len(app.session.Entries())
+
+	app.input.SetValue("/summary")
+	_, cmd = app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	runCommands(app, cmd)
+
+	messages := app.chat.Messages()
+	last := messages[len(messages)-1]
+	if last.Role != "assistant" || last.Content != "- greeted the user" {
+		t.Fatalf("last message = %#v, want the summary as an assistant message", last)
+	}
+	entries := app.session.Entries()
+	if len(entries) != entriesBefore {
+		t.Fatalf("entries = %d, want %d: /summary must not change history", len(entries), entriesBefore)
+	}
+	for _, entry := range entries {
+		if entry.Type == "compaction" {
+			t.Fatalf("entries contain a compaction entry: %#v", entry)
+		}
+	}
+	if runner.calls != 2 {
+		t.Fatalf("runner calls = %d, want the reused runner called for the summary", runner.calls)
+	}
+}
+
+func TestAppSummaryRunsInBackgroundAndEscCancelsIt(t *testing.T) {
+	t.Parallel()
+
+	summarizing := make(chan struct{})
+	runner := &fakeRunner{
+		streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+			out := make(chan llm.Event, 2)
+			if strings.Contains(req.System, "summarize") {
+				close(summarizing)
+				go func() {
+					defer close(out)
+					<-ctx.Done()
+					out <- llm.Event{Type: llm.EventError, Err: ctx.Err()}
+				}()
+				return out, nil
+			}
+			out <- llm.Event{Type: llm.EventTextDelta, TextDelta: "hi"}
+			out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}
+			close(out)
+			return out, nil
+		},
+	}
+	app := NewApp(AppConfig{Runner: runner, MaxTokens: 64})
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("hello")})
+	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	runCommands(app, cmd)
+
+	app.input.SetValue("/summary")
+	_, cmd = app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || app.status.State != "summarizing" {
+		t.Fatalf("/summary cmd = %v, state = %q; want a background task", cmd, app.status.State)
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	<-summarizing
+
+	// The UI keeps handling keys while the model call runs.
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	_, _ = app.Update(<-done)
+	messages := app.chat.Messages()
+	if last := messages[len(messages)-1]; last.Content != "Cancelled." || app.status.State != "idle" {
+		t.Fatalf("last message = %#v, state = %q; want the summary cancelled", last, app.status.State)
+	}
+}
+
 func TestAppSlashQueueShowsQueuedMessages(t *testing.T) {
 	t.Parallel()
 
@@ -456,7 +1096,7 @@ func TestAppSlashQueueShowsQueuedMessages(t *testing.T) {
 	messages := app.chat.Messages()
 	foundQueue := false
 	for _, message := range messages {
-		if message.Role == "assistant" && strings.Contains(message.Content, "- steer: b") {
+		if message.Role == "assistant" && strings.Contains(message.Content, "1. steer: b") {
 			foundQueue = true
 			break
 		}
@@ -469,12 +1109,7 @@ func TestAppSlashQueueShowsQueuedMessages(t *testing.T) {
 	}
 
 	close(block)
-	msg := cmd()
-	_, cmd = app.Update(msg)
-	for cmd != nil {
-		msg = cmd()
-		_, cmd = app.Update(msg)
-	}
+	runCommands(app, cmd)
 }
 
 func TestAppSlashNameAndSession(t *testing.T) {
@@ -556,10 +1191,7 @@ func TestAppSlashNewAndResume(t *testing.T) {
 
 	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
 	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
-	for cmd != nil {
-		msg := cmd()
-		_, cmd = app.Update(msg)
-	}
+	runCommands(app, cmd)
 
 	for _, text := range []string{"/new", "/resume resume-a"} {
 		for _, r := range []rune(text) {
@@ -618,10 +1250,7 @@ func TestAppResumeSelectorKeyboardSwitchesSession(t *testing.T) {
 
 	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
 	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
-	for cmd != nil {
-		msg := cmd()
-		_, cmd = app.Update(msg)
-	}
+	runCommands(app, cmd)
 
 	for _, text := range []string{"/new", "/resume"} {
 		for _, r := range []rune(text) {
@@ -668,10 +1297,7 @@ func TestAppTreeSelectorKeyboardSwitchesLeaf(t *testing.T) {
 			_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
 		}
 		_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
-		for cmd != nil {
-			msg := cmd()
-			_, cmd = app.Update(msg)
-		}
+		runCommands(app, cmd)
 	}
 
 	for _, text := range []string{"/branch 000001", "u1b"} {
@@ -679,10 +1305,7 @@ func TestAppTreeSelectorKeyboardSwitchesLeaf(t *testing.T) {
 			_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
 		}
 		_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
-		for cmd != nil {
-			msg := cmd()
-			_, cmd = app.Update(msg)
-		}
+		runCommands(app, cmd)
 	}
 
 	if got := app.session.LeafID(); got != "000004" {
@@ -700,3 +1323,197 @@ func TestAppTreeSelectorKeyboardSwitchesLeaf(t *testing.T) {
 		t.Fatalf("leaf after tree selector = %q, want 000003", got)
 	}
 }
+
+func TestAppInspectorReportsElapsedAndThroughput(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{ShowInspector: true})
+	clock := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	app.now = func() time.Time { return clock }
+
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventStart}})
+	clock = clock.Add(time.Second)
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventUsage, Usage: &llm.Usage{InputTokens: 5, OutputTokens: 10}}})
+	if app.inspector.Elapsed != time.Second || app.inspector.TokensPerSecond != 10 {
+		t.Fatalf("mid-turn elapsed=%s rate=%.1f, want 1s and 10 tok/s", app.inspector.Elapsed, app.inspector.TokensPerSecond)
+	}
+
+	clock = clock.Add(time.Second)
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{
+		Reason: llm.StopReasonStop,
+		Usage:  llm.Usage{InputTokens: 5, OutputTokens: 40},
+	}}})
+	if app.inspector.Elapsed != 2*time.Second || app.inspector.TokensPerSecond != 20 {
+		t.Fatalf("final elapsed=%s rate=%.1f, want 2s and 20 tok/s", app.inspector.Elapsed, app.inspector.TokensPerSecond)
+	}
+	if rendered := app.inspector.Render(40, app.theme); !strings.Contains(rendered, "Elapsed: 2s (20.0 tok/s)") {
+		t.Fatalf("inspector render = %q, want elapsed and rate", rendered)
+	}
+
+	clock = clock.Add(time.Minute)
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventUsage, Usage: &llm.Usage{OutputTokens: 99}}})
+	if app.inspector.Elapsed != 2*time.Second {
+		t.Fatalf("elapsed after done = %s, want frozen at 2s", app.inspector.Elapsed)
+	}
+
+	_, _ = app.Update(StreamEventMsg{Event: llm.Event{Type: llm.EventStart}})
+	if app.inspector.Elapsed != 0 || app.inspector.TokensPerSecond != 0 {
+		t.Fatalf("new turn elapsed=%s rate=%.1f, want reset", app.inspector.Elapsed, app.inspector.TokensPerSecond)
+	}
+}
+
+func TestAppStatusTicksOnlyWhileStreaming(t *testing.T) {
+	t.Parallel()
+
+	stream := make(chan llm.Event, 1)
+	app := NewApp(AppConfig{})
+	app.activeStream = stream
+	app.status.SetState("streaming")
+	app.ticking = true
+
+	_, cmd := app.Update(statusTickMsg{})
+	if cmd == nil || app.status.frame != 1 {
+		t.Fatalf("tick while streaming: frame = %d, cmd = %v; want frame 1 and another tick", app.status.frame, cmd)
+	}
+
+	stream <- llm.Event{Type: llm.EventUsage, Usage: &llm.Usage{OutputTokens: 42}}
+	_, _ = app.Update(readStreamEventCommand(stream, app.streamGen)())
+	if got := app.status.OutputTokens(); got != 42 {
+		t.Fatalf("status OutputTokens() = %d, want 42", got)
+	}
+
+	app.activeStream = nil
+	if _, cmd := app.Update(statusTickMsg{}); cmd != nil || app.ticking {
+		t.Fatalf("tick after stream ended: cmd = %v, ticking = %v; want ticking stopped", cmd, app.ticking)
+	}
+}
+
+func TestAppInputCountTracksBuffer(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{ShowInputCount: true})
+	for _, r := range "hello world" {
+		_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if view := app.View(); !strings.Contains(view, "11 chars · ~3 tokens") {
+		t.Fatalf("View() = %q, want count for 11 characters", view)
+	}
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	if view := app.View(); !strings.Contains(view, "10 chars · ~3 tokens") {
+		t.Fatalf("View() after backspace = %q, want count for 10 characters", view)
+	}
+}
+
+func TestAppRemappedSubmitKey(t *testing.T) {
+	t.Parallel()
+
+	runner := &fakeRunner{streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+		out := make(chan llm.Event, 1)
+		out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}
+		close(out)
+		return out, nil
+	}}
+	app := NewApp(AppConfig{Runner: runner, KeyBindings: map[string][]string{"submit": {"ctrl+s"}}})
+
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("hi")})
+	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	runCommands(app, cmd)
+	if runner.calls != 0 || app.input.Value() != "hi" {
+		t.Fatalf("after Enter: runner calls = %d, input = %q; want no submission", runner.calls, app.input.Value())
+	}
+
+	_, cmd = app.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	runCommands(app, cmd)
+	if runner.calls != 1 || app.input.Value() != "" {
+		t.Fatalf("after ctrl+s: runner calls = %d, input = %q; want one submission", runner.calls, app.input.Value())
+	}
+}
+
+func TestAppDefaultKeysStillSubmitAndQuit(t *testing.T) {
+	t.Parallel()
+
+	runner := &fakeRunner{streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+		out := make(chan llm.Event, 1)
+		out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}
+		close(out)
+		return out, nil
+	}}
+	app := NewApp(AppConfig{Runner: runner, KeyBindings: map[string][]string{"scroll_up": {"ctrl+k"}}})
+
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("hi")})
+	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	runCommands(app, cmd)
+	if runner.calls != 1 {
+		t.Fatalf("runner calls = %d, want Enter to submit", runner.calls)
+	}
+	if _, cmd := app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}); cmd == nil {
+		t.Fatalf("q with empty input returned no command, want quit")
+	} else if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Fatalf("q with empty input did not quit")
+	}
+}
+
+func TestAppSeedsInspectorFromResumedSession(t *testing.T) {
+	t.Parallel()
+
+	call := llm.ToolCall{ID: "call-1", Name: "read", Arguments: json.RawMessage(`{"path":"a.go"}`)}
+	runner := &fakeRunner{streamFn: func(ctx context.Context, req *llm.Request) (<-chan llm.Event, error) {
+		out := make(chan llm.Event, 5)
+		out <- llm.Event{Type: llm.EventTextDelta, TextDelta: "reading"}
+		out <- llm.Event{Type: llm.EventToolCallStart, ToolCall: &call}
+		out <- llm.Event{Type: llm.EventUsage, Usage: &llm.Usage{InputTokens: 40, OutputTokens: 2, CostUSD: 0.25}}
+		out <- llm.Event{Type: llm.EventDone, Done: &llm.DonePayload{Reason: llm.StopReasonStop}}
+		close(out)
+		return out, nil
+	}}
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+
+	first := NewApp(AppConfig{SessionID: "metrics-a", Runner: runner, SessionStore: store})
+	_, _ = first.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("go")})
+	_, cmd := first.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	runCommands(first, cmd)
+
+	resumed := NewApp(AppConfig{SessionID: "metrics-a", Runner: runner, SessionStore: store})
+	inspector := resumed.inspector
+	if inspector.Turn != 1 || inspector.ToolCounts["read"] != 1 || inspector.CostUSD != 0.25 || inspector.Usage.InputTokens != 40 {
+		t.Fatalf("inspector = %+v, want 1 turn, 1 read call, $0.25, 40 input tokens", inspector)
+	}
+}
+
+func TestAppChatLimitCapsRetainedMessages(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(AppConfig{ChatLimit: 2})
+	for _, text := range []string{"one", "two", "three"} {
+		app.chat.Append("assistant", text)
+	}
+	messages := app.chat.Messages()
+	if len(messages) != 2 || messages[0].Content != "two" || messages[1].Content != "three" {
+		t.Fatalf("chat messages = %#v, want the newest two", messages)
+	}
+}
+
+func TestAppEmptyChatShowsWelcomeAndFirstRunTips(t *testing.T) {
+	t.Parallel()
+
+	store, err := sessionstore.NewStore(filepath.Join(t.TempDir(), ".gar", "sessions"))
+	if err != nil {
+		t.Fatalf("NewStore() err = %v", err)
+	}
+	theme := ResolveTheme("dark")
+
+	app := NewApp(AppConfig{SessionStore: store, Welcome: "Hi there."})
+	rendered := app.chat.Render(100, theme)
+	if !strings.Contains(rendered, "Hi there.") || !strings.Contains(rendered, "Tips:") {
+		t.Fatalf("empty chat = %q, want custom welcome and first-run tips", rendered)
+	}
+
+	quiet := NewApp(AppConfig{SessionStore: store, HideHints: true})
+	rendered = quiet.chat.Render(100, theme)
+	if !strings.Contains(rendered, emptyChatText) || strings.Contains(rendered, "/help") {
+		t.Fatalf("empty chat = %q, want plain placeholder with hints hidden", rendered)
+	}
+}
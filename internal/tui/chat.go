@@ -1,17 +1,33 @@
 package tui
 
 import (
+	"fmt"
 	"strings"
 
+	"gar/internal/llm"
+
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
-const defaultChatLimit = 500
+const (
+	defaultChatLimit = 500
+	// emptyChatText is the empty chat placeholder when hints are hidden.
+	emptyChatText = "No messages yet."
+)
 
 // ChatMessage is one rendered chat item.
 type ChatMessage struct {
 	Role    string
 	Content string
+	// Tool holds the structured result behind a "tool" message, when known.
+	Tool *llm.ToolResult
+	// Model names the model that wrote an assistant message, when known.
+	Model string
+
+	// liveToolID names the tool call whose running output this message
+	// shows until its result arrives.
+	liveToolID string
 }
 
 // ChatModel stores stream messages for display.
@@ -23,6 +39,20 @@ type ChatModel struct {
 	// viewportHeight is the number of visible content lines inside the chat panel.
 	// 0 means unconstrained.
 	viewportHeight int
+
+	// markdown renders assistant content through renderMarkdown. rendered caches
+	// each message's lines for renderWidth, the panel content width of the last Render.
+	markdown    bool
+	renderWidth int
+	rendered    [][]string
+
+	// streaming marks messages[streamIndex] as an in-progress reply that
+	// StreamMessage keeps rewriting until FinishStream.
+	streaming   bool
+	streamIndex int
+
+	// emptyText is shown while there are no messages; "" uses emptyChatText.
+	emptyText string
 }
 
 // NewChatModel creates a chat buffer with retention limit.
@@ -34,6 +64,12 @@ func NewChatModel(maxMessages int) ChatModel {
 	return ChatModel{maxMessages: limit}
 }
 
+// SetEmptyText sets what the chat shows before its first message. An empty
+// text restores the plain placeholder.
+func (m *ChatModel) SetEmptyText(text string) {
+	m.emptyText = strings.TrimSpace(text)
+}
+
 // Append records one message when content is non-empty.
 func (m *ChatModel) Append(role, content string) {
 	text := strings.TrimSpace(content)
@@ -46,10 +82,111 @@ func (m *ChatModel) Append(role, content string) {
 		Role:    strings.TrimSpace(role),
 		Content: text,
 	})
+	m.rendered = append(m.rendered, nil)
+
+	m.trimOverflow()
+	if wasAtBottom {
+		m.scrollToBottom()
+		return
+	}
+	m.clampScrollTop()
+}
 
-	if overflow := len(m.messages) - m.maxMessages; overflow > 0 {
-		m.messages = append([]ChatMessage(nil), m.messages[overflow:]...)
+// AppendToolResult records one tool result. Content is the plain
+// "name: output" fallback; with markdown enabled, results carrying known
+// display data render as a highlighted block instead. A result replaces an
+// in-progress tool message streaming the call's live output.
+func (m *ChatModel) AppendToolResult(result llm.ToolResult) {
+	content := strings.TrimSpace(result.Content)
+	if content == "" {
+		content = "(empty)"
 	}
+	content = fmt.Sprintf("%s: %s", result.ToolName, content)
+	if i := m.liveToolIndex(result.ToolCallID); i >= 0 {
+		m.messages[i].liveToolID = ""
+		m.messages[i].Tool = &result
+		m.replaceContent(i, content)
+		return
+	}
+	m.StreamMessage("tool", content)
+	m.FinishStream()
+	if len(m.messages) > 0 {
+		m.messages[len(m.messages)-1].Tool = &result
+	}
+}
+
+// TagModel records model on the newest message when it is an assistant reply.
+func (m *ChatModel) TagModel(model string) {
+	model = strings.TrimSpace(model)
+	if model == "" || len(m.messages) == 0 {
+		return
+	}
+	last := &m.messages[len(m.messages)-1]
+	if strings.EqualFold(last.Role, "assistant") && last.Model != model {
+		last.Model = model
+		m.rendered[len(m.rendered)-1] = nil
+	}
+}
+
+// StreamMessage shows content as the in-progress message for role, appending
+// it on first use and replacing its content on later calls until FinishStream.
+func (m *ChatModel) StreamMessage(role, content string) {
+	role = strings.TrimSpace(role)
+	if !m.streaming || m.messages[m.streamIndex].Role != role {
+		m.FinishStream()
+		if strings.TrimSpace(content) == "" {
+			return
+		}
+		m.Append(role, content)
+		m.streaming = true
+		m.streamIndex = len(m.messages) - 1
+		return
+	}
+
+	text := strings.TrimSpace(content)
+	if text == "" {
+		return
+	}
+	m.replaceContent(m.streamIndex, text)
+}
+
+// StreamToolOutput shows content as the running output of tool call id. The
+// call's message is appended on first use and rewritten in place after, so
+// calls running side by side each keep their own message.
+func (m *ChatModel) StreamToolOutput(id, content string) {
+	text := strings.TrimSpace(content)
+	if text == "" {
+		return
+	}
+	if i := m.liveToolIndex(id); i >= 0 {
+		m.replaceContent(i, text)
+		return
+	}
+	m.FinishStream()
+	m.Append("tool", text)
+	m.messages[len(m.messages)-1].liveToolID = id
+}
+
+// liveToolIndex returns the index of the message showing the running output
+// of tool call id, or -1.
+func (m *ChatModel) liveToolIndex(id string) int {
+	if id == "" {
+		return -1
+	}
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].liveToolID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// replaceContent rewrites message i, following the bottom of the chat when
+// the view was there.
+func (m *ChatModel) replaceContent(i int, content string) {
+	wasAtBottom := m.isAtBottom()
+	m.messages[i].Content = content
+	m.rendered[i] = nil
 	if wasAtBottom {
 		m.scrollToBottom()
 		return
@@ -57,6 +194,12 @@ func (m *ChatModel) Append(role, content string) {
 	m.clampScrollTop()
 }
 
+// FinishStream freezes the in-progress message so the next StreamMessage starts a new one.
+func (m *ChatModel) FinishStream() {
+	m.streaming = false
+	m.streamIndex = 0
+}
+
 // Messages returns a defensive copy of buffered messages.
 func (m ChatModel) Messages() []ChatMessage {
 	copied := make([]ChatMessage, 0, len(m.messages))
@@ -69,7 +212,17 @@ func (m ChatModel) Messages() []ChatMessage {
 // Clear removes all buffered chat messages.
 func (m *ChatModel) Clear() {
 	m.messages = nil
+	m.rendered = nil
 	m.scrollTop = 0
+	m.FinishStream()
+}
+
+// SetMarkdown toggles markdown rendering for assistant messages.
+func (m *ChatModel) SetMarkdown(enabled bool) {
+	if m.markdown == enabled {
+		return
+	}
+	m.relayout(func() { m.markdown = enabled })
 }
 
 // SetViewportHeight configures the visible line count for chat content.
@@ -77,7 +230,12 @@ func (m *ChatModel) SetViewportHeight(height int) {
 	if height < 0 {
 		height = 0
 	}
+	wasAtBottom := m.isAtBottom()
 	m.viewportHeight = height
+	if wasAtBottom {
+		m.scrollToBottom()
+		return
+	}
 	m.clampScrollTop()
 }
 
@@ -128,18 +286,38 @@ func (m *ChatModel) ScrollToBottom() {
 }
 
 // Render draws chat lines inside a panel.
-func (m ChatModel) Render(width int, theme Theme) string {
+func (m *ChatModel) Render(width int, theme Theme) string {
 	if len(m.messages) == 0 {
-		return renderPanel(width, theme.PanelStyle, "No messages yet.")
+		text := m.emptyText
+		if text == "" {
+			text = emptyChatText
+		}
+		return renderPanel(width, theme.PanelStyle, text)
+	}
+
+	if contentWidth := width - theme.PanelStyle.GetHorizontalPadding(); width > 0 && contentWidth != m.renderWidth {
+		m.relayout(func() { m.renderWidth = contentWidth })
 	}
 
 	lines := make([]string, 0, len(m.messages))
-	for _, message := range m.messages {
-		prefix, style := rolePrefix(message.Role, theme)
-		raw := strings.Split(message.Content, "\n")
+	for i, message := range m.messages {
+		prefix, style := messagePrefix(message, theme)
+		raw := m.messageLines(i)
 		if len(raw) == 0 {
 			continue
 		}
+		if m.renderAsMarkdown(message) {
+			lines = append(lines, style.Render(prefix))
+			lines = append(lines, raw[1:]...)
+			continue
+		}
+		if strings.EqualFold(message.Role, "thinking") {
+			lines = append(lines, style.Render(prefix+" "+raw[0]))
+			for _, line := range raw[1:] {
+				lines = append(lines, style.Render(line))
+			}
+			continue
+		}
 		lines = append(lines, style.Render(prefix)+" "+raw[0])
 		if len(raw) > 1 {
 			lines = append(lines, raw[1:]...)
@@ -162,12 +340,23 @@ func (m ChatModel) Render(width int, theme Theme) string {
 	return renderPanel(width, theme.PanelStyle, strings.Join(lines, "\n"))
 }
 
+// messagePrefix returns the role prefix for message, labelled with its model when known.
+func messagePrefix(message ChatMessage, theme Theme) (string, lipgloss.Style) {
+	prefix, style := rolePrefix(message.Role, theme)
+	if message.Model != "" {
+		prefix = strings.TrimSuffix(prefix, ":") + " (" + message.Model + "):"
+	}
+	return prefix, style
+}
+
 func rolePrefix(role string, theme Theme) (string, lipgloss.Style) {
 	switch strings.ToLower(strings.TrimSpace(role)) {
 	case "assistant":
 		return "assistant:", theme.AssistantPrefixStyle
 	case "tool":
 		return "tool:", theme.ToolPrefixStyle
+	case "thinking":
+		return "thinking:", theme.ThinkingStyle
 	default:
 		return "user:", theme.UserPrefixStyle
 	}
@@ -180,6 +369,22 @@ func renderPanel(width int, style lipgloss.Style, content string) string {
 	return style.Render(content)
 }
 
+// trimOverflow drops the oldest messages beyond maxMessages.
+func (m *ChatModel) trimOverflow() {
+	overflow := len(m.messages) - m.maxMessages
+	if overflow <= 0 {
+		return
+	}
+	m.messages = append([]ChatMessage(nil), m.messages[overflow:]...)
+	m.rendered = append([][]string(nil), m.rendered[overflow:]...)
+	if m.streaming {
+		m.streamIndex -= overflow
+		if m.streamIndex < 0 {
+			m.FinishStream()
+		}
+	}
+}
+
 func (m *ChatModel) isAtBottom() bool {
 	if m.viewportHeight <= 0 {
 		return true
@@ -215,8 +420,114 @@ func (m *ChatModel) clampScrollTop() {
 
 func (m *ChatModel) totalRenderedLines() int {
 	total := 0
-	for _, message := range m.messages {
-		total += len(strings.Split(message.Content, "\n"))
+	for i := range m.messages {
+		total += len(m.messageLines(i))
 	}
 	return total
 }
+
+// messageLines returns the display lines for message i, rendering and caching
+// them on first use. Plain text is hard-wrapped to renderWidth; markdown
+// messages reserve their first line for the role prefix.
+func (m *ChatModel) messageLines(i int) []string {
+	message := m.messages[i]
+	if m.markdown && message.Tool != nil {
+		if m.rendered[i] == nil {
+			lines, ok := renderToolDisplay(message.Tool.ToolName, message.Tool.Content, message.Tool.Display, m.renderWidth)
+			if !ok {
+				lines = m.plainLines(message)
+			}
+			m.rendered[i] = lines
+		}
+		return m.rendered[i]
+	}
+	if !m.renderAsMarkdown(message) {
+		if m.rendered[i] == nil {
+			m.rendered[i] = m.plainLines(message)
+			if message.Tool != nil {
+				m.rendered[i] = append(m.rendered[i], toolDiffLines(message.Tool.Display, m.renderWidth)...)
+			}
+		}
+		return m.rendered[i]
+	}
+	if m.rendered[i] == nil {
+		m.rendered[i] = append([]string{""}, renderMarkdown(message.Content, m.renderWidth)...)
+	}
+	return m.rendered[i]
+}
+
+// plainLines hard-wraps each line of message to renderWidth. The first line
+// shares its row with the role prefix, so its continuation rows are indented
+// to line up after the prefix.
+func (m *ChatModel) plainLines(message ChatMessage) []string {
+	logical := strings.Split(message.Content, "\n")
+	if m.renderWidth <= 0 {
+		return logical
+	}
+	prefix, _ := messagePrefix(message, Theme{})
+	indent := strings.Repeat(" ", ansi.StringWidth(prefix)+1)
+
+	lines := wrapMarkdownLine(logical[0], m.renderWidth-len(indent), true)
+	for j := 1; j < len(lines); j++ {
+		lines[j] = indent + lines[j]
+	}
+	for _, line := range logical[1:] {
+		lines = append(lines, wrapMarkdownLine(line, m.renderWidth, true)...)
+	}
+	return lines
+}
+
+func (m *ChatModel) renderAsMarkdown(message ChatMessage) bool {
+	return m.markdown && strings.EqualFold(message.Role, "assistant")
+}
+
+// relayout applies a change that alters rendered line counts, keeping the
+// viewport pinned to the bottom when it already was and otherwise keeping the
+// same content at the top.
+func (m *ChatModel) relayout(apply func()) {
+	wasAtBottom := m.isAtBottom()
+	anchor := m.topAnchor()
+	apply()
+	for i := range m.rendered {
+		m.rendered[i] = nil
+	}
+	if wasAtBottom {
+		m.scrollToBottom()
+		return
+	}
+	m.scrollToAnchor(anchor)
+}
+
+// chatAnchor locates the top visible line as a row within one message, which
+// survives relayouts that change how many rows each message wraps to.
+type chatAnchor struct {
+	message int
+	row     int
+	rows    int
+}
+
+func (m *ChatModel) topAnchor() chatAnchor {
+	top := m.scrollTop
+	for i := range m.messages {
+		rows := len(m.messageLines(i))
+		if top < rows {
+			return chatAnchor{message: i, row: top, rows: rows}
+		}
+		top -= rows
+	}
+	return chatAnchor{message: len(m.messages)}
+}
+
+// scrollToAnchor scrolls so anchor's message is at the top, mapping its row
+// proportionally onto the message's current row count.
+func (m *ChatModel) scrollToAnchor(anchor chatAnchor) {
+	top := 0
+	for i := 0; i < anchor.message && i < len(m.messages); i++ {
+		top += len(m.messageLines(i))
+	}
+	if anchor.message < len(m.messages) && anchor.rows > 0 {
+		top += anchor.row * len(m.messageLines(anchor.message)) / anchor.rows
+	}
+	m.scrollTop = top
+	m.clampScrollTop()
+}
@@ -1,9 +1,14 @@
 package tui
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
+
+	"gar/internal/llm"
+
+	"github.com/charmbracelet/lipgloss"
 )
 
 func TestChatModelRenderUsesViewportAndScroll(t *testing.T) {
@@ -34,3 +39,259 @@ func TestChatModelRenderUsesViewportAndScroll(t *testing.T) {
 		t.Fatalf("expected scrolled render to exclude m5, got %q", rendered)
 	}
 }
+
+func TestChatModelMarkdownCountsWrappedLines(t *testing.T) {
+	t.Parallel()
+
+	chat := NewChatModel(0)
+	chat.SetMarkdown(true)
+	chat.SetViewportHeight(2)
+	theme := ResolveTheme("dark")
+
+	chat.Append("user", "hi")
+	chat.Append("assistant", "- one two three four five six seven eight")
+
+	_ = chat.Render(24, theme) // content width 22 after padding
+	if got := chat.totalRenderedLines(); got != 4 {
+		t.Fatalf("totalRenderedLines() = %d, want 4", got)
+	}
+	if chat.scrollTop != 2 {
+		t.Fatalf("scrollTop = %d, want 2 (pinned to bottom)", chat.scrollTop)
+	}
+	rendered := chat.Render(24, theme)
+	if !strings.Contains(rendered, "five six seven eight") || strings.Contains(rendered, "hi") {
+		t.Fatalf("expected bottom of wrapped markdown, got %q", rendered)
+	}
+
+	chat.SetMarkdown(false)
+	// Plain text hard-wraps the 41-column reply into 11-column rows after "assistant: ".
+	if got := chat.totalRenderedLines(); got != 5 {
+		t.Fatalf("totalRenderedLines() without markdown = %d, want 5", got)
+	}
+}
+
+func TestChatModelWrapsLongPlainLines(t *testing.T) {
+	t.Parallel()
+
+	chat := NewChatModel(0)
+	theme := ResolveTheme("dark")
+	chat.Append("user", strings.Repeat("a", 30)+"\n"+strings.Repeat("b", 25))
+
+	_ = chat.Render(22, theme) // content width 20 after padding
+	want := []string{
+		strings.Repeat("a", 14),
+		"      " + strings.Repeat("a", 14),
+		"      " + strings.Repeat("a", 2),
+		strings.Repeat("b", 20),
+		strings.Repeat("b", 5),
+	}
+	got := chat.messageLines(0)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("messageLines() = %q, want %q", got, want)
+	}
+	// The panel adds a border row above and below; lipgloss must not re-wrap.
+	rendered := strings.Split(chat.Render(22, theme), "\n")
+	if len(rendered) != len(want)+2 {
+		t.Fatalf("Render() = %d rows, want %d", len(rendered), len(want)+2)
+	}
+	for _, line := range rendered {
+		if width := lipgloss.Width(line); width > 24 {
+			t.Fatalf("rendered line %q is %d columns, want at most 24", line, width)
+		}
+	}
+
+	_ = chat.Render(80, theme)
+	if got := chat.totalRenderedLines(); got != 2 {
+		t.Fatalf("totalRenderedLines() at width 80 = %d, want 2", got)
+	}
+}
+
+func TestChatModelScrollCountsWrappedLines(t *testing.T) {
+	t.Parallel()
+
+	chat := NewChatModel(0)
+	chat.SetViewportHeight(2)
+	theme := ResolveTheme("dark")
+
+	chat.Append("user", "first")
+	chat.Append("assistant", "x"+strings.Repeat(" y", 20)+" z")
+
+	_ = chat.Render(22, theme)
+	total := chat.totalRenderedLines()
+	if total <= 2 {
+		t.Fatalf("totalRenderedLines() = %d, want wrapped reply to span several rows", total)
+	}
+	if chat.scrollTop != total-2 {
+		t.Fatalf("scrollTop = %d, want %d (pinned to bottom)", chat.scrollTop, total-2)
+	}
+	rendered := chat.Render(22, theme)
+	if !strings.Contains(rendered, "y z") || strings.Contains(rendered, "first") {
+		t.Fatalf("expected bottom of wrapped reply, got %q", rendered)
+	}
+
+	chat.ScrollToTop()
+	rendered = chat.Render(22, theme)
+	if !strings.Contains(rendered, "first") || strings.Contains(rendered, "y z") {
+		t.Fatalf("expected top of chat after scrolling, got %q", rendered)
+	}
+	chat.ScrollDown(total)
+	if chat.scrollTop != total-2 {
+		t.Fatalf("scrollTop after ScrollDown = %d, want clamp to %d", chat.scrollTop, total-2)
+	}
+}
+
+func TestChatModelResizeKeepsTopLineWhenScrolledUp(t *testing.T) {
+	t.Parallel()
+
+	chat := NewChatModel(0)
+	chat.SetViewportHeight(3)
+	theme := ResolveTheme("dark")
+	for i := range 12 {
+		chat.Append("user", fmt.Sprintf("msg%d", i)+strings.Repeat(" word", 8))
+	}
+
+	_ = chat.Render(22, theme)
+	chat.ScrollToTop()
+	for i := range 5 {
+		chat.ScrollDown(len(chat.messageLines(i)))
+	}
+	if top := strings.Split(chat.Render(22, theme), "\n")[1]; !strings.Contains(top, "msg5") {
+		t.Fatalf("top line before resize = %q, want msg5", top)
+	}
+
+	for _, width := range []int{60, 30, 22} {
+		rows := strings.Split(chat.Render(width, theme), "\n")
+		if !strings.Contains(rows[1], "msg5") {
+			t.Fatalf("top line at width %d = %q, want msg5 to stay in view", width, rows[1])
+		}
+	}
+	chat.SetViewportHeight(5)
+	if top := strings.Split(chat.Render(22, theme), "\n")[1]; !strings.Contains(top, "msg5") {
+		t.Fatalf("top line after taller viewport = %q, want msg5", top)
+	}
+}
+
+func TestChatModelResizeStaysPinnedToBottom(t *testing.T) {
+	t.Parallel()
+
+	chat := NewChatModel(0)
+	chat.SetViewportHeight(4)
+	theme := ResolveTheme("dark")
+	for i := range 6 {
+		chat.Append("user", fmt.Sprintf("msg%d", i)+strings.Repeat(" word", 8))
+	}
+	_ = chat.Render(22, theme)
+
+	chat.SetViewportHeight(2)
+	if !chat.isAtBottom() {
+		t.Fatalf("scrollTop = %d after shorter viewport, want pinned to %d", chat.scrollTop, chat.maxScrollTop())
+	}
+	for _, width := range []int{60, 22} {
+		rendered := chat.Render(width, theme)
+		if !chat.isAtBottom() {
+			t.Fatalf("scrollTop = %d at width %d, want pinned to %d", chat.scrollTop, width, chat.maxScrollTop())
+		}
+		if !strings.Contains(rendered, "word") || strings.Contains(rendered, "msg0") {
+			t.Fatalf("Render(%d) = %q, want the tail of the last message", width, rendered)
+		}
+	}
+}
+
+func TestChatModelStreamMessageFollowsBottom(t *testing.T) {
+	t.Parallel()
+
+	chat := NewChatModel(0)
+	chat.SetViewportHeight(2)
+	theme := ResolveTheme("dark")
+
+	chat.Append("user", "question")
+	chat.StreamMessage("assistant", "line1")
+	chat.StreamMessage("assistant", "line1\nline2\nline3")
+
+	messages := chat.Messages()
+	if len(messages) != 2 || messages[1].Content != "line1\nline2\nline3" {
+		t.Fatalf("messages = %#v, want streamed content replaced in place", messages)
+	}
+	rendered := chat.Render(80, theme)
+	if !strings.Contains(rendered, "line3") || strings.Contains(rendered, "question") {
+		t.Fatalf("expected render pinned to streamed tail, got %q", rendered)
+	}
+
+	chat.Append("system", "interleaved")
+	chat.StreamMessage("assistant", "line1\nline2\nline3\nline4")
+	if got := len(chat.Messages()); got != 3 {
+		t.Fatalf("message count = %d, want 3 (stream updated in place)", got)
+	}
+
+	chat.FinishStream()
+	chat.StreamMessage("assistant", "fresh")
+	messages = chat.Messages()
+	if len(messages) != 4 || messages[3].Content != "fresh" {
+		t.Fatalf("messages = %#v, want new stream after FinishStream", messages)
+	}
+}
+
+func TestChatModelTagModelLabelsAssistantReplies(t *testing.T) {
+	t.Parallel()
+
+	chat := NewChatModel(0)
+	theme := ResolveTheme("dark")
+
+	chat.Append("user", "question")
+	chat.TagModel("big-model")
+	chat.Append("assistant", "answer")
+	chat.TagModel("big-model")
+	chat.Append("assistant", "legacy answer")
+
+	messages := chat.Messages()
+	if messages[0].Model != "" || messages[1].Model != "big-model" || messages[2].Model != "" {
+		t.Fatalf("messages = %#v, want only the tagged assistant reply labelled", messages)
+	}
+	rendered := chat.Render(80, theme)
+	if !strings.Contains(rendered, "assistant (big-model):") || !strings.Contains(rendered, "assistant: legacy answer") {
+		t.Fatalf("Render() = %q, want model label on tagged reply only", rendered)
+	}
+}
+
+func TestChatModelEmptyTextShownUntilFirstMessage(t *testing.T) {
+	t.Parallel()
+
+	chat := NewChatModel(0)
+	theme := ResolveTheme("dark")
+	if rendered := chat.Render(80, theme); !strings.Contains(rendered, emptyChatText) {
+		t.Fatalf("Render() = %q, want plain placeholder", rendered)
+	}
+
+	chat.SetEmptyText(welcomeText("", false))
+	rendered := chat.Render(80, theme)
+	if !strings.Contains(rendered, defaultWelcome) || !strings.Contains(rendered, "/help") {
+		t.Fatalf("Render() = %q, want greeting with /help hint", rendered)
+	}
+
+	chat.Append("user", "hello")
+	rendered = chat.Render(80, theme)
+	if strings.Contains(rendered, "/help") || !strings.Contains(rendered, "hello") {
+		t.Fatalf("Render() = %q, want hint hidden once a message exists", rendered)
+	}
+}
+
+func TestChatModelPlainToolResultShowsEditDiff(t *testing.T) {
+	t.Parallel()
+
+	chat := NewChatModel(0)
+	theme := ResolveTheme("dark")
+	chat.AppendToolResult(llm.ToolResult{
+		ToolCallID: "call-1",
+		ToolName:   "edit",
+		Content:    "Successfully replaced text in a.go.",
+		Display:    &llm.ToolDisplay{Type: "edit_result", Payload: json.RawMessage(`{"diff":"-1 old line\n+1 new line"}`)},
+	})
+	chat.AppendToolResult(llm.ToolResult{ToolCallID: "call-2", ToolName: "bash", Content: "ok"})
+
+	rendered := chat.Render(80, theme)
+	for _, want := range []string{"Successfully replaced text in a.go.", "-1 old line", "+1 new line", "ok"} {
+		if !strings.Contains(rendered, want) {
+			t.Fatalf("Render() = %q, want %q", rendered, want)
+		}
+	}
+}
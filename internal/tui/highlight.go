@@ -0,0 +1,342 @@
+package tui
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gar/internal/llm"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	codeKeywordStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("170"))
+	codeStringStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("114"))
+	codeNumberStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("215"))
+	codeCommentStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("245")).Italic(true)
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("114"))
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("203"))
+	thoughtStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("245")).Italic(true)
+
+	// grepLinePattern splits "path:12: text" match lines, "path-12- text"
+	// context lines, and "path:12> text" replacement previews.
+	grepLinePattern = regexp.MustCompile(`^(.+?)([:-])(\d+)([:>-] )(.*)$`)
+)
+
+type codeTokenKind int
+
+const (
+	codeTokenPlain codeTokenKind = iota
+	codeTokenKeyword
+	codeTokenString
+	codeTokenNumber
+	codeTokenComment
+)
+
+// codeToken is one lexical span of a highlighted source line.
+type codeToken struct {
+	Kind codeTokenKind
+	Text string
+}
+
+// codeLanguage describes just enough syntax for line-oriented highlighting.
+// Block comments and multi-line strings are not tracked across lines.
+type codeLanguage struct {
+	lineComments []string
+	quotes       string
+	keywords     map[string]bool
+}
+
+func keywordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+var (
+	cLikeKeywords = "break case const continue default do else enum extern for goto if return sizeof static struct switch typedef union void while"
+
+	codeLanguages = map[string]codeLanguage{
+		"go": {
+			lineComments: []string{"//"},
+			quotes:       "\"'`",
+			keywords:     keywordSet("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false iota"),
+		},
+		"python": {
+			lineComments: []string{"#"},
+			quotes:       "\"'",
+			keywords:     keywordSet("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield None True False self"),
+		},
+		"javascript": {
+			lineComments: []string{"//"},
+			quotes:       "\"'`",
+			keywords:     keywordSet("async await break case catch class const continue default delete do else export extends finally for function if import in instanceof interface let new of return switch this throw try type typeof var void while yield null undefined true false"),
+		},
+		"rust": {
+			lineComments: []string{"//"},
+			quotes:       "\"",
+			keywords:     keywordSet("as async await break const continue crate else enum extern fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait type unsafe use where while true false"),
+		},
+		"c": {
+			lineComments: []string{"//"},
+			quotes:       "\"'",
+			keywords:     keywordSet(cLikeKeywords + " class namespace new delete public private protected template this throw try catch virtual bool int char long short float double unsigned signed auto nullptr true false"),
+		},
+		"java": {
+			lineComments: []string{"//"},
+			quotes:       "\"'",
+			keywords:     keywordSet("abstract boolean break case catch class continue default do double else enum extends final finally float for if implements import instanceof int interface long new package private protected public return static super switch this throw throws try void while null true false"),
+		},
+		"shell": {
+			lineComments: []string{"#"},
+			quotes:       "\"'",
+			keywords:     keywordSet("if then else elif fi for while until do done case esac function in return local export set"),
+		},
+		"ruby": {
+			lineComments: []string{"#"},
+			quotes:       "\"'",
+			keywords:     keywordSet("begin class def do else elsif end ensure false for if in module nil not or rescue return self then true unless until when while yield"),
+		},
+		"data": {
+			lineComments: []string{"#"},
+			quotes:       "\"'",
+			keywords:     keywordSet("true false null"),
+		},
+	}
+
+	codeExtensions = map[string]string{
+		".go": "go", ".py": "python",
+		".js": "javascript", ".jsx": "javascript", ".mjs": "javascript", ".ts": "javascript", ".tsx": "javascript",
+		".rs": "rust", ".c": "c", ".h": "c", ".cc": "c", ".cpp": "c", ".hpp": "c",
+		".java": "java", ".kt": "java", ".rb": "ruby",
+		".sh": "shell", ".bash": "shell", ".zsh": "shell",
+		".json": "data", ".yaml": "data", ".yml": "data", ".toml": "data",
+	}
+)
+
+// languageForPath returns the highlighting language for a file path, or "" when unknown.
+func languageForPath(path string) string {
+	return codeExtensions[strings.ToLower(filepath.Ext(strings.TrimSpace(path)))]
+}
+
+// tokenizeCodeLine splits one source line into highlightable tokens.
+func tokenizeCodeLine(line, language string) []codeToken {
+	lang, ok := codeLanguages[language]
+	if !ok {
+		return []codeToken{{Kind: codeTokenPlain, Text: line}}
+	}
+
+	var tokens []codeToken
+	var plain strings.Builder
+	emit := func(kind codeTokenKind, text string) {
+		if plain.Len() > 0 {
+			tokens = append(tokens, codeToken{Kind: codeTokenPlain, Text: plain.String()})
+			plain.Reset()
+		}
+		tokens = append(tokens, codeToken{Kind: kind, Text: text})
+	}
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		rest := string(runes[i:])
+		if hasAnyPrefix(rest, lang.lineComments) {
+			emit(codeTokenComment, rest)
+			break
+		}
+
+		r := runes[i]
+		switch {
+		case strings.ContainsRune(lang.quotes, r):
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				if runes[end] == '\\' && r != '`' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(runes))
+			emit(codeTokenString, string(runes[i:end]))
+			i = end
+		case unicode.IsDigit(r) && (i == 0 || !isIdentRune(runes[i-1])):
+			end := i
+			for end < len(runes) && (isIdentRune(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			emit(codeTokenNumber, string(runes[i:end]))
+			i = end
+		case isIdentRune(r):
+			end := i
+			for end < len(runes) && isIdentRune(runes[end]) {
+				end++
+			}
+			word := string(runes[i:end])
+			if lang.keywords[word] {
+				emit(codeTokenKeyword, word)
+			} else {
+				plain.WriteString(word)
+			}
+			i = end
+		default:
+			plain.WriteRune(r)
+			i++
+		}
+	}
+	if plain.Len() > 0 {
+		tokens = append(tokens, codeToken{Kind: codeTokenPlain, Text: plain.String()})
+	}
+	return tokens
+}
+
+func hasAnyPrefix(text string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// highlightCodeLine renders one source line with token styles.
+func highlightCodeLine(line, language string) string {
+	var out strings.Builder
+	for _, token := range tokenizeCodeLine(line, language) {
+		switch token.Kind {
+		case codeTokenKeyword:
+			out.WriteString(codeKeywordStyle.Render(token.Text))
+		case codeTokenString:
+			out.WriteString(codeStringStyle.Render(token.Text))
+		case codeTokenNumber:
+			out.WriteString(codeNumberStyle.Render(token.Text))
+		case codeTokenComment:
+			out.WriteString(codeCommentStyle.Render(token.Text))
+		default:
+			out.WriteString(token.Text)
+		}
+	}
+	return out.String()
+}
+
+// renderToolDisplay renders structured tool output as a header line followed
+// by an indented, highlighted block. It reports false for display types
+// without a dedicated renderer so callers can fall back to plain text.
+func renderToolDisplay(toolName, content string, display *llm.ToolDisplay, width int) ([]string, bool) {
+	if display == nil {
+		return nil, false
+	}
+
+	var payload struct {
+		Path string `json:"path"`
+		Diff string `json:"diff"`
+		Op   string `json:"op"`
+	}
+	if len(display.Payload) > 0 {
+		_ = json.Unmarshal(display.Payload, &payload)
+	}
+
+	header := toolName
+	var body []string
+	switch display.Type {
+	case "file_content":
+		if payload.Path != "" {
+			header += " " + payload.Path
+		}
+		language := languageForPath(payload.Path)
+		for _, line := range strings.Split(content, "\n") {
+			body = append(body, highlightCodeLine(line, language))
+		}
+	case "grep_result":
+		for _, line := range strings.Split(content, "\n") {
+			match := grepLinePattern.FindStringSubmatch(line)
+			if match == nil {
+				body = append(body, markdownMutedStyle.Render(line))
+				continue
+			}
+			location := match[1] + match[2] + match[3] + match[4]
+			body = append(body, markdownMutedStyle.Render(location)+highlightCodeLine(match[5], languageForPath(match[1])))
+		}
+	case "edit_result":
+		diff := payload.Diff
+		if strings.TrimSpace(diff) == "" {
+			return nil, false
+		}
+		header += " " + firstLine(content)
+		body = highlightDiffLines(diff)
+	case "git_result":
+		if strings.TrimSpace(payload.Diff) == "" {
+			return nil, false
+		}
+		header += " " + payload.Op
+		body = highlightDiffLines(payload.Diff)
+	case "thought":
+		for _, line := range strings.Split(content, "\n") {
+			body = append(body, thoughtStyle.Render(line))
+		}
+	default:
+		return nil, false
+	}
+
+	lines := []string{header}
+	for _, line := range body {
+		for _, wrapped := range wrapMarkdownLine(line, width-len(markdownCodeIndent), true) {
+			lines = append(lines, markdownCodeIndent+wrapped)
+		}
+	}
+	return lines, true
+}
+
+func highlightDiffLines(diff string) []string {
+	var lines []string
+	for _, line := range strings.Split(diff, "\n") {
+		lines = append(lines, diffLineStyle(line).Render(line))
+	}
+	return lines
+}
+
+// diffLineStyle picks the style for one diff line from its leading +, -, or
+// context character.
+func diffLineStyle(line string) lipgloss.Style {
+	switch {
+	case strings.HasPrefix(line, "+"):
+		return diffAddedStyle
+	case strings.HasPrefix(line, "-"):
+		return diffRemovedStyle
+	default:
+		return markdownMutedStyle
+	}
+}
+
+// toolDiffLines renders the diff carried by an edit_result or git_result
+// display as indented, colored lines for plain-text chat, or nil when the
+// display has no diff.
+func toolDiffLines(display *llm.ToolDisplay, width int) []string {
+	if display == nil || (display.Type != "edit_result" && display.Type != "git_result") || len(display.Payload) == 0 {
+		return nil
+	}
+	var payload struct {
+		Diff string `json:"diff"`
+	}
+	if err := json.Unmarshal(display.Payload, &payload); err != nil || strings.TrimSpace(payload.Diff) == "" {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(payload.Diff, "\n") {
+		for _, wrapped := range wrapMarkdownLine(line, width-len(markdownCodeIndent), true) {
+			lines = append(lines, markdownCodeIndent+diffLineStyle(line).Render(wrapped))
+		}
+	}
+	return lines
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return line
+}
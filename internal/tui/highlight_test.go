@@ -0,0 +1,145 @@
+package tui
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gar/internal/llm"
+)
+
+func TestTokenizeCodeLineGo(t *testing.T) {
+	t.Parallel()
+
+	tokens := tokenizeCodeLine(`	return "a//b", 42 // done`, "go")
+	want := []codeToken{
+		{Kind: codeTokenPlain, Text: "\t"},
+		{Kind: codeTokenKeyword, Text: "return"},
+		{Kind: codeTokenPlain, Text: " "},
+		{Kind: codeTokenString, Text: `"a//b"`},
+		{Kind: codeTokenPlain, Text: ", "},
+		{Kind: codeTokenNumber, Text: "42"},
+		{Kind: codeTokenPlain, Text: " "},
+		{Kind: codeTokenComment, Text: "// done"},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("tokenizeCodeLine() = %#v, want %#v", tokens, want)
+	}
+	for i := range want {
+		if tokens[i] != want[i] {
+			t.Fatalf("token[%d] = %#v, want %#v", i, tokens[i], want[i])
+		}
+	}
+
+	if got := tokenizeCodeLine("return x", ""); len(got) != 1 || got[0].Kind != codeTokenPlain {
+		t.Fatalf("unknown language tokens = %#v, want one plain token", got)
+	}
+}
+
+func TestLanguageForPath(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"main.go":          "go",
+		"scripts/run.SH":   "shell",
+		"web/app.tsx":      "javascript",
+		"config.toml":      "data",
+		"README":           "",
+		"notes/readme.txt": "",
+	}
+	for path, want := range tests {
+		if got := languageForPath(path); got != want {
+			t.Fatalf("languageForPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestRenderToolDisplay(t *testing.T) {
+	t.Parallel()
+
+	read := &llm.ToolDisplay{Type: "file_content", Payload: json.RawMessage(`{"path":"main.go"}`)}
+	lines, ok := renderToolDisplay("read", "package main\n\nfunc main() {}", read, 80)
+	if !ok {
+		t.Fatalf("renderToolDisplay(file_content) ok = false")
+	}
+	if len(lines) != 4 || lines[0] != "read main.go" || !strings.HasSuffix(lines[3], "func main() {}") {
+		t.Fatalf("renderToolDisplay(file_content) = %q", lines)
+	}
+
+	grep := &llm.ToolDisplay{Type: "grep_result"}
+	lines, ok = renderToolDisplay("grep", "a/b.go:12: return nil", grep, 80)
+	if !ok || len(lines) != 2 || !strings.HasSuffix(lines[1], "a/b.go:12: return nil") {
+		t.Fatalf("renderToolDisplay(grep_result) = %q, %v", lines, ok)
+	}
+
+	edit := &llm.ToolDisplay{Type: "edit_result", Payload: json.RawMessage(`{"diff":"-1 old\n+1 new"}`)}
+	lines, ok = renderToolDisplay("edit", "Successfully replaced text in a.go.", edit, 80)
+	if !ok || len(lines) != 3 || lines[0] != "edit Successfully replaced text in a.go." {
+		t.Fatalf("renderToolDisplay(edit_result) = %q, %v", lines, ok)
+	}
+
+	gitDiff := &llm.ToolDisplay{Type: "git_result", Payload: json.RawMessage(`{"op":"diff","diff":"-old\n+new"}`)}
+	lines, ok = renderToolDisplay("git", "-old\n+new", gitDiff, 80)
+	if !ok || len(lines) != 3 || lines[0] != "git diff" {
+		t.Fatalf("renderToolDisplay(git_result) = %q, %v", lines, ok)
+	}
+	if _, ok := renderToolDisplay("git", "Working tree clean.", &llm.ToolDisplay{Type: "git_result", Payload: json.RawMessage(`{"op":"status"}`)}, 80); ok {
+		t.Fatalf("renderToolDisplay(git status) ok = true, want plain fallback")
+	}
+
+	thought := &llm.ToolDisplay{Type: "thought"}
+	lines, ok = renderToolDisplay("think", "check tests\nthen edit", thought, 80)
+	if !ok || len(lines) != 3 || lines[0] != "think" || !strings.HasSuffix(lines[2], "then edit") {
+		t.Fatalf("renderToolDisplay(thought) = %q, %v", lines, ok)
+	}
+
+	if _, ok := renderToolDisplay("bash", "ok", &llm.ToolDisplay{Type: "bash_output"}, 80); ok {
+		t.Fatalf("renderToolDisplay(unknown) ok = true, want plain fallback")
+	}
+	if _, ok := renderToolDisplay("bash", "ok", nil, 80); ok {
+		t.Fatalf("renderToolDisplay(nil) ok = true, want plain fallback")
+	}
+}
+
+func TestChatModelRendersToolDisplayOnlyWithMarkdown(t *testing.T) {
+	t.Parallel()
+
+	chat := NewChatModel(0)
+	theme := ResolveTheme("dark")
+	chat.AppendToolResult(llm.ToolResult{
+		ToolName: "read",
+		Content:  "package main",
+		Display:  &llm.ToolDisplay{Type: "file_content", Payload: json.RawMessage(`{"path":"main.go"}`)},
+	})
+
+	if rendered := chat.Render(80, theme); !strings.Contains(rendered, "read: package main") {
+		t.Fatalf("plain render = %q, want name: content fallback", rendered)
+	}
+
+	chat.SetMarkdown(true)
+	rendered := chat.Render(80, theme)
+	if !strings.Contains(rendered, "read main.go") || strings.Contains(rendered, "read: package main") {
+		t.Fatalf("markdown render = %q, want highlighted file block", rendered)
+	}
+}
+
+func TestDiffLineStyleDistinguishesAddedAndRemovedLines(t *testing.T) {
+	t.Parallel()
+
+	added := diffLineStyle("+1 new").GetForeground()
+	removed := diffLineStyle("-1 old").GetForeground()
+	context := diffLineStyle(" 2 same").GetForeground()
+	if added == removed || added == context || removed == context {
+		t.Fatalf("diff styles = added %v removed %v context %v, want three distinct colors", added, removed, context)
+	}
+	if added != diffAddedStyle.GetForeground() || removed != diffRemovedStyle.GetForeground() {
+		t.Fatalf("diff styles = added %v removed %v, want diffAddedStyle and diffRemovedStyle", added, removed)
+	}
+
+	if lines := toolDiffLines(&llm.ToolDisplay{Type: "edit_result", Payload: json.RawMessage(`{"diff":"-a\n+b\n c"}`)}, 80); len(lines) != 3 {
+		t.Fatalf("toolDiffLines(edit_result) = %q, want 3 lines", lines)
+	}
+	if lines := toolDiffLines(&llm.ToolDisplay{Type: "bash_output"}, 80); lines != nil {
+		t.Fatalf("toolDiffLines(bash_output) = %q, want nil for plain fallback", lines)
+	}
+}
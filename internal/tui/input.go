@@ -1,17 +1,31 @@
 package tui
 
 import (
+	"fmt"
 	"strings"
+	"unicode"
+
+	agentsession "gar/internal/agent/session"
+	"gar/internal/llm"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
-// InputModel stores a single-line prompt buffer.
+// inputNewlineMarker stands in for pasted newlines on the single input row.
+const inputNewlineMarker = "↵"
+
+var inputCursorStyle = lipgloss.NewStyle().Reverse(true)
+
+// InputModel stores a single-line prompt buffer with a cursor.
 type InputModel struct {
 	prompt      string
 	placeholder string
-	value       string
+	value       []rune
+	// cursor is the rune offset in value where edits apply.
+	cursor int
+	// showCount adds a character and estimated-token count at the right edge.
+	showCount bool
 }
 
 // NewInputModel constructs the input state.
@@ -28,17 +42,24 @@ func NewInputModel(prompt, placeholder string) InputModel {
 
 // Value returns current raw input text.
 func (m InputModel) Value() string {
-	return m.value
+	return string(m.value)
+}
+
+// Cursor returns the cursor position as a rune offset into Value.
+func (m InputModel) Cursor() int {
+	return m.cursor
 }
 
-// SetValue replaces input text.
+// SetValue replaces input text and moves the cursor to its end.
 func (m *InputModel) SetValue(value string) {
-	m.value = value
+	m.value = []rune(value)
+	m.cursor = len(m.value)
 }
 
 // Clear resets input text.
 func (m *InputModel) Clear() {
-	m.value = ""
+	m.value = nil
+	m.cursor = 0
 }
 
 // HandleKey mutates input state and reports submit key.
@@ -46,36 +67,147 @@ func (m *InputModel) HandleKey(msg tea.KeyMsg) (submitted bool) {
 	switch msg.Type {
 	case tea.KeyEnter:
 		return true
-	case tea.KeyBackspace, tea.KeyDelete:
-		if m.value == "" {
-			return false
+	case tea.KeyBackspace, tea.KeyCtrlH:
+		if m.cursor > 0 {
+			m.deleteRange(m.cursor-1, m.cursor)
+		}
+	case tea.KeyDelete:
+		if m.cursor < len(m.value) {
+			m.deleteRange(m.cursor, m.cursor+1)
+		}
+	case tea.KeyLeft, tea.KeyCtrlB:
+		if m.cursor > 0 {
+			m.cursor--
 		}
-		runes := []rune(m.value)
-		m.value = string(runes[:len(runes)-1])
-		return false
+	case tea.KeyRight, tea.KeyCtrlF:
+		if m.cursor < len(m.value) {
+			m.cursor++
+		}
+	case tea.KeyHome, tea.KeyCtrlA:
+		m.cursor = 0
+	case tea.KeyEnd, tea.KeyCtrlE:
+		m.cursor = len(m.value)
+	case tea.KeyCtrlU:
+		m.deleteRange(0, m.cursor)
+	case tea.KeyCtrlK:
+		m.deleteRange(m.cursor, len(m.value))
+	case tea.KeyCtrlW:
+		m.deleteRange(m.wordStart(), m.cursor)
 	case tea.KeySpace:
-		m.value += " "
-		return false
+		m.insert([]rune(" "))
+	case tea.KeyRunes:
+		m.insert(normalizeInputRunes(msg.Runes))
 	}
+	return false
+}
+
+// SetShowCount toggles the character and token count shown while typing.
+func (m *InputModel) SetShowCount(show bool) {
+	m.showCount = show
+}
 
-	if len(msg.Runes) > 0 {
-		m.value += string(msg.Runes)
+// Count returns the buffer length in characters and its estimated tokens.
+func (m InputModel) Count() (chars, tokens int) {
+	if len(m.value) == 0 {
+		return 0, 0
 	}
-	return false
+	tokens = agentsession.EstimateTokens([]llm.Message{{
+		Role:    llm.RoleUser,
+		Content: []llm.ContentBlock{{Type: llm.ContentTypeText, Text: string(m.value)}},
+	}})
+	return len(m.value), tokens
 }
 
-// Render draws the input line.
+// Render draws the input line with the cursor shown in reverse video.
 func (m InputModel) Render(width int, theme Theme) string {
-	value := m.value
-	valueStyle := theme.InputTextStyle
-	if strings.TrimSpace(value) == "" {
-		value = m.placeholder
-		valueStyle = theme.InputPlaceholderTextStyle
+	line := theme.InputPromptStyle.Render(m.prompt + " ")
+	if len(m.value) == 0 {
+		placeholder := []rune(m.placeholder)
+		if len(placeholder) == 0 {
+			line += inputCursorStyle.Render(" ")
+		} else {
+			line += inputCursorStyle.Render(string(placeholder[0])) + theme.InputPlaceholderTextStyle.Render(string(placeholder[1:]))
+		}
+	} else {
+		before := displayInputText(m.value[:m.cursor])
+		under, after := " ", ""
+		if m.cursor < len(m.value) {
+			under = displayInputText(m.value[m.cursor : m.cursor+1])
+			after = displayInputText(m.value[m.cursor+1:])
+		}
+		line += theme.InputTextStyle.Render(before) + inputCursorStyle.Render(under) + theme.InputTextStyle.Render(after)
 	}
+	line = m.appendCount(line, width, theme)
 
-	line := theme.InputPromptStyle.Render(m.prompt+" ") + valueStyle.Render(value)
 	if width > 0 {
 		return lipgloss.NewStyle().Width(width).Render(line)
 	}
 	return line
 }
+
+// appendCount right-aligns the count after line when it is enabled and fits.
+func (m InputModel) appendCount(line string, width int, theme Theme) string {
+	if !m.showCount || len(m.value) == 0 {
+		return line
+	}
+	chars, tokens := m.Count()
+	count := fmt.Sprintf("%d chars · ~%d tokens", chars, tokens)
+	gap := 1
+	if width > 0 {
+		gap = width - lipgloss.Width(line) - lipgloss.Width(count)
+		if gap < 1 {
+			return line
+		}
+	}
+	return line + strings.Repeat(" ", gap) + theme.InputPlaceholderTextStyle.Render(count)
+}
+
+func (m *InputModel) insert(runes []rune) {
+	if len(runes) == 0 {
+		return
+	}
+	value := make([]rune, 0, len(m.value)+len(runes))
+	value = append(value, m.value[:m.cursor]...)
+	value = append(value, runes...)
+	value = append(value, m.value[m.cursor:]...)
+	m.value = value
+	m.cursor += len(runes)
+}
+
+// deleteRange removes value[start:end] and leaves the cursor at start.
+func (m *InputModel) deleteRange(start, end int) {
+	if start >= end {
+		return
+	}
+	m.value = append(m.value[:start:start], m.value[end:]...)
+	m.cursor = start
+}
+
+// wordStart returns where Ctrl+W stops: the start of the word before the
+// cursor, after skipping any whitespace directly before it.
+func (m InputModel) wordStart() int {
+	i := m.cursor
+	for i > 0 && unicode.IsSpace(m.value[i-1]) {
+		i--
+	}
+	for i > 0 && !unicode.IsSpace(m.value[i-1]) {
+		i--
+	}
+	return i
+}
+
+// normalizeInputRunes folds pasted CRLF and CR line endings to "\n".
+func normalizeInputRunes(runes []rune) []rune {
+	text := string(runes)
+	if !strings.ContainsRune(text, '\r') {
+		return runes
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return []rune(strings.ReplaceAll(text, "\r", "\n"))
+}
+
+// displayInputText shows newlines and tabs as single visible cells.
+func displayInputText(runes []rune) string {
+	text := strings.ReplaceAll(string(runes), "\n", inputNewlineMarker)
+	return strings.ReplaceAll(text, "\t", " ")
+}
@@ -0,0 +1,187 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func typeInput(input *InputModel, text string) {
+	input.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+}
+
+func TestInputModelCursorMovement(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		keys       []tea.KeyType
+		wantCursor int
+	}{
+		{name: "starts at end", wantCursor: 5},
+		{name: "left", keys: []tea.KeyType{tea.KeyLeft, tea.KeyLeft}, wantCursor: 3},
+		{name: "left stops at start", keys: []tea.KeyType{tea.KeyHome, tea.KeyLeft}, wantCursor: 0},
+		{name: "right stops at end", keys: []tea.KeyType{tea.KeyRight}, wantCursor: 5},
+		{name: "home then right", keys: []tea.KeyType{tea.KeyHome, tea.KeyRight}, wantCursor: 1},
+		{name: "ctrl+a", keys: []tea.KeyType{tea.KeyCtrlA}, wantCursor: 0},
+		{name: "ctrl+e", keys: []tea.KeyType{tea.KeyCtrlA, tea.KeyCtrlE}, wantCursor: 5},
+		{name: "end", keys: []tea.KeyType{tea.KeyHome, tea.KeyEnd}, wantCursor: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			input := NewInputModel(">", "")
+			typeInput(&input, "héllo")
+			for _, key := range tt.keys {
+				if input.HandleKey(tea.KeyMsg{Type: key}) {
+					t.Fatalf("HandleKey(%v) submitted, want edit only", key)
+				}
+			}
+			if got := input.Cursor(); got != tt.wantCursor {
+				t.Fatalf("Cursor() = %d, want %d", got, tt.wantCursor)
+			}
+			if got := input.Value(); got != "héllo" {
+				t.Fatalf("Value() = %q, want unchanged", got)
+			}
+		})
+	}
+}
+
+func TestInputModelInsertsAtCursor(t *testing.T) {
+	t.Parallel()
+
+	input := NewInputModel(">", "")
+	typeInput(&input, "helo")
+	input.HandleKey(tea.KeyMsg{Type: tea.KeyLeft})
+	typeInput(&input, "l")
+	if got := input.Value(); got != "hello" {
+		t.Fatalf("Value() = %q, want hello", got)
+	}
+	if got := input.Cursor(); got != 4 {
+		t.Fatalf("Cursor() = %d, want 4", got)
+	}
+
+	input.HandleKey(tea.KeyMsg{Type: tea.KeyHome})
+	input.HandleKey(tea.KeyMsg{Type: tea.KeySpace})
+	if got := input.Value(); got != " hello" || input.Cursor() != 1 {
+		t.Fatalf("Value() = %q, Cursor() = %d; want leading space at 1", got, input.Cursor())
+	}
+
+	input.SetValue("reset")
+	if input.Cursor() != 5 {
+		t.Fatalf("Cursor() after SetValue = %d, want 5", input.Cursor())
+	}
+	input.Clear()
+	if input.Value() != "" || input.Cursor() != 0 {
+		t.Fatalf("after Clear Value() = %q, Cursor() = %d; want empty", input.Value(), input.Cursor())
+	}
+}
+
+func TestInputModelDeletions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		value      string
+		moveLeft   int
+		key        tea.KeyType
+		wantValue  string
+		wantCursor int
+	}{
+		{name: "backspace at end", value: "abc", key: tea.KeyBackspace, wantValue: "ab", wantCursor: 2},
+		{name: "backspace mid", value: "abc", moveLeft: 1, key: tea.KeyBackspace, wantValue: "ac", wantCursor: 1},
+		{name: "backspace at start", value: "abc", moveLeft: 3, key: tea.KeyBackspace, wantValue: "abc", wantCursor: 0},
+		{name: "delete mid", value: "abc", moveLeft: 2, key: tea.KeyDelete, wantValue: "ac", wantCursor: 1},
+		{name: "delete at end", value: "abc", key: tea.KeyDelete, wantValue: "abc", wantCursor: 3},
+		{name: "ctrl+u", value: "one two", moveLeft: 3, key: tea.KeyCtrlU, wantValue: "two", wantCursor: 0},
+		{name: "ctrl+k", value: "one two", moveLeft: 3, key: tea.KeyCtrlK, wantValue: "one ", wantCursor: 4},
+		{name: "ctrl+w word", value: "one two", key: tea.KeyCtrlW, wantValue: "one ", wantCursor: 4},
+		{name: "ctrl+w trailing space", value: "one two  ", key: tea.KeyCtrlW, wantValue: "one ", wantCursor: 4},
+		{name: "ctrl+w mid word", value: "one two", moveLeft: 1, key: tea.KeyCtrlW, wantValue: "one o", wantCursor: 4},
+		{name: "ctrl+w at start", value: "one", moveLeft: 3, key: tea.KeyCtrlW, wantValue: "one", wantCursor: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			input := NewInputModel(">", "")
+			input.SetValue(tt.value)
+			for i := 0; i < tt.moveLeft; i++ {
+				input.HandleKey(tea.KeyMsg{Type: tea.KeyLeft})
+			}
+			input.HandleKey(tea.KeyMsg{Type: tt.key})
+			if got := input.Value(); got != tt.wantValue {
+				t.Fatalf("Value() = %q, want %q", got, tt.wantValue)
+			}
+			if got := input.Cursor(); got != tt.wantCursor {
+				t.Fatalf("Cursor() = %d, want %d", got, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestInputModelMultiLinePaste(t *testing.T) {
+	t.Parallel()
+
+	input := NewInputModel(">", "")
+	typeInput(&input, "see:")
+	input.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("line1\r\nline2\rline3"), Paste: true})
+	if got := input.Value(); got != "see:line1\nline2\nline3" {
+		t.Fatalf("Value() = %q, want normalized newlines", got)
+	}
+
+	rendered := input.Render(0, ResolveTheme("dark"))
+	if strings.Contains(rendered, "\n") || !strings.Contains(rendered, "line1"+inputNewlineMarker+"line2") {
+		t.Fatalf("Render() = %q, want newlines shown as markers on one row", rendered)
+	}
+}
+
+func TestInputModelRenderShowsCursor(t *testing.T) {
+	t.Parallel()
+
+	theme := ResolveTheme("dark")
+	input := NewInputModel(">", "Ask something")
+	if rendered := input.Render(0, theme); !strings.Contains(rendered, "sk something") {
+		t.Fatalf("Render() = %q, want placeholder", rendered)
+	}
+
+	input.SetValue("abc")
+	input.HandleKey(tea.KeyMsg{Type: tea.KeyLeft})
+	want := theme.InputTextStyle.Render("ab") + inputCursorStyle.Render("c")
+	if rendered := input.Render(0, theme); !strings.Contains(rendered, want) {
+		t.Fatalf("Render() = %q, want cursor on c", rendered)
+	}
+	input.HandleKey(tea.KeyMsg{Type: tea.KeyEnd})
+	want = theme.InputTextStyle.Render("abc") + inputCursorStyle.Render(" ")
+	if rendered := input.Render(0, theme); !strings.Contains(rendered, want) {
+		t.Fatalf("Render() = %q, want cursor after text", rendered)
+	}
+}
+
+func TestInputModelRendersCountAtRightEdge(t *testing.T) {
+	t.Parallel()
+
+	theme := ResolveTheme("dark")
+	input := NewInputModel(">", "Ask something")
+	input.SetShowCount(true)
+	if rendered := input.Render(60, theme); strings.Contains(rendered, "chars") {
+		t.Fatalf("Render() empty = %q, want no count", rendered)
+	}
+
+	typeInput(&input, "refactor the parser")
+	if chars, tokens := input.Count(); chars != 19 || tokens != 5 {
+		t.Fatalf("Count() = %d, %d; want 19 chars, 5 tokens", chars, tokens)
+	}
+	rendered := input.Render(60, theme)
+	if !strings.HasSuffix(strings.TrimRight(rendered, " "), "19 chars · ~5 tokens") || lipgloss.Width(rendered) != 60 {
+		t.Fatalf("Render() = %q, want the count right-aligned in 60 columns", rendered)
+	}
+
+	input.SetShowCount(false)
+	if rendered := input.Render(60, theme); strings.Contains(rendered, "chars") {
+		t.Fatalf("Render() disabled = %q, want no count", rendered)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
+	agentsession "gar/internal/agent/session"
 	"gar/internal/llm"
 )
 
@@ -15,6 +17,10 @@ type InspectorModel struct {
 	Usage      llm.Usage
 	CostUSD    float64
 	ToolCounts map[string]int
+	// Elapsed and TokensPerSecond describe the current (or last) provider turn.
+	Elapsed         time.Duration
+	TokensPerSecond float64
+	turnStarted     time.Time
 }
 
 // NewInspectorModel constructs inspector defaults.
@@ -45,6 +51,31 @@ func (m *InspectorModel) SetUsage(usage llm.Usage) {
 	m.CostUSD = usage.CostUSD
 }
 
+// StartTurnTimer begins timing a provider turn, resetting the previous turn's rate.
+func (m *InspectorModel) StartTurnTimer(now time.Time) {
+	m.turnStarted = now
+	m.Elapsed = 0
+	m.TokensPerSecond = 0
+}
+
+// UpdateThroughput recomputes elapsed time and output tokens per second
+// for the running turn. It is a no-op when no turn is being timed.
+func (m *InspectorModel) UpdateThroughput(now time.Time, outputTokens int) {
+	if m.turnStarted.IsZero() {
+		return
+	}
+	m.Elapsed = now.Sub(m.turnStarted)
+	if m.Elapsed > 0 && outputTokens > 0 {
+		m.TokensPerSecond = float64(outputTokens) / m.Elapsed.Seconds()
+	}
+}
+
+// StopTurnTimer records final throughput and freezes the turn's figures.
+func (m *InspectorModel) StopTurnTimer(now time.Time, outputTokens int) {
+	m.UpdateThroughput(now, outputTokens)
+	m.turnStarted = time.Time{}
+}
+
 // RecordToolCall increments tool call count.
 func (m *InspectorModel) RecordToolCall(toolName string) {
 	name := strings.TrimSpace(toolName)
@@ -54,15 +85,37 @@ func (m *InspectorModel) RecordToolCall(toolName string) {
 	m.ToolCounts[name]++
 }
 
+// LoadMetrics replaces the counters with those derived from a stored
+// session, keeping the runtime state and timing.
+func (m *InspectorModel) LoadMetrics(metrics agentsession.Metrics) {
+	m.Turn = metrics.Turns
+	m.Usage = metrics.Usage
+	m.CostUSD = metrics.Usage.CostUSD
+	m.ToolCounts = make(map[string]int, len(metrics.ToolCounts))
+	for name, count := range metrics.ToolCounts {
+		m.ToolCounts[name] = count
+	}
+}
+
 // Render draws the inspector panel.
 func (m InspectorModel) Render(width int, theme Theme) string {
 	lines := []string{
 		"Status: " + m.State,
 		fmt.Sprintf("Turn: %d", m.Turn),
 		fmt.Sprintf("Tokens: %d", m.Usage.TokenCount()),
-		"Cost: " + formatCostUSD(m.CostUSD),
-		"Tools:",
 	}
+	if m.Elapsed > 0 {
+		lines = append(lines, fmt.Sprintf("Elapsed: %s (%.1f tok/s)", m.Elapsed.Round(100*time.Millisecond), m.TokensPerSecond))
+	}
+	if m.Usage.CacheReadTokens > 0 || m.Usage.CacheWriteTokens > 0 {
+		lines = append(lines, fmt.Sprintf(
+			"Cache: %d read / %d write (%.0f%% hit)",
+			m.Usage.CacheReadTokens,
+			m.Usage.CacheWriteTokens,
+			m.Usage.CacheHitRatio()*100,
+		))
+	}
+	lines = append(lines, "Cost: "+formatCostUSD(m.CostUSD), "Tools:")
 
 	if len(m.ToolCounts) == 0 {
 		lines = append(lines, "  none")
@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// KeyAction names an App command that can be bound to keys.
+type KeyAction string
+
+const (
+	KeyActionQuit KeyAction = "quit"
+	// KeyActionQuitWhenIdle quits only while the input is empty and no run is active.
+	KeyActionQuitWhenIdle KeyAction = "quit_when_idle"
+	KeyActionSubmit       KeyAction = "submit"
+	KeyActionFollowUp     KeyAction = "follow_up"
+	KeyActionCancel       KeyAction = "cancel"
+	KeyActionComplete     KeyAction = "complete"
+	KeyActionScrollUp     KeyAction = "scroll_up"
+	KeyActionScrollDown   KeyAction = "scroll_down"
+	KeyActionPageUp       KeyAction = "page_up"
+	KeyActionPageDown     KeyAction = "page_down"
+	KeyActionScrollTop    KeyAction = "scroll_top"
+	KeyActionScrollBottom KeyAction = "scroll_bottom"
+)
+
+// keyAliases maps config spellings to the names tea.KeyMsg.String reports.
+var keyAliases = map[string]string{
+	"space":      " ",
+	"alt+space":  "alt+ ",
+	"ctrl+space": "ctrl+@",
+}
+
+// KeyMap maps actions to the key strings, as reported by tea.KeyMsg.String,
+// that trigger them.
+type KeyMap map[KeyAction][]string
+
+// DefaultKeyMap returns the built-in bindings.
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		KeyActionQuit:         {"ctrl+c"},
+		KeyActionQuitWhenIdle: {"q"},
+		KeyActionSubmit:       {"enter"},
+		KeyActionFollowUp:     {"alt+enter"},
+		KeyActionCancel:       {"esc"},
+		KeyActionComplete:     {"tab"},
+		KeyActionScrollUp:     {"up"},
+		KeyActionScrollDown:   {"down"},
+		KeyActionPageUp:       {"pgup"},
+		KeyActionPageDown:     {"pgdown"},
+		KeyActionScrollTop:    {"home"},
+		KeyActionScrollBottom: {"end"},
+	}
+}
+
+// NewKeyMap returns the defaults with each action in bindings replaced by
+// its keys. Bindings are expected to be validated by the config loader.
+func NewKeyMap(bindings map[string][]string) KeyMap {
+	keys := DefaultKeyMap()
+	for action, specs := range bindings {
+		normalized := make([]string, 0, len(specs))
+		for _, spec := range specs {
+			spec = strings.ToLower(strings.TrimSpace(spec))
+			if alias, ok := keyAliases[spec]; ok {
+				spec = alias
+			}
+			normalized = append(normalized, spec)
+		}
+		keys[KeyAction(action)] = normalized
+	}
+	return keys
+}
+
+// Matches reports whether msg is bound to action.
+func (k KeyMap) Matches(msg tea.KeyMsg, action KeyAction) bool {
+	key := msg.String()
+	for _, spec := range k[action] {
+		if spec == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestNewKeyMapReplacesOnlyBoundActions(t *testing.T) {
+	t.Parallel()
+
+	keys := NewKeyMap(map[string][]string{
+		"scroll_down": {"ctrl+j", "Down"},
+		"submit":      {"ctrl+space"},
+	})
+
+	if !keys.Matches(tea.KeyMsg{Type: tea.KeyCtrlJ}, KeyActionScrollDown) || !keys.Matches(tea.KeyMsg{Type: tea.KeyDown}, KeyActionScrollDown) {
+		t.Fatalf("scroll_down = %q, want ctrl+j and down", keys[KeyActionScrollDown])
+	}
+	if !keys.Matches(tea.KeyMsg{Type: tea.KeyCtrlAt}, KeyActionSubmit) {
+		t.Fatalf("submit = %q, want ctrl+space to match", keys[KeyActionSubmit])
+	}
+	if keys.Matches(tea.KeyMsg{Type: tea.KeyEnter}, KeyActionSubmit) {
+		t.Fatalf("submit still matches enter after remapping")
+	}
+	if !keys.Matches(tea.KeyMsg{Type: tea.KeyEnter, Alt: true}, KeyActionFollowUp) {
+		t.Fatalf("follow_up default alt+enter no longer matches")
+	}
+}
@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var (
+	markdownHeadingStyle    = lipgloss.NewStyle().Bold(true).Underline(true)
+	markdownBoldStyle       = lipgloss.NewStyle().Bold(true)
+	markdownInlineCodeStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("215"))
+	markdownCodeBlockStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("250"))
+	markdownMutedStyle      = lipgloss.NewStyle().Faint(true)
+
+	markdownInlineCodePattern = regexp.MustCompile("`([^`]+)`")
+	markdownBoldPattern       = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	markdownBulletPattern     = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	markdownOrderedPattern    = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	markdownHeadingPattern    = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+	markdownRulePattern       = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+)
+
+const markdownCodeIndent = "  "
+
+// renderMarkdown converts a markdown subset (headings, lists, block quotes,
+// fenced code, inline code, bold) into styled lines wrapped to width.
+// A width <= 0 disables wrapping.
+func renderMarkdown(content string, width int) []string {
+	var lines []string
+	inCode := false
+	for _, raw := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(raw)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			if lang := strings.TrimSpace(strings.TrimPrefix(trimmed, "```")); inCode && lang != "" {
+				lines = append(lines, markdownMutedStyle.Render(markdownCodeIndent+lang))
+			}
+			continue
+		}
+		if inCode {
+			for _, line := range wrapMarkdownLine(raw, width-len(markdownCodeIndent), true) {
+				lines = append(lines, markdownCodeIndent+markdownCodeBlockStyle.Render(line))
+			}
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			lines = append(lines, "")
+		case markdownRulePattern.MatchString(trimmed):
+			ruleWidth := width
+			if ruleWidth <= 0 {
+				ruleWidth = 3
+			}
+			lines = append(lines, markdownMutedStyle.Render(strings.Repeat("─", ruleWidth)))
+		case markdownHeadingPattern.MatchString(trimmed):
+			heading := markdownHeadingPattern.FindStringSubmatch(trimmed)[1]
+			for _, line := range wrapMarkdownLine(heading, width, false) {
+				lines = append(lines, markdownHeadingStyle.Render(line))
+			}
+		case strings.HasPrefix(trimmed, ">"):
+			quote := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			lines = append(lines, hangingIndent(markdownMutedStyle.Render("│ "), markdownMutedStyle.Render("│ "), renderInlineMarkdown(quote), width)...)
+		case markdownBulletPattern.MatchString(raw):
+			match := markdownBulletPattern.FindStringSubmatch(raw)
+			indent := match[1]
+			lines = append(lines, hangingIndent(indent+"• ", indent+"  ", renderInlineMarkdown(match[2]), width)...)
+		case markdownOrderedPattern.MatchString(raw):
+			match := markdownOrderedPattern.FindStringSubmatch(raw)
+			marker := match[1] + match[2] + " "
+			lines = append(lines, hangingIndent(marker, strings.Repeat(" ", len(marker)), renderInlineMarkdown(match[3]), width)...)
+		default:
+			lines = append(lines, wrapMarkdownLine(renderInlineMarkdown(strings.TrimRight(raw, " \t")), width, false)...)
+		}
+	}
+	return lines
+}
+
+func renderInlineMarkdown(text string) string {
+	text = markdownInlineCodePattern.ReplaceAllStringFunc(text, func(match string) string {
+		return markdownInlineCodeStyle.Render(strings.Trim(match, "`"))
+	})
+	return markdownBoldPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return markdownBoldStyle.Render(strings.Trim(match, "*"))
+	})
+}
+
+// hangingIndent wraps text so continuation lines align after the first-line marker.
+func hangingIndent(first, rest, text string, width int) []string {
+	wrapped := wrapMarkdownLine(text, width-ansi.StringWidth(first), false)
+	for i := range wrapped {
+		if i == 0 {
+			wrapped[i] = first + wrapped[i]
+			continue
+		}
+		wrapped[i] = rest + wrapped[i]
+	}
+	return wrapped
+}
+
+func wrapMarkdownLine(text string, width int, hard bool) []string {
+	if width <= 0 {
+		return []string{text}
+	}
+	if hard {
+		return strings.Split(ansi.Hardwrap(text, width, true), "\n")
+	}
+	return strings.Split(ansi.Wrap(text, width, ""), "\n")
+}
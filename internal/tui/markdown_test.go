@@ -0,0 +1,66 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		width   int
+		want    []string
+	}{
+		{
+			name:    "heading and bold",
+			content: "# Title\nsome **bold** text",
+			want:    []string{"Title", "some bold text"},
+		},
+		{
+			name:    "fenced code keeps lines and drops fences",
+			content: "```go\nfunc main() {}\n```",
+			want:    []string{"  go", "  func main() {}"},
+		},
+		{
+			name:    "bullets wrap with hanging indent",
+			content: "- alpha beta gamma",
+			width:   12,
+			want:    []string{"• alpha beta", "  gamma"},
+		},
+		{
+			name:    "ordered list",
+			content: "1. first\n2. `second`",
+			want:    []string{"1. first", "2. second"},
+		},
+		{
+			name:    "paragraph wraps to width",
+			content: "one two three four",
+			width:   9,
+			want:    []string{"one two", "three", "four"},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			lines := renderMarkdown(tc.content, tc.width)
+			got := make([]string, 0, len(lines))
+			for _, line := range lines {
+				got = append(got, ansi.Strip(line))
+				if tc.width > 0 && ansi.StringWidth(line) > tc.width {
+					t.Fatalf("line %q width = %d, want <= %d", line, ansi.StringWidth(line), tc.width)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Fatalf("renderMarkdown() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
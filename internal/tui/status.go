@@ -3,8 +3,24 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
+// statusTickInterval is how often the busy spinner advances.
+const statusTickInterval = 120 * time.Millisecond
+
+// spinnerFrames animate the state token while the agent is busy.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// statusTickMsg advances the status spinner.
+type statusTickMsg struct{}
+
+func statusTickCommand() tea.Cmd {
+	return tea.Tick(statusTickInterval, func(time.Time) tea.Msg { return statusTickMsg{} })
+}
+
 // StatusModel renders the top status bar.
 type StatusModel struct {
 	Version   string
@@ -12,6 +28,16 @@ type StatusModel struct {
 	CWD       string
 	SessionID string
 	State     string
+	// Warning is shown after the state, e.g. when the context is nearly full.
+	Warning string
+	// PlanMode marks that only read-only tools are available.
+	PlanMode bool
+
+	// runTokens counts output tokens of the run's finished turns and
+	// turnTokens those reported so far for the current turn.
+	runTokens  int
+	turnTokens int
+	frame      int
 }
 
 // NewStatusModel constructs status data for rendering.
@@ -33,6 +59,44 @@ func (m *StatusModel) SetState(state string) {
 	}
 }
 
+// SetWarning replaces the warning token; empty clears it.
+func (m *StatusModel) SetWarning(warning string) {
+	m.Warning = strings.TrimSpace(warning)
+}
+
+// Busy reports whether the agent is streaming or running tools.
+func (m StatusModel) Busy() bool {
+	return m.State == "streaming" || m.State == "tool_executing"
+}
+
+// ResetProgress clears the output token counter at the start of a run.
+func (m *StatusModel) ResetProgress() {
+	m.runTokens = 0
+	m.turnTokens = 0
+}
+
+// SetTurnOutputTokens records the output tokens reported so far for the
+// current model turn.
+func (m *StatusModel) SetTurnOutputTokens(tokens int) {
+	m.turnTokens = tokens
+}
+
+// FinishTurn adds the current turn's output tokens to the run total.
+func (m *StatusModel) FinishTurn() {
+	m.runTokens += m.turnTokens
+	m.turnTokens = 0
+}
+
+// OutputTokens returns the output tokens generated so far in the run.
+func (m StatusModel) OutputTokens() int {
+	return m.runTokens + m.turnTokens
+}
+
+// Tick advances the busy spinner by one frame.
+func (m *StatusModel) Tick() {
+	m.frame = (m.frame + 1) % len(spinnerFrames)
+}
+
 // Render draws a one-line status bar.
 func (m StatusModel) Render(width int, theme Theme) string {
 	parts := []string{
@@ -40,7 +104,13 @@ func (m StatusModel) Render(width int, theme Theme) string {
 		fallbackText(m.ModelName, "unknown-model"),
 		fallbackText(m.CWD, "unknown-cwd"),
 		"session: " + fallbackText(m.SessionID, "new"),
-		"state: " + fallbackText(m.State, "idle"),
+		"state: " + m.stateText(),
+	}
+	if m.PlanMode {
+		parts = append(parts, "plan mode")
+	}
+	if m.Warning != "" {
+		parts = append(parts, "warning: "+m.Warning)
 	}
 	line := strings.Join(parts, " | ")
 	style := theme.StatusBarStyle
@@ -50,6 +120,20 @@ func (m StatusModel) Render(width int, theme Theme) string {
 	return style.Render(line)
 }
 
+// stateText is the state token, led by the spinner and followed by the
+// run's output token count while busy.
+func (m StatusModel) stateText() string {
+	state := fallbackText(m.State, "idle")
+	if !m.Busy() {
+		return state
+	}
+	text := spinnerFrames[m.frame] + " " + state
+	if tokens := m.OutputTokens(); tokens > 0 {
+		text += fmt.Sprintf(" · %d tokens", tokens)
+	}
+	return text
+}
+
 func fallbackText(value, fallback string) string {
 	trimmed := strings.TrimSpace(value)
 	if trimmed == "" {
@@ -0,0 +1,67 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatusModelRendersTokenCountWhileBusy(t *testing.T) {
+	t.Parallel()
+
+	theme := newDarkTheme()
+	status := StatusModel{State: "streaming"}
+	status.SetTurnOutputTokens(120)
+	status.FinishTurn()
+	status.SetState("tool_executing")
+	status.SetTurnOutputTokens(35)
+
+	got := status.Render(0, theme)
+	if !strings.Contains(got, "state: "+spinnerFrames[0]+" tool_executing · 155 tokens") {
+		t.Fatalf("Render() = %q, want spinner and 155 tokens", got)
+	}
+
+	status.SetState("idle")
+	if got := status.Render(0, theme); strings.Contains(got, "tokens") || !strings.Contains(got, "state: idle") {
+		t.Fatalf("Render() idle = %q, want plain state", got)
+	}
+
+	status.ResetProgress()
+	status.SetState("streaming")
+	if got := status.Render(0, theme); strings.Contains(got, "tokens") {
+		t.Fatalf("Render() after reset = %q, want no token count", got)
+	}
+}
+
+func TestStatusModelTickAdvancesSpinner(t *testing.T) {
+	t.Parallel()
+
+	theme := newDarkTheme()
+	status := StatusModel{State: "streaming"}
+	first := status.Render(0, theme)
+	status.Tick()
+	second := status.Render(0, theme)
+	if first == second || !strings.Contains(second, spinnerFrames[1]) {
+		t.Fatalf("Render() after Tick = %q, want frame %q (before %q)", second, spinnerFrames[1], first)
+	}
+
+	for range len(spinnerFrames) - 1 {
+		status.Tick()
+	}
+	if got := status.Render(0, theme); got != first {
+		t.Fatalf("Render() after a full cycle = %q, want %q", got, first)
+	}
+}
+
+func TestStatusModelRendersPlanMode(t *testing.T) {
+	t.Parallel()
+
+	theme := newDarkTheme()
+	status := StatusModel{State: "idle"}
+	if got := status.Render(0, theme); strings.Contains(got, "plan mode") {
+		t.Fatalf("Render() = %q, want no plan mode indicator", got)
+	}
+	status.PlanMode = true
+	if got := status.Render(0, theme); !strings.Contains(got, "state: idle | plan mode") {
+		t.Fatalf("Render() = %q, want plan mode indicator", got)
+	}
+}
@@ -1,11 +1,47 @@
 package tui
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/pelletier/go-toml/v2"
 )
 
+// themeBaseKey names the built-in theme a theme file starts from.
+const themeBaseKey = "base"
+
+// MonochromeThemeName names the theme without colors or text attributes,
+// used when color is turned off.
+const MonochromeThemeName = "mono"
+
+// noColorEnv disables color when set to any non-empty value; see no-color.org.
+const noColorEnv = "NO_COLOR"
+
+// themeColorSetters maps each theme file color key to the style it changes.
+var themeColorSetters = map[string]func(*Theme, lipgloss.Color){
+	"status_foreground": func(t *Theme, c lipgloss.Color) { t.StatusBarStyle = t.StatusBarStyle.Foreground(c) },
+	"status_background": func(t *Theme, c lipgloss.Color) { t.StatusBarStyle = t.StatusBarStyle.Background(c) },
+	"panel_border":      func(t *Theme, c lipgloss.Color) { t.PanelStyle = t.PanelStyle.BorderForeground(c) },
+	"inspector_border":  func(t *Theme, c lipgloss.Color) { t.InspectorStyle = t.InspectorStyle.BorderForeground(c) },
+	"user":              func(t *Theme, c lipgloss.Color) { t.UserPrefixStyle = t.UserPrefixStyle.Foreground(c) },
+	"assistant":         func(t *Theme, c lipgloss.Color) { t.AssistantPrefixStyle = t.AssistantPrefixStyle.Foreground(c) },
+	"tool":              func(t *Theme, c lipgloss.Color) { t.ToolPrefixStyle = t.ToolPrefixStyle.Foreground(c) },
+	"thinking":          func(t *Theme, c lipgloss.Color) { t.ThinkingStyle = t.ThinkingStyle.Foreground(c) },
+	"input_prompt":      func(t *Theme, c lipgloss.Color) { t.InputPromptStyle = t.InputPromptStyle.Foreground(c) },
+	"input_text":        func(t *Theme, c lipgloss.Color) { t.InputTextStyle = t.InputTextStyle.Foreground(c) },
+	"input_placeholder": func(t *Theme, c lipgloss.Color) {
+		t.InputPlaceholderTextStyle = t.InputPlaceholderTextStyle.Foreground(c)
+	},
+}
+
 // Theme contains style tokens used by the terminal UI.
 type Theme struct {
 	Name                      string
@@ -15,14 +51,21 @@ type Theme struct {
 	UserPrefixStyle           lipgloss.Style
 	AssistantPrefixStyle      lipgloss.Style
 	ToolPrefixStyle           lipgloss.Style
+	ThinkingStyle             lipgloss.Style
 	InputPromptStyle          lipgloss.Style
 	InputTextStyle            lipgloss.Style
 	InputPlaceholderTextStyle lipgloss.Style
 }
 
-// ResolveTheme returns the configured theme or the dark default.
+// ResolveTheme returns the configured theme or the dark default. It returns
+// the monochrome theme whenever NO_COLOR is set.
 func ResolveTheme(name string) Theme {
+	if os.Getenv(noColorEnv) != "" {
+		return newMonochromeTheme()
+	}
 	switch strings.ToLower(strings.TrimSpace(name)) {
+	case MonochromeThemeName:
+		return newMonochromeTheme()
 	case "light":
 		return newLightTheme()
 	default:
@@ -30,6 +73,105 @@ func ResolveTheme(name string) Theme {
 	}
 }
 
+// ResolveThemeFile loads path with LoadThemeFile, or returns the named theme
+// when path is empty, the file does not exist, or NO_COLOR is set.
+func ResolveThemeFile(name, path string) (Theme, error) {
+	if strings.TrimSpace(path) == "" || os.Getenv(noColorEnv) != "" {
+		return ResolveTheme(name), nil
+	}
+	theme, err := LoadThemeFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ResolveTheme(name), nil
+	}
+	return theme, err
+}
+
+// LoadThemeFile builds a theme from a TOML file, or a JSON file when path ends
+// in .json. The file is a flat map of color keys to lipgloss colors ("#ff8800"
+// or an ANSI number such as "63"); an optional base key picks the built-in
+// theme whose styles it starts from.
+func LoadThemeFile(path string) (Theme, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Theme{}, fmt.Errorf("read theme file: %w", err)
+	}
+	values := map[string]any{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(raw, &values)
+	} else {
+		err = toml.Unmarshal(raw, &values)
+	}
+	if err != nil {
+		return Theme{}, fmt.Errorf("parse theme file %s: %w", path, err)
+	}
+
+	colors := make(map[string]string, len(values))
+	keys := make([]string, 0, len(values))
+	for key, value := range values {
+		if _, ok := themeColorSetters[key]; !ok && key != themeBaseKey {
+			return Theme{}, fmt.Errorf("theme file %s: unknown key %q", path, key)
+		}
+		text, ok := value.(string)
+		if !ok {
+			return Theme{}, fmt.Errorf("theme file %s: %s must be a string", path, key)
+		}
+		colors[key] = strings.TrimSpace(text)
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	theme := ResolveTheme(colors[themeBaseKey])
+	theme.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, key := range keys {
+		if key == themeBaseKey {
+			continue
+		}
+		if !validThemeColor(colors[key]) {
+			return Theme{}, fmt.Errorf("theme file %s: %s has invalid color %q", path, key, colors[key])
+		}
+		themeColorSetters[key](&theme, lipgloss.Color(colors[key]))
+	}
+	return theme, nil
+}
+
+// ColorEnabled reports whether the TUI should use color: configured is on,
+// NO_COLOR is unset or empty, and stdout is a terminal.
+func ColorEnabled(configured bool) bool {
+	if !configured || os.Getenv(noColorEnv) != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// DisableColor makes the styles used for markdown, code, diffs, and tool
+// output, which do not come from the Theme, render plain text: no color,
+// italics, bold, underline, or faint. Call it before starting the TUI,
+// alongside the monochrome theme.
+func DisableColor() {
+	for _, style := range []*lipgloss.Style{
+		&codeKeywordStyle, &codeStringStyle, &codeNumberStyle, &codeCommentStyle,
+		&diffAddedStyle, &diffRemovedStyle, &thoughtStyle,
+		&markdownHeadingStyle, &markdownBoldStyle, &markdownInlineCodeStyle,
+		&markdownCodeBlockStyle, &markdownMutedStyle,
+	} {
+		*style = lipgloss.NewStyle()
+	}
+}
+
+// validThemeColor accepts #rgb and #rrggbb hex colors and ANSI numbers 0-255.
+func validThemeColor(color string) bool {
+	if hex, ok := strings.CutPrefix(color, "#"); ok {
+		if len(hex) != 3 && len(hex) != 6 {
+			return false
+		}
+		_, err := strconv.ParseUint(hex, 16, 32)
+		return err == nil
+	}
+	n, err := strconv.Atoi(color)
+	return err == nil && n >= 0 && n <= 255
+}
+
 func newDarkTheme() Theme {
 	border := lipgloss.Color("63")
 	muted := lipgloss.Color("245")
@@ -50,6 +192,7 @@ func newDarkTheme() Theme {
 		UserPrefixStyle:      lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Bold(true),
 		AssistantPrefixStyle: lipgloss.NewStyle().Foreground(lipgloss.Color("220")).Bold(true),
 		ToolPrefixStyle:      lipgloss.NewStyle().Foreground(lipgloss.Color("111")).Bold(true),
+		ThinkingStyle:        lipgloss.NewStyle().Foreground(muted).Italic(true),
 		InputPromptStyle:     lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Bold(true),
 		InputTextStyle:       lipgloss.NewStyle().Foreground(lipgloss.Color("252")),
 		InputPlaceholderTextStyle: lipgloss.NewStyle().
@@ -58,6 +201,28 @@ func newDarkTheme() Theme {
 	}
 }
 
+// newMonochromeTheme keeps the panel borders and padding of the other themes
+// so the layout is unchanged, but renders every text style plainly.
+func newMonochromeTheme() Theme {
+	return Theme{
+		Name:           MonochromeThemeName,
+		StatusBarStyle: lipgloss.NewStyle().Padding(0, 1),
+		PanelStyle: lipgloss.NewStyle().
+			Border(lipgloss.NormalBorder()).
+			Padding(0, 1),
+		InspectorStyle: lipgloss.NewStyle().
+			Border(lipgloss.NormalBorder()).
+			Padding(0, 1),
+		UserPrefixStyle:           lipgloss.NewStyle(),
+		AssistantPrefixStyle:      lipgloss.NewStyle(),
+		ToolPrefixStyle:           lipgloss.NewStyle(),
+		ThinkingStyle:             lipgloss.NewStyle(),
+		InputPromptStyle:          lipgloss.NewStyle(),
+		InputTextStyle:            lipgloss.NewStyle(),
+		InputPlaceholderTextStyle: lipgloss.NewStyle(),
+	}
+}
+
 func newLightTheme() Theme {
 	border := lipgloss.Color("246")
 	muted := lipgloss.Color("240")
@@ -78,6 +243,7 @@ func newLightTheme() Theme {
 		UserPrefixStyle:      lipgloss.NewStyle().Foreground(lipgloss.Color("25")).Bold(true),
 		AssistantPrefixStyle: lipgloss.NewStyle().Foreground(lipgloss.Color("94")).Bold(true),
 		ToolPrefixStyle:      lipgloss.NewStyle().Foreground(lipgloss.Color("31")).Bold(true),
+		ThinkingStyle:        lipgloss.NewStyle().Foreground(muted).Italic(true),
 		InputPromptStyle:     lipgloss.NewStyle().Foreground(lipgloss.Color("25")).Bold(true),
 		InputTextStyle:       lipgloss.NewStyle().Foreground(lipgloss.Color("16")),
 		InputPlaceholderTextStyle: lipgloss.NewStyle().
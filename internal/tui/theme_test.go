@@ -0,0 +1,150 @@
+package tui
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestLoadThemeFileAppliesColors(t *testing.T) {
+	// A base theme resolves to monochrome under NO_COLOR.
+	t.Setenv("NO_COLOR", "")
+
+	dir := t.TempDir()
+	tomlPath := filepath.Join(dir, "solar.toml")
+	if err := os.WriteFile(tomlPath, []byte("base = \"light\"\nuser = \"#ff8800\"\npanel_border = \"33\"\nstatus_background = \"#123\"\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	theme, err := LoadThemeFile(tomlPath)
+	if err != nil {
+		t.Fatalf("LoadThemeFile() error = %v", err)
+	}
+	if theme.Name != "solar" {
+		t.Fatalf("Name = %q, want solar", theme.Name)
+	}
+	if got := theme.UserPrefixStyle.GetForeground(); got != lipgloss.Color("#ff8800") {
+		t.Fatalf("UserPrefixStyle foreground = %v, want #ff8800", got)
+	}
+	if !theme.UserPrefixStyle.GetBold() {
+		t.Fatal("UserPrefixStyle bold = false, want base style kept")
+	}
+	if got := theme.PanelStyle.GetBorderTopForeground(); got != lipgloss.Color("33") {
+		t.Fatalf("PanelStyle border = %v, want 33", got)
+	}
+	if got := theme.StatusBarStyle.GetBackground(); got != lipgloss.Color("#123") {
+		t.Fatalf("StatusBarStyle background = %v, want #123", got)
+	}
+	if got, want := theme.AssistantPrefixStyle.GetForeground(), newLightTheme().AssistantPrefixStyle.GetForeground(); got != want {
+		t.Fatalf("AssistantPrefixStyle foreground = %v, want light base %v", got, want)
+	}
+
+	jsonPath := filepath.Join(dir, "mono.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"tool":"250","inspector_border":"#aabbcc"}`), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	theme, err = LoadThemeFile(jsonPath)
+	if err != nil {
+		t.Fatalf("LoadThemeFile(json) error = %v", err)
+	}
+	if got := theme.ToolPrefixStyle.GetForeground(); got != lipgloss.Color("250") {
+		t.Fatalf("ToolPrefixStyle foreground = %v, want 250", got)
+	}
+	if got := theme.InspectorStyle.GetBorderTopForeground(); got != lipgloss.Color("#aabbcc") {
+		t.Fatalf("InspectorStyle border = %v, want #aabbcc", got)
+	}
+}
+
+func TestLoadThemeFileRejectsInvalidEntries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "unknown key", content: "usr = \"39\"\n", want: `unknown key "usr"`},
+		{name: "non-string", content: "user = 39\n", want: "user must be a string"},
+		{name: "bad hex", content: "user = \"#12345\"\n", want: "invalid color"},
+		{name: "out of range", content: "tool = \"256\"\n", want: "invalid color"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "theme.toml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			_, err := LoadThemeFile(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoadThemeFile() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveThemeFileFallsBackToNamedTheme(t *testing.T) {
+	// A base theme resolves to monochrome under NO_COLOR.
+	t.Setenv("NO_COLOR", "")
+
+	theme, err := ResolveThemeFile("light", filepath.Join(t.TempDir(), "missing.toml"))
+	if err != nil {
+		t.Fatalf("ResolveThemeFile() error = %v", err)
+	}
+	if theme.Name != "light" {
+		t.Fatalf("Name = %q, want light fallback", theme.Name)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.toml")
+	if err := os.WriteFile(bad, []byte("nope = \"1\"\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := ResolveThemeFile("light", bad); err == nil {
+		t.Fatal("ResolveThemeFile(invalid) error = nil, want validation error")
+	}
+}
+
+func TestResolveThemeHonorsNoColor(t *testing.T) {
+	renderer := lipgloss.NewRenderer(io.Discard)
+	renderer.SetColorProfile(termenv.TrueColor)
+	render := func(style lipgloss.Style, text string) string {
+		return style.Renderer(renderer).Render(text)
+	}
+
+	t.Setenv("NO_COLOR", "")
+	if got := render(ResolveTheme("dark").UserPrefixStyle, "you"); got == "you" {
+		t.Fatalf("dark UserPrefixStyle rendered %q, want escape codes with color on", got)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	theme := ResolveTheme("dark")
+	if theme.Name != MonochromeThemeName {
+		t.Fatalf("ResolveTheme() with NO_COLOR = %q, want %q", theme.Name, MonochromeThemeName)
+	}
+	for name, style := range map[string]lipgloss.Style{
+		"user":      theme.UserPrefixStyle,
+		"assistant": theme.AssistantPrefixStyle,
+		"tool":      theme.ToolPrefixStyle,
+	} {
+		if got := render(style, "prefix"); got != "prefix" {
+			t.Fatalf("%s prefix rendered %q with NO_COLOR, want plain text", name, got)
+		}
+	}
+	if panel := render(theme.PanelStyle, "x"); strings.Contains(panel, "\x1b[") {
+		t.Fatalf("PanelStyle rendered %q with NO_COLOR, want borders without escape codes", panel)
+	}
+
+	fileTheme, err := ResolveThemeFile("light", filepath.Join(t.TempDir(), "missing.toml"))
+	if err != nil || fileTheme.Name != MonochromeThemeName {
+		t.Fatalf("ResolveThemeFile() with NO_COLOR = %q, %v; want monochrome", fileTheme.Name, err)
+	}
+	if ColorEnabled(true) {
+		t.Fatal("ColorEnabled(true) with NO_COLOR = true, want false")
+	}
+}
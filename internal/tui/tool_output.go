@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// toolOutputLineBytes bounds the unfinished line outputTail keeps, so output
+// that never prints a newline cannot grow it without limit.
+const toolOutputLineBytes = 4096
+
+// outputTail keeps the last toolProgressLines lines of streamed tool output
+// in a ring, so a long-running command costs the same per chunk however much
+// it has printed.
+type outputTail struct {
+	lines [toolProgressLines]string
+	// next is the slot the next complete line goes to; full is set once the
+	// ring has wrapped.
+	next int
+	full bool
+	// partial is the text after the last newline.
+	partial string
+}
+
+// Write adds one chunk of output.
+func (t *outputTail) Write(chunk string) {
+	for {
+		i := strings.IndexByte(chunk, '\n')
+		if i < 0 {
+			t.partial = lastBytes(t.partial+chunk, toolOutputLineBytes)
+			return
+		}
+		t.lines[t.next] = lastBytes(t.partial+chunk[:i], toolOutputLineBytes)
+		t.partial = ""
+		t.next = (t.next + 1) % len(t.lines)
+		if t.next == 0 {
+			t.full = true
+		}
+		chunk = chunk[i+1:]
+	}
+}
+
+// String returns at most toolProgressLines trailing lines, oldest first.
+func (t *outputTail) String() string {
+	lines := make([]string, 0, len(t.lines)+1)
+	if t.full {
+		lines = append(lines, t.lines[t.next:]...)
+	}
+	lines = append(lines, t.lines[:t.next]...)
+	if t.partial != "" {
+		lines = append(lines, t.partial)
+	}
+	if len(lines) > toolProgressLines {
+		lines = lines[len(lines)-toolProgressLines:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lastBytes returns the trailing n bytes of text or fewer, never starting
+// inside a multibyte character.
+func lastBytes(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	text = text[len(text)-n:]
+	for len(text) > 0 && !utf8.RuneStart(text[0]) {
+		text = text[1:]
+	}
+	return text
+}
@@ -0,0 +1,36 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestOutputTailKeepsLastLines(t *testing.T) {
+	t.Parallel()
+
+	var tail outputTail
+	tail.Write("one\ntw")
+	tail.Write("o\nthree")
+	if got := tail.String(); got != "one\ntwo\nthree" {
+		t.Fatalf("String() = %q, want lines joined across chunks", got)
+	}
+
+	var want []string
+	for i := 0; i < 3*toolProgressLines; i++ {
+		line := fmt.Sprintf("line %d", i)
+		tail.Write(line + "\n")
+		want = append(want, line)
+	}
+	want = want[len(want)-toolProgressLines:]
+	if got := tail.String(); got != strings.Join(want, "\n") {
+		t.Fatalf("String() = %q, want the last %d lines", got, toolProgressLines)
+	}
+
+	tail.Write(strings.Repeat("é", toolOutputLineBytes))
+	last := tail.String()[strings.LastIndexByte(tail.String(), '\n')+1:]
+	if len(last) > toolOutputLineBytes || !utf8.ValidString(last) {
+		t.Fatalf("unfinished line = %d bytes, valid %v; want capped cleanly", len(last), utf8.ValidString(last))
+	}
+}